@@ -195,7 +195,7 @@ func (in *Route) DeepCopyInto(out *Route) {
 	if in.RetryPolicy != nil {
 		in, out := &in.RetryPolicy, &out.RetryPolicy
 		*out = new(v1.RetryPolicy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -221,7 +221,7 @@ func (in *Service) DeepCopyInto(out *Service) {
 	if in.UpstreamValidation != nil {
 		in, out := &in.UpstreamValidation, &out.UpstreamValidation
 		*out = new(v1.UpstreamValidation)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
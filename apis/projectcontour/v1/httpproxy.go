@@ -32,6 +32,11 @@ type HTTPProxySpec struct {
 	// Includes allow for specific routing configuration to be appended to another HTTPProxy in another namespace.
 	// +optional
 	Includes []Include `json:"includes,omitempty"`
+	// IngressClassName is the ingress class of the Contour which serves
+	// this HTTPProxy. It takes precedence over any ingress class annotation.
+	// If neither is set, every Contour serves the HTTPProxy.
+	// +optional
+	IngressClassName string `json:"ingressClassName,omitempty"`
 }
 
 // Include describes a set of policies that can be applied to an HTTPProxy in a namespace.
@@ -51,11 +56,30 @@ type Include struct {
 type Condition struct {
 	// Prefix defines a prefix match for a request.
 	// +optional
+	// +kubebuilder:validation:Pattern=`^/`
 	Prefix string `json:"prefix,omitempty"`
 
+	// Regex defines a regular expression, in RE2 syntax, which the
+	// whole path of a request must match. It is matched after the
+	// prefixes of any includes the route is reached through, and
+	// cannot be combined with a prefix, nor used in includes.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Exact defines a path which the whole path of a request must
+	// equal. It is appended to the prefixes of any includes the
+	// route is reached through, and cannot be combined with a prefix
+	// or regex, nor used in includes.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
 	// Header specifies the header condition to match.
 	// +optional
 	Header *HeaderCondition `json:"header,omitempty"`
+
+	// QueryParameter specifies the query parameter condition to match.
+	// +optional
+	QueryParameter *QueryParameterCondition `json:"queryParameter,omitempty"`
 }
 
 // HeaderCondition specifies the header condition to match.
@@ -90,6 +114,52 @@ type HeaderCondition struct {
 	// in the request.
 	// +optional
 	NotExact string `json:"notexact,omitempty"`
+
+	// Regex is true if the whole value of the Header matches this
+	// regular expression, in RE2 syntax.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// OneOf is true if the Header is present in the request with
+	// exactly one of these values.
+	// +optional
+	OneOf []string `json:"oneOf,omitempty"`
+
+	// IgnoreCase compares the value of the Header with that of
+	// Contains, NotContains, Exact, NotExact, Regex or OneOf
+	// without regard to case.
+	// +optional
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+}
+
+// QueryParameterCondition specifies how to conditionally match against
+// a query parameter of the request. Exactly one of Exact, Prefix, Regex
+// and Present must be set.
+type QueryParameterCondition struct {
+
+	// Name is the name of the query parameter to match on. Name is
+	// required. Query parameter names are case sensitive.
+	Name string `json:"name"`
+
+	// Exact matches the query parameter whose value is exactly this
+	// string.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Prefix matches the query parameter whose value starts with this
+	// string.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Regex matches the query parameter whose whole value matches this
+	// regular expression.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Present matches the query parameter if it is present in the
+	// request, whatever its value.
+	// +optional
+	Present bool `json:"present,omitempty"`
 }
 
 // VirtualHost appears at most once. If it is present, the object is considered
@@ -97,12 +167,195 @@ type HeaderCondition struct {
 type VirtualHost struct {
 	// The fully qualified domain name of the root of the ingress tree
 	// all leaves of the DAG rooted at this object relate to the fqdn
+	// +kubebuilder:validation:MinLength=1
 	Fqdn string `json:"fqdn"`
 	// If present describes tls properties. The CNI names that will be matched on
 	// are described in fqdn, the tls.secretName secret must contain a
 	// matching certificate
 	// +optional
 	TLS *TLS `json:"tls,omitempty"`
+	// If present, only the named request headers, and those required
+	// to proxy the request, are forwarded to the backend. All other
+	// request headers are removed.
+	// +optional
+	RequestHeadersAllowList []string `json:"requestHeadersAllowList,omitempty"`
+	// The downstream protocol policy of the routes of this virtual host
+	// which have none of their own.
+	// +optional
+	DownstreamProtocolPolicy *DownstreamProtocolPolicy `json:"downstreamProtocolPolicy,omitempty"`
+	// If Maintenance is true, every request to the virtual host is
+	// answered according to the MaintenancePolicy in place of its routes.
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
+	// The maintenance policy for this virtual host.
+	// +optional
+	MaintenancePolicy *MaintenancePolicy `json:"maintenancePolicy,omitempty"`
+	// If present, every request to the virtual host is redirected to
+	// another fqdn. A virtual host with a redirect cannot have routes,
+	// includes, or a tcpproxy.
+	// +optional
+	Redirect *VirtualHostRedirect `json:"redirect,omitempty"`
+	// The trailing slash policy for the routes of this virtual
+	// host, and of the HTTPProxies it includes.
+	// +optional
+	TrailingSlashPolicy *TrailingSlashPolicy `json:"trailingSlashPolicy,omitempty"`
+	// If present, each request to the virtual host is authorized
+	// by an external authorization service before it is routed.
+	// Requires TLS.
+	// +optional
+	Authorization *AuthorizationServer `json:"authorization,omitempty"`
+	// JWTProviders are the issuers of the JSON Web Tokens which
+	// routes of the virtual host may require. Requires TLS.
+	// +optional
+	JWTProviders []JWTProvider `json:"jwtProviders,omitempty"`
+	// If set, whether requests to the virtual host are allowed (true)
+	// or rejected (false) while the rate limit service cannot be
+	// reached, in place of Contour's rate-limit-service.failure-mode-deny.
+	// Requires TLS.
+	// +optional
+	RateLimitFailOpen *bool `json:"rateLimitFailOpen,omitempty"`
+	// If present, browsers on the allowed origins may make
+	// cross-origin requests to each route of the virtual host.
+	// +optional
+	CORSPolicy *CORSPolicy `json:"corsPolicy,omitempty"`
+}
+
+// CORSPolicy allows cross-origin requests to a virtual host.
+type CORSPolicy struct {
+	// AllowOrigin lists the origins, such as https://example.com,
+	// allowed to make requests. The origin * allows any origin.
+	// +optional
+	AllowOrigin []string `json:"allowOrigin,omitempty"`
+	// AllowOriginRegex lists regular expressions matching the
+	// origins allowed to make requests.
+	// +optional
+	AllowOriginRegex []string `json:"allowOriginRegex,omitempty"`
+	// AllowMethods lists the methods allowed in requests.
+	// +optional
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	// AllowHeaders lists the headers allowed in requests.
+	// +optional
+	AllowHeaders []string `json:"allowHeaders,omitempty"`
+	// ExposeHeaders lists the response headers browsers may
+	// expose to the requesting page.
+	// +optional
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+	// MaxAge is how long browsers may cache the result of a
+	// preflight request.
+	// +optional
+	MaxAge string `json:"maxAge,omitempty"`
+	// AllowCredentials, if true, allows requests to carry
+	// credentials such as cookies.
+	// +optional
+	AllowCredentials bool `json:"allowCredentials,omitempty"`
+}
+
+// JWTProvider describes how to verify the JSON Web Tokens of an issuer.
+type JWTProvider struct {
+	// Name identifies the provider in the jwtVerificationPolicy of
+	// a route.
+	Name string `json:"name"`
+	// Issuer, if present, must match the iss claim of the JWT.
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Audiences, if present, must include one of the aud claims
+	// of the JWT.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+	// RemoteJWKS is where the keys which sign the JWTs of this
+	// provider are fetched from.
+	RemoteJWKS RemoteJWKS `json:"remoteJWKS"`
+	// Default, if true, requires a JWT from this provider on every
+	// route of the virtual host without a jwtVerificationPolicy.
+	// Only one provider may be the default.
+	// +optional
+	Default bool `json:"default,omitempty"`
+	// ForwardJWT, if true, forwards the JWT to the upstream
+	// Service. By default it is removed from the request.
+	// +optional
+	ForwardJWT bool `json:"forwardJWT,omitempty"`
+}
+
+// RemoteJWKS is a JSON Web Key Set fetched over HTTP.
+type RemoteJWKS struct {
+	// URI is the http or https URI of the JWKS.
+	URI string `json:"uri"`
+	// Timeout is how long to wait for the JWKS to be fetched.
+	// Defaults to 1s.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// CacheDuration is how long the JWKS is cached for.
+	// Defaults to 5m.
+	// +optional
+	CacheDuration string `json:"cacheDuration,omitempty"`
+	// UpstreamValidation defines how to verify the certificate of
+	// the host serving the JWKS. It is required for https URIs.
+	// +optional
+	UpstreamValidation *UpstreamValidation `json:"validation,omitempty"`
+}
+
+// AuthorizationServer is the external authorization service which
+// authorizes requests to a virtual host.
+type AuthorizationServer struct {
+	// Name is the name of the Service implementing Envoy's
+	// external authorization gRPC service, in the namespace
+	// of the HTTPProxy.
+	Name string `json:"name"`
+	// Port is the port of the Service.
+	Port int `json:"port"`
+	// FailOpen allows requests when the authorization service
+	// cannot be reached or fails to respond. By default such
+	// requests are rejected.
+	// +optional
+	FailOpen bool `json:"failOpen,omitempty"`
+	// ResponseTimeout is how long to wait for the authorization
+	// service to respond. Defaults to 200ms.
+	// +optional
+	ResponseTimeout string `json:"responseTimeout,omitempty"`
+}
+
+// VirtualHostRedirect describes the redirect of every request to
+// a virtual host to another fqdn.
+type VirtualHostRedirect struct {
+	// Fqdn is the fully qualified domain name requests are
+	// redirected to. The path and query string of the request
+	// are preserved.
+	Fqdn string `json:"fqdn"`
+	// Scheme is the scheme of the redirect location, either http
+	// or https. Defaults to the scheme of the request.
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+	// StatusCode is the HTTP status code of the redirect, one of
+	// 301, 302, 303, 307, or 308. Defaults to 301.
+	// +optional
+	StatusCode uint32 `json:"statusCode,omitempty"`
+}
+
+// DownstreamProtocolPolicy restricts the HTTP protocol of the
+// requests a route serves.
+type DownstreamProtocolPolicy struct {
+	// Protocol is the only protocol served, "HTTP/1.1" or "HTTP/2".
+	// +kubebuilder:validation:Enum=HTTP/1.1;HTTP/2
+	Protocol string `json:"protocol"`
+	// Status of the response to requests of any other protocol.
+	// If not supplied, 426 (Upgrade Required) is returned when
+	// HTTP/2 is required and 505 (HTTP Version Not Supported) when
+	// HTTP/1.1 is required.
+	// +optional
+	Status uint32 `json:"status,omitempty"`
+}
+
+// MaintenancePolicy describes how a virtual host in maintenance
+// answers requests.
+type MaintenancePolicy struct {
+	// StatusCode is the HTTP status code returned to every request.
+	// Defaults to 503. Ignored if Service is supplied.
+	// +optional
+	StatusCode uint32 `json:"statusCode,omitempty"`
+	// Service, if supplied, serves every request, for example
+	// with a maintenance page.
+	// +optional
+	Service *Service `json:"service,omitempty"`
 }
 
 // TLS describes tls properties. The CNI names that will be matched on
@@ -114,11 +367,119 @@ type TLS struct {
 	// Minimum TLS version this vhost should negotiate
 	// +optional
 	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
+	// Maximum TLS version this vhost should negotiate
+	// +optional
+	MaximumProtocolVersion string `json:"maximumProtocolVersion,omitempty"`
+	// CipherSuites are the cipher suites this vhost offers for
+	// TLS versions below 1.3, in order of preference.
+	// +optional
+	CipherSuites []string `json:"cipherSuites,omitempty"`
 	// If Passthrough is set to true, the SecretName will be ignored
 	// and the encrypted handshake will be passed through to the
 	// backing cluster.
 	// +optional
 	Passthrough bool `json:"passthrough,omitempty"`
+	// If ForwardTLSAttributes is set to true, the attributes negotiated
+	// during the downstream TLS handshake are forwarded to the backend
+	// as request headers.
+	// +optional
+	ForwardTLSAttributes bool `json:"forwardTLSAttributes,omitempty"`
+	// If StrictSNI is set to true, requests whose Host header does not
+	// name this vhost are rejected with a 421 Misdirected Request, rather
+	// than served by the vhost they name, if the TLS server name of their
+	// connection is this vhost's.
+	// +optional
+	StrictSNI bool `json:"strictSNI,omitempty"`
+	// ClientValidation, if set, requires clients connecting to this
+	// vhost to present a certificate signed by the supplied CA.
+	// +optional
+	ClientValidation *DownstreamValidation `json:"clientValidation,omitempty"`
+	// RedirectPolicy customises the responses which redirect
+	// insecure requests to HTTPS.
+	// +optional
+	RedirectPolicy *HTTPSRedirectPolicy `json:"redirectPolicy,omitempty"`
+}
+
+// DownstreamValidation defines how to validate the certificates
+// presented by clients.
+type DownstreamValidation struct {
+	// Name of the Kubernetes secret, holding the CA bundle in its ca.crt
+	// key, which client certificates must chain to. A secret in another
+	// namespace is named namespace/name, and must be delegated to this
+	// HTTPProxy's namespace.
+	CACertificate string `json:"caSecret"`
+	// Name of the Kubernetes secret, holding a certificate revocation
+	// list in its crl.pem key, which client certificates are checked
+	// against. A secret in another namespace is named namespace/name,
+	// and must be delegated to this HTTPProxy's namespace.
+	// +optional
+	CertificateRevocationList string `json:"crlSecret,omitempty"`
+	// If Optional is set to true, clients which present no certificate
+	// are served. A certificate which is presented must still validate.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+	// ForwardClientCertificate, if set, forwards the details of the
+	// client certificate to the backend in the X-Forwarded-Client-Cert
+	// header, replacing any the client sent.
+	// +optional
+	ForwardClientCertificate *ClientCertificateDetails `json:"forwardClientCertificate,omitempty"`
+}
+
+// ClientCertificateDetails selects the details of the client certificate
+// forwarded in the X-Forwarded-Client-Cert header, in addition to its hash.
+type ClientCertificateDetails struct {
+	// Subject forwards the subject of the certificate.
+	// +optional
+	Subject bool `json:"subject,omitempty"`
+	// Cert forwards the certificate itself, URL encoded PEM.
+	// +optional
+	Cert bool `json:"cert,omitempty"`
+	// Chain forwards the certificate's chain, URL encoded PEM.
+	// +optional
+	Chain bool `json:"chain,omitempty"`
+	// DNS forwards the certificate's DNS subject alt names.
+	// +optional
+	DNS bool `json:"dns,omitempty"`
+	// URI forwards the certificate's URI subject alt name.
+	// +optional
+	URI bool `json:"uri,omitempty"`
+}
+
+// HTTPSRedirectPolicy customises the responses which redirect
+// insecure requests to HTTPS.
+type HTTPSRedirectPolicy struct {
+	// ResponseHeaders are added to the redirect responses.
+	// +optional
+	ResponseHeaders []HeaderValue `json:"responseHeaders,omitempty"`
+	// If StripPath is true requests are redirected to the root
+	// of the virtual host, rather than to the requested path.
+	// +optional
+	StripPath bool `json:"stripPath,omitempty"`
+	// If StripQuery is true the query string of the request is
+	// not included in the redirect.
+	// +optional
+	StripQuery bool `json:"stripQuery,omitempty"`
+}
+
+// HeaderValue is an HTTP header name and value.
+type HeaderValue struct {
+	// Name is the name of the header.
+	Name string `json:"name"`
+	// Value is the value of the header.
+	Value string `json:"value"`
+}
+
+// HeadersPolicy defines how the headers of a request or response
+// are modified as they are proxied.
+type HeadersPolicy struct {
+	// Set replaces the value of each header, adding it if it is not
+	// present. Values may contain Envoy's dynamic values, such as
+	// %DOWNSTREAM_REMOTE_ADDRESS%; any other '%' is literal.
+	// +optional
+	Set []HeaderValue `json:"set,omitempty"`
+	// Remove removes each named header.
+	// +optional
+	Remove []string `json:"remove,omitempty"`
 }
 
 // Route contains the set of routes for a virtual host.
@@ -131,6 +492,11 @@ type Route struct {
 	// Enables websocket support for the route.
 	// +optional
 	EnableWebsockets bool `json:"enableWebsockets,omitempty"`
+	// If present, websocket requests offering any subprotocol,
+	// in their Sec-WebSocket-Protocol header, which is not listed
+	// are rejected. Requires enableWebsockets.
+	// +optional
+	WebsocketSubprotocols []string `json:"websocketSubprotocols,omitempty"`
 	// Allow this path to respond to insecure requests over HTTP which are normally
 	// not permitted when a `virtualhost.tls` block is present.
 	// +optional
@@ -147,6 +513,297 @@ type Route struct {
 	// The load balancing policy for this route.
 	// +optional
 	LoadBalancerPolicy *LoadBalancerPolicy `json:"loadBalancerPolicy,omitempty"`
+	// The blue/green policy for this route.
+	// +optional
+	BlueGreenPolicy *BlueGreenPolicy `json:"blueGreenPolicy,omitempty"`
+	// The classification policy for this route.
+	// +optional
+	ClassificationPolicy *ClassificationPolicy `json:"classificationPolicy,omitempty"`
+	// The session pinning policy for this route.
+	// +optional
+	SessionPinningPolicy *SessionPinningPolicy `json:"sessionPinningPolicy,omitempty"`
+	// The window of time during which this route is served.
+	// +optional
+	ActiveWindow *ActiveWindow `json:"activeWindow,omitempty"`
+	// The experiment policy for this route.
+	// +optional
+	ExperimentPolicy *ExperimentPolicy `json:"experimentPolicy,omitempty"`
+	// The trailing slash policy for this route. Overrides the
+	// trailing slash policy of the virtual host.
+	// +optional
+	TrailingSlashPolicy *TrailingSlashPolicy `json:"trailingSlashPolicy,omitempty"`
+	// The service selector for this route.
+	// +optional
+	ServiceSelector *ServiceSelector `json:"serviceSelector,omitempty"`
+	// The cluster header policy for this route.
+	// +optional
+	ClusterHeaderPolicy *ClusterHeaderPolicy `json:"clusterHeaderPolicy,omitempty"`
+	// Metadata attached to the Envoy route for the filters which
+	// read route metadata.
+	// +optional
+	Metadata []RouteMetadata `json:"metadata,omitempty"`
+	// The downstream protocol policy for this route. It takes
+	// precedence over the policy of the virtual host.
+	// +optional
+	DownstreamProtocolPolicy *DownstreamProtocolPolicy `json:"downstreamProtocolPolicy,omitempty"`
+	// The policy for rate limiting requests to this route with
+	// the rate limit service Contour is configured with.
+	// +optional
+	RateLimitPolicy *RateLimitPolicy `json:"rateLimitPolicy,omitempty"`
+	// The authorization policy for this route.
+	// +optional
+	AuthPolicy *AuthorizationPolicy `json:"authPolicy,omitempty"`
+	// The JWT verification policy for this route.
+	// +optional
+	JWTVerificationPolicy *JWTVerificationPolicy `json:"jwtVerificationPolicy,omitempty"`
+	// The policy for managing the headers of requests to this route.
+	// +optional
+	RequestHeadersPolicy *HeadersPolicy `json:"requestHeadersPolicy,omitempty"`
+	// The policy for managing the headers of responses from this route.
+	// +optional
+	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+	// The direct response policy for this route. If present, the
+	// route answers requests itself and must not have services.
+	// +optional
+	DirectResponsePolicy *HTTPDirectResponsePolicy `json:"directResponsePolicy,omitempty"`
+	// The redirect policy for this route. If present, the route
+	// redirects requests and must not have services.
+	// +optional
+	RequestRedirectPolicy *HTTPRequestRedirectPolicy `json:"requestRedirectPolicy,omitempty"`
+	// The policy for rewriting the path of the request before it is
+	// proxied to the route's services.
+	// +optional
+	PathRewritePolicy *PathRewritePolicy `json:"pathRewritePolicy,omitempty"`
+	// The policy for mirroring requests to a Service, whose responses
+	// are discarded. Cannot be combined with a Service marked mirror.
+	// +optional
+	MirrorPolicy *MirrorPolicy `json:"mirrorPolicy,omitempty"`
+}
+
+// MirrorPolicy describes the Service requests to a route are mirrored
+// to, and what percentage of them.
+type MirrorPolicy struct {
+	// Name is the name of the Kubernetes Service to mirror requests to.
+	Name string `json:"name"`
+	// Port is the port of the Service to mirror requests to.
+	Port int `json:"port"`
+	// Percent is the percentage of requests which are mirrored.
+	// If not supplied all requests are mirrored.
+	// +optional
+	Percent uint32 `json:"percent,omitempty"`
+}
+
+// PathRewritePolicy describes how the path of a request is rewritten
+// after it has been routed. The rewrite does not affect which route
+// the request matches.
+type PathRewritePolicy struct {
+	// ReplacePrefix lists the replacements for the path prefix
+	// matched by the route's conditions, including those of the
+	// includes the route is reached through.
+	// +optional
+	ReplacePrefix []ReplacePrefix `json:"replacePrefix,omitempty"`
+}
+
+// ReplacePrefix describes the replacement of a matched path prefix.
+type ReplacePrefix struct {
+	// Prefix is the matched path prefix this replacement applies to.
+	// If the route is included through several HTTPProxies, each
+	// with its own prefix, this selects the one to replace. If not
+	// specified, the replacement applies to any prefix not named by
+	// another replacement.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// Replacement is the path prefix the matched prefix is replaced
+	// with.
+	Replacement string `json:"replacement"`
+}
+
+// HTTPRequestRedirectPolicy describes how a route redirects requests
+// in place of proxying them to a service. Parts of the redirect
+// location which are not supplied are those of the request.
+type HTTPRequestRedirectPolicy struct {
+	// Scheme is the scheme of the redirect location, either http
+	// or https.
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+	// Hostname is the host name of the redirect location.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// Port is the port of the redirect location.
+	// +optional
+	Port uint32 `json:"port,omitempty"`
+	// Path replaces the whole path of the request. The query
+	// string is preserved. Cannot be combined with Prefix.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Prefix replaces the prefix of the request's path matched by
+	// the route's conditions. Cannot be combined with Path.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// StatusCode is the HTTP status code of the redirect, one of
+	// 301, 302, 303, 307, or 308. Defaults to 302.
+	// +optional
+	StatusCode uint32 `json:"statusCode,omitempty"`
+}
+
+// HTTPDirectResponsePolicy describes the response a route returns
+// in place of proxying the request to a service.
+type HTTPDirectResponsePolicy struct {
+	// StatusCode is the HTTP status code of the response, in the
+	// range 200-599.
+	StatusCode uint32 `json:"statusCode"`
+	// Body is the body of the response, at most 4096 bytes.
+	// +optional
+	Body string `json:"body,omitempty"`
+}
+
+// JWTVerificationPolicy selects the JWT provider whose tokens a route
+// requires.
+type JWTVerificationPolicy struct {
+	// Require is the name of the provider of the virtual host whose
+	// JWT must be presented.
+	// +optional
+	Require string `json:"require,omitempty"`
+	// Disabled, if true, does not require a JWT, even if the
+	// virtual host has a default provider.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// AuthorizationPolicy modifies how requests to a route of a virtual
+// host with an authorization service are authorized.
+type AuthorizationPolicy struct {
+	// Disabled, if true, routes requests without authorizing them.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// RateLimitPolicy describes the descriptors sent to the rate limit
+// service for each request to a route.
+type RateLimitPolicy struct {
+	// Descriptors are the descriptors sent to the rate limit
+	// service. Each descriptor is checked, and the request is
+	// limited if any of them is over its limit.
+	Descriptors []RateLimitDescriptor `json:"descriptors"`
+}
+
+// RateLimitDescriptor is a list of entries which together form a
+// descriptor. A descriptor is not sent if any of its entries
+// cannot be populated from the request.
+type RateLimitDescriptor struct {
+	Entries []RateLimitDescriptorEntry `json:"entries"`
+}
+
+// RateLimitDescriptorEntry is an entry of a descriptor. Exactly one
+// of RequestHeader, RemoteAddress or GenericKey must be supplied.
+type RateLimitDescriptorEntry struct {
+	// RequestHeader adds the value of a request header.
+	// +optional
+	RequestHeader *RequestHeaderDescriptor `json:"requestHeader,omitempty"`
+	// RemoteAddress adds the address of the client, with the
+	// key remote_address.
+	// +optional
+	RemoteAddress *RemoteAddressDescriptor `json:"remoteAddress,omitempty"`
+	// GenericKey adds a fixed value, with the key generic_key.
+	// +optional
+	GenericKey *GenericKeyDescriptor `json:"genericKey,omitempty"`
+}
+
+// RequestHeaderDescriptor adds the value of a request header to a
+// descriptor.
+type RequestHeaderDescriptor struct {
+	// HeaderName is the name of the request header.
+	HeaderName string `json:"headerName"`
+	// DescriptorKey is the key of the entry in the descriptor.
+	DescriptorKey string `json:"descriptorKey"`
+}
+
+// RemoteAddressDescriptor adds the address of the client to a
+// descriptor. It has no fields.
+type RemoteAddressDescriptor struct{}
+
+// GenericKeyDescriptor adds a fixed value to a descriptor.
+type GenericKeyDescriptor struct {
+	// Value is the value of the entry.
+	Value string `json:"value"`
+}
+
+// RouteMetadata is metadata attached to a route for a filter.
+type RouteMetadata struct {
+	// Filter is the name of the filter the metadata is for, for
+	// example envoy.ext_authz. Filters look up the metadata of the
+	// matched route by their name.
+	Filter string `json:"filter"`
+	// Entries are the keys and values of the metadata.
+	Entries []MetadataEntry `json:"entries"`
+}
+
+// MetadataEntry is a metadata key and its typed value. Exactly one
+// of StringValue, NumberValue or BoolValue must be supplied.
+type MetadataEntry struct {
+	// Key is the metadata key.
+	Key string `json:"key"`
+	// StringValue is a string value.
+	// +optional
+	StringValue *string `json:"stringValue,omitempty"`
+	// NumberValue is a numeric value.
+	// +optional
+	NumberValue *int64 `json:"numberValue,omitempty"`
+	// BoolValue is a boolean value.
+	// +optional
+	BoolValue *bool `json:"boolValue,omitempty"`
+}
+
+// ClusterHeaderPolicy routes each request to the Envoy cluster named
+// by a request header, provided it is the cluster of one of the
+// route's services. Requests naming any other cluster are routed to
+// the route's services as usual.
+type ClusterHeaderPolicy struct {
+	// Header is the name of the request header which names the cluster.
+	Header string `json:"header"`
+}
+
+// ServiceSelector routes each request to one of the route's services
+// according to the value of a request header. Requests whose header
+// value is not listed are routed to the route's services as usual.
+type ServiceSelector struct {
+	// Header is the name of the request header whose value
+	// selects the service.
+	Header string `json:"header"`
+	// Values maps header values to the route's services.
+	Values []ServiceSelectorValue `json:"values"`
+}
+
+// ServiceSelectorValue routes requests with a header value to a service.
+type ServiceSelectorValue struct {
+	// Value is the header value.
+	Value string `json:"value"`
+	// Service is the name of the service, from the route's services,
+	// which receives requests with the header value.
+	Service string `json:"service"`
+}
+
+// TrailingSlashPolicy defines how requests for the other form of
+// a route's prefix, with or without a trailing slash, are handled.
+type TrailingSlashPolicy struct {
+	// Action is either redirect, to redirect the request to the
+	// route's prefix, or rewrite, to forward the request with its
+	// path rewritten to the route's prefix.
+	Action string `json:"action"`
+}
+
+// ActiveWindow defines the window of time during which a route is
+// served. Outside the window requests matching the route are
+// answered with a 404.
+type ActiveWindow struct {
+	// Start is the time from which the route is served.
+	// If not supplied the route is served until End.
+	// +optional
+	Start *metav1.Time `json:"start,omitempty"`
+	// End is the time from which the route is no longer served.
+	// If not supplied the route is served from Start onwards.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
 }
 
 // TCPProxy contains the set of services to proxy TCP connections.
@@ -154,6 +811,9 @@ type TCPProxy struct {
 	// The load balancing policy for the backend services.
 	// +optional
 	LoadBalancerPolicy *LoadBalancerPolicy `json:"loadBalancerPolicy,omitempty"`
+	// The health check policy for the backend services.
+	// +optional
+	HealthCheckPolicy *TCPHealthCheckPolicy `json:"healthCheckPolicy,omitempty"`
 	// Services are the services to proxy traffic
 	Services []Service `json:"services,omitempty"`
 
@@ -177,15 +837,123 @@ type Service struct {
 	// Names defined here will be used to look up corresponding endpoints which contain the ips to route.
 	Name string `json:"name"`
 	// Port (defined as Integer) to proxy traffic to since a service can have multiple defined.
-	Port int `json:"port"`
+	// Either Port or PortName must be specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+	// PortName is the name of the port to proxy traffic to. Unlike
+	// Port, it continues to identify the same port if the Service's
+	// ports are renumbered. If Port is also specified, it must be the
+	// number of the named port.
+	// +optional
+	PortName string `json:"portName,omitempty"`
 	// Weight defines percentage of traffic to balance traffic
 	// +optional
 	Weight uint32 `json:"weight,omitempty"`
 	// UpstreamValidation defines how to verify the backend service's certificate
 	// +optional
 	UpstreamValidation *UpstreamValidation `json:"validation,omitempty"`
+	// ClientCertificate is the name of a kubernetes.io/tls Secret whose
+	// certificate and key Envoy presents when connecting to the service,
+	// which must use the tls or h2 upstream protocol. A Secret in another
+	// namespace is named namespace/name, and must be delegated to this
+	// namespace with a TLSCertificateDelegation.
+	// +optional
+	ClientCertificate string `json:"clientCertificate,omitempty"`
 	// If Mirror is true the Service will receive a read only mirror of the traffic for this route.
 	Mirror bool `json:"mirror,omitempty"`
+	// IdleTimeout is the time after which a connection to the service
+	// with no active requests is closed. Durations are expressed in the
+	// same format as TimeoutPolicy; 'infinity' disables the timeout.
+	// If not supplied Envoy's default of one hour applies.
+	// +optional
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+	// MaxConnectionDuration is the maximum lifetime of a connection to
+	// the service. Once reached the connection is drained and closed,
+	// and a new connection is opened for subsequent requests.
+	// If not supplied connections are not closed because of their age.
+	// +optional
+	MaxConnectionDuration string `json:"maxConnectionDuration,omitempty"`
+	// ConnectTimeout is the time allowed for a connection to the
+	// service to be established. Durations are expressed in the same
+	// format as TimeoutPolicy, but must be positive.
+	// If not supplied the timeout is 250ms.
+	// +optional
+	ConnectTimeout string `json:"connectTimeout,omitempty"`
+	// TCPKeepalive enables TCP keepalive probes on the connections
+	// to the service, so that connections silently dropped by a
+	// firewall or load balancer are detected and closed.
+	// +optional
+	TCPKeepalive *TCPKeepalive `json:"tcpKeepalive,omitempty"`
+	// The circuit breaking limits of the connections and requests to
+	// the service. Limits supplied take precedence over those set by
+	// the service's annotations.
+	// +optional
+	CircuitBreakerPolicy *CircuitBreakerPolicy `json:"circuitBreakerPolicy,omitempty"`
+	// Classification is the value of the route's classification header
+	// on responses served by this service. Defaults to the service's name.
+	// +optional
+	Classification string `json:"classification,omitempty"`
+	// DNSLookup is how the addresses of an ExternalName service are
+	// resolved, either strict or logical. With strict DNS requests are
+	// balanced across every address returned; with logical DNS new
+	// connections use the first address returned, which suits large
+	// hosted services whose DNS rotates through many addresses.
+	// Defaults to strict. Ignored unless the service is an ExternalName.
+	// +optional
+	DNSLookup string `json:"dnsLookup,omitempty"`
+	// HealthyPanicThreshold is the percentage of healthy hosts below
+	// which requests are balanced across all hosts, healthy or not.
+	// If not supplied, or 0, panic mode is disabled.
+	// +optional
+	HealthyPanicThreshold uint32 `json:"healthyPanicThreshold,omitempty"`
+	// The policy for managing the headers of requests forwarded to
+	// this service. It is applied before the route's policy, which
+	// takes precedence if both set the same header.
+	// +optional
+	RequestHeadersPolicy *HeadersPolicy `json:"requestHeadersPolicy,omitempty"`
+	// The policy for managing the headers of responses served by
+	// this service. It is applied before the route's policy, which
+	// takes precedence if both set the same header.
+	// +optional
+	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+}
+
+// CircuitBreakerPolicy defines the limits a single Envoy applies to
+// a service. Limits not supplied are taken from the service's
+// annotations or, failing those, Contour's configured defaults.
+type CircuitBreakerPolicy struct {
+	// The maximum number of connections to the service.
+	// +optional
+	MaxConnections uint32 `json:"maxConnections,omitempty"`
+	// The maximum number of requests waiting for a connection to the service.
+	// +optional
+	MaxPendingRequests uint32 `json:"maxPendingRequests,omitempty"`
+	// The maximum number of parallel requests to the service.
+	// +optional
+	MaxRequests uint32 `json:"maxRequests,omitempty"`
+	// The maximum number of parallel retries to the service.
+	// +optional
+	MaxRetries uint32 `json:"maxRetries,omitempty"`
+}
+
+// TCPKeepalive defines the TCP keepalive probes sent on connections
+// to a service. Fields not supplied take the defaults of the operating
+// system Envoy runs on.
+type TCPKeepalive struct {
+	// Probes is the number of unanswered probes after which
+	// the connection is considered dead.
+	// +optional
+	Probes uint32 `json:"probes,omitempty"`
+	// Time is how long a connection is idle before the first
+	// probe is sent, expressed as a duration of whole seconds.
+	// +optional
+	Time string `json:"time,omitempty"`
+	// Interval is the time between probes, expressed as a
+	// duration of whole seconds.
+	// +optional
+	Interval string `json:"interval,omitempty"`
 }
 
 // HTTPHealthCheckPolicy defines health checks on the upstream service.
@@ -208,6 +976,40 @@ type HTTPHealthCheckPolicy struct {
 	// The number of healthy health checks required before a host is marked healthy
 	// +optional
 	HealthyThresholdCount uint32 `json:"healthyThresholdCount"`
+	// ExpectedStatuses lists the response statuses, such as "301", and
+	// inclusive ranges of statuses, such as "200-299", which mark a host
+	// healthy. If not supplied only 200 marks a host healthy.
+	// +optional
+	ExpectedStatuses []string `json:"expectedStatuses,omitempty"`
+	// RequestHeaders are added to each health check request.
+	// The Host header is set with Host instead.
+	// +optional
+	RequestHeaders []HeaderValue `json:"requestHeaders,omitempty"`
+}
+
+// TCPHealthCheckPolicy defines TCP health checks on the upstream service.
+type TCPHealthCheckPolicy struct {
+	// The interval (seconds) between health checks
+	// +optional
+	IntervalSeconds int64 `json:"intervalSeconds"`
+	// The time to wait (seconds) for a health check response
+	// +optional
+	TimeoutSeconds int64 `json:"timeoutSeconds"`
+	// The number of unhealthy health checks required before a host is marked unhealthy
+	// +optional
+	UnhealthyThresholdCount uint32 `json:"unhealthyThresholdCount"`
+	// The number of healthy health checks required before a host is marked healthy
+	// +optional
+	HealthyThresholdCount uint32 `json:"healthyThresholdCount"`
+	// Send is the hex encoded payload, such as "000000FF", written to
+	// the host once connected. If left empty only the connection is checked.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^([0-9a-fA-F]{2})*$`
+	Send string `json:"send,omitempty"`
+	// Receive lists hex encoded payloads which must each be found,
+	// in order, in the host's response for it to be marked healthy.
+	// +optional
+	Receive []string `json:"receive,omitempty"`
 }
 
 // TimeoutPolicy defines the attributes associated with timeout.
@@ -220,8 +1022,10 @@ type TimeoutPolicy struct {
 	// If not supplied the timeout duration is undefined.
 	Response string `json:"response"`
 
-	// Timeout after which if there are no active requests, the connection between Envoy and the
-	// backend will be closed.
+	// Timeout after which a request stream with no activity in either direction is reset.
+	// It is independent of the response timeout, and overrides the stream idle timeout of
+	// Envoy's connection manager for the route, so "infinity" suits long-lived streams
+	// such as server-sent events.
 	Idle string `json:"idle"`
 }
 
@@ -234,19 +1038,181 @@ type RetryPolicy struct {
 	// PerTryTimeout specifies the timeout per retry attempt.
 	// Ignored if NumRetries is not supplied.
 	PerTryTimeout string `json:"perTryTimeout,omitempty"`
+	// RetryOn specifies the conditions on which a request is retried.
+	// Supported conditions are 5xx, gateway-error, reset, connect-failure,
+	// retriable-4xx, refused-stream, retriable-status-codes, and the gRPC
+	// status conditions cancelled, deadline-exceeded, internal,
+	// resource-exhausted and unavailable.
+	// If not supplied, requests are retried on 5xx.
+	// +optional
+	RetryOn []string `json:"retryOn,omitempty"`
+	// RetriableStatusCodes are the HTTP status codes on which a request
+	// is retried. They imply the retriable-status-codes condition.
+	// +optional
+	RetriableStatusCodes []uint32 `json:"retriableStatusCodes,omitempty"`
+	// BackOff configures the exponential backoff between retries.
+	// If not supplied, Envoy's default of 25ms, up to 250ms, is used.
+	// +optional
+	BackOff *RetryBackOff `json:"backOff,omitempty"`
+}
+
+// RetryBackOff defines the exponential backoff between retries.
+// Each retry waits a random time up to a limit, which starts at
+// BaseInterval and doubles with each retry until it reaches MaxInterval.
+type RetryBackOff struct {
+	// BaseInterval is the initial limit of the wait before a retry,
+	// expressed in the format specified by https://godoc.org/time#ParseDuration.
+	BaseInterval string `json:"baseInterval"`
+	// MaxInterval is the greatest limit of the wait before a retry,
+	// expressed in the format specified by https://godoc.org/time#ParseDuration.
+	// Defaults to ten times BaseInterval.
+	// +optional
+	MaxInterval string `json:"maxInterval,omitempty"`
 }
 
 // LoadBalancerPolicy defines the load balancing policy.
 type LoadBalancerPolicy struct {
 	Strategy string `json:"strategy,omitempty"`
+	// Cookie customises the session affinity cookie which the Cookie
+	// strategy hashes to choose an endpoint. It may only be supplied
+	// with the Cookie strategy.
+	// +optional
+	Cookie *SessionAffinityCookie `json:"cookie,omitempty"`
+	// RequestHashPolicies are the attributes of a request the
+	// RequestHash or Maglev strategy hashes to choose an endpoint,
+	// in order. They must be supplied with the RequestHash strategy,
+	// may be supplied with the Maglev strategy, and may not be
+	// supplied with any other.
+	// +optional
+	RequestHashPolicies []RequestHashPolicy `json:"requestHashPolicies,omitempty"`
+}
+
+// RequestHashPolicy is an attribute of a request hashed to choose an
+// endpoint. Exactly one of HeaderName or SourceIP must be supplied.
+type RequestHashPolicy struct {
+	// HeaderName is the name of a request header whose value is hashed.
+	// +optional
+	HeaderName string `json:"headerName,omitempty"`
+	// SourceIP hashes the IP address of the client.
+	// +optional
+	SourceIP bool `json:"sourceIP,omitempty"`
+	// Terminal skips the remaining policies if this one produces a
+	// hash, for example if the request has the header.
+	// +optional
+	Terminal bool `json:"terminal,omitempty"`
+}
+
+// SessionAffinityCookie describes the cookie Envoy sets on responses to
+// requests without one, and hashes to send the client's later requests
+// to the same endpoint.
+type SessionAffinityCookie struct {
+	// Name is the name of the cookie. Defaults to X-Contour-Session-Affinity.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// TTL is the lifetime of the cookie, expressed in the format specified
+	// by https://godoc.org/time#ParseDuration. If not supplied the cookie
+	// lasts until the client's session ends.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
+	// Path is the path of the cookie. Defaults to /.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// ClassificationPolicy records which of a route's services served a
+// request in a response header, so clients can tell canary responses
+// from stable ones.
+type ClassificationPolicy struct {
+	// Header is the name of the response header set to the
+	// classification of the service which served the request.
+	Header string `json:"header"`
+}
+
+// BlueGreenPolicy switches all of the traffic for a route between
+// two of the route's services.
+type BlueGreenPolicy struct {
+	// ActiveService is the name of the service, from the route's
+	// services, which receives the route's traffic.
+	ActiveService string `json:"activeService"`
+	// PreviewService is the name of the service, from the route's
+	// services, which receives requests matching the preview header.
+	PreviewService string `json:"previewService"`
+	// If Promote is set to true, the roles of the active and preview
+	// services are swapped.
+	// +optional
+	Promote bool `json:"promote,omitempty"`
+	// PreviewHeader is a header condition which routes matching
+	// requests to the preview service.
+	// +optional
+	PreviewHeader *HeaderCondition `json:"previewHeader,omitempty"`
+}
+
+// SessionPinningPolicy pins a session to the service which served its
+// first request, so the session is not moved between services when the
+// route's weights change.
+type SessionPinningPolicy struct {
+	// CookieName is the name of the cookie which records the service
+	// a session is pinned to. Defaults to X-Contour-Session-Pin.
+	// +optional
+	CookieName string `json:"cookieName,omitempty"`
+	// TTL is how long a session remains pinned to its service, expressed
+	// in the format specified by https://godoc.org/time#ParseDuration.
+	TTL string `json:"ttl"`
+}
+
+// ExperimentPolicy assigns each session to one of the variants of an
+// experiment, independently of the weights of the route's services.
+// The assignment is recorded in a cookie, so a session remains in its
+// variant, and is forwarded to the services in a request header.
+type ExperimentPolicy struct {
+	// CookieName is the name of the cookie which records the variant
+	// a session is assigned to. Defaults to X-Contour-Experiment.
+	// +optional
+	CookieName string `json:"cookieName,omitempty"`
+	// Header is the name of the request header which carries the
+	// variant to the services. Defaults to X-Contour-Experiment.
+	// +optional
+	Header string `json:"header,omitempty"`
+	// TTL is how long a session remains assigned to its variant.
+	// If not supplied the assignment lasts until the client's session ends.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
+	// Variants are the variants of the experiment. The percentages
+	// of the variants must add up to 100.
+	Variants []ExperimentVariant `json:"variants"`
+}
+
+// ExperimentVariant is a variant of an experiment.
+type ExperimentVariant struct {
+	// Name of the variant. Must consist of lower case alphanumeric
+	// characters or '-'.
+	Name string `json:"name"`
+	// Percent is the percentage of sessions assigned to the variant.
+	Percent uint32 `json:"percent"`
 }
 
 // UpstreamValidation defines how to verify the backend service's certificate
 type UpstreamValidation struct {
-	// Name of the Kubernetes secret be used to validate the certificate presented by the backend
-	CACertificate string `json:"caSecret"`
+	// Name of the Kubernetes secret be used to validate the certificate presented by the backend.
+	// If not set, the CA bundle of the service's upstream trust domain is used.
+	// +optional
+	CACertificate string `json:"caSecret,omitempty"`
 	// Key which is expected to be present in the 'subjectAltName' of the presented certificate
 	SubjectName string `json:"subjectName"`
+	// SubjectNames are further names the certificate may present in its
+	// 'subjectAltName' in place of SubjectName.
+	// +optional
+	SubjectNames []string `json:"subjectNames,omitempty"`
+	// SPKIPins are the base64 encoded SHA-256 hashes of the Subject
+	// Public Key Information of the certificates the backend may present.
+	// If set, the certificate's public key must match one of them.
+	// +optional
+	SPKIPins []string `json:"spkiPins,omitempty"`
+	// CertificateHashes are the hex encoded SHA-256 hashes of the
+	// certificates the backend may present, with or without colons
+	// between bytes. If set, the certificate must match one of them.
+	// +optional
+	CertificateHashes []string `json:"certificateHashes,omitempty"`
 }
 
 // Status reports the current state of the HTTPProxy.
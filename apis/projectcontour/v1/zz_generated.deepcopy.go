@@ -24,6 +24,124 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveWindow) DeepCopyInto(out *ActiveWindow) {
+	*out = *in
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveWindow.
+func (in *ActiveWindow) DeepCopy() *ActiveWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationPolicy) DeepCopyInto(out *AuthorizationPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationPolicy.
+func (in *AuthorizationPolicy) DeepCopy() *AuthorizationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationServer) DeepCopyInto(out *AuthorizationServer) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationServer.
+func (in *AuthorizationServer) DeepCopy() *AuthorizationServer {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlueGreenPolicy) DeepCopyInto(out *BlueGreenPolicy) {
+	*out = *in
+	if in.PreviewHeader != nil {
+		in, out := &in.PreviewHeader, &out.PreviewHeader
+		*out = new(HeaderCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlueGreenPolicy.
+func (in *BlueGreenPolicy) DeepCopy() *BlueGreenPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BlueGreenPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CORSPolicy) DeepCopyInto(out *CORSPolicy) {
+	*out = *in
+	if in.AllowOrigin != nil {
+		in, out := &in.AllowOrigin, &out.AllowOrigin
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowOriginRegex != nil {
+		in, out := &in.AllowOriginRegex, &out.AllowOriginRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowMethods != nil {
+		in, out := &in.AllowMethods, &out.AllowMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowHeaders != nil {
+		in, out := &in.AllowHeaders, &out.AllowHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposeHeaders != nil {
+		in, out := &in.ExposeHeaders, &out.ExposeHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CORSPolicy.
+func (in *CORSPolicy) DeepCopy() *CORSPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CORSPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateDelegation) DeepCopyInto(out *CertificateDelegation) {
 	*out = *in
@@ -45,12 +163,81 @@ func (in *CertificateDelegation) DeepCopy() *CertificateDelegation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CircuitBreakerPolicy) DeepCopyInto(out *CircuitBreakerPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CircuitBreakerPolicy.
+func (in *CircuitBreakerPolicy) DeepCopy() *CircuitBreakerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CircuitBreakerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClassificationPolicy) DeepCopyInto(out *ClassificationPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClassificationPolicy.
+func (in *ClassificationPolicy) DeepCopy() *ClassificationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClassificationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientCertificateDetails) DeepCopyInto(out *ClientCertificateDetails) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientCertificateDetails.
+func (in *ClientCertificateDetails) DeepCopy() *ClientCertificateDetails {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientCertificateDetails)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterHeaderPolicy) DeepCopyInto(out *ClusterHeaderPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterHeaderPolicy.
+func (in *ClusterHeaderPolicy) DeepCopy() *ClusterHeaderPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterHeaderPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
 	if in.Header != nil {
 		in, out := &in.Header, &out.Header
 		*out = new(HeaderCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QueryParameter != nil {
+		in, out := &in.QueryParameter, &out.QueryParameter
+		*out = new(QueryParameterCondition)
 		**out = **in
 	}
 	return
@@ -66,9 +253,125 @@ func (in *Condition) DeepCopy() *Condition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownstreamProtocolPolicy) DeepCopyInto(out *DownstreamProtocolPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownstreamProtocolPolicy.
+func (in *DownstreamProtocolPolicy) DeepCopy() *DownstreamProtocolPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DownstreamProtocolPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownstreamValidation) DeepCopyInto(out *DownstreamValidation) {
+	*out = *in
+	if in.ForwardClientCertificate != nil {
+		in, out := &in.ForwardClientCertificate, &out.ForwardClientCertificate
+		*out = new(ClientCertificateDetails)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownstreamValidation.
+func (in *DownstreamValidation) DeepCopy() *DownstreamValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(DownstreamValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExperimentPolicy) DeepCopyInto(out *ExperimentPolicy) {
+	*out = *in
+	if in.Variants != nil {
+		in, out := &in.Variants, &out.Variants
+		*out = make([]ExperimentVariant, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentPolicy.
+func (in *ExperimentPolicy) DeepCopy() *ExperimentPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ExperimentPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExperimentVariant) DeepCopyInto(out *ExperimentVariant) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentVariant.
+func (in *ExperimentVariant) DeepCopy() *ExperimentVariant {
+	if in == nil {
+		return nil
+	}
+	out := new(ExperimentVariant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenericKeyDescriptor) DeepCopyInto(out *GenericKeyDescriptor) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericKeyDescriptor.
+func (in *GenericKeyDescriptor) DeepCopy() *GenericKeyDescriptor {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericKeyDescriptor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPDirectResponsePolicy) DeepCopyInto(out *HTTPDirectResponsePolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPDirectResponsePolicy.
+func (in *HTTPDirectResponsePolicy) DeepCopy() *HTTPDirectResponsePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPDirectResponsePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTPHealthCheckPolicy) DeepCopyInto(out *HTTPHealthCheckPolicy) {
 	*out = *in
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequestHeaders != nil {
+		in, out := &in.RequestHeaders, &out.RequestHeaders
+		*out = make([]HeaderValue, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -173,67 +476,482 @@ func (in *HTTPProxySpec) DeepCopyInto(out *HTTPProxySpec) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPProxySpec.
-func (in *HTTPProxySpec) DeepCopy() *HTTPProxySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPProxySpec.
+func (in *HTTPProxySpec) DeepCopy() *HTTPProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRequestRedirectPolicy) DeepCopyInto(out *HTTPRequestRedirectPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRequestRedirectPolicy.
+func (in *HTTPRequestRedirectPolicy) DeepCopy() *HTTPRequestRedirectPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRequestRedirectPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPSRedirectPolicy) DeepCopyInto(out *HTTPSRedirectPolicy) {
+	*out = *in
+	if in.ResponseHeaders != nil {
+		in, out := &in.ResponseHeaders, &out.ResponseHeaders
+		*out = make([]HeaderValue, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPSRedirectPolicy.
+func (in *HTTPSRedirectPolicy) DeepCopy() *HTTPSRedirectPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPSRedirectPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderCondition) DeepCopyInto(out *HeaderCondition) {
+	*out = *in
+	if in.OneOf != nil {
+		in, out := &in.OneOf, &out.OneOf
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderCondition.
+func (in *HeaderCondition) DeepCopy() *HeaderCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderValue) DeepCopyInto(out *HeaderValue) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderValue.
+func (in *HeaderValue) DeepCopy() *HeaderValue {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeadersPolicy) DeepCopyInto(out *HeadersPolicy) {
+	*out = *in
+	if in.Set != nil {
+		in, out := &in.Set, &out.Set
+		*out = make([]HeaderValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.Remove != nil {
+		in, out := &in.Remove, &out.Remove
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeadersPolicy.
+func (in *HeadersPolicy) DeepCopy() *HeadersPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HeadersPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Include) DeepCopyInto(out *Include) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Include.
+func (in *Include) DeepCopy() *Include {
+	if in == nil {
+		return nil
+	}
+	out := new(Include)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTProvider) DeepCopyInto(out *JWTProvider) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.RemoteJWKS.DeepCopyInto(&out.RemoteJWKS)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTProvider.
+func (in *JWTProvider) DeepCopy() *JWTProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTVerificationPolicy) DeepCopyInto(out *JWTVerificationPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTVerificationPolicy.
+func (in *JWTVerificationPolicy) DeepCopy() *JWTVerificationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTVerificationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerPolicy) DeepCopyInto(out *LoadBalancerPolicy) {
+	*out = *in
+	if in.Cookie != nil {
+		in, out := &in.Cookie, &out.Cookie
+		*out = new(SessionAffinityCookie)
+		**out = **in
+	}
+	if in.RequestHashPolicies != nil {
+		in, out := &in.RequestHashPolicies, &out.RequestHashPolicies
+		*out = make([]RequestHashPolicy, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerPolicy.
+func (in *LoadBalancerPolicy) DeepCopy() *LoadBalancerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenancePolicy) DeepCopyInto(out *MaintenancePolicy) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(Service)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenancePolicy.
+func (in *MaintenancePolicy) DeepCopy() *MaintenancePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenancePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataEntry) DeepCopyInto(out *MetadataEntry) {
+	*out = *in
+	if in.StringValue != nil {
+		in, out := &in.StringValue, &out.StringValue
+		*out = new(string)
+		**out = **in
+	}
+	if in.NumberValue != nil {
+		in, out := &in.NumberValue, &out.NumberValue
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BoolValue != nil {
+		in, out := &in.BoolValue, &out.BoolValue
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataEntry.
+func (in *MetadataEntry) DeepCopy() *MetadataEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MirrorPolicy) DeepCopyInto(out *MirrorPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MirrorPolicy.
+func (in *MirrorPolicy) DeepCopy() *MirrorPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MirrorPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathRewritePolicy) DeepCopyInto(out *PathRewritePolicy) {
+	*out = *in
+	if in.ReplacePrefix != nil {
+		in, out := &in.ReplacePrefix, &out.ReplacePrefix
+		*out = make([]ReplacePrefix, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathRewritePolicy.
+func (in *PathRewritePolicy) DeepCopy() *PathRewritePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PathRewritePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryParameterCondition) DeepCopyInto(out *QueryParameterCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryParameterCondition.
+func (in *QueryParameterCondition) DeepCopy() *QueryParameterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryParameterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitDescriptor) DeepCopyInto(out *RateLimitDescriptor) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]RateLimitDescriptorEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitDescriptor.
+func (in *RateLimitDescriptor) DeepCopy() *RateLimitDescriptor {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitDescriptor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitDescriptorEntry) DeepCopyInto(out *RateLimitDescriptorEntry) {
+	*out = *in
+	if in.RequestHeader != nil {
+		in, out := &in.RequestHeader, &out.RequestHeader
+		*out = new(RequestHeaderDescriptor)
+		**out = **in
+	}
+	if in.RemoteAddress != nil {
+		in, out := &in.RemoteAddress, &out.RemoteAddress
+		*out = new(RemoteAddressDescriptor)
+		**out = **in
+	}
+	if in.GenericKey != nil {
+		in, out := &in.GenericKey, &out.GenericKey
+		*out = new(GenericKeyDescriptor)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitDescriptorEntry.
+func (in *RateLimitDescriptorEntry) DeepCopy() *RateLimitDescriptorEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitDescriptorEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitPolicy) DeepCopyInto(out *RateLimitPolicy) {
+	*out = *in
+	if in.Descriptors != nil {
+		in, out := &in.Descriptors, &out.Descriptors
+		*out = make([]RateLimitDescriptor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicy.
+func (in *RateLimitPolicy) DeepCopy() *RateLimitPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteAddressDescriptor) DeepCopyInto(out *RemoteAddressDescriptor) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteAddressDescriptor.
+func (in *RemoteAddressDescriptor) DeepCopy() *RemoteAddressDescriptor {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteAddressDescriptor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteJWKS) DeepCopyInto(out *RemoteJWKS) {
+	*out = *in
+	if in.UpstreamValidation != nil {
+		in, out := &in.UpstreamValidation, &out.UpstreamValidation
+		*out = new(UpstreamValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteJWKS.
+func (in *RemoteJWKS) DeepCopy() *RemoteJWKS {
 	if in == nil {
 		return nil
 	}
-	out := new(HTTPProxySpec)
+	out := new(RemoteJWKS)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HeaderCondition) DeepCopyInto(out *HeaderCondition) {
+func (in *ReplacePrefix) DeepCopyInto(out *ReplacePrefix) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderCondition.
-func (in *HeaderCondition) DeepCopy() *HeaderCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplacePrefix.
+func (in *ReplacePrefix) DeepCopy() *ReplacePrefix {
 	if in == nil {
 		return nil
 	}
-	out := new(HeaderCondition)
+	out := new(ReplacePrefix)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Include) DeepCopyInto(out *Include) {
+func (in *RequestHashPolicy) DeepCopyInto(out *RequestHashPolicy) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestHashPolicy.
+func (in *RequestHashPolicy) DeepCopy() *RequestHashPolicy {
+	if in == nil {
+		return nil
 	}
+	out := new(RequestHashPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequestHeaderDescriptor) DeepCopyInto(out *RequestHeaderDescriptor) {
+	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Include.
-func (in *Include) DeepCopy() *Include {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestHeaderDescriptor.
+func (in *RequestHeaderDescriptor) DeepCopy() *RequestHeaderDescriptor {
 	if in == nil {
 		return nil
 	}
-	out := new(Include)
+	out := new(RequestHeaderDescriptor)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LoadBalancerPolicy) DeepCopyInto(out *LoadBalancerPolicy) {
+func (in *RetryBackOff) DeepCopyInto(out *RetryBackOff) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerPolicy.
-func (in *LoadBalancerPolicy) DeepCopy() *LoadBalancerPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryBackOff.
+func (in *RetryBackOff) DeepCopy() *RetryBackOff {
 	if in == nil {
 		return nil
 	}
-	out := new(LoadBalancerPolicy)
+	out := new(RetryBackOff)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -241,6 +959,21 @@ func (in *LoadBalancerPolicy) DeepCopy() *LoadBalancerPolicy {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
 	*out = *in
+	if in.RetryOn != nil {
+		in, out := &in.RetryOn, &out.RetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetriableStatusCodes != nil {
+		in, out := &in.RetriableStatusCodes, &out.RetriableStatusCodes
+		*out = make([]uint32, len(*in))
+		copy(*out, *in)
+	}
+	if in.BackOff != nil {
+		in, out := &in.BackOff, &out.BackOff
+		*out = new(RetryBackOff)
+		**out = **in
+	}
 	return
 }
 
@@ -271,6 +1004,11 @@ func (in *Route) DeepCopyInto(out *Route) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.WebsocketSubprotocols != nil {
+		in, out := &in.WebsocketSubprotocols, &out.WebsocketSubprotocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.TimeoutPolicy != nil {
 		in, out := &in.TimeoutPolicy, &out.TimeoutPolicy
 		*out = new(TimeoutPolicy)
@@ -279,16 +1017,113 @@ func (in *Route) DeepCopyInto(out *Route) {
 	if in.RetryPolicy != nil {
 		in, out := &in.RetryPolicy, &out.RetryPolicy
 		*out = new(RetryPolicy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.HealthCheckPolicy != nil {
 		in, out := &in.HealthCheckPolicy, &out.HealthCheckPolicy
 		*out = new(HTTPHealthCheckPolicy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.LoadBalancerPolicy != nil {
 		in, out := &in.LoadBalancerPolicy, &out.LoadBalancerPolicy
 		*out = new(LoadBalancerPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlueGreenPolicy != nil {
+		in, out := &in.BlueGreenPolicy, &out.BlueGreenPolicy
+		*out = new(BlueGreenPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClassificationPolicy != nil {
+		in, out := &in.ClassificationPolicy, &out.ClassificationPolicy
+		*out = new(ClassificationPolicy)
+		**out = **in
+	}
+	if in.SessionPinningPolicy != nil {
+		in, out := &in.SessionPinningPolicy, &out.SessionPinningPolicy
+		*out = new(SessionPinningPolicy)
+		**out = **in
+	}
+	if in.ActiveWindow != nil {
+		in, out := &in.ActiveWindow, &out.ActiveWindow
+		*out = new(ActiveWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExperimentPolicy != nil {
+		in, out := &in.ExperimentPolicy, &out.ExperimentPolicy
+		*out = new(ExperimentPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrailingSlashPolicy != nil {
+		in, out := &in.TrailingSlashPolicy, &out.TrailingSlashPolicy
+		*out = new(TrailingSlashPolicy)
+		**out = **in
+	}
+	if in.ServiceSelector != nil {
+		in, out := &in.ServiceSelector, &out.ServiceSelector
+		*out = new(ServiceSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterHeaderPolicy != nil {
+		in, out := &in.ClusterHeaderPolicy, &out.ClusterHeaderPolicy
+		*out = new(ClusterHeaderPolicy)
+		**out = **in
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make([]RouteMetadata, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DownstreamProtocolPolicy != nil {
+		in, out := &in.DownstreamProtocolPolicy, &out.DownstreamProtocolPolicy
+		*out = new(DownstreamProtocolPolicy)
+		**out = **in
+	}
+	if in.RateLimitPolicy != nil {
+		in, out := &in.RateLimitPolicy, &out.RateLimitPolicy
+		*out = new(RateLimitPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthPolicy != nil {
+		in, out := &in.AuthPolicy, &out.AuthPolicy
+		*out = new(AuthorizationPolicy)
+		**out = **in
+	}
+	if in.JWTVerificationPolicy != nil {
+		in, out := &in.JWTVerificationPolicy, &out.JWTVerificationPolicy
+		*out = new(JWTVerificationPolicy)
+		**out = **in
+	}
+	if in.RequestHeadersPolicy != nil {
+		in, out := &in.RequestHeadersPolicy, &out.RequestHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResponseHeadersPolicy != nil {
+		in, out := &in.ResponseHeadersPolicy, &out.ResponseHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DirectResponsePolicy != nil {
+		in, out := &in.DirectResponsePolicy, &out.DirectResponsePolicy
+		*out = new(HTTPDirectResponsePolicy)
+		**out = **in
+	}
+	if in.RequestRedirectPolicy != nil {
+		in, out := &in.RequestRedirectPolicy, &out.RequestRedirectPolicy
+		*out = new(HTTPRequestRedirectPolicy)
+		**out = **in
+	}
+	if in.PathRewritePolicy != nil {
+		in, out := &in.PathRewritePolicy, &out.PathRewritePolicy
+		*out = new(PathRewritePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MirrorPolicy != nil {
+		in, out := &in.MirrorPolicy, &out.MirrorPolicy
+		*out = new(MirrorPolicy)
 		**out = **in
 	}
 	return
@@ -304,14 +1139,57 @@ func (in *Route) DeepCopy() *Route {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteMetadata) DeepCopyInto(out *RouteMetadata) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]MetadataEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteMetadata.
+func (in *RouteMetadata) DeepCopy() *RouteMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Service) DeepCopyInto(out *Service) {
 	*out = *in
 	if in.UpstreamValidation != nil {
 		in, out := &in.UpstreamValidation, &out.UpstreamValidation
 		*out = new(UpstreamValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCPKeepalive != nil {
+		in, out := &in.TCPKeepalive, &out.TCPKeepalive
+		*out = new(TCPKeepalive)
+		**out = **in
+	}
+	if in.CircuitBreakerPolicy != nil {
+		in, out := &in.CircuitBreakerPolicy, &out.CircuitBreakerPolicy
+		*out = new(CircuitBreakerPolicy)
 		**out = **in
 	}
+	if in.RequestHeadersPolicy != nil {
+		in, out := &in.RequestHeadersPolicy, &out.RequestHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResponseHeadersPolicy != nil {
+		in, out := &in.ResponseHeadersPolicy, &out.ResponseHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -325,6 +1203,75 @@ func (in *Service) DeepCopy() *Service {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceSelector) DeepCopyInto(out *ServiceSelector) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]ServiceSelectorValue, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceSelector.
+func (in *ServiceSelector) DeepCopy() *ServiceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceSelectorValue) DeepCopyInto(out *ServiceSelectorValue) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceSelectorValue.
+func (in *ServiceSelectorValue) DeepCopy() *ServiceSelectorValue {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceSelectorValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionAffinityCookie) DeepCopyInto(out *SessionAffinityCookie) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionAffinityCookie.
+func (in *SessionAffinityCookie) DeepCopy() *SessionAffinityCookie {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionAffinityCookie)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionPinningPolicy) DeepCopyInto(out *SessionPinningPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionPinningPolicy.
+func (in *SessionPinningPolicy) DeepCopy() *SessionPinningPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionPinningPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Status) DeepCopyInto(out *Status) {
 	*out = *in
@@ -341,13 +1288,55 @@ func (in *Status) DeepCopy() *Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPHealthCheckPolicy) DeepCopyInto(out *TCPHealthCheckPolicy) {
+	*out = *in
+	if in.Receive != nil {
+		in, out := &in.Receive, &out.Receive
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthCheckPolicy.
+func (in *TCPHealthCheckPolicy) DeepCopy() *TCPHealthCheckPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPHealthCheckPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPKeepalive) DeepCopyInto(out *TCPKeepalive) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPKeepalive.
+func (in *TCPKeepalive) DeepCopy() *TCPKeepalive {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPKeepalive)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TCPProxy) DeepCopyInto(out *TCPProxy) {
 	*out = *in
 	if in.LoadBalancerPolicy != nil {
 		in, out := &in.LoadBalancerPolicy, &out.LoadBalancerPolicy
 		*out = new(LoadBalancerPolicy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthCheckPolicy != nil {
+		in, out := &in.HealthCheckPolicy, &out.HealthCheckPolicy
+		*out = new(TCPHealthCheckPolicy)
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Services != nil {
 		in, out := &in.Services, &out.Services
@@ -393,6 +1382,21 @@ func (in *TCPProxyInclude) DeepCopy() *TCPProxyInclude {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLS) DeepCopyInto(out *TLS) {
 	*out = *in
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientValidation != nil {
+		in, out := &in.ClientValidation, &out.ClientValidation
+		*out = new(DownstreamValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RedirectPolicy != nil {
+		in, out := &in.RedirectPolicy, &out.RedirectPolicy
+		*out = new(HTTPSRedirectPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -505,9 +1509,40 @@ func (in *TimeoutPolicy) DeepCopy() *TimeoutPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrailingSlashPolicy) DeepCopyInto(out *TrailingSlashPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrailingSlashPolicy.
+func (in *TrailingSlashPolicy) DeepCopy() *TrailingSlashPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TrailingSlashPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamValidation) DeepCopyInto(out *UpstreamValidation) {
 	*out = *in
+	if in.SubjectNames != nil {
+		in, out := &in.SubjectNames, &out.SubjectNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SPKIPins != nil {
+		in, out := &in.SPKIPins, &out.SPKIPins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CertificateHashes != nil {
+		in, out := &in.CertificateHashes, &out.CertificateHashes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -527,8 +1562,55 @@ func (in *VirtualHost) DeepCopyInto(out *VirtualHost) {
 	if in.TLS != nil {
 		in, out := &in.TLS, &out.TLS
 		*out = new(TLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequestHeadersAllowList != nil {
+		in, out := &in.RequestHeadersAllowList, &out.RequestHeadersAllowList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DownstreamProtocolPolicy != nil {
+		in, out := &in.DownstreamProtocolPolicy, &out.DownstreamProtocolPolicy
+		*out = new(DownstreamProtocolPolicy)
+		**out = **in
+	}
+	if in.MaintenancePolicy != nil {
+		in, out := &in.MaintenancePolicy, &out.MaintenancePolicy
+		*out = new(MaintenancePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redirect != nil {
+		in, out := &in.Redirect, &out.Redirect
+		*out = new(VirtualHostRedirect)
+		**out = **in
+	}
+	if in.TrailingSlashPolicy != nil {
+		in, out := &in.TrailingSlashPolicy, &out.TrailingSlashPolicy
+		*out = new(TrailingSlashPolicy)
+		**out = **in
+	}
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(AuthorizationServer)
+		**out = **in
+	}
+	if in.JWTProviders != nil {
+		in, out := &in.JWTProviders, &out.JWTProviders
+		*out = make([]JWTProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RateLimitFailOpen != nil {
+		in, out := &in.RateLimitFailOpen, &out.RateLimitFailOpen
+		*out = new(bool)
 		**out = **in
 	}
+	if in.CORSPolicy != nil {
+		in, out := &in.CORSPolicy, &out.CORSPolicy
+		*out = new(CORSPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -541,3 +1623,19 @@ func (in *VirtualHost) DeepCopy() *VirtualHost {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualHostRedirect) DeepCopyInto(out *VirtualHostRedirect) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualHostRedirect.
+func (in *VirtualHostRedirect) DeepCopy() *VirtualHostRedirect {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualHostRedirect)
+	in.DeepCopyInto(out)
+	return out
+}
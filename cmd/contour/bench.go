@@ -0,0 +1,274 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	cgrpc "github.com/projectcontour/contour/internal/grpc"
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// benchContext holds the configuration for the bench subcommand.
+type benchContext struct {
+	// Proxies is the number of HTTPProxies, each with its own
+	// Service and Endpoints, to generate.
+	Proxies int
+
+	// Routes is the number of routes of each HTTPProxy.
+	Routes int
+
+	// Endpoints is the number of addresses of each Endpoints.
+	Endpoints int
+
+	// Iterations is the number of times each stage is measured.
+	Iterations int
+}
+
+// registerBench registers the bench subcommand and flags with the
+// Application provided.
+func registerBench(app *kingpin.Application) (*kingpin.CmdClause, *benchContext) {
+	var ctx benchContext
+	bench := app.Command("bench", "Measure DAG and xDS generation for synthetic HTTPProxies, Services and Endpoints")
+	bench.Flag("proxies", "Number of HTTPProxies, each with its own Service and Endpoints").Default("1000").IntVar(&ctx.Proxies)
+	bench.Flag("routes", "Number of routes of each HTTPProxy").Default("1").IntVar(&ctx.Routes)
+	bench.Flag("endpoints", "Number of addresses of each Endpoints").Default("3").IntVar(&ctx.Endpoints)
+	bench.Flag("iterations", "Number of times each stage is measured").Default("5").IntVar(&ctx.Iterations)
+	return bench, &ctx
+}
+
+// benchStage is the measurement of one stage of the benchmark.
+type benchStage struct {
+	name     string
+	elapsed  time.Duration // mean over the iterations
+	allocs   uint64        // mean bytes allocated
+	resource string        // any notes on the stage's output
+}
+
+// doBench generates the objects described by ctx, measures each
+// stage of turning them into xDS resources, and writes a report
+// to w.
+func doBench(ctx *benchContext, w io.Writer) error {
+	switch {
+	case ctx.Proxies < 1:
+		return fmt.Errorf("proxies %d must be at least 1", ctx.Proxies)
+	case ctx.Routes < 1:
+		return fmt.Errorf("routes %d must be at least 1", ctx.Routes)
+	case ctx.Endpoints < 1:
+		return fmt.Errorf("endpoints %d must be at least 1", ctx.Endpoints)
+	case ctx.Iterations < 1:
+		return fmt.Errorf("iterations %d must be at least 1", ctx.Iterations)
+	}
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	objs, endpoints := benchObjects(ctx)
+	var stages []benchStage
+
+	builder := &dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: log,
+		},
+	}
+	stages = append(stages, measure("insert objects", 1, func() string {
+		for _, obj := range objs {
+			builder.Source.Insert(obj)
+		}
+		return fmt.Sprintf("%d objects", len(objs))
+	}))
+
+	var d *dag.DAG
+	stages = append(stages, measure("build DAG", ctx.Iterations, func() string {
+		d = builder.Build()
+		return fmt.Sprintf("%d statuses", len(d.Statuses()))
+	}))
+
+	ch := &contour.CacheHandler{
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		FieldLogger: log,
+	}
+	stages = append(stages, measure("generate xDS", ctx.Iterations, func() string {
+		ch.OnChange(d)
+		return fmt.Sprintf("%d listeners, %d route configurations, %d clusters",
+			len(ch.ListenerCache.Contents()), len(ch.RouteCache.Contents()), len(ch.ClusterCache.Contents()))
+	}))
+
+	et := &contour.EndpointsTranslator{
+		FieldLogger: log,
+	}
+	stages = append(stages, measure("translate endpoints", 1, func() string {
+		for _, ep := range endpoints {
+			et.OnAdd(ep)
+		}
+		return fmt.Sprintf("%d cluster load assignments", len(et.Contents()))
+	}))
+
+	resources := []cgrpc.Resource{&ch.ListenerCache, &ch.RouteCache, &ch.ClusterCache, et}
+	var marshalErr error
+	stages = append(stages, measure("marshal xDS", ctx.Iterations, func() string {
+		size := 0
+		for _, r := range resources {
+			for _, m := range r.Contents() {
+				buf, err := proto.Marshal(m)
+				if err != nil {
+					marshalErr = err
+				}
+				size += len(buf)
+			}
+		}
+		return fmt.Sprintf("%d bytes", size)
+	}))
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+
+	fmt.Fprintf(w, "%d HTTPProxies with %d routes, %d Services, %d Endpoints with %d addresses\n\n",
+		ctx.Proxies, ctx.Routes, ctx.Proxies, ctx.Proxies, ctx.Endpoints)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tTIME\tALLOCATED\tRESULT")
+	for _, s := range stages {
+		fmt.Fprintf(tw, "%s\t%v\t%s\t%s\n", s.name, s.elapsed.Round(time.Microsecond), bytesize(s.allocs), s.resource)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nheap in use after GC: %s\n", bytesize(ms.HeapInuse))
+	return nil
+}
+
+// measure runs f iterations times and returns the mean time taken
+// and bytes allocated, and the result of the last run.
+func measure(name string, iterations int, f func() string) benchStage {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var result string
+	for i := 0; i < iterations; i++ {
+		result = f()
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return benchStage{
+		name:     name,
+		elapsed:  elapsed / time.Duration(iterations),
+		allocs:   (after.TotalAlloc - before.TotalAlloc) / uint64(iterations),
+		resource: result,
+	}
+}
+
+// benchObjects returns the HTTPProxies and Services, and the
+// Endpoints, described by ctx.
+func benchObjects(ctx *benchContext) ([]interface{}, []*v1.Endpoints) {
+	const namespace = "bench"
+	var objs []interface{}
+	var endpoints []*v1.Endpoints
+	for i := 0; i < ctx.Proxies; i++ {
+		name := fmt.Sprintf("bench-%d", i)
+		objs = append(objs, &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Name:       "http",
+					Protocol:   v1.ProtocolTCP,
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+
+		var routes []projcontour.Route
+		for j := 0; j < ctx.Routes; j++ {
+			routes = append(routes, projcontour.Route{
+				Conditions: []projcontour.Condition{{
+					Prefix: fmt.Sprintf("/route-%d", j),
+				}},
+				Services: []projcontour.Service{{
+					Name: name,
+					Port: 80,
+				}},
+			})
+		}
+		objs = append(objs, &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: name + ".example.com",
+				},
+				Routes: routes,
+			},
+		})
+
+		var addresses []v1.EndpointAddress
+		for j := 0; j < ctx.Endpoints; j++ {
+			addresses = append(addresses, v1.EndpointAddress{
+				IP: fmt.Sprintf("10.%d.%d.%d", i/256%256, i%256, j%256),
+			})
+		}
+		endpoints = append(endpoints, &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: addresses,
+				Ports: []v1.EndpointPort{{
+					Name:     "http",
+					Port:     8080,
+					Protocol: v1.ProtocolTCP,
+				}},
+			}},
+		})
+	}
+	return objs, endpoints
+}
+
+// bytesize formats n bytes in binary units.
+func bytesize(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -18,6 +18,7 @@ import (
 	"os"
 
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/envoy"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
@@ -36,6 +37,13 @@ func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *bootstrap
 	bootstrap.Flag("envoy-cafile", "gRPC CA Filename for Envoy to load").Envar("ENVOY_CAFILE").StringVar(&ctx.config.GrpcCABundle)
 	bootstrap.Flag("envoy-cert-file", "gRPC Client cert filename for Envoy to load").Envar("ENVOY_CERT_FILE").StringVar(&ctx.config.GrpcClientCert)
 	bootstrap.Flag("envoy-key-file", "gRPC Client key filename for Envoy to load").Envar("ENVOY_KEY_FILE").StringVar(&ctx.config.GrpcClientKey)
+	bootstrap.Flag("http-address", "Override the address of the HTTP listener this Envoy receives from Contour").StringVar(&ctx.http.Address)
+	bootstrap.Flag("http-port", "Override the port of the HTTP listener this Envoy receives from Contour").IntVar(&ctx.http.Port)
+	bootstrap.Flag("https-address", "Override the address of the HTTPS listener this Envoy receives from Contour").StringVar(&ctx.https.Address)
+	bootstrap.Flag("https-port", "Override the port of the HTTPS listener this Envoy receives from Contour").IntVar(&ctx.https.Port)
+	bootstrap.Flag("contour-stats-only", "Restrict the stats Envoy generates to those used by Contour").BoolVar(&ctx.config.ContourStatsOnly)
+	bootstrap.Flag("stats-inclusion-prefix", "Restrict the stats Envoy generates to those with this prefix (may be repeated)").StringsVar(&ctx.config.StatsInclusionPrefixes)
+	bootstrap.Flag("stats-exclusion-prefix", "Prevent Envoy generating stats with this prefix (may be repeated)").StringsVar(&ctx.config.StatsExclusionPrefixes)
 	bootstrap.Flag("namespace", "The namespace the Envoy container will run in").Envar("CONTOUR_NAMESPACE").Default("projectcontour").StringVar(&ctx.config.Namespace)
 	return bootstrap, &ctx
 }
@@ -43,10 +51,15 @@ func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *bootstrap
 type bootstrapContext struct {
 	config envoy.BootstrapConfig
 	path   string
+
+	// listener address overrides for the HTTP and HTTPS listeners.
+	http, https envoy.ListenerAddress
 }
 
 // doBootstrap writes an Envoy bootstrap configuration file to the supplied path.
 func doBootstrap(ctx *bootstrapContext) {
+	ctx.config.ListenerAddresses = listenerAddresses(ctx.http, ctx.https)
+
 	var out io.Writer
 
 	switch ctx.path {
@@ -67,3 +80,16 @@ func doBootstrap(ctx *bootstrapContext) {
 
 	check(m.Marshal(out, envoy.Bootstrap(&ctx.config)))
 }
+
+// listenerAddresses returns the overrides requested for the HTTP
+// and HTTPS listeners, keyed by listener name.
+func listenerAddresses(http, https envoy.ListenerAddress) map[string]envoy.ListenerAddress {
+	addrs := make(map[string]envoy.ListenerAddress)
+	if http != (envoy.ListenerAddress{}) {
+		addrs[contour.ENVOY_HTTP_LISTENER] = http
+	}
+	if https != (envoy.ListenerAddress{}) {
+		addrs[contour.ENVOY_HTTPS_LISTENER] = https
+	}
+	return addrs
+}
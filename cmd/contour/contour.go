@@ -65,6 +65,16 @@ func main() {
 
 	serve, serveCtx := registerServe(app)
 
+	serveSnapshot, serveSnapshotCtx := registerServeSnapshot(app)
+
+	replay, replayCtx := registerReplay(app)
+
+	bench, benchCtx := registerBench(app)
+
+	logs, logsCtx := registerLogs(app)
+
+	report, reportCtx := registerReport(app)
+
 	args := os.Args[1:]
 	switch kingpin.MustParse(app.Parse(args)) {
 	case bootstrap.FullCommand():
@@ -93,6 +103,16 @@ func main() {
 		check(err)
 		log.Infof("args: %v", args)
 		doServe(log, serveCtx)
+	case serveSnapshot.FullCommand():
+		check(doServeSnapshot(log, serveSnapshotCtx))
+	case replay.FullCommand():
+		check(doReplay(log, replayCtx, os.Stdout))
+	case bench.FullCommand():
+		check(doBench(benchCtx, os.Stdout))
+	case logs.FullCommand():
+		check(doLogs(logsCtx, os.Stdout))
+	case report.FullCommand():
+		check(doReport(reportCtx, os.Stdout))
 	default:
 		app.Usage(args)
 		os.Exit(2)
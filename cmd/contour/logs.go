@@ -0,0 +1,133 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/projectcontour/contour/internal/accesslog"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// logsContext holds the configuration for the logs subcommand.
+type logsContext struct {
+	Kubeconfig string
+	InCluster  bool
+
+	// Namespace, Selector and Container identify the
+	// Envoy containers whose access logs are tailed.
+	Namespace string
+	Selector  string
+	Container string
+
+	// Since is how far back in each log to start.
+	Since time.Duration
+
+	accesslog.Filter
+}
+
+// registerLogs registers the logs subcommand and flags with the
+// Application provided.
+func registerLogs(app *kingpin.Application) (*kingpin.CmdClause, *logsContext) {
+	var ctx logsContext
+	logs := app.Command("logs", "Tail the access logs of Envoy pods, filtered by virtual host and response status")
+	logs.Flag("kubeconfig", "path to kubeconfig (if not in running inside a cluster)").Default(filepath.Join(os.Getenv("HOME"), ".kube", "config")).StringVar(&ctx.Kubeconfig)
+	logs.Flag("incluster", "use in cluster configuration.").BoolVar(&ctx.InCluster)
+	logs.Flag("namespace", "Namespace of the Envoy pods").Default("projectcontour").StringVar(&ctx.Namespace)
+	logs.Flag("selector", "Label selector of the Envoy pods").Default("app=envoy").StringVar(&ctx.Selector)
+	logs.Flag("container", "Name of the Envoy container of each pod").Default("envoy").StringVar(&ctx.Container)
+	logs.Flag("since", "Start this far back in each log").Default("1m").DurationVar(&ctx.Since)
+	logs.Flag("fqdn", "Show only requests to this virtual host").StringVar(&ctx.FQDN)
+	logs.Flag("status", "Show only responses with this status, such as 404, or class of statuses, such as 5xx").StringVar(&ctx.Status)
+	return logs, &ctx
+}
+
+// doLogs follows the logs of the Envoy containers described by ctx,
+// writing each access log line selected by its filter to w, prefixed
+// with the name of the pod. It returns once every log has ended.
+func doLogs(ctx *logsContext, w io.Writer) error {
+	if err := ctx.Filter.Validate(); err != nil {
+		return err
+	}
+
+	client, _, _ := newClient(ctx.Kubeconfig, ctx.InCluster)
+	pods, err := client.CoreV1().Pods(ctx.Namespace).List(metav1.ListOptions{
+		LabelSelector: ctx.Selector,
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pods in namespace %q match selector %q", ctx.Namespace, ctx.Selector)
+	}
+
+	since := int64(ctx.Since / time.Second)
+	var (
+		mu       sync.Mutex // serialises writes to w, and firstErr
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for _, pod := range pods.Items {
+		req := client.CoreV1().Pods(ctx.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{
+			Container:    ctx.Container,
+			Follow:       true,
+			SinceSeconds: &since,
+		})
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := tailLog(req.Stream, &ctx.Filter, func(line string) {
+				mu.Lock()
+				defer mu.Unlock()
+				fmt.Fprintf(w, "%s: %s\n", name, line)
+			})
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("pod %s: %v", name, err)
+				}
+			}
+		}(pod.Name)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// tailLog opens a log and passes each access log line selected by
+// filter to emit until the log ends.
+func tailLog(open func() (io.ReadCloser, error), filter *accesslog.Filter, emit func(string)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if e, ok := accesslog.Parse(line); ok && filter.Match(e) {
+			emit(line)
+		}
+	}
+	return sc.Err()
+}
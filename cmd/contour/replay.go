@@ -0,0 +1,234 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/certgen"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/eventlog"
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/projectcontour/contour/internal/snapshot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// replayContext holds the configuration for the replay subcommand.
+// Only the object filtering settings of the embedded serveContext
+// are used.
+type replayContext struct {
+	*serveContext
+
+	// Path is the recording to replay.
+	Path string
+
+	// Snapshot, if set, is where the xDS snapshot of the
+	// replayed objects is written.
+	Snapshot string
+}
+
+// registerReplay registers the replay subcommand and flags with the
+// Application provided.
+func registerReplay(app *kingpin.Application) (*kingpin.CmdClause, *replayContext) {
+	ctx := &replayContext{
+		serveContext: newServeContext(),
+	}
+	replay := app.Command("replay", "Replay Kubernetes object events recorded by serve --record-events through the DAG builder")
+	replay.Arg("path", "Recording to replay").Required().ExistingFileVar(&ctx.Path)
+	replay.Flag("snapshot", "Write the xDS snapshot of the replayed objects to this file").StringVar(&ctx.Snapshot)
+	replay.Flag("root-namespaces", "Restrict contour to searching these namespaces for root ingress routes").StringVar(&ctx.rootNamespaces)
+	replay.Flag("ingress-class-name", "Contour IngressClass name, or a comma separated list of names").StringVar(&ctx.ingressClass)
+	replay.Flag("ingress-class-regex", "Match ingress classes against each --ingress-class-name as a regular expression").BoolVar(&ctx.ingressClassRegex)
+	return replay, ctx
+}
+
+// doReplay replays the recording at ctx.Path, builds the DAG of the
+// resulting objects and writes the status of each HTTPProxy and
+// IngressRoute to w.
+func doReplay(log logrus.FieldLogger, ctx *replayContext, w io.Writer) error {
+	if err := ctx.verifyIngressClass(); err != nil {
+		return err
+	}
+
+	secrets, err := newPlaceholderSecrets()
+	if err != nil {
+		return err
+	}
+	builder := &dag.Builder{
+		Source: dag.KubernetesCache{
+			RootNamespaces:    ctx.ingressRouteRootNamespaces(),
+			IngressClass:      ctx.ingressClass,
+			IngressClassRegex: ctx.ingressClassRegex,
+			FetchSecret:       secrets.fetch,
+			FieldLogger:       log.WithField("context", "KubernetesCache"),
+		},
+	}
+
+	f, err := os.Open(ctx.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := eventlog.Replay(f, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			secrets.record(obj)
+			builder.Source.Insert(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			secrets.record(newObj)
+			builder.Source.Remove(oldObj)
+			builder.Source.Insert(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			builder.Source.Remove(obj)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.WithField("events", n).Info("replayed recording")
+
+	d := builder.Build()
+
+	var statuses []string
+	for _, st := range d.Statuses() {
+		kind := "unknown"
+		switch st.Object.(type) {
+		case *projcontour.HTTPProxy:
+			kind = "HTTPProxy"
+		case *ingressroutev1.IngressRoute:
+			kind = "IngressRoute"
+		}
+		m := st.Object.GetObjectMeta()
+		line := fmt.Sprintf("%s %s/%s: %s", kind, m.GetNamespace(), m.GetName(), st.Status)
+		if st.Description != "" {
+			line += ": " + st.Description
+		}
+		statuses = append(statuses, line)
+	}
+	sort.Strings(statuses)
+	for _, line := range statuses {
+		fmt.Fprintln(w, line)
+	}
+
+	if ctx.Snapshot == "" {
+		return nil
+	}
+	ch := &contour.CacheHandler{
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		FieldLogger: log.WithField("context", "CacheHandler"),
+	}
+	ch.OnChange(d)
+	out, err := os.Create(ctx.Snapshot)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Write(out, &ch.ClusterCache, &ch.RouteCache, &ch.ListenerCache, &ch.SecretCache); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// placeholderSecrets stands in for the API server during a replay.
+// Recordings hold the metadata of Secrets only, so each recorded
+// Secret which is referenced is given placeholder contents of its
+// type: a self signed certificate and its key, and the certificate
+// again as a CA bundle, for a TLS Secret, or the certificate and a
+// revocation list it signs for any other. The real contents are not
+// known, so every reference to a recorded Secret is taken to be valid.
+type placeholderSecrets struct {
+	// types holds the type of each recorded Secret.
+	types map[string]corev1.SecretType
+
+	cert, key, crl []byte
+}
+
+func newPlaceholderSecrets() (*placeholderSecrets, error) {
+	expiry := time.Now().Add(24 * 365 * time.Hour)
+	cert, key, err := certgen.NewCA("contour replay placeholder", expiry)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	crl, err := ca.CreateCRL(rand.Reader, pair.PrivateKey, nil, time.Now(), expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &placeholderSecrets{
+		types: make(map[string]corev1.SecretType),
+		cert:  cert,
+		key:   key,
+		crl:   pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl}),
+	}, nil
+}
+
+// record records the type of obj, if it is a Secret.
+func (p *placeholderSecrets) record(obj interface{}) {
+	if sec, ok := obj.(*corev1.Secret); ok {
+		p.types[sec.Namespace+"/"+sec.Name] = sec.Type
+	}
+}
+
+// fetch returns the named Secret with placeholder contents.
+func (p *placeholderSecrets) fetch(namespace, name string) (*corev1.Secret, error) {
+	typ, ok := p.types[namespace+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s was not recorded", namespace, name)
+	}
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: typ,
+	}
+	if typ == corev1.SecretTypeTLS {
+		sec.Data = map[string][]byte{
+			corev1.TLSCertKey:       p.cert,
+			corev1.TLSPrivateKeyKey: p.key,
+			"ca.crt":                p.cert,
+		}
+	} else {
+		sec.Data = map[string][]byte{
+			"ca.crt":  p.cert,
+			"crl.pem": p.crl,
+		}
+	}
+	return sec, nil
+}
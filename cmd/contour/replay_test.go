@@ -0,0 +1,116 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/eventlog"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestReplayTLSVirtualHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Secrets are recorded as Contour holds them, without their contents.
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "default",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("certificate"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	k8s.StripSecret(sec)
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	var recording bytes.Buffer
+	rec := &eventlog.Recorder{
+		Writer:      &recording,
+		Next:        cache.ResourceEventHandlerFuncs{},
+		FieldLogger: log,
+	}
+	rec.OnAdd(sec)
+	rec.OnAdd(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Protocol: "TCP",
+				Port:     80,
+			}},
+		},
+	})
+	rec.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secure",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "secure.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec.Name,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "backend",
+					Port: 80,
+				}},
+			}},
+		},
+	})
+
+	path := filepath.Join(dir, "events.json")
+	if err := ioutil.WriteFile(path, recording.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &replayContext{
+		serveContext: newServeContext(),
+		Path:         path,
+		Snapshot:     filepath.Join(dir, "snapshot.tar.gz"),
+	}
+	var out bytes.Buffer
+	if err := doReplay(log, ctx, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the secret is given placeholder contents, so the vhost is valid.
+	want := "HTTPProxy default/secure: valid: valid HTTPProxy\n"
+	if got := out.String(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/projectcontour/contour/internal/debug"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// reportContext holds the configuration for the report subcommand.
+type reportContext struct {
+	// DebugAddr and DebugPort are the address of the debug
+	// http endpoint of the Contour reported on.
+	DebugAddr string
+	DebugPort int
+
+	// Format is how the report is written, table, json or csv.
+	Format string
+}
+
+// registerReport registers the report subcommand and flags with the
+// Application provided.
+func registerReport(app *kingpin.Application) (*kingpin.CmdClause, *reportContext) {
+	var ctx reportContext
+	report := app.Command("report", "Summarise the HTTPProxies and IngressRoutes of each namespace served by a running Contour")
+	report.Flag("debug-http-address", "address of Contour's debug http endpoint").Default("127.0.0.1").StringVar(&ctx.DebugAddr)
+	report.Flag("debug-http-port", "port of Contour's debug http endpoint").Default("6060").IntVar(&ctx.DebugPort)
+	report.Flag("format", "Output format, table, json or csv").Default("table").EnumVar(&ctx.Format, "table", "json", "csv")
+	return report, &ctx
+}
+
+// doReport fetches the per-namespace report of the Contour described
+// by ctx and writes it to w.
+func doReport(ctx *reportContext, w io.Writer) error {
+	client := http.Client{Timeout: 30 * time.Second}
+	url := "http://" + net.JoinHostPort(ctx.DebugAddr, strconv.Itoa(ctx.DebugPort)) + "/debug/report"
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	var reports []debug.NamespaceReport
+	if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
+		return fmt.Errorf("%s: %v", url, err)
+	}
+	return writeReport(w, ctx.Format, reports)
+}
+
+// reportColumns are the columns of the table and csv reports.
+var reportColumns = []string{
+	"namespace",
+	"valid",
+	"warning",
+	"invalid",
+	"orphaned",
+	"fqdns",
+	"plaintext",
+	"tls",
+	"mtls",
+	"passthrough",
+	"deprecated-annotations",
+}
+
+// writeReport writes reports to w in format.
+func writeReport(w io.Writer, format string, reports []debug.NamespaceReport) error {
+	row := func(r debug.NamespaceReport) []string {
+		var row []string
+		row = append(row, r.Namespace)
+		for _, v := range []int{r.Valid, r.Warning, r.Invalid, r.Orphaned, r.FQDNs, r.TLS.Plaintext, r.TLS.Terminated, r.TLS.Mutual, r.TLS.Passthrough, r.DeprecatedAnnotations} {
+			row = append(row, strconv.Itoa(v))
+		}
+		return row
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(reportColumns)
+		for _, r := range reports {
+			cw.Write(row(r))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(reportColumns, "\t"))
+		for _, r := range reports {
+			fmt.Fprintln(tw, strings.Join(row(r), "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("format %q must be one of table, json or csv", format)
+	}
+}
@@ -16,11 +16,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/signal"
 	"reflect"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -30,19 +32,29 @@ import (
 	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/debug"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/eventlog"
+	"github.com/projectcontour/contour/internal/featuregate"
 	cgrpc "github.com/projectcontour/contour/internal/grpc"
 	"github.com/projectcontour/contour/internal/httpsvc"
 	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/projectcontour/contour/internal/provisioner"
 	"github.com/projectcontour/contour/internal/workgroup"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	coreinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/leaderelection"
 )
 
+// apiWatchdogInterval is how often the Kubernetes API server is
+// probed to tell whether the configuration served is stale.
+const apiWatchdogInterval = 5 * time.Second
+
 // registerServe registers the serve subcommand and flags
 // with the Application provided.
 func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext) {
@@ -90,10 +102,14 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 
 	serve.Flag("debug-http-address", "address the debug http endpoint will bind to").StringVar(&ctx.debugAddr)
 	serve.Flag("debug-http-port", "port the debug http endpoint will bind to").IntVar(&ctx.debugPort)
+	serve.Flag("debug-profiling", "Serve pprof profiles and execution traces on the debug http endpoint").BoolVar(&ctx.debugProfiling)
+	serve.Flag("debug-token-file", "File holding the bearer token required to fetch profiles, and snapshots including secrets, from the debug http endpoint").StringVar(&ctx.debugTokenFile)
 
 	serve.Flag("http-address", "address the metrics http endpoint will bind to").StringVar(&ctx.metricsAddr)
 	serve.Flag("http-port", "port the metrics http endpoint will bind to").IntVar(&ctx.metricsPort)
 
+	serve.Flag("record-events", "Record Kubernetes object events to this file for later replay").StringVar(&ctx.recordEvents)
+
 	serve.Flag("contour-cafile", "CA bundle file name for serving gRPC with TLS").Envar("CONTOUR_CAFILE").StringVar(&ctx.caFile)
 	serve.Flag("contour-cert-file", "Contour certificate file name for serving gRPC over TLS").Envar("CONTOUR_CERT_FILE").StringVar(&ctx.contourCert)
 	serve.Flag("contour-key-file", "Contour key file name for serving gRPC over TLS").Envar("CONTOUR_KEY_FILE").StringVar(&ctx.contourKey)
@@ -102,7 +118,8 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 	serve.Flag("ingressroute-root-namespaces", "DEPRECATED (Use 'root-namespaces'): Restrict contour to searching these namespaces for root ingress routes").StringVar(&ctx.rootNamespaces)
 	serve.Flag("root-namespaces", "Restrict contour to searching these namespaces for root ingress routes").StringVar(&ctx.rootNamespaces)
 
-	serve.Flag("ingress-class-name", "Contour IngressClass name").StringVar(&ctx.ingressClass)
+	serve.Flag("ingress-class-name", "Contour IngressClass name, or a comma separated list of names").StringVar(&ctx.ingressClass)
+	serve.Flag("ingress-class-regex", "Match ingress classes against each --ingress-class-name as a regular expression").BoolVar(&ctx.ingressClassRegex)
 
 	serve.Flag("envoy-http-access-log", "Envoy HTTP access log").StringVar(&ctx.httpAccessLog)
 	serve.Flag("envoy-https-access-log", "Envoy HTTPS access log").StringVar(&ctx.httpsAccessLog)
@@ -115,6 +132,8 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 	serve.Flag("accesslog-format", "Format for Envoy access logs").StringVar(&ctx.AccessLogFormat)
 	serve.Flag("disable-leader-election", "Disable leader election mechanism").BoolVar(&ctx.DisableLeaderElection)
 
+	serve.Flag("feature-gates", "Comma separated list of feature=true|false pairs enabling experimental features").StringVar(&ctx.featureGates)
+
 	serve.Flag("use-extensions-v1beta1-ingress", "Subscribe to the deprecated extensions/v1beta1.Ingress type").BoolVar(&ctx.UseExtensionsV1beta1Ingress)
 	return serve, ctx
 }
@@ -122,6 +141,87 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 // doServe runs the contour serve subcommand.
 func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 
+	if err := ctx.verifyIngressClass(); err != nil {
+		return err
+	}
+
+	switch cgrpc.EnvoyVersionPolicy(ctx.EnvoyVersionPolicy) {
+	case cgrpc.WarnUnsupportedEnvoy, cgrpc.RefuseUnsupportedEnvoy:
+	default:
+		return fmt.Errorf("envoy-version-policy %q must be one of warn or refuse", ctx.EnvoyVersionPolicy)
+	}
+
+	switch cgrpc.ResourceVersioning(ctx.ResourceVersioning) {
+	case cgrpc.CounterVersions, cgrpc.ContentVersions:
+	default:
+		return fmt.Errorf("resource-versioning %q must be one of counter or content", ctx.ResourceVersioning)
+	}
+
+	switch ctx.ClientAddress {
+	case "", contour.ClientAddressConnection, contour.ClientAddressXForwardedFor:
+	default:
+		return fmt.Errorf("listener.client-address %q must be one of %s or %s", ctx.ClientAddress, contour.ClientAddressConnection, contour.ClientAddressXForwardedFor)
+	}
+
+	switch ctx.ConnectionBalancer {
+	case "", contour.ConnectionBalancerExact:
+	default:
+		return fmt.Errorf("listener.connection-balancer %q must be %s", ctx.ConnectionBalancer, contour.ConnectionBalancerExact)
+	}
+
+	minTLSVersion := dag.MinProtoVersion(ctx.TLSConfig.MinimumProtocolVersion)
+	maxTLSVersion, ok := dag.ParseTLSProtocolVersion(ctx.TLSConfig.MaximumProtocolVersion)
+	if !ok {
+		return fmt.Errorf("tls.maximum-protocol-version %q must be one of 1.1, 1.2 or 1.3", ctx.TLSConfig.MaximumProtocolVersion)
+	}
+	if ctx.TLSConfig.MaximumProtocolVersion != "" && maxTLSVersion < minTLSVersion {
+		return fmt.Errorf("tls.maximum-protocol-version %q must not be below tls.minimum-protocol-version %q", ctx.TLSConfig.MaximumProtocolVersion, ctx.TLSConfig.MinimumProtocolVersion)
+	}
+	if err := dag.ValidateCipherSuites(ctx.TLSConfig.CipherSuites); err != nil {
+		return fmt.Errorf("tls.cipher-suites: %v", err)
+	}
+
+	var debugToken string
+	if ctx.debugTokenFile != "" {
+		buf, err := ioutil.ReadFile(ctx.debugTokenFile)
+		if err != nil {
+			return err
+		}
+		debugToken = strings.TrimSpace(string(buf))
+		if debugToken == "" {
+			return fmt.Errorf("debug-token-file %q is empty", ctx.debugTokenFile)
+		}
+	}
+
+	if rls := ctx.RateLimitService; rls.Name != "" {
+		switch {
+		case rls.Namespace == "":
+			return fmt.Errorf("rate-limit-service.namespace must be set")
+		case rls.Port < 1 || rls.Port > 65535:
+			return fmt.Errorf("rate-limit-service.port %d must be in the range 1-65535", rls.Port)
+		}
+	}
+
+	if fs := ctx.FallbackService; fs.Name != "" && fs.Namespace == "" {
+		return fmt.Errorf("fallback-service.namespace must be set")
+	}
+
+	for _, ns := range ctx.StrictUpstreamTLS.Namespaces {
+		if ns == "" {
+			return fmt.Errorf("strict-upstream-tls.namespaces must not contain an empty namespace")
+		}
+	}
+
+	trustDomains, err := ctx.upstreamTrustDomains()
+	if err != nil {
+		return err
+	}
+
+	gates, err := ctx.featureGate()
+	if err != nil {
+		return err
+	}
+
 	// step 1. establish k8s client connection
 	client, contourClient, coordinationClient := newClient(ctx.Kubeconfig, ctx.InCluster)
 
@@ -131,10 +231,9 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	contourInformers := contourinformers.NewSharedInformerFactory(contourClient, 0)
 
 	// Create a set of SharedInformerFactories for each root-ingressroute namespace (if defined)
-	var namespacedInformers []coreinformers.SharedInformerFactory
+	namespacedInformers := make(map[string]coreinformers.SharedInformerFactory)
 	for _, namespace := range ctx.ingressRouteRootNamespaces() {
-		inf := coreinformers.NewSharedInformerFactoryWithOptions(client, 0, coreinformers.WithNamespace(namespace))
-		namespacedInformers = append(namespacedInformers, inf)
+		namespacedInformers[namespace] = coreinformers.NewSharedInformerFactoryWithOptions(client, 0, coreinformers.WithNamespace(namespace))
 	}
 
 	// step 3. build our mammoth Kubernetes event handler.
@@ -150,8 +249,24 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 				HTTPSAccessLog:         ctx.httpsAccessLog,
 				AccessLogType:          ctx.AccessLogFormat,
 				AccessLogFields:        ctx.AccessLogFields,
-				MinimumProtocolVersion: dag.MinProtoVersion(ctx.TLSConfig.MinimumProtocolVersion),
+				MinimumProtocolVersion: minTLSVersion,
+				MaximumProtocolVersion: maxTLSVersion,
+				CipherSuites:           ctx.TLSConfig.CipherSuites,
 				RequestTimeout:         ctx.RequestTimeout,
+				HTTP1Options: envoy.HTTP1Options{
+					DisableHTTP10:        ctx.DisableHTTP10,
+					DefaultHostForHTTP10: ctx.DefaultHostForHTTP10,
+					AllowAbsoluteURL:     ctx.AllowAbsoluteURL,
+				},
+				ConnectionBalancer:            ctx.ConnectionBalancer,
+				ClientAddress:                 ctx.ClientAddress,
+				ListenerFiltersTimeout:        ctx.FiltersTimeout,
+				PerConnectionBufferLimitBytes: ctx.PerConnectionBufferLimitBytes,
+			},
+			RouteVisitorConfig: contour.RouteVisitorConfig{
+				RouteStats:        gates.Enabled(featuregate.RouteStats),
+				MergeVirtualHosts: gates.Enabled(featuregate.MergeVirtualHosts),
+				ResponseTimeout:   ctx.ResponseTimeout,
 			},
 			ListenerCache: contour.NewListenerCache(ctx.statsAddr, ctx.statsPort),
 			FieldLogger:   log.WithField("context", "CacheHandler"),
@@ -163,49 +278,104 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		},
 		Builder: dag.Builder{
 			Source: dag.KubernetesCache{
-				RootNamespaces: ctx.ingressRouteRootNamespaces(),
-				IngressClass:   ctx.ingressClass,
-				FieldLogger:    log.WithField("context", "KubernetesCache"),
+				RootNamespaces:            ctx.ingressRouteRootNamespaces(),
+				IngressClass:              ctx.ingressClass,
+				IngressClassRegex:         ctx.ingressClassRegex,
+				SecretDeletionGracePeriod: ctx.SecretDeletionGracePeriod,
+				UpstreamTrustDomains:      trustDomains,
+				// the Secret informers hold metadata only, so the
+				// contents of referenced Secrets are fetched as needed.
+				FetchSecret: func(namespace, name string) (*corev1.Secret, error) {
+					sec, err := client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+					if err != nil {
+						return nil, err
+					}
+					k8s.StripObjectMeta(sec)
+					return sec, nil
+				},
+				FieldLogger: log.WithField("context", "KubernetesCache"),
 			},
 			DisablePermitInsecure: ctx.DisablePermitInsecure,
+			StrictAnnotations:     ctx.StrictAnnotations,
+			RateLimitService: dag.RateLimitServiceConfig{
+				Namespace:       ctx.RateLimitService.Namespace,
+				Name:            ctx.RateLimitService.Name,
+				Port:            ctx.RateLimitService.Port,
+				Domain:          ctx.RateLimitService.Domain,
+				Timeout:         ctx.RateLimitService.Timeout,
+				FailureModeDeny: ctx.RateLimitService.FailureModeDeny,
+			},
+			DefaultCircuitBreakers: dag.CircuitBreakers{
+				MaxConnections:     ctx.CircuitBreakers.MaxConnections,
+				MaxPendingRequests: ctx.CircuitBreakers.MaxPendingRequests,
+				MaxRequests:        ctx.CircuitBreakers.MaxRequests,
+				MaxRetries:         ctx.CircuitBreakers.MaxRetries,
+			},
+			StrictUpstreamTLSNamespaces: ctx.StrictUpstreamTLS.Namespaces,
 		},
 		FieldLogger: log.WithField("context", "contourEventHandler"),
 	}
 
-	// step 4. register our resource event handler with the k8s informers.
+	// step 4. register our resource event handler with the k8s informers,
+	// recording their events if requested.
+	var handler cache.ResourceEventHandler = eh
+	if ctx.recordEvents != "" {
+		// recordings reveal the cluster's configuration, so are
+		// readable only by their owner.
+		f, err := os.OpenFile(ctx.recordEvents, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		handler = &eventlog.Recorder{
+			Writer:      f,
+			Next:        eh,
+			FieldLogger: log.WithField("context", "eventrecorder"),
+		}
+		log.WithField("path", ctx.recordEvents).Info("recording Kubernetes object events")
+	}
 	var informers []cache.SharedIndexInformer
-	informers = registerEventHandler(informers, coreInformers.Core().V1().Services().Informer(), eh)
-	informers = registerEventHandler(informers, contourInformers.Contour().V1beta1().IngressRoutes().Informer(), eh)
-	informers = registerEventHandler(informers, contourInformers.Contour().V1beta1().TLSCertificateDelegations().Informer(), eh)
-	informers = registerEventHandler(informers, contourInformers.Projectcontour().V1().HTTPProxies().Informer(), eh)
-	informers = registerEventHandler(informers, contourInformers.Projectcontour().V1().TLSCertificateDelegations().Informer(), eh)
+	informers = registerEventHandler(informers, k8s.TransformedInformer(coreInformers, corev1.NamespaceAll, &corev1.Service{}), handler)
+	informers = registerEventHandler(informers, contourInformers.Contour().V1beta1().IngressRoutes().Informer(), handler)
+	informers = registerEventHandler(informers, contourInformers.Contour().V1beta1().TLSCertificateDelegations().Informer(), handler)
+	informers = registerEventHandler(informers, contourInformers.Projectcontour().V1().HTTPProxies().Informer(), handler)
+	informers = registerEventHandler(informers, contourInformers.Projectcontour().V1().TLSCertificateDelegations().Informer(), handler)
 
 	// After K8s 1.13 the API server will automatically translate extensions/v1beta1.Ingress objects
 	// to networking/v1beta1.Ingress objects so we should only listen for one type or the other.
 	// The default behavior is to listen for networking/v1beta1.Ingress objects and let the API server
 	// transparently upgrade the extensions version for us.
 	if ctx.UseExtensionsV1beta1Ingress {
-		informers = registerEventHandler(informers, coreInformers.Extensions().V1beta1().Ingresses().Informer(), eh)
+		informers = registerEventHandler(informers, coreInformers.Extensions().V1beta1().Ingresses().Informer(), handler)
 	} else {
-		informers = registerEventHandler(informers, coreInformers.Networking().V1beta1().Ingresses().Informer(), eh)
+		informers = registerEventHandler(informers, coreInformers.Networking().V1beta1().Ingresses().Informer(), handler)
 	}
 
 	// Add informers for each root-ingressroute namespaces
-	for _, inf := range namespacedInformers {
-		informers = registerEventHandler(informers, inf.Core().V1().Secrets().Informer(), eh)
+	for namespace, inf := range namespacedInformers {
+		informers = registerEventHandler(informers, k8s.TransformedInformer(inf, namespace, &corev1.Secret{}), handler)
 	}
 	// If root-ingressroutes are not defined, then add the informer for all namespaces
 	if len(namespacedInformers) == 0 {
-		informers = registerEventHandler(informers, coreInformers.Core().V1().Secrets().Informer(), eh)
+		informers = registerEventHandler(informers, k8s.TransformedInformer(coreInformers, corev1.NamespaceAll, &corev1.Secret{}), handler)
 	}
 
 	// step 5. endpoints updates are handled directly by the EndpointsTranslator
 	// due to their high update rate and their orthogonal nature.
+	readyEndpoints := &contour.ReadyEndpoints{}
+	eh.ReadyEndpoints = readyEndpoints
 	et := &contour.EndpointsTranslator{
-		FieldLogger: log.WithField("context", "endpointstranslator"),
+		FieldLogger:    log.WithField("context", "endpointstranslator"),
+		ReadyEndpoints: readyEndpoints,
+		FallbackService: contour.FallbackService{
+			Namespace: ctx.FallbackService.Namespace,
+			Name:      ctx.FallbackService.Name,
+			PortName:  ctx.FallbackService.PortName,
+		},
 	}
 
-	informers = registerEventHandler(informers, coreInformers.Core().V1().Endpoints().Informer(), et)
+	eh.CacheHandler.EndpointsTranslator = et
+	informers = registerEventHandler(informers, k8s.TransformedInformer(coreInformers, corev1.NamespaceAll, &corev1.Endpoints{}), et)
 
 	// step 6. setup workgroup runner and register informers.
 	var g workgroup.Group
@@ -223,26 +393,52 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 	registry.MustRegister(prometheus.NewGoCollector())
 
-	// step 9. create metrics service and register with workgroup.
+	// step 9. create metrics service, and the watchdog whose state
+	// it reports, and register them with workgroup.
+	if ctx.StaleServingThreshold < 0 {
+		return fmt.Errorf("stale-serving-threshold: %v must not be negative", ctx.StaleServingThreshold)
+	}
+	watchdog := &contour.APIWatchdog{
+		FieldLogger: log.WithField("context", "apiwatchdog"),
+		Probe: func() error {
+			_, err := client.ServerVersion()
+			return err
+		},
+		Interval:  apiWatchdogInterval,
+		Threshold: ctx.StaleServingThreshold,
+	}
+	g.Add(watchdog.Start)
+
 	metricsvc := metrics.Service{
 		Service: httpsvc.Service{
 			Addr:        ctx.metricsAddr,
 			Port:        ctx.metricsPort,
 			FieldLogger: log.WithField("context", "metricsvc"),
 		},
-		Client:   client,
-		Registry: registry,
+		Client:       client,
+		Registry:     registry,
+		ServingState: watchdog.Stale,
 	}
 	g.Add(metricsvc.Start)
 
 	// step 10. create debug service and register with workgroup.
+	xdsResources := []cgrpc.Resource{
+		&eh.CacheHandler.ClusterCache,
+		&eh.CacheHandler.RouteCache,
+		&eh.CacheHandler.ListenerCache,
+		&eh.CacheHandler.SecretCache,
+		et,
+	}
 	debugsvc := debug.Service{
 		Service: httpsvc.Service{
 			Addr:        ctx.debugAddr,
 			Port:        ctx.debugPort,
 			FieldLogger: log.WithField("context", "debugsvc"),
 		},
-		Builder: &eh.Builder,
+		Builder:   &eh.Builder,
+		Resources: xdsResources,
+		Profiling: ctx.debugProfiling,
+		Token:     debugToken,
 	}
 	g.Add(debugsvc.Start)
 
@@ -302,13 +498,50 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		eh.IsLeader = leader
 	}
 
-	// step 12. register our custom metrics and plumb into cache handler
+	// step 12. if enabled, provision the Envoy resources while leader.
+	if ctx.EnvoyProvisioner.Enabled {
+		prov := &provisioner.Provisioner{
+			Client: client,
+			Config: provisioner.Config{
+				Namespace:    ctx.EnvoyProvisioner.Namespace,
+				Name:         ctx.EnvoyProvisioner.Name,
+				Kind:         ctx.EnvoyProvisioner.Kind,
+				Replicas:     ctx.EnvoyProvisioner.Replicas,
+				EnvoyImage:   ctx.EnvoyProvisioner.EnvoyImage,
+				ContourImage: ctx.EnvoyProvisioner.ContourImage,
+				ServiceType:  corev1.ServiceType(ctx.EnvoyProvisioner.ServiceType),
+				XDSAddress:   ctx.EnvoyProvisioner.XDSAddress,
+				XDSPort:      ctx.xdsPort,
+				HTTPPort:     ctx.httpPort,
+				HTTPSPort:    ctx.httpsPort,
+				StatsPort:    ctx.statsPort,
+			},
+			FieldLogger: log.WithField("context", "provisioner"),
+		}
+		if err := prov.Validate(); err != nil {
+			return fmt.Errorf("envoy-provisioner: %v", err)
+		}
+		leader := eh.IsLeader
+		g.Add(func(stop <-chan struct{}) error {
+			select {
+			case <-leader:
+				return prov.Start(stop)
+			case <-stop:
+				return nil
+			}
+		})
+	}
+
+	// step 13. register our custom metrics and plumb into cache handler
 	// and resource event handler.
 	metrics := metrics.NewMetrics(registry)
+	metrics.SetFeatureGateMetric(featureGateStates(gates))
 	eh.Metrics = metrics
+	readyEndpoints.Metrics = metrics
+	watchdog.Metrics = metrics
 	eh.CacheHandler.Metrics = metrics
 
-	// step 13. create grpc handler and register with workgroup.
+	// step 14. create grpc handler and register with workgroup.
 	g.Add(func(stop <-chan struct{}) error {
 		log := log.WithField("context", "grpc")
 
@@ -323,15 +556,12 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		}
 		log.Printf("informer caches synced")
 
-		resources := map[string]cgrpc.Resource{
-			eh.CacheHandler.ClusterCache.TypeURL():  &eh.CacheHandler.ClusterCache,
-			eh.CacheHandler.RouteCache.TypeURL():    &eh.CacheHandler.RouteCache,
-			eh.CacheHandler.ListenerCache.TypeURL(): &eh.CacheHandler.ListenerCache,
-			eh.CacheHandler.SecretCache.TypeURL():   &eh.CacheHandler.SecretCache,
-			et.TypeURL():                            et,
+		resources := make(map[string]cgrpc.Resource, len(xdsResources))
+		for _, r := range xdsResources {
+			resources[r.TypeURL()] = r
 		}
 		opts := ctx.grpcOptions()
-		s := cgrpc.NewAPI(log, resources, registry, opts...)
+		s := cgrpc.NewAPI(log, resources, registry, cgrpc.EnvoyVersionPolicy(ctx.EnvoyVersionPolicy), cgrpc.ResourceVersioning(ctx.ResourceVersioning), opts...)
 		addr := net.JoinHostPort(ctx.xdsAddr, strconv.Itoa(ctx.xdsPort))
 		l, err := net.Listen("tcp", addr)
 		if err != nil {
@@ -354,7 +584,7 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		return s.Serve(l)
 	})
 
-	// step 14. Setup SIGTERM handler
+	// step 15. Setup SIGTERM handler
 	g.Add(func(stop <-chan struct{}) error {
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGTERM)
@@ -367,7 +597,7 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		return nil
 	})
 
-	// step 15. GO!
+	// step 16. GO!
 	return g.Run()
 }
 
@@ -376,6 +606,16 @@ func registerEventHandler(informers []cache.SharedIndexInformer, inf cache.Share
 	return append(informers, inf)
 }
 
+// featureGateStates returns whether each known feature of gates is enabled.
+func featureGateStates(gates *featuregate.FeatureGate) map[metrics.FeatureGateMeta]bool {
+	states := make(map[metrics.FeatureGateMeta]bool)
+	for feature, spec := range gates.Known() {
+		meta := metrics.FeatureGateMeta{Name: string(feature), Stage: string(spec.Stage)}
+		states[meta] = gates.Enabled(feature)
+	}
+	return states
+}
+
 type informer interface {
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 	Start(stopCh <-chan struct{})
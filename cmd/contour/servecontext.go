@@ -18,14 +18,18 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/featuregate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
@@ -45,16 +49,31 @@ type serveContext struct {
 	debugAddr string
 	debugPort int
 
+	// whether the debug handler serves /debug/pprof, and the file
+	// holding the bearer token required to access it, if any
+	debugProfiling bool
+	debugTokenFile string
+
 	// contour's metrics handler parameters
 	metricsAddr string
 	metricsPort int
 
+	// path of the file Kubernetes object events are recorded to
+	recordEvents string
+
 	// ingressroute root namespaces
 	rootNamespaces string
 
-	// ingress class
+	// ingress class, or a comma separated list of ingress classes
 	ingressClass string
 
+	// whether each ingress class is a regular expression
+	ingressClassRegex bool
+
+	// comma separated list of feature=bool pairs, overriding
+	// the feature gates of the configuration file
+	featureGates string
+
 	// envoy's stats listener parameters
 	statsAddr string
 	statsPort int
@@ -91,6 +110,11 @@ type serveContext struct {
 	// permitInsecure field in IngressRoute.
 	DisablePermitInsecure bool `yaml:"disablePermitInsecure,omitempty"`
 
+	// StrictAnnotations reports unknown Contour annotations, and
+	// annotations not valid for their object's kind, as warnings
+	// in the status of IngressRoutes and HTTPProxies.
+	StrictAnnotations bool `yaml:"strict-annotations,omitempty"`
+
 	// DisableLeaderElection can only be set by command line flag.
 	DisableLeaderElection bool `yaml:"-"`
 
@@ -100,6 +124,60 @@ type serveContext struct {
 	// RequestTimeout sets the client request timeout globally for Contour.
 	RequestTimeout time.Duration `yaml:"request-timeout,omitempty"`
 
+	// ResponseTimeout sets the default response timeout of routes
+	// which do not set their own.
+	ResponseTimeout time.Duration `yaml:"response-timeout,omitempty"`
+
+	// FeatureGates enables and disables experimental features
+	// of Contour, by feature name.
+	FeatureGates map[string]bool `yaml:"feature-gates,omitempty"`
+
+	// HTTP1Config holds the HTTP/1 protocol options of Envoy's listeners.
+	HTTP1Config `yaml:"http1,omitempty"`
+
+	// ListenerConfig holds the connection options of Envoy's listeners.
+	ListenerConfig `yaml:"listener,omitempty"`
+
+	// EnvoyVersionPolicy is what Contour does when an Envoy outside
+	// the supported version range connects, "warn" or "refuse".
+	EnvoyVersionPolicy string `yaml:"envoy-version-policy,omitempty"`
+
+	// ResourceVersioning is how the versions of the resources sent
+	// to Envoy are chosen, "counter" or "content".
+	ResourceVersioning string `yaml:"resource-versioning,omitempty"`
+
+	// SecretDeletionGracePeriod is how long Contour continues to
+	// serve the certificate of a deleted TLS Secret.
+	SecretDeletionGracePeriod time.Duration `yaml:"secret-deletion-grace-period,omitempty"`
+
+	// StaleServingThreshold is how long the Kubernetes API server must
+	// be unreachable before the configuration served is stale.
+	StaleServingThreshold time.Duration `yaml:"stale-serving-threshold,omitempty"`
+
+	// EnvoyProvisioner configures Contour to create and maintain
+	// the Envoy workload and Service.
+	EnvoyProvisioner EnvoyProvisionerConfig `yaml:"envoy-provisioner,omitempty"`
+
+	// RateLimitService configures the rate limit service Envoy
+	// consults for routes with a rate limit policy.
+	RateLimitService RateLimitServiceConfig `yaml:"rate-limit-service,omitempty"`
+
+	// FallbackService names the Service which receives the requests
+	// of a cluster with no healthy endpoints.
+	FallbackService FallbackServiceConfig `yaml:"fallback-service,omitempty"`
+
+	// CircuitBreakers are the default circuit breaking limits of
+	// Services which set none with their annotations.
+	CircuitBreakers CircuitBreakersConfig `yaml:"circuit-breakers,omitempty"`
+
+	// UpstreamTrustDomains name the CA bundles which validate the
+	// upstream services whose upstreamValidation names no caSecret.
+	UpstreamTrustDomains []UpstreamTrustDomainConfig `yaml:"upstream-trust-domains,omitempty"`
+
+	// StrictUpstreamTLS names the namespaces whose routes must
+	// connect to their services over validated TLS.
+	StrictUpstreamTLS StrictUpstreamTLSConfig `yaml:"strict-upstream-tls,omitempty"`
+
 	// Should Contour fall back to registering an informer for the deprecated
 	// extensions/v1beta1.Ingress type.
 	// By default this value is false, meaning Contour will register an informer for
@@ -163,6 +241,12 @@ func newServeContext() *serveContext {
 			Namespace:     "projectcontour",
 			Name:          "leader-elect",
 		},
+		EnvoyVersionPolicy:    "warn",
+		ResourceVersioning:    "counter",
+		StaleServingThreshold: 30 * time.Second,
+		EnvoyProvisioner: EnvoyProvisionerConfig{
+			Namespace: "projectcontour",
+		},
 		UseExtensionsV1beta1Ingress: false,
 	}
 }
@@ -170,6 +254,145 @@ func newServeContext() *serveContext {
 // TLSConfig holds configuration file TLS configuration details.
 type TLSConfig struct {
 	MinimumProtocolVersion string `yaml:"minimum-protocol-version"`
+
+	// MaximumProtocolVersion is the maximum TLS version of
+	// virtual hosts which do not set their own.
+	MaximumProtocolVersion string `yaml:"maximum-protocol-version,omitempty"`
+
+	// CipherSuites are the cipher suites offered by virtual
+	// hosts which do not set their own.
+	CipherSuites []string `yaml:"cipher-suites,omitempty"`
+}
+
+// HTTP1Config holds the HTTP/1 protocol options of Envoy's listeners
+// inside the configuration file.
+type HTTP1Config struct {
+	// DisableHTTP10 rejects HTTP/1.0 requests.
+	DisableHTTP10 bool `yaml:"disable-http10,omitempty"`
+
+	// DefaultHostForHTTP10 is the host assumed for HTTP/1.0
+	// requests without a Host: header, which are otherwise rejected.
+	DefaultHostForHTTP10 string `yaml:"default-host-for-http10,omitempty"`
+
+	// AllowAbsoluteURL permits requests with an absolute URL
+	// as their request target.
+	AllowAbsoluteURL bool `yaml:"allow-absolute-url,omitempty"`
+}
+
+// ListenerConfig holds the connection options of Envoy's listeners
+// inside the configuration file.
+type ListenerConfig struct {
+	// ConnectionBalancer is how new connections are balanced across
+	// Envoy's worker threads. If set to "exact" connections are
+	// balanced exactly. If not set Envoy's default applies. Any
+	// other value is rejected.
+	ConnectionBalancer string `yaml:"connection-balancer,omitempty"`
+
+	// ClientAddress is where Envoy takes the client's address from,
+	// "connection" or "x-forwarded-for".
+	ClientAddress string `yaml:"client-address,omitempty"`
+
+	// FiltersTimeout is how long the TLS inspector and PROXY
+	// protocol filter wait for the start of a new connection.
+	FiltersTimeout time.Duration `yaml:"filters-timeout,omitempty"`
+
+	// PerConnectionBufferLimitBytes is the soft limit on the
+	// buffers of each downstream connection.
+	PerConnectionBufferLimitBytes uint32 `yaml:"per-connection-buffer-limit-bytes,omitempty"`
+}
+
+// EnvoyProvisionerConfig holds the description of the Envoy resources
+// Contour provisions inside the configuration file.
+type EnvoyProvisionerConfig struct {
+	// Enabled turns on provisioning of the Envoy resources.
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Namespace of the Envoy resources.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Name of the Envoy workload and Service.
+	Name string `yaml:"name,omitempty"`
+
+	// Kind is the Envoy workload, DaemonSet or Deployment.
+	Kind string `yaml:"kind,omitempty"`
+
+	// Replicas is the number of Envoy pods of a Deployment.
+	Replicas int32 `yaml:"replicas,omitempty"`
+
+	// EnvoyImage is the Envoy container image.
+	EnvoyImage string `yaml:"envoy-image,omitempty"`
+
+	// ContourImage is the image of the bootstrap init container.
+	ContourImage string `yaml:"contour-image,omitempty"`
+
+	// ServiceType is the type of the Envoy Service.
+	ServiceType string `yaml:"service-type,omitempty"`
+
+	// XDSAddress is the address at which Envoy reaches Contour.
+	XDSAddress string `yaml:"xds-address,omitempty"`
+}
+
+// RateLimitServiceConfig holds the description of the rate limit
+// service inside the configuration file.
+type RateLimitServiceConfig struct {
+	// Namespace, Name and Port of the Service which implements
+	// Envoy's rate limit service.
+	Namespace string `yaml:"namespace,omitempty"`
+	Name      string `yaml:"name,omitempty"`
+	Port      int    `yaml:"port,omitempty"`
+
+	// Domain of the descriptors sent to the service.
+	Domain string `yaml:"domain,omitempty"`
+
+	// Timeout of calls to the service.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// FailureModeDeny rejects requests when the service
+	// cannot be reached, rather than allowing them.
+	FailureModeDeny bool `yaml:"failure-mode-deny,omitempty"`
+}
+
+// CircuitBreakersConfig holds the default circuit breaking limits
+// inside the configuration file. A limit of zero takes Envoy's default.
+type CircuitBreakersConfig struct {
+	MaxConnections     uint32 `yaml:"max-connections,omitempty"`
+	MaxPendingRequests uint32 `yaml:"max-pending-requests,omitempty"`
+	MaxRequests        uint32 `yaml:"max-requests,omitempty"`
+	MaxRetries         uint32 `yaml:"max-retries,omitempty"`
+}
+
+// StrictUpstreamTLSConfig holds the namespaces, or "*" for every
+// namespace, whose routes must connect to their services over TLS
+// with upstream validation.
+type StrictUpstreamTLSConfig struct {
+	Namespaces []string `yaml:"namespaces,omitempty"`
+}
+
+// FallbackServiceConfig holds the description of the fallback
+// service inside the configuration file.
+type FallbackServiceConfig struct {
+	Namespace string `yaml:"namespace,omitempty"`
+	Name      string `yaml:"name,omitempty"`
+
+	// PortName is the name of the Service's port, which may
+	// be omitted if the port is unnamed.
+	PortName string `yaml:"port-name,omitempty"`
+}
+
+// UpstreamTrustDomainConfig holds the description of an upstream
+// trust domain inside the configuration file.
+type UpstreamTrustDomainConfig struct {
+	Name string `yaml:"name"`
+
+	// CASecret is the namespace/name of the Secret holding
+	// the CA bundle of the trust domain.
+	CASecret string `yaml:"ca-secret"`
+
+	// Namespaces and Services, the latter in namespace/name
+	// form, are the services of the trust domain. If neither
+	// is set, the trust domain is the default.
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	Services   []string `yaml:"services,omitempty"`
 }
 
 // LeaderElectionConfig holds the config bits for leader election inside the
@@ -252,6 +475,88 @@ func (ctx *serveContext) verifyTLSFlags() error {
 	return nil
 }
 
+// verifyIngressClass indicates if the ingress classes are valid
+// regular expressions when --ingress-class-regex is supplied.
+func (ctx *serveContext) verifyIngressClass() error {
+	if !ctx.ingressClassRegex {
+		return nil
+	}
+	for _, class := range strings.Split(ctx.ingressClass, ",") {
+		if _, err := regexp.Compile(strings.TrimSpace(class)); err != nil {
+			return fmt.Errorf("ingress-class-name %q is not a valid regular expression: %v", class, err)
+		}
+	}
+	return nil
+}
+
+// upstreamTrustDomains returns the upstream trust domains of the
+// configuration file, or an error if they are misconfigured.
+func (ctx *serveContext) upstreamTrustDomains() ([]dag.UpstreamTrustDomain, error) {
+	var domains []dag.UpstreamTrustDomain
+	names := make(map[string]bool)
+	members := make(map[string]string)
+	hasDefault := false
+	for _, td := range ctx.UpstreamTrustDomains {
+		switch {
+		case td.Name == "":
+			return nil, errors.New("upstream-trust-domains: name must be set")
+		case names[td.Name]:
+			return nil, fmt.Errorf("upstream-trust-domains: %q: duplicate name", td.Name)
+		}
+		names[td.Name] = true
+
+		ca := strings.SplitN(td.CASecret, "/", 2)
+		if len(ca) != 2 || ca[0] == "" || ca[1] == "" {
+			return nil, fmt.Errorf("upstream-trust-domains: %q: ca-secret %q must be in namespace/name form", td.Name, td.CASecret)
+		}
+
+		if len(td.Namespaces) == 0 && len(td.Services) == 0 {
+			if hasDefault {
+				return nil, fmt.Errorf("upstream-trust-domains: %q: only one trust domain may omit namespaces and services", td.Name)
+			}
+			hasDefault = true
+		}
+		for _, ns := range td.Namespaces {
+			if other, ok := members[ns]; ok {
+				return nil, fmt.Errorf("upstream-trust-domains: %q: namespace %q is already in trust domain %q", td.Name, ns, other)
+			}
+			members[ns] = td.Name
+		}
+		for _, svc := range td.Services {
+			if v := strings.SplitN(svc, "/", 2); len(v) != 2 || v[0] == "" || v[1] == "" {
+				return nil, fmt.Errorf("upstream-trust-domains: %q: service %q must be in namespace/name form", td.Name, svc)
+			}
+			if other, ok := members[svc]; ok {
+				return nil, fmt.Errorf("upstream-trust-domains: %q: service %q is already in trust domain %q", td.Name, svc, other)
+			}
+			members[svc] = td.Name
+		}
+
+		domains = append(domains, dag.UpstreamTrustDomain{
+			Name:              td.Name,
+			CASecretNamespace: ca[0],
+			CASecretName:      ca[1],
+			Namespaces:        td.Namespaces,
+			Services:          td.Services,
+		})
+	}
+	return domains, nil
+}
+
+// featureGate returns the feature gates of the configuration file,
+// overridden by those of the --feature-gates flag, or an error if
+// either names an unknown feature.
+func (ctx *serveContext) featureGate() (*featuregate.FeatureGate, error) {
+	fg := featuregate.NewContourFeatureGate()
+	if err := fg.SetFromMap(ctx.FeatureGates); err != nil {
+		return nil, fmt.Errorf("feature-gates: %v", err)
+	}
+	if err := fg.Set(ctx.featureGates); err != nil {
+		return nil, fmt.Errorf("--feature-gates: %v", err)
+	}
+	return fg, nil
+}
+
 // ingressRouteRootNamespaces returns a slice of namespaces restricting where
 // contour should look for ingressroute roots.
 func (ctx *serveContext) ingressRouteRootNamespaces() []string {
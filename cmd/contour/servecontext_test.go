@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/featuregate"
 	"gopkg.in/yaml.v2"
 )
 
@@ -105,6 +107,196 @@ func TestServeContextTLSParams(t *testing.T) {
 	}
 }
 
+func TestServeContextIngressClass(t *testing.T) {
+	tests := map[string]struct {
+		ctx         serveContext
+		expecterror bool
+	}{
+		"not a regex": {
+			ctx: serveContext{
+				ingressClass: "nginx(",
+			},
+			expecterror: false,
+		},
+		"valid regexes": {
+			ctx: serveContext{
+				ingressClass:      "contour, nginx-.*",
+				ingressClassRegex: true,
+			},
+			expecterror: false,
+		},
+		"invalid regex": {
+			ctx: serveContext{
+				ingressClass:      "contour,nginx(",
+				ingressClassRegex: true,
+			},
+			expecterror: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.ctx.verifyIngressClass()
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("Ingress class: %v", err)
+			}
+		})
+	}
+}
+
+func TestServeContextUpstreamTrustDomains(t *testing.T) {
+	tests := map[string]struct {
+		domains     []UpstreamTrustDomainConfig
+		want        []dag.UpstreamTrustDomain
+		expecterror bool
+	}{
+		"none": {},
+		"namespace, service and default": {
+			domains: []UpstreamTrustDomainConfig{{
+				Name:       "corp",
+				CASecret:   "projectcontour/corp-ca",
+				Namespaces: []string{"payments"},
+				Services:   []string{"default/ledger"},
+			}, {
+				Name:     "public",
+				CASecret: "projectcontour/public-ca",
+			}},
+			want: []dag.UpstreamTrustDomain{{
+				Name:              "corp",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "corp-ca",
+				Namespaces:        []string{"payments"},
+				Services:          []string{"default/ledger"},
+			}, {
+				Name:              "public",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "public-ca",
+			}},
+		},
+		"missing name": {
+			domains: []UpstreamTrustDomainConfig{{
+				CASecret: "projectcontour/corp-ca",
+			}},
+			expecterror: true,
+		},
+		"ca secret without namespace": {
+			domains: []UpstreamTrustDomainConfig{{
+				Name:     "corp",
+				CASecret: "corp-ca",
+			}},
+			expecterror: true,
+		},
+		"service without namespace": {
+			domains: []UpstreamTrustDomainConfig{{
+				Name:     "corp",
+				CASecret: "projectcontour/corp-ca",
+				Services: []string{"ledger"},
+			}},
+			expecterror: true,
+		},
+		"namespace in two trust domains": {
+			domains: []UpstreamTrustDomainConfig{{
+				Name:       "corp",
+				CASecret:   "projectcontour/corp-ca",
+				Namespaces: []string{"payments"},
+			}, {
+				Name:       "public",
+				CASecret:   "projectcontour/public-ca",
+				Namespaces: []string{"payments"},
+			}},
+			expecterror: true,
+		},
+		"two defaults": {
+			domains: []UpstreamTrustDomainConfig{{
+				Name:     "corp",
+				CASecret: "projectcontour/corp-ca",
+			}, {
+				Name:     "public",
+				CASecret: "projectcontour/public-ca",
+			}},
+			expecterror: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := serveContext{UpstreamTrustDomains: tc.domains}
+			got, err := ctx.upstreamTrustDomains()
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Fatalf("Upstream trust domains: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestServeContextFeatureGate(t *testing.T) {
+	tests := map[string]struct {
+		ctx         serveContext
+		want        map[featuregate.Feature]bool
+		expecterror bool
+	}{
+		"defaults": {
+			want: map[featuregate.Feature]bool{
+				featuregate.MergeVirtualHosts: false,
+				featuregate.RouteStats:        false,
+			},
+		},
+		"config file": {
+			ctx: serveContext{
+				FeatureGates: map[string]bool{"RouteStats": true},
+			},
+			want: map[featuregate.Feature]bool{
+				featuregate.MergeVirtualHosts: false,
+				featuregate.RouteStats:        true,
+			},
+		},
+		"flag overrides config file": {
+			ctx: serveContext{
+				FeatureGates: map[string]bool{"RouteStats": true},
+				featureGates: "RouteStats=false,MergeVirtualHosts=true",
+			},
+			want: map[featuregate.Feature]bool{
+				featuregate.MergeVirtualHosts: true,
+				featuregate.RouteStats:        false,
+			},
+		},
+		"unknown gate in config file": {
+			ctx: serveContext{
+				FeatureGates: map[string]bool{"HTTP3": true},
+			},
+			expecterror: true,
+		},
+		"malformed flag": {
+			ctx: serveContext{
+				featureGates: "RouteStats",
+			},
+			expecterror: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fg, err := tc.ctx.featureGate()
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Fatalf("Feature gates: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			got := make(map[featuregate.Feature]bool)
+			for feature := range fg.Known() {
+				got[feature] = fg.Enabled(feature)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
 func TestConfigFileDefaultOverrideImport(t *testing.T) {
 	tests := map[string]struct {
 		yamlIn string
@@ -144,6 +336,244 @@ tls:
 				return ctx
 			},
 		},
+		"tls cipher suites": {
+			yamlIn: `
+tls:
+  cipher-suites:
+  - "[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]"
+  - ECDHE-ECDSA-AES256-GCM-SHA384
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.TLSConfig.CipherSuites = []string{
+					"[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]",
+					"ECDHE-ECDSA-AES256-GCM-SHA384",
+				}
+				return ctx
+			},
+		},
+		"tls maximum protocol version": {
+			yamlIn: `
+tls:
+  minimum-protocol-version: 1.2
+  maximum-protocol-version: 1.2
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.TLSConfig.MinimumProtocolVersion = "1.2"
+				ctx.TLSConfig.MaximumProtocolVersion = "1.2"
+				return ctx
+			},
+		},
+		"http1 configuration": {
+			yamlIn: `
+http1:
+  default-host-for-http10: example.com
+  allow-absolute-url: true
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.HTTP1Config.DefaultHostForHTTP10 = "example.com"
+				ctx.HTTP1Config.AllowAbsoluteURL = true
+				return ctx
+			},
+		},
+		"listener configuration": {
+			yamlIn: `
+listener:
+  connection-balancer: exact
+  client-address: x-forwarded-for
+  filters-timeout: 5s
+  per-connection-buffer-limit-bytes: 32768
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.ListenerConfig.ConnectionBalancer = "exact"
+				ctx.ListenerConfig.ClientAddress = "x-forwarded-for"
+				ctx.ListenerConfig.FiltersTimeout = 5 * time.Second
+				ctx.ListenerConfig.PerConnectionBufferLimitBytes = 32768
+				return ctx
+			},
+		},
+		"rate limit service": {
+			yamlIn: `
+rate-limit-service:
+  namespace: projectcontour
+  name: ratelimit
+  port: 8081
+  domain: example
+  timeout: 100ms
+  failure-mode-deny: true
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.RateLimitService = RateLimitServiceConfig{
+					Namespace:       "projectcontour",
+					Name:            "ratelimit",
+					Port:            8081,
+					Domain:          "example",
+					Timeout:         100 * time.Millisecond,
+					FailureModeDeny: true,
+				}
+				return ctx
+			},
+		},
+		"fallback service": {
+			yamlIn: `
+fallback-service:
+  namespace: projectcontour
+  name: error-pages
+  port-name: http
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.FallbackService = FallbackServiceConfig{
+					Namespace: "projectcontour",
+					Name:      "error-pages",
+					PortName:  "http",
+				}
+				return ctx
+			},
+		},
+		"circuit breakers": {
+			yamlIn: `
+circuit-breakers:
+  max-connections: 2048
+  max-pending-requests: 512
+  max-requests: 4096
+  max-retries: 16
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.CircuitBreakers = CircuitBreakersConfig{
+					MaxConnections:     2048,
+					MaxPendingRequests: 512,
+					MaxRequests:        4096,
+					MaxRetries:         16,
+				}
+				return ctx
+			},
+		},
+		"strict upstream tls": {
+			yamlIn: `
+strict-upstream-tls:
+  namespaces:
+  - payments
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.StrictUpstreamTLS.Namespaces = []string{"payments"}
+				return ctx
+			},
+		},
+		"upstream trust domains": {
+			yamlIn: `
+upstream-trust-domains:
+- name: corp
+  ca-secret: projectcontour/corp-ca
+  namespaces:
+  - payments
+  services:
+  - default/ledger
+- name: public
+  ca-secret: projectcontour/public-ca
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.UpstreamTrustDomains = []UpstreamTrustDomainConfig{{
+					Name:       "corp",
+					CASecret:   "projectcontour/corp-ca",
+					Namespaces: []string{"payments"},
+					Services:   []string{"default/ledger"},
+				}, {
+					Name:     "public",
+					CASecret: "projectcontour/public-ca",
+				}}
+				return ctx
+			},
+		},
+		"envoy version policy": {
+			yamlIn: `
+envoy-version-policy: refuse
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.EnvoyVersionPolicy = "refuse"
+				return ctx
+			},
+		},
+		"feature gates": {
+			yamlIn: `
+feature-gates:
+  MergeVirtualHosts: true
+  RouteStats: false
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.FeatureGates = map[string]bool{
+					"MergeVirtualHosts": true,
+					"RouteStats":        false,
+				}
+				return ctx
+			},
+		},
+		"strict annotations": {
+			yamlIn: `
+strict-annotations: true
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.StrictAnnotations = true
+				return ctx
+			},
+		},
+		"resource versioning": {
+			yamlIn: `
+resource-versioning: content
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.ResourceVersioning = "content"
+				return ctx
+			},
+		},
+		"secret deletion grace period": {
+			yamlIn: `
+secret-deletion-grace-period: 1h
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.SecretDeletionGracePeriod = time.Hour
+				return ctx
+			},
+		},
+		"stale serving threshold": {
+			yamlIn: `
+stale-serving-threshold: 2m
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.StaleServingThreshold = 2 * time.Minute
+				return ctx
+			},
+		},
+		"envoy provisioner configuration": {
+			yamlIn: `
+envoy-provisioner:
+  enabled: true
+  kind: Deployment
+  replicas: 3
+  service-type: NodePort
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.EnvoyProvisioner.Enabled = true
+				ctx.EnvoyProvisioner.Kind = "Deployment"
+				ctx.EnvoyProvisioner.Replicas = 3
+				ctx.EnvoyProvisioner.ServiceType = "NodePort"
+				return ctx
+			},
+		},
 		"leader election namespace and configmap only": {
 			yamlIn: `
 leaderelection:
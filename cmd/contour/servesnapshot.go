@@ -0,0 +1,102 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	cgrpc "github.com/projectcontour/contour/internal/grpc"
+	"github.com/projectcontour/contour/internal/snapshot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// serveSnapshotContext holds the configuration for serve-snapshot.
+// Only the xDS server settings of the embedded serveContext are used.
+type serveSnapshotContext struct {
+	*serveContext
+
+	// Path is the snapshot tarball to serve.
+	Path string
+}
+
+// registerServeSnapshot registers the serve-snapshot subcommand and
+// flags with the Application provided.
+func registerServeSnapshot(app *kingpin.Application) (*kingpin.CmdClause, *serveSnapshotContext) {
+	ctx := &serveSnapshotContext{
+		serveContext: newServeContext(),
+	}
+	serveSnapshot := app.Command("serve-snapshot", "Serve an exported xDS snapshot read-only, without watching Kubernetes")
+	serveSnapshot.Arg("path", "Snapshot tarball exported from /debug/snapshot").Required().ExistingFileVar(&ctx.Path)
+
+	serveSnapshot.Flag("xds-address", "xDS gRPC API address").StringVar(&ctx.xdsAddr)
+	serveSnapshot.Flag("xds-port", "xDS gRPC API port").IntVar(&ctx.xdsPort)
+
+	serveSnapshot.Flag("contour-cafile", "CA bundle file name for serving gRPC with TLS").Envar("CONTOUR_CAFILE").StringVar(&ctx.caFile)
+	serveSnapshot.Flag("contour-cert-file", "Contour certificate file name for serving gRPC over TLS").Envar("CONTOUR_CERT_FILE").StringVar(&ctx.contourCert)
+	serveSnapshot.Flag("contour-key-file", "Contour key file name for serving gRPC over TLS").Envar("CONTOUR_KEY_FILE").StringVar(&ctx.contourKey)
+	serveSnapshot.Flag("insecure", "Allow serving without TLS secured gRPC").BoolVar(&ctx.PermitInsecureGRPC)
+
+	return serveSnapshot, ctx
+}
+
+// doServeSnapshot serves the contents of the snapshot at ctx.Path
+// until SIGTERM or SIGINT is received.
+func doServeSnapshot(log logrus.FieldLogger, ctx *serveSnapshotContext) error {
+	f, err := os.Open(ctx.Path)
+	if err != nil {
+		return err
+	}
+	snap, err := snapshot.Read(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	log = log.WithField("context", "grpc")
+	resources := make(map[string]cgrpc.Resource, len(snap))
+	for _, r := range snap {
+		resources[r.TypeURL()] = r
+		log.WithField("type_url", r.TypeURL()).WithField("count", len(r.Contents())).Info("loaded snapshot")
+	}
+
+	s := cgrpc.NewAPI(log, resources, prometheus.NewRegistry(), cgrpc.EnvoyVersionPolicy(ctx.EnvoyVersionPolicy), cgrpc.ResourceVersioning(ctx.ResourceVersioning), ctx.grpcOptions()...)
+	addr := net.JoinHostPort(ctx.xdsAddr, strconv.Itoa(ctx.xdsPort))
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log = log.WithField("address", addr).WithField("snapshot", ctx.Path)
+	if ctx.PermitInsecureGRPC {
+		log = log.WithField("insecure", true)
+	}
+
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGTERM, syscall.SIGINT)
+		<-c
+		log.Info("received signal, shutting down")
+		s.Stop()
+	}()
+
+	log.Info("started")
+	defer log.Info("stopped")
+	return s.Serve(l)
+}
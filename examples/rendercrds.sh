@@ -14,3 +14,7 @@ trap "rm -rf $TMPDIR; exit" 0 1 2 15
 controller-gen crd paths=../apis/... output:dir=$TMPDIR
 
 ls $TMPDIR/*.yaml | xargs cat | sed '/^$/d' > contour/01-crds.yaml
+
+# Publish structural schemas, with defaults, which this version of
+# controller-gen cannot generate.
+go run ../hack/structural-crds contour/01-crds.yaml
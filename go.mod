@@ -30,6 +30,7 @@ require (
 	gopkg.in/yaml.v2 v2.2.2
 	honnef.co/go/tools v0.0.1-2019.2.3
 	k8s.io/api v0.0.0-20190918195907-bd6ac527cfd2
+	k8s.io/apiextensions-apiserver v0.0.0-20190918161926-8f644eb6e783
 	k8s.io/apimachinery v0.0.0-20190913080033-27d36303b655
 	k8s.io/client-go v0.0.0-20190918200256-06eb1244587a
 	k8s.io/code-generator v0.0.0-20190912054826-cd179ad6a269
@@ -37,4 +38,5 @@ require (
 	k8s.io/klog v0.4.0
 	mvdan.cc/unparam v0.0.0-20190720180237-d51796306d8f
 	sigs.k8s.io/controller-tools v0.2.2-0.20191004105652-6eef39898e44
+	sigs.k8s.io/yaml v1.1.0
 )
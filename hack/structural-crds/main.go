@@ -0,0 +1,145 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// structural-crds rewrites the projectcontour.io CRDs of the supplied
+// manifest so the API server prunes unknown fields from, and applies
+// defaults to, the objects it stores. The version of controller-gen
+// Contour uses can generate neither, so this is done once it has run.
+//
+// Each rewritten schema is checked to be structural, and its defaults
+// valid, by the API server's own validation.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+const group = "projectcontour.io"
+
+// defaults holds the default of each property, by the dotted path of
+// the property in the objects of each CRD. Only values Contour already
+// assumes when the property is omitted may be defaulted, so that objects
+// stored before the defaults were published behave the same.
+var defaults = map[string]map[string]string{
+	"httpproxies.projectcontour.io": {
+		"spec.virtualhost.tls.minimumProtocolVersion": `"1.1"`,
+		"spec.routes.retryPolicy.count":               `1`,
+		"spec.routes.loadBalancerPolicy.strategy":     `"RoundRobin"`,
+		"spec.tcpproxy.loadBalancerPolicy.strategy":   `"RoundRobin"`,
+	},
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatalf("usage: %s <crds.yaml>", os.Args[0])
+	}
+	path := os.Args[1]
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	for _, doc := range strings.Split(string(buf), "---\n") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var crd v1beta1.CustomResourceDefinition
+		if err := yaml.Unmarshal([]byte(doc), &crd); err != nil {
+			log.Fatal(err)
+		}
+		if crd.Spec.Group == group {
+			if err := structural(&crd); err != nil {
+				log.Fatalf("%s: %v", crd.Name, err)
+			}
+			b, err := yaml.Marshal(&crd)
+			if err != nil {
+				log.Fatal(err)
+			}
+			doc = string(b)
+		}
+		out.WriteString("---\n")
+		out.WriteString(doc)
+	}
+
+	if err := ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// structural applies the defaults of crd to its schema and disables
+// preserving unknown fields, returning an error if the result is not
+// a valid structural schema.
+func structural(crd *v1beta1.CustomResourceDefinition) error {
+	if crd.Spec.Validation == nil || crd.Spec.Validation.OpenAPIV3Schema == nil {
+		return fmt.Errorf("no validation schema")
+	}
+	preserve := false
+	crd.Spec.PreserveUnknownFields = &preserve
+
+	schema := crd.Spec.Validation.OpenAPIV3Schema
+	for path, value := range defaults[crd.Name] {
+		if err := setDefault(schema, strings.Split(path, "."), value); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	var internal apiextensions.JSONSchemaProps
+	if err := v1beta1.Convert_v1beta1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, &internal, nil); err != nil {
+		return err
+	}
+	s, err := structuralschema.NewStructural(&internal)
+	if err != nil {
+		return err
+	}
+	fldPath := field.NewPath("spec", "validation", "openAPIV3Schema")
+	if errs := structuralschema.ValidateStructural(fldPath, s); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	errs, err := structuraldefaulting.ValidateDefaults(fldPath, s, true, true)
+	if err != nil {
+		return err
+	}
+	return errs.ToAggregate()
+}
+
+// setDefault sets the default of the property of schema at path,
+// descending into the items of any array on the way.
+func setDefault(schema *v1beta1.JSONSchemaProps, path []string, value string) error {
+	for schema.Items != nil && schema.Items.Schema != nil {
+		schema = schema.Items.Schema
+	}
+	prop, ok := schema.Properties[path[0]]
+	if !ok {
+		return fmt.Errorf("no property %q", path[0])
+	}
+	if len(path) == 1 {
+		prop.Default = &v1beta1.JSON{Raw: []byte(value)}
+	} else if err := setDefault(&prop, path[1:], value); err != nil {
+		return err
+	}
+	schema.Properties[path[0]] = prop
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accesslog parses the access log lines Envoy writes, in
+// either the envoy or json format Contour configures, and selects
+// those matching a filter.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Entry is the part of an access log line a Filter matches on.
+type Entry struct {
+	// Authority is the request's Host, or :authority, header.
+	Authority string
+
+	// ResponseCode is the status of the response, or
+	// zero if none was sent.
+	ResponseCode int
+}
+
+// envoyFormat matches Envoy's default access log format, capturing
+// %RESPONSE_CODE%, which follows the quoted request line, and
+// %REQ(:AUTHORITY)%, the fourth quoted field after the durations.
+var envoyFormat = regexp.MustCompile(`^\[[^\]]*\] "[^"]*" (\d+) \S+ \S+ \S+ \S+ \S+ "[^"]*" "[^"]*" "[^"]*" "([^"]*)" "[^"]*"\s*$`)
+
+// Parse returns the Entry of an access log line in the envoy or
+// json format, or false if line is not an access log line, such
+// as one of Envoy's own log messages.
+func Parse(line string) (Entry, bool) {
+	if strings.HasPrefix(line, "{") {
+		return parseJSON(line)
+	}
+	m := envoyFormat.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, false
+	}
+	code, err := strconv.Atoi(m[1])
+	if err != nil {
+		return Entry{}, false
+	}
+	return Entry{
+		Authority:    unset(m[2]),
+		ResponseCode: code,
+	}, true
+}
+
+// parseJSON returns the Entry of a json format line, which must
+// include the authority and response_code fields to be matched.
+func parseJSON(line string) (Entry, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return Entry{}, false
+	}
+	authority, ok := fields["authority"]
+	if !ok {
+		return Entry{}, false
+	}
+	code, ok := fields["response_code"]
+	if !ok {
+		return Entry{}, false
+	}
+	var e Entry
+	e.Authority = unset(fmt.Sprint(authority))
+	switch code := code.(type) {
+	case float64:
+		e.ResponseCode = int(code)
+	case string:
+		e.ResponseCode, _ = strconv.Atoi(code)
+	}
+	return e, true
+}
+
+// unset returns s, or "" if s is Envoy's placeholder for
+// a value which is not set.
+func unset(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// Filter selects access log entries. The zero Filter
+// selects every entry.
+type Filter struct {
+	// FQDN, if set, selects entries whose authority is this
+	// host, with or without a port.
+	FQDN string
+
+	// Status, if set, selects entries whose response code
+	// is this status, such as "404", or in this class of
+	// statuses, such as "5xx".
+	Status string
+}
+
+// Validate returns an error if the filter's Status is
+// neither a status nor a class of statuses.
+func (f *Filter) Validate() error {
+	if f.Status == "" {
+		return nil
+	}
+	if _, _, ok := statusRange(f.Status); !ok {
+		return fmt.Errorf("status %q must be a status, such as 404, or a class of statuses, such as 5xx", f.Status)
+	}
+	return nil
+}
+
+// Match reports whether e is selected by the filter.
+func (f *Filter) Match(e Entry) bool {
+	if f.FQDN != "" {
+		host := e.Authority
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.EqualFold(host, f.FQDN) {
+			return false
+		}
+	}
+	if f.Status != "" {
+		min, max, ok := statusRange(f.Status)
+		if !ok || e.ResponseCode < min || e.ResponseCode > max {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRange returns the inclusive range of statuses named by
+// status, a single status or a class such as "5xx".
+func statusRange(status string) (int, int, bool) {
+	s := strings.ToLower(status)
+	if len(s) != 3 || s[0] < '1' || s[0] > '5' {
+		return 0, 0, false
+	}
+	if s[1:] == "xx" {
+		class := int(s[0]-'0') * 100
+		return class, class + 99, true
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, false
+	}
+	return code, code, true
+}
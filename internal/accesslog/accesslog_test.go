@@ -0,0 +1,137 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accesslog
+
+import (
+	"testing"
+
+	"github.com/projectcontour/contour/internal/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		line string
+		want Entry
+		ok   bool
+	}{
+		"envoy format": {
+			line: `[2019-12-04T02:11:04.123Z] "GET /api/v1 HTTP/1.1" 503 UH 0 19 0 - "10.0.0.1" "curl/7.64.1" "9b8a9d5e-5d0e-4f45-8a3f-3c4e9c6e2b7a" "example.com:8080" "-"`,
+			want: Entry{Authority: "example.com:8080", ResponseCode: 503},
+			ok:   true,
+		},
+		"envoy format tcp": {
+			line: `[2019-12-04T02:11:04.123Z] "- - -" 0 - 517 3071 30 - "-" "-" "-" "-" "10.0.1.7:443"`,
+			want: Entry{ResponseCode: 0},
+			ok:   true,
+		},
+		"json format": {
+			line: `{"@timestamp":"2019-12-04T02:11:04.123Z","authority":"example.com","method":"GET","response_code":"404"}`,
+			want: Entry{Authority: "example.com", ResponseCode: 404},
+			ok:   true,
+		},
+		"json format numeric response code": {
+			line: `{"authority":"example.com","response_code":200}`,
+			want: Entry{Authority: "example.com", ResponseCode: 200},
+			ok:   true,
+		},
+		"json format without response code": {
+			line: `{"authority":"example.com","method":"GET"}`,
+			ok:   false,
+		},
+		"envoy log message": {
+			line: `[2019-12-04 02:11:04.123][1][info][main] [source/server/server.cc:516] starting main dispatch loop`,
+			ok:   false,
+		},
+		"blank": {
+			line: "",
+			ok:   false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := Parse(tc.line)
+			if ok != tc.ok {
+				t.Fatalf("expected ok: %v, got %v", tc.ok, ok)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := map[string]struct {
+		filter  Filter
+		entry   Entry
+		want    bool
+		wantErr bool
+	}{
+		"empty filter": {
+			filter: Filter{},
+			entry:  Entry{Authority: "example.com", ResponseCode: 200},
+			want:   true,
+		},
+		"fqdn": {
+			filter: Filter{FQDN: "example.com"},
+			entry:  Entry{Authority: "Example.com", ResponseCode: 200},
+			want:   true,
+		},
+		"fqdn with port": {
+			filter: Filter{FQDN: "example.com"},
+			entry:  Entry{Authority: "example.com:8443", ResponseCode: 200},
+			want:   true,
+		},
+		"other fqdn": {
+			filter: Filter{FQDN: "example.com"},
+			entry:  Entry{Authority: "www.example.com", ResponseCode: 200},
+			want:   false,
+		},
+		"status class": {
+			filter: Filter{Status: "5xx"},
+			entry:  Entry{ResponseCode: 503},
+			want:   true,
+		},
+		"other status class": {
+			filter: Filter{Status: "5XX"},
+			entry:  Entry{ResponseCode: 404},
+			want:   false,
+		},
+		"status": {
+			filter: Filter{FQDN: "example.com", Status: "404"},
+			entry:  Entry{Authority: "example.com", ResponseCode: 404},
+			want:   true,
+		},
+		"invalid status": {
+			filter:  Filter{Status: "6xx"},
+			wantErr: true,
+		},
+		"invalid status class": {
+			filter:  Filter{Status: "5x"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.filter.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.want, tc.filter.Match(tc.entry))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"sync"
+	"time"
+
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// APIWatchdog probes the Kubernetes API server every Interval.
+//
+// While the API server cannot be reached Contour's informers keep
+// the objects they last saw, so Envoy continues to be served the last
+// configuration built. Once the API server has been unreachable for
+// longer than Threshold, APIWatchdog reports that configuration as
+// stale, in the metrics.StaleServingGauge metric and through Stale,
+// until the API server is reached again.
+type APIWatchdog struct {
+	logrus.FieldLogger
+
+	*metrics.Metrics
+
+	// Probe contacts the API server, returning an error if it
+	// cannot be reached.
+	Probe func() error
+
+	// Interval is how often the API server is probed.
+	Interval time.Duration
+
+	// Threshold is how long the API server must be unreachable
+	// before the configuration served is stale.
+	Threshold time.Duration
+
+	mu sync.Mutex
+
+	// lastContact holds the time the API server was last
+	// reached, or the watchdog started.
+	lastContact time.Time
+
+	// lastErr holds the error of the last probe.
+	lastErr error
+
+	stale bool
+
+	// now returns the current time, if set, in place of time.Now.
+	now func() time.Time
+}
+
+// Start probes the API server every Interval until stop is closed.
+func (w *APIWatchdog) Start(stop <-chan struct{}) error {
+	w.Info("started")
+	defer w.Info("stopped")
+
+	w.mu.Lock()
+	w.lastContact = w.clock()
+	w.mu.Unlock()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		w.check()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// check probes the API server and updates whether the
+// configuration served is stale.
+func (w *APIWatchdog) check() {
+	err := w.Probe()
+	now := w.clock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastErr = err
+	if err == nil {
+		w.lastContact = now
+	}
+	stale := err != nil && now.Sub(w.lastContact) > w.Threshold
+	switch {
+	case stale && !w.stale:
+		w.WithError(err).WithField("lastcontact", w.lastContact).Warn("kubernetes API server unreachable, serving stale configuration")
+	case !stale && w.stale:
+		w.Info("kubernetes API server reachable, configuration no longer stale")
+	}
+	w.stale = stale
+
+	if w.Metrics != nil {
+		w.SetStaleServingMetric(stale, w.lastContact)
+	}
+}
+
+// Stale returns whether the configuration served is stale, when
+// the API server was last reached, and the error of the last probe.
+func (w *APIWatchdog) Stale() (bool, time.Time, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stale, w.lastContact, w.lastErr
+}
+
+func (w *APIWatchdog) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
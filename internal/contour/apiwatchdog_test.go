@@ -0,0 +1,63 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAPIWatchdog(t *testing.T) {
+	start := time.Unix(1000, 0)
+	now := start
+	unreachable := errors.New("connection refused")
+	var probeErr error
+
+	w := &APIWatchdog{
+		FieldLogger: testLogger(t),
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		Probe:       func() error { return probeErr },
+		Threshold:   30 * time.Second,
+		lastContact: start,
+		now:         func() time.Time { return now },
+	}
+
+	check := func(advance time.Duration, err error, wantStale bool, wantContact time.Time) {
+		t.Helper()
+		now = now.Add(advance)
+		probeErr = err
+		w.check()
+		stale, lastContact, gotErr := w.Stale()
+		assert.Equal(t, wantStale, stale)
+		assert.Equal(t, wantContact, lastContact)
+		if gotErr != err {
+			t.Fatalf("expected error %v, got %v", err, gotErr)
+		}
+	}
+
+	check(0, nil, false, start)
+	// unreachable, but not for longer than the threshold.
+	check(10*time.Second, unreachable, false, start)
+	check(20*time.Second, unreachable, false, start)
+	// unreachable for longer than the threshold.
+	check(5*time.Second, unreachable, true, start)
+	check(time.Minute, unreachable, true, start)
+	// reachable again.
+	check(time.Second, nil, false, start.Add(96*time.Second))
+}
@@ -28,11 +28,16 @@ import (
 // CacheHandler manages the state of xDS caches.
 type CacheHandler struct {
 	ListenerVisitorConfig
+	RouteVisitorConfig
 	ListenerCache
 	RouteCache
 	ClusterCache
 	SecretCache
 
+	// EndpointsTranslator, if set, is told after each rebuild
+	// which services may fail over to its fallback service.
+	EndpointsTranslator *EndpointsTranslator
+
 	*metrics.Metrics
 
 	logrus.FieldLogger
@@ -46,6 +51,7 @@ func (ch *CacheHandler) OnChange(dag *dag.DAG) {
 	ch.updateListeners(dag)
 	ch.updateRoutes(dag)
 	ch.updateClusters(dag)
+	ch.updateFallbackServices(dag)
 
 	ch.SetDAGLastRebuilt(time.Now())
 }
@@ -61,7 +67,7 @@ func (ch *CacheHandler) updateListeners(root dag.Visitable) {
 }
 
 func (ch *CacheHandler) updateRoutes(root dag.Visitable) {
-	routes := visitRoutes(root)
+	routes := visitRoutes(root, &ch.RouteVisitorConfig)
 	ch.RouteCache.Update(routes)
 }
 
@@ -69,3 +75,10 @@ func (ch *CacheHandler) updateClusters(root dag.Visitable) {
 	clusters := visitClusters(root)
 	ch.ClusterCache.Update(clusters)
 }
+
+func (ch *CacheHandler) updateFallbackServices(root dag.Vertex) {
+	if ch.EndpointsTranslator == nil {
+		return
+	}
+	ch.EndpointsTranslator.SetFallbackServices(visitFallbackServices(root))
+}
@@ -22,7 +22,9 @@ import (
 	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/envoyproxy/go-control-plane/pkg/cache"
 	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,8 +36,32 @@ import (
 type EndpointsTranslator struct {
 	logrus.FieldLogger
 	clusterLoadAssignmentCache
+
+	// ReadyEndpoints, if set, is told of every change to Endpoints.
+	ReadyEndpoints *ReadyEndpoints
+
+	// FallbackService, if its Name is set, names the Service whose
+	// endpoints the clusters of plain HTTP routes fail over to when
+	// none of their own endpoints are healthy.
+	FallbackService FallbackService
 }
 
+// FallbackService names a Service, and the name of its port, whose
+// endpoints are added at a lower priority to the clusters which are
+// routed to over plain HTTP.
+type FallbackService struct {
+	Namespace string
+	Name      string
+	PortName  string
+}
+
+// fallbackOverprovisioningFactor keeps all of a cluster's traffic on
+// its own endpoints for as long as any of them are healthy, so only
+// a cluster with no healthy endpoints fails over to the fallback
+// service. Envoy's default of 140 would send a share of the traffic
+// of a partially healthy cluster to the fallback service.
+const fallbackOverprovisioningFactor = 1000000
+
 func (e *EndpointsTranslator) OnAdd(obj interface{}) {
 	switch obj := obj.(type) {
 	case *v1.Endpoints:
@@ -87,7 +113,7 @@ func (e *EndpointsTranslator) Query(names []string) []proto.Message {
 				ClusterName: n,
 			}
 		}
-		values = append(values, v)
+		values = append(values, e.withFallback(v))
 	}
 	sort.Stable(clusterLoadAssignmentsByName(values))
 	return values
@@ -105,6 +131,9 @@ func (*EndpointsTranslator) TypeURL() string { return cache.EndpointType }
 
 func (e *EndpointsTranslator) addEndpoints(ep *v1.Endpoints) {
 	e.recomputeClusterLoadAssignment(nil, ep)
+	if e.ReadyEndpoints != nil {
+		e.ReadyEndpoints.SetEndpoints(ep)
+	}
 }
 
 func (e *EndpointsTranslator) updateEndpoints(oldep, newep *v1.Endpoints) {
@@ -115,10 +144,16 @@ func (e *EndpointsTranslator) updateEndpoints(oldep, newep *v1.Endpoints) {
 		return
 	}
 	e.recomputeClusterLoadAssignment(oldep, newep)
+	if e.ReadyEndpoints != nil {
+		e.ReadyEndpoints.SetEndpoints(newep)
+	}
 }
 
 func (e *EndpointsTranslator) removeEndpoints(ep *v1.Endpoints) {
 	e.recomputeClusterLoadAssignment(ep, nil)
+	if e.ReadyEndpoints != nil {
+		e.ReadyEndpoints.RemoveEndpoints(ep)
+	}
 }
 
 // recomputeClusterLoadAssignment recomputes the EDS cache taking into account old and new endpoints.
@@ -170,6 +205,9 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 			}
 			seen[cla.ClusterName] = true
 			e.Add(cla)
+			if e.isFallback(newep.ObjectMeta, p.Name) {
+				e.SetFallback(cla.ClusterName, lbendpoints)
+			}
 		}
 	}
 
@@ -183,16 +221,81 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 			if _, ok := seen[name]; !ok {
 				// port is no longer present, remove it.
 				e.Remove(name)
+				if e.isFallback(oldep.ObjectMeta, p.Name) {
+					e.SetFallback(name, nil)
+				}
 			}
 		}
 	}
 
 }
 
+// isFallback returns true if meta and portname name the port of
+// the FallbackService.
+func (e *EndpointsTranslator) isFallback(meta metav1.ObjectMeta, portname string) bool {
+	fs := e.FallbackService
+	return fs.Name != "" && fs.Name == meta.Name && fs.Namespace == meta.Namespace && fs.PortName == portname
+}
+
 type clusterLoadAssignmentCache struct {
 	mu      sync.Mutex
 	entries map[string]*v2.ClusterLoadAssignment
 	Cond
+
+	// fallbackName is the name of the entry of the fallback
+	// service, and fallback its endpoints.
+	fallbackName string
+	fallback     []*envoy_api_v2_endpoint.LbEndpoint
+
+	// fallbackServices are the names of the entries which
+	// fail over to the fallback service.
+	fallbackServices map[string]bool
+}
+
+// SetFallback sets the endpoints which the entries named by
+// SetFallbackServices, but for the named one, fail over to.
+// Every entry may change, so all are notified.
+func (c *clusterLoadAssignmentCache) SetFallback(name string, lbendpoints []*envoy_api_v2_endpoint.LbEndpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallbackName = name
+	c.fallback = lbendpoints
+	c.Notify()
+}
+
+// SetFallbackServices sets the names of the entries which fail
+// over to the fallback endpoints. Every entry may change, so all
+// are notified.
+func (c *clusterLoadAssignmentCache) SetFallbackServices(names map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallbackServices = names
+	c.Notify()
+}
+
+// withFallback returns a with the fallback endpoints added at
+// priority 1, or a itself if there are no fallback endpoints or
+// a does not fail over to them.
+// c.mu must be held.
+func (c *clusterLoadAssignmentCache) withFallback(a *v2.ClusterLoadAssignment) *v2.ClusterLoadAssignment {
+	if len(c.fallback) == 0 || a.ClusterName == c.fallbackName || !c.fallbackServices[a.ClusterName] {
+		return a
+	}
+	endpoints := a.Endpoints
+	if len(endpoints) == 0 {
+		// priorities must start at 0, even if it has no endpoints.
+		endpoints = []*envoy_api_v2_endpoint.LocalityLbEndpoints{{}}
+	}
+	return &v2.ClusterLoadAssignment{
+		ClusterName: a.ClusterName,
+		Endpoints: append(endpoints[:len(endpoints):len(endpoints)], &envoy_api_v2_endpoint.LocalityLbEndpoints{
+			LbEndpoints: c.fallback,
+			Priority:    1,
+		}),
+		Policy: &v2.ClusterLoadAssignment_Policy{
+			OverprovisioningFactor: protobuf.UInt32(fallbackOverprovisioningFactor),
+		},
+	}
 }
 
 // Add adds an entry to the cache. If a ClusterLoadAssignment with the same
@@ -222,11 +325,54 @@ func (c *clusterLoadAssignmentCache) Contents() []proto.Message {
 	defer c.mu.Unlock()
 	var values []proto.Message
 	for _, v := range c.entries {
-		values = append(values, v)
+		values = append(values, c.withFallback(v))
 	}
 	return values
 }
 
+// visitFallbackServices returns the names of the services of root
+// whose clusters may fail over to the fallback service. Only the
+// clusters of routes which forward plain HTTP can; a TLS, HTTP/2 or
+// TCP upstream, or a cluster Envoy itself calls, such as that of an
+// authorization or rate limit service, must not be sent to a service
+// which serves error pages. As every cluster of a service shares its
+// endpoints, a service with any such cluster is excluded.
+func visitFallbackServices(root dag.Vertex) map[string]bool {
+	routed := make(map[string]bool)
+	excluded := make(map[string]bool)
+	name := func(c *dag.Cluster) string {
+		return servicename(metav1.ObjectMeta{Namespace: c.Upstream.Namespace, Name: c.Upstream.Name}, c.Upstream.ServicePort.Name)
+	}
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch v := vertex.(type) {
+		case *dag.Route:
+			v.Visit(func(vertex dag.Vertex) {
+				if c, ok := vertex.(*dag.Cluster); ok {
+					if c.Upstream.Protocol == "" {
+						routed[name(c)] = true
+					} else {
+						excluded[name(c)] = true
+					}
+				}
+			})
+		case *dag.Cluster:
+			excluded[name(v)] = true
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+
+	names := make(map[string]bool)
+	for n := range routed {
+		if !excluded[n] {
+			names[n] = true
+		}
+	}
+	return names
+}
+
 // servicename returns the name of the cluster this meta and port
 // refers to. The CDS name of the cluster may include additional suffixes
 // but these are not known to EDS.
@@ -17,10 +17,15 @@ import (
 	"testing"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/golang/protobuf/proto"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestEndpointsTranslatorContents(t *testing.T) {
@@ -431,6 +436,187 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestEndpointsTranslatorFallbackService(t *testing.T) {
+	et := EndpointsTranslator{
+		FallbackService: FallbackService{
+			Namespace: "projectcontour",
+			Name:      "error-pages",
+			PortName:  "http",
+		},
+	}
+	fallback := endpoints("projectcontour", "error-pages", v1.EndpointSubset{
+		Addresses: addresses("10.0.0.1"),
+		Ports: ports(
+			port("http", 8080),
+		),
+	})
+	e1 := endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24"),
+		Ports: ports(
+			port("", 8080),
+		),
+	})
+	et.OnAdd(e1)
+	et.OnAdd(fallback)
+	et.SetFallbackServices(map[string]bool{
+		"default/simple": true,
+		"default/kuard":  true,
+	})
+
+	withFallback := func(cla *v2.ClusterLoadAssignment) *v2.ClusterLoadAssignment {
+		if len(cla.Endpoints) == 0 {
+			cla.Endpoints = []*envoy_api_v2_endpoint.LocalityLbEndpoints{{}}
+		}
+		cla.Endpoints = append(cla.Endpoints, &envoy_api_v2_endpoint.LocalityLbEndpoints{
+			LbEndpoints: []*envoy_api_v2_endpoint.LbEndpoint{
+				envoy.LBEndpoint(envoy.SocketAddress("10.0.0.1", 8080)),
+			},
+			Priority: 1,
+		})
+		cla.Policy = &v2.ClusterLoadAssignment_Policy{
+			OverprovisioningFactor: protobuf.UInt32(fallbackOverprovisioningFactor),
+		}
+		return cla
+	}
+
+	// every cluster but the fallback service's fails over to it.
+	want := []proto.Message{
+		withFallback(envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080))),
+		envoy.ClusterLoadAssignment("projectcontour/error-pages/http", envoy.SocketAddress("10.0.0.1", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	// a cluster without endpoints sends all its requests to the fallback service.
+	want = []proto.Message{
+		withFallback(envoy.ClusterLoadAssignment("default/kuard")),
+	}
+	assert.Equal(t, want, et.Query([]string{"default/kuard"}))
+
+	// a cluster which is not a plain HTTP route's does not fail over.
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/grpc"),
+	}
+	assert.Equal(t, want, et.Query([]string{"default/grpc"}))
+
+	// once the fallback service has no endpoints, clusters do not fail over.
+	et.OnDelete(fallback)
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+}
+
+func TestVisitFallbackServices(t *testing.T) {
+	service := func(name string, port int32, annotations map[string]string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Name:     "port",
+					Protocol: "TCP",
+					Port:     port,
+				}},
+			},
+		}
+	}
+	route := func(prefix, service string, port int) projcontour.Route {
+		return projcontour.Route{
+			Conditions: []projcontour.Condition{{Prefix: prefix}},
+			Services: []projcontour.Service{{
+				Name: service,
+				Port: port,
+			}},
+		}
+	}
+
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: testLogger(t),
+		},
+		RateLimitService: dag.RateLimitServiceConfig{
+			Namespace: "default",
+			Name:      "ratelimit",
+			Port:      8081,
+		},
+	}
+	for _, o := range []interface{}{
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Type: "kubernetes.io/tls",
+			Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+		},
+		service("web", 80, nil),
+		service("secure", 443, map[string]string{"projectcontour.io/upstream-protocol.tls": "443"}),
+		service("h2", 8443, map[string]string{"projectcontour.io/upstream-protocol.h2": "8443"}),
+		service("grpc", 50051, map[string]string{"projectcontour.io/upstream-protocol.h2c": "50051"}),
+		service("tcp", 8080, nil),
+		service("auth", 9001, nil),
+		service("ratelimit", 8081, nil),
+		&projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "www",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "www.example.com",
+					TLS: &projcontour.TLS{
+						SecretName: "secret",
+					},
+					Authorization: &projcontour.AuthorizationServer{
+						Name: "auth",
+						Port: 9001,
+					},
+				},
+				Routes: []projcontour.Route{
+					route("/web", "web", 80),
+					route("/secure", "secure", 443),
+					route("/h2", "h2", 8443),
+					route("/grpc", "grpc", 50051),
+					// the authorization service is also routed to.
+					route("/auth", "auth", 9001),
+				},
+			},
+		},
+		&projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tcp",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "tcp.example.com",
+					TLS: &projcontour.TLS{
+						SecretName: "secret",
+					},
+				},
+				TCPProxy: &projcontour.TCPProxy{
+					Services: []projcontour.Service{{
+						Name: "tcp",
+						Port: 8080,
+					}},
+				},
+			},
+		},
+	} {
+		builder.Source.Insert(o)
+	}
+
+	// of the TLS, HTTP/2, h2c, TCP proxy, authorization and
+	// rate limit service clusters none fail over.
+	want := map[string]bool{
+		"default/web/port": true,
+	}
+	assert.Equal(t, want, visitFallbackServices(builder.Build()))
+}
+
 func ports(eps ...v1.EndpointPort) []v1.EndpointPort {
 	return eps
 }
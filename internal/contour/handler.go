@@ -27,6 +27,7 @@ import (
 	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/projectcontour/contour/internal/metrics"
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,6 +45,10 @@ type EventHandler struct {
 
 	*metrics.Metrics
 
+	// ReadyEndpoints, if set, is told of the virtual hosts of
+	// every DAG built.
+	ReadyEndpoints *ReadyEndpoints
+
 	logrus.FieldLogger
 
 	// IsLeader will become ready to read when this EventHandler becomes
@@ -65,6 +70,10 @@ type EventHandler struct {
 	// seq is the sequence counter of the number of times
 	// an event has been received.
 	seq int
+
+	// nextChange holds the time at which the last DAG built
+	// becomes stale, or the zero time if it does not.
+	nextChange time.Time
 }
 
 type opAdd struct {
@@ -119,6 +128,13 @@ func (e *EventHandler) run(stop <-chan struct{}) error {
 
 		// pending is a reference to the current timer's channel.
 		pending <-chan time.Time
+
+		// scheduler holds the timer which will expire when the
+		// current DAG becomes stale.
+		scheduler *time.Timer
+
+		// scheduled is a reference to the current scheduler's channel.
+		scheduled <-chan time.Time
 	)
 
 	reset := func() (v int) {
@@ -126,13 +142,29 @@ func (e *EventHandler) run(stop <-chan struct{}) error {
 		return
 	}
 
+	// schedule arranges for the DAG to be rebuilt when a route's
+	// active window next opens or closes.
+	schedule := func() {
+		if scheduler != nil {
+			scheduler.Stop()
+			scheduled = nil
+		}
+		if e.nextChange.IsZero() {
+			return
+		}
+		scheduler = time.NewTimer(time.Until(e.nextChange))
+		scheduled = scheduler.C
+	}
+
 	for {
 		// In the main loop one of four things can happen.
-		// 1. We're waiting for an event on op, stop, or pending, noting that
-		//    pending may be nil if there are no pending events.
+		// 1. We're waiting for an event on op, stop, pending, or scheduled,
+		//    noting that pending and scheduled may be nil if there are no
+		//    pending events or active windows.
 		// 2. We're processing an event.
-		// 3. The holdoff timer from a previous event has fired and we're
-		//    building a new DAG and sending to the CacheHandler.
+		// 3. The holdoff timer from a previous event, or the scheduler for
+		//    a route's active window, has fired and we're building a new
+		//    DAG and sending to the CacheHandler.
 		// 4. We're stopping.
 		//
 		// Only one of these things can happen at a time.
@@ -155,6 +187,7 @@ func (e *EventHandler) run(stop <-chan struct{}) error {
 					e.WithField("last_update", since).WithField("outstanding", reset()).Info("forcing update")
 					e.updateDAG() // rebuild dag and send to CacheHandler.
 					e.incSequence()
+					schedule()
 					continue
 				}
 
@@ -171,6 +204,12 @@ func (e *EventHandler) run(stop <-chan struct{}) error {
 			e.WithField("last_update", time.Since(e.last)).WithField("outstanding", reset()).Info("performing delayed update")
 			e.updateDAG()
 			e.incSequence()
+			schedule()
+		case <-scheduled:
+			e.WithField("next_change", e.nextChange).Info("performing scheduled update")
+			e.updateDAG()
+			e.incSequence()
+			schedule()
 		case <-stop:
 			// shutdown
 			return nil
@@ -186,7 +225,11 @@ func (e *EventHandler) onUpdate(op interface{}) bool {
 	case opAdd:
 		return e.Builder.Source.Insert(op.obj)
 	case opUpdate:
-		if cmp.Equal(op.oldObj, op.newObj,
+		// the contents of a Secret may have been stripped before it
+		// was received, leaving only its ResourceVersion to show it
+		// has changed.
+		_, secret := op.newObj.(*v1.Secret)
+		if !secret && cmp.Equal(op.oldObj, op.newObj,
 			cmpopts.IgnoreFields(ingressroutev1.IngressRoute{}, "Status"),
 			cmpopts.IgnoreFields(metav1.ObjectMeta{}, "ResourceVersion")) {
 			e.WithField("op", "update").Debugf("%T skipping update, only status has changed", op.newObj)
@@ -220,6 +263,10 @@ func (e *EventHandler) incSequence() {
 func (e *EventHandler) updateDAG() {
 	dag := e.Builder.Build()
 	e.CacheHandler.OnChange(dag)
+	e.nextChange = dag.NextChange()
+	if e.ReadyEndpoints != nil {
+		e.ReadyEndpoints.SetVirtualHosts(dag)
+	}
 
 	select {
 	case <-e.IsLeader:
@@ -230,6 +277,8 @@ func (e *EventHandler) updateDAG() {
 		metrics, proxymetrics := calculateRouteMetric(statuses)
 		e.Metrics.SetIngressRouteMetric(metrics)
 		e.Metrics.SetHTTPProxyMetric(proxymetrics)
+		e.Metrics.SetServiceWeightMetric(calculateServiceWeightMetric(dag))
+		e.Metrics.SetDeprecatedAnnotationMetric(calculateDeprecatedAnnotationMetric(dag))
 	default:
 		e.Debug("skipping status update: not the leader")
 	}
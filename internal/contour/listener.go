@@ -19,14 +19,18 @@ import (
 	"time"
 
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	envoy_api_v2_accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/cache"
 	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
 )
 
 const (
@@ -39,6 +43,18 @@ const (
 	DEFAULT_HTTPS_LISTENER_ADDRESS = DEFAULT_HTTP_LISTENER_ADDRESS
 	DEFAULT_HTTPS_LISTENER_PORT    = 8443
 	DEFAULT_ACCESS_LOG_TYPE        = "envoy"
+
+	// ClientAddressConnection takes the client address from the
+	// downstream connection, or its PROXY protocol preamble.
+	ClientAddressConnection = "connection"
+
+	// ClientAddressXForwardedFor takes the client address from the
+	// X-Forwarded-For entry appended by the proxy in front of Envoy.
+	ClientAddressXForwardedFor = "x-forwarded-for"
+
+	// ConnectionBalancerExact balances new connections exactly
+	// across Envoy's worker threads.
+	ConnectionBalancerExact = "exact"
 )
 
 // ListenerVisitorConfig holds configuration parameters for visitListeners.
@@ -75,6 +91,16 @@ type ListenerVisitorConfig struct {
 	// MinimumProtocolVersion defines the min tls protocol version to be used
 	MinimumProtocolVersion envoy_api_v2_auth.TlsParameters_TlsProtocol
 
+	// MaximumProtocolVersion defines the max tls protocol version to be
+	// used by virtual hosts which do not set their own.
+	// If not set, defaults to envoy_api_v2_auth.TlsParameters_TLSv1_3.
+	MaximumProtocolVersion envoy_api_v2_auth.TlsParameters_TlsProtocol
+
+	// CipherSuites defines the cipher suites offered by virtual
+	// hosts which do not set their own.
+	// If not set, Envoy is sent Contour's default cipher suites.
+	CipherSuites []string
+
 	// AccessLogType defines if Envoy logs should be output as Envoy's default or JSON.
 	// Valid values: 'envoy', 'json'
 	// If not set, defaults to 'envoy'
@@ -87,6 +113,34 @@ type ListenerVisitorConfig struct {
 
 	// RequestTimeout configures the request_timeout for all Connection Managers.
 	RequestTimeout time.Duration
+
+	// HTTP1Options configures the HTTP/1 protocol options for all
+	// Connection Managers.
+	HTTP1Options envoy.HTTP1Options
+
+	// ConnectionBalancer configures how the HTTP and HTTPS listeners
+	// balance new connections across Envoy's worker threads.
+	// If set to "exact", connections are balanced exactly, otherwise
+	// Envoy's default applies.
+	ConnectionBalancer string
+
+	// ListenerFiltersTimeout is how long the listener filters, the
+	// TLS inspector and PROXY protocol filter, wait for the start of
+	// a new connection before closing it.
+	// If not set, Envoy's default of 15s applies.
+	ListenerFiltersTimeout time.Duration
+
+	// PerConnectionBufferLimitBytes is the soft limit on the size of
+	// the read and write buffers of each downstream connection.
+	// If not set, Envoy's default of 1MiB applies.
+	PerConnectionBufferLimitBytes uint32
+
+	// ClientAddress is where Envoy takes the address of the client
+	// from, ClientAddressConnection or ClientAddressXForwardedFor.
+	// Envoy logs the address, appends it to X-Forwarded-For, and
+	// uses it wherever the client's address is needed.
+	// If not set, defaults to ClientAddressConnection.
+	ClientAddress string
 }
 
 // httpAddress returns the port for the HTTP (non TLS)
@@ -192,6 +246,15 @@ func (lvc *ListenerVisitorConfig) requestTimeout() time.Duration {
 	return lvc.RequestTimeout
 }
 
+// xffTrustedHops returns the number of X-Forwarded-For entries,
+// appended by proxies in front of Envoy, which are trusted.
+func (lvc *ListenerVisitorConfig) xffTrustedHops() uint32 {
+	if lvc.ClientAddress == ClientAddressXForwardedFor {
+		return 1
+	}
+	return 0
+}
+
 // minProtocolVersion returns the requested minimum TLS protocol
 // version or envoy_api_v2_auth.TlsParameters_TLSv1_1 if not configured {
 func (lvc *ListenerVisitorConfig) minProtoVersion() envoy_api_v2_auth.TlsParameters_TlsProtocol {
@@ -201,6 +264,34 @@ func (lvc *ListenerVisitorConfig) minProtoVersion() envoy_api_v2_auth.TlsParamet
 	return envoy_api_v2_auth.TlsParameters_TLSv1_1
 }
 
+// maxProtoVersion returns the maximum TLS protocol version of a
+// virtual host requesting requested, or the configured maximum if it
+// requests none, or envoy_api_v2_auth.TlsParameters_TLSv1_3 if neither
+// is set. The result is never below min, the minimum version of the
+// virtual host, so that the configured minimum is always enforced.
+func (lvc *ListenerVisitorConfig) maxProtoVersion(requested, min envoy_api_v2_auth.TlsParameters_TlsProtocol) envoy_api_v2_auth.TlsParameters_TlsProtocol {
+	version := requested
+	if version == envoy_api_v2_auth.TlsParameters_TLS_AUTO {
+		version = lvc.MaximumProtocolVersion
+	}
+	if version == envoy_api_v2_auth.TlsParameters_TLS_AUTO {
+		version = envoy_api_v2_auth.TlsParameters_TLSv1_3
+	}
+	if version < min {
+		return min
+	}
+	return version
+}
+
+// cipherSuites returns the cipher suites of a virtual host requesting
+// requested, or the configured cipher suites if it requests none.
+func (lvc *ListenerVisitorConfig) cipherSuites(requested []string) []string {
+	if len(requested) > 0 {
+		return requested
+	}
+	return lvc.CipherSuites
+}
+
 // ListenerCache manages the contents of the gRPC LDS cache.
 type ListenerCache struct {
 	mu           sync.Mutex
@@ -268,6 +359,31 @@ func (c *ListenerCache) Query(names []string) []proto.Message {
 	return values
 }
 
+// ForNode returns the listeners with their addresses replaced by
+// any the node has requested in its metadata.
+func (c *ListenerCache) ForNode(node *envoy_api_v2_core.Node, values []proto.Message) []proto.Message {
+	var rewritten []proto.Message
+	for _, v := range values {
+		l := v.(*v2.Listener)
+		la, ok := envoy.NodeListenerAddress(node, l.Name)
+		if !ok {
+			rewritten = append(rewritten, l)
+			continue
+		}
+		addr := l.Address.GetSocketAddress()
+		if la.Address == "" {
+			la.Address = addr.GetAddress()
+		}
+		if la.Port == 0 {
+			la.Port = int(addr.GetPortValue())
+		}
+		l = proto.Clone(l).(*v2.Listener)
+		l.Address = envoy.SocketAddress(la.Address, la.Port)
+		rewritten = append(rewritten, l)
+	}
+	return rewritten
+}
+
 type listenersByName []proto.Message
 
 func (l listenersByName) Len() int      { return len(l) }
@@ -283,6 +399,15 @@ type listenerVisitor struct {
 
 	listeners map[string]*v2.Listener
 	http      bool // at least one dag.VirtualHost encountered
+
+	// additional http filters for the insecure and secure
+	// connection managers respectively.
+	httpFilters, httpsFilters []*http.HttpFilter
+
+	// rateLimitService, if set, is consulted by the rate limit
+	// filter of each connection manager, which follows the
+	// additional http filters.
+	rateLimitService *dag.RateLimitService
 }
 
 func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.Listener {
@@ -296,6 +421,24 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 			),
 		},
 	}
+	insecure, secure := downstreamProtocolRestricted(root)
+	if insecure {
+		lv.httpFilters = append(lv.httpFilters, envoy.DownstreamProtocolFilter())
+	}
+	if secure {
+		lv.httpsFilters = append(lv.httpsFilters, envoy.DownstreamProtocolFilter())
+	}
+	insecure, secure = requestHeadersAllowListed(root)
+	if insecure {
+		lv.httpFilters = append(lv.httpFilters, envoy.RequestHeadersAllowListFilter())
+	}
+	if secure {
+		lv.httpsFilters = append(lv.httpsFilters, envoy.RequestHeadersAllowListFilter())
+	}
+	if rls := rateLimitService(root); rls != nil {
+		lv.httpFilters = append(lv.httpFilters, envoy.RateLimitFilter(rls))
+		lv.rateLimitService = rls
+	}
 	lv.visit(root)
 
 	// add a listener if there are vhosts bound to http.
@@ -304,11 +447,21 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 			ENVOY_HTTP_LISTENER,
 			lvc.httpAddress(), lvc.httpPort(),
 			proxyProtocol(lvc.UseProxyProto),
-			envoy.HTTPConnectionManager(ENVOY_HTTP_LISTENER, lvc.newInsecureAccessLog(), lvc.requestTimeout()),
+			envoy.HTTPConnectionManager(ENVOY_HTTP_LISTENER, lvc.newInsecureAccessLog(), lvc.requestTimeout(), lvc.HTTP1Options, lvc.xffTrustedHops(), lv.httpFilters...),
 		)
 
 	}
 
+	for _, l := range lv.listeners {
+		l.ConnectionBalanceConfig = envoy.ConnectionBalanceConfig(lvc.ConnectionBalancer)
+		if lvc.ListenerFiltersTimeout > 0 {
+			l.ListenerFiltersTimeout = protobuf.Duration(lvc.ListenerFiltersTimeout)
+		}
+		if lvc.PerConnectionBufferLimitBytes > 0 {
+			l.PerConnectionBufferLimitBytes = protobuf.UInt32(lvc.PerConnectionBufferLimitBytes)
+		}
+	}
+
 	// remove the https listener if there are no vhosts bound to it.
 	if len(lv.listeners[ENVOY_HTTPS_LISTENER].FilterChains) == 0 {
 		delete(lv.listeners, ENVOY_HTTPS_LISTENER)
@@ -327,6 +480,69 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 	return lv.listeners
 }
 
+// requestHeadersAllowListed reports whether any insecure, or secure,
+// virtual host restricts the request headers forwarded to its backends.
+// As a request may be routed to any virtual host on the listener, the
+// filter which enforces the allow list must be present on every
+// connection manager of that listener.
+func requestHeadersAllowListed(root dag.Vertex) (insecure, secure bool) {
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch vh := vertex.(type) {
+		case *dag.VirtualHost:
+			insecure = insecure || len(vh.RequestHeadersAllowList) > 0
+		case *dag.SecureVirtualHost:
+			secure = secure || len(vh.RequestHeadersAllowList) > 0
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+	return insecure, secure
+}
+
+// downstreamProtocolRestricted reports whether any insecure, or
+// secure, virtual host, or any of their routes, has a downstream
+// protocol policy. As with the request header allow list, the
+// filter which enforces it must be present on every connection
+// manager of the listener.
+func downstreamProtocolRestricted(root dag.Vertex) (insecure, secure bool) {
+	restricted := func(vh *dag.VirtualHost) bool {
+		found := vh.DownstreamProtocolPolicy != nil
+		vh.Visit(func(vertex dag.Vertex) {
+			if r, ok := vertex.(*dag.Route); ok && r.DownstreamProtocolPolicy != nil {
+				found = true
+			}
+		})
+		return found
+	}
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch vh := vertex.(type) {
+		case *dag.VirtualHost:
+			insecure = insecure || restricted(vh)
+		case *dag.SecureVirtualHost:
+			secure = secure || restricted(&vh.VirtualHost)
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+	return insecure, secure
+}
+
+// rateLimitService returns the rate limit service of the DAG,
+// or nil if there is none.
+func rateLimitService(root dag.Vertex) *dag.RateLimitService {
+	var rls *dag.RateLimitService
+	root.Visit(func(vertex dag.Vertex) {
+		if r, ok := vertex.(*dag.RateLimitService); ok {
+			rls = r
+		}
+	})
+	return rls
+}
+
 func proxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter {
 	if useProxy {
 		return envoy.ListenerFilters(
@@ -355,9 +571,35 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 		// the listener properly.
 		v.http = true
 	case *dag.SecureVirtualHost:
-		filters := envoy.Filters(
-			envoy.HTTPConnectionManager(ENVOY_HTTPS_LISTENER, v.ListenerVisitorConfig.newSecureAccessLog(), v.ListenerVisitorConfig.requestTimeout()),
-		)
+		httpFilters := v.httpsFilters
+		if vh.AuthorizationServer != nil {
+			// authorize requests once the request header allow list,
+			// which would remove the headers the authorization service
+			// adds to them, has been enforced.
+			httpFilters = append(httpFilters[:len(httpFilters):len(httpFilters)], envoy.ExtAuthzFilter(vh.AuthorizationServer))
+		}
+		if rls := v.rateLimitService; rls != nil {
+			if vh.RateLimitFailOpen != nil {
+				// the failure mode of this host overrides that of the service.
+				r := *rls
+				r.FailureModeDeny = !*vh.RateLimitFailOpen
+				rls = &r
+			}
+			httpFilters = append(httpFilters[:len(httpFilters):len(httpFilters)], envoy.RateLimitFilter(rls))
+		}
+		if len(vh.JWTProviders) > 0 {
+			// reject requests without a valid JWT before they are authorized.
+			httpFilters = append([]*http.HttpFilter{envoy.JWTAuthnFilter(vh.JWTProviders, jwtRules(vh))}, httpFilters...)
+		}
+		routename := ENVOY_HTTPS_LISTENER
+		if ownRouteConfig(vh) {
+			routename = strictSNIRouteName(vh.VirtualHost.Name)
+		}
+		cm := envoy.RoutedHTTPConnectionManager(ENVOY_HTTPS_LISTENER, routename, v.ListenerVisitorConfig.newSecureAccessLog(), v.ListenerVisitorConfig.requestTimeout(), v.ListenerVisitorConfig.HTTP1Options, v.ListenerVisitorConfig.xffTrustedHops(), httpFilters...)
+		if dv := vh.DownstreamValidation; dv != nil && dv.ForwardClientCertificate != nil {
+			cm = envoy.ClientCertificateHTTPConnectionManager(ENVOY_HTTPS_LISTENER, routename, dv.ForwardClientCertificate, v.ListenerVisitorConfig.newSecureAccessLog(), v.ListenerVisitorConfig.requestTimeout(), v.ListenerVisitorConfig.HTTP1Options, v.ListenerVisitorConfig.xffTrustedHops(), httpFilters...)
+		}
+		filters := envoy.Filters(cm)
 		alpnProtos := []string{"h2", "http/1.1"}
 		if vh.TCPProxy != nil {
 			filters = envoy.Filters(
@@ -366,11 +608,15 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 			alpnProtos = nil // do not offer ALPN
 		}
 
+		minVersion := max(v.ListenerVisitorConfig.minProtoVersion(), vh.MinProtoVersion) // choose the higher of the configured or requested tls version
 		fc := envoy.FilterChainTLS(
 			vh.VirtualHost.Name,
 			vh.Secret,
+			vh.DownstreamValidation,
 			filters,
-			max(v.ListenerVisitorConfig.minProtoVersion(), vh.MinProtoVersion), // choose the higher of the configured or requested tls version
+			minVersion,
+			v.ListenerVisitorConfig.maxProtoVersion(vh.MaxProtoVersion, minVersion),
+			v.ListenerVisitorConfig.cipherSuites(vh.CipherSuites),
 			alpnProtos...,
 		)
 
@@ -380,3 +626,29 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 		vertex.Visit(v.visit)
 	}
 }
+
+// jwtRules returns a JWT rule for each route of vh, in the order
+// the routes are matched.
+func jwtRules(vh *dag.SecureVirtualHost) []envoy.JWTRule {
+	var routes []*envoy_api_v2_route.Route
+	providers := make(map[*envoy_api_v2_route.Route]string)
+	vh.Visit(func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok {
+			r := &envoy_api_v2_route.Route{
+				Match: envoy.RouteMatch(route),
+			}
+			routes = append(routes, r)
+			providers[r] = route.JWTProvider
+		}
+	})
+	sortRoutes(routes)
+
+	var rules []envoy.JWTRule
+	for _, r := range routes {
+		rules = append(rules, envoy.JWTRule{
+			Match:    r.Match,
+			Provider: providers[r],
+		})
+	}
+	return rules
+}
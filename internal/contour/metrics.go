@@ -17,6 +17,9 @@
 package contour
 
 import (
+	"strconv"
+	"strings"
+
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/dag"
@@ -69,7 +72,8 @@ func calculateRouteMetric(statuses map[dag.Meta]dag.Status) (metrics.RouteMetric
 
 func calcMetrics(v dag.Status, metricValid map[metrics.Meta]int, metricInvalid map[metrics.Meta]int, metricOrphaned map[metrics.Meta]int, metricTotal map[metrics.Meta]int) {
 	switch v.Status {
-	case dag.StatusValid:
+	case dag.StatusValid, dag.StatusWarning:
+		// objects with warnings are still serving traffic.
 		metricValid[metrics.Meta{VHost: v.Vhost, Namespace: v.Object.GetObjectMeta().GetNamespace()}]++
 	case dag.StatusInvalid:
 		metricInvalid[metrics.Meta{VHost: v.Vhost, Namespace: v.Object.GetObjectMeta().GetNamespace()}]++
@@ -78,3 +82,85 @@ func calcMetrics(v dag.Status, metricValid map[metrics.Meta]int, metricInvalid m
 	}
 	metricTotal[metrics.Meta{Namespace: v.Object.GetObjectMeta().GetNamespace()}]++
 }
+
+// calculateDeprecatedAnnotationMetric returns the number of objects
+// using each deprecated annotation in each namespace.
+func calculateDeprecatedAnnotationMetric(d *dag.DAG) map[metrics.DeprecatedAnnotationMeta]int {
+	counts := make(map[metrics.DeprecatedAnnotationMeta]int)
+	for da, n := range d.DeprecatedAnnotations() {
+		counts[metrics.DeprecatedAnnotationMeta{
+			Namespace:  da.Namespace,
+			Annotation: da.Annotation,
+		}] = n
+	}
+	return counts
+}
+
+// calculateServiceWeightMetric returns the percentage of each route's
+// traffic which is sent to each of its upstream Services.
+func calculateServiceWeightMetric(root dag.Visitable) map[metrics.ServiceWeightMeta]float64 {
+	weights := make(map[metrics.ServiceWeightMeta]float64)
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch v := vertex.(type) {
+		case *dag.VirtualHost:
+			routeWeights(weights, v)
+		case *dag.SecureVirtualHost:
+			routeWeights(weights, &v.VirtualHost)
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	root.Visit(visit)
+	return weights
+}
+
+func routeWeights(weights map[metrics.ServiceWeightMeta]float64, vh *dag.VirtualHost) {
+	vh.Visit(func(vertex dag.Vertex) {
+		route, ok := vertex.(*dag.Route)
+		if !ok {
+			return
+		}
+
+		// mirror the distribution calculated by envoy.weightedClusters,
+		// where no weights at all implies an even distribution.
+		var total uint32
+		for _, c := range route.Clusters {
+			total += c.Weight
+		}
+		shares := make(map[metrics.ServiceWeightMeta]float64)
+		for _, c := range route.Clusters {
+			share := float64(c.Weight) / float64(total)
+			if total == 0 {
+				share = 1 / float64(len(route.Clusters))
+			}
+			meta := metrics.ServiceWeightMeta{
+				VHost:     vh.Name,
+				Route:     routeConditions(route),
+				Namespace: c.Upstream.Namespace,
+				Service:   c.Upstream.Name,
+				Port:      strconv.Itoa(int(c.Upstream.Port)),
+			}
+			shares[meta] += share * 100
+		}
+
+		// a route shared by the insecure and secure virtual
+		// host of the same name is only counted once.
+		for meta, share := range shares {
+			weights[meta] = share
+		}
+	})
+}
+
+// routeConditions returns a textual description of the route's conditions.
+func routeConditions(route *dag.Route) string {
+	s := []string{route.PathCondition.String()}
+	for _, cond := range route.HeaderConditions {
+		s = append(s, cond.String())
+	}
+	for _, cond := range route.QueryParameterConditions {
+		s = append(s, cond.String())
+	}
+	return strings.Join(s, ",")
+}
@@ -1009,3 +1009,135 @@ func TestIngressRouteMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceWeightMetric(t *testing.T) {
+	service := func(name string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol: "TCP",
+					Port:     80,
+				}},
+			},
+		}
+	}
+
+	proxy := func(routes ...projcontour.Route) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: routes,
+			},
+		}
+	}
+
+	meta := func(route, service string) metrics.ServiceWeightMeta {
+		return metrics.ServiceWeightMeta{
+			VHost:     "example.com",
+			Route:     route,
+			Namespace: "default",
+			Service:   service,
+			Port:      "80",
+		}
+	}
+
+	tests := map[string]struct {
+		objs []interface{}
+		want map[metrics.ServiceWeightMeta]float64
+	}{
+		"single service": {
+			objs: []interface{}{
+				service("stable"),
+				proxy(projcontour.Route{
+					Services: []projcontour.Service{{Name: "stable", Port: 80}},
+				}),
+			},
+			want: map[metrics.ServiceWeightMeta]float64{
+				meta("prefix: /", "stable"): 100,
+			},
+		},
+		"weighted canary": {
+			objs: []interface{}{
+				service("stable"),
+				service("canary"),
+				proxy(projcontour.Route{
+					Services: []projcontour.Service{
+						{Name: "stable", Port: 80, Weight: 90},
+						{Name: "canary", Port: 80, Weight: 10},
+					},
+				}),
+			},
+			want: map[metrics.ServiceWeightMeta]float64{
+				meta("prefix: /", "stable"): 90,
+				meta("prefix: /", "canary"): 10,
+			},
+		},
+		"no weights": {
+			objs: []interface{}{
+				service("stable"),
+				service("canary"),
+				proxy(projcontour.Route{
+					Conditions: []projcontour.Condition{{Prefix: "/api"}},
+					Services: []projcontour.Service{
+						{Name: "stable", Port: 80},
+						{Name: "canary", Port: 80},
+					},
+				}),
+			},
+			want: map[metrics.ServiceWeightMeta]float64{
+				meta("prefix: /api", "stable"): 50,
+				meta("prefix: /api", "canary"): 50,
+			},
+		},
+		"routes differing by query parameter": {
+			objs: []interface{}{
+				service("stable"),
+				service("canary"),
+				proxy(projcontour.Route{
+					Services: []projcontour.Service{{Name: "stable", Port: 80}},
+				}, projcontour.Route{
+					Conditions: []projcontour.Condition{{
+						QueryParameter: &projcontour.QueryParameterCondition{
+							Name:  "track",
+							Exact: "canary",
+						},
+					}},
+					Services: []projcontour.Service{
+						{Name: "stable", Port: 80, Weight: 50},
+						{Name: "canary", Port: 80, Weight: 50},
+					},
+				}),
+			},
+			want: map[metrics.ServiceWeightMeta]float64{
+				meta("prefix: /", "stable"):                                     100,
+				meta("prefix: /,query parameter: track exact canary", "stable"): 50,
+				meta("prefix: /,query parameter: track exact canary", "canary"): 50,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := dag.Builder{
+				Source: dag.KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			for _, o := range tc.objs {
+				builder.Source.Insert(o)
+			}
+			got := calculateServiceWeightMetric(builder.Build())
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"sync"
+
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/metrics"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ReadyEndpoints tracks the percentage of the endpoints of each
+// virtual host's upstream Services which are ready, and records it
+// in the metrics.ReadyEndpointsGauge metric, so that global load
+// balancers and DNS systems can steer traffic away from a cluster
+// whose backends are mostly unready.
+type ReadyEndpoints struct {
+	*metrics.Metrics
+
+	mu sync.Mutex
+
+	// upstreams holds the upstream Service ports of each virtual host.
+	upstreams map[string]map[servicePort]bool
+
+	// endpoints holds the Endpoints of each Service.
+	endpoints map[serviceName]*v1.Endpoints
+}
+
+type serviceName struct {
+	namespace, name string
+}
+
+// servicePort identifies a Service port by its name, which is
+// also the name of the matching Endpoints port.
+type servicePort struct {
+	serviceName
+	port string
+}
+
+// SetVirtualHosts replaces the upstream Services of each virtual
+// host with those of the supplied DAG.
+func (re *ReadyEndpoints) SetVirtualHosts(root dag.Visitable) {
+	upstreams := make(map[string]map[servicePort]bool)
+	add := func(vhost string, clusters []*dag.Cluster) {
+		if upstreams[vhost] == nil {
+			upstreams[vhost] = make(map[servicePort]bool)
+		}
+		for _, c := range clusters {
+			s := c.Upstream
+			if s.ExternalName != "" {
+				// ExternalName services have no endpoints.
+				continue
+			}
+			upstreams[vhost][servicePort{serviceName{s.Namespace, s.Name}, s.ServicePort.Name}] = true
+		}
+	}
+	routes := func(vh *dag.VirtualHost) {
+		vh.Visit(func(vertex dag.Vertex) {
+			if r, ok := vertex.(*dag.Route); ok {
+				add(vh.Name, r.Clusters)
+			}
+		})
+	}
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch vh := vertex.(type) {
+		case *dag.VirtualHost:
+			routes(vh)
+		case *dag.SecureVirtualHost:
+			routes(&vh.VirtualHost)
+			if vh.TCPProxy != nil {
+				add(vh.Name, vh.TCPProxy.Clusters)
+			}
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	root.Visit(visit)
+
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.upstreams = upstreams
+	re.update()
+}
+
+// SetEndpoints records the current Endpoints of a Service.
+func (re *ReadyEndpoints) SetEndpoints(ep *v1.Endpoints) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.endpoints == nil {
+		re.endpoints = make(map[serviceName]*v1.Endpoints)
+	}
+	re.endpoints[serviceName{ep.Namespace, ep.Name}] = ep
+	re.update()
+}
+
+// RemoveEndpoints forgets the Endpoints of a Service.
+func (re *ReadyEndpoints) RemoveEndpoints(ep *v1.Endpoints) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	delete(re.endpoints, serviceName{ep.Namespace, ep.Name})
+	re.update()
+}
+
+// update records the percentage of ready endpoints of each virtual
+// host. A virtual host whose Services have no endpoints at all has
+// none ready. It must be called with mu held.
+func (re *ReadyEndpoints) update() {
+	if re.Metrics == nil {
+		return
+	}
+	percent := make(map[string]float64)
+	for vhost, ports := range re.upstreams {
+		var ready, total int
+		for sp := range ports {
+			r, t := countEndpoints(re.endpoints[sp.serviceName], sp.port)
+			ready += r
+			total += t
+		}
+		percent[vhost] = 0
+		if total > 0 {
+			percent[vhost] = float64(ready) * 100 / float64(total)
+		}
+	}
+	re.SetReadyEndpointsMetric(percent)
+}
+
+// countEndpoints returns the number of ready, and of all, addresses
+// of ep which serve the named port.
+func countEndpoints(ep *v1.Endpoints, port string) (ready, total int) {
+	if ep == nil {
+		return 0, 0
+	}
+	for _, s := range ep.Subsets {
+		for _, p := range s.Ports {
+			if p.Name == port {
+				ready += len(s.Addresses)
+				total += len(s.Addresses) + len(s.NotReadyAddresses)
+				break
+			}
+		}
+	}
+	return ready, total
+}
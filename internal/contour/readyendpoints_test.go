@@ -0,0 +1,146 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"testing"
+
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReadyEndpoints(t *testing.T) {
+	service := func(name string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Name:     "http",
+					Protocol: "TCP",
+					Port:     80,
+				}},
+			},
+		}
+	}
+
+	addresses := func(ips ...string) []v1.EndpointAddress {
+		var addrs []v1.EndpointAddress
+		for _, ip := range ips {
+			addrs = append(addrs, v1.EndpointAddress{IP: ip})
+		}
+		return addrs
+	}
+
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: testLogger(t),
+		},
+	}
+	for _, o := range []interface{}{
+		service("stable"),
+		service("canary"),
+		&projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{
+						{Name: "stable", Port: 80},
+						{Name: "canary", Port: 80},
+					},
+				}},
+			},
+		},
+	} {
+		builder.Source.Insert(o)
+	}
+
+	registry := prometheus.NewRegistry()
+	re := &ReadyEndpoints{
+		Metrics: metrics.NewMetrics(registry),
+	}
+
+	gather := func() map[string]float64 {
+		gathering, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]float64)
+		for _, mf := range gathering {
+			if mf.GetName() != metrics.ReadyEndpointsGauge {
+				continue
+			}
+			for _, metric := range mf.Metric {
+				for _, label := range metric.Label {
+					if label.GetName() == "vhost" {
+						got[label.GetValue()] = metric.Gauge.GetValue()
+					}
+				}
+			}
+		}
+		return got
+	}
+
+	// no endpoints are known yet.
+	re.SetVirtualHosts(builder.Build())
+	assert.Equal(t, map[string]float64{"example.com": 0}, gather())
+
+	stable := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stable",
+			Namespace: "default",
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses:         addresses("10.0.0.1", "10.0.0.2"),
+			NotReadyAddresses: addresses("10.0.0.3"),
+			Ports:             []v1.EndpointPort{{Name: "http", Port: 8080}},
+		}, {
+			// addresses of other ports are not counted.
+			NotReadyAddresses: addresses("10.0.0.4"),
+			Ports:             []v1.EndpointPort{{Name: "metrics", Port: 9090}},
+		}},
+	}
+	re.SetEndpoints(stable)
+	re.SetEndpoints(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "canary",
+			Namespace: "default",
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: addresses("10.0.1.1"),
+			Ports:     []v1.EndpointPort{{Name: "http", Port: 8080}},
+		}},
+	})
+	assert.Equal(t, map[string]float64{"example.com": 75}, gather())
+
+	re.RemoveEndpoints(stable)
+	assert.Equal(t, map[string]float64{"example.com": 100}, gather())
+
+	// virtual hosts which are no longer present are removed.
+	re.SetVirtualHosts(&dag.DAG{})
+	assert.Equal(t, map[string]float64{}, gather())
+}
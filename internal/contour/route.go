@@ -17,13 +17,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	"github.com/envoyproxy/go-control-plane/pkg/cache"
 	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
 )
 
 // RouteCache manages the contents of the gRPC RDS cache.
@@ -88,16 +91,41 @@ func (r routeConfigurationsByName) Less(i, j int) bool {
 // TypeURL returns the string type of RouteCache Resource.
 func (*RouteCache) TypeURL() string { return cache.RouteType }
 
+// RouteVisitorConfig holds configuration parameters for visitRoutes.
+type RouteVisitorConfig struct {
+	// RouteStats names each route after the HTTPProxy which defined
+	// it and adds a virtual cluster per route so Envoy records request
+	// statistics for each HTTPProxy, not just each upstream cluster.
+	// If not set, defaults to false.
+	RouteStats bool
+
+	// MergeVirtualHosts merges virtual hosts whose routes, and other
+	// settings, are identical into one serving the domains of each,
+	// reducing the size of the route configuration sent to Envoy.
+	// Envoy's statistics for the merged virtual hosts are recorded
+	// under the name of the first of them.
+	// If not set, defaults to false.
+	MergeVirtualHosts bool
+
+	// ResponseTimeout is the response timeout of routes which do
+	// not set their own. Routes opt out of it with a response
+	// timeout of infinity.
+	// If not set, Envoy's default response timeout applies.
+	ResponseTimeout time.Duration
+}
+
 type routeVisitor struct {
+	*RouteVisitorConfig
 	routes map[string]*v2.RouteConfiguration
 }
 
-func visitRoutes(root dag.Vertex) map[string]*v2.RouteConfiguration {
+func visitRoutes(root dag.Vertex, rvc *RouteVisitorConfig) map[string]*v2.RouteConfiguration {
 	headers := envoy.Headers(
 		envoy.AppendHeader("x-request-start", "t=%START_TIME(%s.%3f)%"),
 	)
 
 	rv := routeVisitor{
+		RouteVisitorConfig: rvc,
 		routes: map[string]*v2.RouteConfiguration{
 			"ingress_http": {
 				Name:                "ingress_http",
@@ -112,10 +140,56 @@ func visitRoutes(root dag.Vertex) map[string]*v2.RouteConfiguration {
 	rv.visit(root)
 	for _, v := range rv.routes {
 		sort.Stable(virtualHostsByName(v.VirtualHosts))
+		if rvc.MergeVirtualHosts {
+			v.VirtualHosts = mergeVirtualHosts(v.VirtualHosts)
+		}
 	}
 	return rv.routes
 }
 
+// strictSNIRouteName returns the name of the route configuration
+// of the secure virtual host fqdn, if it has its own.
+func strictSNIRouteName(fqdn string) string {
+	return ENVOY_HTTPS_LISTENER + "/" + fqdn
+}
+
+// ownRouteConfig reports whether the routes of vh are served from
+// a route configuration of its own, rather than ingress_https.
+// Connections of a virtual host which enforces a policy on its
+// filter chain must not reach the routes of another host by
+// naming it in the Host header, nor other connections reach its
+// routes, so its routes are isolated.
+func ownRouteConfig(vh *dag.SecureVirtualHost) bool {
+	return vh.StrictSNI || vh.DownstreamValidation != nil || vh.AuthorizationServer != nil || len(vh.JWTProviders) > 0 || vh.RateLimitFailOpen != nil
+}
+
+// mergeVirtualHosts returns vhosts with each virtual host which is
+// identical to an earlier one, but for its name and domains, merged
+// into the earlier one by adding its domains.
+func mergeVirtualHosts(vhosts []*envoy_api_v2_route.VirtualHost) []*envoy_api_v2_route.VirtualHost {
+	var merged []*envoy_api_v2_route.VirtualHost
+	seen := make(map[string]*envoy_api_v2_route.VirtualHost)
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	for _, vh := range vhosts {
+		key := *vh
+		key.Name, key.Domains = "", nil
+		buf.Reset()
+		if err := buf.Marshal(&key); err != nil {
+			// cannot happen for a valid virtual host, keep it as is.
+			merged = append(merged, vh)
+			continue
+		}
+		if first, ok := seen[string(buf.Bytes())]; ok {
+			first.Domains = append(first.Domains, vh.Domains...)
+			continue
+		}
+		seen[string(buf.Bytes())] = vh
+		merged = append(merged, vh)
+	}
+	return merged
+}
+
 func (v *routeVisitor) visit(vertex dag.Vertex) {
 	switch l := vertex.(type) {
 	case *dag.Listener:
@@ -123,8 +197,8 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 			switch vh := vertex.(type) {
 			case *dag.VirtualHost:
 				var routes []*envoy_api_v2_route.Route
-				vh.Visit(func(v dag.Vertex) {
-					route, ok := v.(*dag.Route)
+				vh.Visit(func(vertex dag.Vertex) {
+					route, ok := vertex.(*dag.Route)
 					if !ok {
 						return
 					}
@@ -134,41 +208,48 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 						// TODO(dfc) if we ensure the builder never returns a dag.Route connected
 						// to a SecureVirtualHost that requires upgrade, this logic can move to
 						// envoy.RouteRoute.
-						routes = append(routes, &envoy_api_v2_route.Route{
-							Match:  match,
-							Action: envoy.UpgradeHTTPS(),
-						})
+						routes = append(routes, v.upgradeRoute(vh, route, match))
 						return
 					}
-					routes = append(routes, &envoy_api_v2_route.Route{
-						Match:  match,
-						Action: envoy.RouteRoute(route),
-					})
+					routes = append(routes, v.route(vh, route))
 				})
 				if len(routes) < 1 {
 					return
 				}
 				sortRoutes(routes)
 				vhost := envoy.VirtualHost(vh.Name, routes...)
+				v.addVirtualClusters(vhost)
 				v.routes["ingress_http"].VirtualHosts = append(v.routes["ingress_http"].VirtualHosts, vhost)
 			case *dag.SecureVirtualHost:
 				var routes []*envoy_api_v2_route.Route
-				vh.Visit(func(v dag.Vertex) {
-					route, ok := v.(*dag.Route)
+				vh.Visit(func(vertex dag.Vertex) {
+					route, ok := vertex.(*dag.Route)
 					if !ok {
 						return
 					}
 
-					routes = append(routes, &envoy_api_v2_route.Route{
-						Match:  envoy.RouteMatch(route),
-						Action: envoy.RouteRoute(route),
-					})
+					routes = append(routes, v.route(&vh.VirtualHost, route))
 				})
 				if len(routes) < 1 {
 					return
 				}
 				sortRoutes(routes)
 				vhost := envoy.VirtualHost(vh.VirtualHost.Name, routes...)
+				v.addVirtualClusters(vhost)
+				if vh.ForwardTLSAttributes {
+					vhost.RequestHeadersToAdd = envoy.TLSAttributeHeaders()
+				}
+				if ownRouteConfig(vh) {
+					// the connections of this host are routed only to it,
+					// requests for other hosts are misdirected.
+					name := strictSNIRouteName(vh.VirtualHost.Name)
+					v.routes[name] = &v2.RouteConfiguration{
+						Name:                name,
+						RequestHeadersToAdd: v.routes["ingress_https"].RequestHeadersToAdd,
+						VirtualHosts:        []*envoy_api_v2_route.VirtualHost{vhost, envoy.MisdirectedVirtualHost()},
+					}
+					return
+				}
 				v.routes["ingress_https"].VirtualHosts = append(v.routes["ingress_https"].VirtualHosts, vhost)
 			default:
 				// recurse
@@ -181,6 +262,118 @@ func (v *routeVisitor) visit(vertex dag.Vertex) {
 	}
 }
 
+// route returns the Envoy route for a route of vh which forwards
+// the request upstream, or responds directly if the route is degraded
+// or redirects.
+func (v *routeVisitor) route(vh *dag.VirtualHost, route *dag.Route) *envoy_api_v2_route.Route {
+	r := &envoy_api_v2_route.Route{
+		Name:     v.routeName(route),
+		Match:    envoy.RouteMatch(route),
+		Action:   envoy.RouteRoute(route),
+		Metadata: routeMetadata(vh, route),
+
+		RequestHeadersToAdd:     envoy.HeadersToAdd(route.RequestHeadersPolicy),
+		RequestHeadersToRemove:  envoy.HeadersToRemove(route.RequestHeadersPolicy),
+		ResponseHeadersToAdd:    envoy.HeadersToAdd(route.ResponseHeadersPolicy),
+		ResponseHeadersToRemove: envoy.HeadersToRemove(route.ResponseHeadersPolicy),
+	}
+	if route.AuthDisabled {
+		r.TypedPerFilterConfig = envoy.ExtAuthzDisabled()
+	}
+	switch {
+	case route.DirectResponse != nil && route.DirectResponse.Body != "":
+		r.Action = envoy.DirectResponseBody(route.DirectResponse.StatusCode, route.DirectResponse.Body)
+	case route.DirectResponse != nil:
+		r.Action = envoy.DirectResponse(route.DirectResponse.StatusCode)
+	case route.Redirect != nil:
+		r.Action = envoy.RouteRedirect(route.Redirect)
+	default:
+		if ra := r.GetRoute(); ra.Timeout == nil && v.ResponseTimeout > 0 {
+			ra.Timeout = protobuf.Duration(v.ResponseTimeout)
+		}
+	}
+	return r
+}
+
+// upgradeRoute returns the Envoy route for a route of vh which
+// redirects insecure requests to HTTPS.
+func (v *routeVisitor) upgradeRoute(vh *dag.VirtualHost, route *dag.Route, match *envoy_api_v2_route.RouteMatch) *envoy_api_v2_route.Route {
+	r := &envoy_api_v2_route.Route{
+		Name:   v.routeName(route),
+		Match:  match,
+		Action: envoy.UpgradeHTTPS(),
+	}
+	if p := vh.HTTPSRedirectPolicy; p != nil {
+		r.Action = envoy.HTTPSRedirect(p.StripPath, p.StripQuery)
+		for _, h := range p.ResponseHeaders {
+			r.ResponseHeadersToAdd = append(r.ResponseHeadersToAdd, envoy.SetHeader(h.Name, h.Value))
+		}
+	}
+	return r
+}
+
+// routeName returns the Envoy route name for route if per route
+// statistics are enabled.
+func (v *routeVisitor) routeName(route *dag.Route) string {
+	if !v.RouteStats {
+		return ""
+	}
+	return route.Name
+}
+
+// addVirtualClusters adds a virtual cluster for each of the named
+// routes of vhost if per route statistics are enabled.
+func (v *routeVisitor) addVirtualClusters(vhost *envoy_api_v2_route.VirtualHost) {
+	if !v.RouteStats {
+		return
+	}
+	vhost.VirtualClusters = envoy.VirtualClusters(vhost.Routes...)
+}
+
+// routeMetadata returns the metadata of route, merged with the
+// metadata enforcing the virtual host's request header allow list,
+// if it has one, and the route's downstream protocol policy, or
+// failing that the virtual host's, if either has one.
+func routeMetadata(vh *dag.VirtualHost, route *dag.Route) *envoy_api_v2_core.Metadata {
+	md := envoy.RouteMetadata(route)
+	if len(vh.RequestHeadersAllowList) > 0 {
+		md = mergeMetadata(md, envoy.RequestHeadersAllowListMetadata(vh.RequestHeadersAllowList))
+	}
+	if dpp := downstreamProtocolPolicy(vh, route); dpp != nil {
+		md = mergeMetadata(md, envoy.DownstreamProtocolMetadata(dpp.Protocol, dpp.Status))
+	}
+	return md
+}
+
+// mergeMetadata adds the keys of each filter's metadata in
+// from to md. The builder reserves the filters Contour attaches
+// metadata for, so the two never share a key.
+func mergeMetadata(md, from *envoy_api_v2_core.Metadata) *envoy_api_v2_core.Metadata {
+	if md == nil {
+		return from
+	}
+	for filter, s := range from.FilterMetadata {
+		existing, ok := md.FilterMetadata[filter]
+		if !ok {
+			md.FilterMetadata[filter] = s
+			continue
+		}
+		for k, v := range s.Fields {
+			existing.Fields[k] = v
+		}
+	}
+	return md
+}
+
+// downstreamProtocolPolicy returns the downstream protocol policy
+// of route, or of vh if route has none.
+func downstreamProtocolPolicy(vh *dag.VirtualHost, route *dag.Route) *dag.DownstreamProtocolPolicy {
+	if route.DownstreamProtocolPolicy != nil {
+		return route.DownstreamProtocolPolicy
+	}
+	return vh.DownstreamProtocolPolicy
+}
+
 type headerMatcherByName []*envoy_api_v2_route.HeaderMatcher
 
 func (h headerMatcherByName) Len() int      { return len(h) }
@@ -210,8 +403,9 @@ func (v virtualHostsByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
 
 // sortRoutes sorts the given Route slice in place. Routes are ordered
 // first by longest prefix (or regex), then by the length of the
-// HeaderMatch slice (if any). The HeaderMatch slice is also ordered
-// by the matching header name.
+// HeaderMatch slice (if any), then by the length of the
+// QueryParameterMatcher slice (if any). The HeaderMatch slice is also
+// ordered by the matching header name.
 func sortRoutes(routes []*envoy_api_v2_route.Route) {
 	for _, r := range routes {
 		sort.Stable(headerMatcherByName(r.Match.Headers))
@@ -221,7 +415,8 @@ func sortRoutes(routes []*envoy_api_v2_route.Route) {
 }
 
 // longestRouteByHeaders compares the HeaderMatcher slices for lhs and rhs and
-// returns true if lhs is longer.
+// returns true if lhs is longer. Routes with as many HeaderMatchers are
+// compared by their QueryParameterMatchers in the same way.
 func longestRouteByHeaders(lhs, rhs *envoy_api_v2_route.Route) bool {
 	if len(lhs.Match.Headers) == len(rhs.Match.Headers) {
 		pair := make([]*envoy_api_v2_route.HeaderMatcher, 2)
@@ -234,6 +429,8 @@ func longestRouteByHeaders(lhs, rhs *envoy_api_v2_route.Route) bool {
 				return true
 			}
 		}
+
+		return len(lhs.Match.QueryParameters) > len(rhs.Match.QueryParameters)
 	}
 
 	return len(lhs.Match.Headers) > len(rhs.Match.Headers)
@@ -245,6 +442,24 @@ func (l longestRouteFirst) Len() int      { return len(l) }
 func (l longestRouteFirst) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
 func (l longestRouteFirst) Less(i, j int) bool {
 	switch a := l[i].Match.PathSpecifier.(type) {
+	case *envoy_api_v2_route.RouteMatch_Path:
+		switch b := l[j].Match.PathSpecifier.(type) {
+		case *envoy_api_v2_route.RouteMatch_Path:
+			cmp := strings.Compare(a.Path, b.Path)
+			switch cmp {
+			case 1:
+				return true
+			case -1:
+				return false
+			case 0:
+				return longestRouteByHeaders(l[i], l[j])
+			}
+
+			panic("bad compare")
+		default:
+			// Exact paths sort ahead of regexes and prefixes.
+			return true
+		}
 	case *envoy_api_v2_route.RouteMatch_Prefix:
 		switch b := l[j].Match.PathSpecifier.(type) {
 		case *envoy_api_v2_route.RouteMatch_Prefix:
@@ -275,6 +490,24 @@ func (l longestRouteFirst) Less(i, j int) bool {
 				return longestRouteByHeaders(l[i], l[j])
 			}
 
+			panic("bad compare")
+		case *envoy_api_v2_route.RouteMatch_Prefix:
+			return true
+		}
+	case *envoy_api_v2_route.RouteMatch_SafeRegex:
+		switch b := l[j].Match.PathSpecifier.(type) {
+		case *envoy_api_v2_route.RouteMatch_SafeRegex:
+			cmp := strings.Compare(a.SafeRegex.GetRegex(), b.SafeRegex.GetRegex())
+			switch cmp {
+			case 1:
+				// Sort longest regex first.
+				return true
+			case -1:
+				return false
+			case 0:
+				return longestRouteByHeaders(l[i], l[j])
+			}
+
 			panic("bad compare")
 		case *envoy_api_v2_route.RouteMatch_Prefix:
 			return true
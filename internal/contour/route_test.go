@@ -1876,7 +1876,7 @@ func TestRouteVisit(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			root := buildDAG(t, tc.objs...)
-			got := visitRoutes(root)
+			got := visitRoutes(root, &RouteVisitorConfig{})
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -1927,6 +1927,86 @@ func TestSortLongestRouteFirst(t *testing.T) {
 				Match: envoy.RoutePrefix("/"),
 			}},
 		},
+		"safe regex sorts before prefix": {
+			routes: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePrefix("/api"),
+			}, {
+				Match: envoy.RouteSafeRegex("/api/v[0-9]+"),
+			}, {
+				Match: envoy.RouteSafeRegex("/api/v[0-9]+/.*"),
+			}},
+			want: []*envoy_api_v2_route.Route{{
+				Match: envoy.RouteSafeRegex("/api/v[0-9]+/.*"),
+			}, {
+				Match: envoy.RouteSafeRegex("/api/v[0-9]+"),
+			}, {
+				Match: envoy.RoutePrefix("/api"),
+			}},
+		},
+		"exact path sorts before regex and prefix": {
+			routes: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePrefix("/api"),
+			}, {
+				Match: envoy.RouteRegex("/v1/.+"),
+			}, {
+				Match: envoy.RoutePath("/api/"),
+			}, {
+				Match: envoy.RoutePath("/api/v1"),
+			}},
+			want: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePath("/api/v1"),
+			}, {
+				Match: envoy.RoutePath("/api/"),
+			}, {
+				Match: envoy.RouteRegex("/v1/.+"),
+			}, {
+				Match: envoy.RoutePrefix("/api"),
+			}},
+		},
+		"exact path sorts before safe regex and prefix": {
+			routes: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePrefix("/healthz"),
+			}, {
+				Match: envoy.RouteSafeRegex("/healthz/.+"),
+			}, {
+				Match: envoy.RoutePath("/healthz"),
+			}},
+			want: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePath("/healthz"),
+			}, {
+				Match: envoy.RouteSafeRegex("/healthz/.+"),
+			}, {
+				Match: envoy.RoutePrefix("/healthz"),
+			}},
+		},
+		"more query parameters sort before less": {
+			routes: []*envoy_api_v2_route.Route{{
+				Match: envoy.RoutePrefix("/"),
+			}, {
+				Match: &envoy_api_v2_route.RouteMatch{
+					PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+						Prefix: "/",
+					},
+					QueryParameters: []*envoy_api_v2_route.QueryParameterMatcher{{
+						Name:  "flag",
+						Value: "beta",
+					}},
+				},
+			}},
+			want: []*envoy_api_v2_route.Route{{
+				Match: &envoy_api_v2_route.RouteMatch{
+					PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+						Prefix: "/",
+					},
+					QueryParameters: []*envoy_api_v2_route.QueryParameterMatcher{{
+						Name:  "flag",
+						Value: "beta",
+					}},
+				},
+			}, {
+				Match: envoy.RoutePrefix("/"),
+			}},
+		},
 		"more headers sort before less": {
 			routes: []*envoy_api_v2_route.Route{{
 				Match: envoy.RoutePrefix("/"),
@@ -2052,6 +2132,33 @@ func TestSortLongestRouteFirst(t *testing.T) {
 	}
 }
 
+func TestMergeVirtualHosts(t *testing.T) {
+	vhost := func(name, cluster string) *envoy_api_v2_route.VirtualHost {
+		return envoy.VirtualHost(name, envoy.Route(envoy.RoutePrefix("/"), routecluster(cluster)))
+	}
+
+	vhosts := mergeVirtualHosts([]*envoy_api_v2_route.VirtualHost{
+		vhost("a.example.com", "default/kuard/8080/da39a3ee5e"),
+		vhost("b.example.com", "default/other/8080/da39a3ee5e"),
+		vhost("c.example.com", "default/kuard/8080/da39a3ee5e"),
+		vhost("d.example.com", "default/kuard/8080/da39a3ee5e"),
+	})
+
+	// the routes are unchanged, only the domains are merged.
+	got := make(map[string][]string)
+	for _, vh := range vhosts {
+		got[vh.Name] = vh.Domains
+	}
+	assert.Equal(t, map[string][]string{
+		"a.example.com": {
+			"a.example.com", "a.example.com:*",
+			"c.example.com", "c.example.com:*",
+			"d.example.com", "d.example.com:*",
+		},
+		"b.example.com": {"b.example.com", "b.example.com:*"},
+	}, got)
+}
+
 func routecluster(cluster string) *envoy_api_v2_route.Route_Route {
 	return &envoy_api_v2_route.Route_Route{
 		Route: &envoy_api_v2_route.RouteAction{
@@ -95,13 +95,25 @@ func (v *secretVisitor) visit(vertex dag.Vertex) {
 	switch svh := vertex.(type) {
 	case *dag.SecureVirtualHost:
 		if svh.Secret != nil {
-			name := envoy.Secretname(svh.Secret)
-			if _, ok := v.secrets[name]; !ok {
-				s := envoy.Secret(svh.Secret)
-				v.secrets[s.Name] = s
-			}
+			v.addSecret(svh.Secret)
+		}
+		// the clusters of the vhost's routes may present
+		// client certificates.
+		svh.Visit(v.visit)
+	case *dag.Cluster:
+		if svh.ClientCertificate != nil {
+			v.addSecret(svh.ClientCertificate)
 		}
 	default:
 		vertex.Visit(v.visit)
 	}
 }
+
+// addSecret adds secret to the visitor's secrets, if not already present.
+func (v *secretVisitor) addSecret(secret *dag.Secret) {
+	name := envoy.Secretname(secret)
+	if _, ok := v.secrets[name]; !ok {
+		s := envoy.Secret(secret)
+		v.secrets[s.Name] = s
+	}
+}
@@ -464,6 +464,49 @@ func TestSecretVisit(t *testing.T) {
 				secret("default/secret-b/5fe9f5601b", secretdata(CERTIFICATE_2, RSA_PRIVATE_KEY)),
 			),
 		},
+		"httpproxy service with client certificate": {
+			objs: []interface{}{
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "backend",
+						Namespace: "default",
+						Annotations: map[string]string{
+							"projectcontour.io/upstream-protocol.tls": "443",
+						},
+					},
+					Spec: v1.ServiceSpec{
+						Ports: []v1.ServicePort{{
+							Name:       "https",
+							Protocol:   "TCP",
+							Port:       443,
+							TargetPort: intstr.FromInt(8443),
+						}},
+					},
+				},
+				&projcontour.HTTPProxy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "simple",
+						Namespace: "default",
+					},
+					Spec: projcontour.HTTPProxySpec{
+						VirtualHost: &projcontour.VirtualHost{
+							Fqdn: "www.example.com",
+						},
+						Routes: []projcontour.Route{{
+							Services: []projcontour.Service{{
+								Name:              "backend",
+								Port:              443,
+								ClientCertificate: "client",
+							}},
+						}},
+					},
+				},
+				tlssecret("default", "client", secretdata(CERTIFICATE, RSA_PRIVATE_KEY)),
+			},
+			want: secretmap(
+				secret("default/client/28337303ac", secretdata(CERTIFICATE, RSA_PRIVATE_KEY)),
+			),
+		},
 	}
 
 	for name, tc := range tests {
@@ -15,11 +15,14 @@ package dag
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"k8s.io/api/networking/v1beta1"
 )
 
@@ -53,6 +56,8 @@ var annotationsByKind = map[string]map[string]struct{}{
 		"kubernetes.io/ingress.class":                    {},
 		"projectcontour.io/ingress.class":                {},
 		"projectcontour.io/num-retries":                  {},
+		"projectcontour.io/per-try-timeout":              {},
+		"projectcontour.io/request-timeout":              {},
 		"projectcontour.io/response-timeout":             {},
 		"projectcontour.io/retry-on":                     {},
 		"projectcontour.io/tls-minimum-protocol-version": {},
@@ -68,9 +73,11 @@ var annotationsByKind = map[string]map[string]struct{}{
 		"projectcontour.io/upstream-protocol.tls": {},
 	},
 	"HTTPProxy": {
+		"kubernetes.io/ingress.class":     {},
 		"projectcontour.io/ingress.class": {},
 	},
 	"IngressRoute": {
+		"kubernetes.io/ingress.class":     {},
 		"projectcontour.io/ingress.class": {},
 	},
 }
@@ -93,6 +100,31 @@ func validAnnotationForKind(kind string, key string) bool {
 	return true
 }
 
+// annotationIsKnownForAnyKind returns true if the annotation is
+// valid for at least one of the kinds Contour reads annotations of.
+func annotationIsKnownForAnyKind(key string) bool {
+	for kind := range annotationsByKind {
+		if validAnnotationForKind(kind, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidAnnotations returns the known annotations of the Object which
+// are not valid for its kind, and the Contour annotations which are
+// not known at all, sorted by key.
+func invalidAnnotations(kind string, o Object) []string {
+	var keys []string
+	for key := range o.GetObjectMeta().GetAnnotations() {
+		if annotationIsKnown(key) && !validAnnotationForKind(kind, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // compatAnnotation checks the Object for the given annotation, first with the
 // "projectcontour.io/" prefix, and then with the "contour.heptio.com/" prefix
 // if that is not found.
@@ -106,6 +138,19 @@ func compatAnnotation(o Object, key string) string {
 	return a["contour.heptio.com/"+key]
 }
 
+// deprecatedAnnotations returns the deprecated "contour.heptio.com/"
+// annotations of the Object, sorted by key.
+func deprecatedAnnotations(o Object) []string {
+	var keys []string
+	for key := range o.GetObjectMeta().GetAnnotations() {
+		if strings.HasPrefix(key, "contour.heptio.com/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // parseUInt32 parses the supplied string as if it were a uint32.
 // If the value is not present, or malformed, or outside uint32's range, zero is returned.
 func parseUInt32(s string) uint32 {
@@ -180,12 +225,16 @@ func perTryTimeout(i *v1beta1.Ingress) time.Duration {
 	return parseTimeout(compatAnnotation(i, "per-try-timeout"))
 }
 
-// ingressClass returns the first matching ingress class for the following
-// annotations:
+// ingressClass returns the ingress class of an HTTPProxy's
+// spec.ingressClassName, or else the first matching ingress class for
+// the following annotations:
 // 1. projectcontour.io/ingress.class
 // 2. contour.heptio.com/ingress.class
 // 3. kubernetes.io/ingress.class
 func ingressClass(o Object) string {
+	if p, ok := o.(*projectcontour.HTTPProxy); ok && p.Spec.IngressClassName != "" {
+		return p.Spec.IngressClassName
+	}
 	a := o.GetObjectMeta().GetAnnotations()
 	if class, ok := a["projectcontour.io/ingress.class"]; ok {
 		return class
@@ -199,6 +248,34 @@ func ingressClass(o Object) string {
 	return ""
 }
 
+// matchesIngressClass returns true if o has no ingress class, or its
+// ingress class is one of the comma separated classes. If regex is
+// true each of the classes is a regular expression which must match
+// the whole of the ingress class.
+func matchesIngressClass(o Object, classes string, regex bool) bool {
+	class := ingressClass(o)
+	if class == "" {
+		return true
+	}
+	for _, c := range strings.Split(classes, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !regex {
+			if c == class {
+				return true
+			}
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + c + ")$")
+		if err == nil && re.MatchString(class) {
+			return true
+		}
+	}
+	return false
+}
+
 // MinProtoVersion returns the TLS protocol version specified by an ingress annotation
 // or default if non present.
 func MinProtoVersion(version string) envoy_api_v2_auth.TlsParameters_TlsProtocol {
@@ -213,6 +290,24 @@ func MinProtoVersion(version string) envoy_api_v2_auth.TlsParameters_TlsProtocol
 	}
 }
 
+// ParseTLSProtocolVersion returns the TLS protocol version named by
+// version, or envoy_api_v2_auth.TlsParameters_TLS_AUTO if version is
+// blank. It returns false if version names no supported version.
+func ParseTLSProtocolVersion(version string) (envoy_api_v2_auth.TlsParameters_TlsProtocol, bool) {
+	switch strings.TrimSpace(version) {
+	case "":
+		return envoy_api_v2_auth.TlsParameters_TLS_AUTO, true
+	case "1.1":
+		return envoy_api_v2_auth.TlsParameters_TLSv1_1, true
+	case "1.2":
+		return envoy_api_v2_auth.TlsParameters_TLSv1_2, true
+	case "1.3":
+		return envoy_api_v2_auth.TlsParameters_TLSv1_3, true
+	default:
+		return envoy_api_v2_auth.TlsParameters_TLS_AUTO, false
+	}
+}
+
 // maxConnections returns the value of the first matching max-connections
 // annotation for the following annotations:
 // 1. projectcontour.io/max-connections
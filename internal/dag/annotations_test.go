@@ -382,6 +382,159 @@ func TestAnnotationCompat(t *testing.T) {
 	}
 }
 
+func TestDeprecatedAnnotations(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"contour.heptio.com/upstream-protocol.h2": "80",
+				"projectcontour.io/max-connections":       "100",
+				"contour.heptio.com/max-connections":      "100",
+			},
+		},
+	}
+	got := deprecatedAnnotations(svc)
+	want := []string{
+		"contour.heptio.com/max-connections",
+		"contour.heptio.com/upstream-protocol.h2",
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestMatchesIngressClass(t *testing.T) {
+	ingress := func(class string) *v1beta1.Ingress {
+		i := &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+		}
+		if class != "" {
+			i.Annotations["kubernetes.io/ingress.class"] = class
+		}
+		return i
+	}
+
+	tests := map[string]struct {
+		ingress *v1beta1.Ingress
+		classes string
+		regex   bool
+		want    bool
+	}{
+		"no ingress class": {
+			ingress: ingress(""),
+			classes: "contour",
+			want:    true,
+		},
+		"single class": {
+			ingress: ingress("contour"),
+			classes: "contour",
+			want:    true,
+		},
+		"other class": {
+			ingress: ingress("nginx"),
+			classes: "contour",
+			want:    false,
+		},
+		"one of several classes": {
+			ingress: ingress("nginx"),
+			classes: "contour, nginx,traefik",
+			want:    true,
+		},
+		"none of several classes": {
+			ingress: ingress("haproxy"),
+			classes: "contour,nginx,traefik",
+			want:    false,
+		},
+		"empty entry does not match": {
+			ingress: ingress(" "),
+			classes: "contour,,nginx",
+			want:    false,
+		},
+		"class is not a regex without regex": {
+			ingress: ingress("nginx-internal"),
+			classes: "nginx-.*",
+			want:    false,
+		},
+		"regex": {
+			ingress: ingress("nginx-internal"),
+			classes: "contour,nginx-.*",
+			regex:   true,
+			want:    true,
+		},
+		"regex must match the whole class": {
+			ingress: ingress("legacy-nginx-internal"),
+			classes: "nginx-.*",
+			regex:   true,
+			want:    false,
+		},
+		"alternation must match the whole class": {
+			ingress: ingress("contour-internal"),
+			classes: "contour|nginx",
+			regex:   true,
+			want:    false,
+		},
+		"invalid regex does not match": {
+			ingress: ingress("nginx"),
+			classes: "nginx(",
+			regex:   true,
+			want:    false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := matchesIngressClass(tc.ingress, tc.classes, tc.regex)
+			if got != tc.want {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyIngressClass(t *testing.T) {
+	tests := map[string]struct {
+		annotation string
+		spec       string
+		want       string
+	}{
+		"neither": {
+			want: "",
+		},
+		"annotation": {
+			annotation: "nginx",
+			want:       "nginx",
+		},
+		"spec": {
+			spec: "contour",
+			want: "contour",
+		},
+		"spec takes precedence": {
+			annotation: "nginx",
+			spec:       "contour",
+			want:       "contour",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &projectcontour.HTTPProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+				},
+				Spec: projectcontour.HTTPProxySpec{
+					IngressClassName: tc.spec,
+				},
+			}
+			if tc.annotation != "" {
+				p.Annotations["projectcontour.io/ingress.class"] = tc.annotation
+			}
+			got := ingressClass(p)
+			if got != tc.want {
+				t.Fatalf("got: %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestAnnotationKindValidation(t *testing.T) {
 	type status struct {
 		known bool
@@ -461,3 +614,26 @@ func TestAnnotationKindValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestInvalidAnnotations(t *testing.T) {
+	proxy := &projectcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"projectcontour.io/ingress.class":     "contour",
+				"kubernetes.io/ingress.class":         "contour",
+				"projectcontour.io/websocket-route":   "/",
+				"contour.heptio.com/websocket-routes": "/",
+				"example.com/owner":                   "team",
+			},
+		},
+	}
+	got := invalidAnnotations(toKind(proxy), proxy)
+	want := []string{
+		"contour.heptio.com/websocket-routes",
+		"projectcontour.io/websocket-route",
+	}
+	assert.Equal(t, want, got)
+
+	assert.Equal(t, true, annotationIsKnownForAnyKind("contour.heptio.com/websocket-routes"))
+	assert.Equal(t, false, annotationIsKnownForAnyKind("projectcontour.io/websocket-route"))
+}
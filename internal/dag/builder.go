@@ -14,15 +14,21 @@
 package dag
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	"github.com/google/go-cmp/cmp"
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
@@ -39,6 +45,28 @@ type Builder struct {
 	// permitInsecure field in IngressRoute.
 	DisablePermitInsecure bool
 
+	// StrictAnnotations adds a warning to the status of each
+	// IngressRoute and HTTPProxy with a Contour annotation which
+	// is unknown, or not valid for its kind.
+	StrictAnnotations bool
+
+	// Clock returns the current time, against which route
+	// active windows are evaluated. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// RateLimitService, if its Name is set, is the Service
+	// Envoy's rate limit filter consults.
+	RateLimitService RateLimitServiceConfig
+
+	// DefaultCircuitBreakers are the circuit breaking limits of
+	// each Service which sets none with its annotations.
+	DefaultCircuitBreakers CircuitBreakers
+
+	// StrictUpstreamTLSNamespaces are the namespaces whose routes
+	// must connect to their services over TLS, validating the
+	// services' certificates. "*" matches every namespace.
+	StrictUpstreamTLSNamespaces []string
+
 	services map[servicemeta]*Service
 	secrets  map[Meta]*Secret
 
@@ -47,13 +75,39 @@ type Builder struct {
 
 	orphaned map[Meta]bool
 
+	// nextChange is the earliest time at which a route
+	// active window opens or closes.
+	nextChange time.Time
+
+	// inactive records the routes which stand in for
+	// routes outside their active window.
+	inactive map[*Route]bool
+
 	StatusWriter
 }
 
+// RateLimitServiceConfig names the Service which implements Envoy's
+// rate limit service, and how Envoy calls it.
+type RateLimitServiceConfig struct {
+	Namespace string
+	Name      string
+	Port      int
+
+	// Domain, Timeout and FailureModeDeny are as
+	// for RateLimitService. If not set, Domain
+	// defaults to "contour".
+	Domain          string
+	Timeout         time.Duration
+	FailureModeDeny bool
+}
+
 // Build builds a new DAG.
 func (b *Builder) Build() *DAG {
 	b.reset()
 
+	// fetch the contents of any newly referenced secrets.
+	b.Source.fetchSecrets()
+
 	// setup secure vhosts if there is a matching secret
 	// we do this first so that the set of active secure vhosts is stable
 	// during computeIngresses.
@@ -78,6 +132,9 @@ func (b *Builder) reset() {
 	b.securevirtualhosts = make(map[string]*SecureVirtualHost)
 
 	b.statuses = make(map[Meta]Status, len(b.statuses))
+
+	b.nextChange = time.Time{}
+	b.inactive = make(map[*Route]bool)
 }
 
 // lookupService returns a Service that matches the Meta and Port of the Kubernetes' Service.
@@ -115,6 +172,26 @@ func (b *Builder) lookupService(m Meta, port intstr.IntOrString) *Service {
 	return nil
 }
 
+// lookupProxyService returns the Service, and the port, referenced by
+// an HTTPProxy service in namespace, or nil if it is missing. The port
+// is referenced by name if the service has a port name, otherwise by
+// number. An error is returned if the port is invalid, or if both a
+// name and a number are supplied and they identify different ports.
+func (b *Builder) lookupProxyService(namespace string, service projcontour.Service) (*Service, intstr.IntOrString, error) {
+	port := intstr.FromInt(service.Port)
+	switch {
+	case service.PortName != "":
+		port = intstr.FromString(service.PortName)
+	case service.Port < 1 || service.Port > 65535:
+		return nil, port, fmt.Errorf("service %q: port must be in the range 1-65535", service.Name)
+	}
+	s := b.lookupService(Meta{name: service.Name, namespace: namespace}, port)
+	if s != nil && service.PortName != "" && service.Port != 0 && int(s.Port) != service.Port {
+		return nil, port, fmt.Errorf("service %q: port %d does not match port %q, which is port %d", service.Name, service.Port, service.PortName, s.Port)
+	}
+	return s, port, nil
+}
+
 func (b *Builder) addService(svc *v1.Service, port *v1.ServicePort) *Service {
 	s := &Service{
 		Name:        svc.Name,
@@ -122,42 +199,78 @@ func (b *Builder) addService(svc *v1.Service, port *v1.ServicePort) *Service {
 		ServicePort: port,
 
 		Protocol:           upstreamProtocol(svc, port),
-		MaxConnections:     maxConnections(svc),
-		MaxPendingRequests: maxPendingRequests(svc),
-		MaxRequests:        maxRequests(svc),
-		MaxRetries:         maxRetries(svc),
+		GRPC:               isGRPCPort(port),
+		MaxConnections:     orDefault(maxConnections(svc), b.DefaultCircuitBreakers.MaxConnections),
+		MaxPendingRequests: orDefault(maxPendingRequests(svc), b.DefaultCircuitBreakers.MaxPendingRequests),
+		MaxRequests:        orDefault(maxRequests(svc), b.DefaultCircuitBreakers.MaxRequests),
+		MaxRetries:         orDefault(maxRetries(svc), b.DefaultCircuitBreakers.MaxRetries),
 		ExternalName:       externalName(svc),
 	}
 	b.services[s.toMeta()] = s
 	return s
 }
 
+// orDefault returns v, or def if v is zero.
+func orDefault(v, def uint32) uint32 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
 func upstreamProtocol(svc *v1.Service, port *v1.ServicePort) string {
 	up := parseUpstreamProtocols(svc.Annotations)
 	protocol := up[port.Name]
 	if protocol == "" {
 		protocol = up[strconv.Itoa(int(port.Port))]
 	}
+	if protocol == "" && isGRPCPort(port) {
+		// gRPC requires HTTP/2, which without TLS is h2c.
+		protocol = "h2c"
+	}
 	return protocol
 }
 
+// isGRPCPort returns true if port is named "grpc", or
+// named with the prefix "grpc-".
+func isGRPCPort(port *v1.ServicePort) bool {
+	return port.Name == "grpc" || strings.HasPrefix(port.Name, "grpc-")
+}
+
 // lookupSecret returns a Secret if present or nil if the underlying kubernetes
 // secret fails validation or is missing.
 func (b *Builder) lookupSecret(m Meta, validate func(*v1.Secret) bool) *Secret {
 	sec, ok := b.Source.secrets[m]
+	var expires time.Time
 	if !ok {
-		return nil
+		// a deleted Secret is served until its grace period ends.
+		ds, ok := b.Source.deletedSecrets[m]
+		if !ok || !b.now().Before(ds.expires) {
+			return nil
+		}
+		b.changeAt(ds.expires)
+		sec, expires = ds.secret, ds.expires
 	}
 	if !validate(sec) {
 		return nil
 	}
 	s := &Secret{
-		Object: sec,
+		Object:  sec,
+		Expires: expires,
 	}
 	b.secrets[toMeta(sec)] = s
 	return s
 }
 
+// deletedSecretWarning records a warning on sw if the TLS Secret
+// named secretName has been deleted and is within its grace period.
+func deletedSecretWarning(sw *ObjectStatusWriter, secretName string, sec *Secret) {
+	if sec.Expires.IsZero() {
+		return
+	}
+	sw.SetWarning(fmt.Sprintf("TLS Secret [%s] has been deleted, its last certificate is served until %s", secretName, sec.Expires.UTC().Format(time.RFC3339)))
+}
+
 func (b *Builder) lookupVirtualHost(name string) *VirtualHost {
 	vh, ok := b.virtualhosts[name]
 	if !ok {
@@ -277,6 +390,19 @@ func (b *Builder) computeSecureVirtualhosts() {
 	}
 }
 
+// maxProtoVersion returns the maximum TLS protocol version of tls,
+// or an error if it is not supported or is below min.
+func maxProtoVersion(tls *projcontour.TLS, min envoy_api_v2_auth.TlsParameters_TlsProtocol) (envoy_api_v2_auth.TlsParameters_TlsProtocol, error) {
+	max, ok := ParseTLSProtocolVersion(tls.MaximumProtocolVersion)
+	if !ok {
+		return max, fmt.Errorf("TLS maximumProtocolVersion %q is not supported, must be 1.1, 1.2 or 1.3", tls.MaximumProtocolVersion)
+	}
+	if max != envoy_api_v2_auth.TlsParameters_TLS_AUTO && max < min {
+		return max, fmt.Errorf("TLS maximumProtocolVersion %q is below minimumProtocolVersion %q", tls.MaximumProtocolVersion, tls.MinimumProtocolVersion)
+	}
+	return max, nil
+}
+
 func (b *Builder) delegationPermitted(secret Meta, to string) bool {
 	contains := func(haystack []string, needle string) bool {
 		if len(haystack) == 1 && haystack[0] == "*" {
@@ -326,6 +452,16 @@ func (b *Builder) delegationPermitted(secret Meta, to string) bool {
 func (b *Builder) computeIngresses() {
 	// deconstruct each ingress into routes and virtualhost entries
 	for _, ing := range b.Source.ingresses {
+		if b.strictUpstreamTLS(ing.Namespace) {
+			// an Ingress cannot request validation of its
+			// services, so cannot be served from this namespace.
+			if b.Source.FieldLogger != nil {
+				b.Source.WithField("name", ing.Name).
+					WithField("namespace", ing.Namespace).
+					Warn("ingress not served, its namespace requires upstream TLS with validation")
+			}
+			continue
+		}
 
 		// rewrite the default ingress to a stock ingress rule.
 		rules := rulesFromSpec(ing.Spec)
@@ -405,8 +541,26 @@ func (b *Builder) computeIngressRoute(ir *ingressroutev1.IngressRoute) {
 		return
 	}
 
+	if field := unsupportedIngressRouteField(ir.Spec.VirtualHost); field != "" {
+		sw.SetInvalid(fmt.Sprintf("Spec.VirtualHost.%s is not supported by IngressRoute, use HTTPProxy", field))
+		return
+	}
+
 	var enforceTLS, passthrough bool
 	if tls := ir.Spec.VirtualHost.TLS; tls != nil {
+		// an unsupported minimumProtocolVersion is read as 1.1,
+		// as it always has been for IngressRoutes.
+		minVersion := MinProtoVersion(tls.MinimumProtocolVersion)
+		maxVersion, err := maxProtoVersion(tls, minVersion)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		if err := ValidateCipherSuites(tls.CipherSuites); err != nil {
+			sw.SetInvalid(fmt.Sprintf("TLS cipherSuites: %v", err))
+			return
+		}
+
 		m := splitSecret(tls.SecretName, ir.Namespace)
 		sec := b.lookupSecret(m, validSecret)
 		if sec != nil {
@@ -416,8 +570,11 @@ func (b *Builder) computeIngressRoute(ir *ingressroutev1.IngressRoute) {
 			}
 			svhost := b.lookupSecureVirtualHost(ir.Spec.VirtualHost.Fqdn)
 			svhost.Secret = sec
-			svhost.MinProtoVersion = MinProtoVersion(ir.Spec.VirtualHost.TLS.MinimumProtocolVersion)
+			svhost.MinProtoVersion = minVersion
+			svhost.MaxProtoVersion = maxVersion
+			svhost.CipherSuites = tls.CipherSuites
 			enforceTLS = true
+			deletedSecretWarning(sw, tls.SecretName, sec)
 		}
 		// passthrough is true if tls.secretName is not present, and
 		// tls.passthrough is set to true.
@@ -432,10 +589,54 @@ func (b *Builder) computeIngressRoute(ir *ingressroutev1.IngressRoute) {
 
 	if ir.Spec.TCPProxy != nil && (passthrough || enforceTLS) {
 		b.processIngressRouteTCPProxy(sw, ir, nil, host)
+		if sw.values["status"] == StatusInvalid {
+			return
+		}
 	}
 	b.processIngressRoutes(sw, ir, "", nil, host, ir.Spec.TCPProxy == nil && enforceTLS)
 }
 
+// unsupportedIngressRouteField returns the name of the first field of
+// vhost, shared with HTTPProxy, that IngressRoute does not implement,
+// or the empty string if there is none.
+func unsupportedIngressRouteField(vhost *projcontour.VirtualHost) string {
+	if tls := vhost.TLS; tls != nil {
+		switch {
+		case tls.ForwardTLSAttributes:
+			return "TLS.ForwardTLSAttributes"
+		case tls.StrictSNI:
+			return "TLS.StrictSNI"
+		case tls.ClientValidation != nil:
+			return "TLS.ClientValidation"
+		case tls.RedirectPolicy != nil:
+			return "TLS.RedirectPolicy"
+		}
+	}
+	switch {
+	case len(vhost.RequestHeadersAllowList) > 0:
+		return "RequestHeadersAllowList"
+	case vhost.DownstreamProtocolPolicy != nil:
+		return "DownstreamProtocolPolicy"
+	case vhost.Maintenance:
+		return "Maintenance"
+	case vhost.MaintenancePolicy != nil:
+		return "MaintenancePolicy"
+	case vhost.Redirect != nil:
+		return "Redirect"
+	case vhost.TrailingSlashPolicy != nil:
+		return "TrailingSlashPolicy"
+	case vhost.Authorization != nil:
+		return "Authorization"
+	case len(vhost.JWTProviders) > 0:
+		return "JWTProviders"
+	case vhost.RateLimitFailOpen != nil:
+		return "RateLimitFailOpen"
+	case vhost.CORSPolicy != nil:
+		return "CORSPolicy"
+	}
+	return ""
+}
+
 func (b *Builder) computeHTTPProxies() {
 	for _, proxy := range b.validHTTPProxies() {
 		b.computeHTTPProxy(proxy)
@@ -464,13 +665,39 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 		return
 	}
 	sw = sw.WithValue("vhost", host)
-	if strings.Contains(host, "*") {
-		sw.SetInvalid(fmt.Sprintf("Spec.VirtualHost.Fqdn %q cannot use wildcards", host))
+	if strings.Contains(host, "*") && !isWildcardFqdn(host) {
+		sw.SetInvalid(fmt.Sprintf("Spec.VirtualHost.Fqdn %q can only use a wildcard as its first label", host))
 		return
 	}
 
+	if proxy.Spec.VirtualHost.Redirect != nil {
+		if len(proxy.Spec.Routes) > 0 || len(proxy.Spec.Includes) > 0 || proxy.Spec.TCPProxy != nil {
+			sw.SetInvalid("redirect: cannot be combined with routes, includes, or tcpproxy")
+			return
+		}
+		if proxy.Spec.VirtualHost.Maintenance {
+			sw.SetInvalid("redirect: cannot be combined with maintenance")
+			return
+		}
+	}
+
 	var enforceTLS, passthrough bool
 	if tls := proxy.Spec.VirtualHost.TLS; tls != nil {
+		if _, ok := ParseTLSProtocolVersion(tls.MinimumProtocolVersion); !ok {
+			sw.SetInvalid(fmt.Sprintf("TLS minimumProtocolVersion %q is not supported, must be 1.1, 1.2 or 1.3", tls.MinimumProtocolVersion))
+			return
+		}
+		minVersion := MinProtoVersion(tls.MinimumProtocolVersion)
+		maxVersion, err := maxProtoVersion(tls, minVersion)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		if err := ValidateCipherSuites(tls.CipherSuites); err != nil {
+			sw.SetInvalid(fmt.Sprintf("TLS cipherSuites: %v", err))
+			return
+		}
+
 		// attach secrets to TLS enabled vhosts
 		m := splitSecret(tls.SecretName, proxy.Namespace)
 		sec := b.lookupSecret(m, validSecret)
@@ -481,8 +708,13 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 			}
 			svhost := b.lookupSecureVirtualHost(host)
 			svhost.Secret = sec
-			svhost.MinProtoVersion = MinProtoVersion(proxy.Spec.VirtualHost.TLS.MinimumProtocolVersion)
+			svhost.MinProtoVersion = minVersion
+			svhost.MaxProtoVersion = maxVersion
+			svhost.CipherSuites = tls.CipherSuites
+			svhost.ForwardTLSAttributes = tls.ForwardTLSAttributes
+			svhost.StrictSNI = tls.StrictSNI
 			enforceTLS = true
+			deletedSecretWarning(sw, tls.SecretName, sec)
 		}
 		// passthrough is true if tls.secretName is not present, and
 		// tls.passthrough is set to true.
@@ -495,145 +727,961 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 		}
 	}
 
-	if proxy.Spec.TCPProxy != nil && (passthrough || enforceTLS) {
-		if !b.processHTTPProxyTCPProxy(sw, proxy, nil, host) {
-			return
+	if proxy.Spec.TCPProxy != nil && (passthrough || enforceTLS) {
+		// the tcpproxy is attached before the rest of the virtual
+		// host is validated. Should that find the proxy invalid,
+		// the tcpproxy must not be served, as it would bypass the
+		// policies, such as client validation, of the virtual host.
+		defer func() {
+			if sw.values["status"] == StatusInvalid {
+				b.lookupSecureVirtualHost(host).TCPProxy = nil
+			}
+		}()
+		if !b.processHTTPProxyTCPProxy(sw, proxy, nil, host) {
+			return
+		}
+	}
+
+	insecure := b.lookupVirtualHost(host)
+	secure := b.lookupSecureVirtualHost(host)
+	if tls := proxy.Spec.VirtualHost.TLS; tls != nil && tls.ClientValidation != nil {
+		switch {
+		case !enforceTLS:
+			sw.SetInvalid("clientValidation: requires TLS with a secretName")
+			return
+		case proxy.Spec.TCPProxy != nil && tls.ClientValidation.ForwardClientCertificate != nil:
+			sw.SetInvalid("clientValidation: forwardClientCertificate cannot be combined with tcpproxy")
+			return
+		}
+		dv, err := b.lookupDownstreamValidation(tls.ClientValidation, proxy.Namespace)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		secure.DownstreamValidation = dv
+	}
+	if auth := proxy.Spec.VirtualHost.Authorization; auth != nil {
+		switch {
+		case !enforceTLS:
+			sw.SetInvalid("authorization: requires TLS with a secretName")
+			return
+		case proxy.Spec.TCPProxy != nil:
+			sw.SetInvalid("authorization: cannot be combined with tcpproxy")
+			return
+		}
+		as, err := b.authorizationServer(proxy.Namespace, auth)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		secure.AuthorizationServer = as
+	}
+	var defaultJWTProvider string
+	if providers := proxy.Spec.VirtualHost.JWTProviders; len(providers) > 0 {
+		switch {
+		case !enforceTLS:
+			sw.SetInvalid("jwtProviders: requires TLS with a secretName")
+			return
+		case proxy.Spec.TCPProxy != nil:
+			sw.SetInvalid("jwtProviders: cannot be combined with tcpproxy")
+			return
+		}
+		jps, def, err := jwtProviders(proxy.Namespace, providers, func(host string, uv *projcontour.UpstreamValidation) (*UpstreamValidation, error) {
+			return b.lookupUpstreamValidation("jwtProviders", host, uv, proxy.Namespace)
+		})
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		for _, jp := range jps {
+			// keys fetched over http are not validated.
+			if err := b.strictUpstreamTLSError(proxy.Namespace, jp.RemoteJWKS.Cluster.Upstream.Name, jp.RemoteJWKS.Cluster.UpstreamValidation); err != nil {
+				sw.SetInvalid(fmt.Sprintf("jwtProviders: provider %q: %v", jp.Name, err))
+				return
+			}
+		}
+		secure.JWTProviders = jps
+		defaultJWTProvider = def
+	}
+	if failOpen := proxy.Spec.VirtualHost.RateLimitFailOpen; failOpen != nil {
+		switch {
+		case !enforceTLS:
+			sw.SetInvalid("rateLimitFailOpen: requires TLS with a secretName")
+			return
+		case proxy.Spec.TCPProxy != nil:
+			sw.SetInvalid("rateLimitFailOpen: cannot be combined with tcpproxy")
+			return
+		}
+		secure.RateLimitFailOpen = failOpen
+	}
+	if allowList := proxy.Spec.VirtualHost.RequestHeadersAllowList; len(allowList) > 0 {
+		for _, h := range allowList {
+			if isBlank(h) {
+				sw.SetInvalid("Spec.VirtualHost.RequestHeadersAllowList cannot contain a blank header name")
+				return
+			}
+		}
+		insecure.RequestHeadersAllowList = allowList
+		secure.RequestHeadersAllowList = allowList
+		if dv := secure.DownstreamValidation; dv != nil && dv.ForwardClientCertificate != nil {
+			// the client certificate details are forwarded in a
+			// request header, which the allow list would remove.
+			secure.RequestHeadersAllowList = append(allowList[:len(allowList):len(allowList)], "X-Forwarded-Client-Cert")
+		}
+	}
+	dpp, err := downstreamProtocolPolicy(proxy.Spec.VirtualHost.DownstreamProtocolPolicy)
+	if err != nil {
+		sw.SetInvalid(err.Error())
+		return
+	}
+	insecure.DownstreamProtocolPolicy = dpp
+	secure.DownstreamProtocolPolicy = dpp
+	cors, err := corsPolicy(proxy.Spec.VirtualHost.CORSPolicy)
+	if err != nil {
+		sw.SetInvalid(err.Error())
+		return
+	}
+	if tsp := proxy.Spec.VirtualHost.TrailingSlashPolicy; tsp != nil {
+		if _, err := trailingSlashAction(tsp); err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+	}
+	if tls := proxy.Spec.VirtualHost.TLS; tls != nil && tls.RedirectPolicy != nil && enforceTLS {
+		policy, err := httpsRedirectPolicy(tls.RedirectPolicy)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return
+		}
+		insecure.HTTPSRedirectPolicy = policy
+	}
+	if proxy.Spec.VirtualHost.Redirect != nil {
+		route := b.redirectRoute(sw, proxy, enforceTLS)
+		if route == nil {
+			return
+		}
+		insecure.addRoute(route)
+		if enforceTLS {
+			secure.addRoute(route)
+		}
+		sw.SetValid()
+		return
+	}
+	routes := b.computeRoutes(sw, proxy, nil, nil, enforceTLS)
+	if proxy.Spec.VirtualHost.Maintenance {
+		// the routes are still computed, so their status is reported
+		// and their includes are not orphaned, but are not served.
+		if sw.values["status"] == StatusInvalid {
+			// an invalid proxy is not served, even in maintenance.
+			return
+		}
+		route := b.maintenanceRoute(sw, proxy, enforceTLS)
+		if route == nil {
+			return
+		}
+		routes = []*Route{route}
+	}
+	if secure.AuthorizationServer != nil {
+		for _, route := range routes {
+			// requests on the insecure listener cannot be authorized.
+			if !route.HTTPSUpgrade && !route.AuthDisabled {
+				sw.SetInvalid("authorization: routes which permit insecure requests must disable authorization")
+				return
+			}
+		}
+	}
+	if err := resolveJWTProviders(routes, secure.JWTProviders, defaultJWTProvider); err != nil {
+		sw.SetInvalid(err.Error())
+		return
+	}
+	for _, route := range routes {
+		route.CORSPolicy = cors
+	}
+	// routes outside their active window are added first, so an
+	// active route with the same conditions takes their place.
+	sort.SliceStable(routes, func(i, j int) bool {
+		return b.inactive[routes[i]] && !b.inactive[routes[j]]
+	})
+	for _, route := range routes {
+		insecure.addRoute(route)
+		if enforceTLS {
+			secure.addRoute(route)
+		}
+	}
+}
+
+// routeActive reports if the current time is within the supplied
+// active window. The time at which the window next opens or closes
+// is recorded so the DAG can be rebuilt at that time.
+func (b *Builder) routeActive(sw *ObjectStatusWriter, aw *projcontour.ActiveWindow) bool {
+	if aw == nil {
+		return true
+	}
+	if aw.Start != nil && aw.End != nil && !aw.End.After(aw.Start.Time) {
+		sw.SetInvalid("activeWindow: end must be after start")
+		return false
+	}
+
+	now := b.now()
+	active := true
+	if aw.Start != nil && now.Before(aw.Start.Time) {
+		b.changeAt(aw.Start.Time)
+		active = false
+	}
+	if aw.End != nil {
+		if now.Before(aw.End.Time) {
+			b.changeAt(aw.End.Time)
+		} else {
+			active = false
+		}
+	}
+	return active
+}
+
+// inactiveRoute returns the route which answers the requests
+// matched by route, while it is outside its active window, with
+// a 404.
+func inactiveRoute(route *Route) *Route {
+	return &Route{
+		Name:                     route.Name,
+		PathCondition:            route.PathCondition,
+		HeaderConditions:         route.HeaderConditions,
+		QueryParameterConditions: route.QueryParameterConditions,
+		HTTPSUpgrade:             route.HTTPSUpgrade,
+		DirectResponse:           &DirectResponse{StatusCode: http.StatusNotFound},
+		AuthDisabled:             route.AuthDisabled,
+		JWTDisabled:              route.JWTDisabled,
+	}
+}
+
+func (b *Builder) now() time.Time {
+	if b.Clock != nil {
+		return b.Clock()
+	}
+	return time.Now()
+}
+
+// changeAt records that the DAG will change at t.
+func (b *Builder) changeAt(t time.Time) {
+	if b.nextChange.IsZero() || t.Before(b.nextChange) {
+		b.nextChange = t
+	}
+}
+
+// maintenanceRoute returns the route which answers every request
+// to the virtual host of proxy while it is in maintenance.
+func (b *Builder) maintenanceRoute(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, enforceTLS bool) *Route {
+	r := &Route{
+		Name:          proxy.Namespace + "/" + proxy.Name,
+		PathCondition: &PrefixCondition{Prefix: "/"},
+		HTTPSUpgrade:  routeEnforceTLS(enforceTLS, false),
+	}
+
+	mp := proxy.Spec.VirtualHost.MaintenancePolicy
+	if mp == nil || mp.Service == nil {
+		status := uint32(http.StatusServiceUnavailable)
+		if mp != nil && mp.StatusCode != 0 {
+			status = mp.StatusCode
+		}
+		if status < 200 || status > 599 {
+			sw.SetInvalid(fmt.Sprintf("maintenancePolicy: statusCode %d must be in the range 200-599", status))
+			return nil
+		}
+		r.DirectResponse = &DirectResponse{StatusCode: status}
+		return r
+	}
+
+	service := mp.Service
+	s, port, err := b.lookupProxyService(proxy.Namespace, *service)
+	if err != nil {
+		sw.SetInvalid("maintenancePolicy: " + err.Error())
+		return nil
+	}
+	if s == nil {
+		sw.SetInvalid(fmt.Sprintf("maintenancePolicy: Service [%s:%s] is invalid or missing", service.Name, port.String()))
+		return nil
+	}
+	var uv *UpstreamValidation
+	if s.Protocol == "tls" {
+		uv, err = b.lookupUpstreamValidation("maintenancePolicy", service.Name, service.UpstreamValidation, proxy.Namespace)
+		if err != nil {
+			sw.SetInvalid("maintenancePolicy: " + err.Error())
+			return nil
+		}
+	}
+	if err := b.strictUpstreamTLSError(proxy.Namespace, service.Name, uv); err != nil {
+		sw.SetInvalid("maintenancePolicy: " + err.Error())
+		return nil
+	}
+	r.Clusters = []*Cluster{{Upstream: s, UpstreamValidation: uv}}
+	return r
+}
+
+// redirectRoute returns the route which redirects every request
+// to the virtual host of proxy to the fqdn of its redirect.
+func (b *Builder) redirectRoute(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, enforceTLS bool) *Route {
+	redirect := proxy.Spec.VirtualHost.Redirect
+	switch {
+	case isBlank(redirect.Fqdn):
+		sw.SetInvalid("redirect: fqdn must be specified")
+		return nil
+	case strings.Contains(redirect.Fqdn, "*"):
+		sw.SetInvalid(fmt.Sprintf("redirect: fqdn %q cannot use wildcards", redirect.Fqdn))
+		return nil
+	case redirect.Fqdn == proxy.Spec.VirtualHost.Fqdn:
+		sw.SetInvalid("redirect: fqdn cannot be the fqdn of the virtual host")
+		return nil
+	}
+
+	switch redirect.Scheme {
+	case "", "http", "https":
+	default:
+		sw.SetInvalid(fmt.Sprintf("redirect: scheme %q must be one of http or https", redirect.Scheme))
+		return nil
+	}
+
+	status := redirect.StatusCode
+	switch status {
+	case 0:
+		status = http.StatusMovedPermanently
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		sw.SetInvalid(fmt.Sprintf("redirect: statusCode %d must be one of 301, 302, 303, 307, or 308", status))
+		return nil
+	}
+
+	return &Route{
+		Name:          proxy.Namespace + "/" + proxy.Name,
+		PathCondition: &PrefixCondition{Prefix: "/"},
+		HTTPSUpgrade:  routeEnforceTLS(enforceTLS, false),
+		Redirect: &Redirect{
+			Host:       redirect.Fqdn,
+			Scheme:     redirect.Scheme,
+			StatusCode: status,
+		},
+	}
+}
+
+func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, conditions []projcontour.Condition, visited []*projcontour.HTTPProxy, enforceTLS bool) []*Route {
+	for _, v := range visited {
+		// ensure we are not following an edge that produces a cycle
+		var path []string
+		for _, vir := range visited {
+			path = append(path, fmt.Sprintf("%s/%s", vir.Namespace, vir.Name))
+		}
+		if v.Name == proxy.Name && v.Namespace == proxy.Namespace {
+			path = append(path, fmt.Sprintf("%s/%s", proxy.Namespace, proxy.Name))
+			sw.SetInvalid(fmt.Sprintf("include creates a delegation cycle: %s", strings.Join(path, " -> ")))
+			return nil
+		}
+	}
+
+	visited = append(visited, proxy)
+	var routes []*Route
+
+	// invalid records the errors of the includes and routes which
+	// were skipped. A proxy is only invalid if none of its includes
+	// or routes are valid.
+	var invalid []string
+
+	// Check for duplicate conditions on the includes
+	if includeConditionsIdentical(proxy.Spec.Includes) {
+		sw.SetInvalid("duplicate conditions defined on an include")
+		return nil
+	}
+
+	// Loop over and process all includes
+	for _, include := range proxy.Spec.Includes {
+		namespace := include.Namespace
+		if namespace == "" {
+			namespace = proxy.Namespace
+		}
+
+		if delegate, ok := b.Source.httpproxies[Meta{name: include.Name, namespace: namespace}]; ok {
+			if delegate.Spec.VirtualHost != nil {
+				invalid = append(invalid, "root httpproxy cannot delegate to another root httpproxy")
+				continue
+			}
+
+			isw := &ObjectStatusWriter{obj: proxy, values: make(map[string]string)}
+			if !pathConditionsValid(isw, include.Conditions, "include") {
+				invalid = append(invalid, isw.values["description"])
+				continue
+			}
+			if err := headerMatchesValid(include.Conditions); err != nil {
+				invalid = append(invalid, "include: "+err.Error())
+				continue
+			}
+			if err := queryParameterConditionsValid(include.Conditions); err != nil {
+				invalid = append(invalid, "include: "+err.Error())
+				continue
+			}
+
+			sw, commit := b.WithObject(delegate)
+			routes = append(routes, b.computeRoutes(sw, delegate, append(conditions, include.Conditions...), visited, enforceTLS)...)
+			commit()
+
+			// dest is not an orphaned httpproxy, as there is an httpproxy that points to it
+			delete(b.orphaned, Meta{name: delegate.Name, namespace: delegate.Namespace})
+		}
+	}
+	for _, route := range proxy.Spec.Routes {
+		rsw := &ObjectStatusWriter{obj: proxy, values: make(map[string]string)}
+		rs := b.computeRoute(rsw, proxy, route, conditions, enforceTLS)
+		tsp := route.TrailingSlashPolicy
+		if tsp == nil {
+			// visited[0] is the root of the include tree.
+			tsp = visited[0].Spec.VirtualHost.TrailingSlashPolicy
+		}
+		rs = trailingSlashRoutes(rsw, rs, tsp)
+		switch {
+		case b.routeActive(rsw, route.ActiveWindow):
+			routes = append(routes, rs...)
+		case rsw.values["status"] != StatusInvalid:
+			// outside its window the route answers 404, rather
+			// than leaving a broader route to answer in its place.
+			for _, r := range rs {
+				r = inactiveRoute(r)
+				b.inactive[r] = true
+				routes = append(routes, r)
+			}
+		}
+		switch rsw.values["status"] {
+		case StatusInvalid:
+			invalid = append(invalid, rsw.values["description"])
+		case StatusWarning:
+			sw.SetWarning(rsw.values["description"])
+		}
+	}
+
+	if len(invalid) > 0 {
+		if len(routes) == 0 {
+			// nothing left to serve.
+			sw.SetInvalid(strings.Join(invalid, "; "))
+			return nil
+		}
+		sw.SetWarning(strings.Join(invalid, "; "))
+	}
+	sw.SetValid()
+	return routes
+}
+
+// computeRoute returns the routes for a single route of the supplied proxy.
+// Errors are recorded on sw and nil is returned, so that an invalid route
+// does not invalidate its siblings.
+func (b *Builder) computeRoute(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, route projcontour.Route, conditions []projcontour.Condition, enforceTLS bool) []*Route {
+	var routes []*Route
+
+	if len(route.Services) > 1 && route.EnableWebsockets && route.BlueGreenPolicy == nil {
+		sw.SetInvalid("route: cannot specify multiple services and enable websockets")
+		return nil
+	}
+
+	if len(route.WebsocketSubprotocols) > 0 && !route.EnableWebsockets {
+		sw.SetInvalid("websocketSubprotocols: requires enableWebsockets")
+		return nil
+	}
+
+	if !pathConditionsValid(sw, route.Conditions, "route") {
+		return nil
+	}
+
+	conds := append(conditions, route.Conditions...)
+
+	// Look for duplicate exact match headers on this route
+	if !headerConditionsAreValid(conds) {
+		sw.SetInvalid("cannot specify duplicate header 'exact match' conditions in the same route")
+		return nil
+	}
+
+	if err := headerMatchesValid(conds); err != nil {
+		sw.SetInvalid("route: " + err.Error())
+		return nil
+	}
+
+	if err := queryParameterConditionsValid(conds); err != nil {
+		sw.SetInvalid("route: " + err.Error())
+		return nil
+	}
+
+	rp, err := retryPolicy(route.RetryPolicy)
+	if err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
+
+	r := &Route{
+		Name:                     proxy.Namespace + "/" + proxy.Name,
+		PathCondition:            mergePathConditions(conds),
+		HeaderConditions:         mergeHeaderConditions(conds),
+		QueryParameterConditions: mergeQueryParameterConditions(conds),
+		Websocket:                route.EnableWebsockets,
+		HTTPSUpgrade:             routeEnforceTLS(enforceTLS, route.PermitInsecure && !b.DisablePermitInsecure),
+		TimeoutPolicy:            timeoutPolicy(route.TimeoutPolicy),
+		RetryPolicy:              rp,
+	}
+
+	if cp := route.ClassificationPolicy; cp != nil {
+		if isBlank(cp.Header) {
+			sw.SetInvalid("classificationPolicy: header must be specified")
+			return nil
+		}
+		r.ClassificationHeader = cp.Header
+	}
+
+	if len(route.Metadata) > 0 {
+		md, err := routeMetadata(route.Metadata)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.Metadata = md
+	}
+
+	if r.DownstreamProtocolPolicy, err = downstreamProtocolPolicy(route.DownstreamProtocolPolicy); err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
+
+	rl, err := rateLimitPolicy(route.RateLimitPolicy)
+	if err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
+	if rl != nil && b.RateLimitService.Name != "" {
+		if b.lookupRateLimitService() == nil {
+			sw.SetInvalid(fmt.Sprintf("rateLimitPolicy: rate limit Service [%s/%s:%d] is invalid or missing", b.RateLimitService.Namespace, b.RateLimitService.Name, b.RateLimitService.Port))
+			return nil
+		}
+		// the rate limit service is called without validation.
+		if err := b.strictUpstreamTLSError(proxy.Namespace, b.RateLimitService.Name, nil); err != nil {
+			sw.SetInvalid("rateLimitPolicy: " + err.Error())
+			return nil
+		}
+	}
+	r.RateLimitPolicy = rl
+
+	if ap := route.AuthPolicy; ap != nil {
+		r.AuthDisabled = ap.Disabled
+	}
+
+	if jp := route.JWTVerificationPolicy; jp != nil {
+		if jp.Require != "" && jp.Disabled {
+			sw.SetInvalid("jwtVerificationPolicy: require and disabled cannot both be specified")
+			return nil
+		}
+		r.JWTProvider = jp.Require
+		r.JWTDisabled = jp.Disabled
+	}
+
+	if r.RequestHeadersPolicy, err = headersPolicy("requestHeadersPolicy", route.RequestHeadersPolicy); err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
+	if r.ResponseHeadersPolicy, err = headersPolicy("responseHeadersPolicy", route.ResponseHeadersPolicy); err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
+
+	if sp := route.SessionPinningPolicy; sp != nil {
+		if route.BlueGreenPolicy != nil {
+			sw.SetInvalid("sessionPinningPolicy: cannot be combined with blueGreenPolicy")
+			return nil
+		}
+		policy, err := sessionPinningPolicy(sp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.SessionPinningPolicy = policy
+	}
+
+	if lbp := route.LoadBalancerPolicy; lbp != nil && lbp.Cookie != nil {
+		policy, err := sessionAffinityPolicy(lbp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.SessionAffinityPolicy = policy
+	}
+
+	if lbp := route.LoadBalancerPolicy; lbp != nil && (lbp.Strategy == "RequestHash" || len(lbp.RequestHashPolicies) > 0) {
+		policies, err := requestHashPolicies(lbp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.RequestHashPolicies = policies
+	}
+
+	if ep := route.ExperimentPolicy; ep != nil {
+		if route.BlueGreenPolicy != nil || route.SessionPinningPolicy != nil {
+			sw.SetInvalid("experimentPolicy: cannot be combined with blueGreenPolicy or sessionPinningPolicy")
+			return nil
+		}
+		policy, err := experimentPolicy(ep)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.ExperimentPolicy = policy
+	}
+
+	if prp := route.PathRewritePolicy; prp != nil {
+		if route.DirectResponsePolicy != nil || route.RequestRedirectPolicy != nil {
+			sw.SetInvalid("pathRewritePolicy: cannot be combined with directResponsePolicy or requestRedirectPolicy")
+			return nil
+		}
+		// the prefix matched includes those of the includes the
+		// route was reached through.
+		pc, ok := r.PathCondition.(*PrefixCondition)
+		if !ok {
+			sw.SetInvalid("pathRewritePolicy: cannot be combined with a regex or exact condition")
+			return nil
+		}
+		rewrite, err := pathRewrite(prp, pc.Prefix)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		r.PrefixRewrite = rewrite
+	}
+
+	if drp := route.DirectResponsePolicy; drp != nil {
+		if selectsServices(route) || route.RequestRedirectPolicy != nil {
+			sw.SetInvalid("directResponsePolicy: cannot be combined with requestRedirectPolicy, services, serviceSelector, clusterHeaderPolicy, blueGreenPolicy, sessionPinningPolicy or experimentPolicy")
+			return nil
 		}
-	}
-
-	insecure := b.lookupVirtualHost(host)
-	secure := b.lookupSecureVirtualHost(host)
-	routes := b.computeRoutes(sw, proxy, nil, nil, enforceTLS)
-	for _, route := range routes {
-		insecure.addRoute(route)
-		if enforceTLS {
-			secure.addRoute(route)
+		dr, err := directResponse(drp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
 		}
+		r.DirectResponse = dr
+		return []*Route{r}
 	}
-}
 
-func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, conditions []projcontour.Condition, visited []*projcontour.HTTPProxy, enforceTLS bool) []*Route {
-	for _, v := range visited {
-		// ensure we are not following an edge that produces a cycle
-		var path []string
-		for _, vir := range visited {
-			path = append(path, fmt.Sprintf("%s/%s", vir.Namespace, vir.Name))
+	if rrp := route.RequestRedirectPolicy; rrp != nil {
+		if selectsServices(route) {
+			sw.SetInvalid("requestRedirectPolicy: cannot be combined with services, serviceSelector, clusterHeaderPolicy, blueGreenPolicy, sessionPinningPolicy or experimentPolicy")
+			return nil
 		}
-		if v.Name == proxy.Name && v.Namespace == proxy.Namespace {
-			path = append(path, fmt.Sprintf("%s/%s", proxy.Namespace, proxy.Name))
-			sw.SetInvalid(fmt.Sprintf("include creates a delegation cycle: %s", strings.Join(path, " -> ")))
+		redirect, err := requestRedirect(rrp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
 			return nil
 		}
+		r.Redirect = redirect
+		return []*Route{r}
 	}
 
-	visited = append(visited, proxy)
-	var routes []*Route
-
-	// Check for duplicate conditions on the includes
-	if includeConditionsIdentical(proxy.Spec.Includes) {
-		sw.SetInvalid("duplicate conditions defined on an include")
+	hc, err := healthCheckPolicy(route.HealthCheckPolicy)
+	if err != nil {
+		sw.SetInvalid(err.Error())
 		return nil
 	}
 
-	// Loop over and process all includes
-	for _, include := range proxy.Spec.Includes {
-		namespace := include.Namespace
-		if namespace == "" {
-			namespace = proxy.Namespace
+	var missing []string
+	for _, service := range route.Services {
+		s, port, err := b.lookupProxyService(proxy.Namespace, service)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		if s == nil {
+			missing = append(missing, fmt.Sprintf("Service [%s:%s] is invalid or missing", service.Name, port.String()))
+			continue
 		}
 
-		if delegate, ok := b.Source.httpproxies[Meta{name: include.Name, namespace: namespace}]; ok {
-			if delegate.Spec.VirtualHost != nil {
-				sw.SetInvalid("root httpproxy cannot delegate to another root httpproxy")
+		switch service.DNSLookup {
+		case "", "strict", "logical":
+		default:
+			sw.SetInvalid(fmt.Sprintf("service %q: dnsLookup %q must be one of strict or logical", service.Name, service.DNSLookup))
+			return nil
+		}
+		if service.HealthyPanicThreshold > 100 {
+			sw.SetInvalid(fmt.Sprintf("service %q: healthyPanicThreshold must be in the range 0-100", service.Name))
+			return nil
+		}
+
+		reqHP, err := headersPolicy(fmt.Sprintf("service %q: requestHeadersPolicy", service.Name), service.RequestHeadersPolicy)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		respHP, err := headersPolicy(fmt.Sprintf("service %q: responseHeadersPolicy", service.Name), service.ResponseHeadersPolicy)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		if (reqHP != nil || respHP != nil) && (service.Mirror || route.ClusterHeaderPolicy != nil) {
+			sw.SetInvalid(fmt.Sprintf("service %q: header policies cannot be applied to a mirror service or with clusterHeaderPolicy", service.Name))
+			return nil
+		}
+
+		ct, err := connectTimeout(service.ConnectTimeout)
+		if err != nil {
+			sw.SetInvalid(fmt.Sprintf("service %q: %v", service.Name, err))
+			return nil
+		}
+		keepalive, err := tcpKeepalive(service.TCPKeepalive)
+		if err != nil {
+			sw.SetInvalid(fmt.Sprintf("service %q: %v", service.Name, err))
+			return nil
+		}
+
+		var uv *UpstreamValidation
+		if s.Protocol == "tls" {
+			// we can only validate TLS connections to services that talk TLS
+			uv, err = b.lookupUpstreamValidation("??", service.Name, service.UpstreamValidation, proxy.Namespace)
+			if err != nil {
+				sw.SetInvalid(err.Error())
 				return nil
 			}
+		}
+		if err := b.strictUpstreamTLSError(proxy.Namespace, service.Name, uv); err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		clientCert, err := b.lookupClientCertificate(service, s.Protocol, proxy.Namespace)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
 
-			if !pathConditionsValid(sw, include.Conditions, "include") {
-				return nil
+		c := &Cluster{
+			Upstream:              s,
+			LoadBalancerPolicy:    loadBalancerPolicy(route.LoadBalancerPolicy),
+			Weight:                service.Weight,
+			HealthCheckPolicy:     hc,
+			UpstreamValidation:    uv,
+			ClientCertificate:     clientCert,
+			IdleTimeout:           parseTimeout(service.IdleTimeout),
+			MaxConnectionDuration: parseTimeout(service.MaxConnectionDuration),
+			ConnectTimeout:        ct,
+			TCPKeepalive:          keepalive,
+			CircuitBreakers:       circuitBreakers(service.CircuitBreakerPolicy),
+			LogicalDNS:            service.DNSLookup == "logical" && s.ExternalName != "",
+			HealthyPanicThreshold: service.HealthyPanicThreshold,
+			RequestHeadersPolicy:  reqHP,
+			ResponseHeadersPolicy: respHP,
+		}
+		if r.ClassificationHeader != "" {
+			c.Classification = classification(service)
+		}
+		if service.Mirror && r.MirrorPolicy != nil {
+			sw.SetInvalid("only one service per route may be nominated as mirror")
+			return nil
+		}
+		if service.Mirror {
+			r.MirrorPolicy = &MirrorPolicy{
+				Cluster: c,
 			}
+		} else {
+			r.Clusters = append(r.Clusters, c)
+		}
+	}
 
-			sw, commit := b.WithObject(delegate)
-			routes = append(routes, b.computeRoutes(sw, delegate, append(conditions, include.Conditions...), visited, enforceTLS)...)
-			commit()
+	if len(missing) > 0 {
+		// degrade only this route, rather than the whole
+		// proxy, until the missing services appear.
+		sw.SetWarning(strings.Join(missing, ", "))
+		r.Clusters = nil
+		r.MirrorPolicy = nil
+		r.DirectResponse = &DirectResponse{StatusCode: http.StatusServiceUnavailable}
+		return []*Route{r}
+	}
 
-			// dest is not an orphaned httpproxy, as there is an httpproxy that points to it
-			delete(b.orphaned, Meta{name: delegate.Name, namespace: delegate.Namespace})
+	if mp := route.MirrorPolicy; mp != nil {
+		mirror, err := b.mirrorPolicy(sw, proxy.Namespace, route, mp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
 		}
+		r.MirrorPolicy = mirror
 	}
-	for _, route := range proxy.Spec.Routes {
-		if len(route.Services) > 1 && route.EnableWebsockets {
-			sw.SetInvalid("route: cannot specify multiple services and enable websockets")
-			continue
+
+	if ss := route.ServiceSelector; ss != nil {
+		if route.BlueGreenPolicy != nil || route.SessionPinningPolicy != nil || route.ExperimentPolicy != nil {
+			sw.SetInvalid("serviceSelector: cannot be combined with blueGreenPolicy, sessionPinningPolicy or experimentPolicy")
+			return nil
+		}
+		selected, err := serviceSelectorRoutes(r, ss)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+		routes = append(routes, selected...)
+	}
+
+	if chp := route.ClusterHeaderPolicy; chp != nil {
+		if route.BlueGreenPolicy != nil || route.SessionPinningPolicy != nil || route.ExperimentPolicy != nil || route.ServiceSelector != nil {
+			sw.SetInvalid("clusterHeaderPolicy: cannot be combined with blueGreenPolicy, sessionPinningPolicy, experimentPolicy or serviceSelector")
+			return nil
+		}
+		hr, err := clusterHeaderRoute(r, chp)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
 		}
+		routes = append(routes, hr)
+	}
 
-		if !pathConditionsValid(sw, route.Conditions, "route") {
+	if bg := route.BlueGreenPolicy; bg != nil {
+		active, preview, err := blueGreenClusters(r.Clusters, bg)
+		if err != nil {
+			sw.SetInvalid(err.Error())
 			return nil
 		}
+		if bg.PreviewHeader != nil {
+			previewConds := append(conds, projcontour.Condition{Header: bg.PreviewHeader})
+			if !headerConditionsAreValid(previewConds) {
+				sw.SetInvalid("blueGreenPolicy: previewHeader duplicates an 'exact match' condition of the route")
+				return nil
+			}
+			pr := *r
+			pr.HeaderConditions = mergeHeaderConditions(previewConds)
+			pr.Clusters = []*Cluster{preview}
+			routes = append(routes, &pr)
+		}
+		r.Clusters = []*Cluster{active}
+	}
+
+	if sp := r.SessionPinningPolicy; sp != nil {
+		// requests carrying a pinning cookie are routed to the
+		// cluster named by the cookie, ahead of the weighted route.
+		for _, c := range r.Clusters {
+			pr := *r
+			pr.HeaderConditions = append(append([]HeaderCondition{}, r.HeaderConditions...), HeaderCondition{
+				Name:      "cookie",
+				Value:     sp.CookieName + "=" + c.SessionPin(),
+				MatchType: "contains",
+			})
+			pr.Clusters = []*Cluster{c}
+			pr.SessionPinningPolicy = nil
+			routes = append(routes, &pr)
+		}
+	}
 
-		conds := append(conditions, route.Conditions...)
+	if ep := r.ExperimentPolicy; ep != nil {
+		// requests carrying an experiment cookie keep their variant,
+		// ahead of the route which assigns variants.
+		for _, v := range ep.Variants {
+			vr := *r
+			vr.HeaderConditions = append(append([]HeaderCondition{}, r.HeaderConditions...), HeaderCondition{
+				Name:      "cookie",
+				Value:     ep.CookieName + "=" + v.Cookie(),
+				MatchType: "contains",
+			})
+			vr.Variant = v.Name
+			routes = append(routes, &vr)
+		}
+	}
+	routes = append(routes, r)
 
-		// Look for duplicate exact match headers on this route
-		if !headerConditionsAreValid(conds) {
-			sw.SetInvalid("cannot specify duplicate header 'exact match' conditions in the same route")
+	if len(route.WebsocketSubprotocols) > 0 {
+		rejected, err := websocketSubprotocolRoutes(routes, route.WebsocketSubprotocols)
+		if err != nil {
+			sw.SetInvalid(err.Error())
 			return nil
 		}
+		routes = append(rejected, routes...)
+	}
+	return routes
+}
 
-		r := &Route{
-			PathCondition:    mergePathConditions(conds),
-			HeaderConditions: mergeHeaderConditions(conds),
-			Websocket:        route.EnableWebsockets,
-			HTTPSUpgrade:     routeEnforceTLS(enforceTLS, route.PermitInsecure && !b.DisablePermitInsecure),
-			TimeoutPolicy:    timeoutPolicy(route.TimeoutPolicy),
-			RetryPolicy:      retryPolicy(route.RetryPolicy),
+// mirrorPolicy returns the policy mirroring requests to route to the
+// Service mp names, or an error if mp is invalid. If the Service is
+// missing a warning is set, and requests are not mirrored until it
+// appears.
+func (b *Builder) mirrorPolicy(sw *ObjectStatusWriter, namespace string, route projcontour.Route, mp *projcontour.MirrorPolicy) (*MirrorPolicy, error) {
+	for _, service := range route.Services {
+		if service.Mirror {
+			return nil, fmt.Errorf("mirrorPolicy: cannot be combined with a mirror service")
 		}
+	}
+	if len(route.Services) == 0 {
+		return nil, fmt.Errorf("mirrorPolicy: route must have services")
+	}
+	if mp.Percent > 100 {
+		return nil, fmt.Errorf("mirrorPolicy: percent must be in the range 0-100")
+	}
+	s, port, err := b.lookupProxyService(namespace, projcontour.Service{Name: mp.Name, Port: mp.Port})
+	if err != nil {
+		return nil, fmt.Errorf("mirrorPolicy: %v", err)
+	}
+	if s == nil {
+		sw.SetWarning(fmt.Sprintf("mirrorPolicy: Service [%s:%s] is invalid or missing", mp.Name, port.String()))
+		return nil, nil
+	}
+	// a mirror policy cannot request validation of its service.
+	if err := b.strictUpstreamTLSError(namespace, mp.Name, nil); err != nil {
+		return nil, fmt.Errorf("mirrorPolicy: %v", err)
+	}
+	return &MirrorPolicy{
+		Cluster: &Cluster{
+			Upstream:           s,
+			LoadBalancerPolicy: loadBalancerPolicy(route.LoadBalancerPolicy),
+		},
+		Percent: mp.Percent,
+	}, nil
+}
 
-		for _, service := range route.Services {
-			if service.Port < 1 || service.Port > 65535 {
-				sw.SetInvalid(fmt.Sprintf("service %q: port must be in the range 1-65535", service.Name))
-				return nil
-			}
-			m := Meta{name: service.Name, namespace: proxy.Namespace}
-			s := b.lookupService(m, intstr.FromInt(service.Port))
+// selectsServices returns true if route has services, or any of the
+// policies which choose between services.
+func selectsServices(route projcontour.Route) bool {
+	return len(route.Services) > 0 || route.ServiceSelector != nil || route.ClusterHeaderPolicy != nil ||
+		route.BlueGreenPolicy != nil || route.SessionPinningPolicy != nil || route.ExperimentPolicy != nil
+}
 
-			if s == nil {
-				msg := fmt.Sprintf("Service [%s:%d] is invalid or missing", service.Name, service.Port)
-				sw.SetInvalid(msg)
-				return nil
-			}
+// trailingSlashRoutes returns routes, together with the routes which
+// handle requests for the other form of their prefix, with or without
+// a trailing slash, according to tsp. The last of routes is the route
+// which the others were derived from.
+func trailingSlashRoutes(sw *ObjectStatusWriter, routes []*Route, tsp *projcontour.TrailingSlashPolicy) []*Route {
+	if tsp == nil || len(routes) == 0 {
+		return routes
+	}
+	action, err := trailingSlashAction(tsp)
+	if err != nil {
+		sw.SetInvalid(err.Error())
+		return nil
+	}
 
-			var uv *UpstreamValidation
-			var err error
-			if s.Protocol == "tls" {
-				// we can only validate TLS connections to services that talk TLS
-				uv, err = b.lookupUpstreamValidation("??", service.Name, service.UpstreamValidation, proxy.Namespace)
-				if err != nil {
-					sw.SetInvalid(err.Error())
-					return nil
-				}
-			}
+	r := routes[len(routes)-1]
+	pc, ok := r.PathCondition.(*PrefixCondition)
+	if !ok || pc.Prefix == "/" {
+		return routes
+	}
+	other := pc.Prefix + "/"
+	if strings.HasSuffix(pc.Prefix, "/") {
+		other = strings.TrimSuffix(pc.Prefix, "/")
+	}
 
-			c := &Cluster{
-				Upstream:           s,
-				LoadBalancerPolicy: loadBalancerPolicy(route.LoadBalancerPolicy),
-				Weight:             service.Weight,
-				HealthCheckPolicy:  healthCheckPolicy(route.HealthCheckPolicy),
-				UpstreamValidation: uv,
-			}
-			if service.Mirror && r.MirrorPolicy != nil {
-				sw.SetInvalid("only one service per route may be nominated as mirror")
-				return nil
-			}
-			if service.Mirror {
-				r.MirrorPolicy = &MirrorPolicy{
-					Cluster: c,
-				}
-			} else {
-				r.Clusters = append(r.Clusters, c)
-			}
+	if action == "redirect" {
+		return append(routes, &Route{
+			Name:                     r.Name,
+			PathCondition:            &ExactCondition{Path: other},
+			HeaderConditions:         r.HeaderConditions,
+			QueryParameterConditions: r.QueryParameterConditions,
+			HTTPSUpgrade:             r.HTTPSUpgrade,
+			Redirect: &Redirect{
+				Path:       pc.Prefix,
+				StatusCode: http.StatusMovedPermanently,
+			},
+		})
+	}
+
+	// rewrite the path of the other form for each of the routes,
+	// so any cookie or header conditions still apply.
+	for _, rr := range routes {
+		tr := *rr
+		tr.PathCondition = &ExactCondition{Path: other}
+		if tr.PrefixRewrite == "" {
+			tr.PrefixRewrite = pc.Prefix
 		}
-		routes = append(routes, r)
+		routes = append(routes, &tr)
 	}
-	sw.SetValid()
 	return routes
 }
 
@@ -643,7 +1691,7 @@ func includeConditionsIdentical(includes []projcontour.Include) bool {
 		// Now compare each include's set of conditions
 		for _, cA := range includes[i].Conditions {
 			for _, cB := range includes[j].Conditions {
-				if (cA.Prefix == cB.Prefix) && cmp.Equal(cA.Header, cB.Header) {
+				if (cA.Prefix == cB.Prefix) && cmp.Equal(cA.Header, cB.Header) && cmp.Equal(cA.QueryParameter, cB.QueryParameter) {
 					return true
 				}
 			}
@@ -667,6 +1715,10 @@ func (b *Builder) buildDAG() *DAG {
 		dag.roots = append(dag.roots, https)
 	}
 
+	if rls := b.buildRateLimitService(); rls != nil {
+		dag.roots = append(dag.roots, rls)
+	}
+
 	for meta := range b.orphaned {
 		ir, ok := b.Source.ingressroutes[meta]
 		if ok {
@@ -683,10 +1735,142 @@ func (b *Builder) buildDAG() *DAG {
 			commit()
 		}
 	}
+	b.warnDeprecatedAnnotations()
+	if b.StrictAnnotations {
+		b.warnInvalidAnnotations()
+	}
+
 	dag.statuses = b.statuses
+	dag.nextChange = b.nextChange
+	dag.deprecatedAnnotations = b.Source.deprecatedAnnotations()
 	return &dag
 }
 
+// warnInvalidAnnotations adds a warning to the status of each valid
+// IngressRoute and HTTPProxy for each annotation it has which Contour
+// would otherwise silently ignore, such as a misspelt one.
+func (b *Builder) warnInvalidAnnotations() {
+	for m, st := range b.statuses {
+		if st.Status != StatusValid && st.Status != StatusWarning {
+			continue
+		}
+		kind := toKind(st.Object)
+		for _, a := range invalidAnnotations(kind, st.Object) {
+			desc := fmt.Sprintf("annotation %q is not supported on %s", a, kind)
+			if !annotationIsKnownForAnyKind(a) {
+				desc = fmt.Sprintf("annotation %q is unknown", a)
+			}
+			if st.Status == StatusWarning {
+				desc = st.Description + "; " + desc
+			}
+			st.Status = StatusWarning
+			st.Description = desc
+		}
+		b.statuses[m] = st
+	}
+}
+
+// warnDeprecatedAnnotations adds a warning to the status of each
+// valid IngressRoute and HTTPProxy for each deprecated annotation it
+// uses, so their owners can move to the replacements before the
+// deprecated annotations are removed.
+func (b *Builder) warnDeprecatedAnnotations() {
+	for m, st := range b.statuses {
+		if st.Status != StatusValid && st.Status != StatusWarning {
+			continue
+		}
+		for _, a := range deprecatedAnnotations(st.Object) {
+			desc := fmt.Sprintf("annotation %q is deprecated, use %q", a, "projectcontour.io/"+strings.TrimPrefix(a, "contour.heptio.com/"))
+			if st.Status == StatusWarning {
+				desc = st.Description + "; " + desc
+			}
+			st.Status = StatusWarning
+			st.Description = desc
+		}
+		b.statuses[m] = st
+	}
+}
+
+// buildRateLimitService returns the rate limit service, or nil if
+// none is configured or its Service does not exist.
+func (b *Builder) buildRateLimitService() *RateLimitService {
+	rls := b.RateLimitService
+	if rls.Name == "" {
+		return nil
+	}
+	s := b.lookupRateLimitService()
+	if s == nil {
+		if b.Source.FieldLogger != nil {
+			b.Source.WithField("namespace", rls.Namespace).
+				WithField("name", rls.Name).
+				WithField("port", rls.Port).
+				Error("rate limit service is invalid or missing, HTTPProxies with a rateLimitPolicy are invalid")
+		}
+		return nil
+	}
+	return &RateLimitService{
+		Cluster: &Cluster{
+			Upstream: grpcUpstream(s),
+		},
+		Domain:          stringOrDefault(rls.Domain, "contour"),
+		Timeout:         rls.Timeout,
+		FailureModeDeny: rls.FailureModeDeny,
+	}
+}
+
+// lookupRateLimitService returns the Service of the rate limit
+// service, or nil if it does not exist.
+func (b *Builder) lookupRateLimitService() *Service {
+	rls := b.RateLimitService
+	return b.lookupService(Meta{name: rls.Name, namespace: rls.Namespace}, intstr.FromInt(rls.Port))
+}
+
+// authorizationServer returns the authorization service of a virtual
+// host of an HTTPProxy in namespace, or an error if auth is invalid
+// or its Service does not exist.
+func (b *Builder) authorizationServer(namespace string, auth *projcontour.AuthorizationServer) (*AuthorizationServer, error) {
+	if isBlank(auth.Name) {
+		return nil, fmt.Errorf("authorization: name must be specified")
+	}
+	if auth.Port < 1 || auth.Port > 65535 {
+		return nil, fmt.Errorf("authorization: port must be in the range 1-65535")
+	}
+	var timeout time.Duration
+	if auth.ResponseTimeout != "" {
+		d, err := time.ParseDuration(auth.ResponseTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("authorization: responseTimeout %q must be a positive duration", auth.ResponseTimeout)
+		}
+		timeout = d
+	}
+	s := b.lookupService(Meta{name: auth.Name, namespace: namespace}, intstr.FromInt(auth.Port))
+	if s == nil {
+		return nil, fmt.Errorf("authorization: Service [%s:%d] is invalid or missing", auth.Name, auth.Port)
+	}
+	// an authorization server cannot request validation of its service.
+	if err := b.strictUpstreamTLSError(namespace, auth.Name, nil); err != nil {
+		return nil, fmt.Errorf("authorization: %v", err)
+	}
+	return &AuthorizationServer{
+		Cluster: &Cluster{
+			Upstream: grpcUpstream(s),
+		},
+		FailOpen:        auth.FailOpen,
+		ResponseTimeout: timeout,
+	}, nil
+}
+
+// grpcUpstream returns s, or a copy of s using h2c if it has
+// no protocol, as gRPC requires HTTP/2.
+func grpcUpstream(s *Service) *Service {
+	if s.Protocol != "" {
+		return s
+	}
+	h2c := *s
+	h2c.Protocol = "h2c"
+	return &h2c
+}
+
 // buildHTTPListener builds a *dag.Listener for the vhosts bound to port 80.
 // The list of virtual hosts will attached to the listener will be sorted
 // by hostname.
@@ -771,6 +1955,12 @@ func (b *Builder) processIngressRoutes(sw *ObjectStatusWriter, ir *ingressroutev
 				return
 			}
 
+			rp, err := retryPolicy(route.RetryPolicy)
+			if err != nil {
+				sw.SetInvalid(fmt.Sprintf("route %q: %v", route.Match, err))
+				return
+			}
+
 			permitInsecure := route.PermitInsecure && !b.DisablePermitInsecure
 			r := &Route{
 				PathCondition: &PrefixCondition{Prefix: route.Match},
@@ -778,7 +1968,7 @@ func (b *Builder) processIngressRoutes(sw *ObjectStatusWriter, ir *ingressroutev
 				HTTPSUpgrade:  routeEnforceTLS(enforceTLS, permitInsecure),
 				PrefixRewrite: route.PrefixRewrite,
 				TimeoutPolicy: ingressrouteTimeoutPolicy(route.TimeoutPolicy),
-				RetryPolicy:   retryPolicy(route.RetryPolicy),
+				RetryPolicy:   rp,
 			}
 			for _, service := range route.Services {
 				if service.Port < 1 || service.Port > 65535 {
@@ -802,6 +1992,10 @@ func (b *Builder) processIngressRoutes(sw *ObjectStatusWriter, ir *ingressroutev
 						sw.SetInvalid(err.Error())
 					}
 				}
+				if uv == nil && b.strictUpstreamTLS(ir.Namespace) {
+					sw.SetInvalid(fmt.Sprintf("route %q: service %q: upstream TLS with validation is required in namespace %q", route.Match, service.Name, ir.Namespace))
+					return
+				}
 				r.Clusters = append(r.Clusters, &Cluster{
 					Upstream:           s,
 					LoadBalancerPolicy: service.Strategy,
@@ -866,7 +2060,17 @@ func (b *Builder) lookupUpstreamValidation(match string, serviceName string, uv
 		return nil, nil
 	}
 
-	cacert := b.lookupSecret(Meta{name: uv.CACertificate, namespace: namespace}, validCA)
+	ca := Meta{name: uv.CACertificate, namespace: namespace}
+	if uv.CACertificate == "" {
+		// fall back to the CA bundle of the service's trust domain
+		td := b.Source.upstreamTrustDomain(namespace, serviceName)
+		if td == nil {
+			return nil, fmt.Errorf("route %q: service %q: upstreamValidation requested but no caSecret set and no upstream trust domain applies", match, serviceName)
+		}
+		ca = Meta{name: td.CASecretName, namespace: td.CASecretNamespace}
+	}
+
+	cacert := b.lookupSecret(ca, validCA)
 	if cacert == nil {
 		// UpstreamValidation is requested, but cert is missing or not configured
 		return nil, fmt.Errorf("route %q: service %q: upstreamValidation requested but secret not found or misconfigured", match, serviceName)
@@ -876,13 +2080,115 @@ func (b *Builder) lookupUpstreamValidation(match string, serviceName string, uv
 		// UpstreamValidation is requested, but SAN is not provided
 		return nil, fmt.Errorf("route %q: service %q: upstreamValidation requested but subject alt name not found or misconfigured", match, serviceName)
 	}
+	for _, name := range uv.SubjectNames {
+		if isBlank(name) {
+			return nil, fmt.Errorf("route %q: service %q: upstreamValidation subjectNames must not be blank", match, serviceName)
+		}
+	}
+	for _, pin := range uv.SPKIPins {
+		if b, err := base64.StdEncoding.DecodeString(pin); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("route %q: service %q: upstreamValidation spkiPin %q is not a base64 encoded SHA-256 hash", match, serviceName, pin)
+		}
+	}
+	for _, hash := range uv.CertificateHashes {
+		if b, err := hex.DecodeString(strings.Replace(hash, ":", "", -1)); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("route %q: service %q: upstreamValidation certificateHash %q is not a hex encoded SHA-256 hash", match, serviceName, hash)
+		}
+	}
 
 	return &UpstreamValidation{
-		CACertificate: cacert,
-		SubjectName:   uv.SubjectName,
+		CACertificate:     cacert,
+		SubjectName:       uv.SubjectName,
+		SubjectNames:      uv.SubjectNames,
+		SPKIPins:          uv.SPKIPins,
+		CertificateHashes: uv.CertificateHashes,
 	}, nil
 }
 
+// strictUpstreamTLS returns whether the routes of objects in namespace
+// must connect to their services over TLS, validating their certificates.
+func (b *Builder) strictUpstreamTLS(namespace string) bool {
+	for _, ns := range b.StrictUpstreamTLSNamespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// strictUpstreamTLSError returns an error if namespace requires
+// upstream TLS with validation, but the named service is connected
+// to without, uv being nil.
+func (b *Builder) strictUpstreamTLSError(namespace, service string, uv *UpstreamValidation) error {
+	if uv != nil || !b.strictUpstreamTLS(namespace) {
+		return nil
+	}
+	return fmt.Errorf("service %q: upstream TLS with validation is required in namespace %q", service, namespace)
+}
+
+// lookupClientCertificate returns the certificate a service, of the
+// supplied upstream protocol, of an HTTPProxy in namespace presents to
+// its endpoints, if any.
+func (b *Builder) lookupClientCertificate(service projcontour.Service, protocol string, namespace string) (*Secret, error) {
+	if service.ClientCertificate == "" {
+		return nil, nil
+	}
+	if protocol != "tls" && protocol != "h2" {
+		return nil, fmt.Errorf("service %q: clientCertificate requires the tls or h2 upstream protocol", service.Name)
+	}
+	m := splitSecret(service.ClientCertificate, namespace)
+	sec := b.lookupSecret(m, validSecret)
+	if sec == nil {
+		return nil, fmt.Errorf("service %q: clientCertificate Secret [%s] not found or is malformed", service.Name, service.ClientCertificate)
+	}
+	if !b.delegationPermitted(m, namespace) {
+		return nil, fmt.Errorf("service %q: clientCertificate %s: certificate delegation not permitted", service.Name, service.ClientCertificate)
+	}
+	return sec, nil
+}
+
+// lookupDownstreamValidation returns the validation of the certificates
+// presented by clients of a vhost defined in namespace.
+func (b *Builder) lookupDownstreamValidation(dv *projcontour.DownstreamValidation, namespace string) (*DownstreamValidation, error) {
+	if isBlank(dv.CACertificate) {
+		return nil, fmt.Errorf("clientValidation: caSecret must be specified")
+	}
+	m := splitSecret(dv.CACertificate, namespace)
+	cacert := b.lookupSecret(m, validCA)
+	if cacert == nil {
+		return nil, fmt.Errorf("clientValidation: CA Secret [%s] not found or is malformed", dv.CACertificate)
+	}
+	if !b.delegationPermitted(m, namespace) {
+		return nil, fmt.Errorf("clientValidation: %s: certificate delegation not permitted", dv.CACertificate)
+	}
+
+	v := &DownstreamValidation{
+		CACertificate: cacert,
+		Optional:      dv.Optional,
+	}
+	if !isBlank(dv.CertificateRevocationList) {
+		m := splitSecret(dv.CertificateRevocationList, namespace)
+		crl := b.lookupSecret(m, validCRL)
+		if crl == nil {
+			return nil, fmt.Errorf("clientValidation: CRL Secret [%s] not found or is malformed", dv.CertificateRevocationList)
+		}
+		if !b.delegationPermitted(m, namespace) {
+			return nil, fmt.Errorf("clientValidation: %s: certificate delegation not permitted", dv.CertificateRevocationList)
+		}
+		v.CertificateRevocationList = crl
+	}
+	if ccd := dv.ForwardClientCertificate; ccd != nil {
+		v.ForwardClientCertificate = &ClientCertificateDetails{
+			Subject: ccd.Subject,
+			Cert:    ccd.Cert,
+			Chain:   ccd.Chain,
+			DNS:     ccd.DNS,
+			URI:     ccd.URI,
+		}
+	}
+	return v, nil
+}
+
 func (b *Builder) processIngressRouteTCPProxy(sw *ObjectStatusWriter, ir *ingressroutev1.IngressRoute, visited []*ingressroutev1.IngressRoute, host string) {
 	visited = append(visited, ir)
 
@@ -902,9 +2208,23 @@ func (b *Builder) processIngressRouteTCPProxy(sw *ObjectStatusWriter, ir *ingres
 				sw.SetInvalid(fmt.Sprintf("tcpproxy: service %s/%s/%d: not found", ir.Namespace, service.Name, service.Port))
 				return
 			}
+			var uv *UpstreamValidation
+			if s.Protocol == "tls" {
+				var err error
+				uv, err = b.lookupUpstreamValidation("tcpproxy", service.Name, service.UpstreamValidation, ir.Namespace)
+				if err != nil {
+					sw.SetInvalid("tcpproxy: " + err.Error())
+					return
+				}
+			}
+			if err := b.strictUpstreamTLSError(ir.Namespace, service.Name, uv); err != nil {
+				sw.SetInvalid("tcpproxy: " + err.Error())
+				return
+			}
 			proxy.Clusters = append(proxy.Clusters, &Cluster{
 				Upstream:           s,
 				LoadBalancerPolicy: service.Strategy,
+				UpstreamValidation: uv,
 			})
 		}
 		b.lookupSecureVirtualHost(host).TCPProxy = &proxy
@@ -968,17 +2288,40 @@ func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *pr
 	}
 
 	if len(tcpproxy.Services) > 0 {
+		hc, err := tcpHealthCheckPolicy(tcpproxy.HealthCheckPolicy)
+		if err != nil {
+			sw.SetInvalid("tcpproxy: " + err.Error())
+			return false
+		}
 		var proxy TCPProxy
 		for _, service := range httpproxy.Spec.TCPProxy.Services {
-			m := Meta{name: service.Name, namespace: httpproxy.Namespace}
-			s := b.lookupService(m, intstr.FromInt(service.Port))
+			s, port, err := b.lookupProxyService(httpproxy.Namespace, service)
+			if err != nil {
+				sw.SetInvalid("tcpproxy: " + err.Error())
+				return false
+			}
 			if s == nil {
-				sw.SetInvalid(fmt.Sprintf("tcpproxy: service %s/%s/%d: not found", httpproxy.Namespace, service.Name, service.Port))
+				sw.SetInvalid(fmt.Sprintf("tcpproxy: service %s/%s/%s: not found", httpproxy.Namespace, service.Name, port.String()))
+				return false
+			}
+			var uv *UpstreamValidation
+			if s.Protocol == "tls" {
+				uv, err = b.lookupUpstreamValidation("tcpproxy", service.Name, service.UpstreamValidation, httpproxy.Namespace)
+				if err != nil {
+					sw.SetInvalid("tcpproxy: " + err.Error())
+					return false
+				}
+			}
+			if err := b.strictUpstreamTLSError(httpproxy.Namespace, service.Name, uv); err != nil {
+				sw.SetInvalid("tcpproxy: " + err.Error())
 				return false
 			}
 			proxy.Clusters = append(proxy.Clusters, &Cluster{
-				Upstream:           s,
-				LoadBalancerPolicy: loadBalancerPolicy(tcpproxy.LoadBalancerPolicy),
+				Upstream:             s,
+				UpstreamValidation:   uv,
+				LoadBalancerPolicy:   loadBalancerPolicy(tcpproxy.LoadBalancerPolicy),
+				TCPHealthCheckPolicy: hc,
+				CircuitBreakers:      circuitBreakers(service.CircuitBreakerPolicy),
 			})
 		}
 		b.lookupSecureVirtualHost(host).TCPProxy = &proxy
@@ -1065,6 +2408,15 @@ func route(ingress *v1beta1.Ingress, path string, service *Service) *Route {
 	return r
 }
 
+// isWildcardFqdn indicates if fqdn is a wildcard, such as *.example.com,
+// which matches any host in the domain following its first label. Envoy
+// prefers the virtual host of an exact fqdn, then that of the longest
+// wildcard, so a wildcard may overlap the fqdns of other HTTPProxies.
+func isWildcardFqdn(fqdn string) bool {
+	domain := strings.TrimPrefix(fqdn, "*.")
+	return domain != fqdn && strings.Contains(domain, ".") && !strings.Contains(domain, "*")
+}
+
 // isBlank indicates if a string contains nothing but blank characters.
 func isBlank(s string) bool {
 	return len(strings.TrimSpace(s)) == 0
@@ -1132,6 +2484,11 @@ func validCA(s *v1.Secret) bool {
 	return len(s.Data["ca.crt"]) > 0
 }
 
+// validCRL returns true if the Secret contains a certificate revocation list.
+func validCRL(s *v1.Secret) bool {
+	return len(s.Data["crl.pem"]) > 0
+}
+
 // routeEnforceTLS determines if the route should redirect the user to a secure TLS listener
 func routeEnforceTLS(enforceTLS, permitInsecure bool) bool {
 	return enforceTLS && !permitInsecure
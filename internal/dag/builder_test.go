@@ -14,11 +14,14 @@
 package dag
 
 import (
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	v1 "k8s.io/api/core/v1"
@@ -1767,6 +1770,23 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// s14 is an ExternalName service
+	s14 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "saas",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Type:         v1.ServiceTypeExternalName,
+			ExternalName: "api.example.com",
+			Ports: []v1.ServicePort{{
+				Name:     "https",
+				Protocol: "TCP",
+				Port:     443,
+			}},
+		},
+	}
+
 	// ir18 tcp forwards traffic to by TLS pass-throughing
 	// it. It also exposes non HTTP traffic to the the non secure port of the
 	// application so it can give an informational message
@@ -2009,6 +2029,70 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// proxy1f has a service with upstream connection timeouts
+	// and tcp keepalive
+	proxy1f := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name:                  "kuard",
+					Port:                  8080,
+					IdleTimeout:           "30s",
+					MaxConnectionDuration: "infinity",
+					ConnectTimeout:        "2s",
+					TCPKeepalive: &projcontour.TCPKeepalive{
+						Probes:   3,
+						Time:     "5m",
+						Interval: "30s",
+					},
+				}},
+			}},
+		},
+	}
+
+	// proxy1g resolves an ExternalName service with logical dns
+	proxy1g := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/api",
+				}},
+				Services: []projcontour.Service{{
+					Name:                  s14.Name,
+					Port:                  443,
+					DNSLookup:             "logical",
+					HealthyPanicThreshold: 50,
+				}},
+			}, {
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name:      "kuard",
+					Port:      8080,
+					DNSLookup: "logical",
+				}},
+			}},
+		},
+	}
+
 	// proxy6 has TLS and does not specify min tls version
 	proxy6 := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
@@ -2181,6 +2265,32 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	proxy13a := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				MirrorPolicy: &projcontour.MirrorPolicy{
+					Name:    s2.Name,
+					Port:    8080,
+					Percent: 25,
+				},
+			}},
+		},
+	}
+
 	// invalid because tcpproxy both includes another and
 	// has a list of services.
 	proxy37 := &projcontour.HTTPProxy{
@@ -2226,6 +2336,32 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// Invalid because the CA of its client validation is
+	// missing, so its tcpproxy must not be served either.
+	proxy37b := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mtls",
+			Namespace: sec1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mtls.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: "missing",
+					},
+				},
+			},
+			TCPProxy: &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			},
+		},
+	}
+
 	// proxy38 is invalid when combined with proxy39
 	// as the latter is a root.
 	proxy38 := &projcontour.HTTPProxy{
@@ -4716,6 +4852,58 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert httpproxy w/ upstream connection timeouts": {
+			objs: []interface{}{
+				proxy1f, s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com",
+							routeCluster("/", &Cluster{
+								Upstream:              service(s1),
+								IdleTimeout:           30 * time.Second,
+								MaxConnectionDuration: -1,
+								ConnectTimeout:        2 * time.Second,
+								TCPKeepalive: &TCPKeepalive{
+									Probes:   3,
+									Time:     5 * time.Minute,
+									Interval: 30 * time.Second,
+								},
+							}),
+						),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ logical dns externalname service": {
+			objs: []interface{}{
+				proxy1g, s1, s14,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com",
+							routeCluster("/api", &Cluster{
+								Upstream: &Service{
+									Name:         s14.Name,
+									Namespace:    s14.Namespace,
+									ServicePort:  &s14.Spec.Ports[0],
+									ExternalName: "api.example.com",
+								},
+								LogicalDNS:            true,
+								HealthyPanicThreshold: 50,
+							}),
+							// logical dns is ignored for services
+							// which are not ExternalNames.
+							prefixroute("/", service(s1)),
+						),
+					),
+				},
+			),
+		},
 		"insert httpproxy with mirroring route": {
 			objs: []interface{}{
 				proxy12, s1, s2,
@@ -4731,6 +4919,34 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert httpproxy with mirror policy": {
+			objs: []interface{}{
+				proxy13a, s1, s2,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com",
+							withMirrorPercent(prefixroute("/", service(s1)), service(s2), 25),
+						),
+					),
+				},
+			),
+		},
+		"insert httpproxy with mirror policy of missing service": {
+			objs: []interface{}{
+				proxy13a, s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", prefixroute("/", service(s1))),
+					),
+				},
+			),
+		},
 		"insert httpproxy with two mirrors": {
 			objs: []interface{}{
 				proxy13, s1, s2,
@@ -4835,6 +5051,10 @@ func TestDAGInsert(t *testing.T) {
 			objs: []interface{}{proxy37a, s1},
 			want: listeners(),
 		},
+		"insert httpproxy w/ tcpproxy w/ client validation w/ missing ca": {
+			objs: []interface{}{proxy37b, s1, sec1},
+			want: listeners(),
+		},
 		"insert httpproxy w/ tcpproxy w/ missing include": {
 			objs: []interface{}{proxy38, s1},
 			want: listeners(),
@@ -5166,7 +5386,15 @@ func TestDAGInsert(t *testing.T) {
 			objs: []interface{}{
 				proxy103, proxy103a, s1,
 			},
-			want: listeners(),
+			// the invalid include is skipped, the proxy's own route is served.
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", prefixroute("/", service(s1))),
+					),
+				},
+			),
 		},
 		"insert httpproxy duplicate conditions on include": {
 			objs: []interface{}{
@@ -5288,6 +5516,8 @@ func TestDAGInsert(t *testing.T) {
 			}
 			opts := []cmp.Option{
 				cmp.AllowUnexported(VirtualHost{}),
+				// route names are covered by TestDAGRouteName.
+				cmpopts.IgnoreFields(Route{}, "Name"),
 			}
 			if diff := cmp.Diff(want, got, opts...); diff != "" {
 				t.Fatal(diff)
@@ -5321,7 +5551,7 @@ func ingressrulevalue(backend *v1beta1.IngressBackend) v1beta1.IngressRuleValue
 	}
 }
 
-func TestBuilderLookupService(t *testing.T) {
+func TestDAGRouteName(t *testing.T) {
 	s1 := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "kuard",
@@ -5329,120 +5559,1705 @@ func TestBuilderLookupService(t *testing.T) {
 		},
 		Spec: v1.ServiceSpec{
 			Ports: []v1.ServicePort{{
-				Name:       "http",
 				Protocol:   "TCP",
 				Port:       8080,
 				TargetPort: intstr.FromInt(8080),
 			}},
 		},
 	}
-	services := map[Meta]*v1.Service{
-		{name: "service1", namespace: "default"}: s1,
-	}
-
-	tests := map[string]struct {
-		Meta
-		port intstr.IntOrString
-		want *Service
-	}{
-		"lookup service by port number": {
-			Meta: Meta{name: "service1", namespace: "default"},
-			port: intstr.FromInt(8080),
-			want: service(s1),
-		},
-		"lookup service by port name": {
-			Meta: Meta{name: "service1", namespace: "default"},
-			port: intstr.FromString("http"),
-			want: service(s1),
-		},
-		"lookup service by port number (as string)": {
-			Meta: Meta{name: "service1", namespace: "default"},
-			port: intstr.Parse("8080"),
-			want: service(s1),
-		},
-		"lookup service by port number (from string)": {
-			Meta: Meta{name: "service1", namespace: "default"},
-			port: intstr.FromString("8080"),
-			want: service(s1),
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: "marketing",
 		},
+		Spec: s1.Spec,
 	}
-
-	for name, tc := range tests {
-		t.Run(name, func(t *testing.T) {
-			b := Builder{
-				Source: KubernetesCache{
-					services:    services,
-					FieldLogger: testLogger(t),
-				},
-			}
-			b.reset()
-			got := b.lookupService(tc.Meta, tc.port)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
-				t.Fatal(diff)
-			}
-		})
-	}
-}
-
-func TestDAGRootNamespaces(t *testing.T) {
-	ir1 := &ingressroutev1.IngressRoute{
+	proxy1 := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "example-com",
-			Namespace: "allowed1",
+			Namespace: "default",
 		},
-		Spec: ingressroutev1.IngressRouteSpec{
+		Spec: projcontour.HTTPProxySpec{
 			VirtualHost: &projcontour.VirtualHost{
 				Fqdn: "example.com",
 			},
-			Routes: []ingressroutev1.Route{{
-				Match: "/",
-				Services: []ingressroutev1.Service{{
-					Name: "kuard",
-					Port: 8080,
+			Includes: []projcontour.Include{{
+				Name:      "blog",
+				Namespace: "marketing",
+				Conditions: []projcontour.Condition{{
+					Prefix: "/blog",
 				}},
 			}},
-		},
-	}
-
-	// ir2 is like ir1, but in a different namespace
-	ir2 := &ingressroutev1.IngressRoute{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "example-com",
-			Namespace: "allowed2",
-		},
-		Spec: ingressroutev1.IngressRouteSpec{
-			VirtualHost: &projcontour.VirtualHost{
-				Fqdn: "example2.com",
-			},
-			Routes: []ingressroutev1.Route{{
-				Match: "/",
-				Services: []ingressroutev1.Service{{
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
 					Name: "kuard",
 					Port: 8080,
 				}},
 			}},
 		},
 	}
-
-	proxy1 := &projcontour.HTTPProxy{
+	proxy2 := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "example-com",
-			Namespace: "allowed1",
+			Name:      "blog",
+			Namespace: "marketing",
 		},
 		Spec: projcontour.HTTPProxySpec{
-			VirtualHost: &projcontour.VirtualHost{
-				Fqdn: "example.com",
-			},
 			Routes: []projcontour.Route{{
 				Services: []projcontour.Service{{
-					Name: "kuard",
+					Name: "blog",
 					Port: 8080,
 				}},
 			}},
 		},
 	}
 
-	// proxy2 is like proxy1, but in a different namespace
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: testLogger(t),
+		},
+	}
+	for _, o := range []interface{}{s1, s2, proxy1, proxy2} {
+		builder.Source.Insert(o)
+	}
+	dag := builder.Build()
+
+	got := make(map[string]string)
+	var visit func(Vertex)
+	visit = func(v Vertex) {
+		if r, ok := v.(*Route); ok {
+			got[r.PathCondition.(*PrefixCondition).Prefix] = r.Name
+			return
+		}
+		v.Visit(visit)
+	}
+	dag.Visit(visit)
+
+	// routes are named for the proxy which defined them, not the root.
+	want := map[string]string{
+		"/":     "default/example-com",
+		"/blog": "marketing/blog",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDAGDefaultCircuitBreakers(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/max-connections": "9000",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: []projcontour.Condition{{
+					Prefix: "/api",
+				}},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+					CircuitBreakerPolicy: &projcontour.CircuitBreakerPolicy{
+						MaxRequests: 100,
+					},
+				}},
+			}},
+		},
+	}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: testLogger(t),
+		},
+		DefaultCircuitBreakers: CircuitBreakers{
+			MaxConnections: 1024,
+			MaxRequests:    2048,
+			MaxRetries:     8,
+		},
+	}
+	for _, o := range []interface{}{s1, proxy1} {
+		builder.Source.Insert(o)
+	}
+	dag := builder.Build()
+
+	type limits struct {
+		Service  CircuitBreakers
+		Override *CircuitBreakers
+	}
+	got := make(map[string]limits)
+	var visit func(Vertex)
+	visit = func(v Vertex) {
+		if r, ok := v.(*Route); ok {
+			c := r.Clusters[0]
+			got[r.PathCondition.(*PrefixCondition).Prefix] = limits{
+				Service: CircuitBreakers{
+					MaxConnections:     c.Upstream.MaxConnections,
+					MaxPendingRequests: c.Upstream.MaxPendingRequests,
+					MaxRequests:        c.Upstream.MaxRequests,
+					MaxRetries:         c.Upstream.MaxRetries,
+				},
+				Override: c.CircuitBreakers,
+			}
+			return
+		}
+		v.Visit(visit)
+	}
+	dag.Visit(visit)
+
+	// the annotation takes precedence over the default, and the
+	// policy of the /api route's service over both.
+	service := CircuitBreakers{
+		MaxConnections: 9000,
+		MaxRequests:    2048,
+		MaxRetries:     8,
+	}
+	want := map[string]limits{
+		"/":    {Service: service},
+		"/api": {Service: service, Override: &CircuitBreakers{MaxRequests: 100}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDAGRouteActiveWindow(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	launch := time.Date(2019, time.December, 1, 9, 0, 0, 0, time.UTC)
+	end := launch.Add(24 * time.Hour)
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: []projcontour.Condition{{
+					Prefix: "/launch",
+				}},
+				ActiveWindow: &projcontour.ActiveWindow{
+					Start: &metav1.Time{Time: launch},
+					End:   &metav1.Time{Time: end},
+				},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy2 replaces the /launch route with another once
+	// its window closes.
+	proxy2 := proxy1.DeepCopy()
+	proxy2.Spec.Routes = []projcontour.Route{{
+		Conditions: proxy1.Spec.Routes[1].Conditions,
+		ActiveWindow: &projcontour.ActiveWindow{
+			Start: &metav1.Time{Time: end},
+		},
+		Services: []projcontour.Service{{
+			Name: "kuard",
+			Port: 8080,
+		}},
+	}, proxy1.Spec.Routes[1]}
+
+	// routes are recorded by prefix, and those which respond
+	// directly by prefix and status.
+	tests := map[string]struct {
+		proxy          *projcontour.HTTPProxy
+		now            time.Time
+		wantRoutes     []string
+		wantNextChange time.Time
+	}{
+		"before the window opens": {
+			proxy:          proxy1,
+			now:            launch.Add(-time.Hour),
+			wantRoutes:     []string{"/", "/launch 404"},
+			wantNextChange: launch,
+		},
+		"within the window": {
+			proxy:          proxy1,
+			now:            launch,
+			wantRoutes:     []string{"/", "/launch"},
+			wantNextChange: end,
+		},
+		"after the window closes": {
+			proxy:      proxy1,
+			now:        end,
+			wantRoutes: []string{"/", "/launch 404"},
+		},
+		"an active route takes the place of an inactive one": {
+			proxy:      proxy2,
+			now:        end,
+			wantRoutes: []string{"/launch"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+				Clock: func() time.Time { return tc.now },
+			}
+			for _, o := range []interface{}{s1, tc.proxy} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+
+			var got []string
+			var visit func(Vertex)
+			visit = func(v Vertex) {
+				if r, ok := v.(*Route); ok {
+					route := r.PathCondition.(*PrefixCondition).Prefix
+					if r.DirectResponse != nil {
+						route += fmt.Sprintf(" %d", r.DirectResponse.StatusCode)
+					}
+					got = append(got, route)
+					return
+				}
+				v.Visit(visit)
+			}
+			dag.Visit(visit)
+			sort.Strings(got)
+
+			if diff := cmp.Diff(tc.wantRoutes, got); diff != "" {
+				t.Fatal(diff)
+			}
+			if !tc.wantNextChange.Equal(dag.NextChange()) {
+				t.Fatalf("expected next change %v, got %v", tc.wantNextChange, dag.NextChange())
+			}
+		})
+	}
+}
+
+func TestDAGMaintenance(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:        "example.com",
+				Maintenance: true,
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy2 has no valid routes, so is invalid.
+	proxy2 := &projcontour.HTTPProxy{
+		ObjectMeta: proxy1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: proxy1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name:      "kuard",
+					Port:      8080,
+					DNSLookup: "sometimes",
+				}},
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		objs []interface{}
+		want []uint32
+	}{
+		"valid proxy": {
+			objs: []interface{}{s1, proxy1},
+			want: []uint32{503},
+		},
+		"invalid proxy": {
+			objs: []interface{}{s1, proxy2},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			for _, o := range tc.objs {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+
+			var got []uint32
+			var visit func(Vertex)
+			visit = func(v Vertex) {
+				if r, ok := v.(*Route); ok {
+					if r.DirectResponse != nil {
+						got = append(got, r.DirectResponse.StatusCode)
+					}
+					return
+				}
+				v.Visit(visit)
+			}
+			dag.Visit(visit)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGFetchSecretJWTProviders(t *testing.T) {
+	secrets := map[string]*v1.Secret{
+		"secret": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Type: v1.SecretTypeTLS,
+			Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+		},
+		"jwks-ca": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jwks-ca",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"ca.crt": []byte(CERTIFICATE),
+			},
+		},
+	}
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jwt",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "jwt.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: "secret",
+				},
+				JWTProviders: []projcontour.JWTProvider{{
+					Name: "provider-1",
+					RemoteJWKS: projcontour.RemoteJWKS{
+						URI: "https://auth.example.com/jwks.json",
+						UpstreamValidation: &projcontour.UpstreamValidation{
+							CACertificate: "jwks-ca",
+							SubjectName:   "auth.example.com",
+						},
+					},
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: testLogger(t),
+			FetchSecret: func(namespace, name string) (*v1.Secret, error) {
+				return secrets[name], nil
+			},
+		},
+	}
+	// only the metadata of secrets is inserted, their
+	// contents are fetched as they are referenced.
+	for _, sec := range secrets {
+		builder.Source.Insert(&v1.Secret{ObjectMeta: sec.ObjectMeta, Type: sec.Type})
+	}
+	builder.Source.Insert(s1)
+	builder.Source.Insert(proxy1)
+	dag := builder.Build()
+
+	want := map[Meta]Status{
+		{name: proxy1.Name, namespace: proxy1.Namespace}: {
+			Object:      proxy1,
+			Status:      StatusValid,
+			Description: "valid HTTPProxy",
+			Vhost:       "jwt.example.com",
+		},
+	}
+	if diff := cmp.Diff(want, dag.Statuses()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDAGDeletedSecretGracePeriod(t *testing.T) {
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	source := KubernetesCache{
+		FieldLogger:               testLogger(t),
+		SecretDeletionGracePeriod: time.Hour,
+	}
+	for _, o := range []interface{}{sec1, s1, proxy1} {
+		source.Insert(o)
+	}
+	source.Remove(sec1)
+	expires := source.deletedSecrets[toMeta(sec1)].expires
+
+	tests := map[string]struct {
+		now        time.Time
+		wantSecret bool
+		want       Status
+	}{
+		"within the grace period": {
+			now:        expires.Add(-time.Minute),
+			wantSecret: true,
+			want: Status{
+				Object:      proxy1,
+				Status:      StatusWarning,
+				Description: "TLS Secret [secret] has been deleted, its last certificate is served until " + expires.UTC().Format(time.RFC3339),
+				Vhost:       "example.com",
+			},
+		},
+		"after the grace period": {
+			now: expires,
+			want: Status{
+				Object:      proxy1,
+				Status:      StatusInvalid,
+				Description: "TLS Secret [secret] not found or is malformed",
+				Vhost:       "example.com",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: source,
+				Clock:  func() time.Time { return tc.now },
+			}
+			dag := builder.Build()
+
+			var gotSecret bool
+			var visit func(Vertex)
+			visit = func(v Vertex) {
+				if svh, ok := v.(*SecureVirtualHost); ok {
+					gotSecret = svh.Secret != nil
+					return
+				}
+				v.Visit(visit)
+			}
+			dag.Visit(visit)
+			if tc.wantSecret != gotSecret {
+				t.Fatalf("expected secret served: %v, got %v", tc.wantSecret, gotSecret)
+			}
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(proxy1)]); diff != "" {
+				t.Fatal(diff)
+			}
+			if tc.wantSecret && !expires.Equal(dag.NextChange()) {
+				t.Fatalf("expected next change %v, got %v", expires, dag.NextChange())
+			}
+		})
+	}
+}
+
+func TestDAGUpstreamTrustDomains(t *testing.T) {
+	corpCA := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "corp-ca",
+			Namespace: "projectcontour",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	publicCA := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "public-ca",
+			Namespace: "projectcontour",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "internal",
+			Annotations: map[string]string{
+				"projectcontour.io/upstream-protocol.tls": "8080",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "internal",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+					UpstreamValidation: &projcontour.UpstreamValidation{
+						SubjectName: "kuard.internal",
+					},
+				}},
+			}},
+		},
+	}
+
+	corp := UpstreamTrustDomain{
+		Name:              "corp",
+		CASecretNamespace: "projectcontour",
+		CASecretName:      "corp-ca",
+		Namespaces:        []string{"internal"},
+	}
+
+	tests := map[string]struct {
+		domains []UpstreamTrustDomain
+		wantCA  string
+		want    Status
+	}{
+		"namespace trust domain": {
+			domains: []UpstreamTrustDomain{corp},
+			wantCA:  "corp-ca",
+			want:    Status{Object: proxy1, Status: StatusValid, Description: "valid HTTPProxy", Vhost: "example.com"},
+		},
+		"service trust domain takes precedence over namespace": {
+			domains: []UpstreamTrustDomain{corp, {
+				Name:              "public",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "public-ca",
+				Services:          []string{"internal/kuard"},
+			}},
+			wantCA: "public-ca",
+			want:   Status{Object: proxy1, Status: StatusValid, Description: "valid HTTPProxy", Vhost: "example.com"},
+		},
+		"default trust domain": {
+			domains: []UpstreamTrustDomain{{
+				Name:              "public",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "public-ca",
+			}},
+			wantCA: "public-ca",
+			want:   Status{Object: proxy1, Status: StatusValid, Description: "valid HTTPProxy", Vhost: "example.com"},
+		},
+		"no trust domain applies": {
+			domains: []UpstreamTrustDomain{{
+				Name:              "other",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "corp-ca",
+				Namespaces:        []string{"other"},
+			}},
+			want: Status{
+				Object:      proxy1,
+				Status:      StatusInvalid,
+				Description: `route "??": service "kuard": upstreamValidation requested but no caSecret set and no upstream trust domain applies`,
+				Vhost:       "example.com",
+			},
+		},
+		"trust domain secret missing": {
+			domains: []UpstreamTrustDomain{{
+				Name:              "missing",
+				CASecretNamespace: "projectcontour",
+				CASecretName:      "missing-ca",
+			}},
+			want: Status{
+				Object:      proxy1,
+				Status:      StatusInvalid,
+				Description: `route "??": service "kuard": upstreamValidation requested but secret not found or misconfigured`,
+				Vhost:       "example.com",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger:          testLogger(t),
+					UpstreamTrustDomains: tc.domains,
+				},
+			}
+			for _, o := range []interface{}{corpCA, publicCA, s1, proxy1} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+
+			var gotCA string
+			var visit func(Vertex)
+			visit = func(v Vertex) {
+				if c, ok := v.(*Cluster); ok && c.UpstreamValidation != nil {
+					gotCA = c.UpstreamValidation.CACertificate.Object.Name
+				}
+				v.Visit(visit)
+			}
+			dag.Visit(visit)
+			if tc.wantCA != gotCA {
+				t.Fatalf("expected CA %q, got %q", tc.wantCA, gotCA)
+			}
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(proxy1)]); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGUpstreamClientCertificate(t *testing.T) {
+	client := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "client",
+			Namespace: "internal",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	shared := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared-client",
+			Namespace: "projectcontour",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	undelegated := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-client",
+			Namespace: "projectcontour",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	delegation := &projcontour.TLSCertificateDelegation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "delegation",
+			Namespace: "projectcontour",
+		},
+		Spec: projcontour.TLSCertificateDelegationSpec{
+			Delegations: []projcontour.CertificateDelegation{{
+				SecretName:       "shared-client",
+				TargetNamespaces: []string{"internal"},
+			}},
+		},
+	}
+
+	tlsService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "internal",
+			Annotations: map[string]string{
+				"projectcontour.io/upstream-protocol.tls": "8443",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8443,
+				TargetPort: intstr.FromInt(8443),
+			}},
+		},
+	}
+	plainService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plain",
+			Namespace: "internal",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy := func(service string, port int, clientCertificate string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "internal",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name:              service,
+						Port:              port,
+						ClientCertificate: clientCertificate,
+					}},
+				}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		proxy      *projcontour.HTTPProxy
+		wantSecret string
+		want       string
+	}{
+		"same namespace": {
+			proxy:      proxy("kuard", 8443, "client"),
+			wantSecret: "internal/client",
+			want:       "valid HTTPProxy",
+		},
+		"delegated from another namespace": {
+			proxy:      proxy("kuard", 8443, "projectcontour/shared-client"),
+			wantSecret: "projectcontour/shared-client",
+			want:       "valid HTTPProxy",
+		},
+		"not delegated": {
+			proxy: proxy("kuard", 8443, "projectcontour/other-client"),
+			want:  `service "kuard": clientCertificate projectcontour/other-client: certificate delegation not permitted`,
+		},
+		"missing secret": {
+			proxy: proxy("kuard", 8443, "missing"),
+			want:  `service "kuard": clientCertificate Secret [missing] not found or is malformed`,
+		},
+		"plaintext upstream": {
+			proxy: proxy("plain", 8080, "client"),
+			want:  `service "plain": clientCertificate requires the tls or h2 upstream protocol`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			for _, o := range []interface{}{client, shared, undelegated, delegation, tlsService, plainService, tc.proxy} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+
+			var gotSecret string
+			var visit func(Vertex)
+			visit = func(v Vertex) {
+				if c, ok := v.(*Cluster); ok && c.ClientCertificate != nil {
+					gotSecret = c.ClientCertificate.Namespace() + "/" + c.ClientCertificate.Name()
+				}
+				v.Visit(visit)
+			}
+			dag.Visit(visit)
+			if tc.wantSecret != gotSecret {
+				t.Fatalf("expected client certificate %q, got %q", tc.wantSecret, gotSecret)
+			}
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(tc.proxy)].Description); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGUpstreamValidationPins(t *testing.T) {
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/upstream-protocol.tls": "8443",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8443,
+				TargetPort: intstr.FromInt(8443),
+			}},
+		},
+	}
+	proxy := func(uv *projcontour.UpstreamValidation) *projcontour.HTTPProxy {
+		uv.CACertificate = ca.Name
+		uv.SubjectName = "kuard.default"
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name:               service.Name,
+						Port:               8443,
+						UpstreamValidation: uv,
+					}},
+				}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		uv   *projcontour.UpstreamValidation
+		want string
+	}{
+		"subject names and pins": {
+			uv: &projcontour.UpstreamValidation{
+				SubjectNames:      []string{"kuard.internal"},
+				SPKIPins:          []string{"ZPRqdSahhtI0ZVJFOuR4ylEkRnT1shuhUL1IOzn3yBI="},
+				CertificateHashes: []string{"64f46a7526a186d2346552453ae478ca51244674f5b21ba150bd483b39f7c812"},
+			},
+			want: "valid HTTPProxy",
+		},
+		"certificate hash with colons": {
+			uv: &projcontour.UpstreamValidation{
+				CertificateHashes: []string{"64:F4:6A:75:26:A1:86:D2:34:65:52:45:3A:E4:78:CA:51:24:46:74:F5:B2:1B:A1:50:BD:48:3B:39:F7:C8:12"},
+			},
+			want: "valid HTTPProxy",
+		},
+		"blank subject name": {
+			uv: &projcontour.UpstreamValidation{
+				SubjectNames: []string{" "},
+			},
+			want: `route "??": service "kuard": upstreamValidation subjectNames must not be blank`,
+		},
+		"spki pin not base64": {
+			uv: &projcontour.UpstreamValidation{
+				SPKIPins: []string{"not a pin"},
+			},
+			want: `route "??": service "kuard": upstreamValidation spkiPin "not a pin" is not a base64 encoded SHA-256 hash`,
+		},
+		"spki pin too short": {
+			uv: &projcontour.UpstreamValidation{
+				SPKIPins: []string{"cGlu"},
+			},
+			want: `route "??": service "kuard": upstreamValidation spkiPin "cGlu" is not a base64 encoded SHA-256 hash`,
+		},
+		"certificate hash not hex": {
+			uv: &projcontour.UpstreamValidation{
+				CertificateHashes: []string{"zz"},
+			},
+			want: `route "??": service "kuard": upstreamValidation certificateHash "zz" is not a hex encoded SHA-256 hash`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			p := proxy(tc.uv)
+			for _, o := range []interface{}{ca, service, p} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(p)].Description); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGTLSProtocolVersions(t *testing.T) {
+	sec := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	proxy := func(min, max string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+					TLS: &projcontour.TLS{
+						SecretName:             sec.Name,
+						MinimumProtocolVersion: min,
+						MaximumProtocolVersion: max,
+					},
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name: service.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+	ingressroute := func(min, max string) *ingressroutev1.IngressRoute {
+		return &ingressroutev1.IngressRoute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "default",
+			},
+			Spec: ingressroutev1.IngressRouteSpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+					TLS: &projcontour.TLS{
+						SecretName:             sec.Name,
+						MinimumProtocolVersion: min,
+						MaximumProtocolVersion: max,
+					},
+				},
+				Routes: []ingressroutev1.Route{{
+					Match: "/",
+					Services: []ingressroutev1.Service{{
+						Name: service.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		obj        Object
+		want       string
+		wantMin    envoy_api_v2_auth.TlsParameters_TlsProtocol
+		wantMax    envoy_api_v2_auth.TlsParameters_TlsProtocol
+		wantSecure bool
+	}{
+		"defaults": {
+			obj:        proxy("", ""),
+			want:       "valid HTTPProxy",
+			wantMin:    envoy_api_v2_auth.TlsParameters_TLSv1_1,
+			wantMax:    envoy_api_v2_auth.TlsParameters_TLS_AUTO,
+			wantSecure: true,
+		},
+		"tls 1.2 only": {
+			obj:        proxy("1.2", "1.2"),
+			want:       "valid HTTPProxy",
+			wantMin:    envoy_api_v2_auth.TlsParameters_TLSv1_2,
+			wantMax:    envoy_api_v2_auth.TlsParameters_TLSv1_2,
+			wantSecure: true,
+		},
+		"unsupported minimum": {
+			obj:  proxy("1.0", ""),
+			want: `TLS minimumProtocolVersion "1.0" is not supported, must be 1.1, 1.2 or 1.3`,
+		},
+		"unsupported maximum": {
+			obj:  proxy("", "2.0"),
+			want: `TLS maximumProtocolVersion "2.0" is not supported, must be 1.1, 1.2 or 1.3`,
+		},
+		"maximum below minimum": {
+			obj:  proxy("1.3", "1.2"),
+			want: `TLS maximumProtocolVersion "1.2" is below minimumProtocolVersion "1.3"`,
+		},
+		"ingressroute maximum": {
+			obj:        ingressroute("1.2", "1.3"),
+			want:       "valid IngressRoute",
+			wantMin:    envoy_api_v2_auth.TlsParameters_TLSv1_2,
+			wantMax:    envoy_api_v2_auth.TlsParameters_TLSv1_3,
+			wantSecure: true,
+		},
+		"ingressroute maximum below minimum": {
+			obj:  ingressroute("1.3", "1.1"),
+			want: `TLS maximumProtocolVersion "1.1" is below minimumProtocolVersion "1.3"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			for _, o := range []interface{}{sec, service, tc.obj} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(tc.obj)].Description); diff != "" {
+				t.Fatal(diff)
+			}
+
+			var svh *SecureVirtualHost
+			dag.Visit(func(v Vertex) {
+				if l, ok := v.(*Listener); ok {
+					for _, vh := range l.VirtualHosts {
+						if s, ok := vh.(*SecureVirtualHost); ok {
+							svh = s
+						}
+					}
+				}
+			})
+			if (svh != nil) != tc.wantSecure {
+				t.Fatalf("expected secure virtual host: %v, got %v", tc.wantSecure, svh)
+			}
+			if svh != nil && (svh.MinProtoVersion != tc.wantMin || svh.MaxProtoVersion != tc.wantMax) {
+				t.Fatalf("expected versions %v-%v, got %v-%v", tc.wantMin, tc.wantMax, svh.MinProtoVersion, svh.MaxProtoVersion)
+			}
+		})
+	}
+}
+
+func TestDAGTLSCipherSuites(t *testing.T) {
+	sec := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	proxy := func(suites ...string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+					TLS: &projcontour.TLS{
+						SecretName:   sec.Name,
+						CipherSuites: suites,
+					},
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name: service.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		proxy      *projcontour.HTTPProxy
+		want       string
+		wantSuites []string
+	}{
+		"default": {
+			proxy: proxy(),
+			want:  "valid HTTPProxy",
+		},
+		"supported": {
+			proxy:      proxy("[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]", "ECDHE-ECDSA-AES256-GCM-SHA384"),
+			want:       "valid HTTPProxy",
+			wantSuites: []string{"[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]", "ECDHE-ECDSA-AES256-GCM-SHA384"},
+		},
+		"unsupported": {
+			proxy: proxy("ECDHE-ECDSA-AES256-GCM-SHA384", "RC4-SHA"),
+			want:  `TLS cipherSuites: cipher suite "RC4-SHA" is not supported`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+			}
+			for _, o := range []interface{}{sec, service, tc.proxy} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(tc.proxy)].Description); diff != "" {
+				t.Fatal(diff)
+			}
+
+			var gotSuites []string
+			dag.Visit(func(v Vertex) {
+				if l, ok := v.(*Listener); ok {
+					for _, vh := range l.VirtualHosts {
+						if svh, ok := vh.(*SecureVirtualHost); ok {
+							gotSuites = svh.CipherSuites
+						}
+					}
+				}
+			})
+			if diff := cmp.Diff(tc.wantSuites, gotSuites); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGStrictUpstreamTLS(t *testing.T) {
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca",
+			Namespace: "payments",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	tlsService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ledger",
+			Namespace: "payments",
+			Annotations: map[string]string{
+				"projectcontour.io/upstream-protocol.tls": "8443",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8443,
+				TargetPort: intstr.FromInt(8443),
+			}},
+		},
+	}
+	plainService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "payments",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	proxy := func(service string, port int, uv *projcontour.UpstreamValidation) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-com",
+				Namespace: "payments",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name:               service,
+						Port:               port,
+						UpstreamValidation: uv,
+					}},
+				}},
+			},
+		}
+	}
+	validation := &projcontour.UpstreamValidation{
+		CACertificate: ca.Name,
+		SubjectName:   "ledger.payments",
+	}
+	ingressroute := &ingressroutev1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "payments",
+		},
+		Spec: ingressroutev1.IngressRouteSpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []ingressroutev1.Route{{
+				Match: "/",
+				Services: []ingressroutev1.Service{{
+					Name: plainService.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+	sec := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "payments",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	// validProxy returns a valid proxy, with TLS, modified by f.
+	validProxy := func(f func(*projcontour.HTTPProxy)) *projcontour.HTTPProxy {
+		p := proxy("ledger", 8443, validation)
+		p.Spec.VirtualHost.TLS = &projcontour.TLS{SecretName: sec.Name}
+		f(p)
+		return p
+	}
+	tcpproxy := func(service string, port int, uv *projcontour.UpstreamValidation) *projcontour.HTTPProxy {
+		return validProxy(func(p *projcontour.HTTPProxy) {
+			p.Spec.Routes = nil
+			p.Spec.TCPProxy = &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name:               service,
+					Port:               port,
+					UpstreamValidation: uv,
+				}},
+			}
+		})
+	}
+	maintenance := func(service string, port int, uv *projcontour.UpstreamValidation) *projcontour.HTTPProxy {
+		return validProxy(func(p *projcontour.HTTPProxy) {
+			p.Spec.VirtualHost.Maintenance = true
+			p.Spec.VirtualHost.MaintenancePolicy = &projcontour.MaintenancePolicy{
+				Service: &projcontour.Service{
+					Name:               service,
+					Port:               port,
+					UpstreamValidation: uv,
+				},
+			}
+		})
+	}
+
+	tests := map[string]struct {
+		namespaces       []string
+		rateLimitService RateLimitServiceConfig
+		obj              interface{}
+		want             string
+	}{
+		"tls with validation": {
+			namespaces: []string{"payments"},
+			obj:        proxy("ledger", 8443, validation),
+			want:       "valid HTTPProxy",
+		},
+		"tls without validation": {
+			namespaces: []string{"payments"},
+			obj:        proxy("ledger", 8443, nil),
+			want:       `service "ledger": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"plaintext": {
+			namespaces: []string{"*"},
+			obj:        proxy("kuard", 8080, nil),
+			want:       `service "kuard": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"plaintext in another namespace": {
+			namespaces: []string{"accounts"},
+			obj:        proxy("kuard", 8080, nil),
+			want:       "valid HTTPProxy",
+		},
+		"ingressroute plaintext": {
+			namespaces: []string{"payments"},
+			obj:        ingressroute,
+			want:       `route "/": service "kuard": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"tcpproxy tls with validation": {
+			namespaces: []string{"payments"},
+			obj:        tcpproxy("ledger", 8443, validation),
+			want:       "valid HTTPProxy",
+		},
+		"tcpproxy plaintext": {
+			namespaces: []string{"payments"},
+			obj:        tcpproxy("kuard", 8080, nil),
+			want:       `tcpproxy: service "kuard": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"ingressroute tcpproxy plaintext": {
+			namespaces: []string{"payments"},
+			obj: &ingressroutev1.IngressRoute{
+				ObjectMeta: ingressroute.ObjectMeta,
+				Spec: ingressroutev1.IngressRouteSpec{
+					VirtualHost: &projcontour.VirtualHost{
+						Fqdn: "example.com",
+						TLS:  &projcontour.TLS{SecretName: sec.Name},
+					},
+					TCPProxy: &ingressroutev1.TCPProxy{
+						Services: []ingressroutev1.Service{{
+							Name: plainService.Name,
+							Port: 8080,
+						}},
+					},
+				},
+			},
+			want: `tcpproxy: service "kuard": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"mirror policy": {
+			namespaces: []string{"payments"},
+			obj: validProxy(func(p *projcontour.HTTPProxy) {
+				p.Spec.Routes[0].MirrorPolicy = &projcontour.MirrorPolicy{
+					Name: "ledger",
+					Port: 8443,
+				}
+			}),
+			want: `mirrorPolicy: service "ledger": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"maintenance tls with validation": {
+			namespaces: []string{"payments"},
+			obj:        maintenance("ledger", 8443, validation),
+			want:       "valid HTTPProxy",
+		},
+		"maintenance plaintext": {
+			namespaces: []string{"payments"},
+			obj:        maintenance("kuard", 8080, nil),
+			want:       `maintenancePolicy: service "kuard": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"authorization": {
+			namespaces: []string{"payments"},
+			obj: validProxy(func(p *projcontour.HTTPProxy) {
+				p.Spec.VirtualHost.Authorization = &projcontour.AuthorizationServer{
+					Name: "ledger",
+					Port: 8443,
+				}
+			}),
+			want: `authorization: service "ledger": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"rate limit service": {
+			namespaces: []string{"payments"},
+			rateLimitService: RateLimitServiceConfig{
+				Namespace: "payments",
+				Name:      "ledger",
+				Port:      8443,
+			},
+			obj: validProxy(func(p *projcontour.HTTPProxy) {
+				p.Spec.Routes[0].RateLimitPolicy = &projcontour.RateLimitPolicy{
+					Descriptors: []projcontour.RateLimitDescriptor{{
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							RemoteAddress: &projcontour.RemoteAddressDescriptor{},
+						}},
+					}},
+				}
+			}),
+			want: `rateLimitPolicy: service "ledger": upstream TLS with validation is required in namespace "payments"`,
+		},
+		"jwks over http": {
+			namespaces: []string{"payments"},
+			obj: validProxy(func(p *projcontour.HTTPProxy) {
+				p.Spec.VirtualHost.JWTProviders = []projcontour.JWTProvider{{
+					Name: "provider-1",
+					RemoteJWKS: projcontour.RemoteJWKS{
+						URI: "http://auth.example.com/jwks.json",
+					},
+				}}
+			}),
+			want: `jwtProviders: provider "provider-1": service "auth.example.com": upstream TLS with validation is required in namespace "payments"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+				StrictUpstreamTLSNamespaces: tc.namespaces,
+				RateLimitService:            tc.rateLimitService,
+			}
+			for _, o := range []interface{}{ca, sec, tlsService, plainService, tc.obj} {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+			if diff := cmp.Diff(tc.want, dag.Statuses()[toMeta(tc.obj.(Object))].Description); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	// an Ingress cannot request upstream validation,
+	// so is not served from a strict namespace.
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: testLogger(t),
+		},
+		StrictUpstreamTLSNamespaces: []string{"payments"},
+	}
+	builder.Source.Insert(plainService)
+	builder.Source.Insert(&v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "payments",
+		},
+		Spec: v1beta1.IngressSpec{
+			Backend: backend(plainService.Name, intstr.FromInt(8080)),
+		},
+	})
+	var vhosts int
+	builder.Build().Visit(func(v Vertex) {
+		if l, ok := v.(*Listener); ok {
+			vhosts += len(l.VirtualHosts)
+		}
+	})
+	if vhosts != 0 {
+		t.Fatalf("expected no virtual hosts, got %d", vhosts)
+	}
+}
+
+func TestBuilderLookupService(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:       "http",
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	services := map[Meta]*v1.Service{
+		{name: "service1", namespace: "default"}: s1,
+	}
+
+	tests := map[string]struct {
+		Meta
+		port intstr.IntOrString
+		want *Service
+	}{
+		"lookup service by port number": {
+			Meta: Meta{name: "service1", namespace: "default"},
+			port: intstr.FromInt(8080),
+			want: service(s1),
+		},
+		"lookup service by port name": {
+			Meta: Meta{name: "service1", namespace: "default"},
+			port: intstr.FromString("http"),
+			want: service(s1),
+		},
+		"lookup service by port number (as string)": {
+			Meta: Meta{name: "service1", namespace: "default"},
+			port: intstr.Parse("8080"),
+			want: service(s1),
+		},
+		"lookup service by port number (from string)": {
+			Meta: Meta{name: "service1", namespace: "default"},
+			port: intstr.FromString("8080"),
+			want: service(s1),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := Builder{
+				Source: KubernetesCache{
+					services:    services,
+					FieldLogger: testLogger(t),
+				},
+			}
+			b.reset()
+			got := b.lookupService(tc.Meta, tc.port)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestDAGRootNamespaces(t *testing.T) {
+	ir1 := &ingressroutev1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "allowed1",
+		},
+		Spec: ingressroutev1.IngressRouteSpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []ingressroutev1.Route{{
+				Match: "/",
+				Services: []ingressroutev1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// ir2 is like ir1, but in a different namespace
+	ir2 := &ingressroutev1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "allowed2",
+		},
+		Spec: ingressroutev1.IngressRouteSpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example2.com",
+			},
+			Routes: []ingressroutev1.Route{{
+				Match: "/",
+				Services: []ingressroutev1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "allowed1",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy2 is like proxy1, but in a different namespace
 	proxy2 := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "example-com",
@@ -5958,3 +7773,9 @@ func withMirror(r *Route, mirror *Service) *Route {
 	return r
 
 }
+
+func withMirrorPercent(r *Route, mirror *Service, percent uint32) *Route {
+	r = withMirror(r, mirror)
+	r.MirrorPolicy.Percent = percent
+	return r
+}
@@ -16,6 +16,7 @@ package dag
 import (
 	"bytes"
 	"encoding/json"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
@@ -37,10 +38,31 @@ type KubernetesCache struct {
 	// namespace.
 	RootNamespaces []string
 
-	// Contour's IngressClass.
+	// Contour's IngressClass, or a comma separated list of
+	// IngressClasses.
 	// If not set, defaults to DEFAULT_INGRESS_CLASS.
 	IngressClass string
 
+	// IngressClassRegex treats each of the IngressClasses as a
+	// regular expression matching the whole ingress class.
+	IngressClassRegex bool
+
+	// SecretDeletionGracePeriod is how long the last contents of a
+	// deleted Secret continue to be served.
+	// If not set, deleted Secrets are dropped immediately.
+	SecretDeletionGracePeriod time.Duration
+
+	// FetchSecret, if set, returns the named Secret from the API server.
+	// The Secrets inserted into the cache are then taken to carry their
+	// metadata only, and the cache holds the contents of just those
+	// Secrets referenced by its Ingresses, IngressRoutes and HTTPProxies,
+	// fetching them when the DAG is built.
+	FetchSecret func(namespace, name string) (*v1.Secret, error)
+
+	// UpstreamTrustDomains name the CA bundles which validate the
+	// upstream services whose upstreamValidation names no caSecret.
+	UpstreamTrustDomains []UpstreamTrustDomain
+
 	ingresses            map[Meta]*v1beta1.Ingress
 	ingressroutes        map[Meta]*ingressroutev1.IngressRoute
 	httpproxies          map[Meta]*projectcontour.HTTPProxy
@@ -49,9 +71,69 @@ type KubernetesCache struct {
 	httpproxydelegations map[Meta]*projectcontour.TLSCertificateDelegation
 	services             map[Meta]*v1.Service
 
+	// deletedSecrets holds the Secrets which have been deleted,
+	// but are still within their grace period.
+	deletedSecrets map[Meta]deletedSecret
+
+	// secretNames holds the Secrets known to exist when FetchSecret
+	// is set, whether or not their contents are held.
+	secretNames map[Meta]bool
+
 	logrus.FieldLogger
 }
 
+// deletedSecret is a deleted Secret and the time at which its
+// grace period ends.
+type deletedSecret struct {
+	secret  *v1.Secret
+	expires time.Time
+}
+
+// An UpstreamTrustDomain names the Secret holding the CA bundle which
+// validates the certificates of a group of upstream services.
+type UpstreamTrustDomain struct {
+	Name string
+
+	// CASecretNamespace and CASecretName name the Secret
+	// holding the CA bundle.
+	CASecretNamespace string
+	CASecretName      string
+
+	// Namespaces and Services, the latter in namespace/name form,
+	// are the services of the trust domain. A trust domain with
+	// neither applies to every service not in another domain.
+	Namespaces []string
+	Services   []string
+}
+
+// upstreamTrustDomain returns the trust domain of the named service,
+// or nil if there is none. A trust domain naming the service takes
+// precedence over one naming its namespace, and either over the
+// default trust domain.
+func (kc *KubernetesCache) upstreamTrustDomain(namespace, name string) *UpstreamTrustDomain {
+	var byNamespace, byDefault *UpstreamTrustDomain
+	for i := range kc.UpstreamTrustDomains {
+		td := &kc.UpstreamTrustDomains[i]
+		for _, s := range td.Services {
+			if s == namespace+"/"+name {
+				return td
+			}
+		}
+		for _, ns := range td.Namespaces {
+			if ns == namespace && byNamespace == nil {
+				byNamespace = td
+			}
+		}
+		if len(td.Namespaces) == 0 && len(td.Services) == 0 && byDefault == nil {
+			byDefault = td
+		}
+	}
+	if byNamespace != nil {
+		return byNamespace
+	}
+	return byDefault
+}
+
 // Meta holds the name and namespace of a Kubernetes object.
 type Meta struct {
 	name, namespace string
@@ -105,8 +187,9 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 			// allow users to add arbitrary orthogonal annotations
 			// to object that we inspect.
 			if annotationIsKnown(key) && !validAnnotationForKind(kind, key) {
-				// TODO(jpeach): this should be exposed
-				// to the user as a status condition.
+				// The Builder's StrictAnnotations also
+				// reports these in the status of
+				// IngressRoutes and HTTPProxies.
 				om := obj.GetObjectMeta()
 				kc.WithField("name", om.GetName()).
 					WithField("namespace", om.GetNamespace()).
@@ -120,6 +203,9 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 
 	switch obj := obj.(type) {
 	case *v1.Secret:
+		if kc.FetchSecret != nil {
+			return kc.insertSecretName(obj)
+		}
 		valid, err := isValidSecret(obj)
 		if !valid {
 			if err != nil {
@@ -138,6 +224,7 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 			kc.secrets = make(map[Meta]*v1.Secret)
 		}
 		kc.secrets[m] = obj
+		delete(kc.deletedSecrets, m)
 		return kc.secretTriggersRebuild(obj)
 	case *v1.Service:
 		m := toMeta(obj)
@@ -147,8 +234,7 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 		kc.services[m] = obj
 		return kc.serviceTriggersRebuild(obj)
 	case *v1beta1.Ingress:
-		class := ingressClass(obj)
-		if class != "" && class != kc.ingressClass() {
+		if !kc.matchesIngressClass(obj) {
 			return false
 		}
 		m := toMeta(obj)
@@ -168,8 +254,7 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 		}
 		return kc.Insert(ingress)
 	case *ingressroutev1.IngressRoute:
-		class := ingressClass(obj)
-		if class != "" && class != kc.ingressClass() {
+		if !kc.matchesIngressClass(obj) {
 			return false
 		}
 		m := toMeta(obj)
@@ -179,8 +264,7 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 		kc.ingressroutes[m] = obj
 		return true
 	case *projectcontour.HTTPProxy:
-		class := ingressClass(obj)
-		if class != "" && class != kc.ingressClass() {
+		if !kc.matchesIngressClass(obj) {
 			return false
 		}
 		m := toMeta(obj)
@@ -217,6 +301,172 @@ func (kc *KubernetesCache) ingressClass() string {
 	return stringOrDefault(kc.IngressClass, DEFAULT_INGRESS_CLASS)
 }
 
+// matchesIngressClass returns true if obj is to be served by
+// a Contour with the configured IngressClasses.
+func (kc *KubernetesCache) matchesIngressClass(obj Object) bool {
+	return matchesIngressClass(obj, kc.ingressClass(), kc.IngressClassRegex)
+}
+
+// deprecatedAnnotations counts the Ingresses, IngressRoutes,
+// HTTPProxies and Services in the cache using each deprecated
+// annotation in each namespace.
+func (kc *KubernetesCache) deprecatedAnnotations() map[DeprecatedAnnotation]int {
+	counts := make(map[DeprecatedAnnotation]int)
+	count := func(obj Object) {
+		for _, a := range deprecatedAnnotations(obj) {
+			counts[DeprecatedAnnotation{
+				Namespace:  obj.GetObjectMeta().GetNamespace(),
+				Annotation: a,
+			}]++
+		}
+	}
+	for _, i := range kc.ingresses {
+		count(i)
+	}
+	for _, ir := range kc.ingressroutes {
+		count(ir)
+	}
+	for _, proxy := range kc.httpproxies {
+		count(proxy)
+	}
+	for _, svc := range kc.services {
+		count(svc)
+	}
+	return counts
+}
+
+// retainDeletedSecret keeps the deleted Secret sec until its grace
+// period ends, and forgets any Secrets whose grace period has ended.
+func (kc *KubernetesCache) retainDeletedSecret(m Meta, sec *v1.Secret) {
+	now := time.Now()
+	for k, ds := range kc.deletedSecrets {
+		if !now.Before(ds.expires) {
+			delete(kc.deletedSecrets, k)
+		}
+	}
+	if kc.deletedSecrets == nil {
+		kc.deletedSecrets = make(map[Meta]deletedSecret)
+	}
+	kc.deletedSecrets[m] = deletedSecret{
+		secret:  sec,
+		expires: now.Add(kc.SecretDeletionGracePeriod),
+	}
+}
+
+// insertSecretName records that the Secret sec, whose contents are
+// not known, exists. Any contents held for it are dropped to be
+// fetched again. It returns true if sec is referenced.
+func (kc *KubernetesCache) insertSecretName(sec *v1.Secret) bool {
+	m := toMeta(sec)
+	if kc.secretNames == nil {
+		kc.secretNames = make(map[Meta]bool)
+	}
+	kc.secretNames[m] = true
+	delete(kc.secrets, m)
+	delete(kc.deletedSecrets, m)
+	return kc.referencedSecrets()[m]
+}
+
+// referencedSecrets returns the Secrets named by the TLS configuration
+// of the cache's Ingresses, IngressRoutes and HTTPProxies, the CA
+// certificates named by the upstream validation of their services,
+// the client certificates their services present, and the CA bundles of the upstream trust domains.
+// Whether a reference across namespaces is permitted by a delegation
+// is left to the DAG builder.
+func (kc *KubernetesCache) referencedSecrets() map[Meta]bool {
+	refs := make(map[Meta]bool)
+	for _, td := range kc.UpstreamTrustDomains {
+		refs[Meta{name: td.CASecretName, namespace: td.CASecretNamespace}] = true
+	}
+	for _, ing := range kc.ingresses {
+		for _, tls := range ing.Spec.TLS {
+			refs[splitSecret(tls.SecretName, ing.Namespace)] = true
+		}
+	}
+	for _, ir := range kc.ingressroutes {
+		if vh := ir.Spec.VirtualHost; vh != nil && vh.TLS != nil {
+			refs[splitSecret(vh.TLS.SecretName, ir.Namespace)] = true
+		}
+		for _, route := range ir.Spec.Routes {
+			for _, service := range route.Services {
+				if uv := service.UpstreamValidation; uv != nil && uv.CACertificate != "" {
+					refs[Meta{name: uv.CACertificate, namespace: ir.Namespace}] = true
+				}
+			}
+		}
+	}
+	for _, proxy := range kc.httpproxies {
+		if vh := proxy.Spec.VirtualHost; vh != nil && vh.TLS != nil {
+			refs[splitSecret(vh.TLS.SecretName, proxy.Namespace)] = true
+			if cv := vh.TLS.ClientValidation; cv != nil {
+				if cv.CACertificate != "" {
+					refs[splitSecret(cv.CACertificate, proxy.Namespace)] = true
+				}
+				if cv.CertificateRevocationList != "" {
+					refs[splitSecret(cv.CertificateRevocationList, proxy.Namespace)] = true
+				}
+			}
+		}
+		if vh := proxy.Spec.VirtualHost; vh != nil {
+			for _, p := range vh.JWTProviders {
+				if uv := p.RemoteJWKS.UpstreamValidation; uv != nil && uv.CACertificate != "" {
+					refs[Meta{name: uv.CACertificate, namespace: proxy.Namespace}] = true
+				}
+			}
+		}
+		for _, route := range proxy.Spec.Routes {
+			for _, service := range route.Services {
+				if uv := service.UpstreamValidation; uv != nil && uv.CACertificate != "" {
+					refs[Meta{name: uv.CACertificate, namespace: proxy.Namespace}] = true
+				}
+				if service.ClientCertificate != "" {
+					refs[splitSecret(service.ClientCertificate, proxy.Namespace)] = true
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// fetchSecrets fetches the contents of the known Secrets which are
+// referenced but not held, and drops those held which are no longer
+// referenced. It does nothing unless FetchSecret is set.
+func (kc *KubernetesCache) fetchSecrets() {
+	if kc.FetchSecret == nil {
+		return
+	}
+	refs := kc.referencedSecrets()
+	for m := range kc.secrets {
+		if !refs[m] {
+			delete(kc.secrets, m)
+		}
+	}
+	for m := range refs {
+		if _, ok := kc.secrets[m]; ok || !kc.secretNames[m] {
+			continue
+		}
+		log := kc.WithField("name", m.name).
+			WithField("namespace", m.namespace).
+			WithField("kind", "Secret").
+			WithField("version", "v1")
+		sec, err := kc.FetchSecret(m.namespace, m.name)
+		if err != nil {
+			log.WithError(err).Error("failed to fetch referenced secret")
+			continue
+		}
+		if valid, err := isValidSecret(sec); !valid {
+			if err != nil {
+				log.Error(err)
+			}
+			continue
+		}
+		if kc.secrets == nil {
+			kc.secrets = make(map[Meta]*v1.Secret)
+		}
+		kc.secrets[m] = sec
+	}
+}
+
 // Remove removes obj from the KubernetesCache.
 // Remove returns a boolean indicating if the cache changed after the remove operation.
 func (kc *KubernetesCache) Remove(obj interface{}) bool {
@@ -232,8 +482,12 @@ func (kc *KubernetesCache) remove(obj interface{}) bool {
 	switch obj := obj.(type) {
 	case *v1.Secret:
 		m := toMeta(obj)
-		_, ok := kc.secrets[m]
+		delete(kc.secretNames, m)
+		sec, ok := kc.secrets[m]
 		delete(kc.secrets, m)
+		if ok && kc.SecretDeletionGracePeriod > 0 {
+			kc.retainDeletedSecret(m, sec)
+		}
 		return ok
 	case *v1.Service:
 		m := toMeta(obj)
@@ -358,6 +612,10 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 		// that any change to a CA secret will trigger a rebuild.
 		return true
 	}
+	if _, isCRL := secret.Data["crl.pem"]; isCRL {
+		// as above, any change to a CRL secret triggers a rebuild.
+		return true
+	}
 
 	delegations := make(map[string]bool) // targetnamespace/secretname to bool
 
@@ -430,6 +688,16 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 	}
 
 	for _, proxy := range kc.httpproxies {
+		// whether a client certificate may be used across
+		// namespaces is left to the DAG builder.
+		for _, route := range proxy.Spec.Routes {
+			for _, service := range route.Services {
+				if service.ClientCertificate != "" && splitSecret(service.ClientCertificate, proxy.Namespace) == toMeta(secret) {
+					return true
+				}
+			}
+		}
+
 		vh := proxy.Spec.VirtualHost
 		if vh == nil {
 			// not a root ingress
@@ -460,12 +728,15 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 }
 
 // transposeIngress transposes extensionis/v1beta1.Ingress objects into
-// networking/v1beta1.Ingress objects.
+// networking/v1beta1.Ingress objects. All Ingress API versions are
+// transposed to networking/v1beta1 before they are stored so that
+// annotations are interpreted identically regardless of the version
+// the object was delivered with.
 func transposeIngress(src *extensionsv1beta1.Ingress, dst *v1beta1.Ingress) error {
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	if err := enc.Encode(src); err != nil {
-		return nil
+		return err
 	}
 	dec := json.NewDecoder(&buf)
 	return dec.Decode(dst)
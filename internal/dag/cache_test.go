@@ -18,6 +18,7 @@ import (
 
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/assert"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
@@ -91,6 +92,32 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: false,
 		},
+		"insert CRL secret": {
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secret",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"crl.pem": []byte(CRL),
+				},
+			},
+			want: true,
+		},
+		"insert CRL secret w/ certificate in place of CRL": {
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secret",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"crl.pem": []byte(CERTIFICATE),
+				},
+			},
+			want: false,
+		},
 
 		"insert secret referenced by ingress": {
 			pre: []interface{}{
@@ -373,6 +400,34 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert secret referenced by httpproxy service client certificate": {
+			pre: []interface{}{
+				&projcontour.HTTPProxy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "simple",
+						Namespace: "extra",
+					},
+					Spec: projcontour.HTTPProxySpec{
+						Routes: []projcontour.Route{{
+							Services: []projcontour.Service{{
+								Name:              "backend",
+								Port:              443,
+								ClientCertificate: "default/secret",
+							}},
+						}},
+					},
+				},
+			},
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secret",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeTLS,
+				Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+			},
+			want: true,
+		},
 		"insert secret referenced by httpproxy via tls delegation": {
 			pre: []interface{}{
 				&projcontour.HTTPProxy{
@@ -1092,6 +1147,127 @@ func TestKubernetesCacheRemove(t *testing.T) {
 	}
 }
 
+func TestKubernetesCacheFetchSecret(t *testing.T) {
+	secret := func(name string, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Type: v1.SecretTypeTLS,
+			Data: data,
+		}
+	}
+
+	var fetched []string
+	cache := KubernetesCache{
+		FetchSecret: func(namespace, name string) (*v1.Secret, error) {
+			fetched = append(fetched, namespace+"/"+name)
+			return secret(name, secretdata(CERTIFICATE, RSA_PRIVATE_KEY)), nil
+		},
+		FieldLogger: testLogger(t),
+	}
+	held := func() []string {
+		var names []string
+		for m := range cache.secrets {
+			names = append(names, m.namespace+"/"+m.name)
+		}
+		return names
+	}
+
+	// secrets which are not referenced are neither fetched nor held.
+	assert.Equal(t, false, cache.Insert(secret("tls", nil)))
+	assert.Equal(t, false, cache.Insert(secret("unused", nil)))
+	cache.fetchSecrets()
+	assert.Equal(t, []string(nil), fetched)
+	assert.Equal(t, []string(nil), held())
+
+	proxy := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &projcontour.TLS{
+					SecretName: "tls",
+				},
+			},
+		},
+	}
+	cache.Insert(proxy)
+
+	// a newly referenced secret is fetched once.
+	cache.fetchSecrets()
+	cache.fetchSecrets()
+	assert.Equal(t, []string{"default/tls"}, fetched)
+	assert.Equal(t, []string{"default/tls"}, held())
+
+	// an update to a referenced secret is fetched again.
+	assert.Equal(t, true, cache.Insert(secret("tls", nil)))
+	cache.fetchSecrets()
+	assert.Equal(t, []string{"default/tls", "default/tls"}, fetched)
+
+	// a secret which is no longer referenced is dropped.
+	cache.Remove(proxy)
+	cache.fetchSecrets()
+	assert.Equal(t, []string(nil), held())
+
+	// a referenced secret which does not exist is not fetched.
+	proxy.Spec.VirtualHost.TLS.SecretName = "missing"
+	cache.Insert(proxy)
+	cache.fetchSecrets()
+	assert.Equal(t, []string{"default/tls", "default/tls"}, fetched)
+
+	// the CA bundle of an upstream trust domain is referenced.
+	cache.Insert(secret("ca", nil))
+	cache.UpstreamTrustDomains = []UpstreamTrustDomain{{
+		Name:              "corp",
+		CASecretNamespace: "default",
+		CASecretName:      "ca",
+	}}
+	cache.fetchSecrets()
+	assert.Equal(t, []string{"default/tls", "default/tls", "default/ca"}, fetched)
+}
+
+func TestKubernetesCacheDeprecatedAnnotations(t *testing.T) {
+	meta := func(namespace string, annotations ...string) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{
+			Name:        "kuard",
+			Namespace:   namespace,
+			Annotations: map[string]string{},
+		}
+		for _, a := range annotations {
+			m.Annotations[a] = "contour"
+		}
+		return m
+	}
+
+	cache := KubernetesCache{
+		FieldLogger: testLogger(t),
+	}
+	cache.Insert(&v1beta1.Ingress{
+		ObjectMeta: meta("default", "contour.heptio.com/ingress.class", "contour.heptio.com/num-retries"),
+	})
+	cache.Insert(&projcontour.HTTPProxy{
+		ObjectMeta: meta("default", "contour.heptio.com/ingress.class"),
+	})
+	cache.Insert(&ingressroutev1.IngressRoute{
+		ObjectMeta: meta("roots", "projectcontour.io/ingress.class"),
+	})
+	cache.Insert(&v1.Service{
+		ObjectMeta: meta("roots", "contour.heptio.com/max-connections"),
+	})
+
+	want := map[DeprecatedAnnotation]int{
+		{Namespace: "default", Annotation: "contour.heptio.com/ingress.class"}: 2,
+		{Namespace: "default", Annotation: "contour.heptio.com/num-retries"}:   1,
+		{Namespace: "roots", Annotation: "contour.heptio.com/max-connections"}: 1,
+	}
+	assert.Equal(t, want, cache.deprecatedAnnotations())
+}
+
 func testLogger(t *testing.T) logrus.FieldLogger {
 	log := logrus.New()
 	log.Out = &testWriter{t}
@@ -16,24 +16,51 @@ package dag
 import (
 	"fmt"
 	"regexp"
+	"regexp/syntax"
 	"strings"
 
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 )
 
 // mergePathConditions merges the given slice of prefix Conditions into a single
-// prefix Condition.
+// prefix Condition, or, if one of them is a regex, into a regex Condition
+// which matches the merged prefix followed by the regex, or, if one of them
+// is an exact path, into an exact Condition of the merged prefix followed by
+// the path.
 // pathConditionsValid guarantees that if a prefix is present, it will start with a
 // / character, so we can simply concatenate.
 func mergePathConditions(conds []projcontour.Condition) Condition {
 	prefix := ""
+	regex := ""
+	exact := ""
 	for _, cond := range conds {
 		prefix = prefix + cond.Prefix
+		if cond.Regex != "" {
+			regex = cond.Regex
+		}
+		if cond.Exact != "" {
+			exact = cond.Exact
+		}
 	}
 
 	re := regexp.MustCompile(`//+`)
 	prefix = re.ReplaceAllString(prefix, `/`)
 
+	if regex != "" {
+		if strings.HasPrefix(regex, "/") {
+			prefix = strings.TrimSuffix(prefix, "/")
+		}
+		return &SafeRegexCondition{
+			Regex: regexp.QuoteMeta(prefix) + regex,
+		}
+	}
+
+	if exact != "" {
+		return &ExactCondition{
+			Path: re.ReplaceAllString(prefix+exact, `/`),
+		}
+	}
+
 	// After the merge operation is done, if the string is still empty, then
 	// we need to set the prefix to /.
 	// Remember that this step is done AFTER all the includes have happened.
@@ -49,9 +76,12 @@ func mergePathConditions(conds []projcontour.Condition) Condition {
 }
 
 // pathConditionsValid validates a slice of Conditions can be correctly merged.
-// It encodes the business rules about what is allowed for prefix Conditions.
+// It encodes the business rules about what is allowed for prefix, regex and
+// exact Conditions.
 func pathConditionsValid(sw *ObjectStatusWriter, conds []projcontour.Condition, conditionsContext string) bool {
 	prefixCount := 0
+	regexCount := 0
+	exactCount := 0
 	for _, cond := range conds {
 		if cond.Prefix != "" {
 			prefixCount++
@@ -60,14 +90,73 @@ func pathConditionsValid(sw *ObjectStatusWriter, conds []projcontour.Condition,
 				return false
 			}
 		}
+		if cond.Regex != "" {
+			regexCount++
+			if conditionsContext == "include" {
+				sw.SetInvalid(fmt.Sprintf("%s: Regex conditions are not allowed", conditionsContext))
+				return false
+			}
+			if err := regexValid(cond.Regex); err != nil {
+				sw.SetInvalid(fmt.Sprintf("%s: Regex %q is invalid: %v", conditionsContext, cond.Regex, err))
+				return false
+			}
+		}
+		if cond.Exact != "" {
+			exactCount++
+			if conditionsContext == "include" {
+				sw.SetInvalid(fmt.Sprintf("%s: Exact conditions are not allowed", conditionsContext))
+				return false
+			}
+			if cond.Exact[0] != '/' {
+				sw.SetInvalid(fmt.Sprintf("%s: Exact conditions must start with /, %s was supplied", conditionsContext, cond.Exact))
+				return false
+			}
+		}
 		if prefixCount > 1 {
 			sw.SetInvalid(fmt.Sprintf("%s: More than one prefix is not allowed in a condition block", conditionsContext))
 			return false
 		}
+		if regexCount > 1 {
+			sw.SetInvalid(fmt.Sprintf("%s: More than one regex is not allowed in a condition block", conditionsContext))
+			return false
+		}
+		if prefixCount > 0 && regexCount > 0 {
+			sw.SetInvalid(fmt.Sprintf("%s: Prefix and regex cannot both be specified in a condition block", conditionsContext))
+			return false
+		}
+		if exactCount > 1 {
+			sw.SetInvalid(fmt.Sprintf("%s: More than one exact path is not allowed in a condition block", conditionsContext))
+			return false
+		}
+		if exactCount > 0 && prefixCount+regexCount > 0 {
+			sw.SetInvalid(fmt.Sprintf("%s: Exact path cannot be combined with a prefix or regex in a condition block", conditionsContext))
+			return false
+		}
 	}
 	return true
 }
 
+// maxRegexInstructions bounds the size of the program a path or query
+// parameter regex compiles to, limiting the cost to Envoy of matching it.
+const maxRegexInstructions = 500
+
+// regexValid returns an error if regex is not a valid RE2 regular
+// expression, or compiles to more than maxRegexInstructions.
+func regexValid(regex string) error {
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return err
+	}
+	if len(prog.Inst) > maxRegexInstructions {
+		return fmt.Errorf("compiles to %d instructions, more than the limit of %d", len(prog.Inst), maxRegexInstructions)
+	}
+	return nil
+}
+
 func mergeHeaderConditions(conds []projcontour.Condition) []HeaderCondition {
 	var hc []HeaderCondition
 	for _, cond := range conds {
@@ -81,29 +170,52 @@ func mergeHeaderConditions(conds []projcontour.Condition) []HeaderCondition {
 			})
 		case cond.Header.Contains != "":
 			hc = append(hc, HeaderCondition{
-				Name:      cond.Header.Name,
-				Value:     cond.Header.Contains,
-				MatchType: "contains",
+				Name:       cond.Header.Name,
+				Value:      cond.Header.Contains,
+				MatchType:  "contains",
+				IgnoreCase: cond.Header.IgnoreCase,
 			})
 		case cond.Header.NotContains != "":
 			hc = append(hc, HeaderCondition{
-				Name:      cond.Header.Name,
-				Value:     cond.Header.NotContains,
-				MatchType: "contains",
-				Invert:    true,
+				Name:       cond.Header.Name,
+				Value:      cond.Header.NotContains,
+				MatchType:  "contains",
+				Invert:     true,
+				IgnoreCase: cond.Header.IgnoreCase,
 			})
 		case cond.Header.Exact != "":
 			hc = append(hc, HeaderCondition{
-				Name:      cond.Header.Name,
-				Value:     cond.Header.Exact,
-				MatchType: "exact",
+				Name:       cond.Header.Name,
+				Value:      cond.Header.Exact,
+				MatchType:  "exact",
+				IgnoreCase: cond.Header.IgnoreCase,
 			})
 		case cond.Header.NotExact != "":
 			hc = append(hc, HeaderCondition{
-				Name:      cond.Header.Name,
-				Value:     cond.Header.NotExact,
-				MatchType: "exact",
-				Invert:    true,
+				Name:       cond.Header.Name,
+				Value:      cond.Header.NotExact,
+				MatchType:  "exact",
+				Invert:     true,
+				IgnoreCase: cond.Header.IgnoreCase,
+			})
+		case cond.Header.Regex != "":
+			hc = append(hc, HeaderCondition{
+				Name:       cond.Header.Name,
+				Value:      cond.Header.Regex,
+				MatchType:  "regex",
+				IgnoreCase: cond.Header.IgnoreCase,
+			})
+		case len(cond.Header.OneOf) > 0:
+			// the header must equal one of the values.
+			var quoted []string
+			for _, v := range cond.Header.OneOf {
+				quoted = append(quoted, regexp.QuoteMeta(v))
+			}
+			hc = append(hc, HeaderCondition{
+				Name:       cond.Header.Name,
+				Value:      "(" + strings.Join(quoted, "|") + ")",
+				MatchType:  "regex",
+				IgnoreCase: cond.Header.IgnoreCase,
 			})
 		}
 	}
@@ -129,3 +241,92 @@ func headerConditionsAreValid(conditions []projcontour.Condition) bool {
 	}
 	return true
 }
+
+// headerMatchesValid returns an error if the regex of a header Condition
+// is invalid, or a value of its oneOf is empty.
+func headerMatchesValid(conds []projcontour.Condition) error {
+	for _, cond := range conds {
+		h := cond.Header
+		if h == nil {
+			continue
+		}
+		if h.Regex != "" {
+			if err := regexValid(h.Regex); err != nil {
+				return fmt.Errorf("header %q: regex %q is invalid: %v", h.Name, h.Regex, err)
+			}
+		}
+		for _, v := range h.OneOf {
+			if v == "" {
+				return fmt.Errorf("header %q: oneOf values must not be empty", h.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// queryParameterConditionsValid returns an error if any of the query
+// parameter Conditions does not name a parameter or set exactly one way
+// to match it, or if two of them require a parameter to exactly match
+// different values, which no request could satisfy.
+func queryParameterConditionsValid(conds []projcontour.Condition) error {
+	exact := map[string]string{}
+	for _, cond := range conds {
+		qp := cond.QueryParameter
+		if qp == nil {
+			continue
+		}
+		if qp.Name == "" {
+			return fmt.Errorf("queryParameter: name is required")
+		}
+		matches := 0
+		for _, set := range []bool{qp.Exact != "", qp.Prefix != "", qp.Regex != "", qp.Present} {
+			if set {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("queryParameter %q: exactly one of exact, prefix, regex or present must be specified", qp.Name)
+		}
+		if qp.Regex != "" {
+			if err := regexValid(qp.Regex); err != nil {
+				return fmt.Errorf("queryParameter %q: regex %q is invalid: %v", qp.Name, qp.Regex, err)
+			}
+		}
+		if qp.Exact != "" {
+			if other, ok := exact[qp.Name]; ok && other != qp.Exact {
+				return fmt.Errorf("queryParameter %q: cannot exactly match both %q and %q", qp.Name, other, qp.Exact)
+			}
+			exact[qp.Name] = qp.Exact
+		}
+	}
+	return nil
+}
+
+// mergeQueryParameterConditions returns the query parameter Conditions
+// of conds. Conditions repeated through includes are only matched once.
+func mergeQueryParameterConditions(conds []projcontour.Condition) []QueryParameterCondition {
+	var qc []QueryParameterCondition
+	seen := map[QueryParameterCondition]bool{}
+	for _, cond := range conds {
+		qp := cond.QueryParameter
+		if qp == nil {
+			continue
+		}
+		var c QueryParameterCondition
+		switch {
+		case qp.Present:
+			c = QueryParameterCondition{Name: qp.Name, MatchType: "present"}
+		case qp.Exact != "":
+			c = QueryParameterCondition{Name: qp.Name, Value: qp.Exact, MatchType: "exact"}
+		case qp.Prefix != "":
+			c = QueryParameterCondition{Name: qp.Name, Value: qp.Prefix, MatchType: "prefix"}
+		case qp.Regex != "":
+			c = QueryParameterCondition{Name: qp.Name, Value: qp.Regex, MatchType: "regex"}
+		}
+		if !seen[c] {
+			seen[c] = true
+			qc = append(qc, c)
+		}
+	}
+	return qc
+}
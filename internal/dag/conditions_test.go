@@ -83,6 +83,54 @@ func TestPathCondition(t *testing.T) {
 			}},
 			want: &PrefixCondition{Prefix: "/"},
 		},
+		"regex": {
+			conditions: []projcontour.Condition{{
+				Regex: "/v[0-9]+/.*",
+			}},
+			want: &SafeRegexCondition{Regex: "/v[0-9]+/.*"},
+		},
+		"regex after prefixes": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/a.b",
+			}, {
+				Prefix: "/c/",
+			}, {
+				Regex: "/v[0-9]+",
+			}},
+			want: &SafeRegexCondition{Regex: `/a\.b/c/v[0-9]+`},
+		},
+		"exact": {
+			conditions: []projcontour.Condition{{
+				Exact: "/healthz",
+			}},
+			want: &ExactCondition{Path: "/healthz"},
+		},
+		"exact after prefixes": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/api/",
+			}, {
+				Prefix: "/v1",
+			}, {
+				Exact: "/healthz",
+			}},
+			want: &ExactCondition{Path: "/api/v1/healthz"},
+		},
+		"exact after prefix with trailing slash": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/api/",
+			}, {
+				Exact: "/",
+			}},
+			want: &ExactCondition{Path: "/api/"},
+		},
+		"regex after prefix, without slash": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/api",
+			}, {
+				Regex: "(/.*)?",
+			}},
+			want: &SafeRegexCondition{Regex: "/api(/.*)?"},
+		},
 	}
 
 	for name, tc := range tests {
@@ -234,6 +282,59 @@ func TestHeaderConditions(t *testing.T) {
 				Value:     "abcdef",
 			}},
 		},
+		"single header regex": {
+			conditions: []projcontour.Condition{{
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-request-id",
+					Regex: "[a-f0-9]+",
+				},
+			}},
+			want: []HeaderCondition{{
+				Name:      "x-request-id",
+				MatchType: "regex",
+				Value:     "[a-f0-9]+",
+			}},
+		},
+		"single header oneOf": {
+			conditions: []projcontour.Condition{{
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-env",
+					OneOf: []string{"staging", "prod.eu"},
+				},
+			}},
+			want: []HeaderCondition{{
+				Name:      "x-env",
+				MatchType: "regex",
+				Value:     `(staging|prod\.eu)`,
+			}},
+		},
+		"header exact and notcontains ignoring case": {
+			conditions: []projcontour.Condition{{
+				Header: &projcontour.HeaderCondition{
+					Name:       "x-env",
+					Exact:      "Staging",
+					IgnoreCase: true,
+				},
+			}, {
+				Header: &projcontour.HeaderCondition{
+					Name:        "user-agent",
+					NotContains: "Bot",
+					IgnoreCase:  true,
+				},
+			}},
+			want: []HeaderCondition{{
+				Name:       "x-env",
+				MatchType:  "exact",
+				Value:      "Staging",
+				IgnoreCase: true,
+			}, {
+				Name:       "user-agent",
+				MatchType:  "contains",
+				Value:      "Bot",
+				Invert:     true,
+				IgnoreCase: true,
+			}},
+		},
 	}
 
 	for name, tc := range tests {
@@ -247,6 +348,7 @@ func TestHeaderConditions(t *testing.T) {
 func TestPrefixConditionsValid(t *testing.T) {
 	tests := map[string]struct {
 		conditions []projcontour.Condition
+		context    string
 		want       bool
 	}{
 		"empty condition list": {
@@ -305,6 +407,96 @@ func TestPrefixConditionsValid(t *testing.T) {
 			}},
 			want: false,
 		},
+		"valid regex condition": {
+			conditions: []projcontour.Condition{{
+				Regex: "/v[0-9]+/.*",
+			}},
+			want: true,
+		},
+		"invalid regex condition": {
+			conditions: []projcontour.Condition{{
+				Regex: "/v[0-9+",
+			}},
+			want: false,
+		},
+		"regex condition not supported by RE2": {
+			conditions: []projcontour.Condition{{
+				Regex: "/(?!admin).*",
+			}},
+			want: false,
+		},
+		"regex condition too complex": {
+			conditions: []projcontour.Condition{{
+				Regex: "/[a-z]{1000}",
+			}},
+			want: false,
+		},
+		"two regex conditions": {
+			conditions: []projcontour.Condition{{
+				Regex: "/api.*",
+			}, {
+				Regex: "/v1.*",
+			}},
+			want: false,
+		},
+		"prefix and regex conditions": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/api",
+			}, {
+				Regex: "/v1.*",
+			}},
+			want: false,
+		},
+		"valid exact condition": {
+			conditions: []projcontour.Condition{{
+				Exact: "/healthz",
+			}},
+			want: true,
+		},
+		"exact condition without slash": {
+			conditions: []projcontour.Condition{{
+				Exact: "healthz",
+			}},
+			want: false,
+		},
+		"two exact conditions": {
+			conditions: []projcontour.Condition{{
+				Exact: "/healthz",
+			}, {
+				Exact: "/readyz",
+			}},
+			want: false,
+		},
+		"prefix and exact conditions": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/api",
+			}, {
+				Exact: "/healthz",
+			}},
+			want: false,
+		},
+		"regex and exact conditions": {
+			conditions: []projcontour.Condition{{
+				Regex: "/api/.*",
+			}, {
+				Exact: "/healthz",
+			}},
+			want: false,
+		},
+		"exact condition in include": {
+			conditions: []projcontour.Condition{{
+				Exact: "/healthz",
+			}},
+			context: "include",
+			want:    false,
+		},
+		"regex condition in include": {
+			conditions: []projcontour.Condition{{
+				Regex: "/v1.*",
+			}},
+			context: "include",
+			want:    false,
+		},
 	}
 
 	for name, tc := range tests {
@@ -319,7 +511,7 @@ func TestPrefixConditionsValid(t *testing.T) {
 					Namespace: "test",
 				},
 			})
-			got := pathConditionsValid(sw, tc.conditions, "test")
+			got := pathConditionsValid(sw, tc.conditions, stringOrDefault(tc.context, "test"))
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -396,3 +588,233 @@ func TestValidateHeaderConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderMatchesValid(t *testing.T) {
+	tests := map[string]struct {
+		conditions []projcontour.Condition
+		want       string // the error, if any
+	}{
+		"empty condition list": {
+			conditions: nil,
+			want:       "",
+		},
+		"valid conditions": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/blog",
+			}, {
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-request-id",
+					Regex: "[a-f0-9]+",
+				},
+			}, {
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-env",
+					OneOf: []string{"staging", "prod"},
+				},
+			}},
+			want: "",
+		},
+		"invalid regex": {
+			conditions: []projcontour.Condition{{
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-request-id",
+					Regex: "[a-f",
+				},
+			}},
+			want: "header \"x-request-id\": regex \"[a-f\" is invalid: error parsing regexp: missing closing ]: `[a-f`",
+		},
+		"empty oneOf value": {
+			conditions: []projcontour.Condition{{
+				Header: &projcontour.HeaderCondition{
+					Name:  "x-env",
+					OneOf: []string{"staging", ""},
+				},
+			}},
+			want: `header "x-env": oneOf values must not be empty`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			if err := headerMatchesValid(tc.conditions); err != nil {
+				got = err.Error()
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestQueryParameterConditions(t *testing.T) {
+	tests := map[string]struct {
+		conditions []projcontour.Condition
+		want       []QueryParameterCondition
+	}{
+		"empty condition list": {
+			conditions: nil,
+			want:       nil,
+		},
+		"prefix": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/",
+			}},
+			want: nil,
+		},
+		"each match type": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:    "debug",
+					Present: true,
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:   "user",
+					Prefix: "test-",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "page",
+					Regex: "[0-9]+",
+				},
+			}},
+			want: []QueryParameterCondition{{
+				Name:      "debug",
+				MatchType: "present",
+			}, {
+				Name:      "flag",
+				Value:     "beta",
+				MatchType: "exact",
+			}, {
+				Name:      "user",
+				Value:     "test-",
+				MatchType: "prefix",
+			}, {
+				Name:      "page",
+				Value:     "[0-9]+",
+				MatchType: "regex",
+			}},
+		},
+		"repeated condition": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}},
+			want: []QueryParameterCondition{{
+				Name:      "flag",
+				Value:     "beta",
+				MatchType: "exact",
+			}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := mergeQueryParameterConditions(tc.conditions)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateQueryParameterConditions(t *testing.T) {
+	tests := map[string]struct {
+		conditions []projcontour.Condition
+		want       string // the error, if any
+	}{
+		"empty condition list": {
+			conditions: nil,
+			want:       "",
+		},
+		"valid conditions": {
+			conditions: []projcontour.Condition{{
+				Prefix: "/blog",
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "page",
+					Regex: "[0-9]+",
+				},
+			}},
+			want: "",
+		},
+		"missing name": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Present: true,
+				},
+			}},
+			want: "queryParameter: name is required",
+		},
+		"no match": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name: "flag",
+				},
+			}},
+			want: `queryParameter "flag": exactly one of exact, prefix, regex or present must be specified`,
+		},
+		"two matches": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:    "flag",
+					Exact:   "beta",
+					Present: true,
+				},
+			}},
+			want: `queryParameter "flag": exactly one of exact, prefix, regex or present must be specified`,
+		},
+		"invalid regex": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "page",
+					Regex: "[0-9",
+				},
+			}},
+			want: "queryParameter \"page\": regex \"[0-9\" is invalid: error parsing regexp: missing closing ]: `[0-9`",
+		},
+		"conflicting exact matches": {
+			conditions: []projcontour.Condition{{
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "alpha",
+				},
+			}, {
+				QueryParameter: &projcontour.QueryParameterCondition{
+					Name:  "flag",
+					Exact: "beta",
+				},
+			}},
+			want: `queryParameter "flag": cannot exactly match both "alpha" and "beta"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			if err := queryParameterConditionsValid(tc.conditions); err != nil {
+				got = err.Error()
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
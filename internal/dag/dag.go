@@ -33,6 +33,21 @@ type DAG struct {
 
 	// status computed while building this dag.
 	statuses map[Meta]Status
+
+	// nextChange is the time at which the next route
+	// active window opens or closes.
+	nextChange time.Time
+
+	// deprecatedAnnotations counts the objects using each
+	// deprecated annotation in each namespace.
+	deprecatedAnnotations map[DeprecatedAnnotation]int
+}
+
+// DeprecatedAnnotation identifies a deprecated annotation used by
+// objects in a namespace.
+type DeprecatedAnnotation struct {
+	Namespace  string
+	Annotation string
 }
 
 // Visit calls fn on each root of this DAG.
@@ -48,6 +63,20 @@ func (d *DAG) Statuses() map[Meta]Status {
 	return d.statuses
 }
 
+// NextChange returns the time at which this DAG will become stale
+// because a route's active window opens or closes. If no window is
+// pending the zero time is returned.
+func (d *DAG) NextChange() time.Time {
+	return d.nextChange
+}
+
+// DeprecatedAnnotations returns the number of Ingresses, IngressRoutes,
+// HTTPProxies and Services using each deprecated annotation in each
+// namespace.
+func (d *DAG) DeprecatedAnnotations() map[DeprecatedAnnotation]int {
+	return d.deprecatedAnnotations
+}
+
 type Condition interface {
 	fmt.Stringer
 }
@@ -61,6 +90,15 @@ func (pc *PrefixCondition) String() string {
 	return "prefix: " + pc.Prefix
 }
 
+// ExactCondition matches the whole path of a URL.
+type ExactCondition struct {
+	Path string
+}
+
+func (ec *ExactCondition) String() string {
+	return "exact: " + ec.Path
+}
+
 // RegexCondition matches the URL by regular expression.
 type RegexCondition struct {
 	Regex string
@@ -70,20 +108,62 @@ func (rc *RegexCondition) String() string {
 	return "regex: " + rc.Regex
 }
 
+// SafeRegexCondition matches the whole URL path by an RE2 regular
+// expression.
+type SafeRegexCondition struct {
+	Regex string
+}
+
+func (sc *SafeRegexCondition) String() string {
+	return "safe regex: " + sc.Regex
+}
+
 type HeaderCondition struct {
+	Name       string
+	Value      string
+	MatchType  string
+	Invert     bool
+	IgnoreCase bool
+}
+
+func (hc *HeaderCondition) String() string {
+	s := "header: " + hc.Name
+	if hc.Invert {
+		s += " not"
+	}
+	s += " " + hc.MatchType
+	if hc.IgnoreCase {
+		s += " ignoring case"
+	}
+	if hc.Value != "" {
+		s += " " + hc.Value
+	}
+	return s
+}
+
+// QueryParameterCondition matches a query parameter of the request.
+// MatchType is one of "exact", "prefix", "regex" or "present".
+type QueryParameterCondition struct {
 	Name      string
 	Value     string
 	MatchType string
-	Invert    bool
 }
 
-func (hc *HeaderCondition) String() string {
-	return "header: " + hc.Name
+func (qc *QueryParameterCondition) String() string {
+	s := "query parameter: " + qc.Name + " " + qc.MatchType
+	if qc.Value != "" {
+		s += " " + qc.Value
+	}
+	return s
 }
 
 // Route defines the properties of a route to a Cluster.
 type Route struct {
 
+	// Name is the namespace/name of the HTTPProxy which
+	// defined this route, or blank for other route sources.
+	Name string
+
 	// PathCondition specifies a Condition to match on the request path.
 	// Must not be nil.
 	PathCondition Condition
@@ -92,6 +172,10 @@ type Route struct {
 	// match on the request headers.
 	HeaderConditions []HeaderCondition
 
+	// QueryParameterConditions specifies a set of additional
+	// Conditions to match on the request's query parameters.
+	QueryParameterConditions []QueryParameterCondition
+
 	Clusters []*Cluster
 
 	// Should this route generate a 301 upgrade if accessed
@@ -113,6 +197,314 @@ type Route struct {
 
 	// Mirror Policy defines the mirroring policy for this Route.
 	MirrorPolicy *MirrorPolicy
+
+	// DirectResponse, if set, is returned to the client in place
+	// of forwarding the request to the route's Clusters.
+	DirectResponse *DirectResponse
+
+	// Redirect, if set, redirects the request to another host
+	// in place of forwarding it to the route's Clusters.
+	Redirect *Redirect
+
+	// ClassificationHeader, if set, is the name of the response
+	// header set to the Classification of the Cluster which
+	// served the request.
+	ClassificationHeader string
+
+	// ClusterHeader, if set, is the name of the request header
+	// which names which of Clusters to route the request to.
+	ClusterHeader string
+
+	// Metadata is attached to the route for the filters named
+	// by its keys. Values are strings, float64s or bools.
+	Metadata map[string]map[string]interface{}
+
+	// RateLimitPolicy, if set, defines the descriptors sent to
+	// the rate limit service for requests to this route.
+	RateLimitPolicy *RateLimitPolicy
+
+	// DownstreamProtocolPolicy, if set, restricts the HTTP
+	// protocol of the requests this route serves.
+	DownstreamProtocolPolicy *DownstreamProtocolPolicy
+
+	// AuthDisabled, if true, routes requests to this route
+	// without the authorization of the virtual host's
+	// AuthorizationServer.
+	AuthDisabled bool
+
+	// JWTProvider is the name of the JWTProvider of the
+	// SecureVirtualHost whose JWT requests to this route
+	// must present. If blank no JWT is required.
+	JWTProvider string
+
+	// JWTDisabled, if true, does not require a JWT from the
+	// default JWTProvider of the virtual host.
+	JWTDisabled bool
+
+	// CORSPolicy, if set, allows cross-origin requests
+	// to this route.
+	CORSPolicy *CORSPolicy
+
+	// RequestHeadersPolicy, if set, modifies the headers of
+	// requests to this route.
+	RequestHeadersPolicy *HeadersPolicy
+
+	// ResponseHeadersPolicy, if set, modifies the headers of
+	// responses from this route.
+	ResponseHeadersPolicy *HeadersPolicy
+
+	// SessionPinningPolicy, if set, records the Cluster which
+	// served a request in a cookie.
+	SessionPinningPolicy *SessionPinningPolicy
+
+	// SessionAffinityPolicy, if set, customises the cookie hashed
+	// by Clusters using the Cookie load balancing strategy.
+	SessionAffinityPolicy *SessionAffinityPolicy
+
+	// RequestHashPolicies are the attributes of a request hashed
+	// by Clusters using the RequestHash load balancing strategy.
+	RequestHashPolicies []RequestHashPolicy
+
+	// ExperimentPolicy, if set, assigns requests to the variants
+	// of an experiment.
+	ExperimentPolicy *ExperimentPolicy
+
+	// Variant, if set, is the experiment variant of the requests
+	// matched by this route, which have already been assigned
+	// to it. Requests are not assigned again.
+	Variant string
+}
+
+// ExperimentPolicy defines how requests are assigned to the
+// variants of an experiment.
+type ExperimentPolicy struct {
+	// CookieName is the name of the cookie recording the variant.
+	CookieName string
+
+	// Header is the name of the request header carrying the variant.
+	Header string
+
+	// TTL is the lifetime of the cookie. A TTL of zero
+	// lasts until the client's session ends.
+	TTL time.Duration
+
+	// Variants are the variants of the experiment.
+	Variants []Variant
+}
+
+// Variant is a variant of an experiment.
+type Variant struct {
+	// Name is the name of the variant.
+	Name string
+
+	// Percent is the percentage of requests assigned to the variant.
+	Percent uint32
+}
+
+// Cookie returns the value of an experiment cookie which assigns
+// a session to this Variant. As with Cluster.SessionPin the value
+// is terminated with a '.' so that no value is a prefix of another.
+func (v Variant) Cookie() string {
+	return v.Name + "."
+}
+
+// RateLimitPolicy defines the descriptors sent to the rate limit
+// service for requests to a route.
+type RateLimitPolicy struct {
+	// Descriptors are the entries of each descriptor.
+	Descriptors [][]RateLimitDescriptorEntry
+}
+
+// RateLimitDescriptorEntry is an entry of a rate limit descriptor.
+// Exactly one of HeaderName, RemoteAddress or GenericKey is set.
+type RateLimitDescriptorEntry struct {
+	// HeaderName is the request header whose value is
+	// the value of the entry with key DescriptorKey.
+	HeaderName    string
+	DescriptorKey string
+
+	// RemoteAddress, if true, adds the client's address.
+	RemoteAddress bool
+
+	// GenericKey is a fixed value of the entry.
+	GenericKey string
+}
+
+// AuthorizationServer is the external authorization gRPC service
+// which authorizes requests to a SecureVirtualHost.
+type AuthorizationServer struct {
+	// Cluster is the cluster of the authorization service.
+	Cluster *Cluster
+
+	// FailOpen, if true, allows requests when the service
+	// cannot be reached. Otherwise they are rejected.
+	FailOpen bool
+
+	// ResponseTimeout is the timeout of calls to the service.
+	// If zero, Envoy's default of 200ms applies.
+	ResponseTimeout time.Duration
+}
+
+// CORSPolicy allows cross-origin requests from browsers.
+type CORSPolicy struct {
+	// AllowOrigin are the exact origins allowed, or "*".
+	AllowOrigin []string
+
+	// AllowOriginRegex are regular expressions matching
+	// the origins allowed.
+	AllowOriginRegex []string
+
+	AllowMethods  []string
+	AllowHeaders  []string
+	ExposeHeaders []string
+
+	// MaxAge is how long a preflight response may be cached.
+	// If zero, the browser's default applies.
+	MaxAge time.Duration
+
+	AllowCredentials bool
+}
+
+// JWTProvider verifies the JSON Web Tokens of an issuer.
+type JWTProvider struct {
+	Name string
+
+	// Issuer, if not blank, must match the iss claim.
+	Issuer string
+
+	// Audiences, if not empty, must include an aud claim.
+	Audiences []string
+
+	// RemoteJWKS is where the provider's keys are fetched from.
+	RemoteJWKS RemoteJWKS
+
+	// ForwardJWT, if true, forwards the JWT upstream.
+	ForwardJWT bool
+}
+
+// RemoteJWKS is a JSON Web Key Set fetched over HTTP.
+type RemoteJWKS struct {
+	URI string
+
+	// Cluster is the cluster of the host serving the JWKS.
+	Cluster *Cluster
+
+	// Timeout is the timeout of requests for the JWKS.
+	Timeout time.Duration
+
+	// CacheDuration is how long the JWKS is cached for.
+	CacheDuration time.Duration
+}
+
+// RateLimitService is the gRPC service Envoy's rate limit filter
+// consults for routes with a RateLimitPolicy.
+type RateLimitService struct {
+	// Cluster is the cluster of the rate limit service.
+	Cluster *Cluster
+
+	// Domain is the domain of the descriptors sent to
+	// the service.
+	Domain string
+
+	// Timeout is the timeout of calls to the service.
+	// If zero, Envoy's default of 20ms applies.
+	Timeout time.Duration
+
+	// FailureModeDeny, if true, rejects requests when the
+	// service cannot be reached. Otherwise they are allowed.
+	FailureModeDeny bool
+}
+
+func (r *RateLimitService) Visit(f func(Vertex)) {
+	f(r.Cluster)
+}
+
+// SessionPinningPolicy defines the cookie used to pin a session
+// to the Cluster which served its first request.
+type SessionPinningPolicy struct {
+	// CookieName is the name of the cookie.
+	CookieName string
+
+	// TTL is the lifetime of the cookie.
+	TTL time.Duration
+}
+
+// SessionAffinityPolicy defines the cookie hashed to keep a client's
+// requests on the same endpoint.
+type SessionAffinityPolicy struct {
+	// CookieName is the name of the cookie.
+	CookieName string
+
+	// TTL is the lifetime of the cookie. If zero, the cookie
+	// lasts until the client's session ends.
+	TTL time.Duration
+
+	// Path is the path of the cookie.
+	Path string
+}
+
+// RequestHashPolicy defines an attribute of a request hashed to
+// choose an endpoint. Exactly one of HeaderName or SourceIP is set.
+type RequestHashPolicy struct {
+	// HeaderName is the name of the request header hashed.
+	HeaderName string
+
+	// SourceIP hashes the IP address of the client.
+	SourceIP bool
+
+	// Terminal skips the remaining policies if this one
+	// produces a hash.
+	Terminal bool
+}
+
+// DirectResponse defines the response returned by a route
+// without contacting an upstream cluster.
+type DirectResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode uint32
+
+	// Body, if set, is the body of the response.
+	Body string
+}
+
+// Redirect defines the redirect returned by a route in place of
+// forwarding the request upstream.
+type Redirect struct {
+	// Host is the host of the redirect location. If empty
+	// the host of the request is used.
+	Host string
+
+	// Path, if set, replaces the path of the request.
+	Path string
+
+	// PrefixRewrite, if set, replaces the prefix of the
+	// request's path matched by the route.
+	PrefixRewrite string
+
+	// Port, if set, is the port of the redirect location.
+	Port uint32
+
+	// Scheme is the scheme of the redirect location. If empty
+	// the scheme of the request is used.
+	Scheme string
+
+	// StatusCode is the HTTP status code of the redirect.
+	StatusCode uint32
+}
+
+// TCPKeepalive defines the TCP keepalive probes sent on upstream
+// connections. Zero values imply the operating system's defaults.
+type TCPKeepalive struct {
+	// Probes is the number of unanswered probes after which
+	// the connection is considered dead.
+	Probes uint32
+
+	// Time is how long a connection is idle before the
+	// first probe is sent.
+	Time time.Duration
+
+	// Interval is the time between probes.
+	Interval time.Duration
 }
 
 // TimeoutPolicy defines the timeout policy for a route.
@@ -123,7 +515,10 @@ type TimeoutPolicy struct {
 	// A timeout of -1 represents "infinity"
 	ResponseTimeout time.Duration
 
-	// IdleTimeout is the timeout applied to idle connections.
+	// IdleTimeout is the timeout applied to a request stream
+	// with no activity, independent of ResponseTimeout.
+	// A timeout of zero implies "use envoy's default"
+	// A timeout of -1 represents "infinity"
 	IdleTimeout time.Duration
 }
 
@@ -140,11 +535,27 @@ type RetryPolicy struct {
 	// PerTryTimeout specifies the timeout per retry attempt.
 	// Ignored if RetryOn is blank.
 	PerTryTimeout time.Duration
+
+	// RetriableStatusCodes are the HTTP status codes retried
+	// by the retriable-status-codes condition of RetryOn.
+	RetriableStatusCodes []uint32
+
+	// BackOffBaseInterval is the initial limit of the wait between
+	// retries. If zero, Envoy's default is used.
+	BackOffBaseInterval time.Duration
+
+	// BackOffMaxInterval is the greatest limit of the wait between
+	// retries. If zero, ten times BackOffBaseInterval is used.
+	BackOffMaxInterval time.Duration
 }
 
 // MirrorPolicy desinges the mirroring policy for a route.
 type MirrorPolicy struct {
 	Cluster *Cluster
+
+	// Percent is the percentage of requests which are mirrored.
+	// If zero, all requests are mirrored.
+	Percent uint32
 }
 
 // UpstreamValidation defines how to validate the certificate on the upstream service
@@ -155,12 +566,53 @@ type UpstreamValidation struct {
 	// SubjectName holds an optional subject name which Envoy will check against the
 	// certificate presented by the upstream.
 	SubjectName string
+	// SubjectNames are further subject names the upstream's
+	// certificate may present in place of SubjectName.
+	SubjectNames []string
+	// SPKIPins are the base64 SHA-256 hashes of the public keys,
+	// one of which the upstream's certificate must have, if any.
+	SPKIPins []string
+	// CertificateHashes are the hex SHA-256 hashes of the
+	// certificates, one of which the upstream must present, if any.
+	CertificateHashes []string
+}
+
+// DownstreamValidation defines how to validate the certificates
+// presented by clients.
+type DownstreamValidation struct {
+	// CACertificate holds a reference to the Secret containing the CA
+	// client certificates must chain to.
+	CACertificate *Secret
+
+	// CertificateRevocationList, if set, holds a reference to the
+	// Secret containing the CRL client certificates are checked against.
+	CertificateRevocationList *Secret
+
+	// Optional permits clients which present no certificate.
+	Optional bool
+
+	// ForwardClientCertificate, if set, selects the details of the
+	// client certificate forwarded to the backend.
+	ForwardClientCertificate *ClientCertificateDetails
+}
+
+// ClientCertificateDetails selects the details of the client certificate
+// forwarded in the X-Forwarded-Client-Cert header.
+type ClientCertificateDetails struct {
+	Subject bool
+	Cert    bool
+	Chain   bool
+	DNS     bool
+	URI     bool
 }
 
 func (r *Route) Visit(f func(Vertex)) {
 	for _, c := range r.Clusters {
 		f(c)
 	}
+	if r.MirrorPolicy != nil && r.MirrorPolicy.Cluster != nil {
+		f(r.MirrorPolicy.Cluster)
+	}
 }
 
 // A VirtualHost represents a named L4/L7 service.
@@ -169,9 +621,53 @@ type VirtualHost struct {
 	// as defined by RFC 3986.
 	Name string
 
+	// RequestHeadersAllowList, if not empty, restricts the request
+	// headers forwarded to the backend to the named headers.
+	RequestHeadersAllowList []string
+
+	// HTTPSRedirectPolicy, if set, customises the responses of
+	// the routes of this VirtualHost which redirect to HTTPS.
+	HTTPSRedirectPolicy *HTTPSRedirectPolicy
+
+	// DownstreamProtocolPolicy, if set, restricts the HTTP protocol
+	// of the requests served by the routes of this VirtualHost which
+	// have no policy of their own.
+	DownstreamProtocolPolicy *DownstreamProtocolPolicy
+
 	routes map[string]*Route
 }
 
+// HTTPSRedirectPolicy customises the responses which redirect
+// insecure requests to HTTPS.
+type HTTPSRedirectPolicy struct {
+	// ResponseHeaders are added to the redirect responses.
+	ResponseHeaders []HeaderValue
+
+	// StripPath redirects to the root path.
+	StripPath bool
+
+	// StripQuery removes the query string.
+	StripQuery bool
+}
+
+// HeaderValue is an HTTP header name and value.
+type HeaderValue struct {
+	Name  string
+	Value string
+}
+
+// HeadersPolicy defines how the headers of a request or
+// response are modified.
+type HeadersPolicy struct {
+	// Set are the headers whose values are replaced, or
+	// added if missing. Values are escaped for Envoy, so
+	// any '%' not introducing a dynamic value is doubled.
+	Set []HeaderValue
+
+	// Remove are the names of the headers removed.
+	Remove []string
+}
+
 func (v *VirtualHost) addRoute(route *Route) {
 	if v.routes == nil {
 		v.routes = make(map[string]*Route)
@@ -184,6 +680,13 @@ func conditionsToString(r *Route) string {
 	for _, cond := range r.HeaderConditions {
 		s = append(s, cond.String())
 	}
+	for _, cond := range r.QueryParameterConditions {
+		s = append(s, cond.String())
+	}
+	if r.ClusterHeader != "" {
+		// the route also matches on its cluster header.
+		s = append(s, "cluster header: "+r.ClusterHeader)
+	}
 	return strings.Join(s, ",")
 }
 
@@ -205,11 +708,43 @@ type SecureVirtualHost struct {
 	// TLS minimum protocol version. Defaults to envoy_api_v2_auth.TlsParameters_TLS_AUTO
 	MinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol
 
+	// TLS maximum protocol version. Defaults to envoy_api_v2_auth.TlsParameters_TLS_AUTO
+	MaxProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol
+
+	// CipherSuites are the cipher suites offered for TLS versions
+	// below 1.3. If empty, the default cipher suites are offered.
+	CipherSuites []string
+
 	// The cert and key for this host.
 	Secret *Secret
 
+	// ForwardTLSAttributes indicates that the attributes negotiated
+	// during the TLS handshake should be forwarded as request headers.
+	ForwardTLSAttributes bool
+
+	// StrictSNI rejects requests, on connections whose TLS server
+	// name is this host, for any other host.
+	StrictSNI bool
+
+	// DownstreamValidation, if set, validates the certificates
+	// presented by clients of this host.
+	DownstreamValidation *DownstreamValidation
+
 	// Service to TCP proxy all incoming connections.
 	*TCPProxy
+
+	// AuthorizationServer, if set, authorizes each request
+	// to this host.
+	AuthorizationServer *AuthorizationServer
+
+	// JWTProviders verify the JWTs required by routes
+	// of this host.
+	JWTProviders []*JWTProvider
+
+	// RateLimitFailOpen, if set, overrides whether requests to
+	// this host are allowed while the rate limit service cannot
+	// be reached.
+	RateLimitFailOpen *bool
 }
 
 func (s *SecureVirtualHost) Visit(f func(Vertex)) {
@@ -217,6 +752,12 @@ func (s *SecureVirtualHost) Visit(f func(Vertex)) {
 	if s.TCPProxy != nil {
 		f(s.TCPProxy)
 	}
+	if s.AuthorizationServer != nil {
+		f(s.AuthorizationServer.Cluster)
+	}
+	for _, p := range s.JWTProviders {
+		f(p.RemoteJWKS.Cluster)
+	}
 	if s.Secret != nil {
 		f(s.Secret) // secret is not required if vhost is using tls passthrough
 	}
@@ -281,6 +822,10 @@ type Service struct {
 	// One of "", "h2", "h2c", or "tls".
 	Protocol string
 
+	// GRPC is true if the port is named for gRPC, that is
+	// "grpc" or starting with "grpc-".
+	GRPC bool
+
 	// Circuit breaking limits
 
 	// Max connections is maximum number of connections
@@ -335,22 +880,87 @@ type Cluster struct {
 	// UpstreamValidation defines how to verify the backend service's certificate
 	UpstreamValidation *UpstreamValidation
 
+	// ClientCertificate is the certificate, and key, presented
+	// when connecting to the backend service over TLS.
+	ClientCertificate *Secret
+
 	// The load balancer type to use when picking a host in the cluster.
 	// See https://www.envoyproxy.io/docs/envoy/latest/api-v2/api/v2/cds.proto#envoy-api-enum-cluster-lbpolicy
 	LoadBalancerPolicy string
 
 	// Cluster health check policy.
 	*HealthCheckPolicy
+
+	// TCPHealthCheckPolicy, if set, health checks the hosts of
+	// a TCPProxy's Cluster with a TCP, rather than HTTP, check.
+	TCPHealthCheckPolicy *TCPHealthCheckPolicy
+
+	// IdleTimeout is the time after which an upstream connection
+	// with no active requests is closed.
+	IdleTimeout time.Duration
+
+	// MaxConnectionDuration is the maximum lifetime of an upstream
+	// connection.
+	MaxConnectionDuration time.Duration
+
+	// ConnectTimeout is the time allowed for an upstream connection
+	// to be established. Zero implies Contour's default.
+	ConnectTimeout time.Duration
+
+	// TCPKeepalive, if set, enables TCP keepalive probes on
+	// upstream connections.
+	TCPKeepalive *TCPKeepalive
+
+	// CircuitBreakers, if set, are circuit breaking limits which
+	// take precedence over those of the Upstream.
+	CircuitBreakers *CircuitBreakers
+
+	// Classification identifies this Cluster in the route's
+	// classification header.
+	Classification string
+
+	// LogicalDNS resolves the ExternalName of the Upstream with
+	// logical, rather than strict, DNS.
+	LogicalDNS bool
+
+	// HealthyPanicThreshold is the percentage of healthy hosts
+	// below which all hosts are balanced across. Zero disables
+	// panic mode.
+	HealthyPanicThreshold uint32
+
+	// SNI is the server name sent when the Upstream's
+	// protocol is "tls". If blank none is sent.
+	SNI string
+
+	// RequestHeadersPolicy, if set, modifies the headers of
+	// requests forwarded to this Cluster.
+	RequestHeadersPolicy *HeadersPolicy
+
+	// ResponseHeadersPolicy, if set, modifies the headers of
+	// responses served by this Cluster.
+	ResponseHeadersPolicy *HeadersPolicy
 }
 
 func (c Cluster) Visit(f func(Vertex)) {
 	f(c.Upstream)
 }
 
+// SessionPin returns the value of a session pinning cookie which
+// pins a session to this Cluster. The value is terminated with a
+// '.', which cannot appear in a service name, so that no value is
+// a prefix of another.
+func (c *Cluster) SessionPin() string {
+	return fmt.Sprintf("%s.%d.", c.Upstream.Name, c.Upstream.Port)
+}
+
 // Secret represents a K8s Secret for TLS usage as a DAG Vertex. A Secret is
 // a leaf in the DAG.
 type Secret struct {
 	Object *v1.Secret
+
+	// Expires, if set, is the end of the grace period during
+	// which the Secret is served after it has been deleted.
+	Expires time.Time
 }
 
 func (s *Secret) Name() string       { return s.Object.Name }
@@ -380,4 +990,52 @@ type HealthCheckPolicy struct {
 	Timeout            time.Duration
 	UnhealthyThreshold uint32
 	HealthyThreshold   uint32
+
+	// ExpectedStatuses, if set, are the response statuses which
+	// mark a host healthy. Otherwise only 200 does.
+	ExpectedStatuses []StatusRange
+
+	// RequestHeaders are added to each health check request.
+	RequestHeaders []HeaderValue
+}
+
+// CircuitBreakers are circuit breaking limits. A limit of
+// zero is not set.
+type CircuitBreakers struct {
+	MaxConnections     uint32
+	MaxPendingRequests uint32
+	MaxRequests        uint32
+	MaxRetries         uint32
+}
+
+// DownstreamProtocolPolicy restricts the HTTP protocol of requests.
+type DownstreamProtocolPolicy struct {
+	// Protocol is the only protocol served, "HTTP/1.1" or "HTTP/2".
+	Protocol string
+
+	// Status of the response to requests of any other protocol.
+	Status uint32
+}
+
+// TCPHealthCheckPolicy is the TCP health check policy of a Cluster.
+type TCPHealthCheckPolicy struct {
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold uint32
+	HealthyThreshold   uint32
+
+	// Send is the hex encoded payload written to the host.
+	// If empty only the connection is checked.
+	Send string
+
+	// Receive are the hex encoded payloads which must be
+	// found, in order, in the host's response.
+	Receive []string
+}
+
+// StatusRange is the range of HTTP statuses from Start up to,
+// but not including, End.
+type StatusRange struct {
+	Start uint32
+	End   uint32
 }
@@ -14,23 +14,97 @@
 package dag
 
 import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-func retryPolicy(rp *projcontour.RetryPolicy) *RetryPolicy {
+// retryOnConditions are the retry conditions Envoy supports, including
+// those of the x-envoy-retry-grpc-on header.
+var retryOnConditions = map[string]bool{
+	"5xx":                    true,
+	"gateway-error":          true,
+	"reset":                  true,
+	"connect-failure":        true,
+	"retriable-4xx":          true,
+	"refused-stream":         true,
+	"retriable-status-codes": true,
+	"cancelled":              true,
+	"deadline-exceeded":      true,
+	"internal":               true,
+	"resource-exhausted":     true,
+	"unavailable":            true,
+}
+
+// retryPolicy returns the retry policy for the supplied route policy,
+// or an error if its retry conditions or status codes are not valid.
+func retryPolicy(rp *projcontour.RetryPolicy) (*RetryPolicy, error) {
 	if rp == nil {
-		return nil
+		return nil, nil
 	}
+
+	var retryOn []string
+	seen := make(map[string]bool)
+	add := func(cond string) {
+		if !seen[cond] {
+			seen[cond] = true
+			retryOn = append(retryOn, cond)
+		}
+	}
+	for _, cond := range rp.RetryOn {
+		if !retryOnConditions[cond] {
+			return nil, fmt.Errorf("retryPolicy: retryOn condition %q is not supported", cond)
+		}
+		add(cond)
+	}
+	for _, code := range rp.RetriableStatusCodes {
+		if code < 100 || code > 599 {
+			return nil, fmt.Errorf("retryPolicy: retriable status code %d must be in the range 100-599", code)
+		}
+	}
+	switch {
+	case len(rp.RetriableStatusCodes) > 0:
+		add("retriable-status-codes")
+	case seen["retriable-status-codes"]:
+		return nil, fmt.Errorf("retryPolicy: retriable-status-codes requires retriableStatusCodes")
+	}
+	if len(retryOn) == 0 {
+		retryOn = []string{"5xx"}
+	}
+
 	perTryTimeout, _ := time.ParseDuration(rp.PerTryTimeout)
-	return &RetryPolicy{
-		RetryOn:       "5xx",
-		NumRetries:    max(1, rp.NumRetries),
-		PerTryTimeout: perTryTimeout,
+	policy := &RetryPolicy{
+		RetryOn:              strings.Join(retryOn, ","),
+		NumRetries:           max(1, rp.NumRetries),
+		PerTryTimeout:        perTryTimeout,
+		RetriableStatusCodes: rp.RetriableStatusCodes,
+	}
+	if bo := rp.BackOff; bo != nil {
+		base, err := time.ParseDuration(bo.BaseInterval)
+		if err != nil || base <= 0 {
+			return nil, fmt.Errorf("retryPolicy: backOff baseInterval %q must be a positive duration", bo.BaseInterval)
+		}
+		policy.BackOffBaseInterval = base
+		if bo.MaxInterval != "" {
+			maxInterval, err := time.ParseDuration(bo.MaxInterval)
+			if err != nil || maxInterval < base {
+				return nil, fmt.Errorf("retryPolicy: backOff maxInterval %q must be a duration of at least baseInterval", bo.MaxInterval)
+			}
+			policy.BackOffMaxInterval = maxInterval
+		}
 	}
+	return policy, nil
 }
 
 // ingressRetryPolicy builds a RetryPolicy from ingress annotations.
@@ -105,11 +179,11 @@ func ingressrouteHealthCheckPolicy(hc *ingressroutev1.HealthCheck) *HealthCheckP
 	}
 }
 
-func healthCheckPolicy(hc *projcontour.HTTPHealthCheckPolicy) *HealthCheckPolicy {
+func healthCheckPolicy(hc *projcontour.HTTPHealthCheckPolicy) (*HealthCheckPolicy, error) {
 	if hc == nil {
-		return nil
+		return nil, nil
 	}
-	return &HealthCheckPolicy{
+	policy := &HealthCheckPolicy{
 		Path:               hc.Path,
 		Host:               hc.Host,
 		Interval:           time.Duration(hc.IntervalSeconds) * time.Second,
@@ -117,6 +191,116 @@ func healthCheckPolicy(hc *projcontour.HTTPHealthCheckPolicy) *HealthCheckPolicy
 		UnhealthyThreshold: hc.UnhealthyThresholdCount,
 		HealthyThreshold:   hc.HealthyThresholdCount,
 	}
+	for _, status := range hc.ExpectedStatuses {
+		sr, err := statusRange(status)
+		if err != nil {
+			return nil, fmt.Errorf("healthCheckPolicy: expectedStatuses: %v", err)
+		}
+		policy.ExpectedStatuses = append(policy.ExpectedStatuses, sr)
+	}
+	names := make(map[string]bool)
+	for _, h := range hc.RequestHeaders {
+		if err := modifiableHeader(h.Name); err != nil {
+			return nil, fmt.Errorf("healthCheckPolicy: requestHeaders: %v", err)
+		}
+		key := http.CanonicalHeaderKey(h.Name)
+		if names[key] {
+			return nil, fmt.Errorf("healthCheckPolicy: requestHeaders: header %q is set more than once", h.Name)
+		}
+		names[key] = true
+		policy.RequestHeaders = append(policy.RequestHeaders, HeaderValue{Name: key, Value: escapeHeaderValue(h.Value)})
+	}
+	return policy, nil
+}
+
+func tcpHealthCheckPolicy(hc *projcontour.TCPHealthCheckPolicy) (*TCPHealthCheckPolicy, error) {
+	if hc == nil {
+		return nil, nil
+	}
+	policy := &TCPHealthCheckPolicy{
+		Interval:           time.Duration(hc.IntervalSeconds) * time.Second,
+		Timeout:            time.Duration(hc.TimeoutSeconds) * time.Second,
+		UnhealthyThreshold: hc.UnhealthyThresholdCount,
+		HealthyThreshold:   hc.HealthyThresholdCount,
+	}
+	if hc.Send != "" {
+		if _, err := hex.DecodeString(hc.Send); err != nil {
+			return nil, fmt.Errorf("healthCheckPolicy: send %q is not a hex encoded payload", hc.Send)
+		}
+		policy.Send = strings.ToUpper(hc.Send)
+	}
+	for i, r := range hc.Receive {
+		if _, err := hex.DecodeString(r); err != nil || r == "" {
+			return nil, fmt.Errorf("healthCheckPolicy: receive[%d] %q is not a hex encoded payload", i, r)
+		}
+		policy.Receive = append(policy.Receive, strings.ToUpper(r))
+	}
+	return policy, nil
+}
+
+// statusRange parses a status, such as "301", or an inclusive
+// range of statuses, such as "200-299".
+func statusRange(status string) (StatusRange, error) {
+	first, last := status, status
+	if i := strings.Index(status, "-"); i >= 0 {
+		first, last = status[:i], status[i+1:]
+	}
+	start, err := strconv.ParseUint(strings.TrimSpace(first), 10, 32)
+	if err != nil {
+		return StatusRange{}, fmt.Errorf("%q is not a status or range of statuses", status)
+	}
+	end, err := strconv.ParseUint(strings.TrimSpace(last), 10, 32)
+	if err != nil {
+		return StatusRange{}, fmt.Errorf("%q is not a status or range of statuses", status)
+	}
+	if start < 100 || end > 599 || start > end {
+		return StatusRange{}, fmt.Errorf("%q must be in the range 100-599", status)
+	}
+	return StatusRange{Start: uint32(start), End: uint32(end) + 1}, nil
+}
+
+// connectTimeout returns the parsed connect timeout, which must
+// be a positive duration, or zero if none is supplied.
+func connectTimeout(d string) (time.Duration, error) {
+	if d == "" {
+		return 0, nil
+	}
+	t, err := time.ParseDuration(d)
+	if err != nil || t <= 0 {
+		return 0, fmt.Errorf("connectTimeout %q must be a positive duration", d)
+	}
+	return t, nil
+}
+
+// tcpKeepalive returns the TCP keepalive of a service, or an error
+// if its durations are not whole numbers of seconds.
+func tcpKeepalive(k *projcontour.TCPKeepalive) (*TCPKeepalive, error) {
+	if k == nil {
+		return nil, nil
+	}
+	seconds := func(field, d string) (time.Duration, error) {
+		if d == "" {
+			return 0, nil
+		}
+		t, err := time.ParseDuration(d)
+		if err != nil || t < time.Second || t%time.Second != 0 {
+			return 0, fmt.Errorf("tcpKeepalive: %s %q must be a duration of whole seconds", field, d)
+		}
+		return t, nil
+	}
+	keepaliveTime, err := seconds("time", k.Time)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := seconds("interval", k.Interval)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPKeepalive{
+		Probes:   k.Probes,
+		Time:     keepaliveTime,
+		Interval: interval,
+	}, nil
 }
 
 // loadBalancerPolicy returns the load balancer strategy or
@@ -132,11 +316,837 @@ func loadBalancerPolicy(lbp *projcontour.LoadBalancerPolicy) string {
 		return "Random"
 	case "Cookie":
 		return "Cookie"
+	case "RequestHash":
+		return "RequestHash"
+	case "Maglev":
+		return "Maglev"
 	default:
 		return ""
 	}
 }
 
+// blueGreenClusters returns the clusters which receive the active and
+// preview traffic for a route, honouring the policy's promote toggle.
+func blueGreenClusters(clusters []*Cluster, bg *projcontour.BlueGreenPolicy) (*Cluster, *Cluster, error) {
+	lookup := func(name string) *Cluster {
+		for _, c := range clusters {
+			if c.Upstream.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	if bg.ActiveService == bg.PreviewService {
+		return nil, nil, fmt.Errorf("blueGreenPolicy: activeService and previewService must be different services")
+	}
+	active := lookup(bg.ActiveService)
+	if active == nil {
+		return nil, nil, fmt.Errorf("blueGreenPolicy: activeService %q is not a service of the route", bg.ActiveService)
+	}
+	preview := lookup(bg.PreviewService)
+	if preview == nil {
+		return nil, nil, fmt.Errorf("blueGreenPolicy: previewService %q is not a service of the route", bg.PreviewService)
+	}
+	if bg.PreviewHeader != nil && isBlank(bg.PreviewHeader.Name) {
+		return nil, nil, fmt.Errorf("blueGreenPolicy: previewHeader must specify a header name")
+	}
+	if bg.Promote {
+		return preview, active, nil
+	}
+	return active, preview, nil
+}
+
+// serviceSelectorRoutes returns a copy of r for each value of the
+// service selector, matching requests with the value and routed to
+// its service, or an error if the selector is invalid.
+func serviceSelectorRoutes(r *Route, ss *projcontour.ServiceSelector) ([]*Route, error) {
+	if isBlank(ss.Header) {
+		return nil, fmt.Errorf("serviceSelector: header must be specified")
+	}
+	if len(ss.Values) == 0 {
+		return nil, fmt.Errorf("serviceSelector: at least one value must be specified")
+	}
+	for _, hc := range r.HeaderConditions {
+		if hc.MatchType == "exact" && strings.EqualFold(hc.Name, ss.Header) {
+			return nil, fmt.Errorf("serviceSelector: header %q duplicates an 'exact match' condition of the route", ss.Header)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var routes []*Route
+	for _, v := range ss.Values {
+		if isBlank(v.Value) {
+			return nil, fmt.Errorf("serviceSelector: value must be specified")
+		}
+		if seen[v.Value] {
+			return nil, fmt.Errorf("serviceSelector: value %q is specified more than once", v.Value)
+		}
+		seen[v.Value] = true
+
+		var clusters []*Cluster
+		for _, c := range r.Clusters {
+			if c.Upstream.Name == v.Service {
+				clusters = append(clusters, c)
+			}
+		}
+		if len(clusters) == 0 {
+			return nil, fmt.Errorf("serviceSelector: value %q: service %q is not a service of the route", v.Value, v.Service)
+		}
+
+		sr := *r
+		sr.HeaderConditions = append(append([]HeaderCondition{}, r.HeaderConditions...), HeaderCondition{
+			Name:      ss.Header,
+			Value:     v.Value,
+			MatchType: "exact",
+		})
+		sr.Clusters = clusters
+		routes = append(routes, &sr)
+	}
+	return routes, nil
+}
+
+// clusterHeaderRoute returns a copy of r which routes requests to the
+// cluster, of r's clusters, named by the policy's header, or an error
+// if the policy is invalid.
+func clusterHeaderRoute(r *Route, chp *projcontour.ClusterHeaderPolicy) (*Route, error) {
+	if isBlank(chp.Header) {
+		return nil, fmt.Errorf("clusterHeaderPolicy: header must be specified")
+	}
+	for _, hc := range r.HeaderConditions {
+		if hc.MatchType == "exact" && strings.EqualFold(hc.Name, chp.Header) {
+			return nil, fmt.Errorf("clusterHeaderPolicy: header %q duplicates an 'exact match' condition of the route", chp.Header)
+		}
+	}
+	hr := *r
+	hr.ClusterHeader = chp.Header
+	return &hr, nil
+}
+
+// circuitBreakers returns the circuit breaking limits of cbp,
+// or nil if it sets none.
+func circuitBreakers(cbp *projcontour.CircuitBreakerPolicy) *CircuitBreakers {
+	if cbp == nil {
+		return nil
+	}
+	cb := CircuitBreakers{
+		MaxConnections:     cbp.MaxConnections,
+		MaxPendingRequests: cbp.MaxPendingRequests,
+		MaxRequests:        cbp.MaxRequests,
+		MaxRetries:         cbp.MaxRetries,
+	}
+	if cb == (CircuitBreakers{}) {
+		return nil
+	}
+	return &cb
+}
+
+// downstreamProtocolPolicy returns the downstream protocol policy,
+// with its status defaulted if not supplied, or an error if the
+// policy is invalid.
+func downstreamProtocolPolicy(dpp *projcontour.DownstreamProtocolPolicy) (*DownstreamProtocolPolicy, error) {
+	if dpp == nil {
+		return nil, nil
+	}
+	policy := &DownstreamProtocolPolicy{
+		Protocol: dpp.Protocol,
+		Status:   dpp.Status,
+	}
+	switch dpp.Protocol {
+	case "HTTP/2":
+		if policy.Status == 0 {
+			policy.Status = http.StatusUpgradeRequired
+		}
+	case "HTTP/1.1":
+		if policy.Status == 0 {
+			policy.Status = http.StatusHTTPVersionNotSupported
+		}
+	default:
+		return nil, fmt.Errorf("downstreamProtocolPolicy: protocol %q must be HTTP/1.1 or HTTP/2", dpp.Protocol)
+	}
+	if policy.Status < 400 || policy.Status > 599 {
+		return nil, fmt.Errorf("downstreamProtocolPolicy: status %d must be in the range 400-599", dpp.Status)
+	}
+	return policy, nil
+}
+
+// luaFilter is the name of the Lua filter, whose route metadata
+// Contour uses to enforce request header allow lists and
+// downstream protocol policies.
+const luaFilter = "envoy.lua"
+
+// routeMetadata returns the route metadata described by md, keyed
+// by filter and then by key, or an error if md is invalid.
+func routeMetadata(md []projcontour.RouteMetadata) (map[string]map[string]interface{}, error) {
+	metadata := make(map[string]map[string]interface{})
+	for _, rm := range md {
+		if isBlank(rm.Filter) {
+			return nil, fmt.Errorf("metadata: filter must be specified")
+		}
+		if rm.Filter == luaFilter {
+			return nil, fmt.Errorf("metadata: filter %q is reserved", rm.Filter)
+		}
+		if _, ok := metadata[rm.Filter]; ok {
+			return nil, fmt.Errorf("metadata: filter %q is specified more than once", rm.Filter)
+		}
+		values := make(map[string]interface{})
+		for _, e := range rm.Entries {
+			if isBlank(e.Key) {
+				return nil, fmt.Errorf("metadata: filter %q: key must be specified", rm.Filter)
+			}
+			if _, ok := values[e.Key]; ok {
+				return nil, fmt.Errorf("metadata: filter %q: key %q is specified more than once", rm.Filter, e.Key)
+			}
+			var set int
+			if e.StringValue != nil {
+				values[e.Key] = *e.StringValue
+				set++
+			}
+			if e.NumberValue != nil {
+				values[e.Key] = float64(*e.NumberValue)
+				set++
+			}
+			if e.BoolValue != nil {
+				values[e.Key] = *e.BoolValue
+				set++
+			}
+			if set != 1 {
+				return nil, fmt.Errorf("metadata: filter %q: key %q must have exactly one of stringValue, numberValue or boolValue", rm.Filter, e.Key)
+			}
+		}
+		metadata[rm.Filter] = values
+	}
+	return metadata, nil
+}
+
+// websocketSubprotocolRoutes returns, for each of routes, a route
+// which rejects websocket requests offering a subprotocol other than
+// those listed, or an error if a subprotocol is not a valid token.
+func websocketSubprotocolRoutes(routes []*Route, subprotocols []string) ([]*Route, error) {
+	var quoted []string
+	for _, p := range subprotocols {
+		if isBlank(p) || strings.ContainsAny(p, " \t,;\"()/<>@[]?={}\\") {
+			return nil, fmt.Errorf("websocketSubprotocols: %q is not a valid subprotocol", p)
+		}
+		quoted = append(quoted, regexp.QuoteMeta(p))
+	}
+	// the header lists the offered subprotocols separated by commas.
+	allowed := "(" + strings.Join(quoted, "|") + ")"
+	regex := `\s*` + allowed + `\s*(,\s*` + allowed + `\s*)*`
+
+	var rejected []*Route
+	for _, r := range routes {
+		rr := *r
+		rr.HeaderConditions = append(append([]HeaderCondition{}, r.HeaderConditions...), HeaderCondition{
+			Name:      "sec-websocket-protocol",
+			Value:     regex,
+			MatchType: "regex",
+			Invert:    true,
+		})
+		rr.Clusters = nil
+		rr.MirrorPolicy = nil
+		rr.DirectResponse = &DirectResponse{StatusCode: http.StatusForbidden}
+		rejected = append(rejected, &rr)
+	}
+	return rejected, nil
+}
+
+// rateLimitPolicy returns the rate limit policy described by rl,
+// or an error if rl is invalid.
+func rateLimitPolicy(rl *projcontour.RateLimitPolicy) (*RateLimitPolicy, error) {
+	if rl == nil {
+		return nil, nil
+	}
+	if len(rl.Descriptors) == 0 {
+		return nil, fmt.Errorf("rateLimitPolicy: at least one descriptor must be specified")
+	}
+	var rp RateLimitPolicy
+	for i, d := range rl.Descriptors {
+		if len(d.Entries) == 0 {
+			return nil, fmt.Errorf("rateLimitPolicy: descriptor %d: at least one entry must be specified", i)
+		}
+		var entries []RateLimitDescriptorEntry
+		for j, e := range d.Entries {
+			var entry RateLimitDescriptorEntry
+			var set int
+			if rh := e.RequestHeader; rh != nil {
+				if isBlank(rh.HeaderName) || isBlank(rh.DescriptorKey) {
+					return nil, fmt.Errorf("rateLimitPolicy: descriptor %d: entry %d: requestHeader must specify headerName and descriptorKey", i, j)
+				}
+				entry.HeaderName, entry.DescriptorKey = rh.HeaderName, rh.DescriptorKey
+				set++
+			}
+			if e.RemoteAddress != nil {
+				entry.RemoteAddress = true
+				set++
+			}
+			if gk := e.GenericKey; gk != nil {
+				if isBlank(gk.Value) {
+					return nil, fmt.Errorf("rateLimitPolicy: descriptor %d: entry %d: genericKey must specify value", i, j)
+				}
+				entry.GenericKey = gk.Value
+				set++
+			}
+			if set != 1 {
+				return nil, fmt.Errorf("rateLimitPolicy: descriptor %d: entry %d must have exactly one of requestHeader, remoteAddress or genericKey", i, j)
+			}
+			entries = append(entries, entry)
+		}
+		rp.Descriptors = append(rp.Descriptors, entries)
+	}
+	return &rp, nil
+}
+
+// sessionPinningPolicy returns the session pinning policy for the
+// supplied route policy, or an error if its ttl is not a positive duration.
+func sessionPinningPolicy(sp *projcontour.SessionPinningPolicy) (*SessionPinningPolicy, error) {
+	ttl, err := time.ParseDuration(sp.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("sessionPinningPolicy: ttl %q must be a positive duration", sp.TTL)
+	}
+	name := sp.CookieName
+	switch {
+	case name == "":
+		name = "X-Contour-Session-Pin"
+	case strings.ContainsAny(name, "()<>@,;:\\\"/[]?={} \t"):
+		return nil, fmt.Errorf("sessionPinningPolicy: cookieName %q is not a valid cookie name", name)
+	}
+	return &SessionPinningPolicy{
+		CookieName: name,
+		TTL:        ttl,
+	}, nil
+}
+
+// sessionAffinityPolicy returns the session affinity policy for the
+// cookie of the supplied load balancer policy, or an error if the
+// policy does not use the Cookie strategy or the cookie is invalid.
+func sessionAffinityPolicy(lbp *projcontour.LoadBalancerPolicy) (*SessionAffinityPolicy, error) {
+	if lbp.Strategy != "Cookie" {
+		return nil, fmt.Errorf("loadBalancerPolicy: cookie requires the Cookie strategy")
+	}
+	cookie := lbp.Cookie
+	policy := SessionAffinityPolicy{
+		CookieName: cookie.Name,
+		Path:       cookie.Path,
+	}
+	switch {
+	case policy.CookieName == "":
+		policy.CookieName = "X-Contour-Session-Affinity"
+	case strings.ContainsAny(policy.CookieName, "()<>@,;:\\\"/[]?={} \t"):
+		return nil, fmt.Errorf("loadBalancerPolicy: cookie name %q is not a valid cookie name", policy.CookieName)
+	}
+	switch {
+	case policy.Path == "":
+		policy.Path = "/"
+	case !strings.HasPrefix(policy.Path, "/") || strings.ContainsAny(policy.Path, "; \t"):
+		return nil, fmt.Errorf("loadBalancerPolicy: cookie path %q must be an absolute path", policy.Path)
+	}
+	if cookie.TTL != "" {
+		ttl, err := time.ParseDuration(cookie.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("loadBalancerPolicy: cookie ttl %q must be a positive duration", cookie.TTL)
+		}
+		policy.TTL = ttl
+	}
+	return &policy, nil
+}
+
+// requestHashPolicies returns the request hash policies of the supplied
+// load balancer policy, or an error if the policy does not use the
+// RequestHash or Maglev strategy, or its policies are invalid, or
+// missing with the RequestHash strategy.
+func requestHashPolicies(lbp *projcontour.LoadBalancerPolicy) ([]RequestHashPolicy, error) {
+	switch {
+	case lbp.Strategy != "RequestHash" && lbp.Strategy != "Maglev":
+		return nil, fmt.Errorf("loadBalancerPolicy: requestHashPolicies requires the RequestHash or Maglev strategy")
+	case lbp.Strategy == "RequestHash" && len(lbp.RequestHashPolicies) == 0:
+		return nil, fmt.Errorf("loadBalancerPolicy: RequestHash strategy requires requestHashPolicies")
+	}
+	var policies []RequestHashPolicy
+	for i, rhp := range lbp.RequestHashPolicies {
+		if (rhp.HeaderName != "") == rhp.SourceIP {
+			return nil, fmt.Errorf("loadBalancerPolicy: requestHashPolicies[%d] must specify exactly one of headerName or sourceIP", i)
+		}
+		if rhp.HeaderName != "" {
+			if errs := validation.IsHTTPHeaderName(rhp.HeaderName); len(errs) > 0 {
+				return nil, fmt.Errorf("loadBalancerPolicy: requestHashPolicies[%d]: header %q is invalid: %s", i, rhp.HeaderName, strings.Join(errs, ", "))
+			}
+		}
+		policies = append(policies, RequestHashPolicy{
+			HeaderName: rhp.HeaderName,
+			SourceIP:   rhp.SourceIP,
+			Terminal:   rhp.Terminal,
+		})
+	}
+	return policies, nil
+}
+
+// experimentPolicy returns the experiment policy for the supplied route
+// policy, or an error if its variants or ttl are not valid.
+func experimentPolicy(ep *projcontour.ExperimentPolicy) (*ExperimentPolicy, error) {
+	if len(ep.Variants) < 2 {
+		return nil, fmt.Errorf("experimentPolicy: at least two variants must be specified")
+	}
+	policy := &ExperimentPolicy{
+		CookieName: ep.CookieName,
+		Header:     ep.Header,
+	}
+	if policy.CookieName == "" {
+		policy.CookieName = "X-Contour-Experiment"
+	}
+	if strings.ContainsAny(policy.CookieName, "()<>@,;:\\\"/[]?={} \t") {
+		return nil, fmt.Errorf("experimentPolicy: cookieName %q is not a valid cookie name", policy.CookieName)
+	}
+	if policy.Header == "" {
+		policy.Header = "X-Contour-Experiment"
+	}
+	if ep.TTL != "" {
+		ttl, err := time.ParseDuration(ep.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("experimentPolicy: ttl %q must be a positive duration", ep.TTL)
+		}
+		policy.TTL = ttl
+	}
+
+	names := make(map[string]bool)
+	var total uint32
+	for _, v := range ep.Variants {
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("experimentPolicy: variant %q: %s", v.Name, strings.Join(errs, ", "))
+		}
+		if names[v.Name] {
+			return nil, fmt.Errorf("experimentPolicy: variant %q is specified more than once", v.Name)
+		}
+		names[v.Name] = true
+		total += v.Percent
+		policy.Variants = append(policy.Variants, Variant{Name: v.Name, Percent: v.Percent})
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("experimentPolicy: variant percentages must add up to 100, not %d", total)
+	}
+	return policy, nil
+}
+
+// httpsRedirectPolicy returns the redirect policy for the supplied
+// policy, or an error if a response header has no name.
+func httpsRedirectPolicy(rp *projcontour.HTTPSRedirectPolicy) (*HTTPSRedirectPolicy, error) {
+	policy := &HTTPSRedirectPolicy{
+		StripPath:  rp.StripPath,
+		StripQuery: rp.StripQuery,
+	}
+	for _, h := range rp.ResponseHeaders {
+		if isBlank(h.Name) {
+			return nil, fmt.Errorf("tls.redirectPolicy: responseHeaders cannot contain a blank header name")
+		}
+		policy.ResponseHeaders = append(policy.ResponseHeaders, HeaderValue{Name: h.Name, Value: h.Value})
+	}
+	return policy, nil
+}
+
+// trailingSlashAction returns the action of the supplied trailing
+// slash policy, or an error if it is not one of redirect or rewrite.
+func trailingSlashAction(tsp *projcontour.TrailingSlashPolicy) (string, error) {
+	switch tsp.Action {
+	case "redirect", "rewrite":
+		return tsp.Action, nil
+	default:
+		return "", fmt.Errorf("trailingSlashPolicy: action %q must be one of redirect or rewrite", tsp.Action)
+	}
+}
+
+// jwtProviders returns the JWT providers of a virtual host of an
+// HTTPProxy in namespace and the name of its default provider, or an
+// error if any provider is invalid. The host of each provider's JWKS
+// URI is resolved through DNS by a synthesized Cluster. The certificate
+// of the host of an https URI is validated as returned by validation.
+func jwtProviders(namespace string, providers []projcontour.JWTProvider, validation func(host string, uv *projcontour.UpstreamValidation) (*UpstreamValidation, error)) ([]*JWTProvider, string, error) {
+	var jps []*JWTProvider
+	var def string
+	names := make(map[string]bool)
+	for _, p := range providers {
+		if isBlank(p.Name) {
+			return nil, "", fmt.Errorf("jwtProviders: name must be specified")
+		}
+		if names[p.Name] {
+			return nil, "", fmt.Errorf("jwtProviders: provider %q is defined more than once", p.Name)
+		}
+		names[p.Name] = true
+		if p.Default {
+			if def != "" {
+				return nil, "", fmt.Errorf("jwtProviders: only one provider may be the default")
+			}
+			def = p.Name
+		}
+		jwks, err := remoteJWKS(namespace, p.RemoteJWKS, validation)
+		if err != nil {
+			return nil, "", fmt.Errorf("jwtProviders: provider %q: %v", p.Name, err)
+		}
+		jps = append(jps, &JWTProvider{
+			Name:       p.Name,
+			Issuer:     p.Issuer,
+			Audiences:  p.Audiences,
+			RemoteJWKS: jwks,
+			ForwardJWT: p.ForwardJWT,
+		})
+	}
+	return jps, def, nil
+}
+
+func remoteJWKS(namespace string, jwks projcontour.RemoteJWKS, validation func(host string, uv *projcontour.UpstreamValidation) (*UpstreamValidation, error)) (RemoteJWKS, error) {
+	u, err := url.Parse(jwks.URI)
+	if err != nil || u.Hostname() == "" {
+		return RemoteJWKS{}, fmt.Errorf("remoteJWKS: uri %q must be an absolute URI", jwks.URI)
+	}
+	var port int
+	switch u.Scheme {
+	case "http":
+		port = 80
+	case "https":
+		port = 443
+	default:
+		return RemoteJWKS{}, fmt.Errorf("remoteJWKS: uri %q must use http or https", jwks.URI)
+	}
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return RemoteJWKS{}, fmt.Errorf("remoteJWKS: uri %q has an invalid port", jwks.URI)
+		}
+	}
+	timeout, err := positiveDuration(jwks.Timeout)
+	if err != nil {
+		return RemoteJWKS{}, fmt.Errorf("remoteJWKS: timeout %q must be a positive duration", jwks.Timeout)
+	}
+	cacheDuration, err := positiveDuration(jwks.CacheDuration)
+	if err != nil {
+		return RemoteJWKS{}, fmt.Errorf("remoteJWKS: cacheDuration %q must be a positive duration", jwks.CacheDuration)
+	}
+
+	cluster := &Cluster{
+		Upstream: &Service{
+			Name:      u.Hostname(),
+			Namespace: namespace,
+			ServicePort: &v1.ServicePort{
+				Name:     u.Scheme,
+				Protocol: v1.ProtocolTCP,
+				Port:     int32(port),
+			},
+			ExternalName: u.Hostname(),
+		},
+	}
+	switch {
+	case u.Scheme == "https":
+		// keys fetched from an unverified host cannot be trusted.
+		if jwks.UpstreamValidation == nil {
+			return RemoteJWKS{}, fmt.Errorf("remoteJWKS: uri %q uses https, validation must be specified", jwks.URI)
+		}
+		uv, err := validation(u.Hostname(), jwks.UpstreamValidation)
+		if err != nil {
+			return RemoteJWKS{}, fmt.Errorf("remoteJWKS: %v", err)
+		}
+		cluster.Upstream.Protocol = "tls"
+		cluster.SNI = u.Hostname()
+		cluster.UpstreamValidation = uv
+	case jwks.UpstreamValidation != nil:
+		return RemoteJWKS{}, fmt.Errorf("remoteJWKS: validation requires an https uri, not %q", jwks.URI)
+	}
+	return RemoteJWKS{
+		URI:           jwks.URI,
+		Cluster:       cluster,
+		Timeout:       timeout,
+		CacheDuration: cacheDuration,
+	}, nil
+}
+
+// positiveDuration parses d, which if not blank must be a positive
+// duration.
+func positiveDuration(d string) (time.Duration, error) {
+	if d == "" {
+		return 0, nil
+	}
+	v, err := time.ParseDuration(d)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("duration %q is not positive", d)
+	}
+	return v, nil
+}
+
+// resolveJWTProviders requires the default JWT provider, if any, on
+// each of routes without a jwtVerificationPolicy, and returns an error
+// if a route requires a provider which is not one of providers or
+// requires a JWT on requests which are not upgraded to HTTPS.
+func resolveJWTProviders(routes []*Route, providers []*JWTProvider, def string) error {
+	for _, r := range routes {
+		switch {
+		case r.JWTProvider != "":
+			if !hasJWTProvider(providers, r.JWTProvider) {
+				return fmt.Errorf("jwtVerificationPolicy: provider %q is not defined on the virtual host", r.JWTProvider)
+			}
+		case !r.JWTDisabled:
+			r.JWTProvider = def
+		}
+		// requests on the insecure listener cannot be verified.
+		if r.JWTProvider != "" && !r.HTTPSUpgrade {
+			return fmt.Errorf("jwtVerificationPolicy: routes which permit insecure requests cannot require a JWT")
+		}
+	}
+	return nil
+}
+
+func hasJWTProvider(providers []*JWTProvider, name string) bool {
+	for _, p := range providers {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// corsPolicy returns the CORS policy of a virtual host, or an error
+// if cp is invalid.
+func corsPolicy(cp *projcontour.CORSPolicy) (*CORSPolicy, error) {
+	if cp == nil {
+		return nil, nil
+	}
+	if len(cp.AllowOrigin) == 0 && len(cp.AllowOriginRegex) == 0 {
+		return nil, fmt.Errorf("corsPolicy: allowOrigin or allowOriginRegex must be specified")
+	}
+	for _, o := range cp.AllowOrigin {
+		if isBlank(o) {
+			return nil, fmt.Errorf("corsPolicy: allowOrigin cannot contain a blank origin")
+		}
+		if o == "*" && cp.AllowCredentials {
+			return nil, fmt.Errorf("corsPolicy: allowOrigin * cannot be combined with allowCredentials")
+		}
+	}
+	for _, re := range cp.AllowOriginRegex {
+		if _, err := regexp.Compile(re); err != nil || isBlank(re) {
+			return nil, fmt.Errorf("corsPolicy: allowOriginRegex %q is not a valid regular expression", re)
+		}
+	}
+	for _, f := range []struct {
+		name   string
+		values []string
+	}{
+		{"allowMethods", cp.AllowMethods},
+		{"allowHeaders", cp.AllowHeaders},
+		{"exposeHeaders", cp.ExposeHeaders},
+	} {
+		// methods, like header names, are HTTP tokens.
+		for _, v := range f.values {
+			if errs := validation.IsHTTPHeaderName(v); len(errs) > 0 {
+				return nil, fmt.Errorf("corsPolicy: %s %q is invalid: %s", f.name, v, strings.Join(errs, ", "))
+			}
+		}
+	}
+	maxAge, err := positiveDuration(cp.MaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("corsPolicy: maxAge %q must be a positive duration", cp.MaxAge)
+	}
+	return &CORSPolicy{
+		AllowOrigin:      cp.AllowOrigin,
+		AllowOriginRegex: cp.AllowOriginRegex,
+		AllowMethods:     cp.AllowMethods,
+		AllowHeaders:     cp.AllowHeaders,
+		ExposeHeaders:    cp.ExposeHeaders,
+		MaxAge:           maxAge,
+		AllowCredentials: cp.AllowCredentials,
+	}, nil
+}
+
+// headersPolicy returns the policy for the headers of a request or
+// response, or an error if hp is invalid. field names the policy in
+// errors.
+func headersPolicy(field string, hp *projcontour.HeadersPolicy) (*HeadersPolicy, error) {
+	if hp == nil {
+		return nil, nil
+	}
+	policy := &HeadersPolicy{}
+	names := make(map[string]bool)
+	for _, h := range hp.Set {
+		if err := modifiableHeader(h.Name); err != nil {
+			return nil, fmt.Errorf("%s: set: %v", field, err)
+		}
+		key := http.CanonicalHeaderKey(h.Name)
+		if names[key] {
+			return nil, fmt.Errorf("%s: header %q is set more than once", field, h.Name)
+		}
+		names[key] = true
+		policy.Set = append(policy.Set, HeaderValue{Name: key, Value: escapeHeaderValue(h.Value)})
+	}
+	for _, name := range hp.Remove {
+		if err := modifiableHeader(name); err != nil {
+			return nil, fmt.Errorf("%s: remove: %v", field, err)
+		}
+		key := http.CanonicalHeaderKey(name)
+		if names[key] {
+			return nil, fmt.Errorf("%s: header %q is set or removed more than once", field, name)
+		}
+		names[key] = true
+		policy.Remove = append(policy.Remove, key)
+	}
+	return policy, nil
+}
+
+// modifiableHeader returns an error if name is not a valid header
+// name, or names the Host header, which Envoy does not modify.
+func modifiableHeader(name string) error {
+	if errs := validation.IsHTTPHeaderName(name); len(errs) > 0 {
+		return fmt.Errorf("header %q is invalid: %s", name, strings.Join(errs, ", "))
+	}
+	if strings.EqualFold(name, "Host") {
+		return fmt.Errorf("header %q cannot be modified", name)
+	}
+	return nil
+}
+
+// envoyHeaderVariable matches the dynamic values Envoy substitutes
+// into header values at the start of a string.
+var envoyHeaderVariable = regexp.MustCompile(`^%(` + strings.Join([]string{
+	"DOWNSTREAM_REMOTE_ADDRESS",
+	"DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT",
+	"DOWNSTREAM_LOCAL_ADDRESS",
+	"DOWNSTREAM_LOCAL_ADDRESS_WITHOUT_PORT",
+	"DOWNSTREAM_LOCAL_PORT",
+	"DOWNSTREAM_LOCAL_URI_SAN",
+	"DOWNSTREAM_PEER_URI_SAN",
+	"DOWNSTREAM_LOCAL_SUBJECT",
+	"DOWNSTREAM_PEER_SUBJECT",
+	"DOWNSTREAM_PEER_ISSUER",
+	"DOWNSTREAM_TLS_SESSION_ID",
+	"DOWNSTREAM_TLS_CIPHER",
+	"DOWNSTREAM_TLS_VERSION",
+	"DOWNSTREAM_PEER_FINGERPRINT_256",
+	"DOWNSTREAM_PEER_SERIAL",
+	"DOWNSTREAM_PEER_CERT_V_START",
+	"DOWNSTREAM_PEER_CERT_V_END",
+	"UPSTREAM_REMOTE_ADDRESS",
+	"HOSTNAME",
+	"PROTOCOL",
+	"START_TIME",
+	`REQ\([A-Za-z0-9-]+\)`,
+}, "|") + `)%`)
+
+// escapeHeaderValue returns value with each '%' which does not
+// introduce one of Envoy's dynamic values doubled, so Envoy treats
+// it as literal rather than rejecting the configuration.
+func escapeHeaderValue(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' {
+			sb.WriteByte(value[i])
+			continue
+		}
+		if v := envoyHeaderVariable.FindString(value[i:]); v != "" {
+			sb.WriteString(v)
+			i += len(v) - 1
+			continue
+		}
+		sb.WriteString("%%")
+	}
+	return sb.String()
+}
+
+// maxDirectResponseBody is the largest body Envoy returns from a
+// direct response by default.
+const maxDirectResponseBody = 4096
+
+// directResponse returns the response of a direct response policy,
+// or an error if drp is invalid.
+func directResponse(drp *projcontour.HTTPDirectResponsePolicy) (*DirectResponse, error) {
+	if drp.StatusCode < 200 || drp.StatusCode > 599 {
+		return nil, fmt.Errorf("directResponsePolicy: statusCode %d must be in the range 200-599", drp.StatusCode)
+	}
+	if len(drp.Body) > maxDirectResponseBody {
+		return nil, fmt.Errorf("directResponsePolicy: body must be at most %d bytes", maxDirectResponseBody)
+	}
+	return &DirectResponse{
+		StatusCode: drp.StatusCode,
+		Body:       drp.Body,
+	}, nil
+}
+
+// requestRedirect returns the redirect of a request redirect policy,
+// or an error if rrp is invalid.
+func requestRedirect(rrp *projcontour.HTTPRequestRedirectPolicy) (*Redirect, error) {
+	switch rrp.Scheme {
+	case "", "http", "https":
+	default:
+		return nil, fmt.Errorf("requestRedirectPolicy: scheme %q must be one of http or https", rrp.Scheme)
+	}
+	if strings.Contains(rrp.Hostname, "*") {
+		return nil, fmt.Errorf("requestRedirectPolicy: hostname %q cannot use wildcards", rrp.Hostname)
+	}
+	if rrp.Port > 65535 {
+		return nil, fmt.Errorf("requestRedirectPolicy: port %d must be in the range 1-65535", rrp.Port)
+	}
+	if rrp.Path != "" && rrp.Prefix != "" {
+		return nil, fmt.Errorf("requestRedirectPolicy: path and prefix cannot both be specified")
+	}
+	for field, p := range map[string]string{"path": rrp.Path, "prefix": rrp.Prefix} {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("requestRedirectPolicy: %s %q must start with /", field, p)
+		}
+	}
+
+	status := rrp.StatusCode
+	switch status {
+	case 0:
+		status = http.StatusFound
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("requestRedirectPolicy: statusCode %d must be one of 301, 302, 303, 307, or 308", status)
+	}
+
+	return &Redirect{
+		Host:          rrp.Hostname,
+		Scheme:        rrp.Scheme,
+		Port:          rrp.Port,
+		Path:          rrp.Path,
+		PrefixRewrite: rrp.Prefix,
+		StatusCode:    status,
+	}, nil
+}
+
+// pathRewrite returns the replacement for prefix, the path prefix
+// matched by a route, from the path rewrite policy prp, or an error if
+// prp is invalid. If no replacement applies to prefix the path is not
+// rewritten, and pathRewrite returns the empty string.
+func pathRewrite(prp *projcontour.PathRewritePolicy, prefix string) (string, error) {
+	replacements := make(map[string]string)
+	for _, rp := range prp.ReplacePrefix {
+		if rp.Prefix != "" && !strings.HasPrefix(rp.Prefix, "/") {
+			return "", fmt.Errorf("pathRewritePolicy: prefix %q must start with /", rp.Prefix)
+		}
+		if rp.Replacement == "" {
+			return "", fmt.Errorf("pathRewritePolicy: replacement for prefix %q must be specified", rp.Prefix)
+		}
+		if _, ok := replacements[rp.Prefix]; ok {
+			if rp.Prefix == "" {
+				return "", fmt.Errorf("pathRewritePolicy: ambiguous replacement, more than one replacement without a prefix")
+			}
+			return "", fmt.Errorf("pathRewritePolicy: ambiguous replacement, prefix %q is replaced more than once", rp.Prefix)
+		}
+		replacements[rp.Prefix] = rp.Replacement
+	}
+
+	if replacement, ok := replacements[prefix]; ok {
+		return replacement, nil
+	}
+	return replacements[""], nil
+}
+
+// classification returns the value of the classification header
+// for responses served by the supplied service.
+func classification(service projcontour.Service) string {
+	if service.Classification != "" {
+		return service.Classification
+	}
+	return service.Name
+}
+
 func parseTimeout(timeout string) time.Duration {
 	if timeout == "" {
 		// Blank is interpreted as no timeout specified, use envoy defaults
@@ -175,6 +175,7 @@ func TestRetryPolicyIngressRoute(t *testing.T) {
 	tests := map[string]struct {
 		rp   *projcontour.RetryPolicy
 		want *RetryPolicy
+		err  string
 	}{
 		"nil retry policy": {
 			rp:   nil,
@@ -216,11 +217,126 @@ func TestRetryPolicyIngressRoute(t *testing.T) {
 				PerTryTimeout: 0 * time.Second,
 			},
 		},
+		"retry on conditions": {
+			rp: &projcontour.RetryPolicy{
+				NumRetries: 3,
+				RetryOn:    []string{"gateway-error", "reset", "unavailable", "reset"},
+			},
+			want: &RetryPolicy{
+				RetryOn:    "gateway-error,reset,unavailable",
+				NumRetries: 3,
+			},
+		},
+		"retriable status codes": {
+			rp: &projcontour.RetryPolicy{
+				RetryOn:              []string{"connect-failure"},
+				RetriableStatusCodes: []uint32{409, 503},
+			},
+			want: &RetryPolicy{
+				RetryOn:              "connect-failure,retriable-status-codes",
+				NumRetries:           1,
+				RetriableStatusCodes: []uint32{409, 503},
+			},
+		},
+		"retriable status codes condition": {
+			rp: &projcontour.RetryPolicy{
+				RetryOn:              []string{"retriable-status-codes"},
+				RetriableStatusCodes: []uint32{409},
+			},
+			want: &RetryPolicy{
+				RetryOn:              "retriable-status-codes",
+				NumRetries:           1,
+				RetriableStatusCodes: []uint32{409},
+			},
+		},
+		"back off": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					BaseInterval: "100ms",
+					MaxInterval:  "2s",
+				},
+			},
+			want: &RetryPolicy{
+				RetryOn:             "5xx",
+				NumRetries:          1,
+				BackOffBaseInterval: 100 * time.Millisecond,
+				BackOffMaxInterval:  2 * time.Second,
+			},
+		},
+		"back off without max interval": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					BaseInterval: "100ms",
+				},
+			},
+			want: &RetryPolicy{
+				RetryOn:             "5xx",
+				NumRetries:          1,
+				BackOffBaseInterval: 100 * time.Millisecond,
+			},
+		},
+		"back off without base interval": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					MaxInterval: "2s",
+				},
+			},
+			err: `retryPolicy: backOff baseInterval "" must be a positive duration`,
+		},
+		"back off zero base interval": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					BaseInterval: "0s",
+				},
+			},
+			err: `retryPolicy: backOff baseInterval "0s" must be a positive duration`,
+		},
+		"back off max interval less than base interval": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					BaseInterval: "1s",
+					MaxInterval:  "500ms",
+				},
+			},
+			err: `retryPolicy: backOff maxInterval "500ms" must be a duration of at least baseInterval`,
+		},
+		"back off invalid max interval": {
+			rp: &projcontour.RetryPolicy{
+				BackOff: &projcontour.RetryBackOff{
+					BaseInterval: "1s",
+					MaxInterval:  "forever",
+				},
+			},
+			err: `retryPolicy: backOff maxInterval "forever" must be a duration of at least baseInterval`,
+		},
+		"unsupported condition": {
+			rp: &projcontour.RetryPolicy{
+				RetryOn: []string{"5xx", "timeout"},
+			},
+			err: `retryPolicy: retryOn condition "timeout" is not supported`,
+		},
+		"retriable status code out of range": {
+			rp: &projcontour.RetryPolicy{
+				RetriableStatusCodes: []uint32{503, 600},
+			},
+			err: "retryPolicy: retriable status code 600 must be in the range 100-599",
+		},
+		"retriable status codes condition without codes": {
+			rp: &projcontour.RetryPolicy{
+				RetryOn: []string{"retriable-status-codes"},
+			},
+			err: "retryPolicy: retriable-status-codes requires retriableStatusCodes",
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			got := retryPolicy(tc.rp)
+			got, err := retryPolicy(tc.rp)
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			assert.Equal(t, tc.err, gotErr)
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -330,6 +446,16 @@ func TestTimeoutPolicy(t *testing.T) {
 				IdleTimeout: 900 * time.Second,
 			},
 		},
+		"infinite idle timeout with response timeout": {
+			tp: &projcontour.TimeoutPolicy{
+				Response: "30s",
+				Idle:     "infinity",
+			},
+			want: &TimeoutPolicy{
+				ResponseTimeout: 30 * time.Second,
+				IdleTimeout:     -1,
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -340,6 +466,352 @@ func TestTimeoutPolicy(t *testing.T) {
 	}
 }
 
+func TestConnectTimeout(t *testing.T) {
+	tests := map[string]struct {
+		timeout string
+		want    time.Duration
+		wantErr bool
+	}{
+		"not supplied": {
+			timeout: "",
+			want:    0,
+		},
+		"valid": {
+			timeout: "2s",
+			want:    2 * time.Second,
+		},
+		"zero": {
+			timeout: "0s",
+			wantErr: true,
+		},
+		"infinity": {
+			timeout: "infinity",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := connectTimeout(tc.timeout)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTCPKeepalive(t *testing.T) {
+	tests := map[string]struct {
+		keepalive *projcontour.TCPKeepalive
+		want      *TCPKeepalive
+		wantErr   bool
+	}{
+		"nil": {
+			keepalive: nil,
+			want:      nil,
+		},
+		"empty": {
+			keepalive: &projcontour.TCPKeepalive{},
+			want:      &TCPKeepalive{},
+		},
+		"all fields": {
+			keepalive: &projcontour.TCPKeepalive{
+				Probes:   3,
+				Time:     "5m",
+				Interval: "30s",
+			},
+			want: &TCPKeepalive{
+				Probes:   3,
+				Time:     5 * time.Minute,
+				Interval: 30 * time.Second,
+			},
+		},
+		"fractional seconds": {
+			keepalive: &projcontour.TCPKeepalive{
+				Interval: "1500ms",
+			},
+			wantErr: true,
+		},
+		"invalid time": {
+			keepalive: &projcontour.TCPKeepalive{
+				Time: "forever",
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tcpKeepalive(tc.keepalive)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHealthCheckPolicy(t *testing.T) {
+	tests := map[string]struct {
+		hc      *projcontour.HTTPHealthCheckPolicy
+		want    *HealthCheckPolicy
+		wantErr bool
+	}{
+		"nil": {
+			hc:   nil,
+			want: nil,
+		},
+		"path and host": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path:            "/healthz",
+				Host:            "kuard.example.com",
+				IntervalSeconds: 5,
+			},
+			want: &HealthCheckPolicy{
+				Path:     "/healthz",
+				Host:     "kuard.example.com",
+				Interval: 5 * time.Second,
+			},
+		},
+		"expected statuses": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path:             "/healthz",
+				ExpectedStatuses: []string{"200-299", "301"},
+			},
+			want: &HealthCheckPolicy{
+				Path: "/healthz",
+				ExpectedStatuses: []StatusRange{
+					{Start: 200, End: 300},
+					{Start: 301, End: 302},
+				},
+			},
+		},
+		"request headers": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path: "/healthz",
+				RequestHeaders: []projcontour.HeaderValue{
+					{Name: "x-health-token", Value: "100%"},
+				},
+			},
+			want: &HealthCheckPolicy{
+				Path: "/healthz",
+				RequestHeaders: []HeaderValue{
+					{Name: "X-Health-Token", Value: "100%%"},
+				},
+			},
+		},
+		"status out of range": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path:             "/healthz",
+				ExpectedStatuses: []string{"200-600"},
+			},
+			wantErr: true,
+		},
+		"reversed range": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path:             "/healthz",
+				ExpectedStatuses: []string{"299-200"},
+			},
+			wantErr: true,
+		},
+		"not a status": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path:             "/healthz",
+				ExpectedStatuses: []string{"2xx"},
+			},
+			wantErr: true,
+		},
+		"host request header": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path: "/healthz",
+				RequestHeaders: []projcontour.HeaderValue{
+					{Name: "Host", Value: "kuard.example.com"},
+				},
+			},
+			wantErr: true,
+		},
+		"duplicate request header": {
+			hc: &projcontour.HTTPHealthCheckPolicy{
+				Path: "/healthz",
+				RequestHeaders: []projcontour.HeaderValue{
+					{Name: "X-Health-Token", Value: "a"},
+					{Name: "x-health-token", Value: "b"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := healthCheckPolicy(tc.hc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTCPHealthCheckPolicy(t *testing.T) {
+	tests := map[string]struct {
+		hc      *projcontour.TCPHealthCheckPolicy
+		want    *TCPHealthCheckPolicy
+		wantErr bool
+	}{
+		"nil": {
+			hc:   nil,
+			want: nil,
+		},
+		"connect only": {
+			hc: &projcontour.TCPHealthCheckPolicy{
+				IntervalSeconds:       5,
+				HealthyThresholdCount: 2,
+			},
+			want: &TCPHealthCheckPolicy{
+				Interval:         5 * time.Second,
+				HealthyThreshold: 2,
+			},
+		},
+		"send and receive": {
+			hc: &projcontour.TCPHealthCheckPolicy{
+				Send:    "50494e470d0a",
+				Receive: []string{"2b504f4e47", "0D0A"},
+			},
+			want: &TCPHealthCheckPolicy{
+				Send:    "50494E470D0A",
+				Receive: []string{"2B504F4E47", "0D0A"},
+			},
+		},
+		"send not hex": {
+			hc: &projcontour.TCPHealthCheckPolicy{
+				Send: "PING",
+			},
+			wantErr: true,
+		},
+		"odd length send": {
+			hc: &projcontour.TCPHealthCheckPolicy{
+				Send: "0d0",
+			},
+			wantErr: true,
+		},
+		"empty receive": {
+			hc: &projcontour.TCPHealthCheckPolicy{
+				Receive: []string{""},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tcpHealthCheckPolicy(tc.hc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	tests := map[string]struct {
+		cbp  *projcontour.CircuitBreakerPolicy
+		want *CircuitBreakers
+	}{
+		"nil": {
+			cbp:  nil,
+			want: nil,
+		},
+		"empty": {
+			cbp:  &projcontour.CircuitBreakerPolicy{},
+			want: nil,
+		},
+		"limits": {
+			cbp: &projcontour.CircuitBreakerPolicy{
+				MaxConnections: 100,
+				MaxRetries:     3,
+			},
+			want: &CircuitBreakers{
+				MaxConnections: 100,
+				MaxRetries:     3,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := circuitBreakers(tc.cbp)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDownstreamProtocolPolicy(t *testing.T) {
+	tests := map[string]struct {
+		dpp     *projcontour.DownstreamProtocolPolicy
+		want    *DownstreamProtocolPolicy
+		wantErr bool
+	}{
+		"nil": {
+			dpp:  nil,
+			want: nil,
+		},
+		"HTTP/2 default status": {
+			dpp: &projcontour.DownstreamProtocolPolicy{
+				Protocol: "HTTP/2",
+			},
+			want: &DownstreamProtocolPolicy{
+				Protocol: "HTTP/2",
+				Status:   426,
+			},
+		},
+		"HTTP/1.1 default status": {
+			dpp: &projcontour.DownstreamProtocolPolicy{
+				Protocol: "HTTP/1.1",
+			},
+			want: &DownstreamProtocolPolicy{
+				Protocol: "HTTP/1.1",
+				Status:   505,
+			},
+		},
+		"explicit status": {
+			dpp: &projcontour.DownstreamProtocolPolicy{
+				Protocol: "HTTP/2",
+				Status:   403,
+			},
+			want: &DownstreamProtocolPolicy{
+				Protocol: "HTTP/2",
+				Status:   403,
+			},
+		},
+		"unknown protocol": {
+			dpp: &projcontour.DownstreamProtocolPolicy{
+				Protocol: "HTTP/1.0",
+			},
+			wantErr: true,
+		},
+		"status not an error": {
+			dpp: &projcontour.DownstreamProtocolPolicy{
+				Protocol: "HTTP/2",
+				Status:   301,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := downstreamProtocolPolicy(tc.dpp)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestLoadBalancerPolicy(t *testing.T) {
 	tests := map[string]struct {
 		lbp  *projcontour.LoadBalancerPolicy
@@ -371,6 +843,18 @@ func TestLoadBalancerPolicy(t *testing.T) {
 			},
 			want: "Cookie",
 		},
+		"RequestHash": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+			},
+			want: "RequestHash",
+		},
+		"Maglev": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Maglev",
+			},
+			want: "Maglev",
+		},
 		"unknown": {
 			lbp: &projcontour.LoadBalancerPolicy{
 				Strategy: "please",
@@ -387,6 +871,589 @@ func TestLoadBalancerPolicy(t *testing.T) {
 	}
 }
 
+func TestBlueGreenClusters(t *testing.T) {
+	blue := &Cluster{Upstream: &Service{Name: "blue"}}
+	green := &Cluster{Upstream: &Service{Name: "green"}}
+
+	tests := map[string]struct {
+		bg          *projcontour.BlueGreenPolicy
+		wantActive  *Cluster
+		wantPreview *Cluster
+		wantErr     bool
+	}{
+		"active and preview": {
+			bg: &projcontour.BlueGreenPolicy{
+				ActiveService:  "blue",
+				PreviewService: "green",
+			},
+			wantActive:  blue,
+			wantPreview: green,
+		},
+		"promoted": {
+			bg: &projcontour.BlueGreenPolicy{
+				ActiveService:  "blue",
+				PreviewService: "green",
+				Promote:        true,
+			},
+			wantActive:  green,
+			wantPreview: blue,
+		},
+		"same service": {
+			bg: &projcontour.BlueGreenPolicy{
+				ActiveService:  "blue",
+				PreviewService: "blue",
+			},
+			wantErr: true,
+		},
+		"missing active service": {
+			bg: &projcontour.BlueGreenPolicy{
+				ActiveService:  "red",
+				PreviewService: "green",
+			},
+			wantErr: true,
+		},
+		"preview header without name": {
+			bg: &projcontour.BlueGreenPolicy{
+				ActiveService:  "blue",
+				PreviewService: "green",
+				PreviewHeader:  &projcontour.HeaderCondition{Present: true},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			active, preview, err := blueGreenClusters([]*Cluster{blue, green}, tc.bg)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantActive, active)
+			assert.Equal(t, tc.wantPreview, preview)
+		})
+	}
+}
+
+func TestSessionPinningPolicy(t *testing.T) {
+	tests := map[string]struct {
+		sp      *projcontour.SessionPinningPolicy
+		want    *SessionPinningPolicy
+		wantErr bool
+	}{
+		"default cookie name": {
+			sp: &projcontour.SessionPinningPolicy{
+				TTL: "10m",
+			},
+			want: &SessionPinningPolicy{
+				CookieName: "X-Contour-Session-Pin",
+				TTL:        10 * time.Minute,
+			},
+		},
+		"custom cookie name": {
+			sp: &projcontour.SessionPinningPolicy{
+				CookieName: "release",
+				TTL:        "1h",
+			},
+			want: &SessionPinningPolicy{
+				CookieName: "release",
+				TTL:        time.Hour,
+			},
+		},
+		"missing ttl": {
+			sp:      &projcontour.SessionPinningPolicy{},
+			wantErr: true,
+		},
+		"negative ttl": {
+			sp: &projcontour.SessionPinningPolicy{
+				TTL: "-5s",
+			},
+			wantErr: true,
+		},
+		"invalid cookie name": {
+			sp: &projcontour.SessionPinningPolicy{
+				CookieName: "my cookie",
+				TTL:        "10m",
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := sessionPinningPolicy(tc.sp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSessionAffinityPolicy(t *testing.T) {
+	tests := map[string]struct {
+		lbp     *projcontour.LoadBalancerPolicy
+		want    *SessionAffinityPolicy
+		wantErr bool
+	}{
+		"defaults": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				Cookie:   &projcontour.SessionAffinityCookie{},
+			},
+			want: &SessionAffinityPolicy{
+				CookieName: "X-Contour-Session-Affinity",
+				Path:       "/",
+			},
+		},
+		"custom cookie": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				Cookie: &projcontour.SessionAffinityCookie{
+					Name: "affinity",
+					TTL:  "1h",
+					Path: "/cart",
+				},
+			},
+			want: &SessionAffinityPolicy{
+				CookieName: "affinity",
+				TTL:        time.Hour,
+				Path:       "/cart",
+			},
+		},
+		"not cookie strategy": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Random",
+				Cookie:   &projcontour.SessionAffinityCookie{},
+			},
+			wantErr: true,
+		},
+		"invalid cookie name": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				Cookie: &projcontour.SessionAffinityCookie{
+					Name: "my cookie",
+				},
+			},
+			wantErr: true,
+		},
+		"relative path": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				Cookie: &projcontour.SessionAffinityCookie{
+					Path: "cart",
+				},
+			},
+			wantErr: true,
+		},
+		"negative ttl": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				Cookie: &projcontour.SessionAffinityCookie{
+					TTL: "-5s",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := sessionAffinityPolicy(tc.lbp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRequestHashPolicies(t *testing.T) {
+	tests := map[string]struct {
+		lbp  *projcontour.LoadBalancerPolicy
+		want []RequestHashPolicy
+		err  string
+	}{
+		"header and source ip": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					HeaderName: "X-Tenant",
+					Terminal:   true,
+				}, {
+					SourceIP: true,
+				}},
+			},
+			want: []RequestHashPolicy{{
+				HeaderName: "X-Tenant",
+				Terminal:   true,
+			}, {
+				SourceIP: true,
+			}},
+		},
+		"maglev": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Maglev",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					SourceIP: true,
+				}},
+			},
+			want: []RequestHashPolicy{{
+				SourceIP: true,
+			}},
+		},
+		"maglev without policies": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Maglev",
+			},
+		},
+		"not request hash strategy": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					SourceIP: true,
+				}},
+			},
+			err: "loadBalancerPolicy: requestHashPolicies requires the RequestHash or Maglev strategy",
+		},
+		"no policies": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+			},
+			err: "loadBalancerPolicy: RequestHash strategy requires requestHashPolicies",
+		},
+		"header and source ip in one policy": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					HeaderName: "X-Tenant",
+					SourceIP:   true,
+				}},
+			},
+			err: "loadBalancerPolicy: requestHashPolicies[0] must specify exactly one of headerName or sourceIP",
+		},
+		"empty policy": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					SourceIP: true,
+				}, {}},
+			},
+			err: "loadBalancerPolicy: requestHashPolicies[1] must specify exactly one of headerName or sourceIP",
+		},
+		"invalid header": {
+			lbp: &projcontour.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []projcontour.RequestHashPolicy{{
+					HeaderName: "X Tenant",
+				}},
+			},
+			err: `loadBalancerPolicy: requestHashPolicies[0]: header "X Tenant" is invalid: a valid HTTP header must consist of alphanumeric characters or '-' (e.g. 'X-Header-Name', regex used for validation is '[-A-Za-z0-9]+')`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := requestHashPolicies(tc.lbp)
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			assert.Equal(t, tc.err, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExperimentPolicy(t *testing.T) {
+	variants := []projcontour.ExperimentVariant{{
+		Name:    "control",
+		Percent: 50,
+	}, {
+		Name:    "treatment",
+		Percent: 50,
+	}}
+
+	tests := map[string]struct {
+		ep      *projcontour.ExperimentPolicy
+		want    *ExperimentPolicy
+		wantErr bool
+	}{
+		"defaults": {
+			ep: &projcontour.ExperimentPolicy{
+				Variants: variants,
+			},
+			want: &ExperimentPolicy{
+				CookieName: "X-Contour-Experiment",
+				Header:     "X-Contour-Experiment",
+				Variants: []Variant{
+					{Name: "control", Percent: 50},
+					{Name: "treatment", Percent: 50},
+				},
+			},
+		},
+		"custom cookie, header and ttl": {
+			ep: &projcontour.ExperimentPolicy{
+				CookieName: "checkout",
+				Header:     "x-checkout-variant",
+				TTL:        "168h",
+				Variants:   variants,
+			},
+			want: &ExperimentPolicy{
+				CookieName: "checkout",
+				Header:     "x-checkout-variant",
+				TTL:        168 * time.Hour,
+				Variants: []Variant{
+					{Name: "control", Percent: 50},
+					{Name: "treatment", Percent: 50},
+				},
+			},
+		},
+		"single variant": {
+			ep: &projcontour.ExperimentPolicy{
+				Variants: variants[:1],
+			},
+			wantErr: true,
+		},
+		"percentages do not add up to 100": {
+			ep: &projcontour.ExperimentPolicy{
+				Variants: []projcontour.ExperimentVariant{{
+					Name:    "control",
+					Percent: 50,
+				}, {
+					Name:    "treatment",
+					Percent: 60,
+				}},
+			},
+			wantErr: true,
+		},
+		"duplicate variant": {
+			ep: &projcontour.ExperimentPolicy{
+				Variants: []projcontour.ExperimentVariant{variants[0], variants[0]},
+			},
+			wantErr: true,
+		},
+		"invalid variant name": {
+			ep: &projcontour.ExperimentPolicy{
+				Variants: []projcontour.ExperimentVariant{{
+					Name:    "Control.v1",
+					Percent: 50,
+				}, variants[1]},
+			},
+			wantErr: true,
+		},
+		"invalid ttl": {
+			ep: &projcontour.ExperimentPolicy{
+				TTL:      "forever",
+				Variants: variants,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := experimentPolicy(tc.ep)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHeadersPolicy(t *testing.T) {
+	tests := map[string]struct {
+		hp      *projcontour.HeadersPolicy
+		want    *HeadersPolicy
+		wantErr bool
+	}{
+		"nil": {
+			hp:   nil,
+			want: nil,
+		},
+		"set and remove": {
+			hp: &projcontour.HeadersPolicy{
+				Set: []projcontour.HeaderValue{{
+					Name:  "x-forwarded-client",
+					Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+				}, {
+					Name:  "X-Request-Source",
+					Value: "%REQ(x-request-id)% via contour",
+				}},
+				Remove: []string{"x-internal-token"},
+			},
+			want: &HeadersPolicy{
+				Set: []HeaderValue{{
+					Name:  "X-Forwarded-Client",
+					Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+				}, {
+					Name:  "X-Request-Source",
+					Value: "%REQ(x-request-id)% via contour",
+				}},
+				Remove: []string{"X-Internal-Token"},
+			},
+		},
+		"literal percent signs are escaped": {
+			hp: &projcontour.HeadersPolicy{
+				Set: []projcontour.HeaderValue{{
+					Name:  "x-discount",
+					Value: "100% off %UNKNOWN% from %HOSTNAME%",
+				}},
+			},
+			want: &HeadersPolicy{
+				Set: []HeaderValue{{
+					Name:  "X-Discount",
+					Value: "100%% off %%UNKNOWN%% from %HOSTNAME%",
+				}},
+			},
+		},
+		"duplicate set": {
+			hp: &projcontour.HeadersPolicy{
+				Set: []projcontour.HeaderValue{{
+					Name:  "x-foo",
+					Value: "a",
+				}, {
+					Name:  "X-Foo",
+					Value: "b",
+				}},
+			},
+			wantErr: true,
+		},
+		"set and removed": {
+			hp: &projcontour.HeadersPolicy{
+				Set: []projcontour.HeaderValue{{
+					Name:  "x-foo",
+					Value: "a",
+				}},
+				Remove: []string{"x-foo"},
+			},
+			wantErr: true,
+		},
+		"invalid name": {
+			hp: &projcontour.HeadersPolicy{
+				Remove: []string{"x foo"},
+			},
+			wantErr: true,
+		},
+		"host": {
+			hp: &projcontour.HeadersPolicy{
+				Set: []projcontour.HeaderValue{{
+					Name:  "host",
+					Value: "www.example.com",
+				}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := headersPolicy("requestHeadersPolicy", tc.hp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPathRewrite(t *testing.T) {
+	tests := map[string]struct {
+		prp     *projcontour.PathRewritePolicy
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		"replacement without prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Replacement: "/api/v2",
+				}},
+			},
+			prefix: "/v2",
+			want:   "/api/v2",
+		},
+		"replacement of matched prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix:      "/foo",
+					Replacement: "/",
+				}, {
+					Prefix:      "/bar",
+					Replacement: "/baz",
+				}, {
+					Replacement: "/default",
+				}},
+			},
+			prefix: "/bar",
+			want:   "/baz",
+		},
+		"replacement without prefix for unmatched prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix:      "/foo",
+					Replacement: "/",
+				}, {
+					Replacement: "/default",
+				}},
+			},
+			prefix: "/bar",
+			want:   "/default",
+		},
+		"no replacement for unmatched prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix:      "/foo",
+					Replacement: "/",
+				}},
+			},
+			prefix: "/bar",
+			want:   "",
+		},
+		"duplicate prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix:      "/foo",
+					Replacement: "/",
+				}, {
+					Prefix:      "/foo",
+					Replacement: "/bar",
+				}},
+			},
+			prefix:  "/foo",
+			wantErr: true,
+		},
+		"duplicate replacement without prefix": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Replacement: "/",
+				}, {
+					Replacement: "/bar",
+				}},
+			},
+			prefix:  "/foo",
+			wantErr: true,
+		},
+		"prefix without slash": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix:      "foo",
+					Replacement: "/",
+				}},
+			},
+			prefix:  "/foo",
+			wantErr: true,
+		},
+		"missing replacement": {
+			prp: &projcontour.PathRewritePolicy{
+				ReplacePrefix: []projcontour.ReplacePrefix{{
+					Prefix: "/foo",
+				}},
+			},
+			prefix:  "/foo",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := pathRewrite(tc.prp, tc.prefix)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestParseTimeout(t *testing.T) {
 	tests := map[string]struct {
 		duration string
@@ -49,7 +49,7 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, fmt.Errorf("invalid TLS private key: %v", err)
 		}
 
-	// Generic secrets may have a 'ca.crt' only.
+	// Generic secrets may have a 'ca.crt', a 'crl.pem', or both, only.
 	case v1.SecretTypeOpaque, "":
 		if _, ok := secret.Data[v1.TLSCertKey]; ok {
 			return false, nil
@@ -59,7 +59,7 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, nil
 		}
 
-		if data := secret.Data["ca.crt"]; len(data) == 0 {
+		if len(secret.Data["ca.crt"]) == 0 && len(secret.Data["crl.pem"]) == 0 {
 			return false, nil
 		}
 
@@ -78,6 +78,12 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 		}
 	}
 
+	if data := secret.Data["crl.pem"]; len(data) > 0 {
+		if err := validateCRL(data); err != nil {
+			return false, fmt.Errorf("invalid certificate revocation list: %v", err)
+		}
+	}
+
 	return true, nil
 }
 
@@ -135,6 +141,31 @@ func validateCertificate(data []byte) error {
 	return nil
 }
 
+func validateCRL(data []byte) error {
+	var exists bool
+
+	for containsPEMHeader(data) {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return errors.New("failed to parse PEM block")
+		}
+		if block.Type != "X509 CRL" {
+			return fmt.Errorf("unexpected block type '%s'", block.Type)
+		}
+		if _, err := x509.ParseCRL(block.Bytes); err != nil {
+			return err
+		}
+		exists = true
+	}
+
+	if !exists {
+		return errors.New("failed to locate certificate revocation list")
+	}
+
+	return nil
+}
+
 func validatePrivateKey(data []byte) error {
 	var keys int
 
@@ -148,6 +148,15 @@ AwEHoUQDQgAEVQ4e/iGctyilxh2OiPHaP8WYVfow4tVU2w2lnhKmZwYo3bManRLI
 BCA2FjSAaiMLSD5VViuHxfcZABdh28iN8w==
 -----END EC PRIVATE KEY-----
 `
+
+	// CRL is a certificate revocation list, revoking no certificates,
+	// issued by a test CA.
+	CRL = `-----BEGIN X509 CRL-----
+MIGqMFMCAQEwCgYIKoZIzj0EAwIwFDESMBAGA1UEAwwJY2xpZW50LWNhFw0yNjEw
+MTUxMzQ1MTNaFw0zNjEwMTIxMzQ1MTNaoA4wDDAKBgNVHRQEAwIBATAKBggqhkjO
+PQQDAgNHADBEAiA8xCp6JPPhSytlt6m1mSziv63l8BVHrZjwmLbaFP3EtwIgHlRg
+UDVRmXu6nHypRutP5QRGf+BKVwKu5YLV7mJQYmU=
+-----END X509 CRL-----`
 )
 
 func secretdata(cert, key string) map[string][]byte {
@@ -23,6 +23,7 @@ const (
 	StatusValid    = "valid"
 	StatusInvalid  = "invalid"
 	StatusOrphaned = "orphaned"
+	StatusWarning  = "warning"
 )
 
 // Status contains the status for an IngressRoute (valid / invalid / orphan, etc)
@@ -94,7 +95,25 @@ func (osw *ObjectStatusWriter) SetInvalid(desc string) {
 	osw.WithValue("description", desc).WithValue("status", StatusInvalid)
 }
 
+// SetWarning records that the object is valid, but that part of its
+// configuration could not be applied. Warnings accumulate, but do not
+// replace an invalid status.
+func (osw *ObjectStatusWriter) SetWarning(desc string) {
+	switch osw.values["status"] {
+	case StatusInvalid:
+		return
+	case StatusWarning:
+		desc = osw.values["description"] + "; " + desc
+	}
+	osw.WithValue("description", desc).WithValue("status", StatusWarning)
+}
+
+// SetValid records that the object is valid, unless a warning has
+// already been recorded.
 func (osw *ObjectStatusWriter) SetValid() {
+	if osw.values["status"] == StatusWarning {
+		return
+	}
 	switch osw.obj.(type) {
 	case *projcontour.HTTPProxy:
 		osw.WithValue("description", "valid HTTPProxy").WithValue("status", StatusValid)
@@ -15,6 +15,7 @@ package dag
 
 import (
 	"testing"
+	"time"
 
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
@@ -1733,6 +1734,1019 @@ func TestDAGIngressRouteStatus(t *testing.T) {
 		},
 	}
 
+	// proxy with one valid and one invalid route
+	proxy60 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "partial",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "partial.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}, {
+				Conditions: []projcontour.Condition{{
+					Prefix: "api",
+				}},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy61 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "classification",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "classification.example.com",
+			},
+			Routes: []projcontour.Route{{
+				ClassificationPolicy: &projcontour.ClassificationPolicy{},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy62 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "maintenance",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:        "maintenance.example.com",
+				Maintenance: true,
+				MaintenancePolicy: &projcontour.MaintenancePolicy{
+					Service: &projcontour.Service{
+						Name: "missing",
+						Port: 8080,
+					},
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy63 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "window",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "window.example.com",
+			},
+			Routes: []projcontour.Route{{
+				ActiveWindow: &projcontour.ActiveWindow{
+					Start: &metav1.Time{Time: time.Date(2019, time.December, 2, 0, 0, 0, 0, time.UTC)},
+					End:   &metav1.Time{Time: time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC)},
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy64 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redirect",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "redirect.example.com",
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn: "www.example.com",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy65 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redirect-status",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "redirect-status.example.com",
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn:       "www.example.com",
+					StatusCode: 200,
+				},
+			},
+		},
+	}
+
+	proxy66 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redirect-valid",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "redirect-valid.example.com",
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn: "www.example.com",
+				},
+			},
+		},
+	}
+
+	proxy67 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "trailing-slash",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "trailing-slash.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/docs/",
+				}},
+				TrailingSlashPolicy: &projcontour.TrailingSlashPolicy{
+					Action: "append",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy68 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dns-lookup",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "dns-lookup.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name:      s1.Name,
+					Port:      8080,
+					DNSLookup: "round-robin",
+				}},
+			}},
+		},
+	}
+
+	proxy69 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-selector",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "service-selector.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				ServiceSelector: &projcontour.ServiceSelector{
+					Header: "X-Shard",
+					Values: []projcontour.ServiceSelectorValue{
+						{Value: "a", Service: s1.Name},
+						{Value: "a", Service: s1.Name},
+					},
+				},
+			}},
+		},
+	}
+
+	proxy70 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-header",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "cluster-header.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				ClusterHeaderPolicy: &projcontour.ClusterHeaderPolicy{
+					Header: " ",
+				},
+			}},
+		},
+	}
+
+	tier, limit := "gold", int64(100)
+	proxy71 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "route-metadata",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "route-metadata.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				Metadata: []projcontour.RouteMetadata{{
+					Filter: "envoy.ext_authz",
+					Entries: []projcontour.MetadataEntry{{
+						Key:         "tier",
+						StringValue: &tier,
+						NumberValue: &limit,
+					}},
+				}},
+			}},
+		},
+	}
+
+	proxy72 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "port-name",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "port-name.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name:     s1.Name,
+					PortName: "http",
+				}},
+			}},
+		},
+	}
+
+	proxy73 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "port-name-mismatch",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "port-name-mismatch.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name:     s1.Name,
+					Port:     80,
+					PortName: "http",
+				}},
+			}},
+		},
+	}
+
+	proxy74 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rate-limit-header",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "rate-limit.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				RateLimitPolicy: &projcontour.RateLimitPolicy{
+					Descriptors: []projcontour.RateLimitDescriptor{{
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							RequestHeader: &projcontour.RequestHeaderDescriptor{
+								HeaderName: "X-Tenant",
+							},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+
+	proxy75 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "authorization-insecure",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "authorization.example.com",
+				Authorization: &projcontour.AuthorizationServer{
+					Name: s1.Name,
+					Port: 8080,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy76 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "authorization-missing",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "authorization.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				Authorization: &projcontour.AuthorizationServer{
+					Name: "auth",
+					Port: 9001,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy77 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jwt-unknown-provider",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "jwt.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				JWTProviders: []projcontour.JWTProvider{{
+					Name: "provider-1",
+					RemoteJWKS: projcontour.RemoteJWKS{
+						URI: "http://auth.example.com/jwks.json",
+					},
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				JWTVerificationPolicy: &projcontour.JWTVerificationPolicy{
+					Require: "provider-2",
+				},
+			}},
+		},
+	}
+
+	proxy78 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jwt-invalid-uri",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "jwt.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				JWTProviders: []projcontour.JWTProvider{{
+					Name: "provider-1",
+					RemoteJWKS: projcontour.RemoteJWKS{
+						URI: "ftp://auth.example.com/jwks.json",
+					},
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy79 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cors-invalid-regex",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "cors.example.com",
+				CORSPolicy: &projcontour.CORSPolicy{
+					AllowOriginRegex: []string{"https://(.*\\.example.com"},
+				},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxy80 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "headers-policy-host",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "headers.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+					RequestHeadersPolicy: &projcontour.HeadersPolicy{
+						Set: []projcontour.HeaderValue{{
+							Name:  "Host",
+							Value: "internal.example.com",
+						}},
+					},
+				}},
+			}},
+		},
+	}
+
+	proxy81 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "direct-response-status",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "direct.example.com",
+			},
+			Routes: []projcontour.Route{{
+				DirectResponsePolicy: &projcontour.HTTPDirectResponsePolicy{
+					StatusCode: 99,
+				},
+			}},
+		},
+	}
+
+	proxy82 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "request-redirect-with-services",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "redirect.example.com",
+			},
+			Routes: []projcontour.Route{{
+				RequestRedirectPolicy: &projcontour.HTTPRequestRedirectPolicy{
+					Hostname: "www.example.com",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy83 includes proxy84, whose route replaces the prefix
+	// it is included at ambiguously.
+	proxy83 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "path-rewrite-root",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "rewrite.example.com",
+			},
+			Includes: []projcontour.Include{{
+				Name:      "path-rewrite-child",
+				Namespace: s1.Namespace,
+				Conditions: []projcontour.Condition{{
+					Prefix: "/api",
+				}},
+			}},
+		},
+	}
+
+	proxy84 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "path-rewrite-child",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				PathRewritePolicy: &projcontour.PathRewritePolicy{
+					ReplacePrefix: []projcontour.ReplacePrefix{{
+						Prefix:      "/api",
+						Replacement: "/",
+					}, {
+						Prefix:      "/api",
+						Replacement: "/v1",
+					}},
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy85 has a route whose regex condition does not compile.
+	proxy85 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "invalid-regex",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "regex.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Regex: "/v[0-9",
+				}},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy86 has a route whose header regex condition does not compile.
+	proxy86 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "invalid-header-regex",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "header-regex.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Header: &projcontour.HeaderCondition{
+						Name:  "x-request-id",
+						Regex: "[a-f",
+					},
+				}},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy87 mirrors requests both with a mirror service and a
+	// mirror policy.
+	proxy87 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "two-mirrors",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mirror.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}, {
+					Name:   s1.Name,
+					Port:   8080,
+					Mirror: true,
+				}},
+				MirrorPolicy: &projcontour.MirrorPolicy{
+					Name: s1.Name,
+					Port: 8080,
+				},
+			}},
+		},
+	}
+
+	// proxy88 mirrors more than all requests.
+	proxy88 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mirror-percent",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mirror.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				MirrorPolicy: &projcontour.MirrorPolicy{
+					Name:    s1.Name,
+					Port:    8080,
+					Percent: 101,
+				},
+			}},
+		},
+	}
+
+	// proxy89 mirrors requests to a missing service.
+	proxy89 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mirror-missing",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mirror.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				MirrorPolicy: &projcontour.MirrorPolicy{
+					Name: "shadow",
+					Port: 8080,
+				},
+			}},
+		},
+	}
+
+	// proxy90 uses a deprecated annotation.
+	proxy90 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deprecated-annotation",
+			Namespace: s1.Namespace,
+			Annotations: map[string]string{
+				"contour.heptio.com/ingress.class": "contour",
+			},
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "deprecated.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy91 mirrors requests to a missing service, and uses a
+	// deprecated annotation.
+	proxy91 := proxy89.DeepCopy()
+	proxy91.Annotations = map[string]string{
+		"contour.heptio.com/ingress.class": "contour",
+	}
+
+	// proxy92 retries on an unsupported condition.
+	proxy92 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "retry-on",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "retry.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				RetryPolicy: &projcontour.RetryPolicy{
+					RetryOn: []string{"timeout"},
+				},
+			}},
+		},
+	}
+
+	// proxy93 has a service with a fractional tcp keepalive interval.
+	proxy93 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcp-keepalive",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "keepalive.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+					TCPKeepalive: &projcontour.TCPKeepalive{
+						Interval: "1500ms",
+					},
+				}},
+			}},
+		},
+	}
+
+	// proxy94 health checks expecting a status out of range.
+	proxy94 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "health-check-statuses",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "health.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				HealthCheckPolicy: &projcontour.HTTPHealthCheckPolicy{
+					Path:             "/healthz",
+					ExpectedStatuses: []string{"200-600"},
+				},
+			}},
+		},
+	}
+
+	// proxy95 health checks its tcpproxy with a payload which is not hex encoded.
+	proxy95 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcp-health-check",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "passthrough.example.com",
+				TLS: &projcontour.TLS{
+					Passthrough: true,
+				},
+			},
+			TCPProxy: &projcontour.TCPProxy{
+				HealthCheckPolicy: &projcontour.TCPHealthCheckPolicy{
+					Send: "PING",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			},
+		},
+	}
+
+	// proxy96 validates client certificates against a CA secret which does not exist.
+	proxy96 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "missing-client-ca",
+			Namespace: sec1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mtls.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: "missing",
+					},
+				},
+			},
+		},
+	}
+
+	// proxy97 validates client certificates on a vhost which passes TLS through.
+	proxy97 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "passthrough-client-validation",
+			Namespace: sec1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "passthrough.example.com",
+				TLS: &projcontour.TLS{
+					Passthrough: true,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: "clients",
+					},
+				},
+			},
+		},
+	}
+
+	clientCA := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "client-ca",
+			Namespace: sec1.Namespace,
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+
+	// proxy98 checks client certificates against a CRL secret which does not exist.
+	proxy98 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "missing-client-crl",
+			Namespace: sec1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mtls.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate:             clientCA.Name,
+						CertificateRevocationList: "missing",
+					},
+				},
+			},
+		},
+	}
+
+	jwtProxy := func(name string, jwks projcontour.RemoteJWKS) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s1.Namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "jwt.example.com",
+					TLS: &projcontour.TLS{
+						SecretName: sec1.Name,
+					},
+					JWTProviders: []projcontour.JWTProvider{{
+						Name:       "provider-1",
+						RemoteJWKS: jwks,
+					}},
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name: s1.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	// proxy99 fetches its JWKS over https without validating the host.
+	proxy99 := jwtProxy("jwt-unvalidated-jwks", projcontour.RemoteJWKS{
+		URI: "https://auth.example.com/jwks.json",
+	})
+
+	// proxy100 validates the JWKS host against a CA which does not exist.
+	proxy100 := jwtProxy("jwt-missing-jwks-ca", projcontour.RemoteJWKS{
+		URI: "https://auth.example.com/jwks.json",
+		UpstreamValidation: &projcontour.UpstreamValidation{
+			CACertificate: "missing",
+			SubjectName:   "auth.example.com",
+		},
+	})
+
+	// proxy101 validates the JWKS host against clientCA.
+	proxy101 := jwtProxy("jwt-validated-jwks", projcontour.RemoteJWKS{
+		URI: "https://auth.example.com/jwks.json",
+		UpstreamValidation: &projcontour.UpstreamValidation{
+			CACertificate: clientCA.Name,
+			SubjectName:   "auth.example.com",
+		},
+	})
+
+	// proxy102 requests validation of a JWKS fetched over http.
+	proxy102 := jwtProxy("jwt-http-jwks-validation", projcontour.RemoteJWKS{
+		URI: "http://auth.example.com/jwks.json",
+		UpstreamValidation: &projcontour.UpstreamValidation{
+			CACertificate: clientCA.Name,
+			SubjectName:   "auth.example.com",
+		},
+	})
+
+	failOpen := true
+	proxy103 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rate-limit-fail-open-insecure",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:              "rate-limit.example.com",
+				RateLimitFailOpen: &failOpen,
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxy104 tcpproxies a vhost whose client validation CA
+	// secret does not exist.
+	proxy104 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcpproxy-missing-client-ca",
+			Namespace: sec1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: proxy96.Spec.VirtualHost,
+			TCPProxy: &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			},
+		},
+	}
+
+	// ir32 asks for client validation, which only HTTPProxy implements.
+	ir32 := &ingressroutev1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "client-validation",
+			Namespace: sec1.Namespace,
+		},
+		Spec: ingressroutev1.IngressRouteSpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mtls.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: sec1.Name,
+					},
+				},
+			},
+			Routes: []ingressroutev1.Route{{
+				Match: "/",
+				Services: []ingressroutev1.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// ir33 asks for an authorization server, which only HTTPProxy
+	// implements.
+	ir33 := &ingressroutev1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "authorization",
+			Namespace: sec1.Namespace,
+		},
+		Spec: ingressroutev1.IngressRouteSpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "auth.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				Authorization: &projcontour.AuthorizationServer{
+					Name: s1.Name,
+					Port: 8080,
+				},
+			},
+			Routes: ir32.Spec.Routes,
+		},
+	}
+
 	tests := map[string]struct {
 		objs []interface{}
 		want map[Meta]Status
@@ -1740,366 +2754,888 @@ func TestDAGIngressRouteStatus(t *testing.T) {
 		"valid ingressroute": {
 			objs: []interface{}{ir1, s4},
 			want: map[Meta]Status{
-				{name: ir1.Name, namespace: ir1.Namespace}: {Object: ir1, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+				{name: ir1.Name, namespace: ir1.Namespace}: {Object: ir1, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+			},
+		},
+		"invalid port in service": {
+			objs: []interface{}{ir2},
+			want: map[Meta]Status{
+				{name: ir2.Name, namespace: ir2.Namespace}: {Object: ir2, Status: "invalid", Description: `route "/foo": service "home": port must be in the range 1-65535`, Vhost: "example.com"},
+			},
+		},
+		"root ingressroute outside of roots namespace": {
+			objs: []interface{}{ir3},
+			want: map[Meta]Status{
+				{name: ir3.Name, namespace: ir3.Namespace}: {Object: ir3, Status: "invalid", Description: "root IngressRoute cannot be defined in this namespace"},
+			},
+		},
+		"delegated route's match prefix does not match parent's prefix": {
+			objs: []interface{}{ir1, ir4, s4},
+			want: map[Meta]Status{
+				{name: ir1.Name, namespace: ir1.Namespace}: {Object: ir1, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+				{name: ir4.Name, namespace: ir4.Namespace}: {Object: ir4, Status: "invalid", Description: `the path prefix "/doesnotmatch" does not match the parent's path prefix "/prefix"`},
+			},
+		},
+		"root ingressroute does not specify FQDN": {
+			objs: []interface{}{ir13},
+			want: map[Meta]Status{
+				{name: ir13.Name, namespace: ir13.Namespace}: {Object: ir13, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+			},
+		},
+		"self-edge produces a cycle": {
+			objs: []interface{}{ir6},
+			want: map[Meta]Status{
+				{name: ir6.Name, namespace: ir6.Namespace}: {
+					Object:      ir6,
+					Status:      "invalid",
+					Description: "root ingressroute cannot delegate to another root ingressroute",
+					Vhost:       "example.com",
+				},
 			},
 		},
-		"invalid port in service": {
-			objs: []interface{}{ir2},
+		"child delegates to parent, producing a cycle": {
+			objs: []interface{}{ir7, ir8},
+			want: map[Meta]Status{
+				{name: ir7.Name, namespace: ir7.Namespace}: {
+					Object:      ir7,
+					Status:      "valid",
+					Description: "valid IngressRoute",
+					Vhost:       "example.com",
+				},
+				{name: ir8.Name, namespace: ir8.Namespace}: {
+					Object:      ir8,
+					Status:      "invalid",
+					Description: "route creates a delegation cycle: roots/parent -> roots/child -> roots/child",
+				},
+			},
+		},
+		"route has a list of services and also delegates": {
+			objs: []interface{}{ir9},
+			want: map[Meta]Status{
+				{name: ir9.Name, namespace: ir9.Namespace}: {Object: ir9, Status: "invalid", Description: `route "/foo": cannot specify services and delegate in the same route`, Vhost: "example.com"},
+			},
+		},
+		"ingressroute is an orphaned route": {
+			objs: []interface{}{ir8},
+			want: map[Meta]Status{
+				{name: ir8.Name, namespace: ir8.Namespace}: {Object: ir8, Status: "orphaned", Description: "this IngressRoute is not part of a delegation chain from a root IngressRoute"},
+			},
+		},
+		"ingressroute delegates to multiple ingressroutes, one is invalid": {
+			objs: []interface{}{ir10, ir11, ir12, s6, s7},
+			want: map[Meta]Status{
+				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "valid", Description: "valid IngressRoute"},
+				{name: ir12.Name, namespace: ir12.Namespace}: {Object: ir12, Status: "invalid", Description: `route "/bar": service "foo3": port must be in the range 1-65535`},
+				{name: ir10.Name, namespace: ir10.Namespace}: {Object: ir10, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+			},
+		},
+		"invalid parent orphans children": {
+			objs: []interface{}{ir14, ir11},
+			want: map[Meta]Status{
+				{name: ir14.Name, namespace: ir14.Namespace}: {Object: ir14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "orphaned", Description: "this IngressRoute is not part of a delegation chain from a root IngressRoute"},
+			},
+		},
+		"multi-parent children is not orphaned when one of the parents is invalid": {
+			objs: []interface{}{ir14, ir11, ir10, s5, s6},
+			want: map[Meta]Status{
+				{name: ir14.Name, namespace: ir14.Namespace}: {Object: ir14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "valid", Description: "valid IngressRoute"},
+				{name: ir10.Name, namespace: ir10.Namespace}: {Object: ir10, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+			},
+		},
+		"invalid FQDN contains wildcard": {
+			objs: []interface{}{ir15},
+			want: map[Meta]Status{
+				{name: ir15.Name, namespace: ir15.Namespace}: {Object: ir15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" cannot use wildcards`, Vhost: "example.*.com"},
+			},
+		},
+		"missing service shows invalid status": {
+			objs: []interface{}{ir16},
+			want: map[Meta]Status{
+				{name: ir16.Name, namespace: ir16.Namespace}: {
+					Object:      ir16,
+					Status:      "invalid",
+					Description: `Service [invalid:8080] is invalid or missing`,
+					Vhost:       ir16.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		"insert ingressroute": {
+			objs: []interface{}{s1, ir17},
+			want: map[Meta]Status{
+				{name: ir17.Name, namespace: ir17.Namespace}: {
+					Object:      ir17,
+					Status:      StatusValid,
+					Description: "valid IngressRoute",
+					Vhost:       "example.com",
+				},
+			},
+		},
+		"insert conflicting ingressroutes due to fqdn reuse": {
+			objs: []interface{}{ir17, ir18},
+			want: map[Meta]Status{
+				{name: ir17.Name, namespace: ir17.Namespace}: {
+					Object:      ir17,
+					Status:      StatusInvalid,
+					Description: `fqdn "example.com" is used in multiple IngressRoutes: roots/example-com, roots/other-example`,
+					Vhost:       "example.com",
+				},
+				{name: ir18.Name, namespace: ir18.Namespace}: {
+					Object:      ir18,
+					Status:      StatusInvalid,
+					Description: `fqdn "example.com" is used in multiple IngressRoutes: roots/example-com, roots/other-example`,
+					Vhost:       "example.com",
+				},
+			},
+		},
+		"root ingress delegating to another root": {
+			objs: []interface{}{ir20, ir21},
+			want: map[Meta]Status{
+				{name: ir20.Name, namespace: ir20.Namespace}: {
+					Object:      ir20,
+					Status:      StatusInvalid,
+					Description: `fqdn "blog.containersteve.com" is used in multiple IngressRoutes: marketing/blog, roots/root-blog`,
+					Vhost:       "blog.containersteve.com",
+				},
+				{name: ir21.Name, namespace: ir21.Namespace}: {
+					Object:      ir21,
+					Status:      StatusInvalid,
+					Description: `fqdn "blog.containersteve.com" is used in multiple IngressRoutes: marketing/blog, roots/root-blog`,
+					Vhost:       "blog.containersteve.com",
+				},
+			},
+		},
+		"root ingress delegating to another root w/ different hostname": {
+			objs: []interface{}{ir22, ir23, s8},
+			want: map[Meta]Status{
+				{name: ir22.Name, namespace: ir22.Namespace}: {
+					Object:      ir22,
+					Status:      StatusInvalid,
+					Description: "root ingressroute cannot delegate to another root ingressroute",
+					Vhost:       "blog.containersteve.com",
+				},
+				{name: ir23.Name, namespace: ir23.Namespace}: {
+					Object:      ir23,
+					Status:      StatusValid,
+					Description: `valid IngressRoute`,
+					Vhost:       "www.containersteve.com",
+				},
+			},
+		},
+		// issue 1399
+		"service shared across ingress and ingressroute tcpproxy": {
+			objs: []interface{}{
+				sec1, s9, i1, ir24,
+			},
+			want: map[Meta]Status{
+				{name: ir24.Name, namespace: ir24.Namespace}: {
+					Object:      ir24,
+					Status:      StatusValid,
+					Description: `valid IngressRoute`,
+					Vhost:       "example.com",
+				},
+			},
+		},
+		// issue 1347
+		"check status set when tcpproxy combined with tls delegation failure": {
+			objs: []interface{}{
+				sec2,
+				ir25,
+			},
+			want: map[Meta]Status{
+				{name: ir25.Name, namespace: ir25.Namespace}: {
+					Object:      ir25,
+					Status:      StatusInvalid,
+					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
+					Vhost:       ir25.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		// issue 1348
+		"check status set when routes combined with tls delegation failure": {
+			objs: []interface{}{
+				sec2,
+				ir26,
+			},
+			want: map[Meta]Status{
+				{name: ir26.Name, namespace: ir26.Namespace}: {
+					Object:      ir26,
+					Status:      StatusInvalid,
+					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
+					Vhost:       ir26.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		// issue 1348
+		"check status set when httpproxy routes combined with tls delegation failure": {
+			objs: []interface{}{
+				sec2,
+				proxy19,
+			},
+			want: map[Meta]Status{
+				{name: proxy19.Name, namespace: proxy19.Namespace}: {
+					Object:      proxy19,
+					Status:      StatusInvalid,
+					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
+					Vhost:       proxy19.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		// issue 910
+		"non tls routes can be combined with tcp proxy": {
+			objs: []interface{}{
+				s10,
+				ir27,
+			},
+			want: map[Meta]Status{
+				{name: ir27.Name, namespace: ir27.Namespace}: {
+					Object:      ir27,
+					Status:      StatusValid,
+					Description: `valid IngressRoute`,
+					Vhost:       ir27.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		// issue 1452
+		"ingressroute with missing secret delegation should be invalid": {
+			objs: []interface{}{
+				s10,
+				ir28,
+			},
+			want: map[Meta]Status{
+				{name: ir28.Name, namespace: ir28.Namespace}: {
+					Object:      ir28,
+					Status:      StatusInvalid,
+					Description: "TLS Secret [heptio-contour/ssl-cert] not found or is malformed",
+					Vhost:       ir28.Spec.VirtualHost.Fqdn,
+				},
+			},
+		},
+		"two root ingressroutes delegated to the same object should not conflict on hostname": {
+			objs: []interface{}{
+				s1, ir29, ir30, ir31,
+			},
+			want: map[Meta]Status{
+				{name: ir29.Name, namespace: ir29.Namespace}: {
+					Object:      ir29,
+					Status:      "valid",
+					Description: "valid IngressRoute",
+					Vhost:       "site1.com",
+				},
+				{name: ir30.Name, namespace: ir30.Namespace}: {
+					Object:      ir30,
+					Status:      "valid",
+					Description: "valid IngressRoute",
+					Vhost:       "site2.com",
+				},
+				{name: ir31.Name, namespace: ir31.Namespace}: {
+					Object:      ir31,
+					Status:      "valid",
+					Description: "valid IngressRoute",
+				},
+			},
+		},
+		"valid proxy": {
+			objs: []interface{}{proxy1, s4},
 			want: map[Meta]Status{
-				{name: ir2.Name, namespace: ir2.Namespace}: {Object: ir2, Status: "invalid", Description: `route "/foo": service "home": port must be in the range 1-65535`, Vhost: "example.com"},
+				{name: proxy1.Name, namespace: proxy1.Namespace}: {Object: proxy1, Status: "valid", Description: "valid HTTPProxy", Vhost: "example.com"},
 			},
 		},
-		"root ingressroute outside of roots namespace": {
-			objs: []interface{}{ir3},
+		"proxy invalid port in service": {
+			objs: []interface{}{proxy2},
 			want: map[Meta]Status{
-				{name: ir3.Name, namespace: ir3.Namespace}: {Object: ir3, Status: "invalid", Description: "root IngressRoute cannot be defined in this namespace"},
+				{name: proxy2.Name, namespace: proxy2.Namespace}: {Object: proxy2, Status: "invalid", Description: `service "home": port must be in the range 1-65535`, Vhost: "example.com"},
 			},
 		},
-		"delegated route's match prefix does not match parent's prefix": {
-			objs: []interface{}{ir1, ir4, s4},
+		"root proxy outside of roots namespace": {
+			objs: []interface{}{proxy3},
 			want: map[Meta]Status{
-				{name: ir1.Name, namespace: ir1.Namespace}: {Object: ir1, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
-				{name: ir4.Name, namespace: ir4.Namespace}: {Object: ir4, Status: "invalid", Description: `the path prefix "/doesnotmatch" does not match the parent's path prefix "/prefix"`},
+				{name: proxy3.Name, namespace: proxy3.Namespace}: {Object: proxy3, Status: "invalid", Description: "root HTTPProxy cannot be defined in this namespace"},
 			},
 		},
-		"root ingressroute does not specify FQDN": {
-			objs: []interface{}{ir13},
+		"root proxy does not specify FQDN": {
+			objs: []interface{}{proxy13},
 			want: map[Meta]Status{
-				{name: ir13.Name, namespace: ir13.Namespace}: {Object: ir13, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+				{name: proxy13.Name, namespace: proxy13.Namespace}: {Object: proxy13, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
 			},
 		},
-		"self-edge produces a cycle": {
-			objs: []interface{}{ir6},
+		"proxy self-edge produces a cycle": {
+			objs: []interface{}{proxy6, s1},
 			want: map[Meta]Status{
-				{name: ir6.Name, namespace: ir6.Namespace}: {
-					Object:      ir6,
-					Status:      "invalid",
-					Description: "root ingressroute cannot delegate to another root ingressroute",
+				{name: proxy6.Name, namespace: proxy6.Namespace}: {
+					Object:      proxy6,
+					Status:      "warning",
+					Description: "Service [green:80] is invalid or missing; root httpproxy cannot delegate to another root httpproxy",
 					Vhost:       "example.com",
 				},
 			},
 		},
-		"child delegates to parent, producing a cycle": {
-			objs: []interface{}{ir7, ir8},
+		"proxy child delegates to parent, producing a cycle": {
+			objs: []interface{}{proxy7, proxy8},
 			want: map[Meta]Status{
-				{name: ir7.Name, namespace: ir7.Namespace}: {
-					Object:      ir7,
+				{name: proxy7.Name, namespace: proxy7.Namespace}: {
+					Object:      proxy7,
 					Status:      "valid",
-					Description: "valid IngressRoute",
+					Description: "valid HTTPProxy",
 					Vhost:       "example.com",
 				},
-				{name: ir8.Name, namespace: ir8.Namespace}: {
-					Object:      ir8,
+				{name: proxy8.Name, namespace: proxy8.Namespace}: {
+					Object:      proxy8,
 					Status:      "invalid",
-					Description: "route creates a delegation cycle: roots/parent -> roots/child -> roots/child",
+					Description: "include creates a delegation cycle: roots/parent -> roots/child -> roots/child",
 				},
 			},
 		},
-		"route has a list of services and also delegates": {
-			objs: []interface{}{ir9},
+		"proxy orphaned route": {
+			objs: []interface{}{proxy8},
 			want: map[Meta]Status{
-				{name: ir9.Name, namespace: ir9.Namespace}: {Object: ir9, Status: "invalid", Description: `route "/foo": cannot specify services and delegate in the same route`, Vhost: "example.com"},
+				{name: proxy8.Name, namespace: proxy8.Namespace}: {Object: proxy8, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
 			},
 		},
-		"ingressroute is an orphaned route": {
-			objs: []interface{}{ir8},
+		"proxy invalid parent orphans children": {
+			objs: []interface{}{proxy14, proxy11},
 			want: map[Meta]Status{
-				{name: ir8.Name, namespace: ir8.Namespace}: {Object: ir8, Status: "orphaned", Description: "this IngressRoute is not part of a delegation chain from a root IngressRoute"},
+				{name: proxy14.Name, namespace: proxy14.Namespace}: {Object: proxy14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+				{name: proxy11.Name, namespace: proxy11.Namespace}: {Object: proxy11, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
 			},
 		},
-		"ingressroute delegates to multiple ingressroutes, one is invalid": {
-			objs: []interface{}{ir10, ir11, ir12, s6, s7},
+		"proxy invalid FQDN contains wildcard": {
+			objs: []interface{}{proxy15},
 			want: map[Meta]Status{
-				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "valid", Description: "valid IngressRoute"},
-				{name: ir12.Name, namespace: ir12.Namespace}: {Object: ir12, Status: "invalid", Description: `route "/bar": service "foo3": port must be in the range 1-65535`},
-				{name: ir10.Name, namespace: ir10.Namespace}: {Object: ir10, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+				{name: proxy15.Name, namespace: proxy15.Namespace}: {Object: proxy15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" can only use a wildcard as its first label`, Vhost: "example.*.com"},
 			},
 		},
-		"invalid parent orphans children": {
-			objs: []interface{}{ir14, ir11},
+		"proxy missing service shows warning status": {
+			objs: []interface{}{proxy16},
 			want: map[Meta]Status{
-				{name: ir14.Name, namespace: ir14.Namespace}: {Object: ir14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
-				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "orphaned", Description: "this IngressRoute is not part of a delegation chain from a root IngressRoute"},
+				{name: proxy16.Name, namespace: proxy16.Namespace}: {
+					Object:      proxy16,
+					Status:      "warning",
+					Description: `Service [invalid:8080] is invalid or missing`,
+					Vhost:       proxy16.Spec.VirtualHost.Fqdn,
+				},
 			},
 		},
-		"multi-parent children is not orphaned when one of the parents is invalid": {
-			objs: []interface{}{ir14, ir11, ir10, s5, s6},
+		"proxy with an invalid route serves its valid routes": {
+			objs: []interface{}{proxy60, s1},
 			want: map[Meta]Status{
-				{name: ir14.Name, namespace: ir14.Namespace}: {Object: ir14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
-				{name: ir11.Name, namespace: ir11.Namespace}: {Object: ir11, Status: "valid", Description: "valid IngressRoute"},
-				{name: ir10.Name, namespace: ir10.Namespace}: {Object: ir10, Status: "valid", Description: "valid IngressRoute", Vhost: "example.com"},
+				{name: proxy60.Name, namespace: proxy60.Namespace}: {
+					Object:      proxy60,
+					Status:      "warning",
+					Description: "route: Prefix conditions must start with /, api was supplied",
+					Vhost:       "partial.example.com",
+				},
 			},
 		},
-		"invalid FQDN contains wildcard": {
-			objs: []interface{}{ir15},
+		"proxy with classification policy missing header": {
+			objs: []interface{}{proxy61, s1},
 			want: map[Meta]Status{
-				{name: ir15.Name, namespace: ir15.Namespace}: {Object: ir15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" cannot use wildcards`, Vhost: "example.*.com"},
+				{name: proxy61.Name, namespace: proxy61.Namespace}: {
+					Object:      proxy61,
+					Status:      StatusInvalid,
+					Description: "classificationPolicy: header must be specified",
+					Vhost:       "classification.example.com",
+				},
 			},
 		},
-		"missing service shows invalid status": {
-			objs: []interface{}{ir16},
+		"proxy in maintenance with missing maintenance service": {
+			objs: []interface{}{proxy62, s1},
 			want: map[Meta]Status{
-				{name: ir16.Name, namespace: ir16.Namespace}: {
-					Object:      ir16,
-					Status:      "invalid",
-					Description: `Service [invalid:8080] is invalid or missing`,
-					Vhost:       ir16.Spec.VirtualHost.Fqdn,
+				{name: proxy62.Name, namespace: proxy62.Namespace}: {
+					Object:      proxy62,
+					Status:      StatusInvalid,
+					Description: "maintenancePolicy: Service [missing:8080] is invalid or missing",
+					Vhost:       "maintenance.example.com",
 				},
 			},
 		},
-		"insert ingressroute": {
-			objs: []interface{}{s1, ir17},
+		"proxy with active window ending before it starts": {
+			objs: []interface{}{proxy63, s1},
 			want: map[Meta]Status{
-				{name: ir17.Name, namespace: ir17.Namespace}: {
-					Object:      ir17,
-					Status:      StatusValid,
-					Description: "valid IngressRoute",
-					Vhost:       "example.com",
+				{name: proxy63.Name, namespace: proxy63.Namespace}: {
+					Object:      proxy63,
+					Status:      StatusInvalid,
+					Description: "activeWindow: end must be after start",
+					Vhost:       "window.example.com",
 				},
 			},
 		},
-		"insert conflicting ingressroutes due to fqdn reuse": {
-			objs: []interface{}{ir17, ir18},
+		"redirect proxy with routes": {
+			objs: []interface{}{proxy64, s1},
 			want: map[Meta]Status{
-				{name: ir17.Name, namespace: ir17.Namespace}: {
-					Object:      ir17,
+				{name: proxy64.Name, namespace: proxy64.Namespace}: {
+					Object:      proxy64,
 					Status:      StatusInvalid,
-					Description: `fqdn "example.com" is used in multiple IngressRoutes: roots/example-com, roots/other-example`,
-					Vhost:       "example.com",
+					Description: "redirect: cannot be combined with routes, includes, or tcpproxy",
+					Vhost:       "redirect.example.com",
 				},
-				{name: ir18.Name, namespace: ir18.Namespace}: {
-					Object:      ir18,
+			},
+		},
+		"redirect proxy with invalid status code": {
+			objs: []interface{}{proxy65},
+			want: map[Meta]Status{
+				{name: proxy65.Name, namespace: proxy65.Namespace}: {
+					Object:      proxy65,
 					Status:      StatusInvalid,
-					Description: `fqdn "example.com" is used in multiple IngressRoutes: roots/example-com, roots/other-example`,
-					Vhost:       "example.com",
+					Description: "redirect: statusCode 200 must be one of 301, 302, 303, 307, or 308",
+					Vhost:       "redirect-status.example.com",
 				},
 			},
 		},
-		"root ingress delegating to another root": {
-			objs: []interface{}{ir20, ir21},
+		"redirect proxy without services": {
+			objs: []interface{}{proxy66},
 			want: map[Meta]Status{
-				{name: ir20.Name, namespace: ir20.Namespace}: {
-					Object:      ir20,
+				{name: proxy66.Name, namespace: proxy66.Namespace}: {
+					Object:      proxy66,
+					Status:      StatusValid,
+					Description: "valid HTTPProxy",
+					Vhost:       "redirect-valid.example.com",
+				},
+			},
+		},
+		"route with unknown trailing slash action": {
+			objs: []interface{}{proxy67, s1},
+			want: map[Meta]Status{
+				{name: proxy67.Name, namespace: proxy67.Namespace}: {
+					Object:      proxy67,
 					Status:      StatusInvalid,
-					Description: `fqdn "blog.containersteve.com" is used in multiple IngressRoutes: marketing/blog, roots/root-blog`,
-					Vhost:       "blog.containersteve.com",
+					Description: `trailingSlashPolicy: action "append" must be one of redirect or rewrite`,
+					Vhost:       "trailing-slash.example.com",
 				},
-				{name: ir21.Name, namespace: ir21.Namespace}: {
-					Object:      ir21,
+			},
+		},
+		"service with unknown dns lookup": {
+			objs: []interface{}{proxy68, s1},
+			want: map[Meta]Status{
+				{name: proxy68.Name, namespace: proxy68.Namespace}: {
+					Object:      proxy68,
 					Status:      StatusInvalid,
-					Description: `fqdn "blog.containersteve.com" is used in multiple IngressRoutes: marketing/blog, roots/root-blog`,
-					Vhost:       "blog.containersteve.com",
+					Description: `service "kuard": dnsLookup "round-robin" must be one of strict or logical`,
+					Vhost:       "dns-lookup.example.com",
 				},
 			},
 		},
-		"root ingress delegating to another root w/ different hostname": {
-			objs: []interface{}{ir22, ir23, s8},
+		"cluster header policy without header": {
+			objs: []interface{}{proxy70, s1},
 			want: map[Meta]Status{
-				{name: ir22.Name, namespace: ir22.Namespace}: {
-					Object:      ir22,
+				{name: proxy70.Name, namespace: proxy70.Namespace}: {
+					Object:      proxy70,
 					Status:      StatusInvalid,
-					Description: "root ingressroute cannot delegate to another root ingressroute",
-					Vhost:       "blog.containersteve.com",
+					Description: "clusterHeaderPolicy: header must be specified",
+					Vhost:       "cluster-header.example.com",
 				},
-				{name: ir23.Name, namespace: ir23.Namespace}: {
-					Object:      ir23,
+			},
+		},
+		"route metadata entry with two values": {
+			objs: []interface{}{proxy71, s1},
+			want: map[Meta]Status{
+				{name: proxy71.Name, namespace: proxy71.Namespace}: {
+					Object:      proxy71,
+					Status:      StatusInvalid,
+					Description: `metadata: filter "envoy.ext_authz": key "tier" must have exactly one of stringValue, numberValue or boolValue`,
+					Vhost:       "route-metadata.example.com",
+				},
+			},
+		},
+		"service port referenced by name": {
+			objs: []interface{}{proxy72, s1},
+			want: map[Meta]Status{
+				{name: proxy72.Name, namespace: proxy72.Namespace}: {
+					Object:      proxy72,
 					Status:      StatusValid,
-					Description: `valid IngressRoute`,
-					Vhost:       "www.containersteve.com",
+					Description: "valid HTTPProxy",
+					Vhost:       "port-name.example.com",
 				},
 			},
 		},
-		// issue 1399
-		"service shared across ingress and ingressroute tcpproxy": {
-			objs: []interface{}{
-				sec1, s9, i1, ir24,
+		"service port name and number mismatch": {
+			objs: []interface{}{proxy73, s1},
+			want: map[Meta]Status{
+				{name: proxy73.Name, namespace: proxy73.Namespace}: {
+					Object:      proxy73,
+					Status:      StatusInvalid,
+					Description: `service "kuard": port 80 does not match port "http", which is port 8080`,
+					Vhost:       "port-name-mismatch.example.com",
+				},
 			},
+		},
+		"rate limit request header without descriptor key": {
+			objs: []interface{}{proxy74, s1},
 			want: map[Meta]Status{
-				{name: ir24.Name, namespace: ir24.Namespace}: {
-					Object:      ir24,
+				{name: proxy74.Name, namespace: proxy74.Namespace}: {
+					Object:      proxy74,
+					Status:      StatusInvalid,
+					Description: "rateLimitPolicy: descriptor 0: entry 0: requestHeader must specify headerName and descriptorKey",
+					Vhost:       "rate-limit.example.com",
+				},
+			},
+		},
+		"rate limit fail open without tls": {
+			objs: []interface{}{proxy103, s1},
+			want: map[Meta]Status{
+				{name: proxy103.Name, namespace: proxy103.Namespace}: {
+					Object:      proxy103,
+					Status:      StatusInvalid,
+					Description: "rateLimitFailOpen: requires TLS with a secretName",
+					Vhost:       "rate-limit.example.com",
+				},
+			},
+		},
+		"authorization without tls": {
+			objs: []interface{}{proxy75, s1},
+			want: map[Meta]Status{
+				{name: proxy75.Name, namespace: proxy75.Namespace}: {
+					Object:      proxy75,
+					Status:      StatusInvalid,
+					Description: "authorization: requires TLS with a secretName",
+					Vhost:       "authorization.example.com",
+				},
+			},
+		},
+		"authorization service missing": {
+			objs: []interface{}{proxy76, s1, sec1},
+			want: map[Meta]Status{
+				{name: proxy76.Name, namespace: proxy76.Namespace}: {
+					Object:      proxy76,
+					Status:      StatusInvalid,
+					Description: "authorization: Service [auth:9001] is invalid or missing",
+					Vhost:       "authorization.example.com",
+				},
+			},
+		},
+		"jwt verification requires an undefined provider": {
+			objs: []interface{}{proxy77, s1, sec1},
+			want: map[Meta]Status{
+				{name: proxy77.Name, namespace: proxy77.Namespace}: {
+					Object:      proxy77,
+					Status:      StatusInvalid,
+					Description: `jwtVerificationPolicy: provider "provider-2" is not defined on the virtual host`,
+					Vhost:       "jwt.example.com",
+				},
+			},
+		},
+		"jwt provider with invalid jwks uri": {
+			objs: []interface{}{proxy78, s1, sec1},
+			want: map[Meta]Status{
+				{name: proxy78.Name, namespace: proxy78.Namespace}: {
+					Object:      proxy78,
+					Status:      StatusInvalid,
+					Description: `jwtProviders: provider "provider-1": remoteJWKS: uri "ftp://auth.example.com/jwks.json" must use http or https`,
+					Vhost:       "jwt.example.com",
+				},
+			},
+		},
+		"cors policy with invalid origin regex": {
+			objs: []interface{}{proxy79, s1},
+			want: map[Meta]Status{
+				{name: proxy79.Name, namespace: proxy79.Namespace}: {
+					Object:      proxy79,
+					Status:      StatusInvalid,
+					Description: `corsPolicy: allowOriginRegex "https://(.*\\.example.com" is not a valid regular expression`,
+					Vhost:       "cors.example.com",
+				},
+			},
+		},
+		"service headers policy setting host": {
+			objs: []interface{}{proxy80, s1},
+			want: map[Meta]Status{
+				{name: proxy80.Name, namespace: proxy80.Namespace}: {
+					Object:      proxy80,
+					Status:      StatusInvalid,
+					Description: `service "kuard": requestHeadersPolicy: set: header "Host" cannot be modified`,
+					Vhost:       "headers.example.com",
+				},
+			},
+		},
+		"direct response with invalid status code": {
+			objs: []interface{}{proxy81},
+			want: map[Meta]Status{
+				{name: proxy81.Name, namespace: proxy81.Namespace}: {
+					Object:      proxy81,
+					Status:      StatusInvalid,
+					Description: "directResponsePolicy: statusCode 99 must be in the range 200-599",
+					Vhost:       "direct.example.com",
+				},
+			},
+		},
+		"request redirect with services": {
+			objs: []interface{}{proxy82, s1},
+			want: map[Meta]Status{
+				{name: proxy82.Name, namespace: proxy82.Namespace}: {
+					Object:      proxy82,
+					Status:      StatusInvalid,
+					Description: "requestRedirectPolicy: cannot be combined with services, serviceSelector, clusterHeaderPolicy, blueGreenPolicy, sessionPinningPolicy or experimentPolicy",
+					Vhost:       "redirect.example.com",
+				},
+			},
+		},
+		"path rewrite conflict in included proxy": {
+			objs: []interface{}{proxy83, proxy84, s1},
+			want: map[Meta]Status{
+				{name: proxy83.Name, namespace: proxy83.Namespace}: {
+					Object:      proxy83,
 					Status:      StatusValid,
-					Description: `valid IngressRoute`,
-					Vhost:       "example.com",
+					Description: "valid HTTPProxy",
+					Vhost:       "rewrite.example.com",
+				},
+				{name: proxy84.Name, namespace: proxy84.Namespace}: {
+					Object:      proxy84,
+					Status:      StatusInvalid,
+					Description: `pathRewritePolicy: ambiguous replacement, prefix "/api" is replaced more than once`,
 				},
 			},
 		},
-		// issue 1347
-		"check status set when tcpproxy combined with tls delegation failure": {
-			objs: []interface{}{
-				sec2,
-				ir25,
+		"invalid regex condition": {
+			objs: []interface{}{proxy85, s1},
+			want: map[Meta]Status{
+				{name: proxy85.Name, namespace: proxy85.Namespace}: {
+					Object:      proxy85,
+					Status:      StatusInvalid,
+					Description: "route: Regex \"/v[0-9\" is invalid: error parsing regexp: missing closing ]: `[0-9`",
+					Vhost:       "regex.example.com",
+				},
 			},
+		},
+		"invalid header regex condition": {
+			objs: []interface{}{proxy86, s1},
 			want: map[Meta]Status{
-				{name: ir25.Name, namespace: ir25.Namespace}: {
-					Object:      ir25,
+				{name: proxy86.Name, namespace: proxy86.Namespace}: {
+					Object:      proxy86,
 					Status:      StatusInvalid,
-					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
-					Vhost:       ir25.Spec.VirtualHost.Fqdn,
+					Description: "route: header \"x-request-id\": regex \"[a-f\" is invalid: error parsing regexp: missing closing ]: `[a-f`",
+					Vhost:       "header-regex.example.com",
+				},
+			},
+		},
+		"mirror policy with mirror service": {
+			objs: []interface{}{proxy87, s1},
+			want: map[Meta]Status{
+				{name: proxy87.Name, namespace: proxy87.Namespace}: {
+					Object:      proxy87,
+					Status:      StatusInvalid,
+					Description: "mirrorPolicy: cannot be combined with a mirror service",
+					Vhost:       "mirror.example.com",
+				},
+			},
+		},
+		"mirror policy percent out of range": {
+			objs: []interface{}{proxy88, s1},
+			want: map[Meta]Status{
+				{name: proxy88.Name, namespace: proxy88.Namespace}: {
+					Object:      proxy88,
+					Status:      StatusInvalid,
+					Description: "mirrorPolicy: percent must be in the range 0-100",
+					Vhost:       "mirror.example.com",
+				},
+			},
+		},
+		"mirror policy service missing": {
+			objs: []interface{}{proxy89, s1},
+			want: map[Meta]Status{
+				{name: proxy89.Name, namespace: proxy89.Namespace}: {
+					Object:      proxy89,
+					Status:      StatusWarning,
+					Description: "mirrorPolicy: Service [shadow:8080] is invalid or missing",
+					Vhost:       "mirror.example.com",
+				},
+			},
+		},
+		"deprecated annotation": {
+			objs: []interface{}{proxy90, s1},
+			want: map[Meta]Status{
+				{name: proxy90.Name, namespace: proxy90.Namespace}: {
+					Object:      proxy90,
+					Status:      StatusWarning,
+					Description: `annotation "contour.heptio.com/ingress.class" is deprecated, use "projectcontour.io/ingress.class"`,
+					Vhost:       "deprecated.example.com",
+				},
+			},
+		},
+		"deprecated annotation with warning": {
+			objs: []interface{}{proxy91, s1},
+			want: map[Meta]Status{
+				{name: proxy91.Name, namespace: proxy91.Namespace}: {
+					Object:      proxy91,
+					Status:      StatusWarning,
+					Description: `mirrorPolicy: Service [shadow:8080] is invalid or missing; annotation "contour.heptio.com/ingress.class" is deprecated, use "projectcontour.io/ingress.class"`,
+					Vhost:       "mirror.example.com",
 				},
 			},
 		},
-		// issue 1348
-		"check status set when routes combined with tls delegation failure": {
-			objs: []interface{}{
-				sec2,
-				ir26,
-			},
+		"unsupported retry on condition": {
+			objs: []interface{}{proxy92, s1},
 			want: map[Meta]Status{
-				{name: ir26.Name, namespace: ir26.Namespace}: {
-					Object:      ir26,
+				{name: proxy92.Name, namespace: proxy92.Namespace}: {
+					Object:      proxy92,
 					Status:      StatusInvalid,
-					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
-					Vhost:       ir26.Spec.VirtualHost.Fqdn,
+					Description: `retryPolicy: retryOn condition "timeout" is not supported`,
+					Vhost:       "retry.example.com",
 				},
 			},
 		},
-		// issue 1348
-		"check status set when httpproxy routes combined with tls delegation failure": {
-			objs: []interface{}{
-				sec2,
-				proxy19,
-			},
+		"fractional tcp keepalive interval": {
+			objs: []interface{}{proxy93, s1},
 			want: map[Meta]Status{
-				{name: proxy19.Name, namespace: proxy19.Namespace}: {
-					Object:      proxy19,
+				{name: proxy93.Name, namespace: proxy93.Namespace}: {
+					Object:      proxy93,
 					Status:      StatusInvalid,
-					Description: sec2.Namespace + "/" + sec2.Name + ": certificate delegation not permitted",
-					Vhost:       proxy19.Spec.VirtualHost.Fqdn,
+					Description: `service "kuard": tcpKeepalive: interval "1500ms" must be a duration of whole seconds`,
+					Vhost:       "keepalive.example.com",
 				},
 			},
 		},
-		// issue 910
-		"non tls routes can be combined with tcp proxy": {
-			objs: []interface{}{
-				s10,
-				ir27,
-			},
+		"health check expected status out of range": {
+			objs: []interface{}{proxy94, s1},
 			want: map[Meta]Status{
-				{name: ir27.Name, namespace: ir27.Namespace}: {
-					Object:      ir27,
-					Status:      StatusValid,
-					Description: `valid IngressRoute`,
-					Vhost:       ir27.Spec.VirtualHost.Fqdn,
+				{name: proxy94.Name, namespace: proxy94.Namespace}: {
+					Object:      proxy94,
+					Status:      StatusInvalid,
+					Description: `healthCheckPolicy: expectedStatuses: "200-600" must be in the range 100-599`,
+					Vhost:       "health.example.com",
 				},
 			},
 		},
-		// issue 1452
-		"ingressroute with missing secret delegation should be invalid": {
-			objs: []interface{}{
-				s10,
-				ir28,
-			},
+		"tcpproxy health check payload not hex encoded": {
+			objs: []interface{}{proxy95, s1},
 			want: map[Meta]Status{
-				{name: ir28.Name, namespace: ir28.Namespace}: {
-					Object:      ir28,
+				{name: proxy95.Name, namespace: proxy95.Namespace}: {
+					Object:      proxy95,
 					Status:      StatusInvalid,
-					Description: "TLS Secret [heptio-contour/ssl-cert] not found or is malformed",
-					Vhost:       ir28.Spec.VirtualHost.Fqdn,
+					Description: `tcpproxy: healthCheckPolicy: send "PING" is not a hex encoded payload`,
+					Vhost:       "passthrough.example.com",
 				},
 			},
 		},
-		"two root ingressroutes delegated to the same object should not conflict on hostname": {
-			objs: []interface{}{
-				s1, ir29, ir30, ir31,
-			},
+		"client validation ca secret missing": {
+			objs: []interface{}{proxy96, sec1},
 			want: map[Meta]Status{
-				{name: ir29.Name, namespace: ir29.Namespace}: {
-					Object:      ir29,
-					Status:      "valid",
-					Description: "valid IngressRoute",
-					Vhost:       "site1.com",
-				},
-				{name: ir30.Name, namespace: ir30.Namespace}: {
-					Object:      ir30,
-					Status:      "valid",
-					Description: "valid IngressRoute",
-					Vhost:       "site2.com",
-				},
-				{name: ir31.Name, namespace: ir31.Namespace}: {
-					Object:      ir31,
-					Status:      "valid",
-					Description: "valid IngressRoute",
+				{name: proxy96.Name, namespace: proxy96.Namespace}: {
+					Object:      proxy96,
+					Status:      StatusInvalid,
+					Description: "clientValidation: CA Secret [missing] not found or is malformed",
+					Vhost:       "mtls.example.com",
 				},
 			},
 		},
-		"valid proxy": {
-			objs: []interface{}{proxy1, s4},
+		"tcpproxy with client validation ca secret missing": {
+			objs: []interface{}{proxy104, s1, sec1},
 			want: map[Meta]Status{
-				{name: proxy1.Name, namespace: proxy1.Namespace}: {Object: proxy1, Status: "valid", Description: "valid HTTPProxy", Vhost: "example.com"},
+				{name: proxy104.Name, namespace: proxy104.Namespace}: {
+					Object:      proxy104,
+					Status:      StatusInvalid,
+					Description: "clientValidation: CA Secret [missing] not found or is malformed",
+					Vhost:       "mtls.example.com",
+				},
 			},
 		},
-		"proxy invalid port in service": {
-			objs: []interface{}{proxy2},
+		"ingressroute with client validation": {
+			objs: []interface{}{ir32, s1, sec1},
 			want: map[Meta]Status{
-				{name: proxy2.Name, namespace: proxy2.Namespace}: {Object: proxy2, Status: "invalid", Description: `service "home": port must be in the range 1-65535`, Vhost: "example.com"},
+				{name: ir32.Name, namespace: ir32.Namespace}: {
+					Object:      ir32,
+					Status:      StatusInvalid,
+					Description: "Spec.VirtualHost.TLS.ClientValidation is not supported by IngressRoute, use HTTPProxy",
+					Vhost:       "mtls.example.com",
+				},
 			},
 		},
-		"root proxy outside of roots namespace": {
-			objs: []interface{}{proxy3},
+		"ingressroute with authorization": {
+			objs: []interface{}{ir33, s1, sec1},
 			want: map[Meta]Status{
-				{name: proxy3.Name, namespace: proxy3.Namespace}: {Object: proxy3, Status: "invalid", Description: "root HTTPProxy cannot be defined in this namespace"},
+				{name: ir33.Name, namespace: ir33.Namespace}: {
+					Object:      ir33,
+					Status:      StatusInvalid,
+					Description: "Spec.VirtualHost.Authorization is not supported by IngressRoute, use HTTPProxy",
+					Vhost:       "auth.example.com",
+				},
 			},
 		},
-		"root proxy does not specify FQDN": {
-			objs: []interface{}{proxy13},
+		"client validation with tls passthrough": {
+			objs: []interface{}{proxy97},
 			want: map[Meta]Status{
-				{name: proxy13.Name, namespace: proxy13.Namespace}: {Object: proxy13, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
+				{name: proxy97.Name, namespace: proxy97.Namespace}: {
+					Object:      proxy97,
+					Status:      StatusInvalid,
+					Description: "clientValidation: requires TLS with a secretName",
+					Vhost:       "passthrough.example.com",
+				},
 			},
 		},
-		"proxy self-edge produces a cycle": {
-			objs: []interface{}{proxy6, s1},
+		"client validation crl secret missing": {
+			objs: []interface{}{proxy98, sec1, clientCA},
 			want: map[Meta]Status{
-				{name: proxy6.Name, namespace: proxy6.Namespace}: {
-					Object:      proxy6,
-					Status:      "invalid",
-					Description: "root httpproxy cannot delegate to another root httpproxy",
-					Vhost:       "example.com",
+				{name: proxy98.Name, namespace: proxy98.Namespace}: {
+					Object:      proxy98,
+					Status:      StatusInvalid,
+					Description: "clientValidation: CRL Secret [missing] not found or is malformed",
+					Vhost:       "mtls.example.com",
 				},
 			},
 		},
-		"proxy child delegates to parent, producing a cycle": {
-			objs: []interface{}{proxy7, proxy8},
+		"jwt provider with unvalidated https jwks": {
+			objs: []interface{}{proxy99, s1, sec1},
 			want: map[Meta]Status{
-				{name: proxy7.Name, namespace: proxy7.Namespace}: {
-					Object:      proxy7,
-					Status:      "valid",
-					Description: "valid HTTPProxy",
-					Vhost:       "example.com",
+				{name: proxy99.Name, namespace: proxy99.Namespace}: {
+					Object:      proxy99,
+					Status:      StatusInvalid,
+					Description: `jwtProviders: provider "provider-1": remoteJWKS: uri "https://auth.example.com/jwks.json" uses https, validation must be specified`,
+					Vhost:       "jwt.example.com",
 				},
-				{name: proxy8.Name, namespace: proxy8.Namespace}: {
-					Object:      proxy8,
-					Status:      "invalid",
-					Description: "include creates a delegation cycle: roots/parent -> roots/child -> roots/child",
+			},
+		},
+		"jwt provider jwks validation ca missing": {
+			objs: []interface{}{proxy100, s1, sec1},
+			want: map[Meta]Status{
+				{name: proxy100.Name, namespace: proxy100.Namespace}: {
+					Object:      proxy100,
+					Status:      StatusInvalid,
+					Description: `jwtProviders: provider "provider-1": remoteJWKS: route "jwtProviders": service "auth.example.com": upstreamValidation requested but secret not found or misconfigured`,
+					Vhost:       "jwt.example.com",
 				},
 			},
 		},
-		"proxy orphaned route": {
-			objs: []interface{}{proxy8},
+		"jwt provider with validated https jwks": {
+			objs: []interface{}{proxy101, s1, sec1, clientCA},
 			want: map[Meta]Status{
-				{name: proxy8.Name, namespace: proxy8.Namespace}: {Object: proxy8, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
+				{name: proxy101.Name, namespace: proxy101.Namespace}: {
+					Object:      proxy101,
+					Status:      StatusValid,
+					Description: "valid HTTPProxy",
+					Vhost:       "jwt.example.com",
+				},
 			},
 		},
-		"proxy invalid parent orphans children": {
-			objs: []interface{}{proxy14, proxy11},
+		"jwt provider with http jwks validation": {
+			objs: []interface{}{proxy102, s1, sec1, clientCA},
 			want: map[Meta]Status{
-				{name: proxy14.Name, namespace: proxy14.Namespace}: {Object: proxy14, Status: "invalid", Description: "Spec.VirtualHost.Fqdn must be specified"},
-				{name: proxy11.Name, namespace: proxy11.Namespace}: {Object: proxy11, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
+				{name: proxy102.Name, namespace: proxy102.Namespace}: {
+					Object:      proxy102,
+					Status:      StatusInvalid,
+					Description: `jwtProviders: provider "provider-1": remoteJWKS: validation requires an https uri, not "http://auth.example.com/jwks.json"`,
+					Vhost:       "jwt.example.com",
+				},
 			},
 		},
-		"proxy invalid FQDN contains wildcard": {
-			objs: []interface{}{proxy15},
+		"service port name missing": {
+			objs: []interface{}{proxy72, s4},
 			want: map[Meta]Status{
-				{name: proxy15.Name, namespace: proxy15.Namespace}: {Object: proxy15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" cannot use wildcards`, Vhost: "example.*.com"},
+				{name: proxy72.Name, namespace: proxy72.Namespace}: {
+					Object:      proxy72,
+					Status:      StatusWarning,
+					Description: "Service [kuard:http] is invalid or missing",
+					Vhost:       "port-name.example.com",
+				},
 			},
 		},
-		"proxy missing service shows invalid status": {
-			objs: []interface{}{proxy16},
+		"service selector with duplicate value": {
+			objs: []interface{}{proxy69, s1},
 			want: map[Meta]Status{
-				{name: proxy16.Name, namespace: proxy16.Namespace}: {
-					Object:      proxy16,
-					Status:      "invalid",
-					Description: `Service [invalid:8080] is invalid or missing`,
-					Vhost:       proxy16.Spec.VirtualHost.Fqdn,
+				{name: proxy69.Name, namespace: proxy69.Namespace}: {
+					Object:      proxy69,
+					Status:      StatusInvalid,
+					Description: `serviceSelector: value "a" is specified more than once`,
+					Vhost:       "service-selector.example.com",
 				},
 			},
 		},
@@ -2208,7 +3744,7 @@ func TestDAGIngressRouteStatus(t *testing.T) {
 			want: map[Meta]Status{
 				{name: proxy33.Name, namespace: proxy33.Namespace}: {
 					Object:      proxy33,
-					Status:      "invalid",
+					Status:      "warning",
 					Description: "include: More than one prefix is not allowed in a condition block",
 					Vhost:       "example.com",
 				}, {name: proxy34.Name, namespace: proxy34.Namespace}: {
@@ -2234,7 +3770,7 @@ func TestDAGIngressRouteStatus(t *testing.T) {
 			want: map[Meta]Status{
 				{name: proxy36.Name, namespace: proxy36.Namespace}: {
 					Object:      proxy36,
-					Status:      "invalid",
+					Status:      "warning",
 					Description: "include: Prefix conditions must start with /, api was supplied",
 					Vhost:       "example.com",
 				}, {name: proxy34.Name, namespace: proxy34.Namespace}: {
@@ -2373,3 +3909,183 @@ func TestDAGIngressRouteStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestDAGStatusStrictAnnotations(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "roots",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+	proxy := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "typo",
+			Namespace: s1.Namespace,
+			Annotations: map[string]string{
+				"projectcontour.io/ingress.class":   "contour",
+				"projectcontour.io/websocket-route": "/",
+				"projectcontour.io/max-requests":    "100",
+			},
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "typo.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		strict bool
+		want   map[Meta]Status
+	}{
+		"not strict": {
+			want: map[Meta]Status{
+				{name: proxy.Name, namespace: proxy.Namespace}: {
+					Object:      proxy,
+					Status:      StatusValid,
+					Description: "valid HTTPProxy",
+					Vhost:       "typo.example.com",
+				},
+			},
+		},
+		"strict": {
+			strict: true,
+			want: map[Meta]Status{
+				{name: proxy.Name, namespace: proxy.Namespace}: {
+					Object:      proxy,
+					Status:      StatusWarning,
+					Description: `annotation "projectcontour.io/max-requests" is not supported on HTTPProxy; annotation "projectcontour.io/websocket-route" is unknown`,
+					Vhost:       "typo.example.com",
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+				StrictAnnotations: tc.strict,
+			}
+			builder.Source.Insert(s1)
+			builder.Source.Insert(proxy)
+			got := builder.Build().Statuses()
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDAGStatusRateLimitService(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "roots",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+
+	rls := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ratelimit",
+			Namespace: "projectcontour",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     8081,
+			}},
+		},
+	}
+
+	proxy := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rate-limit",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "rate-limit.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 8080,
+				}},
+				RateLimitPolicy: &projcontour.RateLimitPolicy{
+					Descriptors: []projcontour.RateLimitDescriptor{{
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							RemoteAddress: &projcontour.RemoteAddressDescriptor{},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+
+	tests := map[string]struct {
+		objs []interface{}
+		want map[Meta]Status
+	}{
+		"rate limit service present": {
+			objs: []interface{}{s1, rls, proxy},
+			want: map[Meta]Status{
+				{name: proxy.Name, namespace: proxy.Namespace}: {
+					Object:      proxy,
+					Status:      StatusValid,
+					Description: "valid HTTPProxy",
+					Vhost:       "rate-limit.example.com",
+				},
+			},
+		},
+		"rate limit service missing": {
+			objs: []interface{}{s1, proxy},
+			want: map[Meta]Status{
+				{name: proxy.Name, namespace: proxy.Namespace}: {
+					Object:      proxy,
+					Status:      StatusInvalid,
+					Description: "rateLimitPolicy: rate limit Service [projectcontour/ratelimit:8081] is invalid or missing",
+					Vhost:       "rate-limit.example.com",
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: testLogger(t),
+				},
+				RateLimitService: RateLimitServiceConfig{
+					Namespace: rls.Namespace,
+					Name:      rls.Name,
+					Port:      8081,
+				},
+			}
+			for _, o := range tc.objs {
+				builder.Source.Insert(o)
+			}
+			got := builder.Build().Statuses()
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
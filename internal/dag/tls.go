@@ -0,0 +1,58 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validCipherSuites are the cipher suites Envoy accepts for the
+// TLS versions below 1.3. The cipher suites of TLS 1.3 are not
+// configurable.
+var validCipherSuites = map[string]bool{
+	"ECDHE-ECDSA-AES128-GCM-SHA256": true,
+	"ECDHE-RSA-AES128-GCM-SHA256":   true,
+	"ECDHE-ECDSA-CHACHA20-POLY1305": true,
+	"ECDHE-RSA-CHACHA20-POLY1305":   true,
+	"ECDHE-ECDSA-AES128-SHA":        true,
+	"ECDHE-RSA-AES128-SHA":          true,
+	"AES128-GCM-SHA256":             true,
+	"AES128-SHA":                    true,
+	"ECDHE-ECDSA-AES256-GCM-SHA384": true,
+	"ECDHE-RSA-AES256-GCM-SHA384":   true,
+	"ECDHE-ECDSA-AES256-SHA":        true,
+	"ECDHE-RSA-AES256-SHA":          true,
+	"AES256-GCM-SHA384":             true,
+	"AES256-SHA":                    true,
+}
+
+// ValidateCipherSuites returns an error naming the first of suites
+// Envoy does not accept. Each suite is either the name of a cipher
+// suite, or a group of cipher suites of equal preference, such as
+// "[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]".
+func ValidateCipherSuites(suites []string) error {
+	for _, suite := range suites {
+		names := []string{suite}
+		if strings.HasPrefix(suite, "[") && strings.HasSuffix(suite, "]") {
+			names = strings.Split(suite[1:len(suite)-1], "|")
+		}
+		for _, name := range names {
+			if !validCipherSuites[name] {
+				return fmt.Errorf("cipher suite %q is not supported", suite)
+			}
+		}
+	}
+	return nil
+}
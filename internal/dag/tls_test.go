@@ -0,0 +1,61 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"testing"
+
+	"github.com/projectcontour/contour/internal/assert"
+)
+
+func TestValidateCipherSuites(t *testing.T) {
+	tests := map[string]struct {
+		suites []string
+		want   string
+	}{
+		"none": {},
+		"single suites": {
+			suites: []string{"ECDHE-ECDSA-AES256-GCM-SHA384", "ECDHE-RSA-AES256-GCM-SHA384"},
+		},
+		"equal preference group": {
+			suites: []string{"[ECDHE-ECDSA-AES128-GCM-SHA256|ECDHE-ECDSA-CHACHA20-POLY1305]"},
+		},
+		"unsupported suite": {
+			suites: []string{"ECDHE-RSA-AES256-GCM-SHA384", "RC4-SHA"},
+			want:   `cipher suite "RC4-SHA" is not supported`,
+		},
+		"unsupported suite in group": {
+			suites: []string{"[ECDHE-RSA-AES128-GCM-SHA256|DES-CBC3-SHA]"},
+			want:   `cipher suite "[ECDHE-RSA-AES128-GCM-SHA256|DES-CBC3-SHA]" is not supported`,
+		},
+		"empty group": {
+			suites: []string{"[]"},
+			want:   `cipher suite "[]" is not supported`,
+		},
+		"tls 1.3 suite": {
+			suites: []string{"TLS_AES_128_GCM_SHA256"},
+			want:   `cipher suite "TLS_AES_128_GCM_SHA256" is not supported`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			if err := ValidateCipherSuites(tc.suites); err != nil {
+				got = err.Error()
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -12,15 +12,20 @@
 // limitations under the License.
 
 // Package debug provides http endpoints for healthcheck, metrics,
-// and pprof debugging.
+// and, when enabled, pprof debugging.
 package debug
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"net/http"
 	"net/http/pprof"
 
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
 	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/grpc"
 	"github.com/projectcontour/contour/internal/httpsvc"
+	"github.com/projectcontour/contour/internal/snapshot"
 )
 
 // Service serves various http endpoints including /debug/pprof.
@@ -28,26 +33,67 @@ type Service struct {
 	httpsvc.Service
 
 	Builder *dag.Builder
+
+	// Resources are the xDS caches exported by /debug/snapshot.
+	Resources []grpc.Resource
+
+	// Profiling enables the /debug/pprof endpoints, which serve
+	// CPU and heap profiles and execution traces.
+	Profiling bool
+
+	// Token, if set, is the bearer token requests to the
+	// /debug/pprof endpoints, and for snapshots which include
+	// secrets, must present.
+	Token string
 }
 
 // Start fulfills the g.Start contract.
 // When stop is closed the http server will shutdown.
 func (svc *Service) Start(stop <-chan struct{}) error {
-	registerProfile(&svc.ServeMux)
+	if svc.Profiling {
+		registerProfile(&svc.ServeMux, svc.Token)
+	}
 	registerDotWriter(&svc.ServeMux, svc.Builder)
+	registerReferences(&svc.ServeMux, svc.Builder)
+	registerReport(&svc.ServeMux, svc.Builder)
+	registerSnapshot(&svc.ServeMux, svc.Resources, svc.Token)
 	return svc.Service.Start(stop)
 }
 
-func registerProfile(mux *http.ServeMux) {
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-	mux.Handle("/debug/pprof/block", pprof.Handler("block"))
-	mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-	mux.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-	mux.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+// registerProfile serves the net/http/pprof handlers, requiring
+// token as a bearer token if it is not empty.
+func registerProfile(mux *http.ServeMux, token string) {
+	handle := func(pattern string, h http.Handler) {
+		mux.Handle(pattern, requireToken(token, h))
+	}
+	handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+	handle("/debug/pprof/block", pprof.Handler("block"))
+	handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	handle("/debug/pprof/heap", pprof.Handler("heap"))
+	handle("/debug/pprof/mutex", pprof.Handler("mutex"))
+	handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+}
+
+// requireToken returns h, wrapped to reject requests which do not
+// present token as a bearer token if token is not empty.
+func requireToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
 
 func registerDotWriter(mux *http.ServeMux, builder *dag.Builder) {
@@ -58,3 +104,48 @@ func registerDotWriter(mux *http.ServeMux, builder *dag.Builder) {
 		dw.writeDot(w)
 	})
 }
+
+// registerSnapshot serves a tarball of the xDS caches. Secrets hold
+// private keys so are only included when ?secrets=true is supplied,
+// and then only to requests which present token as a bearer token.
+// Without a token secrets are never served.
+func registerSnapshot(mux *http.ServeMux, resources []grpc.Resource, token string) {
+	withSecrets := snapshotHandler(resources, true)
+	if token == "" {
+		withSecrets = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "secrets can only be exported with a debug token", http.StatusForbidden)
+		})
+	} else {
+		withSecrets = requireToken(token, withSecrets)
+	}
+	withoutSecrets := snapshotHandler(resources, false)
+	mux.HandleFunc("/debug/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("secrets") == "true" {
+			withSecrets.ServeHTTP(w, r)
+			return
+		}
+		withoutSecrets.ServeHTTP(w, r)
+	})
+}
+
+// snapshotHandler returns a handler which writes a tarball of
+// resources, including their secrets if secrets is true.
+func snapshotHandler(resources []grpc.Resource, secrets bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var export []grpc.Resource
+		for _, res := range resources {
+			if res.TypeURL() == cache.SecretType && !secrets {
+				continue
+			}
+			export = append(export, res)
+		}
+		var buf bytes.Buffer
+		if err := snapshot.Write(&buf, export...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="contour-snapshot.tar.gz"`)
+		buf.WriteTo(w)
+	})
+}
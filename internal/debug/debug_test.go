@@ -0,0 +1,123 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterProfile(t *testing.T) {
+	tests := map[string]struct {
+		token         string
+		authorization string
+		want          int
+	}{
+		"no token": {
+			want: http.StatusOK,
+		},
+		"token presented": {
+			token:         "s3cr3t",
+			authorization: "Bearer s3cr3t",
+			want:          http.StatusOK,
+		},
+		"token missing": {
+			token: "s3cr3t",
+			want:  http.StatusUnauthorized,
+		},
+		"token wrong": {
+			token:         "s3cr3t",
+			authorization: "Bearer guess",
+			want:          http.StatusUnauthorized,
+		},
+		"token not bearer": {
+			token:         "s3cr3t",
+			authorization: "s3cr3t",
+			want:          http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mux http.ServeMux
+			registerProfile(&mux, tc.token)
+
+			req := httptest.NewRequest("GET", "/debug/pprof/goroutine", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRegisterSnapshot(t *testing.T) {
+	tests := map[string]struct {
+		token         string
+		query         string
+		authorization string
+		want          int
+	}{
+		"no secrets": {
+			want: http.StatusOK,
+		},
+		"no secrets, token configured": {
+			token: "s3cr3t",
+			want:  http.StatusOK,
+		},
+		"secrets without a configured token": {
+			query: "?secrets=true",
+			want:  http.StatusForbidden,
+		},
+		"secrets, token presented": {
+			token:         "s3cr3t",
+			query:         "?secrets=true",
+			authorization: "Bearer s3cr3t",
+			want:          http.StatusOK,
+		},
+		"secrets, token missing": {
+			token: "s3cr3t",
+			query: "?secrets=true",
+			want:  http.StatusUnauthorized,
+		},
+		"secrets, token wrong": {
+			token:         "s3cr3t",
+			query:         "?secrets=true",
+			authorization: "Bearer guess",
+			want:          http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mux http.ServeMux
+			registerSnapshot(&mux, nil, tc.token)
+
+			req := httptest.NewRequest("GET", "/debug/snapshot"+tc.query, nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
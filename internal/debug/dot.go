@@ -59,6 +59,8 @@ func (c *ctx) writeVertex(v dag.Vertex) {
 		fmt.Fprintf(c.w, `"%p" [shape=record, label="{tcpproxy}"]`+"\n", v)
 	case *dag.Cluster:
 		fmt.Fprintf(c.w, `"%p" [shape=record, label="{cluster|{%s|weight %d}}"]`+"\n", v, envoy.Clustername(v), v.Weight)
+	case *dag.RateLimitService:
+		fmt.Fprintf(c.w, `"%p" [shape=record, label="{ratelimit|%s}"]`+"\n", v, v.Domain)
 	}
 }
 
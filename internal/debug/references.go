@@ -0,0 +1,193 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/projectcontour/contour/internal/dag"
+)
+
+// references records the virtual hosts, and the HTTPProxies,
+// which depend on a Secret or Service.
+type references struct {
+	VirtualHosts []string `json:"virtualhosts"`
+	HTTPProxies  []string `json:"httpproxies"`
+}
+
+// referrer returns a function which reports if a dag.Secret or
+// dag.Service is the object of the supplied kind, namespace and name.
+func referrer(kind, namespace, name string) (func(dag.Vertex) bool, error) {
+	switch kind {
+	case "secret":
+		return func(v dag.Vertex) bool {
+			s, ok := v.(*dag.Secret)
+			return ok && s.Namespace() == namespace && s.Name() == name
+		}, nil
+	case "service":
+		return func(v dag.Vertex) bool {
+			s, ok := v.(*dag.Service)
+			return ok && s.Namespace == namespace && s.Name == name
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %q, must be one of secret or service", kind)
+	}
+}
+
+// findReferences walks the DAG returning the virtual hosts, and the
+// HTTPProxies whose routes, depend on a vertex matched by refers.
+func findReferences(root dag.Visitable, refers func(dag.Vertex) bool) *references {
+	vhosts := make(map[string]bool)
+	proxies := make(map[string]bool)
+
+	secret := func(s *dag.Secret) bool {
+		return s != nil && refers(s)
+	}
+
+	// cluster reports if refers matches the cluster's service, its
+	// client certificate or its upstream validation CA.
+	cluster := func(c *dag.Cluster) bool {
+		if c == nil {
+			return false
+		}
+		if uv := c.UpstreamValidation; uv != nil && secret(uv.CACertificate) {
+			return true
+		}
+		if secret(c.ClientCertificate) {
+			return true
+		}
+		return c.Upstream != nil && refers(c.Upstream)
+	}
+
+	// the rate limit service is consulted by every route with
+	// a rate limit policy.
+	var rateLimited bool
+	root.Visit(func(v dag.Vertex) {
+		if rls, ok := v.(*dag.RateLimitService); ok && cluster(rls.Cluster) {
+			rateLimited = true
+		}
+	})
+
+	route := func(r *dag.Route) bool {
+		if rateLimited && r.RateLimitPolicy != nil {
+			return true
+		}
+		if r.MirrorPolicy != nil && cluster(r.MirrorPolicy.Cluster) {
+			return true
+		}
+		for _, c := range r.Clusters {
+			if cluster(c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// routes records vh, and the HTTPProxies of those of its
+	// routes for which match returns true, as referrers.
+	routes := func(vh *dag.VirtualHost, match func(*dag.Route) bool) {
+		vh.Visit(func(child dag.Vertex) {
+			r, ok := child.(*dag.Route)
+			if !ok || !match(r) {
+				return
+			}
+			vhosts[vh.Name] = true
+			if r.Name != "" {
+				proxies[r.Name] = true
+			}
+		})
+	}
+	every := func(*dag.Route) bool { return true }
+
+	var visit func(dag.Vertex)
+	visit = func(v dag.Vertex) {
+		var vh *dag.VirtualHost
+		switch v := v.(type) {
+		case *dag.VirtualHost:
+			vh = v
+		case *dag.SecureVirtualHost:
+			vh = &v.VirtualHost
+			// every route on the vhost is served with its secret,
+			// client validation and authorization.
+			if secret(v.Secret) {
+				vhosts[vh.Name] = true
+				routes(vh, every)
+			}
+			if dv := v.DownstreamValidation; dv != nil && (secret(dv.CACertificate) || secret(dv.CertificateRevocationList)) {
+				vhosts[vh.Name] = true
+				routes(vh, every)
+			}
+			if as := v.AuthorizationServer; as != nil && cluster(as.Cluster) {
+				vhosts[vh.Name] = true
+				routes(vh, every)
+			}
+			for _, jp := range v.JWTProviders {
+				if !cluster(jp.RemoteJWKS.Cluster) {
+					continue
+				}
+				vhosts[vh.Name] = true
+				name := jp.Name
+				routes(vh, func(r *dag.Route) bool {
+					return r.JWTProvider == name
+				})
+			}
+			if v.TCPProxy != nil {
+				for _, c := range v.TCPProxy.Clusters {
+					if cluster(c) {
+						vhosts[vh.Name] = true
+					}
+				}
+			}
+		default:
+			v.Visit(visit)
+			return
+		}
+		routes(vh, route)
+	}
+	root.Visit(visit)
+
+	return &references{
+		VirtualHosts: keys(vhosts),
+		HTTPProxies:  keys(proxies),
+	}
+}
+
+func keys(m map[string]bool) []string {
+	s := []string{}
+	for k := range m {
+		s = append(s, k)
+	}
+	sort.Strings(s)
+	return s
+}
+
+func registerReferences(mux *http.ServeMux, builder *dag.Builder) {
+	mux.HandleFunc("/debug/dag/references", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		refers, err := referrer(q.Get("kind"), q.Get("namespace"), q.Get("name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findReferences(builder.Build(), refers)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
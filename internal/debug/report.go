@@ -0,0 +1,161 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+)
+
+// NamespaceReport summarises the HTTPProxies and IngressRoutes
+// of a namespace, and the virtual hosts they serve.
+type NamespaceReport struct {
+	Namespace string `json:"namespace"`
+
+	// Valid, Warning, Invalid and Orphaned count the HTTPProxies
+	// and IngressRoutes of the namespace with each status.
+	Valid    int `json:"valid"`
+	Warning  int `json:"warning"`
+	Invalid  int `json:"invalid"`
+	Orphaned int `json:"orphaned"`
+
+	// FQDNs counts the virtual hosts of the namespace's valid
+	// root HTTPProxies and IngressRoutes.
+	FQDNs int `json:"fqdns"`
+
+	// TLS is how those virtual hosts are served.
+	TLS TLSReport `json:"tls"`
+
+	// DeprecatedAnnotations counts the uses of deprecated
+	// annotations by the namespace's objects.
+	DeprecatedAnnotations int `json:"deprecatedAnnotations"`
+}
+
+// TLSReport counts virtual hosts by how they are served.
+type TLSReport struct {
+	// Plaintext virtual hosts are served only over HTTP.
+	Plaintext int `json:"plaintext"`
+
+	// Terminated virtual hosts have TLS terminated by Envoy,
+	// without validating client certificates.
+	Terminated int `json:"terminated"`
+
+	// Mutual virtual hosts have TLS terminated by Envoy, which
+	// validates client certificates.
+	Mutual int `json:"mutual"`
+
+	// Passthrough virtual hosts have TLS passed through to
+	// their upstream service.
+	Passthrough int `json:"passthrough"`
+}
+
+// report returns a NamespaceReport for each namespace with an
+// HTTPProxy or IngressRoute, or an object using a deprecated
+// annotation, sorted by namespace.
+func report(d *dag.DAG) []NamespaceReport {
+	namespaces := make(map[string]*NamespaceReport)
+	ns := func(name string) *NamespaceReport {
+		r, ok := namespaces[name]
+		if !ok {
+			r = &NamespaceReport{Namespace: name}
+			namespaces[name] = r
+		}
+		return r
+	}
+
+	secure := make(map[string]*dag.SecureVirtualHost)
+	d.Visit(func(v dag.Vertex) {
+		if l, ok := v.(*dag.Listener); ok {
+			for _, vh := range l.VirtualHosts {
+				if svh, ok := vh.(*dag.SecureVirtualHost); ok {
+					secure[svh.Name] = svh
+				}
+			}
+		}
+	})
+
+	for _, st := range d.Statuses() {
+		r := ns(st.Object.GetObjectMeta().GetNamespace())
+		switch st.Status {
+		case dag.StatusValid:
+			r.Valid++
+		case dag.StatusWarning:
+			r.Warning++
+		case dag.StatusInvalid:
+			r.Invalid++
+		case dag.StatusOrphaned:
+			r.Orphaned++
+		}
+
+		// objects with warnings are still serving traffic.
+		if st.Status != dag.StatusValid && st.Status != dag.StatusWarning {
+			continue
+		}
+		var fqdn string
+		switch o := st.Object.(type) {
+		case *projcontour.HTTPProxy:
+			if o.Spec.VirtualHost != nil {
+				fqdn = o.Spec.VirtualHost.Fqdn
+			}
+		case *ingressroutev1.IngressRoute:
+			if o.Spec.VirtualHost != nil {
+				fqdn = o.Spec.VirtualHost.Fqdn
+			}
+		}
+		if fqdn == "" {
+			continue
+		}
+		r.FQDNs++
+		svh, ok := secure[fqdn]
+		switch {
+		case !ok:
+			r.TLS.Plaintext++
+		case svh.Secret == nil:
+			r.TLS.Passthrough++
+		case svh.DownstreamValidation != nil:
+			r.TLS.Mutual++
+		default:
+			r.TLS.Terminated++
+		}
+	}
+
+	for da, count := range d.DeprecatedAnnotations() {
+		ns(da.Namespace).DeprecatedAnnotations += count
+	}
+
+	reports := make([]NamespaceReport, 0, len(namespaces))
+	for _, r := range namespaces {
+		reports = append(reports, *r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Namespace < reports[j].Namespace
+	})
+	return reports
+}
+
+func registerReport(mux *http.ServeMux, builder *dag.Builder) {
+	mux.HandleFunc("/debug/report", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report(builder.Build())); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestReport(t *testing.T) {
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "certificate",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey:       []byte(CERTIFICATE),
+			v1.TLSPrivateKeyKey: []byte(RSA_PRIVATE_KEY),
+		},
+	}
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "clients",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"contour.heptio.com/upstream-protocol.h2c": "8080",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: "marketing",
+		},
+		Spec: s1.Spec,
+	}
+	routes := func(svc *v1.Service) []projcontour.Route {
+		return []projcontour.Route{{
+			Services: []projcontour.Service{{
+				Name: svc.Name,
+				Port: 8080,
+			}},
+		}}
+	}
+	plaintext := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plaintext",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "plaintext.example.com",
+			},
+			Routes: routes(s1),
+		},
+	}
+	terminated := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "terminated",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "terminated.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+			},
+			Routes: routes(s1),
+		},
+	}
+	mutual := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mutual",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "mutual.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: ca.Name,
+					},
+				},
+			},
+			Routes: routes(s1),
+		},
+	}
+	passthrough := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "passthrough",
+			Namespace: "marketing",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "passthrough.example.com",
+				TLS: &projcontour.TLS{
+					Passthrough: true,
+				},
+			},
+			TCPProxy: &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name: s2.Name,
+					Port: 8080,
+				}},
+			},
+		},
+	}
+	invalid := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "missing-secret",
+			Namespace: "marketing",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "invalid.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: "missing",
+				},
+			},
+			Routes: routes(s2),
+		},
+	}
+	orphaned := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "orphaned",
+			Namespace: "marketing",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: routes(s2),
+		},
+	}
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: log,
+		},
+	}
+	for _, o := range []interface{}{sec1, ca, s1, s2, plaintext, terminated, mutual, passthrough, invalid, orphaned} {
+		builder.Source.Insert(o)
+	}
+
+	want := []NamespaceReport{{
+		Namespace: "default",
+		Valid:     3,
+		FQDNs:     3,
+		TLS: TLSReport{
+			Plaintext:  1,
+			Terminated: 1,
+			Mutual:     1,
+		},
+		DeprecatedAnnotations: 1,
+	}, {
+		Namespace: "marketing",
+		Valid:     1,
+		Invalid:   1,
+		Orphaned:  1,
+		FQDNs:     1,
+		TLS: TLSReport{
+			Passthrough: 1,
+		},
+	}}
+	got := report(builder.Build())
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
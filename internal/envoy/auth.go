@@ -16,6 +16,8 @@ package envoy
 import (
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
 )
 
 var (
@@ -44,6 +46,9 @@ var (
 	}
 )
 
+// CRLKey is the key of the certificate revocation list in a Secret.
+const CRLKey = "crl.pem"
+
 // UpstreamTLSContext creates an envoy_api_v2_auth.UpstreamTlsContext. By default
 // UpstreamTLSContext returns a HTTP/1.1 TLS enabled context. A list of
 // additional ALPN protocols can be provided.
@@ -67,6 +72,34 @@ func UpstreamTLSContext(ca []byte, subjectName string, alpnProtocols ...string)
 	return context
 }
 
+// upstreamClientCertificate sets the certificate presented by Envoy
+// in context to that of secret, served by SDS, if secret is not nil.
+func upstreamClientCertificate(context *envoy_api_v2_auth.UpstreamTlsContext, secret *dag.Secret) {
+	if secret == nil {
+		return
+	}
+	context.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*envoy_api_v2_auth.SdsSecretConfig{{
+		Name:      Secretname(secret),
+		SdsConfig: ConfigSource("contour"),
+	}}
+}
+
+// upstreamValidationPins adds the further subject names, SPKI pins
+// and certificate hashes of uv to the validation context of context.
+// It does nothing if context does not validate the upstream.
+func upstreamValidationPins(context *envoy_api_v2_auth.UpstreamTlsContext, uv *dag.UpstreamValidation) {
+	if uv == nil {
+		return
+	}
+	vc, ok := context.CommonTlsContext.ValidationContextType.(*envoy_api_v2_auth.CommonTlsContext_ValidationContext)
+	if !ok {
+		return
+	}
+	vc.ValidationContext.VerifySubjectAltName = append(vc.ValidationContext.VerifySubjectAltName, uv.SubjectNames...)
+	vc.ValidationContext.VerifyCertificateSpki = uv.SPKIPins
+	vc.ValidationContext.VerifyCertificateHash = uv.CertificateHashes
+}
+
 func validationContext(ca []byte, subjectName string) *envoy_api_v2_auth.CommonTlsContext_ValidationContext {
 	if len(ca) < 1 {
 		// no ca provided, nothing to do
@@ -91,14 +124,21 @@ func validationContext(ca []byte, subjectName string) *envoy_api_v2_auth.CommonT
 	}
 }
 
-// DownstreamTLSContext creates a new DownstreamTlsContext.
-func DownstreamTLSContext(secretName string, tlsMinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, alpnProtos ...string) *envoy_api_v2_auth.DownstreamTlsContext {
-	return &envoy_api_v2_auth.DownstreamTlsContext{
+// DownstreamTLSContext creates a new DownstreamTlsContext negotiating TLS
+// versions from tlsMinProtoVersion to tlsMaxProtoVersion, and offering
+// cipherSuites, or the default cipher suites if none are given, for the
+// versions below TLS 1.3. If peerValidation is set, the certificates
+// clients present are validated against its CA.
+func DownstreamTLSContext(secretName string, tlsMinProtoVersion, tlsMaxProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, cipherSuites []string, peerValidation *dag.DownstreamValidation, alpnProtos ...string) *envoy_api_v2_auth.DownstreamTlsContext {
+	if len(cipherSuites) == 0 {
+		cipherSuites = ciphers
+	}
+	context := &envoy_api_v2_auth.DownstreamTlsContext{
 		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
 			TlsParams: &envoy_api_v2_auth.TlsParameters{
 				TlsMinimumProtocolVersion: tlsMinProtoVersion,
-				TlsMaximumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_3,
-				CipherSuites:              ciphers,
+				TlsMaximumProtocolVersion: tlsMaxProtoVersion,
+				CipherSuites:              cipherSuites,
 			},
 			TlsCertificateSdsSecretConfigs: []*envoy_api_v2_auth.SdsSecretConfig{{
 				Name:      secretName,
@@ -107,4 +147,29 @@ func DownstreamTLSContext(secretName string, tlsMinProtoVersion envoy_api_v2_aut
 			AlpnProtocols: alpnProtos,
 		},
 	}
+
+	if peerValidation != nil {
+		// a client certificate, if presented, must chain to the CA
+		// whether or not one is required.
+		vc := &envoy_api_v2_auth.CertificateValidationContext{
+			TrustedCa: &envoy_api_v2_core.DataSource{
+				Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+					InlineBytes: peerValidation.CACertificate.Object.Data[CACertificateKey],
+				},
+			},
+		}
+		if crl := peerValidation.CertificateRevocationList; crl != nil {
+			vc.Crl = &envoy_api_v2_core.DataSource{
+				Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+					InlineBytes: crl.Object.Data[CRLKey],
+				},
+			}
+		}
+		context.CommonTlsContext.ValidationContextType = &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
+			ValidationContext: vc,
+		}
+		context.RequireClientCertificate = protobuf.Bool(!peerValidation.Optional)
+	}
+
+	return context
 }
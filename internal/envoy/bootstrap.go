@@ -26,6 +26,8 @@ import (
 	clusterv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v2"
+	envoy_config_metrics_v2 "github.com/envoyproxy/go-control-plane/envoy/config/metrics/v2"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/projectcontour/contour/internal/protobuf"
 )
 
@@ -86,12 +88,22 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 				},
 			}},
 		},
+		StatsConfig: &envoy_config_metrics_v2.StatsConfig{
+			StatsTags:    statsTags(),
+			StatsMatcher: statsMatcher(c),
+		},
 		Admin: &bootstrap.Admin{
 			AccessLogPath: c.adminAccessLogPath(),
 			Address:       SocketAddress(c.adminAddress(), c.adminPort()),
 		},
 	}
 
+	if md := NodeMetadata(c.ListenerAddresses); md != nil {
+		b.Node = &envoy_api_v2_core.Node{
+			Metadata: md,
+		}
+	}
+
 	if c.GrpcClientCert != "" || c.GrpcClientKey != "" || c.GrpcCABundle != "" {
 		// If one of the two TLS options is not empty, they all must be not empty
 		if !(c.GrpcClientCert != "" && c.GrpcClientKey != "" && c.GrpcCABundle != "") {
@@ -103,6 +115,96 @@ func Bootstrap(c *BootstrapConfig) *bootstrap.Bootstrap {
 	return b
 }
 
+// statsTags returns the tag specifiers which replace Envoy's defaults
+// for virtual host and virtual cluster stats. Contour names virtual
+// hosts after their fully qualified domain name so the default
+// extractors, which stop at the first dot, would truncate the virtual
+// host and miss the per route virtual cluster altogether.
+func statsTags() []*envoy_config_metrics_v2.TagSpecifier {
+	return []*envoy_config_metrics_v2.TagSpecifier{{
+		TagName: "envoy.virtual_host",
+		TagValue: &envoy_config_metrics_v2.TagSpecifier_Regex{
+			Regex: `^vhost\.((.*?)\.)vcluster\.`,
+		},
+	}, {
+		TagName: "envoy.virtual_cluster",
+		TagValue: &envoy_config_metrics_v2.TagSpecifier_Regex{
+			Regex: `^vhost\..*?\.vcluster\.((.*?)\.)`,
+		},
+	}}
+}
+
+// contourStatsPrefixes are the prefixes of the Envoy stats, other than
+// per cluster stats, used by Contour's dashboards and documentation.
+var contourStatsPrefixes = []string{
+	"cluster_manager.",
+	"control_plane.",
+	"http.",
+	"listener.",
+	"listener_manager.",
+	"server.",
+	"vhost.",
+}
+
+// contourClusterStats matches the per cluster stats used by Contour's
+// dashboards and documentation. Per cluster stats are the bulk of
+// Envoy's stats so only the request, connection and membership
+// summaries are kept.
+const contourClusterStats = `cluster\..+\.(membership_(healthy|total)|upstream_cx_(active|connect_fail|total)|upstream_rq_([0-9][0-9x]{2}|active|pending_overflow|retry|time|timeout|total))`
+
+// statsMatcher returns the stats matcher restricting the stats Envoy
+// generates, or nil if Envoy should generate all of its stats.
+func statsMatcher(c *BootstrapConfig) *envoy_config_metrics_v2.StatsMatcher {
+	prefixes := func(prefixes []string) []*matcher.StringMatcher {
+		var patterns []*matcher.StringMatcher
+		for _, p := range prefixes {
+			patterns = append(patterns, &matcher.StringMatcher{
+				MatchPattern: &matcher.StringMatcher_Prefix{Prefix: p},
+			})
+		}
+		return patterns
+	}
+
+	switch {
+	case len(c.StatsExclusionPrefixes) > 0:
+		if c.ContourStatsOnly || len(c.StatsInclusionPrefixes) > 0 {
+			log.Fatal("--stats-exclusion-prefix cannot be combined with --stats-inclusion-prefix or --contour-stats-only.")
+		}
+		return &envoy_config_metrics_v2.StatsMatcher{
+			StatsMatcher: &envoy_config_metrics_v2.StatsMatcher_ExclusionList{
+				ExclusionList: &matcher.ListStringMatcher{
+					Patterns: prefixes(c.StatsExclusionPrefixes),
+				},
+			},
+		}
+	case c.ContourStatsOnly || len(c.StatsInclusionPrefixes) > 0:
+		var patterns []*matcher.StringMatcher
+		if c.ContourStatsOnly {
+			patterns = append(prefixes(contourStatsPrefixes), &matcher.StringMatcher{
+				MatchPattern: &matcher.StringMatcher_SafeRegex{
+					SafeRegex: &matcher.RegexMatcher{
+						EngineType: &matcher.RegexMatcher_GoogleRe2{
+							GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+								MaxProgramSize: protobuf.UInt32(1000),
+							},
+						},
+						Regex: contourClusterStats,
+					},
+				},
+			})
+		}
+		return &envoy_config_metrics_v2.StatsMatcher{
+			StatsMatcher: &envoy_config_metrics_v2.StatsMatcher_InclusionList{
+				InclusionList: &matcher.ListStringMatcher{
+					Patterns: append(patterns, prefixes(c.StatsInclusionPrefixes)...),
+				},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
 func upstreamFileTLSContext(cafile, certfile, keyfile string) *envoy_api_v2_auth.UpstreamTlsContext {
 	context := &envoy_api_v2_auth.UpstreamTlsContext{
 		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
@@ -169,6 +271,25 @@ type BootstrapConfig struct {
 
 	// GrpcClientKey is the filename that contains a client key for secure gRPC with TLS.
 	GrpcClientKey string
+
+	// ListenerAddresses overrides, by listener name, the addresses
+	// this Envoy binds the listeners it receives from Contour to.
+	ListenerAddresses map[string]ListenerAddress
+
+	// ContourStatsOnly restricts the stats Envoy generates to those
+	// used by Contour's dashboards and documentation.
+	// If not set, defaults to false.
+	ContourStatsOnly bool
+
+	// StatsInclusionPrefixes restricts the stats Envoy generates to
+	// those whose name starts with one of the supplied prefixes, in
+	// addition to Contour's stats if ContourStatsOnly is set.
+	StatsInclusionPrefixes []string
+
+	// StatsExclusionPrefixes prevents Envoy generating stats whose name
+	// starts with one of the supplied prefixes. Cannot be combined with
+	// ContourStatsOnly or StatsInclusionPrefixes.
+	StatsExclusionPrefixes []string
 }
 
 func (c *BootstrapConfig) xdsAddress() string   { return stringOrDefault(c.XDSAddress, "127.0.0.1") }
@@ -135,6 +135,168 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
+  "admin": {
+    "access_log_path": "/dev/null",
+    "address": {
+      "socket_address": {
+        "address": "127.0.0.1",
+        "port_value": 9001
+      }
+    }
+  }
+}`,
+		},
+		"--http-port=80 --https-address=:: --https-port=443": {
+			config: BootstrapConfig{
+				Namespace: "testing-ns",
+				ListenerAddresses: map[string]ListenerAddress{
+					"ingress_http":  {Port: 80},
+					"ingress_https": {Address: "::", Port: 443},
+				},
+			},
+			want: `{
+  "node": {
+    "metadata": {
+      "listeners": {
+        "ingress_http": {
+          "port": 80
+        },
+        "ingress_https": {
+          "address": "::",
+          "port": 443
+        }
+      }
+    }
+  },
+  "static_resources": {
+    "clusters": [
+      {
+        "name": "contour",
+        "alt_stat_name": "testing-ns_contour_8001",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "contour",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {},
+        "upstream_connection_options": {
+          "tcp_keepalive": {
+            "keepalive_probes": 3,
+            "keepalive_time": 30,
+            "keepalive_interval": 5
+          }
+        }
+      },
+      {
+        "name": "service-stats",
+        "alt_stat_name": "testing-ns_service-stats_9001",
+        "type": "LOGICAL_DNS",
+        "connect_timeout": "0.250s",
+        "load_assignment": {
+          "cluster_name": "service-stats",
+          "endpoints": [   
+            {                          
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 9001
+                      }    
+                    }     
+                  }
+                }          
+              ]                        
+            }
+          ]
+        }
+      }
+    ]
+  },
+  "dynamic_resources": {
+    "lds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    },
+    "cds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    }
+  },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
   "admin": {
     "access_log_path": "/dev/null",
     "address": {
@@ -258,6 +420,18 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
   "admin": {
     "access_log_path": "/dev/null",
     "address": {
@@ -380,6 +554,18 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
   "admin": {
     "access_log_path": "/var/log/admin.log",
     "address": {
@@ -503,6 +689,18 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
   "admin": {
     "access_log_path": "/dev/null",
     "address": {
@@ -624,6 +822,18 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
   "admin": {
     "access_log_path": "/dev/null",
     "address": {
@@ -770,6 +980,341 @@ func TestBootstrap(t *testing.T) {
       }
     }
   },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ]
+  },
+  "admin": {
+    "access_log_path": "/dev/null",
+    "address": {
+      "socket_address": {
+        "address": "127.0.0.1",
+        "port_value": 9001
+      }
+    }
+  }
+}`,
+		},
+		"contour stats only": {
+			config: BootstrapConfig{
+				Namespace:              "testing-ns",
+				ContourStatsOnly:       true,
+				StatsInclusionPrefixes: []string{"runtime."},
+			},
+			want: `{
+  "static_resources": {
+    "clusters": [
+      {
+        "name": "contour",
+        "alt_stat_name": "testing-ns_contour_8001",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "contour",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {
+
+        },
+        "upstream_connection_options": {
+          "tcp_keepalive": {
+            "keepalive_probes": 3,
+            "keepalive_time": 30,
+            "keepalive_interval": 5
+          }
+        }
+      },
+      {
+        "name": "service-stats",
+        "alt_stat_name": "testing-ns_service-stats_9001",
+        "type": "LOGICAL_DNS",
+        "connect_timeout": "0.250s",
+        "load_assignment": {
+          "cluster_name": "service-stats",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 9001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        }
+      }
+    ]
+  },
+  "dynamic_resources": {
+    "lds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    },
+    "cds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    }
+  },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ],
+    "stats_matcher": {
+      "inclusion_list": {
+        "patterns": [
+          {
+            "prefix": "cluster_manager."
+          },
+          {
+            "prefix": "control_plane."
+          },
+          {
+            "prefix": "http."
+          },
+          {
+            "prefix": "listener."
+          },
+          {
+            "prefix": "listener_manager."
+          },
+          {
+            "prefix": "server."
+          },
+          {
+            "prefix": "vhost."
+          },
+          {
+            "safe_regex": {
+              "google_re2": {
+                "max_program_size": 1000
+              },
+              "regex": "cluster\\..+\\.(membership_(healthy|total)|upstream_cx_(active|connect_fail|total)|upstream_rq_([0-9][0-9x]{2}|active|pending_overflow|retry|time|timeout|total))"
+            }
+          },
+          {
+            "prefix": "runtime."
+          }
+        ]
+      }
+    }
+  },
+  "admin": {
+    "access_log_path": "/dev/null",
+    "address": {
+      "socket_address": {
+        "address": "127.0.0.1",
+        "port_value": 9001
+      }
+    }
+  }
+}`,
+		},
+		"stats exclusion prefixes": {
+			config: BootstrapConfig{
+				Namespace:              "testing-ns",
+				StatsExclusionPrefixes: []string{"cluster.", "tls."},
+			},
+			want: `{
+  "static_resources": {
+    "clusters": [
+      {
+        "name": "contour",
+        "alt_stat_name": "testing-ns_contour_8001",
+        "type": "STRICT_DNS",
+        "connect_timeout": "5s",
+        "load_assignment": {
+          "cluster_name": "contour",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 8001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        },
+        "circuit_breakers": {
+          "thresholds": [
+            {
+              "priority": "HIGH",
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            },
+            {
+              "max_connections": 100000,
+              "max_pending_requests": 100000,
+              "max_requests": 60000000,
+              "max_retries": 50
+            }
+          ]
+        },
+        "http2_protocol_options": {
+
+        },
+        "upstream_connection_options": {
+          "tcp_keepalive": {
+            "keepalive_probes": 3,
+            "keepalive_time": 30,
+            "keepalive_interval": 5
+          }
+        }
+      },
+      {
+        "name": "service-stats",
+        "alt_stat_name": "testing-ns_service-stats_9001",
+        "type": "LOGICAL_DNS",
+        "connect_timeout": "0.250s",
+        "load_assignment": {
+          "cluster_name": "service-stats",
+          "endpoints": [
+            {
+              "lb_endpoints": [
+                {
+                  "endpoint": {
+                    "address": {
+                      "socket_address": {
+                        "address": "127.0.0.1",
+                        "port_value": 9001
+                      }
+                    }
+                  }
+                }
+              ]
+            }
+          ]
+        }
+      }
+    ]
+  },
+  "dynamic_resources": {
+    "lds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    },
+    "cds_config": {
+      "api_config_source": {
+        "api_type": "GRPC",
+        "grpc_services": [
+          {
+            "envoy_grpc": {
+              "cluster_name": "contour"
+            }
+          }
+        ]
+      }
+    }
+  },
+  "stats_config": {
+    "stats_tags": [
+      {
+        "tag_name": "envoy.virtual_host",
+        "regex": "^vhost\\.((.*?)\\.)vcluster\\."
+      },
+      {
+        "tag_name": "envoy.virtual_cluster",
+        "regex": "^vhost\\..*?\\.vcluster\\.((.*?)\\.)"
+      }
+    ],
+    "stats_matcher": {
+      "exclusion_list": {
+        "patterns": [
+          {
+            "prefix": "cluster."
+          },
+          {
+            "prefix": "tls."
+          }
+        ]
+      }
+    }
+  },
   "admin": {
     "access_log_path": "/dev/null",
     "address": {
@@ -59,36 +59,71 @@ func Cluster(c *dag.Cluster) *v2.Cluster {
 	default:
 		// external name set, use hard coded DNS name
 		cluster.ClusterDiscoveryType = ClusterDiscoveryType(v2.Cluster_STRICT_DNS)
+		if c.LogicalDNS {
+			cluster.ClusterDiscoveryType = ClusterDiscoveryType(v2.Cluster_LOGICAL_DNS)
+		}
 		cluster.LoadAssignment = StaticClusterLoadAssignment(service)
 	}
 
+	if c.HealthyPanicThreshold > 0 {
+		cluster.CommonLbConfig.HealthyPanicThreshold = &envoy_type.Percent{
+			Value: float64(c.HealthyPanicThreshold),
+		}
+	}
+
 	// Drain connections immediately if using healthchecks and the endpoint is known to be removed
-	if c.HealthCheckPolicy != nil {
+	if c.HealthCheckPolicy != nil || c.TCPHealthCheckPolicy != nil {
 		cluster.DrainConnectionsOnHostRemoval = true
 	}
 
-	if anyPositive(service.MaxConnections, service.MaxPendingRequests, service.MaxRequests, service.MaxRetries) {
+	if cb := circuitBreakers(c); anyPositive(cb.MaxConnections, cb.MaxPendingRequests, cb.MaxRequests, cb.MaxRetries) {
 		cluster.CircuitBreakers = &envoy_cluster.CircuitBreakers{
 			Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
-				MaxConnections:     u32nil(service.MaxConnections),
-				MaxPendingRequests: u32nil(service.MaxPendingRequests),
-				MaxRequests:        u32nil(service.MaxRequests),
-				MaxRetries:         u32nil(service.MaxRetries),
+				MaxConnections:     u32nil(cb.MaxConnections),
+				MaxPendingRequests: u32nil(cb.MaxPendingRequests),
+				MaxRequests:        u32nil(cb.MaxRequests),
+				MaxRetries:         u32nil(cb.MaxRetries),
 			}},
 		}
 	}
 
+	if c.IdleTimeout != 0 || c.MaxConnectionDuration != 0 {
+		cluster.CommonHttpProtocolOptions = &envoy_api_v2_core.HttpProtocolOptions{
+			IdleTimeout:           timeout(c.IdleTimeout),
+			MaxConnectionDuration: timeout(c.MaxConnectionDuration),
+		}
+	}
+
+	if c.ConnectTimeout > 0 {
+		cluster.ConnectTimeout = protobuf.Duration(c.ConnectTimeout)
+	}
+
+	if k := c.TCPKeepalive; k != nil {
+		cluster.UpstreamConnectionOptions = &v2.UpstreamConnectionOptions{
+			TcpKeepalive: &envoy_api_v2_core.TcpKeepalive{
+				KeepaliveProbes:   u32nil(k.Probes),
+				KeepaliveTime:     u32nil(uint32(k.Time / time.Second)),
+				KeepaliveInterval: u32nil(uint32(k.Interval / time.Second)),
+			},
+		}
+	}
+
 	switch c.Upstream.Protocol {
 	case "tls":
 		cluster.TlsContext = UpstreamTLSContext(
 			upstreamValidationCACert(c),
 			upstreamValidationSubjectAltName(c),
 		)
+		cluster.TlsContext.Sni = c.SNI
+		upstreamValidationPins(cluster.TlsContext, c.UpstreamValidation)
+		upstreamClientCertificate(cluster.TlsContext, c.ClientCertificate)
 	case "h2":
 		cluster.TlsContext = UpstreamTLSContext(
 			upstreamValidationCACert(c),
 			upstreamValidationSubjectAltName(c),
 			"h2")
+		upstreamValidationPins(cluster.TlsContext, c.UpstreamValidation)
+		upstreamClientCertificate(cluster.TlsContext, c.ClientCertificate)
 		fallthrough
 	case "h2c":
 		cluster.Http2ProtocolOptions = &envoy_api_v2_core.Http2ProtocolOptions{}
@@ -149,20 +184,28 @@ func lbPolicy(strategy string) v2.Cluster_LbPolicy {
 		return v2.Cluster_LEAST_REQUEST
 	case "Random":
 		return v2.Cluster_RANDOM
-	case "Cookie":
+	case "Cookie", "RequestHash":
 		return v2.Cluster_RING_HASH
+	case "Maglev":
+		return v2.Cluster_MAGLEV
 	default:
 		return v2.Cluster_ROUND_ROBIN
 	}
 }
 
 func edshealthcheck(c *dag.Cluster) []*envoy_api_v2_core.HealthCheck {
-	if c.HealthCheckPolicy == nil {
+	switch {
+	case c.HealthCheckPolicy != nil:
+		return []*envoy_api_v2_core.HealthCheck{
+			healthCheck(c),
+		}
+	case c.TCPHealthCheckPolicy != nil:
+		return []*envoy_api_v2_core.HealthCheck{
+			tcpHealthCheck(c),
+		}
+	default:
 		return nil
 	}
-	return []*envoy_api_v2_core.HealthCheck{
-		healthCheck(c),
-	}
 }
 
 // Clustername returns the name of the CDS cluster for this service.
@@ -183,10 +226,50 @@ func Clustername(cluster *dag.Cluster) string {
 			buf += strconv.Itoa(int(hc.HealthyThreshold))
 		}
 		buf += hc.Path
+		for _, sr := range hc.ExpectedStatuses {
+			buf += fmt.Sprintf("status%d-%d", sr.Start, sr.End)
+		}
+		for _, h := range hc.RequestHeaders {
+			buf += "header" + h.Name + ":" + h.Value
+		}
+	}
+	if hc := cluster.TCPHealthCheckPolicy; hc != nil {
+		buf += fmt.Sprintf("tcp%s%s%d%d", hc.Timeout, hc.Interval, hc.UnhealthyThreshold, hc.HealthyThreshold)
+		buf += "send" + hc.Send
+		for _, r := range hc.Receive {
+			buf += "receive" + r
+		}
 	}
 	if uv := cluster.UpstreamValidation; uv != nil {
 		buf += uv.CACertificate.Object.ObjectMeta.Name
 		buf += uv.SubjectName
+		buf += strings.Join(uv.SubjectNames, ",")
+		buf += strings.Join(uv.SPKIPins, ",")
+		buf += strings.Join(uv.CertificateHashes, ",")
+	}
+	if cc := cluster.ClientCertificate; cc != nil {
+		buf += "client" + cc.Namespace() + "/" + cc.Name()
+	}
+	if cluster.IdleTimeout != 0 {
+		buf += "idle" + cluster.IdleTimeout.String()
+	}
+	if cluster.MaxConnectionDuration != 0 {
+		buf += "max" + cluster.MaxConnectionDuration.String()
+	}
+	if cluster.ConnectTimeout != 0 {
+		buf += "connect" + cluster.ConnectTimeout.String()
+	}
+	if k := cluster.TCPKeepalive; k != nil {
+		buf += fmt.Sprintf("keepalive%d%s%s", k.Probes, k.Time, k.Interval)
+	}
+	if cb := cluster.CircuitBreakers; cb != nil {
+		buf += fmt.Sprintf("circuit%d-%d-%d-%d", cb.MaxConnections, cb.MaxPendingRequests, cb.MaxRequests, cb.MaxRetries)
+	}
+	if cluster.LogicalDNS {
+		buf += "logical"
+	}
+	if cluster.HealthyPanicThreshold > 0 {
+		buf += "panic" + strconv.Itoa(int(cluster.HealthyPanicThreshold))
 	}
 
 	hash := sha1.Sum([]byte(buf))
@@ -250,6 +333,33 @@ func min(a, b int) int {
 }
 
 // anyPositive indicates if any of the values provided are greater than zero.
+// circuitBreakers returns the circuit breaking limits of the cluster,
+// those of its policy where set and otherwise those of its service.
+func circuitBreakers(c *dag.Cluster) dag.CircuitBreakers {
+	service := c.Upstream
+	cb := dag.CircuitBreakers{
+		MaxConnections:     service.MaxConnections,
+		MaxPendingRequests: service.MaxPendingRequests,
+		MaxRequests:        service.MaxRequests,
+		MaxRetries:         service.MaxRetries,
+	}
+	if p := c.CircuitBreakers; p != nil {
+		if p.MaxConnections > 0 {
+			cb.MaxConnections = p.MaxConnections
+		}
+		if p.MaxPendingRequests > 0 {
+			cb.MaxPendingRequests = p.MaxPendingRequests
+		}
+		if p.MaxRequests > 0 {
+			cb.MaxRequests = p.MaxRequests
+		}
+		if p.MaxRetries > 0 {
+			cb.MaxRetries = p.MaxRetries
+		}
+	}
+	return cb
+}
+
 func anyPositive(first uint32, rest ...uint32) bool {
 	if first > 0 {
 		return true
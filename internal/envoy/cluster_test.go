@@ -18,6 +18,7 @@ import (
 	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	envoy_cluster "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
@@ -63,6 +64,19 @@ func TestCluster(t *testing.T) {
 		},
 	}
 
+	clientCert := &dag.Secret{
+		Object: &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "client",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				v1.TLSCertKey:       []byte("certificate"),
+				v1.TLSPrivateKeyKey: []byte("key"),
+			},
+		},
+	}
+
 	tests := map[string]struct {
 		cluster *dag.Cluster
 		want    *v2.Cluster
@@ -167,6 +181,76 @@ func TestCluster(t *testing.T) {
 				TlsContext: UpstreamTLSContext([]byte("cacert"), "foo.bar.io"),
 			},
 		},
+		"verify tls upstream with subject names and pins": {
+			cluster: &dag.Cluster{
+				Upstream: service(s1, "tls"),
+				UpstreamValidation: &dag.UpstreamValidation{
+					CACertificate: &dag.Secret{
+						Object: &v1.Secret{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "secret",
+								Namespace: "default",
+							},
+							Data: map[string][]byte{
+								"ca.crt": []byte("cacert"),
+							},
+						},
+					},
+					SubjectName:       "foo.bar.io",
+					SubjectNames:      []string{"foo.bar.internal"},
+					SPKIPins:          []string{"ZPRqdSahhtI0ZVJFOuR4ylEkRnT1shuhUL1IOzn3yBI="},
+					CertificateHashes: []string{"64f46a7526a186d2346552453ae478ca51244674f5b21ba150bd483b39f7c812"},
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/bc6b7dcfce",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				TlsContext: &envoy_api_v2_auth.UpstreamTlsContext{
+					CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
+						ValidationContextType: &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
+							ValidationContext: &envoy_api_v2_auth.CertificateValidationContext{
+								TrustedCa: &envoy_api_v2_core.DataSource{
+									Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+										InlineBytes: []byte("cacert"),
+									},
+								},
+								VerifySubjectAltName:  []string{"foo.bar.io", "foo.bar.internal"},
+								VerifyCertificateSpki: []string{"ZPRqdSahhtI0ZVJFOuR4ylEkRnT1shuhUL1IOzn3yBI="},
+								VerifyCertificateHash: []string{"64f46a7526a186d2346552453ae478ca51244674f5b21ba150bd483b39f7c812"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"tls upstream with client certificate": {
+			cluster: &dag.Cluster{
+				Upstream:          service(s1, "tls"),
+				ClientCertificate: clientCert,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/00d06c5cec",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				TlsContext: &envoy_api_v2_auth.UpstreamTlsContext{
+					CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
+						TlsCertificateSdsSecretConfigs: []*envoy_api_v2_auth.SdsSecretConfig{{
+							Name:      Secretname(clientCert),
+							SdsConfig: ConfigSource("contour"),
+						}},
+					},
+				},
+			},
+		},
 		"projectcontour.io/max-connections": {
 			cluster: &dag.Cluster{
 				Upstream: &dag.Service{
@@ -190,6 +274,36 @@ func TestCluster(t *testing.T) {
 				},
 			},
 		},
+		"circuit breaker policy": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name: s1.Name, Namespace: s1.Namespace,
+					ServicePort:    &s1.Spec.Ports[0],
+					MaxConnections: 9000,
+					MaxRetries:     7,
+				},
+				CircuitBreakers: &dag.CircuitBreakers{
+					MaxConnections: 100,
+					MaxRequests:    200,
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/ceeffd9f30",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CircuitBreakers: &envoy_cluster.CircuitBreakers{
+					Thresholds: []*envoy_cluster.CircuitBreakers_Thresholds{{
+						MaxConnections: protobuf.UInt32(100),
+						MaxRequests:    protobuf.UInt32(200),
+						MaxRetries:     protobuf.UInt32(7),
+					}},
+				},
+			},
+		},
 		"projectcontour.io/max-pending-requests": {
 			cluster: &dag.Cluster{
 				Upstream: &dag.Service{
@@ -336,6 +450,133 @@ func TestCluster(t *testing.T) {
 				}},
 			},
 		},
+		"tcp service with tcp healthcheck": {
+			cluster: &dag.Cluster{
+				Upstream: service(s1),
+				TCPHealthCheckPolicy: &dag.TCPHealthCheckPolicy{
+					Send:    "50494E47",
+					Receive: []string{"504F4E47"},
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/70eaa88b51",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				DrainConnectionsOnHostRemoval: true,
+				HealthChecks: []*envoy_api_v2_core.HealthCheck{{
+					Timeout:            durationOrDefault(0, hcTimeout),
+					Interval:           durationOrDefault(0, hcInterval),
+					UnhealthyThreshold: countOrDefault(0, hcUnhealthyThreshold),
+					HealthyThreshold:   countOrDefault(0, hcHealthyThreshold),
+					HealthChecker: &envoy_api_v2_core.HealthCheck_TcpHealthCheck_{
+						TcpHealthCheck: &envoy_api_v2_core.HealthCheck_TcpHealthCheck{
+							Send:    payload("50494E47"),
+							Receive: []*envoy_api_v2_core.HealthCheck_Payload{payload("504F4E47")},
+						},
+					},
+				}},
+			},
+		},
+		"cluster with idle timeout and max connection duration": {
+			cluster: &dag.Cluster{
+				Upstream:              service(s1),
+				IdleTimeout:           30 * time.Second,
+				MaxConnectionDuration: 5 * time.Minute,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/2c90d61d2d",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+					IdleTimeout:           protobuf.Duration(30 * time.Second),
+					MaxConnectionDuration: protobuf.Duration(5 * time.Minute),
+				},
+			},
+		},
+		"cluster with connect timeout and tcp keepalive": {
+			cluster: &dag.Cluster{
+				Upstream:       service(s1),
+				ConnectTimeout: 1500 * time.Millisecond,
+				TCPKeepalive: &dag.TCPKeepalive{
+					Probes: 3,
+					Time:   5 * time.Minute,
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/168febdcf1",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				ConnectTimeout: protobuf.Duration(1500 * time.Millisecond),
+				UpstreamConnectionOptions: &v2.UpstreamConnectionOptions{
+					TcpKeepalive: &envoy_api_v2_core.TcpKeepalive{
+						KeepaliveProbes: protobuf.UInt32(3),
+						KeepaliveTime:   protobuf.UInt32(300),
+					},
+				},
+			},
+		},
+		"externalName service with logical dns": {
+			cluster: &dag.Cluster{
+				Upstream:   service(s2),
+				LogicalDNS: true,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/38dd669280",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_LOGICAL_DNS),
+				LoadAssignment:       StaticClusterLoadAssignment(service(s2)),
+			},
+		},
+		"cluster with healthy panic threshold": {
+			cluster: &dag.Cluster{
+				Upstream:              service(s1),
+				HealthyPanicThreshold: 50,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/1060b87aca",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CommonLbConfig: &v2.Cluster_CommonLbConfig{
+					HealthyPanicThreshold: &envoy_type.Percent{
+						Value: 50,
+					},
+				},
+			},
+		},
+		"cluster with infinite idle timeout": {
+			cluster: &dag.Cluster{
+				Upstream:    service(s1),
+				IdleTimeout: -1,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/fa8241cc40",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+					IdleTimeout: protobuf.Duration(0),
+				},
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -438,6 +679,61 @@ func TestClustername(t *testing.T) {
 			},
 			want: "default/backend/80/6bf46b7b3a",
 		},
+		"idle timeout and max connection duration": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:      "backend",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Name:       "http",
+						Protocol:   "TCP",
+						Port:       80,
+						TargetPort: intstr.FromInt(6502),
+					},
+				},
+				IdleTimeout:           30 * time.Second,
+				MaxConnectionDuration: 5 * time.Minute,
+			},
+			want: "default/backend/80/2c90d61d2d",
+		},
+		"connect timeout and tcp keepalive": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:      "backend",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Name:       "http",
+						Protocol:   "TCP",
+						Port:       80,
+						TargetPort: intstr.FromInt(6502),
+					},
+				},
+				ConnectTimeout: 2 * time.Second,
+				TCPKeepalive: &dag.TCPKeepalive{
+					Probes: 3,
+					Time:   5 * time.Minute,
+				},
+			},
+			want: "default/backend/80/c4ca6faf5b",
+		},
+		"logical dns and healthy panic threshold": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:         "backend",
+					Namespace:    "default",
+					ExternalName: "api.example.com",
+					ServicePort: &v1.ServicePort{
+						Name:       "http",
+						Protocol:   "TCP",
+						Port:       80,
+						TargetPort: intstr.FromInt(6502),
+					},
+				},
+				LogicalDNS:            true,
+				HealthyPanicThreshold: 50,
+			},
+			want: "default/backend/80/57a90770e5",
+		},
 	}
 
 	for name, tc := range tests {
@@ -454,12 +750,13 @@ func TestLBPolicy(t *testing.T) {
 		"Random":               v2.Cluster_RANDOM,
 		"":                     v2.Cluster_ROUND_ROBIN,
 		"unknown":              v2.Cluster_ROUND_ROBIN,
+		"RequestHash":          v2.Cluster_RING_HASH,
 		"Cookie":               v2.Cluster_RING_HASH,
+		"Maglev":               v2.Cluster_MAGLEV,
 
-		// RingHash and Maglev were removed as options in 0.13.
+		// RingHash was removed as an option in 0.13.
 		// See #1150
 		"RingHash": v2.Cluster_ROUND_ROBIN,
-		"Maglev":   v2.Cluster_ROUND_ROBIN,
 	}
 
 	for policy, want := range tests {
@@ -0,0 +1,65 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	ext_authz "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/ext_authz/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// extAuthzFilter is the name of Envoy's external authorization
+// HTTP filter.
+const extAuthzFilter = "envoy.ext_authz"
+
+// ExtAuthzFilter returns the HTTP filter which authorizes each
+// request with the supplied authorization service.
+func ExtAuthzFilter(as *dag.AuthorizationServer) *http.HttpFilter {
+	grpc := &envoy_api_v2_core.GrpcService{
+		TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+				ClusterName: Clustername(as.Cluster),
+			},
+		},
+	}
+	if as.ResponseTimeout > 0 {
+		grpc.Timeout = protobuf.Duration(as.ResponseTimeout)
+	}
+	return &http.HttpFilter{
+		Name: extAuthzFilter,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: toAny(&ext_authz.ExtAuthz{
+				Services: &ext_authz.ExtAuthz_GrpcService{
+					GrpcService: grpc,
+				},
+				FailureModeAllow: as.FailOpen,
+			}),
+		},
+	}
+}
+
+// ExtAuthzDisabled returns the per filter config of a route
+// whose requests are not authorized.
+func ExtAuthzDisabled() map[string]*any.Any {
+	return map[string]*any.Any{
+		extAuthzFilter: toAny(&ext_authz.ExtAuthzPerRoute{
+			Override: &ext_authz.ExtAuthzPerRoute_Disabled{
+				Disabled: true,
+			},
+		}),
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"strings"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	lua "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+// requestHeadersAllowListKey is the key, in the envoy.lua route
+// metadata, of the set of request headers permitted on a route.
+const requestHeadersAllowListKey = "request-headers-allow-list"
+
+// requestHeadersAllowListScript removes every request header, other
+// than pseudo headers, not present in the route's allow list. Routes
+// without an allow list are left untouched.
+const requestHeadersAllowListScript = `function envoy_on_request(request_handle)
+  local allowed = request_handle:metadata():get("` + requestHeadersAllowListKey + `")
+  if allowed == nil then
+    return
+  end
+  local remove = {}
+  for key, _ in pairs(request_handle:headers()) do
+    if string.sub(key, 1, 1) ~= ":" and allowed[key] == nil then
+      table.insert(remove, key)
+    end
+  end
+  for _, key in ipairs(remove) do
+    request_handle:headers():remove(key)
+  end
+end
+`
+
+// requiredRequestHeaders are always permitted by a request header
+// allow list as Envoy, or the backend, requires them to proxy the request.
+var requiredRequestHeaders = []string{
+	"content-length",
+	"content-type",
+	"transfer-encoding",
+	"x-forwarded-for",
+	"x-forwarded-proto",
+	"x-request-id",
+}
+
+// RequestHeadersAllowListFilter returns the HTTP filter which enforces
+// the request header allow list attached to a route's metadata.
+func RequestHeadersAllowListFilter() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: wellknown.Lua,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: toAny(&lua.Lua{
+				InlineCode: requestHeadersAllowListScript,
+			}),
+		},
+	}
+}
+
+// RequestHeadersAllowListMetadata returns the route metadata which
+// restricts the request headers forwarded to the backend to the
+// supplied names.
+func RequestHeadersAllowListMetadata(headers []string) *envoy_api_v2_core.Metadata {
+	allowed := make(map[string]*_struct.Value)
+	allow := func(h string) {
+		allowed[strings.ToLower(h)] = &_struct.Value{
+			Kind: &_struct.Value_BoolValue{BoolValue: true},
+		}
+	}
+	for _, h := range requiredRequestHeaders {
+		allow(h)
+	}
+	for _, h := range headers {
+		allow(h)
+	}
+	return &envoy_api_v2_core.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			wellknown.Lua: {
+				Fields: map[string]*_struct.Value{
+					requestHeadersAllowListKey: {
+						Kind: &_struct.Value_StructValue{
+							StructValue: &_struct.Struct{Fields: allowed},
+						},
+					},
+				},
+			},
+		},
+	}
+}
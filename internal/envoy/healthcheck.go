@@ -17,6 +17,7 @@ import (
 	"time"
 
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/projectcontour/contour/internal/dag"
@@ -40,6 +41,18 @@ func healthCheck(cluster *dag.Cluster) *envoy_api_v2_core.HealthCheck {
 		host = hc.Host
 	}
 
+	var statuses []*envoy_type.Int64Range
+	for _, sr := range hc.ExpectedStatuses {
+		statuses = append(statuses, &envoy_type.Int64Range{
+			Start: int64(sr.Start),
+			End:   int64(sr.End),
+		})
+	}
+	var headers []*envoy_api_v2_core.HeaderValueOption
+	for _, h := range hc.RequestHeaders {
+		headers = append(headers, SetHeader(h.Name, h.Value))
+	}
+
 	// TODO(dfc) why do we need to specify our own default, what is the default
 	// that envoy applies if these fields are left nil?
 	return &envoy_api_v2_core.HealthCheck{
@@ -49,13 +62,47 @@ func healthCheck(cluster *dag.Cluster) *envoy_api_v2_core.HealthCheck {
 		HealthyThreshold:   countOrDefault(hc.HealthyThreshold, hcHealthyThreshold),
 		HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
 			HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
-				Path: hc.Path,
-				Host: host,
+				Path:                hc.Path,
+				Host:                host,
+				ExpectedStatuses:    statuses,
+				RequestHeadersToAdd: headers,
 			},
 		},
 	}
 }
 
+// tcpHealthCheck returns a *envoy_api_v2_core.HealthCheck value
+// for a TCP health check.
+func tcpHealthCheck(cluster *dag.Cluster) *envoy_api_v2_core.HealthCheck {
+	hc := cluster.TCPHealthCheckPolicy
+	tcp := &envoy_api_v2_core.HealthCheck_TcpHealthCheck{}
+	if hc.Send != "" {
+		tcp.Send = payload(hc.Send)
+	}
+	for _, r := range hc.Receive {
+		tcp.Receive = append(tcp.Receive, payload(r))
+	}
+
+	return &envoy_api_v2_core.HealthCheck{
+		Timeout:            durationOrDefault(hc.Timeout, hcTimeout),
+		Interval:           durationOrDefault(hc.Interval, hcInterval),
+		UnhealthyThreshold: countOrDefault(hc.UnhealthyThreshold, hcUnhealthyThreshold),
+		HealthyThreshold:   countOrDefault(hc.HealthyThreshold, hcHealthyThreshold),
+		HealthChecker: &envoy_api_v2_core.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: tcp,
+		},
+	}
+}
+
+// payload returns a health check payload of the supplied hex encoded text.
+func payload(text string) *envoy_api_v2_core.HealthCheck_Payload {
+	return &envoy_api_v2_core.HealthCheck_Payload{
+		Payload: &envoy_api_v2_core.HealthCheck_Payload_Text{
+			Text: text,
+		},
+	}
+}
+
 func durationOrDefault(d, def time.Duration) *duration.Duration {
 	if d != 0 {
 		return protobuf.Duration(d)
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	"github.com/google/go-cmp/cmp"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/protobuf"
@@ -90,6 +91,39 @@ func TestHealthCheck(t *testing.T) {
 				},
 			},
 		},
+		"healthcheck expected statuses and request headers": {
+			cluster: &dag.Cluster{
+				HealthCheckPolicy: &dag.HealthCheckPolicy{
+					Path: "/healthy",
+					ExpectedStatuses: []dag.StatusRange{
+						{Start: 200, End: 300},
+						{Start: 301, End: 302},
+					},
+					RequestHeaders: []dag.HeaderValue{
+						{Name: "X-Health-Token", Value: "secret"},
+					},
+				},
+			},
+			want: &envoy_api_v2_core.HealthCheck{
+				Timeout:            protobuf.Duration(hcTimeout),
+				Interval:           protobuf.Duration(hcInterval),
+				UnhealthyThreshold: protobuf.UInt32(3),
+				HealthyThreshold:   protobuf.UInt32(2),
+				HealthChecker: &envoy_api_v2_core.HealthCheck_HttpHealthCheck_{
+					HttpHealthCheck: &envoy_api_v2_core.HealthCheck_HttpHealthCheck{
+						Path: "/healthy",
+						Host: "contour-envoy-healthcheck",
+						ExpectedStatuses: []*envoy_type.Int64Range{
+							{Start: 200, End: 300},
+							{Start: 301, End: 302},
+						},
+						RequestHeadersToAdd: []*envoy_api_v2_core.HeaderValueOption{
+							SetHeader("X-Health-Token", "secret"),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -102,3 +136,61 @@ func TestHealthCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestTCPHealthCheck(t *testing.T) {
+	tests := map[string]struct {
+		cluster *dag.Cluster
+		want    *envoy_api_v2_core.HealthCheck
+	}{
+		"connect only": {
+			cluster: &dag.Cluster{
+				TCPHealthCheckPolicy: new(dag.TCPHealthCheckPolicy),
+			},
+			want: &envoy_api_v2_core.HealthCheck{
+				Timeout:            protobuf.Duration(hcTimeout),
+				Interval:           protobuf.Duration(hcInterval),
+				UnhealthyThreshold: protobuf.UInt32(3),
+				HealthyThreshold:   protobuf.UInt32(2),
+				HealthChecker: &envoy_api_v2_core.HealthCheck_TcpHealthCheck_{
+					TcpHealthCheck: &envoy_api_v2_core.HealthCheck_TcpHealthCheck{},
+				},
+			},
+		},
+		"send and receive": {
+			cluster: &dag.Cluster{
+				TCPHealthCheckPolicy: &dag.TCPHealthCheckPolicy{
+					Timeout:            5 * time.Second,
+					Interval:           7 * time.Second,
+					UnhealthyThreshold: 4,
+					HealthyThreshold:   1,
+					Send:               "50494E470D0A",
+					Receive:            []string{"2B504F4E47", "0D0A"},
+				},
+			},
+			want: &envoy_api_v2_core.HealthCheck{
+				Timeout:            protobuf.Duration(5 * time.Second),
+				Interval:           protobuf.Duration(7 * time.Second),
+				UnhealthyThreshold: protobuf.UInt32(4),
+				HealthyThreshold:   protobuf.UInt32(1),
+				HealthChecker: &envoy_api_v2_core.HealthCheck_TcpHealthCheck_{
+					TcpHealthCheck: &envoy_api_v2_core.HealthCheck_TcpHealthCheck{
+						Send: payload("50494E470D0A"),
+						Receive: []*envoy_api_v2_core.HealthCheck_Payload{
+							payload("2B504F4E47"),
+							payload("0D0A"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tcpHealthCheck(tc.cluster)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"time"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	jwt_authn "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/jwt_authn/v2alpha"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// jwtAuthnFilter is the name of Envoy's JWT authentication
+// HTTP filter.
+const jwtAuthnFilter = "envoy.filters.http.jwt_authn"
+
+// JWTRule is the JWT provider required by requests which match a route.
+// A blank Provider requires no JWT.
+type JWTRule struct {
+	Match    *envoy_api_v2_route.RouteMatch
+	Provider string
+}
+
+// JWTAuthnFilter returns the HTTP filter which verifies the JWTs of
+// requests with the supplied providers. The first of rules which
+// matches a request determines the provider whose JWT it requires,
+// so rules must be in the order of the routes they match.
+func JWTAuthnFilter(providers []*dag.JWTProvider, rules []JWTRule) *http.HttpFilter {
+	config := &jwt_authn.JwtAuthentication{
+		Providers: make(map[string]*jwt_authn.JwtProvider, len(providers)),
+	}
+	for _, p := range providers {
+		config.Providers[p.Name] = jwtProvider(p)
+	}
+	for _, r := range rules {
+		rule := &jwt_authn.RequirementRule{
+			Match: r.Match,
+		}
+		if r.Provider != "" {
+			rule.Requires = &jwt_authn.JwtRequirement{
+				RequiresType: &jwt_authn.JwtRequirement_ProviderName{
+					ProviderName: r.Provider,
+				},
+			}
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return &http.HttpFilter{
+		Name: jwtAuthnFilter,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: toAny(config),
+		},
+	}
+}
+
+func jwtProvider(p *dag.JWTProvider) *jwt_authn.JwtProvider {
+	timeout := p.RemoteJWKS.Timeout
+	if timeout == 0 {
+		// Envoy requires the timeout of the fetch be set.
+		timeout = time.Second
+	}
+	jwks := &jwt_authn.RemoteJwks{
+		HttpUri: &envoy_api_v2_core.HttpUri{
+			Uri: p.RemoteJWKS.URI,
+			HttpUpstreamType: &envoy_api_v2_core.HttpUri_Cluster{
+				Cluster: Clustername(p.RemoteJWKS.Cluster),
+			},
+			Timeout: protobuf.Duration(timeout),
+		},
+	}
+	if p.RemoteJWKS.CacheDuration > 0 {
+		jwks.CacheDuration = protobuf.Duration(p.RemoteJWKS.CacheDuration)
+	}
+	return &jwt_authn.JwtProvider{
+		Issuer:    p.Issuer,
+		Audiences: p.Audiences,
+		JwksSourceSpecifier: &jwt_authn.JwtProvider_RemoteJwks{
+			RemoteJwks: jwks,
+		},
+		Forward: p.ForwardJWT,
+	}
+}
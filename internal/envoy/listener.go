@@ -46,6 +46,21 @@ func ProxyProtocol() *envoy_api_v2_listener.ListenerFilter {
 	}
 }
 
+// ConnectionBalanceConfig returns the connection balance config for
+// the named balancer. The only balancer is "exact", which balances
+// new connections exactly across Envoy's worker threads. For any
+// other name nil is returned, and Envoy's default applies.
+func ConnectionBalanceConfig(balancer string) *v2.Listener_ConnectionBalanceConfig {
+	if balancer != "exact" {
+		return nil
+	}
+	return &v2.Listener_ConnectionBalanceConfig{
+		BalanceType: &v2.Listener_ConnectionBalanceConfig_ExactBalance_{
+			ExactBalance: &v2.Listener_ConnectionBalanceConfig_ExactBalance{},
+		},
+	}
+}
+
 // Listener returns a new v2.Listener for the supplied address, port, and filters.
 func Listener(name, address string, port int, lf []*envoy_api_v2_listener.ListenerFilter, filters ...*envoy_api_v2_listener.Filter) *v2.Listener {
 	l := &v2.Listener{
@@ -64,59 +79,128 @@ func Listener(name, address string, port int, lf []*envoy_api_v2_listener.Listen
 	return l
 }
 
+// HTTP1Options holds the HTTP/1 protocol options of a
+// HTTP Connection Manager.
+type HTTP1Options struct {
+	// DisableHTTP10 rejects HTTP/1.0 requests.
+	DisableHTTP10 bool
+
+	// DefaultHostForHTTP10 is the host assumed for HTTP/1.0
+	// requests which do not carry a Host: header. If empty
+	// these requests are rejected.
+	DefaultHostForHTTP10 string
+
+	// AllowAbsoluteURL permits requests whose request target
+	// is an absolute URL, as sent to a forward proxy.
+	AllowAbsoluteURL bool
+}
+
+// http1ProtocolOptions returns the Envoy HTTP/1 protocol options
+// for the supplied options.
+func http1ProtocolOptions(opts HTTP1Options) *envoy_api_v2_core.Http1ProtocolOptions {
+	po := &envoy_api_v2_core.Http1ProtocolOptions{
+		// Enable support for HTTP/1.0 requests that carry
+		// a Host: header. See #537.
+		AcceptHttp_10: !opts.DisableHTTP10,
+	}
+	if po.AcceptHttp_10 {
+		po.DefaultHostForHttp_10 = opts.DefaultHostForHTTP10
+	}
+	if opts.AllowAbsoluteURL {
+		po.AllowAbsoluteUrl = protobuf.Bool(true)
+	}
+	return po
+}
+
 // HTTPConnectionManager creates a new HTTP Connection Manager filter
-// for the supplied route, access log, and client request timeout.
-func HTTPConnectionManager(routename string, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration) *envoy_api_v2_listener.Filter {
+// for the supplied route, access log, client request timeout, HTTP/1
+// protocol options, and number of trusted X-Forwarded-For hops. Any
+// additional filters supplied are inserted ahead of the router filter.
+func HTTPConnectionManager(routename string, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration, http1 HTTP1Options, xffTrustedHops uint32, filters ...*http.HttpFilter) *envoy_api_v2_listener.Filter {
+	return RoutedHTTPConnectionManager(routename, routename, accesslogger, requestTimeout, http1, xffTrustedHops, filters...)
+}
 
+// RoutedHTTPConnectionManager is as HTTPConnectionManager, but records
+// its statistics under statPrefix rather than the name of its route.
+func RoutedHTTPConnectionManager(statPrefix, routename string, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration, http1 HTTP1Options, xffTrustedHops uint32, filters ...*http.HttpFilter) *envoy_api_v2_listener.Filter {
+	return httpConnectionManagerFilter(httpConnectionManager(statPrefix, routename, accesslogger, requestTimeout, http1, xffTrustedHops, filters...))
+}
+
+// ClientCertificateHTTPConnectionManager is as RoutedHTTPConnectionManager,
+// but replaces the X-Forwarded-Client-Cert header of each request with the
+// hash, and the supplied details, of the client's certificate.
+func ClientCertificateHTTPConnectionManager(statPrefix, routename string, details *dag.ClientCertificateDetails, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration, http1 HTTP1Options, xffTrustedHops uint32, filters ...*http.HttpFilter) *envoy_api_v2_listener.Filter {
+	hcm := httpConnectionManager(statPrefix, routename, accesslogger, requestTimeout, http1, xffTrustedHops, filters...)
+	hcm.ForwardClientCertDetails = http.HttpConnectionManager_SANITIZE_SET
+	hcm.SetCurrentClientCertDetails = &http.HttpConnectionManager_SetCurrentClientCertDetails{
+		Cert:  details.Cert,
+		Chain: details.Chain,
+		Dns:   details.DNS,
+		Uri:   details.URI,
+	}
+	if details.Subject {
+		hcm.SetCurrentClientCertDetails.Subject = protobuf.Bool(true)
+	}
+	return httpConnectionManagerFilter(hcm)
+}
+
+func httpConnectionManagerFilter(hcm *http.HttpConnectionManager) *envoy_api_v2_listener.Filter {
 	return &envoy_api_v2_listener.Filter{
 		Name: wellknown.HTTPConnectionManager,
 		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
-			TypedConfig: toAny(&http.HttpConnectionManager{
-				StatPrefix: routename,
-				RouteSpecifier: &http.HttpConnectionManager_Rds{
-					Rds: &http.Rds{
-						RouteConfigName: routename,
-						ConfigSource: &envoy_api_v2_core.ConfigSource{
-							ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
-								ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
-									ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
-									GrpcServices: []*envoy_api_v2_core.GrpcService{{
-										TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
-											EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
-												ClusterName: "contour",
-											},
-										},
-									}},
+			TypedConfig: toAny(hcm),
+		},
+	}
+}
+
+func httpConnectionManager(statPrefix, routename string, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration, http1 HTTP1Options, xffTrustedHops uint32, filters ...*http.HttpFilter) *http.HttpConnectionManager {
+	httpFilters := []*http.HttpFilter{{
+		Name: wellknown.Gzip,
+	}, {
+		Name: wellknown.GRPCWeb,
+	}, {
+		Name: wellknown.CORS,
+	}}
+	httpFilters = append(httpFilters, filters...)
+	httpFilters = append(httpFilters, &http.HttpFilter{
+		Name: wellknown.Router,
+	})
+
+	return &http.HttpConnectionManager{
+		StatPrefix: statPrefix,
+		RouteSpecifier: &http.HttpConnectionManager_Rds{
+			Rds: &http.Rds{
+				RouteConfigName: routename,
+				ConfigSource: &envoy_api_v2_core.ConfigSource{
+					ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
+						ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
+							ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
+							GrpcServices: []*envoy_api_v2_core.GrpcService{{
+								TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+										ClusterName: "contour",
+									},
 								},
-							},
+							}},
 						},
 					},
 				},
-				HttpFilters: []*http.HttpFilter{{
-					Name: wellknown.Gzip,
-				}, {
-					Name: wellknown.GRPCWeb,
-				}, {
-					Name: wellknown.Router,
-				}},
-				HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
-					// Enable support for HTTP/1.0 requests that carry
-					// a Host: header. See #537.
-					AcceptHttp_10: true,
-				},
-				AccessLog:        accesslogger,
-				UseRemoteAddress: protobuf.Bool(true),
-				NormalizePath:    protobuf.Bool(true),
-				// Sets the idle timeout for HTTP connections to 60 seconds.
-				// This is chosen as a rough default to stop idle connections wasting resources,
-				// without stopping slow connections from being terminated too quickly.
-				IdleTimeout:    protobuf.Duration(60 * time.Second),
-				RequestTimeout: ptypes.DurationProto(requestTimeout),
-
-				// issue #1487 pass through X-Request-Id if provided.
-				PreserveExternalRequestId: true,
-			}),
+			},
 		},
+		HttpFilters:         httpFilters,
+		HttpProtocolOptions: http1ProtocolOptions(http1),
+		AccessLog:           accesslogger,
+		UseRemoteAddress:    protobuf.Bool(true),
+		XffNumTrustedHops:   xffTrustedHops,
+		NormalizePath:       protobuf.Bool(true),
+		// Sets the idle timeout for HTTP connections to 60 seconds.
+		// This is chosen as a rough default to stop idle connections wasting resources,
+		// without stopping slow connections from being terminated too quickly.
+		IdleTimeout:    protobuf.Duration(60 * time.Second),
+		RequestTimeout: ptypes.DurationProto(requestTimeout),
+
+		// issue #1487 pass through X-Request-Id if provided.
+		PreserveExternalRequestId: true,
 	}
 }
 
@@ -240,7 +324,7 @@ func FilterChains(filters ...*envoy_api_v2_listener.Filter) []*envoy_api_v2_list
 }
 
 // FilterChainTLS returns a TLS enabled envoy_api_v2_listener.FilterChain,
-func FilterChainTLS(domain string, secret *dag.Secret, filters []*envoy_api_v2_listener.Filter, tlsMinProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, alpnProtos ...string) *envoy_api_v2_listener.FilterChain {
+func FilterChainTLS(domain string, secret *dag.Secret, peerValidation *dag.DownstreamValidation, filters []*envoy_api_v2_listener.Filter, tlsMinProtoVersion, tlsMaxProtoVersion envoy_api_v2_auth.TlsParameters_TlsProtocol, cipherSuites []string, alpnProtos ...string) *envoy_api_v2_listener.FilterChain {
 	fc := &envoy_api_v2_listener.FilterChain{
 		Filters: filters,
 		FilterChainMatch: &envoy_api_v2_listener.FilterChainMatch{
@@ -249,7 +333,7 @@ func FilterChainTLS(domain string, secret *dag.Secret, filters []*envoy_api_v2_l
 	}
 	// attach certificate data to this listener if provided.
 	if secret != nil {
-		fc.TlsContext = DownstreamTLSContext(Secretname(secret), tlsMinProtoVersion, alpnProtos...)
+		fc.TlsContext = DownstreamTLSContext(Secretname(secret), tlsMinProtoVersion, tlsMaxProtoVersion, cipherSuites, peerValidation, alpnProtos...)
 	}
 	return fc
 }
@@ -259,10 +343,16 @@ func ListenerFilters(filters ...*envoy_api_v2_listener.ListenerFilter) []*envoy_
 	return filters
 }
 
+// toAny marshals pb into an *any.Any. It is marshalled deterministically,
+// so identical resources embedding it have identical encodings.
 func toAny(pb proto.Message) *any.Any {
-	a, err := ptypes.MarshalAny(pb)
-	if err != nil {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(pb); err != nil {
 		panic(err.Error())
 	}
-	return a
+	return &any.Any{
+		TypeUrl: "type.googleapis.com/" + proto.MessageName(pb),
+		Value:   buf.Bytes(),
+	}
 }
@@ -46,13 +46,13 @@ func TestListener(t *testing.T) {
 			address: "0.0.0.0",
 			port:    9000,
 			f: []*envoy_api_v2_listener.Filter{
-				HTTPConnectionManager("http", FileAccessLogEnvoy("/dev/null"), 0),
+				HTTPConnectionManager("http", FileAccessLogEnvoy("/dev/null"), 0, HTTP1Options{}, 0),
 			},
 			want: &v2.Listener{
 				Name:    "http",
 				Address: SocketAddress("0.0.0.0", 9000),
 				FilterChains: FilterChains(
-					HTTPConnectionManager("http", FileAccessLogEnvoy("/dev/null"), 0),
+					HTTPConnectionManager("http", FileAccessLogEnvoy("/dev/null"), 0, HTTP1Options{}, 0),
 				),
 			},
 		},
@@ -64,7 +64,7 @@ func TestListener(t *testing.T) {
 				ProxyProtocol(),
 			},
 			f: []*envoy_api_v2_listener.Filter{
-				HTTPConnectionManager("http-proxy", FileAccessLogEnvoy("/dev/null"), 0),
+				HTTPConnectionManager("http-proxy", FileAccessLogEnvoy("/dev/null"), 0, HTTP1Options{}, 0),
 			},
 			want: &v2.Listener{
 				Name:    "http-proxy",
@@ -73,7 +73,7 @@ func TestListener(t *testing.T) {
 					ProxyProtocol(),
 				),
 				FilterChains: FilterChains(
-					HTTPConnectionManager("http-proxy", FileAccessLogEnvoy("/dev/null"), 0),
+					HTTPConnectionManager("http-proxy", FileAccessLogEnvoy("/dev/null"), 0, HTTP1Options{}, 0),
 				),
 			},
 		},
@@ -160,7 +160,7 @@ func TestSocketAddress(t *testing.T) {
 func TestDownstreamTLSContext(t *testing.T) {
 	const secretName = "default/tls-cert"
 
-	got := DownstreamTLSContext(secretName, envoy_api_v2_auth.TlsParameters_TLSv1_1, "h2", "http/1.1")
+	got := DownstreamTLSContext(secretName, envoy_api_v2_auth.TlsParameters_TLSv1_1, envoy_api_v2_auth.TlsParameters_TLSv1_3, nil, nil, "h2", "http/1.1")
 	want := &envoy_api_v2_auth.DownstreamTlsContext{
 		CommonTlsContext: &envoy_api_v2_auth.CommonTlsContext{
 			TlsParams: &envoy_api_v2_auth.TlsParameters{
@@ -198,6 +198,90 @@ func TestDownstreamTLSContext(t *testing.T) {
 		},
 	}
 	assert.Equal(t, want, got)
+
+	// cipher suites given replace the defaults.
+	got = DownstreamTLSContext(secretName, envoy_api_v2_auth.TlsParameters_TLSv1_2, envoy_api_v2_auth.TlsParameters_TLSv1_2, []string{"ECDHE-RSA-AES256-GCM-SHA384"}, nil, "h2", "http/1.1")
+	want.CommonTlsContext.TlsParams = &envoy_api_v2_auth.TlsParameters{
+		TlsMinimumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_2,
+		TlsMaximumProtocolVersion: envoy_api_v2_auth.TlsParameters_TLSv1_2,
+		CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestDownstreamTLSContextPeerValidation(t *testing.T) {
+	secret := func(key, value string) *dag.Secret {
+		return &dag.Secret{
+			Object: &v1.Secret{
+				Data: map[string][]byte{
+					key: []byte(value),
+				},
+			},
+		}
+	}
+	inline := func(value string) *envoy_api_v2_core.DataSource {
+		return &envoy_api_v2_core.DataSource{
+			Specifier: &envoy_api_v2_core.DataSource_InlineBytes{
+				InlineBytes: []byte(value),
+			},
+		}
+	}
+	tests := map[string]struct {
+		peerValidation *dag.DownstreamValidation
+		want           *envoy_api_v2_auth.CertificateValidationContext
+		required       bool
+	}{
+		"required": {
+			peerValidation: &dag.DownstreamValidation{
+				CACertificate: secret(CACertificateKey, "ca"),
+			},
+			want: &envoy_api_v2_auth.CertificateValidationContext{
+				TrustedCa: inline("ca"),
+			},
+			required: true,
+		},
+		"optional": {
+			peerValidation: &dag.DownstreamValidation{
+				CACertificate: secret(CACertificateKey, "ca"),
+				Optional:      true,
+			},
+			want: &envoy_api_v2_auth.CertificateValidationContext{
+				TrustedCa: inline("ca"),
+			},
+			required: false,
+		},
+		"certificate revocation list": {
+			peerValidation: &dag.DownstreamValidation{
+				CACertificate:             secret(CACertificateKey, "ca"),
+				CertificateRevocationList: secret(CRLKey, "crl"),
+			},
+			want: &envoy_api_v2_auth.CertificateValidationContext{
+				TrustedCa: inline("ca"),
+				Crl:       inline("crl"),
+			},
+			required: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DownstreamTLSContext("default/tls-cert", envoy_api_v2_auth.TlsParameters_TLSv1_1, envoy_api_v2_auth.TlsParameters_TLSv1_3, nil, tc.peerValidation)
+			assert.Equal(t, protobuf.Bool(tc.required), got.RequireClientCertificate)
+			assert.Equal(t, &envoy_api_v2_auth.CommonTlsContext_ValidationContext{
+				ValidationContext: tc.want,
+			}, got.CommonTlsContext.ValidationContextType)
+		})
+	}
+}
+
+func TestConnectionBalanceConfig(t *testing.T) {
+	want := &v2.Listener_ConnectionBalanceConfig{
+		BalanceType: &v2.Listener_ConnectionBalanceConfig_ExactBalance_{
+			ExactBalance: &v2.Listener_ConnectionBalanceConfig_ExactBalance{},
+		},
+	}
+	assert.Equal(t, want, ConnectionBalanceConfig("exact"))
+	assert.Equal(t, (*v2.Listener_ConnectionBalanceConfig)(nil), ConnectionBalanceConfig(""))
 }
 
 func TestHTTPConnectionManager(t *testing.T) {
@@ -205,6 +289,8 @@ func TestHTTPConnectionManager(t *testing.T) {
 		routename      string
 		accesslogger   []*envoy_api_v2_accesslog.AccessLog
 		requestTimeout time.Duration
+		http1          HTTP1Options
+		xffTrustedHops uint32
 		want           *envoy_api_v2_listener.Filter
 	}{
 		"default": {
@@ -239,6 +325,8 @@ func TestHTTPConnectionManager(t *testing.T) {
 							Name: wellknown.Gzip,
 						}, {
 							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.CORS,
 						}, {
 							Name: wellknown.Router,
 						}},
@@ -289,6 +377,8 @@ func TestHTTPConnectionManager(t *testing.T) {
 							Name: wellknown.Gzip,
 						}, {
 							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.CORS,
 						}, {
 							Name: wellknown.Router,
 						}},
@@ -307,10 +397,170 @@ func TestHTTPConnectionManager(t *testing.T) {
 				},
 			},
 		},
+		"http/1.0 default host and absolute urls": {
+			routename:      "default/kuard",
+			accesslogger:   FileAccessLogEnvoy("/dev/stdout"),
+			requestTimeout: 0,
+			http1: HTTP1Options{
+				DefaultHostForHTTP10: "example.com",
+				AllowAbsoluteURL:     true,
+			},
+			want: &envoy_api_v2_listener.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+					TypedConfig: toAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_api_v2_core.ConfigSource{
+									ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
+											ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
+											GrpcServices: []*envoy_api_v2_core.GrpcService{{
+												TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: wellknown.Gzip,
+						}, {
+							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.CORS,
+						}, {
+							Name: wellknown.Router,
+						}},
+						HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
+							AcceptHttp_10:         true,
+							DefaultHostForHttp_10: "example.com",
+							AllowAbsoluteUrl:      protobuf.Bool(true),
+						},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						NormalizePath:             protobuf.Bool(true),
+						IdleTimeout:               protobuf.Duration(60 * time.Second),
+						RequestTimeout:            protobuf.Duration(0),
+						PreserveExternalRequestId: true,
+					}),
+				},
+			},
+		},
+		"http/1.0 disabled": {
+			routename:      "default/kuard",
+			accesslogger:   FileAccessLogEnvoy("/dev/stdout"),
+			requestTimeout: 0,
+			http1: HTTP1Options{
+				DisableHTTP10:        true,
+				DefaultHostForHTTP10: "example.com",
+			},
+			want: &envoy_api_v2_listener.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+					TypedConfig: toAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_api_v2_core.ConfigSource{
+									ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
+											ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
+											GrpcServices: []*envoy_api_v2_core.GrpcService{{
+												TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: wellknown.Gzip,
+						}, {
+							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.CORS,
+						}, {
+							Name: wellknown.Router,
+						}},
+						HttpProtocolOptions:       &envoy_api_v2_core.Http1ProtocolOptions{},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						NormalizePath:             protobuf.Bool(true),
+						IdleTimeout:               protobuf.Duration(60 * time.Second),
+						RequestTimeout:            protobuf.Duration(0),
+						PreserveExternalRequestId: true,
+					}),
+				},
+			},
+		},
+		"trusted x-forwarded-for hop": {
+			routename:      "default/kuard",
+			accesslogger:   FileAccessLogEnvoy("/dev/stdout"),
+			requestTimeout: 0,
+			xffTrustedHops: 1,
+			want: &envoy_api_v2_listener.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+					TypedConfig: toAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_api_v2_core.ConfigSource{
+									ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
+											ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
+											GrpcServices: []*envoy_api_v2_core.GrpcService{{
+												TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: wellknown.Gzip,
+						}, {
+							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.CORS,
+						}, {
+							Name: wellknown.Router,
+						}},
+						HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
+							AcceptHttp_10: true,
+						},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						XffNumTrustedHops:         1,
+						NormalizePath:             protobuf.Bool(true),
+						IdleTimeout:               protobuf.Duration(60 * time.Second),
+						RequestTimeout:            protobuf.Duration(0),
+						PreserveExternalRequestId: true,
+					}),
+				},
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			got := HTTPConnectionManager(tc.routename, tc.accesslogger, tc.requestTimeout)
+			got := HTTPConnectionManager(tc.routename, tc.accesslogger, tc.requestTimeout, tc.http1, tc.xffTrustedHops)
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -0,0 +1,80 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+// listenersMetadataKey is the key in Envoy's node metadata which holds
+// the listener addresses requested by that Envoy.
+const listenersMetadataKey = "listeners"
+
+// ListenerAddress overrides the address and port an Envoy binds a
+// listener to. A blank Address or zero Port leaves the value Contour
+// is configured with unchanged.
+type ListenerAddress struct {
+	Address string
+	Port    int
+}
+
+// NodeMetadata returns the node metadata with which an Envoy requests
+// that the named listeners bind to the supplied addresses, or nil if
+// there are no addresses to request.
+func NodeMetadata(listeners map[string]ListenerAddress) *_struct.Struct {
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]*_struct.Value)
+	for name, la := range listeners {
+		addr := make(map[string]*_struct.Value)
+		if la.Address != "" {
+			addr["address"] = sv(la.Address)
+		}
+		if la.Port != 0 {
+			addr["port"] = &_struct.Value{
+				Kind: &_struct.Value_NumberValue{NumberValue: float64(la.Port)},
+			}
+		}
+		fields[name] = &_struct.Value{
+			Kind: &_struct.Value_StructValue{StructValue: &_struct.Struct{Fields: addr}},
+		}
+	}
+
+	return &_struct.Struct{
+		Fields: map[string]*_struct.Value{
+			listenersMetadataKey: {
+				Kind: &_struct.Value_StructValue{StructValue: &_struct.Struct{Fields: fields}},
+			},
+		},
+	}
+}
+
+// NodeListenerAddress returns the address the node has requested for
+// the named listener, if any.
+func NodeListenerAddress(node *envoy_api_v2_core.Node, name string) (ListenerAddress, bool) {
+	listeners := node.GetMetadata().GetFields()[listenersMetadataKey].GetStructValue()
+	v, ok := listeners.GetFields()[name]
+	if !ok {
+		return ListenerAddress{}, false
+	}
+	fields := v.GetStructValue().GetFields()
+	la := ListenerAddress{
+		Address: fields["address"].GetStringValue(),
+		Port:    int(fields["port"].GetNumberValue()),
+	}
+	return la, la.Address != "" || la.Port != 0
+}
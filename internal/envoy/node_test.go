@@ -0,0 +1,80 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"testing"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNodeListenerAddress(t *testing.T) {
+	node := &envoy_api_v2_core.Node{
+		Metadata: NodeMetadata(map[string]ListenerAddress{
+			"ingress_http":  {Port: 80},
+			"ingress_https": {Address: "::", Port: 443},
+		}),
+	}
+
+	tests := map[string]struct {
+		node   *envoy_api_v2_core.Node
+		name   string
+		want   ListenerAddress
+		wantOK bool
+	}{
+		"nil node": {
+			node: nil,
+			name: "ingress_http",
+		},
+		"no metadata": {
+			node: &envoy_api_v2_core.Node{Id: "envoy"},
+			name: "ingress_http",
+		},
+		"port only": {
+			node:   node,
+			name:   "ingress_http",
+			want:   ListenerAddress{Port: 80},
+			wantOK: true,
+		},
+		"address and port": {
+			node:   node,
+			name:   "ingress_https",
+			want:   ListenerAddress{Address: "::", Port: 443},
+			wantOK: true,
+		},
+		"unknown listener": {
+			node: node,
+			name: "stats-health",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := NodeListenerAddress(tc.node, tc.name)
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok: %v, got %v", tc.wantOK, ok)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestNodeMetadataEmpty(t *testing.T) {
+	if got := NodeMetadata(nil); got != nil {
+		t.Fatalf("expected nil, got %v", got)
+	}
+}
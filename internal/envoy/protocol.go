@@ -0,0 +1,80 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"strconv"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	lua "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+// downstreamProtocolKey is the key, in the envoy.lua route metadata,
+// of the protocol a route serves and the status of its response to
+// requests of any other protocol.
+const downstreamProtocolKey = "downstream-protocol"
+
+// downstreamProtocolScript responds, with the route's status, to
+// requests whose protocol is not the one the route serves. Routes
+// without a downstream protocol are left untouched.
+const downstreamProtocolScript = `function envoy_on_request(request_handle)
+  local policy = request_handle:metadata():get("` + downstreamProtocolKey + `")
+  if policy == nil then
+    return
+  end
+  if request_handle:streamInfo():protocol() ~= policy["protocol"] then
+    request_handle:respond({[":status"] = policy["status"]}, "")
+  end
+end
+`
+
+// DownstreamProtocolFilter returns the HTTP filter which enforces
+// the downstream protocol attached to a route's metadata.
+func DownstreamProtocolFilter() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: wellknown.Lua,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: toAny(&lua.Lua{
+				InlineCode: downstreamProtocolScript,
+			}),
+		},
+	}
+}
+
+// DownstreamProtocolMetadata returns the route metadata which restricts
+// the route to requests of the supplied protocol, as named by Envoy,
+// answering any other with status.
+func DownstreamProtocolMetadata(protocol string, status uint32) *envoy_api_v2_core.Metadata {
+	return &envoy_api_v2_core.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			wellknown.Lua: {
+				Fields: map[string]*_struct.Value{
+					downstreamProtocolKey: {
+						Kind: &_struct.Value_StructValue{
+							StructValue: &_struct.Struct{
+								Fields: map[string]*_struct.Value{
+									"protocol": sv(protocol),
+									"status":   sv(strconv.Itoa(int(status))),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	ratelimit "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rate_limit/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	ratelimit_config "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// RateLimitFilter returns the HTTP filter which checks the rate
+// limit descriptors of each request's route with the supplied
+// rate limit service.
+func RateLimitFilter(rls *dag.RateLimitService) *http.HttpFilter {
+	rl := &ratelimit.RateLimit{
+		Domain:          rls.Domain,
+		FailureModeDeny: rls.FailureModeDeny,
+		RateLimitService: &ratelimit_config.RateLimitServiceConfig{
+			GrpcService: &envoy_api_v2_core.GrpcService{
+				TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+					EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+						ClusterName: Clustername(rls.Cluster),
+					},
+				},
+			},
+		},
+	}
+	if rls.Timeout > 0 {
+		rl.Timeout = protobuf.Duration(rls.Timeout)
+	}
+	return &http.HttpFilter{
+		Name: wellknown.HTTPRateLimit,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: toAny(rl),
+		},
+	}
+}
@@ -15,15 +15,21 @@ package envoy
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/duration"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/protobuf"
 )
@@ -43,16 +49,40 @@ func Route(match *envoy_api_v2_route.RouteMatch, action *envoy_api_v2_route.Rout
 
 // RouteMatch creates a *envoy_api_v2_route.RouteMatch for the supplied *dag.Route.
 func RouteMatch(route *dag.Route) *envoy_api_v2_route.RouteMatch {
+	var match *envoy_api_v2_route.RouteMatch
 	switch c := route.PathCondition.(type) {
 	case *dag.RegexCondition:
-		return RouteRegex(c.Regex, route.HeaderConditions...)
+		match = RouteRegex(c.Regex, route.HeaderConditions...)
+	case *dag.SafeRegexCondition:
+		match = RouteSafeRegex(c.Regex, route.HeaderConditions...)
 	case *dag.PrefixCondition:
-		return RoutePrefix(c.Prefix, route.HeaderConditions...)
+		match = RoutePrefix(c.Prefix, route.HeaderConditions...)
+	case *dag.ExactCondition:
+		match = RoutePath(c.Path, route.HeaderConditions...)
 	default:
-		return &envoy_api_v2_route.RouteMatch{
+		match = &envoy_api_v2_route.RouteMatch{
 			Headers: headerMatcher(route.HeaderConditions),
 		}
 	}
+	if route.ClusterHeader != "" {
+		match.Headers = append(match.Headers, clusterHeaderMatcher(route))
+	}
+	match.QueryParameters = queryParameterMatcher(route.QueryParameterConditions)
+	return match
+}
+
+// clusterHeaderMatcher returns a HeaderMatcher which matches only if
+// the route's cluster header names one of the route's clusters.
+func clusterHeaderMatcher(route *dag.Route) *envoy_api_v2_route.HeaderMatcher {
+	var names []string
+	for _, c := range route.Clusters {
+		names = append(names, regexp.QuoteMeta(Clustername(c)))
+	}
+	regex, maxProgramSize := alternation(names)
+	return &envoy_api_v2_route.HeaderMatcher{
+		Name:                 route.ClusterHeader,
+		HeaderMatchSpecifier: safeRegexMatch(regex, maxProgramSize),
+	}
 }
 
 // RouteRegex returns a regex matcher.
@@ -65,6 +95,40 @@ func RouteRegex(regex string, headers ...dag.HeaderCondition) *envoy_api_v2_rout
 	}
 }
 
+// maxRegexProgramSize is the largest program Envoy will compile a path
+// or header regex from an HTTPProxy to. The DAG limits the size of the
+// program Go compiles the regex to, which RE2 does not count in quite the
+// same way, so this leaves ample room.
+const maxRegexProgramSize = 2000
+
+// RouteSafeRegex returns a matcher of the whole path by the RE2
+// regular expression regex.
+func RouteSafeRegex(regex string, headers ...dag.HeaderCondition) *envoy_api_v2_route.RouteMatch {
+	return &envoy_api_v2_route.RouteMatch{
+		PathSpecifier: &envoy_api_v2_route.RouteMatch_SafeRegex{
+			SafeRegex: &matcher.RegexMatcher{
+				EngineType: &matcher.RegexMatcher_GoogleRe2{
+					GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+						MaxProgramSize: protobuf.UInt32(maxRegexProgramSize),
+					},
+				},
+				Regex: regex,
+			},
+		},
+		Headers: headerMatcher(headers),
+	}
+}
+
+// RoutePath returns an exact path matcher.
+func RoutePath(path string, headers ...dag.HeaderCondition) *envoy_api_v2_route.RouteMatch {
+	return &envoy_api_v2_route.RouteMatch{
+		PathSpecifier: &envoy_api_v2_route.RouteMatch_Path{
+			Path: path,
+		},
+		Headers: headerMatcher(headers),
+	}
+}
+
 // RoutePrefix returns a prefix matcher.
 func RoutePrefix(prefix string, headers ...dag.HeaderCondition) *envoy_api_v2_route.RouteMatch {
 	return &envoy_api_v2_route.RouteMatch{
@@ -86,6 +150,8 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 		PrefixRewrite:       r.PrefixRewrite,
 		HashPolicy:          hashPolicy(r),
 		RequestMirrorPolicy: mirrorPolicy(r),
+		RateLimits:          rateLimits(r),
+		Cors:                corsPolicy(r.CORSPolicy),
 	}
 
 	if r.Websocket {
@@ -96,14 +162,23 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 		)
 	}
 
-	switch len(r.Clusters) {
-	case 1:
+	switch {
+	case r.ClusterHeader != "":
+		// the route's match ensures the header names one of its clusters.
+		ra.ClusterSpecifier = &envoy_api_v2_route.RouteAction_ClusterHeader{
+			ClusterHeader: r.ClusterHeader,
+		}
+	case len(r.Clusters) == 1 && r.ClassificationHeader == "" && r.SessionPinningPolicy == nil && r.ExperimentPolicy == nil && !hasHeadersPolicy(r.Clusters[0]):
 		ra.ClusterSpecifier = &envoy_api_v2_route.RouteAction_Cluster{
 			Cluster: Clustername(r.Clusters[0]),
 		}
 	default:
+		// classification headers, pinning cookies, experiment
+		// variants and service header policies are added per
+		// weighted cluster, so a route with any of them always
+		// uses weighted clusters.
 		ra.ClusterSpecifier = &envoy_api_v2_route.RouteAction_WeightedClusters{
-			WeightedClusters: weightedClusters(r.Clusters),
+			WeightedClusters: weightedClusters(r),
 		}
 	}
 	return &envoy_api_v2_route.Route_Route{
@@ -111,18 +186,70 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 	}
 }
 
+// rateLimits returns a rate limit for each descriptor of the route's
+// rate limit policy, or nil if it has none.
+func rateLimits(r *dag.Route) []*envoy_api_v2_route.RateLimit {
+	if r.RateLimitPolicy == nil {
+		return nil
+	}
+	var limits []*envoy_api_v2_route.RateLimit
+	for _, d := range r.RateLimitPolicy.Descriptors {
+		var actions []*envoy_api_v2_route.RateLimit_Action
+		for _, e := range d {
+			switch {
+			case e.HeaderName != "":
+				actions = append(actions, &envoy_api_v2_route.RateLimit_Action{
+					ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_RequestHeaders_{
+						RequestHeaders: &envoy_api_v2_route.RateLimit_Action_RequestHeaders{
+							HeaderName:    e.HeaderName,
+							DescriptorKey: e.DescriptorKey,
+						},
+					},
+				})
+			case e.RemoteAddress:
+				actions = append(actions, &envoy_api_v2_route.RateLimit_Action{
+					ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_RemoteAddress_{
+						RemoteAddress: &envoy_api_v2_route.RateLimit_Action_RemoteAddress{},
+					},
+				})
+			default:
+				actions = append(actions, &envoy_api_v2_route.RateLimit_Action{
+					ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_GenericKey_{
+						GenericKey: &envoy_api_v2_route.RateLimit_Action_GenericKey{
+							DescriptorValue: e.GenericKey,
+						},
+					},
+				})
+			}
+		}
+		limits = append(limits, &envoy_api_v2_route.RateLimit{
+			Actions: actions,
+		})
+	}
+	return limits
+}
+
 // hashPolicy returns a slice of hash policies iff at least one of the route's
-// clusters supplied uses the `Cookie` load balancing stategy.
+// clusters supplied uses the `Cookie`, `RequestHash` or `Maglev` load balancing stategy.
 func hashPolicy(r *dag.Route) []*envoy_api_v2_route.RouteAction_HashPolicy {
 	for _, c := range r.Clusters {
+		if c.LoadBalancerPolicy == "RequestHash" || c.LoadBalancerPolicy == "Maglev" {
+			return requestHashPolicies(r.RequestHashPolicies)
+		}
 		if c.LoadBalancerPolicy == "Cookie" {
+			cookie := &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+				Name: "X-Contour-Session-Affinity",
+				Ttl:  protobuf.Duration(0),
+				Path: "/",
+			}
+			if sa := r.SessionAffinityPolicy; sa != nil {
+				cookie.Name = sa.CookieName
+				cookie.Ttl = protobuf.Duration(sa.TTL)
+				cookie.Path = sa.Path
+			}
 			return []*envoy_api_v2_route.RouteAction_HashPolicy{{
 				PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
-					Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
-						Name: "X-Contour-Session-Affinity",
-						Ttl:  protobuf.Duration(0),
-						Path: "/",
-					},
+					Cookie: cookie,
 				},
 			}}
 		}
@@ -130,20 +257,74 @@ func hashPolicy(r *dag.Route) []*envoy_api_v2_route.RouteAction_HashPolicy {
 	return nil
 }
 
+// requestHashPolicies returns the hash policies of the request
+// attributes supplied, in order.
+func requestHashPolicies(policies []dag.RequestHashPolicy) []*envoy_api_v2_route.RouteAction_HashPolicy {
+	var hps []*envoy_api_v2_route.RouteAction_HashPolicy
+	for _, p := range policies {
+		hp := &envoy_api_v2_route.RouteAction_HashPolicy{
+			Terminal: p.Terminal,
+		}
+		if p.SourceIP {
+			hp.PolicySpecifier = &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties_{
+				ConnectionProperties: &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties{
+					SourceIp: true,
+				},
+			}
+		} else {
+			hp.PolicySpecifier = &envoy_api_v2_route.RouteAction_HashPolicy_Header_{
+				Header: &envoy_api_v2_route.RouteAction_HashPolicy_Header{
+					HeaderName: p.HeaderName,
+				},
+			}
+		}
+		hps = append(hps, hp)
+	}
+	return hps
+}
+
 func mirrorPolicy(r *dag.Route) *envoy_api_v2_route.RouteAction_RequestMirrorPolicy {
 	if r.MirrorPolicy == nil {
 		return nil
 	}
-	return &envoy_api_v2_route.RouteAction_RequestMirrorPolicy{
+	mp := &envoy_api_v2_route.RouteAction_RequestMirrorPolicy{
 		Cluster: Clustername(r.MirrorPolicy.Cluster),
 	}
+	if p := r.MirrorPolicy.Percent; p > 0 && p < 100 {
+		mp.RuntimeFraction = &envoy_api_v2_core.RuntimeFractionalPercent{
+			DefaultValue: &envoy_type.FractionalPercent{
+				Numerator:   p,
+				Denominator: envoy_type.FractionalPercent_HUNDRED,
+			},
+		}
+	}
+	return mp
 }
 
 func responseTimeout(r *dag.Route) *duration.Duration {
-	if r.TimeoutPolicy == nil {
-		return nil
+	if r.TimeoutPolicy != nil && r.TimeoutPolicy.ResponseTimeout != 0 {
+		return timeout(r.TimeoutPolicy.ResponseTimeout)
+	}
+	if grpcRoute(r) {
+		// streaming RPCs may last indefinitely, so routes to gRPC
+		// services have no response timeout unless one is set.
+		return protobuf.Duration(0)
+	}
+	return nil
+}
+
+// grpcRoute returns true if each of the route's clusters is a
+// gRPC service.
+func grpcRoute(r *dag.Route) bool {
+	if len(r.Clusters) == 0 {
+		return false
 	}
-	return timeout(r.TimeoutPolicy.ResponseTimeout)
+	for _, c := range r.Clusters {
+		if !c.Upstream.GRPC {
+			return false
+		}
+	}
+	return true
 }
 
 func idleTimeout(r *dag.Route) *duration.Duration {
@@ -182,7 +363,8 @@ func retryPolicy(r *dag.Route) *envoy_api_v2_route.RetryPolicy {
 	}
 
 	rp := &envoy_api_v2_route.RetryPolicy{
-		RetryOn: r.RetryPolicy.RetryOn,
+		RetryOn:              r.RetryPolicy.RetryOn,
+		RetriableStatusCodes: r.RetryPolicy.RetriableStatusCodes,
 	}
 	if r.RetryPolicy.NumRetries > 0 {
 		rp.NumRetries = protobuf.UInt32(r.RetryPolicy.NumRetries)
@@ -190,6 +372,14 @@ func retryPolicy(r *dag.Route) *envoy_api_v2_route.RetryPolicy {
 	if r.RetryPolicy.PerTryTimeout > 0 {
 		rp.PerTryTimeout = protobuf.Duration(r.RetryPolicy.PerTryTimeout)
 	}
+	if r.RetryPolicy.BackOffBaseInterval > 0 {
+		rp.RetryBackOff = &envoy_api_v2_route.RetryPolicy_RetryBackOff{
+			BaseInterval: protobuf.Duration(r.RetryPolicy.BackOffBaseInterval),
+		}
+		if r.RetryPolicy.BackOffMaxInterval > 0 {
+			rp.RetryBackOff.MaxInterval = protobuf.Duration(r.RetryPolicy.BackOffMaxInterval)
+		}
+	}
 	return rp
 }
 
@@ -204,34 +394,235 @@ func UpgradeHTTPS() *envoy_api_v2_route.Route_Redirect {
 	}
 }
 
-// weightedClusters returns a route.WeightedCluster for multiple services.
-func weightedClusters(clusters []*dag.Cluster) *envoy_api_v2_route.WeightedCluster {
-	var wc envoy_api_v2_route.WeightedCluster
+// HTTPSRedirect returns a route Action that redirects the request to HTTPS.
+// If stripPath is true the request is redirected to the root path, and if
+// stripQuery is true the request's query string is removed.
+func HTTPSRedirect(stripPath, stripQuery bool) *envoy_api_v2_route.Route_Redirect {
+	redirect := UpgradeHTTPS()
+	if stripPath {
+		redirect.Redirect.PathRewriteSpecifier = &envoy_api_v2_route.RedirectAction_PathRedirect{
+			PathRedirect: "/",
+		}
+	}
+	redirect.Redirect.StripQuery = stripQuery
+	return redirect
+}
+
+// HostRedirect returns a route Action that redirects the request to
+// host, preserving its path and query string. If scheme is empty the
+// scheme of the request is preserved. The status must be one of 301,
+// 302, 303, 307, or 308, otherwise 301 is used.
+func HostRedirect(host, scheme string, status uint32) *envoy_api_v2_route.Route_Redirect {
+	redirect := &envoy_api_v2_route.RedirectAction{
+		HostRedirect: host,
+		ResponseCode: redirectResponseCode(status),
+	}
+	switch scheme {
+	case "https":
+		redirect.SchemeRewriteSpecifier = &envoy_api_v2_route.RedirectAction_HttpsRedirect{
+			HttpsRedirect: true,
+		}
+	case "":
+	default:
+		redirect.SchemeRewriteSpecifier = &envoy_api_v2_route.RedirectAction_SchemeRedirect{
+			SchemeRedirect: scheme,
+		}
+	}
+	return &envoy_api_v2_route.Route_Redirect{
+		Redirect: redirect,
+	}
+}
+
+// PathRedirect returns a route Action that permanently redirects the
+// request to path on the same host, preserving its query string.
+func PathRedirect(path string) *envoy_api_v2_route.Route_Redirect {
+	return &envoy_api_v2_route.Route_Redirect{
+		Redirect: &envoy_api_v2_route.RedirectAction{
+			PathRewriteSpecifier: &envoy_api_v2_route.RedirectAction_PathRedirect{
+				PathRedirect: path,
+			},
+		},
+	}
+}
+
+// RouteRedirect returns a route Action for the supplied redirect.
+func RouteRedirect(r *dag.Redirect) *envoy_api_v2_route.Route_Redirect {
+	redirect := HostRedirect(r.Host, r.Scheme, r.StatusCode)
+	switch {
+	case r.Path != "":
+		redirect.Redirect.PathRewriteSpecifier = &envoy_api_v2_route.RedirectAction_PathRedirect{
+			PathRedirect: r.Path,
+		}
+	case r.PrefixRewrite != "":
+		redirect.Redirect.PathRewriteSpecifier = &envoy_api_v2_route.RedirectAction_PrefixRewrite{
+			PrefixRewrite: r.PrefixRewrite,
+		}
+	}
+	redirect.Redirect.PortRedirect = r.Port
+	return redirect
+}
+
+func redirectResponseCode(status uint32) envoy_api_v2_route.RedirectAction_RedirectResponseCode {
+	switch status {
+	case http.StatusFound:
+		return envoy_api_v2_route.RedirectAction_FOUND
+	case http.StatusSeeOther:
+		return envoy_api_v2_route.RedirectAction_SEE_OTHER
+	case http.StatusTemporaryRedirect:
+		return envoy_api_v2_route.RedirectAction_TEMPORARY_REDIRECT
+	case http.StatusPermanentRedirect:
+		return envoy_api_v2_route.RedirectAction_PERMANENT_REDIRECT
+	default:
+		return envoy_api_v2_route.RedirectAction_MOVED_PERMANENTLY
+	}
+}
+
+// DirectResponse returns a route Action that responds to the request
+// with the supplied status code without contacting an upstream cluster.
+func DirectResponse(status uint32) *envoy_api_v2_route.Route_DirectResponse {
+	return &envoy_api_v2_route.Route_DirectResponse{
+		DirectResponse: &envoy_api_v2_route.DirectResponseAction{
+			Status: status,
+		},
+	}
+}
+
+// DirectResponseBody returns a route Action that responds to the
+// request with the supplied status code and body without contacting
+// an upstream cluster.
+func DirectResponseBody(status uint32, body string) *envoy_api_v2_route.Route_DirectResponse {
+	dr := DirectResponse(status)
+	dr.DirectResponse.Body = &envoy_api_v2_core.DataSource{
+		Specifier: &envoy_api_v2_core.DataSource_InlineString{
+			InlineString: body,
+		},
+	}
+	return dr
+}
+
+// RouteMetadata returns the metadata attached to the route for
+// filters, or nil if the route has none.
+func RouteMetadata(r *dag.Route) *envoy_api_v2_core.Metadata {
+	if len(r.Metadata) == 0 {
+		return nil
+	}
+	md := &envoy_api_v2_core.Metadata{
+		FilterMetadata: make(map[string]*_struct.Struct),
+	}
+	for filter, values := range r.Metadata {
+		fields := make(map[string]*_struct.Value)
+		for k, v := range values {
+			switch v := v.(type) {
+			case string:
+				fields[k] = sv(v)
+			case float64:
+				fields[k] = &_struct.Value{Kind: &_struct.Value_NumberValue{NumberValue: v}}
+			case bool:
+				fields[k] = &_struct.Value{Kind: &_struct.Value_BoolValue{BoolValue: v}}
+			}
+		}
+		md.FilterMetadata[filter] = &_struct.Struct{Fields: fields}
+	}
+	return md
+}
+
+// weightedClusters returns the weighted clusters for the route's clusters.
+// Each cluster sets the route's classification header, and session pinning
+// cookie, on the responses it serves. If the route assigns experiment
+// variants each cluster is repeated for each variant, weighted by the
+// variant's percentage.
+func weightedClusters(r *dag.Route) *envoy_api_v2_route.WeightedCluster {
 	var total uint32
-	for _, cluster := range clusters {
+	for _, cluster := range r.Clusters {
 		total += cluster.Weight
-		wc.Clusters = append(wc.Clusters, &envoy_api_v2_route.WeightedCluster_ClusterWeight{
-			Name:   Clustername(cluster),
-			Weight: protobuf.UInt32(cluster.Weight),
-		})
 	}
-	// Check if no weights were defined, if not default to even distribution
-	if total == 0 {
-		for _, c := range wc.Clusters {
-			c.Weight.Value = 1
+
+	var wc envoy_api_v2_route.WeightedCluster
+	for _, cluster := range r.Clusters {
+		weight := cluster.Weight
+		if total == 0 {
+			// no weights were defined, default to even distribution
+			weight = 1
+		}
+		for _, v := range variants(r) {
+			cw := &envoy_api_v2_route.WeightedCluster_ClusterWeight{
+				Name:   Clustername(cluster),
+				Weight: protobuf.UInt32(weight * v.Percent),
+			}
+			if ep := r.ExperimentPolicy; ep != nil {
+				cw.RequestHeadersToAdd = Headers(SetHeader(ep.Header, v.Name))
+				if r.Variant == "" {
+					cw.ResponseHeadersToAdd = append(cw.ResponseHeadersToAdd, AppendHeader("set-cookie", experimentCookie(ep, v)))
+				}
+			}
+			if r.ClassificationHeader != "" {
+				cw.ResponseHeadersToAdd = append(cw.ResponseHeadersToAdd, SetHeader(r.ClassificationHeader, cluster.Classification))
+			}
+			if sp := r.SessionPinningPolicy; sp != nil {
+				// append, rather than set, so the cookies set by the
+				// cluster are preserved.
+				cw.ResponseHeadersToAdd = append(cw.ResponseHeadersToAdd, AppendHeader("set-cookie", sessionPinCookie(sp, cluster)))
+			}
+			cw.RequestHeadersToAdd = append(cw.RequestHeadersToAdd, HeadersToAdd(cluster.RequestHeadersPolicy)...)
+			cw.RequestHeadersToRemove = HeadersToRemove(cluster.RequestHeadersPolicy)
+			cw.ResponseHeadersToAdd = append(cw.ResponseHeadersToAdd, HeadersToAdd(cluster.ResponseHeadersPolicy)...)
+			cw.ResponseHeadersToRemove = HeadersToRemove(cluster.ResponseHeadersPolicy)
+			wc.Clusters = append(wc.Clusters, cw)
 		}
-		total = uint32(len(clusters))
 	}
-	wc.TotalWeight = protobuf.UInt32(total)
+
+	var sum uint32
+	for _, cw := range wc.Clusters {
+		sum += cw.Weight.Value
+	}
+	wc.TotalWeight = protobuf.UInt32(sum)
 
 	sort.Stable(clusterWeightByName(wc.Clusters))
 	return &wc
 }
 
+// hasHeadersPolicy returns true if the cluster modifies the headers
+// of the requests it is forwarded or the responses it serves.
+func hasHeadersPolicy(c *dag.Cluster) bool {
+	return c.RequestHeadersPolicy != nil || c.ResponseHeadersPolicy != nil
+}
+
+// variants returns the experiment variants the route assigns requests
+// to. A route which does not assign variants has a single variant, so
+// its requests are weighted only by cluster.
+func variants(r *dag.Route) []dag.Variant {
+	switch {
+	case r.ExperimentPolicy == nil:
+		return []dag.Variant{{Percent: 1}}
+	case r.Variant != "":
+		return []dag.Variant{{Name: r.Variant, Percent: 1}}
+	default:
+		return r.ExperimentPolicy.Variants
+	}
+}
+
+// experimentCookie returns the Set-Cookie value which assigns a
+// session to the supplied variant.
+func experimentCookie(ep *dag.ExperimentPolicy, v dag.Variant) string {
+	cookie := fmt.Sprintf("%s=%s; Path=/; HttpOnly", ep.CookieName, v.Cookie())
+	if ep.TTL > 0 {
+		cookie += fmt.Sprintf("; Max-Age=%d", int64(ep.TTL/time.Second))
+	}
+	return cookie
+}
+
+// sessionPinCookie returns the Set-Cookie value which pins a
+// session to the supplied cluster for the policy's TTL.
+func sessionPinCookie(sp *dag.SessionPinningPolicy, cluster *dag.Cluster) string {
+	return fmt.Sprintf("%s=%s; Max-Age=%d; Path=/; HttpOnly", sp.CookieName, cluster.SessionPin(), int64(sp.TTL/time.Second))
+}
+
 // VirtualHost creates a new route.VirtualHost.
 func VirtualHost(hostname string, routes ...*envoy_api_v2_route.Route) *envoy_api_v2_route.VirtualHost {
 	domains := []string{hostname}
-	if hostname != "*" {
+	// Envoy allows only one wildcard in a domain, so wildcard hostnames
+	// cannot also match a Host header with a port.
+	if !strings.HasPrefix(hostname, "*") {
 		domains = append(domains, hostname+":*")
 	}
 	return &envoy_api_v2_route.VirtualHost{
@@ -241,6 +632,116 @@ func VirtualHost(hostname string, routes ...*envoy_api_v2_route.Route) *envoy_ap
 	}
 }
 
+// MisdirectedVirtualHost returns a virtual host which responds to
+// requests for any host with 421 Misdirected Request.
+func MisdirectedVirtualHost() *envoy_api_v2_route.VirtualHost {
+	return &envoy_api_v2_route.VirtualHost{
+		Name:    "misdirected",
+		Domains: []string{"*"},
+		Routes: []*envoy_api_v2_route.Route{{
+			Match:  RoutePrefix("/"),
+			Action: DirectResponse(http.StatusMisdirectedRequest),
+		}},
+	}
+}
+
+// VirtualClusters returns a virtual cluster for each named route, in
+// the order supplied, so Envoy records request statistics per route.
+// Virtual clusters are matched on the request's :path header so the path
+// matches of routes are translated to the equivalent header matchers.
+// Consecutive routes of the same name and header matchers share one
+// virtual cluster, whose :path matcher matches the path of any of them.
+// As Envoy counts a request against the first virtual cluster it
+// matches, only consecutive routes may share.
+func VirtualClusters(routes ...*envoy_api_v2_route.Route) []*envoy_api_v2_route.VirtualCluster {
+	var vclusters []*envoy_api_v2_route.VirtualCluster
+	var group []*envoy_api_v2_route.Route
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		vclusters = append(vclusters, &envoy_api_v2_route.VirtualCluster{
+			Name:    VirtualClusterName(group[0].Name),
+			Headers: append([]*envoy_api_v2_route.HeaderMatcher{virtualClusterPath(group)}, group[0].Match.Headers...),
+		})
+		group = nil
+	}
+	for _, r := range routes {
+		if r.Name == "" {
+			continue
+		}
+		if len(group) > 0 && !sameVirtualCluster(group[0], r) {
+			flush()
+		}
+		group = append(group, r)
+	}
+	flush()
+	return vclusters
+}
+
+// sameVirtualCluster returns true if the named routes a and b can share
+// a virtual cluster.
+func sameVirtualCluster(a, b *envoy_api_v2_route.Route) bool {
+	if a.Name != b.Name || len(a.Match.Headers) != len(b.Match.Headers) {
+		return false
+	}
+	// legacy regexes cannot be combined with the RE2 regex of others.
+	for _, r := range []*envoy_api_v2_route.Route{a, b} {
+		if _, ok := r.Match.PathSpecifier.(*envoy_api_v2_route.RouteMatch_Regex); ok {
+			return false
+		}
+	}
+	for i := range a.Match.Headers {
+		if !proto.Equal(a.Match.Headers[i], b.Match.Headers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// virtualClusterPath returns the :path matcher of the virtual cluster
+// of routes.
+func virtualClusterPath(routes []*envoy_api_v2_route.Route) *envoy_api_v2_route.HeaderMatcher {
+	path := &envoy_api_v2_route.HeaderMatcher{Name: ":path"}
+	if len(routes) == 1 {
+		switch p := routes[0].Match.PathSpecifier.(type) {
+		case *envoy_api_v2_route.RouteMatch_Prefix:
+			path.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: p.Prefix}
+			return path
+		case *envoy_api_v2_route.RouteMatch_Regex:
+			// :path includes the query string, the route's regex does not.
+			path.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: p.Regex + `(\?.*)?`}
+			return path
+		}
+	}
+
+	var alternatives []string
+	var maxProgramSize uint32
+	for _, r := range routes {
+		switch p := r.Match.PathSpecifier.(type) {
+		case *envoy_api_v2_route.RouteMatch_Prefix:
+			alternatives = append(alternatives, regexp.QuoteMeta(p.Prefix)+".*")
+		case *envoy_api_v2_route.RouteMatch_Path:
+			alternatives = append(alternatives, regexp.QuoteMeta(p.Path)+`(\?.*)?`)
+		case *envoy_api_v2_route.RouteMatch_SafeRegex:
+			alternatives = append(alternatives, "(?:"+p.SafeRegex.GetRegex()+`)(\?.*)?`)
+			maxProgramSize += maxRegexProgramSize
+		default:
+			alternatives = append(alternatives, ".*")
+		}
+	}
+	regex, size := alternation(alternatives)
+	path.HeaderMatchSpecifier = safeRegexMatch(regex, maxProgramSize+size)
+	return path
+}
+
+// VirtualClusterName returns the stat safe name of the virtual cluster
+// for the named route. Kubernetes names cannot contain underscores so
+// the result is unambiguous.
+func VirtualClusterName(name string) string {
+	return strings.NewReplacer("/", "_", ".", "_").Replace(name)
+}
+
 // RouteConfiguration returns a *v2.RouteConfiguration.
 func RouteConfiguration(name string, virtualhosts ...*envoy_api_v2_route.VirtualHost) *v2.RouteConfiguration {
 	return &v2.RouteConfiguration{
@@ -278,6 +779,51 @@ func AppendHeader(key, value string) *envoy_api_v2_core.HeaderValueOption {
 	}
 }
 
+// SetHeader returns a HeaderValueOption which replaces any existing
+// value of the named header, so it cannot be forged by the client.
+func SetHeader(key, value string) *envoy_api_v2_core.HeaderValueOption {
+	return &envoy_api_v2_core.HeaderValueOption{
+		Header: &envoy_api_v2_core.HeaderValue{
+			Key:   key,
+			Value: value,
+		},
+		Append: protobuf.Bool(false),
+	}
+}
+
+// HeadersToAdd returns the headers set by the supplied policy, or
+// nil if the policy is nil.
+func HeadersToAdd(hp *dag.HeadersPolicy) []*envoy_api_v2_core.HeaderValueOption {
+	if hp == nil {
+		return nil
+	}
+	var headers []*envoy_api_v2_core.HeaderValueOption
+	for _, h := range hp.Set {
+		headers = append(headers, SetHeader(h.Name, h.Value))
+	}
+	return headers
+}
+
+// HeadersToRemove returns the names of the headers removed by the
+// supplied policy, or nil if the policy is nil.
+func HeadersToRemove(hp *dag.HeadersPolicy) []string {
+	if hp == nil {
+		return nil
+	}
+	return hp.Remove
+}
+
+// TLSAttributeHeaders returns the set of request headers used to
+// forward the attributes of the downstream TLS session to the backend.
+func TLSAttributeHeaders() []*envoy_api_v2_core.HeaderValueOption {
+	return Headers(
+		SetHeader("x-contour-tls-version", "%DOWNSTREAM_TLS_VERSION%"),
+		SetHeader("x-contour-tls-cipher", "%DOWNSTREAM_TLS_CIPHER%"),
+		SetHeader("x-contour-tls-session-id", "%DOWNSTREAM_TLS_SESSION_ID%"),
+		SetHeader("x-contour-tls-peer-fingerprint", "%DOWNSTREAM_PEER_FINGERPRINT_256%"),
+	)
+}
+
 func headerMatcher(headers []dag.HeaderCondition) []*envoy_api_v2_route.HeaderMatcher {
 	var envoyHeaders []*envoy_api_v2_route.HeaderMatcher
 
@@ -289,17 +835,74 @@ func headerMatcher(headers []dag.HeaderCondition) []*envoy_api_v2_route.HeaderMa
 
 		switch h.MatchType {
 		case "exact":
+			if h.IgnoreCase {
+				header.HeaderMatchSpecifier = ignoreCaseMatch(regexp.QuoteMeta(h.Value))
+				break
+			}
 			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: h.Value}
 		case "contains":
+			if h.IgnoreCase {
+				header.HeaderMatchSpecifier = ignoreCaseMatch(".*" + regexp.QuoteMeta(h.Value) + ".*")
+				break
+			}
 			header.HeaderMatchSpecifier = containsMatch(h.Value)
 		case "present":
 			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true}
+		case "regex":
+			if h.IgnoreCase {
+				header.HeaderMatchSpecifier = ignoreCaseMatch(h.Value)
+				break
+			}
+			header.HeaderMatchSpecifier = safeRegexMatch(h.Value, regexProgramSize(h.Value))
 		}
 		envoyHeaders = append(envoyHeaders, header)
 	}
 	return envoyHeaders
 }
 
+// queryParameterMatcher returns the matchers of the supplied query
+// parameter conditions. Envoy 1.11 does not understand the string_match
+// and present_match matchers, so conditions are expressed with value
+// and regex, which match on presence alone if value is empty.
+func queryParameterMatcher(conds []dag.QueryParameterCondition) []*envoy_api_v2_route.QueryParameterMatcher {
+	var matchers []*envoy_api_v2_route.QueryParameterMatcher
+	for _, c := range conds {
+		qp := &envoy_api_v2_route.QueryParameterMatcher{
+			Name: c.Name,
+		}
+		switch c.MatchType {
+		case "exact":
+			qp.Value = c.Value
+		case "prefix":
+			qp.Value = regexp.QuoteMeta(c.Value) + ".*"
+			qp.Regex = protobuf.Bool(true)
+		case "regex":
+			qp.Value = c.Value
+			qp.Regex = protobuf.Bool(true)
+		}
+		matchers = append(matchers, qp)
+	}
+	return matchers
+}
+
+// ignoreCaseMatch returns a HeaderMatchSpecifier which matches if the
+// entire header value matches regex, ignoring case.
+func ignoreCaseMatch(regex string) *envoy_api_v2_route.HeaderMatcher_SafeRegexMatch {
+	regex = "(?i)" + regex
+	return safeRegexMatch(regex, regexProgramSize(regex))
+}
+
+// regexProgramSize returns the maximum program size of a header regex,
+// which is either generated by Contour, and compiles to at most twice
+// its length, or validated by the DAG to compile to a program of
+// bounded size.
+func regexProgramSize(regex string) uint32 {
+	if size := uint32(2 * len(regex)); size > maxRegexProgramSize {
+		return size
+	}
+	return maxRegexProgramSize
+}
+
 // containsMatch returns a HeaderMatchSpecifier which will match the
 // supplied substring
 func containsMatch(s string) *envoy_api_v2_route.HeaderMatcher_SafeRegexMatch {
@@ -308,11 +911,68 @@ func containsMatch(s string) *envoy_api_v2_route.HeaderMatcher_SafeRegexMatch {
 	// formed from s. see [projectcontour/contour/#1751 & envoyproxy/envoy#8283]
 	regex := fmt.Sprintf(".*%s.*", regexp.QuoteMeta(s))
 
+	return safeRegexMatch(regex, uint32(len(regex)))
+}
+
+// safeRegexMatch returns a HeaderMatchSpecifier which matches if the
+// entire header value matches the supplied regular expression, which
+// must compile to at most maxProgramSize instructions.
+// corsPolicy returns the Envoy CORS policy of the supplied policy,
+// or nil if cp is nil.
+func corsPolicy(cp *dag.CORSPolicy) *envoy_api_v2_route.CorsPolicy {
+	if cp == nil {
+		return nil
+	}
+	policy := &envoy_api_v2_route.CorsPolicy{
+		AllowMethods:     strings.Join(cp.AllowMethods, ","),
+		AllowHeaders:     strings.Join(cp.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(cp.ExposeHeaders, ","),
+		AllowCredentials: protobuf.Bool(cp.AllowCredentials),
+	}
+	for _, o := range cp.AllowOrigin {
+		// Envoy allows any origin if an origin matcher matches "*".
+		policy.AllowOriginStringMatch = append(policy.AllowOriginStringMatch, &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Exact{
+				Exact: o,
+			},
+		})
+	}
+	for _, re := range cp.AllowOriginRegex {
+		policy.AllowOriginStringMatch = append(policy.AllowOriginStringMatch, &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_SafeRegex{
+				SafeRegex: &matcher.RegexMatcher{
+					EngineType: &matcher.RegexMatcher_GoogleRe2{
+						GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+							MaxProgramSize: protobuf.UInt32(uint32(2 * len(re))),
+						},
+					},
+					Regex: re,
+				},
+			},
+		})
+	}
+	if cp.MaxAge > 0 {
+		policy.MaxAge = strconv.Itoa(int(cp.MaxAge.Seconds()))
+	}
+	return policy
+}
+
+// alternation returns the regex matching any of alternatives, and
+// the largest program it compiles to beyond those of any nested
+// regexes.
+func alternation(alternatives []string) (string, uint32) {
+	regex := strings.Join(alternatives, "|")
+	// each alternative costs a few instructions beyond those of its
+	// characters.
+	return regex, uint32(len(regex) + 4*len(alternatives))
+}
+
+func safeRegexMatch(regex string, maxProgramSize uint32) *envoy_api_v2_route.HeaderMatcher_SafeRegexMatch {
 	return &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
 		SafeRegexMatch: &matcher.RegexMatcher{
 			EngineType: &matcher.RegexMatcher_GoogleRe2{
 				GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
-					MaxProgramSize: protobuf.UInt32(uint32(len(regex))),
+					MaxProgramSize: protobuf.UInt32(maxProgramSize),
 				},
 			},
 			Regex: regex,
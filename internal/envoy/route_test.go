@@ -20,6 +20,7 @@ import (
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/projectcontour/contour/internal/assert"
 	"github.com/projectcontour/contour/internal/dag"
@@ -93,6 +94,22 @@ func TestRouteRoute(t *testing.T) {
 		},
 		LoadBalancerPolicy: "Cookie",
 	}
+	c3 := &dag.Cluster{
+		Upstream: &dag.Service{
+			Name:        s1.Name,
+			Namespace:   s1.Namespace,
+			ServicePort: &s1.Spec.Ports[0],
+		},
+		LoadBalancerPolicy: "RequestHash",
+	}
+	c4 := &dag.Cluster{
+		Upstream: &dag.Service{
+			Name:        s1.Name,
+			Namespace:   s1.Namespace,
+			ServicePort: &s1.Spec.Ports[0],
+		},
+		LoadBalancerPolicy: "Maglev",
+	}
 
 	tests := map[string]struct {
 		route *dag.Route
@@ -110,6 +127,49 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"mirror": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				MirrorPolicy: &dag.MirrorPolicy{
+					Cluster: c1,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RequestMirrorPolicy: &envoy_api_v2_route.RouteAction_RequestMirrorPolicy{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+				},
+			},
+		},
+		"mirror percentage of requests": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				MirrorPolicy: &dag.MirrorPolicy{
+					Cluster: c1,
+					Percent: 10,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RequestMirrorPolicy: &envoy_api_v2_route.RouteAction_RequestMirrorPolicy{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+						RuntimeFraction: &envoy_api_v2_core.RuntimeFractionalPercent{
+							DefaultValue: &envoy_type.FractionalPercent{
+								Numerator:   10,
+								Denominator: envoy_type.FractionalPercent_HUNDRED,
+							},
+						},
+					},
+				},
+			},
+		},
 		"websocket": {
 			route: &dag.Route{
 				Websocket: true,
@@ -214,6 +274,54 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"retriable status codes": {
+			route: &dag.Route{
+				RetryPolicy: &dag.RetryPolicy{
+					RetryOn:              "reset,retriable-status-codes",
+					NumRetries:           2,
+					RetriableStatusCodes: []uint32{409, 503},
+				},
+				Clusters: []*dag.Cluster{c1},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RetryPolicy: &envoy_api_v2_route.RetryPolicy{
+						RetryOn:              "reset,retriable-status-codes",
+						NumRetries:           protobuf.UInt32(2),
+						RetriableStatusCodes: []uint32{409, 503},
+					},
+				},
+			},
+		},
+		"retry back off": {
+			route: &dag.Route{
+				RetryPolicy: &dag.RetryPolicy{
+					RetryOn:             "5xx",
+					NumRetries:          3,
+					BackOffBaseInterval: 100 * time.Millisecond,
+					BackOffMaxInterval:  2 * time.Second,
+				},
+				Clusters: []*dag.Cluster{c1},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RetryPolicy: &envoy_api_v2_route.RetryPolicy{
+						RetryOn:    "5xx",
+						NumRetries: protobuf.UInt32(3),
+						RetryBackOff: &envoy_api_v2_route.RetryPolicy_RetryBackOff{
+							BaseInterval: protobuf.Duration(100 * time.Millisecond),
+							MaxInterval:  protobuf.Duration(2 * time.Second),
+						},
+					},
+				},
+			},
+		},
 		"retry-on: 503": {
 			route: &dag.Route{
 				RetryPolicy: &dag.RetryPolicy{
@@ -321,6 +429,86 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"single service w/ session affinity cookie": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c2},
+				SessionAffinityPolicy: &dag.SessionAffinityPolicy{
+					CookieName: "affinity",
+					TTL:        time.Hour,
+					Path:       "/cart",
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/e4f81994fe",
+					},
+					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
+							Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+								Name: "affinity",
+								Ttl:  protobuf.Duration(time.Hour),
+								Path: "/cart",
+							},
+						},
+					}},
+				},
+			},
+		},
+		"single service w/ request hash": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c3},
+				RequestHashPolicies: []dag.RequestHashPolicy{{
+					HeaderName: "X-Tenant",
+					Terminal:   true,
+				}, {
+					SourceIP: true,
+				}},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/1a2ffc1fef",
+					},
+					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Header_{
+							Header: &envoy_api_v2_route.RouteAction_HashPolicy_Header{
+								HeaderName: "X-Tenant",
+							},
+						},
+						Terminal: true,
+					}, {
+						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties_{
+							ConnectionProperties: &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties{
+								SourceIp: true,
+							},
+						},
+					}},
+				},
+			},
+		},
+		"single service w/ maglev": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c4},
+				RequestHashPolicies: []dag.RequestHashPolicy{{
+					SourceIP: true,
+				}},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/843e4ded8f",
+					},
+					HashPolicy: []*envoy_api_v2_route.RouteAction_HashPolicy{{
+						PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties_{
+							ConnectionProperties: &envoy_api_v2_route.RouteAction_HashPolicy_ConnectionProperties{
+								SourceIp: true,
+							},
+						},
+					}},
+				},
+			},
+		},
 		"multiple service w/ session affinity": {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{c2, c2},
@@ -394,6 +582,7 @@ func TestRouteRoute(t *testing.T) {
 func TestWeightedClusters(t *testing.T) {
 	tests := map[string]struct {
 		clusters []*dag.Cluster
+		header   string
 		want     *envoy_api_v2_route.WeightedCluster
 	}{
 		"multiple services w/o weights": {
@@ -498,11 +687,47 @@ func TestWeightedClusters(t *testing.T) {
 				TotalWeight: protobuf.UInt32(100),
 			},
 		},
+		"multiple weighted services with classification header": {
+			clusters: []*dag.Cluster{{
+				Upstream: &dag.Service{
+					Name:      "kuard",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Port: 8080,
+					},
+				},
+				Weight:         90,
+				Classification: "stable",
+			}, {
+				Upstream: &dag.Service{
+					Name:      "kuard-canary",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Port: 8080,
+					},
+				},
+				Weight:         10,
+				Classification: "canary",
+			}},
+			header: "x-served-by",
+			want: &envoy_api_v2_route.WeightedCluster{
+				Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+					Name:                 "default/kuard-canary/8080/da39a3ee5e",
+					Weight:               protobuf.UInt32(10),
+					ResponseHeadersToAdd: Headers(SetHeader("x-served-by", "canary")),
+				}, {
+					Name:                 "default/kuard/8080/da39a3ee5e",
+					Weight:               protobuf.UInt32(90),
+					ResponseHeadersToAdd: Headers(SetHeader("x-served-by", "stable")),
+				}},
+				TotalWeight: protobuf.UInt32(100),
+			},
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			got := weightedClusters(tc.clusters)
+			got := weightedClusters(&dag.Route{Clusters: tc.clusters, ClassificationHeader: tc.header})
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -579,6 +804,14 @@ func TestVirtualHost(t *testing.T) {
 				Domains: []string{"www.example.com", "www.example.com:*"},
 			},
 		},
+		"wildcard hostname": {
+			hostname: "*.example.com",
+			port:     9999,
+			want: &envoy_api_v2_route.VirtualHost{
+				Name:    "*.example.com",
+				Domains: []string{"*.example.com"},
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -588,6 +821,139 @@ func TestVirtualHost(t *testing.T) {
 	}
 }
 
+func TestVirtualClusters(t *testing.T) {
+	tests := map[string]struct {
+		routes []*envoy_api_v2_route.Route
+		want   []*envoy_api_v2_route.VirtualCluster
+	}{
+		"unnamed route": {
+			routes: []*envoy_api_v2_route.Route{{
+				Match: RoutePrefix("/"),
+			}},
+			want: nil,
+		},
+		"prefix route with header conditions": {
+			routes: []*envoy_api_v2_route.Route{{
+				Name: "default/www.example.com",
+				Match: RoutePrefix("/api", dag.HeaderCondition{
+					Name:      "x-tenant",
+					MatchType: "present",
+				}),
+			}},
+			want: []*envoy_api_v2_route.VirtualCluster{{
+				Name: "default_www_example_com",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/api"},
+				}, {
+					Name:                 "x-tenant",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true},
+				}},
+			}},
+		},
+		"regex route": {
+			routes: []*envoy_api_v2_route.Route{{
+				Name:  "default/simple",
+				Match: RouteRegex("/v[0-9]+/.*"),
+			}},
+			want: []*envoy_api_v2_route.VirtualCluster{{
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: `/v[0-9]+/.*(\?.*)?`},
+				}},
+			}},
+		},
+		"exact route": {
+			routes: []*envoy_api_v2_route.Route{{
+				Name:  "default/simple",
+				Match: RoutePath("/healthz"),
+			}},
+			want: []*envoy_api_v2_route.VirtualCluster{{
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: safeRegexMatch(`/healthz(\?.*)?`, 15+4),
+				}},
+			}},
+		},
+		"consecutive routes share a virtual cluster": {
+			routes: []*envoy_api_v2_route.Route{{
+				Name:  "default/simple",
+				Match: RoutePath("/healthz"),
+			}, {
+				Name:  "default/simple",
+				Match: RouteSafeRegex("/v[0-9]+"),
+			}, {
+				Match: RoutePrefix("/unnamed"),
+			}, {
+				Name:  "default/simple",
+				Match: RoutePrefix("/api.v1"),
+			}},
+			want: []*envoy_api_v2_route.VirtualCluster{{
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name: ":path",
+					HeaderMatchSpecifier: safeRegexMatch(`/healthz(\?.*)?|(?:/v[0-9]+)(\?.*)?|/api\.v1.*`,
+						maxRegexProgramSize+46+4*3),
+				}},
+			}},
+		},
+		"routes of other names or headers do not share": {
+			routes: []*envoy_api_v2_route.Route{{
+				Name:  "default/simple",
+				Match: RoutePrefix("/api"),
+			}, {
+				Name: "default/simple",
+				Match: RoutePrefix("/api", dag.HeaderCondition{
+					Name:      "x-tenant",
+					MatchType: "present",
+				}),
+			}, {
+				Name:  "default/other",
+				Match: RoutePrefix("/"),
+			}, {
+				Name:  "default/simple",
+				Match: RoutePrefix("/"),
+			}},
+			want: []*envoy_api_v2_route.VirtualCluster{{
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/api"},
+				}},
+			}, {
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/api"},
+				}, {
+					Name:                 "x-tenant",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true},
+				}},
+			}, {
+				Name: "default_other",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/"},
+				}},
+			}, {
+				Name: "default_simple",
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:                 ":path",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/"},
+				}},
+			}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := VirtualClusters(tc.routes...)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestUpgradeHTTPS(t *testing.T) {
 	got := UpgradeHTTPS()
 	want := &envoy_api_v2_route.Route_Redirect{
@@ -601,6 +967,138 @@ func TestUpgradeHTTPS(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestHTTPSRedirect(t *testing.T) {
+	got := HTTPSRedirect(true, true)
+	want := &envoy_api_v2_route.Route_Redirect{
+		Redirect: &envoy_api_v2_route.RedirectAction{
+			SchemeRewriteSpecifier: &envoy_api_v2_route.RedirectAction_HttpsRedirect{
+				HttpsRedirect: true,
+			},
+			PathRewriteSpecifier: &envoy_api_v2_route.RedirectAction_PathRedirect{
+				PathRedirect: "/",
+			},
+			StripQuery: true,
+		},
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, UpgradeHTTPS(), HTTPSRedirect(false, false))
+}
+
+func TestHostRedirect(t *testing.T) {
+	tests := map[string]struct {
+		scheme string
+		status uint32
+		want   *envoy_api_v2_route.Route_Redirect
+	}{
+		"default": {
+			want: &envoy_api_v2_route.Route_Redirect{
+				Redirect: &envoy_api_v2_route.RedirectAction{
+					HostRedirect: "www.example.com",
+					ResponseCode: envoy_api_v2_route.RedirectAction_MOVED_PERMANENTLY,
+				},
+			},
+		},
+		"https 308": {
+			scheme: "https",
+			status: 308,
+			want: &envoy_api_v2_route.Route_Redirect{
+				Redirect: &envoy_api_v2_route.RedirectAction{
+					HostRedirect: "www.example.com",
+					ResponseCode: envoy_api_v2_route.RedirectAction_PERMANENT_REDIRECT,
+					SchemeRewriteSpecifier: &envoy_api_v2_route.RedirectAction_HttpsRedirect{
+						HttpsRedirect: true,
+					},
+				},
+			},
+		},
+		"http 302": {
+			scheme: "http",
+			status: 302,
+			want: &envoy_api_v2_route.Route_Redirect{
+				Redirect: &envoy_api_v2_route.RedirectAction{
+					HostRedirect: "www.example.com",
+					ResponseCode: envoy_api_v2_route.RedirectAction_FOUND,
+					SchemeRewriteSpecifier: &envoy_api_v2_route.RedirectAction_SchemeRedirect{
+						SchemeRedirect: "http",
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := HostRedirect("www.example.com", tc.scheme, tc.status)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRouteRedirect(t *testing.T) {
+	got := RouteRedirect(&dag.Redirect{
+		Path:       "/api/",
+		StatusCode: 301,
+	})
+	want := &envoy_api_v2_route.Route_Redirect{
+		Redirect: &envoy_api_v2_route.RedirectAction{
+			PathRewriteSpecifier: &envoy_api_v2_route.RedirectAction_PathRedirect{
+				PathRedirect: "/api/",
+			},
+		},
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, PathRedirect("/api/"), got)
+	assert.Equal(t, HostRedirect("www.example.com", "https", 308), RouteRedirect(&dag.Redirect{
+		Host:       "www.example.com",
+		Scheme:     "https",
+		StatusCode: 308,
+	}))
+	assert.Equal(t, &envoy_api_v2_route.Route_Redirect{
+		Redirect: &envoy_api_v2_route.RedirectAction{
+			HostRedirect: "www.example.com",
+			PortRedirect: 8443,
+			ResponseCode: envoy_api_v2_route.RedirectAction_FOUND,
+			PathRewriteSpecifier: &envoy_api_v2_route.RedirectAction_PrefixRewrite{
+				PrefixRewrite: "/v2/",
+			},
+		},
+	}, RouteRedirect(&dag.Redirect{
+		Host:          "www.example.com",
+		Port:          8443,
+		PrefixRewrite: "/v2/",
+		StatusCode:    302,
+	}))
+}
+
+func TestDirectResponse(t *testing.T) {
+	got := DirectResponse(503)
+	want := &envoy_api_v2_route.Route_DirectResponse{
+		DirectResponse: &envoy_api_v2_route.DirectResponseAction{
+			Status: 503,
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestDirectResponseBody(t *testing.T) {
+	got := DirectResponseBody(403, "down for maintenance")
+	want := &envoy_api_v2_route.Route_DirectResponse{
+		DirectResponse: &envoy_api_v2_route.DirectResponseAction{
+			Status: 403,
+			Body: &envoy_api_v2_core.DataSource{
+				Specifier: &envoy_api_v2_core.DataSource_InlineString{
+					InlineString: "down for maintenance",
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
+
 func TestRouteMatch(t *testing.T) {
 	tests := map[string]struct {
 		route *dag.Route
@@ -684,6 +1182,82 @@ func TestRouteMatch(t *testing.T) {
 				}},
 			},
 		},
+		"exact match ignoring case": {
+			route: &dag.Route{
+				HeaderConditions: []dag.HeaderCondition{{
+					Name:       "x-header",
+					Value:      "Prod.EU",
+					MatchType:  "exact",
+					IgnoreCase: true,
+				}},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name: "x-header",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: &matcher.RegexMatcher{
+							EngineType: &matcher.RegexMatcher_GoogleRe2{
+								GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+									MaxProgramSize: protobuf.UInt32(maxRegexProgramSize),
+								},
+							},
+							Regex: "(?i)Prod\\.EU",
+						},
+					},
+				}},
+			},
+		},
+		"not contains match ignoring case": {
+			route: &dag.Route{
+				HeaderConditions: []dag.HeaderCondition{{
+					Name:       "user-agent",
+					Value:      "Bot",
+					MatchType:  "contains",
+					Invert:     true,
+					IgnoreCase: true,
+				}},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name:        "user-agent",
+					InvertMatch: true,
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: &matcher.RegexMatcher{
+							EngineType: &matcher.RegexMatcher_GoogleRe2{
+								GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+									MaxProgramSize: protobuf.UInt32(maxRegexProgramSize),
+								},
+							},
+							Regex: "(?i).*Bot.*",
+						},
+					},
+				}},
+			},
+		},
+		"regex match": {
+			route: &dag.Route{
+				HeaderConditions: []dag.HeaderCondition{{
+					Name:      "x-request-id",
+					Value:     "[a-f0-9]+",
+					MatchType: "regex",
+				}},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name: "x-request-id",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: &matcher.RegexMatcher{
+							EngineType: &matcher.RegexMatcher_GoogleRe2{
+								GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+									MaxProgramSize: protobuf.UInt32(maxRegexProgramSize),
+								},
+							},
+							Regex: "[a-f0-9]+",
+						},
+					},
+				}},
+			},
+		},
 		"path prefix": {
 			route: &dag.Route{
 				PathCondition: &dag.PrefixCondition{
@@ -711,6 +1285,79 @@ func TestRouteMatch(t *testing.T) {
 				},
 			},
 		},
+		"query parameters": {
+			route: &dag.Route{
+				PathCondition: &dag.PrefixCondition{
+					Prefix: "/",
+				},
+				QueryParameterConditions: []dag.QueryParameterCondition{{
+					Name:      "debug",
+					MatchType: "present",
+				}, {
+					Name:      "flag",
+					Value:     "beta",
+					MatchType: "exact",
+				}, {
+					Name:      "user",
+					Value:     "test.",
+					MatchType: "prefix",
+				}, {
+					Name:      "page",
+					Value:     "[0-9]+",
+					MatchType: "regex",
+				}},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+					Prefix: "/",
+				},
+				QueryParameters: []*envoy_api_v2_route.QueryParameterMatcher{{
+					Name: "debug",
+				}, {
+					Name:  "flag",
+					Value: "beta",
+				}, {
+					Name:  "user",
+					Value: `test\..*`,
+					Regex: protobuf.Bool(true),
+				}, {
+					Name:  "page",
+					Value: "[0-9]+",
+					Regex: protobuf.Bool(true),
+				}},
+			},
+		},
+		"path safe regex": {
+			route: &dag.Route{
+				PathCondition: &dag.SafeRegexCondition{
+					Regex: "/v[0-9]+/.*",
+				},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				PathSpecifier: &envoy_api_v2_route.RouteMatch_SafeRegex{
+					SafeRegex: &matcher.RegexMatcher{
+						EngineType: &matcher.RegexMatcher_GoogleRe2{
+							GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+								MaxProgramSize: protobuf.UInt32(maxRegexProgramSize),
+							},
+						},
+						Regex: "/v[0-9]+/.*",
+					},
+				},
+			},
+		},
+		"exact path": {
+			route: &dag.Route{
+				PathCondition: &dag.ExactCondition{
+					Path: "/api/",
+				},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				PathSpecifier: &envoy_api_v2_route.RouteMatch_Path{
+					Path: "/api/",
+				},
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -0,0 +1,188 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventlog records the Kubernetes object events Contour
+// receives to a file, and replays a recording to an event handler.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	contourscheme "github.com/projectcontour/contour/apis/generated/clientset/versioned/scheme"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	opAdd    = "add"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// event is a single line of a recording.
+type event struct {
+	Time       time.Time       `json:"time"`
+	Op         string          `json:"op"`
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Object     json.RawMessage `json:"object"`
+	OldObject  json.RawMessage `json:"oldObject,omitempty"`
+}
+
+// objectScheme knows the Kubernetes and Contour types Contour watches.
+var objectScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(scheme.AddToScheme(objectScheme))
+	utilruntime.Must(contourscheme.AddToScheme(objectScheme))
+}
+
+// Recorder is a cache.ResourceEventHandler which writes each event
+// it receives to Writer as a line of JSON before passing it to Next.
+// Events which cannot be recorded are logged and passed on.
+type Recorder struct {
+	mu sync.Mutex
+
+	Writer io.Writer
+	Next   cache.ResourceEventHandler
+
+	logrus.FieldLogger
+}
+
+func (r *Recorder) OnAdd(obj interface{}) {
+	r.record(opAdd, nil, obj)
+	r.Next.OnAdd(obj)
+}
+
+func (r *Recorder) OnUpdate(oldObj, newObj interface{}) {
+	r.record(opUpdate, oldObj, newObj)
+	r.Next.OnUpdate(oldObj, newObj)
+}
+
+func (r *Recorder) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		r.record(opDelete, nil, tombstone.Obj)
+	} else {
+		r.record(opDelete, nil, obj)
+	}
+	r.Next.OnDelete(obj)
+}
+
+func (r *Recorder) record(op string, oldObj, obj interface{}) {
+	ev, err := newEvent(op, oldObj, obj)
+	if err == nil {
+		var buf []byte
+		buf, err = json.Marshal(ev)
+		if err == nil {
+			r.mu.Lock()
+			_, err = r.Writer.Write(append(buf, '\n'))
+			r.mu.Unlock()
+		}
+	}
+	if err != nil {
+		r.WithError(err).WithField("op", op).Errorf("%T failed to record event", obj)
+	}
+}
+
+func newEvent(op string, oldObj, obj interface{}) (*event, error) {
+	o, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a runtime.Object", obj)
+	}
+	gvks, _, err := objectScheme.ObjectKinds(o)
+	if err != nil {
+		return nil, err
+	}
+	ev := &event{
+		Time:       time.Now(),
+		Op:         op,
+		APIVersion: gvks[0].GroupVersion().String(),
+		Kind:       gvks[0].Kind,
+	}
+	if ev.Object, err = json.Marshal(obj); err != nil {
+		return nil, err
+	}
+	if oldObj != nil {
+		if ev.OldObject, err = json.Marshal(oldObj); err != nil {
+			return nil, err
+		}
+	}
+	return ev, nil
+}
+
+// Replay reads a recording written by a Recorder from r and passes
+// each of its events to h, in the order they were recorded. It
+// returns the number of events replayed.
+func Replay(r io.Reader, h cache.ResourceEventHandler) (int, error) {
+	sc := bufio.NewScanner(r)
+	// Secrets and large HTTPProxies make for long lines.
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	n := 0
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		n++
+		var ev event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return n, fmt.Errorf("event %d: %v", n, err)
+		}
+		gv, err := schema.ParseGroupVersion(ev.APIVersion)
+		if err != nil {
+			return n, fmt.Errorf("event %d: %v", n, err)
+		}
+		gvk := gv.WithKind(ev.Kind)
+		obj, err := decode(gvk, ev.Object)
+		if err != nil {
+			return n, fmt.Errorf("event %d: %v", n, err)
+		}
+		switch ev.Op {
+		case opAdd:
+			h.OnAdd(obj)
+		case opUpdate:
+			var oldObj runtime.Object
+			if len(ev.OldObject) > 0 {
+				if oldObj, err = decode(gvk, ev.OldObject); err != nil {
+					return n, fmt.Errorf("event %d: %v", n, err)
+				}
+			}
+			h.OnUpdate(oldObj, obj)
+		case opDelete:
+			h.OnDelete(obj)
+		default:
+			return n, fmt.Errorf("event %d: unknown op %q", n, ev.Op)
+		}
+	}
+	return n, sc.Err()
+}
+
+func decode(gvk schema.GroupVersionKind, data []byte) (runtime.Object, error) {
+	obj, err := objectScheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,135 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// received is a cache.ResourceEventHandler which records the
+// events it receives.
+type received struct {
+	events []interface{}
+}
+
+type add struct{ Obj interface{} }
+type update struct{ OldObj, NewObj interface{} }
+type del struct{ Obj interface{} }
+
+func (r *received) OnAdd(obj interface{}) { r.events = append(r.events, add{obj}) }
+func (r *received) OnUpdate(oldObj, newObj interface{}) {
+	r.events = append(r.events, update{oldObj, newObj})
+}
+func (r *received) OnDelete(obj interface{}) { r.events = append(r.events, del{obj}) }
+
+func TestRecordReplay(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+	p1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+	p2 := p1.DeepCopy()
+	p2.Spec.VirtualHost.Fqdn = "www.example.com"
+
+	var buf bytes.Buffer
+	var live received
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	rec := &Recorder{
+		Writer:      &buf,
+		Next:        &live,
+		FieldLogger: log,
+	}
+	rec.OnAdd(s1)
+	rec.OnAdd(p1)
+	rec.OnUpdate(p1, p2)
+	rec.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/simple", Obj: p2})
+	rec.OnDelete(s1)
+
+	// events are passed on unchanged.
+	assert.Equal(t, []interface{}{
+		add{s1},
+		add{p1},
+		update{p1, p2},
+		del{cache.DeletedFinalStateUnknown{Key: "default/simple", Obj: p2}},
+		del{s1},
+	}, live.events)
+
+	var replayed received
+	n, err := Replay(&buf, &replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, n)
+
+	// tombstones are replayed as the deleted object.
+	assert.Equal(t, []interface{}{
+		add{s1},
+		add{p1},
+		update{p1, p2},
+		del{p2},
+		del{s1},
+	}, replayed.events)
+}
+
+func TestReplayInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":     "not json\n",
+		"unknown kind": `{"op":"add","apiVersion":"v1","kind":"Unknown","object":{}}` + "\n",
+		"unknown op":   `{"op":"patch","apiVersion":"v1","kind":"Service","object":{}}` + "\n",
+	}
+
+	for name, recording := range tests {
+		t.Run(name, func(t *testing.T) {
+			var replayed received
+			if _, err := Replay(bytes.NewBufferString(recording), &replayed); err == nil {
+				t.Fatal("expected error replaying invalid recording")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featuregate holds the set of experimental behaviours Contour
+// supports, each with a maturity stage and a default, and which of them
+// are enabled.
+package featuregate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature names a behaviour governed by a feature gate.
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+const (
+	// Alpha features are disabled by default and may change,
+	// or be removed, in any release.
+	Alpha Stage = "ALPHA"
+
+	// Beta features are enabled by default. They may still
+	// change, but are not removed without deprecation.
+	Beta Stage = "BETA"
+
+	// GA features are always enabled; their gate remains only
+	// so existing settings of it are accepted.
+	GA Stage = "GA"
+
+	// Deprecated features are to be removed in a later release.
+	Deprecated Stage = "DEPRECATED"
+)
+
+// Spec describes a feature.
+type Spec struct {
+	// Default is whether the feature is enabled if its
+	// gate is not set.
+	Default bool
+
+	// Stage is the maturity of the feature.
+	Stage Stage
+
+	// Since is the release of Contour which introduced
+	// the feature at this stage.
+	Since string
+}
+
+// FeatureGate records which of a set of known features are enabled.
+type FeatureGate struct {
+	known   map[Feature]Spec
+	enabled map[Feature]bool
+}
+
+// New returns a FeatureGate for the known features, each
+// enabled according to its default.
+func New(known map[Feature]Spec) *FeatureGate {
+	return &FeatureGate{
+		known:   known,
+		enabled: make(map[Feature]bool),
+	}
+}
+
+// Set enables and disables features from a comma separated
+// list of feature=bool pairs, such as "A=true,B=false".
+// It implements the Set method of kingpin.Value.
+func (f *FeatureGate) Set(value string) error {
+	m := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("feature gate %q must be of the form feature=true|false", s)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("feature gate %q: invalid value %q", kv[0], kv[1])
+		}
+		m[strings.TrimSpace(kv[0])] = enabled
+	}
+	return f.SetFromMap(m)
+}
+
+// SetFromMap enables and disables the features named in m. It
+// returns an error, changing nothing, if any feature is unknown,
+// or is GA and would be disabled.
+func (f *FeatureGate) SetFromMap(m map[string]bool) error {
+	for name, enabled := range m {
+		spec, ok := f.known[Feature(name)]
+		if !ok {
+			return fmt.Errorf("unknown feature gate %q, known gates are: %s", name, strings.Join(f.KnownFeatures(), ", "))
+		}
+		if spec.Stage == GA && enabled != spec.Default {
+			return fmt.Errorf("feature gate %q is GA and cannot be set to %v", name, enabled)
+		}
+	}
+	for name, enabled := range m {
+		f.enabled[Feature(name)] = enabled
+	}
+	return nil
+}
+
+// String returns the features which have been set, in the form
+// accepted by Set. It implements the String method of kingpin.Value.
+func (f *FeatureGate) String() string {
+	var pairs []string
+	for feature, enabled := range f.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", feature, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled returns whether feature is enabled. Unknown
+// features are never enabled.
+func (f *FeatureGate) Enabled(feature Feature) bool {
+	if enabled, ok := f.enabled[feature]; ok {
+		return enabled
+	}
+	return f.known[feature].Default
+}
+
+// Known returns the specs of the known features.
+func (f *FeatureGate) Known() map[Feature]Spec {
+	return f.known
+}
+
+// KnownFeatures returns a description of each known feature, such as
+// "Feature=true|false (ALPHA - default=false, since v1.1.0)", sorted
+// by name.
+func (f *FeatureGate) KnownFeatures() []string {
+	var known []string
+	for feature, spec := range f.known {
+		known = append(known, fmt.Sprintf("%s=true|false (%s - default=%v, since %s)", feature, spec.Stage, spec.Default, spec.Since))
+	}
+	sort.Strings(known)
+	return known
+}
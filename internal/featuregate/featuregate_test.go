@@ -0,0 +1,103 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregate
+
+import (
+	"testing"
+
+	"github.com/projectcontour/contour/internal/assert"
+)
+
+func TestFeatureGate(t *testing.T) {
+	known := map[Feature]Spec{
+		"Alpha":  {Default: false, Stage: Alpha, Since: "v1.1.0"},
+		"Beta":   {Default: true, Stage: Beta, Since: "v1.1.0"},
+		"Stable": {Default: true, Stage: GA, Since: "v1.1.0"},
+	}
+
+	tests := map[string]struct {
+		value   string
+		want    map[Feature]bool
+		wantErr bool
+	}{
+		"defaults": {
+			value: "",
+			want:  map[Feature]bool{"Alpha": false, "Beta": true, "Stable": true},
+		},
+		"enable alpha, disable beta": {
+			value: "Alpha=true, Beta=false",
+			want:  map[Feature]bool{"Alpha": true, "Beta": false, "Stable": true},
+		},
+		"ga set to its default": {
+			value: "Stable=true",
+			want:  map[Feature]bool{"Alpha": false, "Beta": true, "Stable": true},
+		},
+		"ga disabled": {
+			value:   "Stable=false",
+			wantErr: true,
+		},
+		"unknown feature": {
+			value:   "Alpha=true,HTTP3=true",
+			wantErr: true,
+		},
+		"missing value": {
+			value:   "Alpha",
+			wantErr: true,
+		},
+		"invalid value": {
+			value:   "Alpha=maybe",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fg := New(known)
+			err := fg.Set(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				// a rejected value changes nothing.
+				assert.Equal(t, "", fg.String())
+				return
+			}
+			got := make(map[Feature]bool)
+			for feature := range known {
+				got[feature] = fg.Enabled(feature)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFeatureGateUnknownDisabled(t *testing.T) {
+	fg := New(map[Feature]Spec{})
+	assert.Equal(t, false, fg.Enabled("Delta"))
+}
+
+func TestFeatureGateString(t *testing.T) {
+	fg := New(map[Feature]Spec{
+		"A": {Stage: Alpha, Since: "v1.1.0"},
+		"B": {Stage: Alpha, Since: "v1.1.0"},
+	})
+	if err := fg.Set("B=true,A=false"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "A=false,B=true", fg.String())
+	assert.Equal(t, []string{
+		"A=true|false (ALPHA - default=false, since v1.1.0)",
+		"B=true|false (ALPHA - default=false, since v1.1.0)",
+	}, fg.KnownFeatures())
+}
@@ -0,0 +1,37 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregate
+
+const (
+	// MergeVirtualHosts merges virtual hosts whose routes are
+	// identical into one, reducing the size of the route
+	// configuration sent to Envoy.
+	MergeVirtualHosts Feature = "MergeVirtualHosts"
+
+	// RouteStats names each route after the HTTPProxy which
+	// defined it, so Envoy reports statistics per route.
+	RouteStats Feature = "RouteStats"
+)
+
+// ContourFeatures are the features of Contour governed by feature gates.
+var ContourFeatures = map[Feature]Spec{
+	MergeVirtualHosts: {Default: false, Stage: Alpha, Since: "v1.1.0"},
+	RouteStats:        {Default: false, Stage: Alpha, Since: "v1.1.0"},
+}
+
+// NewContourFeatureGate returns a FeatureGate for the
+// features of Contour, each at its default.
+func NewContourFeatureGate() *FeatureGate {
+	return New(ContourFeatures)
+}
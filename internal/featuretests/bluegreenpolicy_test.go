@@ -0,0 +1,136 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestBlueGreenPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	blue := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blue",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(blue)
+
+	green := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "green",
+			Namespace: blue.Namespace,
+		},
+		Spec: blue.Spec,
+	}
+	rh.OnAdd(green)
+
+	proxy := func(promote bool) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "simple",
+				Namespace: blue.Namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "bluegreen.example.com",
+				},
+				Routes: []projcontour.Route{{
+					Conditions: prefixCondition("/"),
+					Services: []projcontour.Service{{
+						Name: blue.Name,
+						Port: 80,
+					}, {
+						Name: green.Name,
+						Port: 80,
+					}},
+					BlueGreenPolicy: &projcontour.BlueGreenPolicy{
+						ActiveService:  blue.Name,
+						PreviewService: green.Name,
+						Promote:        promote,
+						PreviewHeader: &projcontour.HeaderCondition{
+							Name:    "x-preview",
+							Present: true,
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	preview := dag.HeaderCondition{
+		Name:      "x-preview",
+		MatchType: "present",
+	}
+
+	p1 := proxy(false)
+	rh.OnAdd(p1)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("bluegreen.example.com",
+					envoy.Route(envoy.RoutePrefix("/", preview), routeCluster("default/green/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/blue/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// promoting the preview service flips the traffic atomically.
+	p2 := proxy(true)
+	rh.OnUpdate(p1, p2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("bluegreen.example.com",
+					envoy.Route(envoy.RoutePrefix("/", preview), routeCluster("default/blue/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/green/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// a preview service which is not one of the route's services is invalid.
+	p3 := proxy(false)
+	p3.Spec.Routes[0].BlueGreenPolicy.PreviewService = "missing"
+	rh.OnUpdate(p2, p3)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestClassificationPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	stable := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(stable)
+
+	canary := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard-v2",
+			Namespace: stable.Namespace,
+		},
+		Spec: stable.Spec,
+	}
+	rh.OnAdd(canary)
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: stable.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				ClassificationPolicy: &projcontour.ClassificationPolicy{
+					Header: "x-release",
+				},
+				Services: []projcontour.Service{{
+					Name:   stable.Name,
+					Port:   80,
+					Weight: 90,
+				}, {
+					Name:           canary.Name,
+					Port:           80,
+					Weight:         10,
+					Classification: "canary",
+				}},
+			}},
+		},
+	})
+
+	// each weighted cluster sets the header to its classification,
+	// which defaults to the name of the service.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					envoy.Route(envoy.RoutePrefix("/"), &envoy_api_v2_route.Route_Route{
+						Route: &envoy_api_v2_route.RouteAction{
+							ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
+								WeightedClusters: &envoy_api_v2_route.WeightedCluster{
+									Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{{
+										Name:                 "default/kuard-v2/80/da39a3ee5e",
+										Weight:               protobuf.UInt32(10),
+										ResponseHeadersToAdd: envoy.Headers(envoy.SetHeader("x-release", "canary")),
+									}, {
+										Name:                 "default/kuard/80/da39a3ee5e",
+										Weight:               protobuf.UInt32(90),
+										ResponseHeadersToAdd: envoy.Headers(envoy.SetHeader("x-release", "kuard")),
+									}},
+									TotalWeight: protobuf.UInt32(100),
+								},
+							},
+						},
+					}),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,265 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClientValidation(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "clients",
+			Namespace: sec1.Namespace,
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	rh.OnAdd(ca)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: sec1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     80,
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: ca.Name,
+						ForwardClientCertificate: &projcontour.ClientCertificateDetails{
+							Subject: true,
+							URI:     true,
+						},
+					},
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	// client certificates are required, and their details
+	// forwarded, on connections to the vhost.
+	dv := &dag.DownstreamValidation{
+		CACertificate: &dag.Secret{Object: ca},
+		ForwardClientCertificate: &dag.ClientCertificateDetails{
+			Subject: true,
+			URI:     true,
+		},
+	}
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: []*envoy_api_v2_listener.FilterChain{
+					envoy.FilterChainTLS(
+						"kuard.example.com",
+						&dag.Secret{Object: sec1},
+						dv,
+						envoy.Filters(
+							envoy.ClientCertificateHTTPConnectionManager("ingress_https", "ingress_https/kuard.example.com", dv.ForwardClientCertificate, envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0),
+						),
+						envoy_api_v2_auth.TlsParameters_TLSv1_1,
+						envoy_api_v2_auth.TlsParameters_TLSv1_3,
+						nil,
+						"h2", "http/1.1",
+					),
+				},
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// a vhost without client validation shares ingress_https.
+	hp4 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "other.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnAdd(hp4)
+
+	vhost := func(fqdn string) *envoy_api_v2_route.VirtualHost {
+		return envoy.VirtualHost(fqdn,
+			&envoy_api_v2_route.Route{
+				Match:  envoy.RoutePrefix("/"),
+				Action: routeCluster("default/backend/80/da39a3ee5e"),
+			},
+		)
+	}
+
+	// the protected vhost is not reachable from the connections of
+	// other vhosts by naming it in the Host header.
+	c.Request(routeType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https", vhost("other.example.com")),
+		),
+		TypeUrl: routeType,
+	})
+
+	// and its own connections serve only it, misdirecting other hosts.
+	c.Request(routeType, "ingress_https/kuard.example.com").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https/kuard.example.com",
+				vhost("kuard.example.com"),
+				envoy.MisdirectedVirtualHost(),
+			),
+		),
+		TypeUrl: routeType,
+	})
+	rh.OnDelete(hp4)
+
+	// a missing CA secret invalidates the proxy.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: "missing",
+					},
+				},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+	})
+
+	crl := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "revoked-clients",
+			Namespace: sec1.Namespace,
+		},
+		Data: map[string][]byte{
+			"crl.pem": []byte(CRL),
+		},
+	}
+	rh.OnAdd(crl)
+
+	// client certificates are checked against the CRL, if one is supplied.
+	hp3 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate:             ca.Name,
+						CertificateRevocationList: crl.Name,
+					},
+				},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp2, hp3)
+
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: []*envoy_api_v2_listener.FilterChain{
+					envoy.FilterChainTLS(
+						"kuard.example.com",
+						&dag.Secret{Object: sec1},
+						&dag.DownstreamValidation{
+							CACertificate:             &dag.Secret{Object: ca},
+							CertificateRevocationList: &dag.Secret{Object: crl},
+						},
+						envoy.Filters(
+							envoy.RoutedHTTPConnectionManager("ingress_https", "ingress_https/kuard.example.com", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0),
+						),
+						envoy_api_v2_auth.TlsParameters_TLSv1_1,
+						envoy_api_v2_auth.TlsParameters_TLSv1_3,
+						nil,
+						"h2", "http/1.1",
+					),
+				},
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
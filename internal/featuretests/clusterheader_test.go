@@ -0,0 +1,120 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestClusterHeaderPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	for _, name := range []string{"app-a", "app-b"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	p1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "gateway.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: "app-a",
+					Port: 80,
+				}, {
+					Name: "app-b",
+					Port: 80,
+				}},
+				ClusterHeaderPolicy: &projcontour.ClusterHeaderPolicy{
+					Header: "X-Upstream-Cluster",
+				},
+			}},
+		},
+	}
+	rh.OnAdd(p1)
+
+	regex := "default/app-a/80/da39a3ee5e|default/app-b/80/da39a3ee5e"
+
+	// requests naming one of the route's clusters are routed to it,
+	// all others are balanced across the route's services.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("gateway.example.com",
+					&envoy_api_v2_route.Route{
+						Match: &envoy_api_v2_route.RouteMatch{
+							PathSpecifier: &envoy_api_v2_route.RouteMatch_Prefix{
+								Prefix: "/",
+							},
+							Headers: []*envoy_api_v2_route.HeaderMatcher{{
+								Name: "X-Upstream-Cluster",
+								HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+									SafeRegexMatch: &matcher.RegexMatcher{
+										EngineType: &matcher.RegexMatcher_GoogleRe2{
+											GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+												MaxProgramSize: protobuf.UInt32(uint32(len(regex) + 8)),
+											},
+										},
+										Regex: regex,
+									},
+								},
+							}},
+						},
+						Action: &envoy_api_v2_route.Route_Route{
+							Route: &envoy_api_v2_route.RouteAction{
+								ClusterSpecifier: &envoy_api_v2_route.RouteAction_ClusterHeader{
+									ClusterHeader: "X-Upstream-Cluster",
+								},
+							},
+						},
+					},
+					envoy.Route(envoy.RoutePrefix("/"), routeWeightedCluster(
+						weightedCluster{"default/app-a/80/da39a3ee5e", 1},
+						weightedCluster{"default/app-b/80/da39a3ee5e", 1},
+					)),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
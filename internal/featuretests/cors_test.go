@@ -0,0 +1,139 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				CORSPolicy: &projcontour.CORSPolicy{
+					AllowOrigin:      []string{"https://www.example.com"},
+					AllowOriginRegex: []string{`https://.*\.example\.org`},
+					AllowMethods:     []string{"GET", "POST"},
+					AllowHeaders:     []string{"Authorization", "Content-Type"},
+					ExposeHeaders:    []string{"X-Request-Id"},
+					MaxAge:           "10m",
+					AllowCredentials: true,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	action := routeCluster("default/backend/80/da39a3ee5e")
+	action.Route.Cors = &envoy_api_v2_route.CorsPolicy{
+		AllowOriginStringMatch: []*matcher.StringMatcher{{
+			MatchPattern: &matcher.StringMatcher_Exact{
+				Exact: "https://www.example.com",
+			},
+		}, {
+			MatchPattern: &matcher.StringMatcher_SafeRegex{
+				SafeRegex: &matcher.RegexMatcher{
+					EngineType: &matcher.RegexMatcher_GoogleRe2{
+						GoogleRe2: &matcher.RegexMatcher_GoogleRE2{
+							MaxProgramSize: protobuf.UInt32(48),
+						},
+					},
+					Regex: `https://.*\.example\.org`,
+				},
+			},
+		}},
+		AllowMethods:     "GET,POST",
+		AllowHeaders:     "Authorization,Content-Type",
+		ExposeHeaders:    "X-Request-Id",
+		MaxAge:           "600",
+		AllowCredentials: protobuf.Bool(true),
+	}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: action,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// credentials cannot be allowed from any origin.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				CORSPolicy: &projcontour.CORSPolicy{
+					AllowOrigin:      []string{"*"},
+					AllowCredentials: true,
+				},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
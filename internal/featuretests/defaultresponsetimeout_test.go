@@ -0,0 +1,101 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDefaultResponseTimeout(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.CacheHandler.RouteVisitorConfig.ResponseTimeout = 30 * time.Second
+	})
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/slow"),
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1m",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/events"),
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "infinity",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	})
+
+	// routes without a response timeout use the default, routes
+	// with a response timeout of infinity opt out of it.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					envoy.Route(envoy.RoutePrefix("/slow"), withResponseTimeout(routeCluster("default/kuard/80/da39a3ee5e"), time.Minute)),
+					envoy.Route(envoy.RoutePrefix("/events"), withResponseTimeout(routeCluster("default/kuard/80/da39a3ee5e"), 0)),
+					envoy.Route(envoy.RoutePrefix("/"), withResponseTimeout(routeCluster("default/kuard/80/da39a3ee5e"), 30*time.Second)),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDownstreamProtocolPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	// the vhost requires HTTP/2, except on /legacy which
+	// requires HTTP/1.1 and answers other requests with 400.
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				DownstreamProtocolPolicy: &projcontour.DownstreamProtocolPolicy{
+					Protocol: "HTTP/2",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/legacy"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+				DownstreamProtocolPolicy: &projcontour.DownstreamProtocolPolicy{
+					Protocol: "HTTP/1.1",
+					Status:   400,
+				},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/legacy"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: envoy.DownstreamProtocolMetadata("HTTP/1.1", 400),
+					},
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: envoy.DownstreamProtocolMetadata("HTTP/2", 426),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.DownstreamProtocolFilter(),
+					),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// an unknown protocol invalidates the proxy.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				DownstreamProtocolPolicy: &projcontour.DownstreamProtocolPolicy{
+					Protocol: "HTTP/3",
+				},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+	})
+}
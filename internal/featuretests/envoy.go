@@ -165,10 +165,13 @@ func filterchaintls(domain string, secret *v1.Secret, filter *envoy_api_v2_liste
 		envoy.FilterChainTLS(
 			domain,
 			&dag.Secret{Object: secret},
+			nil,
 			[]*envoy_api_v2_listener.Filter{
 				filter,
 			},
 			envoy_api_v2_auth.TlsParameters_TLSv1_1,
+			envoy_api_v2_auth.TlsParameters_TLSv1_3,
+			nil,
 			alpn...,
 		),
 	}
@@ -0,0 +1,125 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestExactCondition(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	service := func(name string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		}
+	}
+	rh.OnAdd(service("health"))
+	rh.OnAdd(service("backend"))
+
+	root := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "root",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "exact.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: "backend",
+					Port: 80,
+				}},
+			}, {
+				Conditions: []projcontour.Condition{{
+					Exact: "/healthz",
+				}},
+				Services: []projcontour.Service{{
+					Name: "health",
+					Port: 80,
+				}},
+			}},
+			Includes: []projcontour.Include{{
+				Name:       "child",
+				Namespace:  "default",
+				Conditions: prefixCondition("/api"),
+			}},
+		},
+	}
+	rh.OnAdd(root)
+
+	child := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "child",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Exact: "/healthz",
+				}},
+				Services: []projcontour.Service{{
+					Name: "health",
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(child)
+
+	// exact paths, including those under an include's prefix, sort
+	// ahead of prefixes, so /healthz-internal is not sent to health.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("exact.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePath("/healthz"),
+						Action: routeCluster("default/health/80/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePath("/api/healthz"),
+						Action: routeCluster("default/health/80/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: routeCluster("default/backend/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
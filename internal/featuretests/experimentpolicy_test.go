@@ -0,0 +1,128 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestExperimentPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				ExperimentPolicy: &projcontour.ExperimentPolicy{
+					TTL: "24h",
+					Variants: []projcontour.ExperimentVariant{{
+						Name:    "control",
+						Percent: 80,
+					}, {
+						Name:    "treatment",
+						Percent: 20,
+					}},
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	})
+
+	assigned := func(variant string) dag.HeaderCondition {
+		return dag.HeaderCondition{
+			Name:      "cookie",
+			Value:     "X-Contour-Experiment=" + variant + ".",
+			MatchType: "contains",
+		}
+	}
+
+	variant := func(variant string, weight uint32, assign bool) *envoy_api_v2_route.WeightedCluster_ClusterWeight {
+		cw := &envoy_api_v2_route.WeightedCluster_ClusterWeight{
+			Name:                "default/kuard/80/da39a3ee5e",
+			Weight:              protobuf.UInt32(weight),
+			RequestHeadersToAdd: envoy.Headers(envoy.SetHeader("X-Contour-Experiment", variant)),
+		}
+		if assign {
+			cw.ResponseHeadersToAdd = envoy.Headers(envoy.AppendHeader("set-cookie", "X-Contour-Experiment="+variant+".; Path=/; HttpOnly; Max-Age=86400"))
+		}
+		return cw
+	}
+
+	weighted := func(total uint32, clusters ...*envoy_api_v2_route.WeightedCluster_ClusterWeight) *envoy_api_v2_route.Route_Route {
+		return &envoy_api_v2_route.Route_Route{
+			Route: &envoy_api_v2_route.RouteAction{
+				ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
+					WeightedClusters: &envoy_api_v2_route.WeightedCluster{
+						Clusters:    clusters,
+						TotalWeight: protobuf.UInt32(total),
+					},
+				},
+			},
+		}
+	}
+
+	// requests carrying an experiment cookie keep their variant, other
+	// requests are assigned a variant by percentage and issued a cookie.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					envoy.Route(envoy.RoutePrefix("/", assigned("control")), weighted(1, variant("control", 1, false))),
+					envoy.Route(envoy.RoutePrefix("/", assigned("treatment")), weighted(1, variant("treatment", 1, false))),
+					envoy.Route(envoy.RoutePrefix("/"), weighted(100,
+						variant("treatment", 20, true),
+						variant("control", 80, true),
+					)),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,352 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRequestHeadersAllowList(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:                    "kuard.example.com",
+				RequestHeadersAllowList: []string{"Authorization", "X-Tenant"},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: envoy.RequestHeadersAllowListMetadata([]string{"authorization", "x-tenant"}),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RequestHeadersAllowListFilter(),
+					),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// a blank header name invalidates the proxy.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:                    "kuard.example.com",
+				RequestHeadersAllowList: []string{"Authorization", " "},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+	})
+}
+
+func TestRequestHeadersAllowListForwardClientCertificate(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "clients",
+			Namespace: sec1.Namespace,
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	rh.OnAdd(ca)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: sec1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: ca.Name,
+						ForwardClientCertificate: &projcontour.ClientCertificateDetails{
+							Subject: true,
+						},
+					},
+				},
+				RequestHeadersAllowList: []string{"X-Tenant"},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	// the header carrying the forwarded client certificate
+	// details is allowed through to the backend.
+	c.Request(routeType, "ingress_https/kuard.example.com").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https/kuard.example.com",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: envoy.RequestHeadersAllowListMetadata([]string{"x-tenant", "x-forwarded-client-cert"}),
+					},
+				),
+				envoy.MisdirectedVirtualHost(),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// without forwarding, the header is removed like any other.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+					ClientValidation: &projcontour.DownstreamValidation{
+						CACertificate: ca.Name,
+					},
+				},
+				RequestHeadersAllowList: []string{"X-Tenant"},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_https/kuard.example.com").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https/kuard.example.com",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: envoy.RequestHeadersAllowListMetadata([]string{"x-tenant"}),
+					},
+				),
+				envoy.MisdirectedVirtualHost(),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
+
+func TestRequestHeadersAllowListExternalAuthorization(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: sec1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auth",
+			Namespace: sec1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       9001,
+				TargetPort: intstr.FromInt(9001),
+			}},
+		},
+	}
+	rh.OnAdd(s2)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				Authorization: &projcontour.AuthorizationServer{
+					Name: s2.Name,
+					Port: 9001,
+				},
+				RequestHeadersAllowList: []string{"X-Tenant"},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	as := &dag.AuthorizationServer{
+		Cluster: &dag.Cluster{
+			Upstream: &dag.Service{
+				Name:        s2.Name,
+				Namespace:   s2.Namespace,
+				ServicePort: &s2.Spec.Ports[0],
+			},
+		},
+	}
+
+	// the allow list is enforced before the request is authorized,
+	// so the headers added by the authorization service reach the
+	// backend.
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: filterchaintls("kuard.example.com", sec1,
+					envoy.RoutedHTTPConnectionManager("ingress_https", "ingress_https/kuard.example.com", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RequestHeadersAllowListFilter(),
+						envoy.ExtAuthzFilter(as),
+					),
+					"h2", "http/1.1",
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
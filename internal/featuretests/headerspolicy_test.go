@@ -0,0 +1,142 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestHeadersPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "x-client-address",
+						Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+					}},
+					Remove: []string{"x-internal-token"},
+				},
+				ResponseHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "x-discount",
+						Value: "100%",
+					}},
+					Remove: []string{"server"},
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:                   envoy.RoutePrefix("/"),
+						Action:                  routeCluster("default/backend/80/da39a3ee5e"),
+						RequestHeadersToAdd:     envoy.Headers(envoy.SetHeader("X-Client-Address", "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%")),
+						RequestHeadersToRemove:  []string{"X-Internal-Token"},
+						ResponseHeadersToAdd:    envoy.Headers(envoy.SetHeader("X-Discount", "100%%")),
+						ResponseHeadersToRemove: []string{"Server"},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// service policies are applied to the service's weighted cluster.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: hp1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+					RequestHeadersPolicy: &projcontour.HeadersPolicy{
+						Set: []projcontour.HeaderValue{{
+							Name:  "x-backend",
+							Value: "backend",
+						}},
+					},
+					ResponseHeadersPolicy: &projcontour.HeadersPolicy{
+						Remove: []string{"x-backend-version"},
+					},
+				}},
+			}},
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	action := routeWeightedCluster(weightedCluster{"default/backend/80/da39a3ee5e", 1})
+	cw := action.Route.GetWeightedClusters().Clusters[0]
+	cw.RequestHeadersToAdd = envoy.Headers(envoy.SetHeader("X-Backend", "backend"))
+	cw.ResponseHeadersToRemove = []string{"X-Backend-Version"}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: action,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -687,5 +687,34 @@ func TestIngressClassAnnotation(t *testing.T) {
 		TypeUrl: routeType,
 	})
 
-	rh.OnDelete(proxy7)
+	// spec.ingressClassName takes precedence over the annotation.
+	proxy8 := proxy7.DeepCopy()
+	proxy8.Spec.IngressClassName = "contour"
+	rh.OnUpdate(proxy7, proxy8)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
+
+	proxy9 := proxy6.DeepCopy()
+	proxy9.Spec.IngressClassName = "linkerd"
+	rh.OnUpdate(proxy8, proxy9)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost(proxy9.Spec.VirtualHost.Fqdn,
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/kuard/8080/da39a3ee5e")),
+				),
+			),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
+
+	rh.OnDelete(proxy9)
 }
@@ -0,0 +1,108 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// TestIngressAnnotationsAcrossAPIVersions asserts that the route
+// annotations are handled identically regardless of the API version
+// an Ingress object is delivered with.
+func TestIngressAnnotationsAcrossAPIVersions(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	annotations := map[string]string{
+		"projectcontour.io/response-timeout":  "1m20s",
+		"contour.heptio.com/response-timeout": "10s", // projectcontour.io takes precedence
+		"contour.heptio.com/retry-on":         "5xx",
+		"projectcontour.io/num-retries":       "3",
+		"projectcontour.io/per-try-timeout":   "150ms",
+		"projectcontour.io/websocket-routes":  "/",
+	}
+
+	want := &v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("*",
+					envoy.Route(envoy.RoutePrefix("/"),
+						withWebsocket(
+							withResponseTimeout(
+								withRetryPolicy(routeCluster("default/backend/80/da39a3ee5e"), "5xx", 3, 150*time.Millisecond),
+								80*time.Second,
+							),
+						),
+					),
+				),
+			),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	}
+
+	i1 := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hello",
+			Namespace:   s1.Namespace,
+			Annotations: annotations,
+		},
+		Spec: v1beta1.IngressSpec{
+			Backend: backend(s1),
+		},
+	}
+	rh.OnAdd(i1)
+	c.Request(routeType).Equals(want)
+	rh.OnDelete(i1)
+
+	i2 := &extensionsv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hello",
+			Namespace:   s1.Namespace,
+			Annotations: annotations,
+		},
+		Spec: extensionsv1beta1.IngressSpec{
+			Backend: &extensionsv1beta1.IngressBackend{
+				ServiceName: s1.Name,
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+	rh.OnAdd(i2)
+	c.Request(routeType).Equals(want)
+}
@@ -0,0 +1,264 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestJWTVerification(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auth-ca",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte(CERTIFICATE),
+		},
+	}
+	rh.OnAdd(ca)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				JWTProviders: []projcontour.JWTProvider{{
+					Name:      "provider-1",
+					Issuer:    "https://auth.example.com",
+					Audiences: []string{"kuard"},
+					RemoteJWKS: projcontour.RemoteJWKS{
+						URI:     "https://auth.example.com/.well-known/jwks.json",
+						Timeout: "2s",
+						UpstreamValidation: &projcontour.UpstreamValidation{
+							CACertificate: ca.Name,
+							SubjectName:   "auth.example.com",
+						},
+					},
+					Default: true,
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/public"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+				JWTVerificationPolicy: &projcontour.JWTVerificationPolicy{
+					Disabled: true,
+				},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	// the host serving the JWKS is added as a DNS cluster, whose
+	// certificate is validated.
+	jwksTLS := envoy.UpstreamTLSContext([]byte(CERTIFICATE), "auth.example.com")
+	jwksTLS.Sni = "auth.example.com"
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			DefaultCluster(&v2.Cluster{
+				Name:                 "default/auth.example.com/443/b72da26a72",
+				AltStatName:          "default_auth.example.com_443",
+				ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_STRICT_DNS),
+				LoadAssignment: &v2.ClusterLoadAssignment{
+					ClusterName: "default/auth.example.com/https",
+					Endpoints:   envoy.Endpoints(envoy.SocketAddress("auth.example.com", 443)),
+				},
+				TlsContext: jwksTLS,
+			}),
+			cluster("default/backend/80/da39a3ee5e", "default/backend", "default_backend_80"),
+		),
+		TypeUrl: clusterType,
+	})
+
+	providers := []*dag.JWTProvider{{
+		Name:      "provider-1",
+		Issuer:    "https://auth.example.com",
+		Audiences: []string{"kuard"},
+		RemoteJWKS: dag.RemoteJWKS{
+			URI: "https://auth.example.com/.well-known/jwks.json",
+			Cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:      "auth.example.com",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Name:     "https",
+						Protocol: "TCP",
+						Port:     443,
+					},
+					Protocol:     "tls",
+					ExternalName: "auth.example.com",
+				},
+				SNI: "auth.example.com",
+				UpstreamValidation: &dag.UpstreamValidation{
+					CACertificate: &dag.Secret{Object: ca},
+					SubjectName:   "auth.example.com",
+				},
+			},
+			Timeout: 2 * time.Second,
+		},
+	}}
+
+	// routes are matched most specific first, as by the route table.
+	rules := []envoy.JWTRule{{
+		Match: envoy.RoutePrefix("/public"),
+	}, {
+		Match:    envoy.RoutePrefix("/"),
+		Provider: "provider-1",
+	}}
+
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: filterchaintls("kuard.example.com", sec1,
+					envoy.RoutedHTTPConnectionManager("ingress_https", "ingress_https/kuard.example.com", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.JWTAuthnFilter(providers, rules),
+					),
+					"h2", "http/1.1",
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// a vhost without JWT verification shares ingress_https, from
+	// whose connections the verified vhost cannot be reached by
+	// naming it in the Host header.
+	hp3 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "other.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services:   hp1.Spec.Routes[0].Services,
+			}},
+		},
+	}
+	rh.OnAdd(hp3)
+
+	vhost := func(fqdn string, routes ...*envoy_api_v2_route.Route) *envoy_api_v2_route.VirtualHost {
+		return envoy.VirtualHost(fqdn, append(routes, &envoy_api_v2_route.Route{
+			Match:  envoy.RoutePrefix("/"),
+			Action: routeCluster("default/backend/80/da39a3ee5e"),
+		})...)
+	}
+
+	c.Request(routeType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https", vhost("other.example.com")),
+		),
+		TypeUrl: routeType,
+	})
+
+	// the verified vhost is served from its own route configuration.
+	c.Request(routeType, "ingress_https/kuard.example.com").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https/kuard.example.com",
+				vhost("kuard.example.com", &envoy_api_v2_route.Route{
+					Match:  envoy.RoutePrefix("/public"),
+					Action: routeCluster("default/backend/80/da39a3ee5e"),
+				}),
+				envoy.MisdirectedVirtualHost(),
+			),
+		),
+		TypeUrl: routeType,
+	})
+	rh.OnDelete(hp3)
+
+	// a route which permits insecure requests must not require a JWT.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: hp1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions:     prefixCondition("/"),
+				Services:       hp1.Spec.Routes[0].Services,
+				PermitInsecure: true,
+			}},
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -116,6 +116,15 @@ KMkcE4BT8IZIHQ+wIMhmYLAdSQCVVv8x78jN0sZCC0fjqVuyPdYQ8sIc3OHsJZcW
 lzewFW72lfsiB/RxWZ/XwXONXeW5Quf+XwbGGboTofyzTxzsYSwn1U9Kt8iaY8zr
 z7Z5SQCSf2Js9V9lJcodYswWlxrdtoRKA/WgrvQkZhGGAePTUVoO5Lab29M8
 -----END RSA PRIVATE KEY-----`
+
+	// CRL is a certificate revocation list, revoking no certificates,
+	// issued by a test CA.
+	CRL = `-----BEGIN X509 CRL-----
+MIGqMFMCAQEwCgYIKoZIzj0EAwIwFDESMBAGA1UEAwwJY2xpZW50LWNhFw0yNjEw
+MTUxMzQ1MTNaFw0zNjEwMTIxMzQ1MTNaoA4wDDAKBgNVHRQEAwIBATAKBggqhkjO
+PQQDAgNHADBEAiA8xCp6JPPhSytlt6m1mSziv63l8BVHrZjwmLbaFP3EtwIgHlRg
+UDVRmXu6nHypRutP5QRGf+BKVwKu5YLV7mJQYmU=
+-----END X509 CRL-----`
 )
 
 func secretdata(cert, key string) map[string][]byte {
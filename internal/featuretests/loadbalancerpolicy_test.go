@@ -15,11 +15,14 @@ package featuretests
 
 import (
 	"testing"
+	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -246,4 +249,39 @@ func TestLoadBalancerPolicySessionAffinity(t *testing.T) {
 		),
 		TypeUrl: routeType,
 	})
+
+	// custom session affinity cookie
+	proxy3 := proxy2.DeepCopy()
+	proxy3.Spec.Routes[0].LoadBalancerPolicy.Cookie = &projcontour.SessionAffinityCookie{
+		Name: "cart-affinity",
+		TTL:  "30m",
+		Path: "/cart",
+	}
+	rh.OnUpdate(proxy2, proxy3)
+
+	affinity := routeWeightedCluster(
+		weightedCluster{"default/app/80/e4f81994fe", 1},
+		weightedCluster{"default/app/8080/e4f81994fe", 1},
+	)
+	affinity.Route.HashPolicy = []*envoy_api_v2_route.RouteAction_HashPolicy{{
+		PolicySpecifier: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie_{
+			Cookie: &envoy_api_v2_route.RouteAction_HashPolicy_Cookie{
+				Name: "cart-affinity",
+				Ttl:  protobuf.Duration(30 * time.Minute),
+				Path: "/cart",
+			},
+		},
+	}}
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("www.example.com",
+					envoy.Route(envoy.RoutePrefix("/cart"), affinity),
+				),
+			),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
 }
@@ -0,0 +1,139 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestVirtualHostMaintenance(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "maintenance-page",
+			Namespace: s1.Namespace,
+		},
+		Spec: s1.Spec,
+	}
+	rh.OnAdd(s2)
+
+	proxy := func(maintenance bool, mp *projcontour.MaintenancePolicy) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "simple",
+				Namespace: s1.Namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn:              "example.com",
+					Maintenance:       maintenance,
+					MaintenancePolicy: mp,
+				},
+				Routes: []projcontour.Route{{
+					Conditions: prefixCondition("/"),
+					Services: []projcontour.Service{{
+						Name: s1.Name,
+						Port: 80,
+					}},
+				}, {
+					Conditions: prefixCondition("/api"),
+					Services: []projcontour.Service{{
+						Name: s1.Name,
+						Port: 80,
+					}},
+				}},
+			},
+		}
+	}
+
+	// in maintenance every request is answered with a 503.
+	p1 := proxy(true, nil)
+	rh.OnAdd(p1)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: envoy.DirectResponse(503),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// the maintenance service serves every request.
+	p2 := proxy(true, &projcontour.MaintenancePolicy{
+		Service: &projcontour.Service{
+			Name: s2.Name,
+			Port: 80,
+		},
+	})
+	rh.OnUpdate(p1, p2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/maintenance-page/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// leaving maintenance restores the routes, the policy is kept
+	// ready for the next maintenance window.
+	p3 := proxy(false, p2.Spec.VirtualHost.MaintenancePolicy)
+	rh.OnUpdate(p2, p3)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					envoy.Route(envoy.RoutePrefix("/api"), routeCluster("default/kuard/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/kuard/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -17,6 +17,8 @@ import (
 	"testing"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/envoy"
@@ -88,4 +90,54 @@ func TestMirrorPolicy(t *testing.T) {
 		),
 		TypeUrl: routeType,
 	})
+
+	// the mirror service's cluster is sent to Envoy.
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			cluster("default/kuard/8080/da39a3ee5e", "default/kuard", "default_kuard_8080"),
+			cluster("default/kuarder/8080/da39a3ee5e", "default/kuarder", "default_kuarder_8080"),
+		),
+		TypeUrl: clusterType,
+	})
+
+	// a mirror policy mirrors a percentage of requests.
+	p2 := &projcontour.HTTPProxy{
+		ObjectMeta: p1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: p1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: svc1.Name,
+					Port: 8080,
+				}},
+				MirrorPolicy: &projcontour.MirrorPolicy{
+					Name:    svc2.Name,
+					Port:    8080,
+					Percent: 5,
+				},
+			}},
+		},
+	}
+	rh.OnUpdate(p1, p2)
+
+	mirrored := withMirrorPolicy(routeCluster("default/kuard/8080/da39a3ee5e"), "default/kuarder/8080/da39a3ee5e")
+	mirrored.Route.RequestMirrorPolicy.RuntimeFraction = &envoy_api_v2_core.RuntimeFractionalPercent{
+		DefaultValue: &envoy_type.FractionalPercent{
+			Numerator:   5,
+			Denominator: envoy_type.FractionalPercent_HUNDRED,
+		},
+	}
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost(p1.Spec.VirtualHost.Fqdn,
+					envoy.Route(envoy.RoutePrefix("/"), mirrored),
+				),
+			),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
 }
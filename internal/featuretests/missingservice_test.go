@@ -0,0 +1,124 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestMissingServiceDegradesRoute(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/missing"),
+				Services: []projcontour.Service{{
+					Name: "missing",
+					Port: 80,
+				}},
+			}, {
+				Conditions: prefixCondition("/wrongport"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 9999,
+				}},
+			}},
+		},
+	})
+
+	// the routes to the missing service and port respond with 503,
+	// the route to kuard is unaffected.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/wrongport"),
+						Action: envoy.DirectResponse(503),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/missing"),
+						Action: envoy.DirectResponse(503),
+					},
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/kuard/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// once the service appears the route is restored.
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "missing",
+			Namespace: s1.Namespace,
+		},
+		Spec: s1.Spec,
+	}
+	rh.OnAdd(s2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/wrongport"),
+						Action: envoy.DirectResponse(503),
+					},
+					envoy.Route(envoy.RoutePrefix("/missing"), routeCluster("default/missing/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/"), routeCluster("default/kuard/80/da39a3ee5e")),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPathRewritePolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	// the child is included by two roots, at different prefixes.
+	root := func(name, fqdn, prefix string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s1.Namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+				},
+				Includes: []projcontour.Include{{
+					Name:       "child",
+					Namespace:  s1.Namespace,
+					Conditions: prefixCondition(prefix),
+				}},
+			},
+		}
+	}
+	rh.OnAdd(root("api", "api.example.com", "/api"))
+	rh.OnAdd(root("legacy", "legacy.example.com", "/v1"))
+
+	child := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "child",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				PathRewritePolicy: &projcontour.PathRewritePolicy{
+					ReplacePrefix: []projcontour.ReplacePrefix{{
+						Prefix:      "/api",
+						Replacement: "/",
+					}},
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(child)
+
+	// only the prefix the replacement names is rewritten.
+	rewritten := routeCluster("default/backend/80/da39a3ee5e")
+	rewritten.Route.PrefixRewrite = "/"
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("api.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/api"),
+						Action: rewritten,
+					},
+				),
+				envoy.VirtualHost("legacy.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/v1"),
+						Action: routeCluster("default/backend/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// a replacement without a prefix applies to the other prefixes.
+	child2 := child.DeepCopy()
+	child2.Spec.Routes[0].PathRewritePolicy.ReplacePrefix = append(
+		child2.Spec.Routes[0].PathRewritePolicy.ReplacePrefix,
+		projcontour.ReplacePrefix{Replacement: "/api/v1"},
+	)
+	rh.OnUpdate(child, child2)
+
+	legacy := routeCluster("default/backend/80/da39a3ee5e")
+	legacy.Route.PrefixRewrite = "/api/v1"
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("api.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/api"),
+						Action: rewritten,
+					},
+				),
+				envoy.VirtualHost("legacy.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/v1"),
+						Action: legacy,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
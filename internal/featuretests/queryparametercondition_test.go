@@ -0,0 +1,161 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestQueryParameterCondition(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	service := func(name string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		}
+	}
+	rh.OnAdd(service("stable"))
+	rh.OnAdd(service("beta"))
+
+	// requests with ?flag=beta are sent to the beta child, others
+	// to the stable child.
+	root := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "root",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "query.example.com",
+			},
+			Includes: []projcontour.Include{{
+				Name:      "beta",
+				Namespace: "default",
+				Conditions: []projcontour.Condition{{
+					QueryParameter: &projcontour.QueryParameterCondition{
+						Name:  "flag",
+						Exact: "beta",
+					},
+				}},
+			}, {
+				Name:      "stable",
+				Namespace: "default",
+			}},
+		},
+	}
+	rh.OnAdd(root)
+
+	child := func(name string, conds ...projcontour.Condition) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				Routes: []projcontour.Route{{
+					Conditions: conds,
+					Services: []projcontour.Service{{
+						Name: name,
+						Port: 80,
+					}},
+				}},
+			},
+		}
+	}
+	rh.OnAdd(child("stable"))
+	beta := child("beta", projcontour.Condition{
+		Prefix: "/api",
+	}, projcontour.Condition{
+		Header: &projcontour.HeaderCondition{
+			Name:    "x-canary",
+			Present: true,
+		},
+	})
+	rh.OnAdd(beta)
+
+	// the include's query parameter condition is combined with the
+	// path and header conditions of the route.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("query.example.com",
+					&envoy_api_v2_route.Route{
+						Match: queryParameterMatch(envoy.RoutePrefix("/api", dag.HeaderCondition{
+							Name:      "x-canary",
+							MatchType: "present",
+						}), &envoy_api_v2_route.QueryParameterMatcher{
+							Name:  "flag",
+							Value: "beta",
+						}),
+						Action: routeCluster("default/beta/80/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: routeCluster("default/stable/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// an invalid query parameter condition drops the route.
+	beta2 := beta.DeepCopy()
+	beta2.Spec.Routes[0].Conditions = append(beta2.Spec.Routes[0].Conditions, projcontour.Condition{
+		QueryParameter: &projcontour.QueryParameterCondition{
+			Name:  "flag",
+			Exact: "alpha",
+		},
+	})
+	rh.OnUpdate(beta, beta2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("query.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: routeCluster("default/stable/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
+
+func queryParameterMatch(match *envoy_api_v2_route.RouteMatch, qps ...*envoy_api_v2_route.QueryParameterMatcher) *envoy_api_v2_route.RouteMatch {
+	match.QueryParameters = qps
+	return match
+}
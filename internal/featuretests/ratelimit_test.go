@@ -0,0 +1,374 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRateLimitPolicy(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.RateLimitService = dag.RateLimitServiceConfig{
+			Namespace: "projectcontour",
+			Name:      "ratelimit",
+			Port:      8081,
+			Domain:    "contour",
+			Timeout:   100 * time.Millisecond,
+		}
+	})
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ratelimit",
+			Namespace: "projectcontour",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8081,
+				TargetPort: intstr.FromInt(8081),
+			}},
+		},
+	}
+	rh.OnAdd(s2)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+				RateLimitPolicy: &projcontour.RateLimitPolicy{
+					Descriptors: []projcontour.RateLimitDescriptor{{
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							RemoteAddress: &projcontour.RemoteAddressDescriptor{},
+						}},
+					}, {
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							GenericKey: &projcontour.GenericKeyDescriptor{
+								Value: "api",
+							},
+						}, {
+							RequestHeader: &projcontour.RequestHeaderDescriptor{
+								HeaderName:    "X-Tenant",
+								DescriptorKey: "tenant",
+							},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	action := routeCluster("default/backend/80/da39a3ee5e")
+	action.Route.RateLimits = []*envoy_api_v2_route.RateLimit{{
+		Actions: []*envoy_api_v2_route.RateLimit_Action{{
+			ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_RemoteAddress_{
+				RemoteAddress: &envoy_api_v2_route.RateLimit_Action_RemoteAddress{},
+			},
+		}},
+	}, {
+		Actions: []*envoy_api_v2_route.RateLimit_Action{{
+			ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_GenericKey_{
+				GenericKey: &envoy_api_v2_route.RateLimit_Action_GenericKey{
+					DescriptorValue: "api",
+				},
+			},
+		}, {
+			ActionSpecifier: &envoy_api_v2_route.RateLimit_Action_RequestHeaders_{
+				RequestHeaders: &envoy_api_v2_route.RateLimit_Action_RequestHeaders{
+					HeaderName:    "X-Tenant",
+					DescriptorKey: "tenant",
+				},
+			},
+		}},
+	}}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: action,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// the rate limit service is added as an HTTP/2 cluster.
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			cluster("default/backend/80/da39a3ee5e", "default/backend", "default_backend_80"),
+			h2cCluster(cluster("projectcontour/ratelimit/8081/da39a3ee5e", "projectcontour/ratelimit", "projectcontour_ratelimit_8081")),
+		),
+		TypeUrl: clusterType,
+	})
+
+	rls := &dag.RateLimitService{
+		Cluster: &dag.Cluster{
+			Upstream: &dag.Service{
+				Name:        s2.Name,
+				Namespace:   s2.Namespace,
+				ServicePort: &s2.Spec.Ports[0],
+			},
+		},
+		Domain:  "contour",
+		Timeout: 100 * time.Millisecond,
+	}
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RateLimitFilter(rls),
+					),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// an entry with more than one source invalidates the route.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: hp1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services:   hp1.Spec.Routes[0].Services,
+				RateLimitPolicy: &projcontour.RateLimitPolicy{
+					Descriptors: []projcontour.RateLimitDescriptor{{
+						Entries: []projcontour.RateLimitDescriptorEntry{{
+							RemoteAddress: &projcontour.RemoteAddressDescriptor{},
+							GenericKey: &projcontour.GenericKeyDescriptor{
+								Value: "api",
+							},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
+
+func TestRateLimitFailOpen(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.RateLimitService = dag.RateLimitServiceConfig{
+			Namespace: "projectcontour",
+			Name:      "ratelimit",
+			Port:      8081,
+			Domain:    "contour",
+		}
+	})
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ratelimit",
+			Namespace: "projectcontour",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8081,
+				TargetPort: intstr.FromInt(8081),
+			}},
+		},
+	}
+	rh.OnAdd(s2)
+
+	failOpen := false
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				RateLimitFailOpen: &failOpen,
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	rls := &dag.RateLimitService{
+		Cluster: &dag.Cluster{
+			Upstream: &dag.Service{
+				Name:        s2.Name,
+				Namespace:   s2.Namespace,
+				ServicePort: &s2.Spec.Ports[0],
+			},
+		},
+		Domain: "contour",
+	}
+	deny := *rls
+	deny.FailureModeDeny = true
+
+	// the vhost rejects requests while the rate limit service
+	// cannot be reached, and is served from its own route
+	// configuration so other vhosts cannot reach its routes.
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: filterchaintls("kuard.example.com", sec1,
+					envoy.RoutedHTTPConnectionManager("ingress_https", "ingress_https/kuard.example.com", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RateLimitFilter(&deny),
+					),
+					"h2", "http/1.1",
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// the insecure listener follows the rate limit service.
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RateLimitFilter(rls),
+					),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// without an override the vhost follows the rate limit service.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+				TLS:  hp1.Spec.VirtualHost.TLS,
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: filterchaintls("kuard.example.com", sec1,
+					envoy.HTTPConnectionManager("ingress_https", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0,
+						envoy.RateLimitFilter(rls),
+					),
+					"h2", "http/1.1",
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
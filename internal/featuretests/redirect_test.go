@@ -0,0 +1,135 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVirtualHostRedirect(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	p1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apex",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn: "www.example.com",
+				},
+			},
+		},
+	}
+	rh.OnAdd(p1)
+
+	// no services are required to redirect.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: envoy.HostRedirect("www.example.com", "", 301),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: p1.Namespace,
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	p2 := &projcontour.HTTPProxy{
+		ObjectMeta: p1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &projcontour.TLS{
+					SecretName: sec1.Name,
+				},
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn:       "www.example.com",
+					Scheme:     "https",
+					StatusCode: 308,
+				},
+			},
+		},
+	}
+	rh.OnUpdate(p1, p2)
+
+	// insecure requests are upgraded to https first, as with any
+	// other TLS enabled virtual host.
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: envoy.UpgradeHTTPS(),
+					},
+				),
+			),
+			envoy.RouteConfiguration("ingress_https",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: envoy.HostRedirect("www.example.com", "https", 308),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// a redirect to the virtual host itself is invalid.
+	p3 := &projcontour.HTTPProxy{
+		ObjectMeta: p1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+				Redirect: &projcontour.VirtualHostRedirect{
+					Fqdn: "example.com",
+				},
+			},
+		},
+	}
+	rh.OnUpdate(p2, p3)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
+}
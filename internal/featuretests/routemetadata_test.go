@@ -0,0 +1,168 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRouteMetadata(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	tier, limit, audit := "gold", int64(100), true
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+				Metadata: []projcontour.RouteMetadata{{
+					Filter: "envoy.ext_authz",
+					Entries: []projcontour.MetadataEntry{{
+						Key:         "tier",
+						StringValue: &tier,
+					}, {
+						Key:         "limit",
+						NumberValue: &limit,
+					}, {
+						Key:       "audit",
+						BoolValue: &audit,
+					}},
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	extAuthz := &_struct.Struct{
+		Fields: map[string]*_struct.Value{
+			"tier":  {Kind: &_struct.Value_StringValue{StringValue: "gold"}},
+			"limit": {Kind: &_struct.Value_NumberValue{NumberValue: 100}},
+			"audit": {Kind: &_struct.Value_BoolValue{BoolValue: true}},
+		},
+	}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/"),
+						Action: routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: &envoy_api_v2_core.Metadata{
+							FilterMetadata: map[string]*_struct.Struct{
+								"envoy.ext_authz": extAuthz,
+							},
+						},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// the metadata is merged with that of a request header allow list.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:                    "kuard.example.com",
+				RequestHeadersAllowList: []string{"Authorization"},
+			},
+			Routes: hp1.Spec.Routes,
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	md := envoy.RequestHeadersAllowListMetadata([]string{"authorization"})
+	md.FilterMetadata["envoy.ext_authz"] = extAuthz
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    envoy.RoutePrefix("/"),
+						Action:   routeCluster("default/backend/80/da39a3ee5e"),
+						Metadata: md,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// metadata for the filter enforcing request header allow lists
+	// invalidates the route.
+	hp3 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: hp1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services:   hp1.Spec.Routes[0].Services,
+				Metadata: []projcontour.RouteMetadata{{
+					Filter: "envoy.lua",
+					Entries: []projcontour.MetadataEntry{{
+						Key:         "tier",
+						StringValue: &tier,
+					}},
+				}},
+			}},
+		},
+	}
+	rh.OnUpdate(hp2, hp3)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,130 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRouteStats(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.CacheHandler.RouteVisitorConfig.RouteStats = true
+	})
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: "marketing",
+		},
+		Spec: s1.Spec,
+	}
+	rh.OnAdd(s2)
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example.com",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Includes: []projcontour.Include{{
+				Name:       "blog",
+				Namespace:  s2.Namespace,
+				Conditions: prefixCondition("/blog"),
+			}},
+			Routes: []projcontour.Route{{
+				Conditions: prefixCondition("/"),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	})
+
+	rh.OnAdd(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: s2.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: s2.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	})
+
+	vhost := envoy.VirtualHost("example.com",
+		&envoy_api_v2_route.Route{
+			Name:   "marketing/blog",
+			Match:  envoy.RoutePrefix("/blog"),
+			Action: routeCluster("marketing/blog/80/da39a3ee5e"),
+		},
+		&envoy_api_v2_route.Route{
+			Name:   "default/example.com",
+			Match:  envoy.RoutePrefix("/"),
+			Action: routeCluster("default/kuard/80/da39a3ee5e"),
+		},
+	)
+	vhost.VirtualClusters = []*envoy_api_v2_route.VirtualCluster{{
+		Name: "marketing_blog",
+		Headers: []*envoy_api_v2_route.HeaderMatcher{{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/blog"},
+		}},
+	}, {
+		Name: "default_example_com",
+		Headers: []*envoy_api_v2_route.HeaderMatcher{{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/"},
+		}},
+	}}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http", vhost),
+		),
+		TypeUrl: routeType,
+	})
+}
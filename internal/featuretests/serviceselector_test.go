@@ -0,0 +1,121 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestServiceSelector(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	for _, name := range []string{"shard-a", "shard-b"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	proxy := func(values ...projcontour.ServiceSelectorValue) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "simple",
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "shards.example.com",
+				},
+				Routes: []projcontour.Route{{
+					Conditions: prefixCondition("/"),
+					Services: []projcontour.Service{{
+						Name: "shard-a",
+						Port: 80,
+					}, {
+						Name: "shard-b",
+						Port: 80,
+					}},
+					ServiceSelector: &projcontour.ServiceSelector{
+						Header: "X-Shard",
+						Values: values,
+					},
+				}},
+			},
+		}
+	}
+
+	shard := func(value string) dag.HeaderCondition {
+		return dag.HeaderCondition{
+			Name:      "X-Shard",
+			Value:     value,
+			MatchType: "exact",
+		}
+	}
+
+	p1 := proxy(
+		projcontour.ServiceSelectorValue{Value: "a", Service: "shard-a"},
+		projcontour.ServiceSelectorValue{Value: "b", Service: "shard-b"},
+	)
+	rh.OnAdd(p1)
+
+	// each value is routed to its service, other requests are
+	// balanced across the route's services.
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("shards.example.com",
+					envoy.Route(envoy.RoutePrefix("/", shard("a")), routeCluster("default/shard-a/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/", shard("b")), routeCluster("default/shard-b/80/da39a3ee5e")),
+					envoy.Route(envoy.RoutePrefix("/"), routeWeightedCluster(
+						weightedCluster{"default/shard-a/80/da39a3ee5e", 1},
+						weightedCluster{"default/shard-b/80/da39a3ee5e", 1},
+					)),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// a value whose service is not one of the route's services is invalid.
+	p2 := proxy(
+		projcontour.ServiceSelectorValue{Value: "a", Service: "shard-a"},
+		projcontour.ServiceSelectorValue{Value: "c", Service: "shard-c"},
+	)
+	rh.OnUpdate(p1, p2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStrictSNI(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: sec1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     80,
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	proxy := func(name, fqdn string, strict bool) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s1.Namespace,
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &projcontour.TLS{
+						SecretName: sec1.Name,
+						StrictSNI:  strict,
+					},
+				},
+				Routes: []projcontour.Route{{
+					Conditions: prefixCondition("/"),
+					Services: []projcontour.Service{{
+						Name: s1.Name,
+						Port: 80,
+					}},
+				}},
+			},
+		}
+	}
+	rh.OnAdd(proxy("strict", "kuard.example.com", true))
+	rh.OnAdd(proxy("shared", "other.example.com", false))
+
+	vhost := func(fqdn string) *envoy_api_v2_route.VirtualHost {
+		return envoy.VirtualHost(fqdn,
+			&envoy_api_v2_route.Route{
+				Match:  envoy.RoutePrefix("/"),
+				Action: routeCluster("default/backend/80/da39a3ee5e"),
+			},
+		)
+	}
+
+	// the strict vhost is not served on the connections of other vhosts.
+	c.Request(routeType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https", vhost("other.example.com")),
+		),
+		TypeUrl: routeType,
+	})
+
+	// and its own connections serve only it, misdirecting other hosts.
+	c.Request(routeType, "ingress_https/kuard.example.com").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_https/kuard.example.com",
+				vhost("kuard.example.com"),
+				&envoy_api_v2_route.VirtualHost{
+					Name:    "misdirected",
+					Domains: []string{"*"},
+					Routes: []*envoy_api_v2_route.Route{{
+						Match:  envoy.RoutePrefix("/"),
+						Action: envoy.DirectResponse(421),
+					}},
+				},
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/projectcontour/contour/internal/envoy"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -220,3 +221,79 @@ func TestUpstreamProtocolH2(t *testing.T) {
 		TypeUrl: clusterType,
 	})
 }
+
+// Test that contour treats ports named for gRPC as h2c without
+// a timeout, unless annotated otherwise.
+func TestUpstreamProtocolGRPC(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:       "grpc",
+				Protocol:   "TCP",
+				Port:       9000,
+				TargetPort: intstr.FromInt(9000),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	i1 := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{
+				ServiceName: "kuard",
+				ServicePort: intstr.FromInt(9000),
+			},
+		},
+	}
+	rh.OnAdd(i1)
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			h2cCluster(cluster("default/kuard/9000/da39a3ee5e", "default/kuard/grpc", "default_kuard_9000")),
+		),
+		TypeUrl: clusterType,
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("*",
+					envoy.Route(envoy.RoutePrefix("/"), withResponseTimeout(routeCluster("default/kuard/9000/da39a3ee5e"), 0)),
+				),
+			),
+			envoy.RouteConfiguration("ingress_https"),
+		),
+		TypeUrl: routeType,
+	})
+
+	// an annotation overrides the protocol of the port.
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/upstream-protocol.h2": "grpc",
+			},
+		},
+		Spec: s1.Spec,
+	}
+	rh.OnUpdate(s1, s2)
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			h2cCluster(tlsCluster(cluster("default/kuard/9000/da39a3ee5e", "default/kuard/grpc", "default_kuard_9000"), nil, "", "h2")),
+		),
+		TypeUrl: clusterType,
+	})
+}
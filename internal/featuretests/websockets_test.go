@@ -17,8 +17,10 @@ import (
 	"testing"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	ingressroutev1 "github.com/projectcontour/contour/apis/contour/v1beta1"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
@@ -416,3 +418,89 @@ func TestWebsocketHTTPProxy(t *testing.T) {
 	})
 
 }
+
+func TestWebsocketSubprotocols(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ws",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "websocket.hello.world",
+			},
+			Routes: []projcontour.Route{{
+				Conditions:            prefixCondition("/ws"),
+				EnableWebsockets:      true,
+				WebsocketSubprotocols: []string{"chat.v1", "chat.v2"},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	// requests offering any other subprotocol are rejected.
+	offered := dag.HeaderCondition{
+		Name:      "sec-websocket-protocol",
+		Value:     `\s*(chat\.v1|chat\.v2)\s*(,\s*(chat\.v1|chat\.v2)\s*)*`,
+		MatchType: "regex",
+		Invert:    true,
+	}
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("websocket.hello.world",
+					&envoy_api_v2_route.Route{
+						Match:  envoy.RoutePrefix("/ws", offered),
+						Action: envoy.DirectResponse(403),
+					},
+					envoy.Route(envoy.RoutePrefix("/ws"), withWebsocket(routeCluster("default/ws/80/da39a3ee5e"))),
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// subprotocols require websockets.
+	hp2 := &projcontour.HTTPProxy{
+		ObjectMeta: hp1.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: hp1.Spec.VirtualHost,
+			Routes: []projcontour.Route{{
+				Conditions:            prefixCondition("/ws"),
+				WebsocketSubprotocols: []string{"chat.v1"},
+				Services:              hp1.Spec.Routes[0].Services,
+			}},
+		},
+	}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(routeType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvoyFailuresCounter is the name of the metric counting the
+	// updates Envoys rejected and the xDS streams they closed soon
+	// after opening.
+	EnvoyFailuresCounter = "contour_xds_envoy_failures_total"
+
+	// BackoffEnvoysGauge is the name of the metric holding the number
+	// of Envoys whose responses are being delayed.
+	BackoffEnvoysGauge = "contour_xds_backoff_envoys"
+)
+
+// The reasons an Envoy's stream is counted as failing.
+const (
+	failureRejected  = "rejected"
+	failureReconnect = "reconnect"
+)
+
+const (
+	// minStreamDuration is how long a stream must stay open for its
+	// Envoy not to be considered to be reconnecting in a loop.
+	minStreamDuration = 10 * time.Second
+
+	// backoffThreshold is the number of failures a stream may have
+	// before responses on it are delayed.
+	backoffThreshold = 3
+
+	// initialBackoff is the delay after backoffThreshold failures.
+	// It doubles with each further failure, up to maxBackoff.
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+
+	// forgetFailuresAfter is how long a stream must go without
+	// failing for its previous failures to be forgotten.
+	forgetFailuresAfter = 5 * time.Minute
+)
+
+// backoff delays the responses sent to Envoys which repeatedly reject
+// updates or reconnect, so a broken Envoy cannot keep the xDS server
+// busy marshalling resources at the expense of the healthy ones.
+// Failures are counted per node and resource type, as an Envoy opens
+// a stream for each type.
+type backoff struct {
+	mu      sync.Mutex
+	streams map[backoffKey]*streamFailures
+
+	failures *prometheus.CounterVec
+
+	// now returns the current time. If nil, time.Now is used.
+	now func() time.Time
+}
+
+type backoffKey struct {
+	node, typeURL string
+}
+
+// streamFailures are the recent failures of a node's stream.
+type streamFailures struct {
+	count int
+	last  time.Time
+}
+
+// delay returns how long after the last failure responses are delayed.
+func (sf *streamFailures) delay() time.Duration {
+	if sf.count < backoffThreshold {
+		return 0
+	}
+	d := initialBackoff
+	for i := backoffThreshold; i < sf.count && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+func newBackoff(registry *prometheus.Registry) *backoff {
+	b := &backoff{
+		streams: make(map[backoffKey]*streamFailures),
+		failures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: EnvoyFailuresCounter,
+				Help: "Total number of updates rejected by Envoys, and of xDS streams closed within 10 seconds of opening.",
+			},
+			[]string{"reason"},
+		),
+	}
+	registry.MustRegister(b.failures, prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: BackoffEnvoysGauge,
+			Help: "Number of Envoys which have failed repeatedly in the last 5 minutes, and whose responses are delayed.",
+		},
+		b.backoffEnvoys,
+	))
+	return b
+}
+
+func (b *backoff) time() time.Time {
+	if b.now == nil {
+		return time.Now()
+	}
+	return b.now()
+}
+
+// failed records that the stream of typeURL resources to node failed
+// for reason. Streams from Envoys which do not identify themselves
+// are not tracked.
+func (b *backoff) failed(log logrus.FieldLogger, node, typeURL, reason string) {
+	if b == nil || node == "" {
+		return
+	}
+	b.failures.WithLabelValues(reason).Inc()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.time()
+	b.forget(now)
+	key := backoffKey{node: node, typeURL: typeURL}
+	sf, ok := b.streams[key]
+	if !ok {
+		sf = new(streamFailures)
+		b.streams[key] = sf
+	}
+	sf.count++
+	sf.last = now
+	if d := sf.delay(); d > 0 {
+		log.WithField("node_id", node).WithField("failures", sf.count).WithField("reason", reason).WithField("backoff", d).
+			Warn("delaying responses to repeatedly failing Envoy")
+	}
+}
+
+// forget drops the failures of streams which have not failed for
+// forgetFailuresAfter. The caller must hold b.mu.
+func (b *backoff) forget(now time.Time) {
+	for key, sf := range b.streams {
+		if now.Sub(sf.last) >= forgetFailuresAfter {
+			delete(b.streams, key)
+		}
+	}
+}
+
+// wait blocks until a response to node's stream of typeURL resources
+// may be sent, or ctx is done.
+func (b *backoff) wait(ctx context.Context, node, typeURL string) error {
+	if b == nil || node == "" {
+		return nil
+	}
+	b.mu.Lock()
+	var d time.Duration
+	if sf, ok := b.streams[backoffKey{node: node, typeURL: typeURL}]; ok {
+		d = sf.last.Add(sf.delay()).Sub(b.time())
+	}
+	b.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoffEnvoys returns the number of nodes with a stream whose
+// responses are delayed.
+func (b *backoff) backoffEnvoys() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.forget(b.time())
+	nodes := make(map[string]bool)
+	for key, sf := range b.streams {
+		if sf.delay() > 0 {
+			nodes[key.node] = true
+		}
+	}
+	return float64(len(nodes))
+}
@@ -0,0 +1,102 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStreamFailuresDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		6:  8 * time.Second,
+		9:  time.Minute,
+		50: time.Minute,
+	}
+
+	for count, want := range tests {
+		sf := streamFailures{count: count}
+		if got := sf.delay(); got != want {
+			t.Errorf("%d failures: expected delay %v, got %v", count, want, got)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	now := time.Now()
+	b := newBackoff(prometheus.NewRegistry())
+	b.now = func() time.Time { return now }
+
+	// a few failures do not delay responses.
+	b.failed(log, "envoy-1", "routes", failureRejected)
+	b.failed(log, "envoy-1", "routes", failureRejected)
+	assertFloat(t, 0, b.backoffEnvoys())
+
+	// failures of other types, or nodes, are counted separately.
+	b.failed(log, "envoy-1", "clusters", failureReconnect)
+	b.failed(log, "envoy-2", "routes", failureReconnect)
+	assertFloat(t, 0, b.backoffEnvoys())
+
+	b.failed(log, "envoy-1", "routes", failureReconnect)
+	assertFloat(t, 1, b.backoffEnvoys())
+	assertFloat(t, 2, testutil.ToFloat64(b.failures.WithLabelValues(failureRejected)))
+	assertFloat(t, 3, testutil.ToFloat64(b.failures.WithLabelValues(failureReconnect)))
+
+	// the delay is measured from the last failure, so a wait
+	// after it has passed returns at once.
+	now = now.Add(time.Second)
+	if err := b.wait(context.Background(), "envoy-1", "routes"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a wait during the delay ends when the stream does.
+	ctx, cancel := context.WithCancel(context.Background())
+	b.failed(log, "envoy-1", "routes", failureReconnect)
+	cancel()
+	if err := b.wait(ctx, "envoy-1", "routes"); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// failures are forgotten after a quiet period.
+	now = now.Add(forgetFailuresAfter)
+	assertFloat(t, 0, b.backoffEnvoys())
+	b.failed(log, "envoy-1", "routes", failureReconnect)
+	assertFloat(t, 0, b.backoffEnvoys())
+
+	// Envoys which do not identify themselves are not tracked.
+	for i := 0; i < backoffThreshold; i++ {
+		b.failed(log, "", "routes", failureReconnect)
+	}
+	assertFloat(t, 0, b.backoffEnvoys())
+}
+
+func assertFloat(t *testing.T, want, got float64) {
+	t.Helper()
+	if want != got {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+// ResourceVersioning is the scheme by which the version_info of
+// xDS responses is chosen.
+type ResourceVersioning string
+
+const (
+	// CounterVersions numbers responses by the count of changes
+	// seen by this Contour. The versions of identical resources
+	// differ between Contour replicas, and across restarts.
+	CounterVersions ResourceVersioning = "counter"
+
+	// ContentVersions identifies responses by a hash of the
+	// resources they carry, so identical resources have the same
+	// version whichever Contour sends them. A response whose
+	// version matches the one the Envoy last accepted is not sent.
+	ContentVersions ResourceVersioning = "content"
+)
+
+// contentVersion returns a hash of the marshalled resources. The
+// resources must be marshalled deterministically for the hash of
+// identical resources to match.
+func contentVersion(resources []*any.Any) string {
+	h := sha256.New()
+	var size [8]byte
+	for _, r := range resources {
+		for _, b := range [][]byte{[]byte(r.TypeUrl), r.Value} {
+			// prefix each field with its length, so the
+			// boundaries between resources are hashed too.
+			binary.BigEndian.PutUint64(size[:], uint64(len(b)))
+			h.Write(size[:])
+			h.Write(b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
@@ -31,11 +31,17 @@ import (
 )
 
 // NewAPI returns a *grpc.Server which responds to the Envoy v2 xDS gRPC API.
-func NewAPI(log logrus.FieldLogger, resources map[string]Resource, registry *prometheus.Registry, opts ...grpc.ServerOption) *grpc.Server {
+// Envoys outside the supported version range are handled according to policy,
+// and responses to Envoys which repeatedly fail are delayed. Responses are
+// versioned by the given scheme.
+func NewAPI(log logrus.FieldLogger, resources map[string]Resource, registry *prometheus.Registry, policy EnvoyVersionPolicy, versioning ResourceVersioning, opts ...grpc.ServerOption) *grpc.Server {
 	s := &grpcServer{
 		xdsHandler{
 			FieldLogger: log,
 			resources:   resources,
+			versions:    newVersionChecker(policy, registry),
+			backoff:     newBackoff(registry),
+			versioning:  versioning,
 		},
 		grpc_prometheus.NewServerMetrics(),
 	}
@@ -206,7 +206,7 @@ func TestGRPC(t *testing.T) {
 				ch.ListenerCache.TypeURL(): &ch.ListenerCache,
 				ch.SecretCache.TypeURL():   &ch.SecretCache,
 				et.TypeURL():               et,
-			}, r)
+			}, r, WarnUnsupportedEnvoy, CounterVersions)
 			l, err := net.Listen("tcp", "127.0.0.1:0")
 			check(t, err)
 			done := make(chan error, 1)
@@ -0,0 +1,137 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EnvoyVersionPolicy is the action taken when an Envoy outside the
+// supported version range connects.
+type EnvoyVersionPolicy string
+
+const (
+	// WarnUnsupportedEnvoy logs a warning and serves the Envoy.
+	WarnUnsupportedEnvoy EnvoyVersionPolicy = "warn"
+
+	// RefuseUnsupportedEnvoy closes the Envoy's xDS streams.
+	RefuseUnsupportedEnvoy EnvoyVersionPolicy = "refuse"
+)
+
+// UnsupportedEnvoyCounter is the name of the metric counting xDS
+// streams opened by Envoys outside the supported version range.
+const UnsupportedEnvoyCounter = "contour_xds_unsupported_envoy_streams_total"
+
+// The range of Envoy versions, inclusive of minEnvoyVersion and
+// exclusive of maxEnvoyVersion, which understand the resources
+// Contour generates.
+var (
+	minEnvoyVersion = envoyVersion{1, 11, 0}
+	maxEnvoyVersion = envoyVersion{1, 13, 0}
+)
+
+type envoyVersion struct {
+	major, minor, patch int
+}
+
+func (v envoyVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+func (v envoyVersion) less(o envoyVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	return v.patch < o.patch
+}
+
+// parseBuildVersion returns the version in an Envoy node's build
+// version, which takes the form
+// <revision>/<version>/<status>/<build type>/<ssl version>,
+// for example 3504d40f752eb5c20bc2883053547717bcb92fd8/1.11.2/Clean/RELEASE/BoringSSL.
+func parseBuildVersion(bv string) (envoyVersion, bool) {
+	parts := strings.Split(bv, "/")
+	if len(parts) < 2 {
+		return envoyVersion{}, false
+	}
+	// drop any pre-release suffix, as in 1.13.0-dev.
+	version := strings.SplitN(parts[1], "-", 2)[0]
+	nums := strings.Split(version, ".")
+	if len(nums) != 3 {
+		return envoyVersion{}, false
+	}
+	var v [3]int
+	for i, n := range nums {
+		var err error
+		if v[i], err = strconv.Atoi(n); err != nil {
+			return envoyVersion{}, false
+		}
+	}
+	return envoyVersion{v[0], v[1], v[2]}, true
+}
+
+// versionChecker compares the version of each connecting Envoy
+// against the supported range.
+type versionChecker struct {
+	policy      EnvoyVersionPolicy
+	unsupported *prometheus.CounterVec
+}
+
+func newVersionChecker(policy EnvoyVersionPolicy, registry *prometheus.Registry) *versionChecker {
+	vc := &versionChecker{
+		policy: policy,
+		unsupported: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: UnsupportedEnvoyCounter,
+				Help: "Total number of xDS streams opened by Envoys outside the supported version range.",
+			},
+			[]string{"version"},
+		),
+	}
+	registry.MustRegister(vc.unsupported)
+	return vc
+}
+
+// check returns an error if node is an Envoy outside the supported
+// version range and the policy is to refuse it. Nodes which do not
+// report a recognisable version are not checked.
+func (vc *versionChecker) check(log logrus.FieldLogger, node *envoy_api_v2_core.Node) error {
+	if vc == nil {
+		return nil
+	}
+	v, ok := parseBuildVersion(node.GetBuildVersion())
+	if !ok || (!v.less(minEnvoyVersion) && v.less(maxEnvoyVersion)) {
+		return nil
+	}
+
+	vc.unsupported.WithLabelValues(v.String()).Inc()
+	msg := fmt.Sprintf("Envoy version %s is not supported, supported versions are %s up to but not including %s", v, minEnvoyVersion, maxEnvoyVersion)
+	if vc.policy == RefuseUnsupportedEnvoy {
+		log.Error(msg)
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+	log.Warn(msg)
+	return nil
+}
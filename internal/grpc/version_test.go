@@ -0,0 +1,115 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"io/ioutil"
+	"testing"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseBuildVersion(t *testing.T) {
+	tests := map[string]struct {
+		bv     string
+		want   envoyVersion
+		wantOK bool
+	}{
+		"release": {
+			bv:     "3504d40f752eb5c20bc2883053547717bcb92fd8/1.11.2/Clean/RELEASE/BoringSSL",
+			want:   envoyVersion{1, 11, 2},
+			wantOK: true,
+		},
+		"pre-release": {
+			bv:     "e349fb6139e4b7a59a9a359be0ea45dd61e589c5/1.13.0-dev/Modified/DEBUG/BoringSSL",
+			want:   envoyVersion{1, 13, 0},
+			wantOK: true,
+		},
+		"empty": {
+			bv: "",
+		},
+		"no version": {
+			bv: "3504d40f752eb5c20bc2883053547717bcb92fd8",
+		},
+		"malformed version": {
+			bv: "3504d40f752eb5c20bc2883053547717bcb92fd8/1.x.2/Clean/RELEASE/BoringSSL",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := parseBuildVersion(tc.bv)
+			if ok != tc.wantOK || got != tc.want {
+				t.Fatalf("expected %v, %v, got %v, %v", tc.want, tc.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestVersionCheckerCheck(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	tests := map[string]struct {
+		policy      EnvoyVersionPolicy
+		bv          string
+		wantCode    codes.Code
+		unsupported float64
+	}{
+		"supported": {
+			policy: RefuseUnsupportedEnvoy,
+			bv:     "3504d40f752eb5c20bc2883053547717bcb92fd8/1.11.2/Clean/RELEASE/BoringSSL",
+		},
+		"unknown version": {
+			policy: RefuseUnsupportedEnvoy,
+			bv:     "",
+		},
+		"too old, warn": {
+			policy:      WarnUnsupportedEnvoy,
+			bv:          "e8a2d1e24dc9a0d5b2a8fb8f9f5e1c0dc8df5b7a/1.10.0/Clean/RELEASE/BoringSSL",
+			unsupported: 1,
+		},
+		"too old, refuse": {
+			policy:      RefuseUnsupportedEnvoy,
+			bv:          "e8a2d1e24dc9a0d5b2a8fb8f9f5e1c0dc8df5b7a/1.10.0/Clean/RELEASE/BoringSSL",
+			wantCode:    codes.FailedPrecondition,
+			unsupported: 1,
+		},
+		"too new, refuse": {
+			policy:      RefuseUnsupportedEnvoy,
+			bv:          "e349fb6139e4b7a59a9a359be0ea45dd61e589c5/1.13.0-dev/Modified/DEBUG/BoringSSL",
+			wantCode:    codes.FailedPrecondition,
+			unsupported: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vc := newVersionChecker(tc.policy, prometheus.NewRegistry())
+			err := vc.check(log, &envoy_api_v2_core.Node{BuildVersion: tc.bv})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("expected code %v, got %v", tc.wantCode, got)
+			}
+			v, _ := parseBuildVersion(tc.bv)
+			if got := testutil.ToFloat64(vc.unsupported.WithLabelValues(v.String())); got != tc.unsupported {
+				t.Fatalf("expected %v unsupported streams, got %v", tc.unsupported, got)
+			}
+		})
+	}
+}
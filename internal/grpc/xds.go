@@ -18,8 +18,10 @@ import (
 	"fmt"
 	"strconv"
 	"sync/atomic"
+	"time"
 
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/sirupsen/logrus"
@@ -41,11 +43,21 @@ type Resource interface {
 	TypeURL() string
 }
 
+// NodeResource is implemented by Resources whose contents depend
+// on the Envoy node which requested them.
+type NodeResource interface {
+	// ForNode returns resources adjusted for the supplied node.
+	ForNode(node *envoy_api_v2_core.Node, resources []proto.Message) []proto.Message
+}
+
 // xdsHandler implements the Envoy xDS gRPC protocol.
 type xdsHandler struct {
 	logrus.FieldLogger
 	connections counter
 	resources   map[string]Resource // registered resource types
+	versions    *versionChecker
+	backoff     *backoff
+	versioning  ResourceVersioning
 }
 
 type grpcStream interface {
@@ -59,6 +71,11 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 	// bump connection counter and set it as a field on the logger
 	log := xh.WithField("connection", xh.connections.next())
 
+	// the node and resource type of the stream, taken from its first
+	// request, identify it to backoff.
+	var node, typeURL string
+	start := time.Now()
+
 	// set up some nice function exit handling which notifies if the
 	// stream terminated on error or not.
 	defer func() {
@@ -67,6 +84,9 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 		} else {
 			log.Info("stream terminated")
 		}
+		if time.Since(start) < minStreamDuration {
+			xh.backoff.failed(log, node, typeURL, failureReconnect)
+		}
 	}()
 
 	ch := make(chan int, 1)
@@ -77,6 +97,10 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 	last := -1
 	ctx := st.Context()
 
+	// the Envoy's version is checked on the first request which
+	// identifies the node.
+	checked := false
+
 	// now stick in this loop until the client disconnects.
 	for {
 		// first we wait for the request from Envoy, this is part of
@@ -88,14 +112,27 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 
 		// note: redeclare log in this scope so the next time around the loop all is forgotten.
 		log := log.WithField("version_info", req.VersionInfo).WithField("response_nonce", req.ResponseNonce)
+		if typeURL == "" {
+			typeURL = req.TypeUrl
+		}
 		if req.Node != nil {
 			log = log.WithField("node_id", req.Node.Id)
+			if node == "" {
+				node = req.Node.Id
+			}
+			if !checked {
+				checked = true
+				if err := xh.versions.check(log.WithField("build_version", req.Node.BuildVersion), req.Node); err != nil {
+					return err
+				}
+			}
 		}
 
 		if err := req.ErrorDetail; err != nil {
 			// if Envoy rejected the last update log the details here.
 			// TODO(dfc) issue 1176: handle xDS ACK/NACK
 			log.WithField("code", err.Code).Error(err.Message)
+			xh.backoff.failed(log, node, typeURL, failureRejected)
 		}
 
 		// from the request we derive the resource to stream which have
@@ -107,56 +144,83 @@ func (xh *xdsHandler) stream(st grpcStream) (err error) {
 		log = log.WithField("resource_names", req.ResourceNames).WithField("type_url", req.TypeUrl)
 		log.Info("stream_wait")
 
+		// an Envoy which keeps failing waits before it is sent
+		// anything more.
+		if err := xh.backoff.wait(ctx, node, typeURL); err != nil {
+			return err
+		}
+
 		// now we wait for a notification, if this is the first request received on this
 		// connection last will be less than zero and that will trigger a response immediately.
-		r.Register(ch, last, req.ResourceNames...)
-		select {
-		case last = <-ch:
-			// boom, something in the cache has changed.
-			// TODO(dfc) the thing that has changed may not be in the scope of the filter
-			// so we're going to be sending an update that is a no-op. See #426
-
-			var resources []proto.Message
-			switch len(req.ResourceNames) {
-			case 0:
-				// no resource hints supplied, return the full
-				// contents of the resource
-				resources = r.Contents()
-			default:
-				// resource hints supplied, return exactly those
-				resources = r.Query(req.ResourceNames)
-			}
-
-			any, err := toAny(r.TypeURL(), resources)
-			if err != nil {
-				return err
+		for sent := false; !sent; {
+			r.Register(ch, last, req.ResourceNames...)
+			select {
+			case last = <-ch:
+				// boom, something in the cache has changed.
+				// TODO(dfc) the thing that has changed may not be in the scope of the filter
+				// so we're going to be sending an update that is a no-op. See #426
+
+				var resources []proto.Message
+				switch len(req.ResourceNames) {
+				case 0:
+					// no resource hints supplied, return the full
+					// contents of the resource
+					resources = r.Contents()
+				default:
+					// resource hints supplied, return exactly those
+					resources = r.Query(req.ResourceNames)
+				}
+				if nr, ok := r.(NodeResource); ok && req.Node != nil {
+					resources = nr.ForNode(req.Node, resources)
+				}
+
+				any, err := toAny(r.TypeURL(), resources)
+				if err != nil {
+					return err
+				}
+
+				version := strconv.Itoa(last)
+				if xh.versioning == ContentVersions {
+					version = contentVersion(any)
+					if version == req.VersionInfo {
+						// the Envoy already has these resources,
+						// wait for the next change.
+						log.WithField("version", version).Info("response unchanged")
+						continue
+					}
+				}
+
+				resp := &envoy_api_v2.DiscoveryResponse{
+					VersionInfo: version,
+					Resources:   any,
+					TypeUrl:     r.TypeURL(),
+					Nonce:       strconv.Itoa(last),
+				}
+				if err := st.Send(resp); err != nil {
+					return err
+				}
+				sent = true
+				log.WithField("count", len(resources)).Info("response")
+			case <-ctx.Done():
+				return ctx.Err()
 			}
-
-			resp := &envoy_api_v2.DiscoveryResponse{
-				VersionInfo: strconv.Itoa(last),
-				Resources:   any,
-				TypeUrl:     r.TypeURL(),
-				Nonce:       strconv.Itoa(last),
-			}
-			if err := st.Send(resp); err != nil {
-				return err
-			}
-			log.WithField("count", len(resources)).Info("response")
-		case <-ctx.Done():
-			return ctx.Err()
 		}
 	}
 }
 
 // toAny converts the contents of a resourcer's Values to the
-// respective slice of *any.Any.
+// respective slice of *any.Any. Values are marshalled
+// deterministically, so identical values have identical encodings.
 func toAny(typeURL string, values []proto.Message) ([]*any.Any, error) {
 	var resources []*any.Any
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
 	for _, value := range values {
-		v, err := proto.Marshal(value)
-		if err != nil {
+		buf.Reset()
+		if err := buf.Marshal(value); err != nil {
 			return nil, err
 		}
+		v := append([]byte(nil), buf.Bytes()...)
 		resources = append(resources, &any.Any{TypeUrl: typeURL, Value: v})
 	}
 	return resources, nil
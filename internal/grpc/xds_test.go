@@ -150,6 +150,74 @@ func TestXDSHandlerStream(t *testing.T) {
 	}
 }
 
+func TestXDSHandlerContentVersions(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	cla := func(name string) []proto.Message {
+		return []proto.Message{&v2.ClusterLoadAssignment{ClusterName: name}}
+	}
+	// each notification delivers the next set of contents; the
+	// second is identical to the first.
+	contents := [][]proto.Message{cla("a"), cla("a"), cla("b")}
+	notified := 0
+	xh := xdsHandler{
+		FieldLogger: log,
+		versioning:  ContentVersions,
+		resources: map[string]Resource{
+			"com.heptio.potato": &mockResource{
+				register: func(ch chan int, i int) {
+					ch <- i + 1
+				},
+				contents: func() []proto.Message {
+					notified++
+					return contents[notified-1]
+				},
+				typeurl: func() string { return "com.heptio.potato" },
+			},
+		},
+	}
+
+	var sent []*v2.DiscoveryResponse
+	st := &mockStream{
+		context: context.Background,
+		recv: func() (*v2.DiscoveryRequest, error) {
+			req := &v2.DiscoveryRequest{
+				TypeUrl: "com.heptio.potato",
+			}
+			switch len(sent) {
+			case 0:
+			case 1:
+				// acknowledge the first response.
+				req.VersionInfo = sent[0].VersionInfo
+			default:
+				return nil, io.EOF
+			}
+			return req, nil
+		},
+		send: func(resp *v2.DiscoveryResponse) error {
+			sent = append(sent, resp)
+			return nil
+		},
+	}
+
+	if err := xh.stream(st); err != io.EOF {
+		t.Fatalf("expected: %v, got: %v", io.EOF, err)
+	}
+
+	// the unchanged contents were not sent.
+	if len(sent) != 2 || notified != 3 {
+		t.Fatalf("expected 2 responses to 3 notifications, got %d responses to %d", len(sent), notified)
+	}
+	for i, name := range []string{"a", "b"} {
+		any, err := toAny("com.heptio.potato", cla(name))
+		check(t, err)
+		if want := contentVersion(any); sent[i].VersionInfo != want {
+			t.Errorf("response %d: expected version %q, got %q", i, want, sent[i].VersionInfo)
+		}
+	}
+}
+
 type mockStream struct {
 	context func() context.Context
 	send    func(*v2.DiscoveryResponse) error
@@ -11,7 +11,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package k8s contains helpers for setting the IngressRoute status
+// Package k8s contains helpers for setting the IngressRoute status,
+// and for the informers which watch Kubernetes objects.
 package k8s
 
 import (
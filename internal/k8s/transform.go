@@ -0,0 +1,158 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TransformFunc modifies an object received from the API server
+// before it is stored in an informer's cache. The object is freshly
+// decoded so it may be modified in place.
+type TransformFunc func(obj runtime.Object)
+
+// TransformListWatch returns a ListerWatcher which passes every object
+// listed or watched by lw to f.
+func TransformListWatch(lw cache.ListerWatcher, f TransformFunc) cache.ListerWatcher {
+	return &transformListWatch{ListerWatcher: lw, transform: f}
+}
+
+type transformListWatch struct {
+	cache.ListerWatcher
+	transform TransformFunc
+}
+
+func (t *transformListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	list, err := t.ListerWatcher.List(options)
+	if err != nil {
+		return nil, err
+	}
+	err = meta.EachListItem(list, func(obj runtime.Object) error {
+		t.transform(obj)
+		return nil
+	})
+	return list, err
+}
+
+func (t *transformListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := t.ListerWatcher.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(ev watch.Event) (watch.Event, bool) {
+		if ev.Type != watch.Error {
+			t.transform(ev.Object)
+		}
+		return ev, true
+	}), nil
+}
+
+// StripObjectMeta removes the fields of an object's metadata which
+// Contour does not use: its managed fields and the configuration
+// recorded by kubectl apply, which repeats the whole object.
+func StripObjectMeta(obj runtime.Object) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	m.SetManagedFields(nil)
+	if annotations := m.GetAnnotations(); annotations != nil {
+		delete(annotations, v1.LastAppliedConfigAnnotation)
+		if len(annotations) == 0 {
+			m.SetAnnotations(nil)
+		}
+	}
+}
+
+// StripEndpoints removes the fields of an Endpoints which Contour
+// does not use, in addition to those removed by StripObjectMeta: the
+// pod-template-hash label, and the references to the pods and nodes
+// behind each address.
+func StripEndpoints(obj runtime.Object) {
+	StripObjectMeta(obj)
+	ep, ok := obj.(*v1.Endpoints)
+	if !ok {
+		return
+	}
+	if ep.Labels != nil {
+		delete(ep.Labels, "pod-template-hash")
+	}
+	for i := range ep.Subsets {
+		stripAddresses(ep.Subsets[i].Addresses)
+		stripAddresses(ep.Subsets[i].NotReadyAddresses)
+	}
+}
+
+// StripSecret removes the contents of a Secret, in addition to the
+// fields removed by StripObjectMeta, leaving only its metadata and
+// type. The contents of the Secrets Contour uses are fetched on demand.
+func StripSecret(obj runtime.Object) {
+	StripObjectMeta(obj)
+	sec, ok := obj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	sec.Data = nil
+	sec.StringData = nil
+}
+
+func stripAddresses(addresses []v1.EndpointAddress) {
+	for i := range addresses {
+		addresses[i].TargetRef = nil
+		addresses[i].NodeName = nil
+	}
+}
+
+// TransformedInformer returns the informer of factory for obj, which
+// must be a Service, Endpoints or Secret, registering one which strips
+// the fields Contour does not use before caching objects if factory has
+// none. These are the objects a large cluster has tens of thousands of.
+// The informer for Secrets holds their metadata only.
+// namespace must be the namespace factory is restricted to, if any.
+func TransformedInformer(factory informers.SharedInformerFactory, namespace string, obj runtime.Object) cache.SharedIndexInformer {
+	var resource string
+	transform := StripObjectMeta
+	switch obj.(type) {
+	case *v1.Service:
+		resource = "services"
+	case *v1.Endpoints:
+		resource = "endpoints"
+		transform = StripEndpoints
+	case *v1.Secret:
+		resource = "secrets"
+		transform = StripSecret
+	default:
+		panic(fmt.Sprintf("no transformed informer for %T", obj))
+	}
+	return factory.InformerFor(obj, func(client kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		lw := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), resource, namespace, fields.Everything())
+		return cache.NewSharedIndexInformer(
+			TransformListWatch(lw, transform),
+			obj,
+			resync,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	})
+}
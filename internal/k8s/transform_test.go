@@ -0,0 +1,192 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/projectcontour/contour/internal/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestStripEndpoints(t *testing.T) {
+	node := "node-1"
+	ep := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Labels: map[string]string{
+				"app":               "kuard",
+				"pod-template-hash": "5d4b8c7f6",
+			},
+			Annotations: map[string]string{
+				v1.LastAppliedConfigAnnotation: `{"apiVersion":"v1","kind":"Endpoints"}`,
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{
+				Manager: "kube-controller-manager",
+			}},
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{
+				IP:       "10.0.0.1",
+				NodeName: &node,
+				TargetRef: &v1.ObjectReference{
+					Kind: "Pod",
+					Name: "kuard-5d4b8c7f6-abcde",
+				},
+			}},
+			NotReadyAddresses: []v1.EndpointAddress{{
+				IP:       "10.0.0.2",
+				NodeName: &node,
+			}},
+			Ports: []v1.EndpointPort{{
+				Port: 8080,
+			}},
+		}},
+	}
+
+	StripEndpoints(ep)
+
+	assert.Equal(t, &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+			Labels: map[string]string{
+				"app": "kuard",
+			},
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{
+				IP: "10.0.0.1",
+			}},
+			NotReadyAddresses: []v1.EndpointAddress{{
+				IP: "10.0.0.2",
+			}},
+			Ports: []v1.EndpointPort{{
+				Port: 8080,
+			}},
+		}},
+	}, ep)
+}
+
+func TestStripObjectMetaKeepsAnnotations(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kuard",
+			Annotations: map[string]string{
+				"contour.heptio.com/upstream-protocol.tls": "443",
+				v1.LastAppliedConfigAnnotation:             `{"apiVersion":"v1","kind":"Service"}`,
+			},
+		},
+	}
+
+	StripObjectMeta(svc)
+
+	assert.Equal(t, map[string]string{
+		"contour.heptio.com/upstream-protocol.tls": "443",
+	}, svc.Annotations)
+}
+
+func TestStripSecret(t *testing.T) {
+	sec := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "default",
+			Annotations: map[string]string{
+				v1.LastAppliedConfigAnnotation: `{"apiVersion":"v1","kind":"Secret"}`,
+			},
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey:       []byte("certificate"),
+			v1.TLSPrivateKeyKey: []byte("key"),
+		},
+		StringData: map[string]string{
+			"ca.crt": "ca",
+		},
+	}
+
+	StripSecret(sec)
+
+	assert.Equal(t, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+	}, sec)
+}
+
+func TestTransformListWatch(t *testing.T) {
+	service := func(name string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Annotations: map[string]string{
+					v1.LastAppliedConfigAnnotation: "{}",
+				},
+			},
+		}
+	}
+
+	fw := watch.NewFake()
+	lw := TransformListWatch(&cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return &v1.ServiceList{
+				Items: []v1.Service{*service("listed")},
+			}, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return fw, nil
+		},
+	}, StripObjectMeta)
+
+	list, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &v1.ServiceList{
+		Items: []v1.Service{{
+			ObjectMeta: metav1.ObjectMeta{Name: "listed"},
+		}},
+	}, list)
+
+	w, err := lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	go fw.Add(service("watched"))
+	ev := <-w.ResultChan()
+	assert.Equal(t, watch.Event{
+		Type:   watch.Added,
+		Object: &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "watched"}},
+	}, ev)
+}
+
+func TestTransformedInformer(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	inf := TransformedInformer(factory, v1.NamespaceAll, &v1.Endpoints{})
+
+	// the factory's typed accessors return the transformed informer.
+	if got := factory.Core().V1().Endpoints().Informer(); got != inf {
+		t.Fatalf("expected factory to return transformed informer, got %v", got)
+	}
+}
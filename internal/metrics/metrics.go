@@ -40,13 +40,26 @@ type Metrics struct {
 	proxyValidGauge     *prometheus.GaugeVec
 	proxyOrphanedGauge  *prometheus.GaugeVec
 
+	serviceWeightGauge  *prometheus.GaugeVec
+	readyEndpointsGauge *prometheus.GaugeVec
+
+	deprecatedAnnotationsGauge *prometheus.GaugeVec
+
+	featureGateGauge *prometheus.GaugeVec
+
+	staleServingGauge         *prometheus.GaugeVec
+	kubernetesAPIContactGauge *prometheus.GaugeVec
+
 	dagRebuildGauge             *prometheus.GaugeVec
 	CacheHandlerOnUpdateSummary prometheus.Summary
 	ResourceEventHandlerSummary *prometheus.SummaryVec
 
 	// Keep a local cache of metrics for comparison on updates
-	ingressRouteMetricCache *RouteMetric
-	proxyMetricCache        *RouteMetric
+	ingressRouteMetricCache    *RouteMetric
+	proxyMetricCache           *RouteMetric
+	serviceWeightCache         map[ServiceWeightMeta]float64
+	readyEndpointsCache        map[string]float64
+	deprecatedAnnotationsCache map[DeprecatedAnnotationMeta]int
 }
 
 // RouteMetric stores various metrics for IngressRoute objects
@@ -63,6 +76,22 @@ type Meta struct {
 	VHost, Namespace string
 }
 
+// ServiceWeightMeta identifies an upstream Service of a route
+type ServiceWeightMeta struct {
+	VHost, Route, Namespace, Service, Port string
+}
+
+// DeprecatedAnnotationMeta identifies a deprecated annotation used
+// in a namespace
+type DeprecatedAnnotationMeta struct {
+	Namespace, Annotation string
+}
+
+// FeatureGateMeta identifies a feature gate and the stage of its feature
+type FeatureGateMeta struct {
+	Name, Stage string
+}
+
 const (
 	IngressRouteTotalGauge     = "contour_ingressroute_total"
 	IngressRouteRootTotalGauge = "contour_ingressroute_root_total"
@@ -76,6 +105,16 @@ const (
 	HTTPProxyValidGauge     = "contour_httpproxy_valid_total"
 	HTTPProxyOrphanedGauge  = "contour_httpproxy_orphaned_total"
 
+	ServiceWeightGauge  = "contour_route_service_weight_percent"
+	ReadyEndpointsGauge = "contour_vhost_ready_endpoints_percent"
+
+	DeprecatedAnnotationsGauge = "contour_deprecated_annotations_total"
+
+	FeatureGateGauge = "contour_feature_gate_enabled"
+
+	StaleServingGauge         = "contour_stale_serving"
+	KubernetesAPIContactGauge = "contour_kubernetes_api_last_contact_timestamp"
+
 	DAGRebuildGauge             = "contour_dagrebuild_timestamp"
 	cacheHandlerOnUpdateSummary = "contour_cachehandler_onupdate_duration_seconds"
 	resourceEventHandlerSummary = "contour_resourceeventhandler_duration_seconds"
@@ -161,6 +200,48 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 			},
 			[]string{"namespace"},
 		),
+		serviceWeightGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: ServiceWeightGauge,
+				Help: "Percentage of a route's traffic which is sent to each of its upstream Services.",
+			},
+			[]string{"vhost", "route", "namespace", "service", "port"},
+		),
+		readyEndpointsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: ReadyEndpointsGauge,
+				Help: "Percentage of the endpoints of a virtual host's upstream Services which are ready.",
+			},
+			[]string{"vhost"},
+		),
+		deprecatedAnnotationsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: DeprecatedAnnotationsGauge,
+				Help: "Total number of Ingresses, IngressRoutes, HTTPProxies and Services using each deprecated contour.heptio.com annotation.",
+			},
+			[]string{"namespace", "annotation"},
+		),
+		featureGateGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: FeatureGateGauge,
+				Help: "Whether each feature gate is enabled (1) or disabled (0).",
+			},
+			[]string{"name", "stage"},
+		),
+		staleServingGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: StaleServingGauge,
+				Help: "Whether Contour is serving configuration built before the Kubernetes API server became unreachable (1) or not (0).",
+			},
+			[]string{},
+		),
+		kubernetesAPIContactGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: KubernetesAPIContactGauge,
+				Help: "Timestamp of the last successful contact with the Kubernetes API server.",
+			},
+			[]string{},
+		),
 		dagRebuildGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: DAGRebuildGauge,
@@ -198,6 +279,12 @@ func (m *Metrics) register(registry *prometheus.Registry) {
 		m.proxyInvalidGauge,
 		m.proxyValidGauge,
 		m.proxyOrphanedGauge,
+		m.serviceWeightGauge,
+		m.readyEndpointsGauge,
+		m.deprecatedAnnotationsGauge,
+		m.featureGateGauge,
+		m.staleServingGauge,
+		m.kubernetesAPIContactGauge,
 		m.dagRebuildGauge,
 		m.CacheHandlerOnUpdateSummary,
 		m.ResourceEventHandlerSummary,
@@ -224,6 +311,11 @@ func (m *Metrics) Zero() {
 	m.SetDAGLastRebuilt(time.Now())
 	m.SetIngressRouteMetric(zeroes)
 	m.SetHTTPProxyMetric(zeroes)
+	m.SetServiceWeightMetric(map[ServiceWeightMeta]float64{{}: 0})
+	m.SetReadyEndpointsMetric(map[string]float64{"": 0})
+	m.SetDeprecatedAnnotationMetric(map[DeprecatedAnnotationMeta]int{{}: 0})
+	m.SetFeatureGateMetric(map[FeatureGateMeta]bool{{}: false})
+	m.SetStaleServingMetric(false, time.Now())
 
 	defer prometheus.NewTimer(m.CacheHandlerOnUpdateSummary).ObserveDuration()
 
@@ -335,18 +427,98 @@ func (m *Metrics) SetHTTPProxyMetric(metrics RouteMetric) {
 	}
 }
 
+// SetServiceWeightMetric sets the percentage of traffic sent to each
+// upstream Service of each route.
+func (m *Metrics) SetServiceWeightMetric(weights map[ServiceWeightMeta]float64) {
+	for meta, value := range weights {
+		m.serviceWeightGauge.WithLabelValues(meta.VHost, meta.Route, meta.Namespace, meta.Service, meta.Port).Set(value)
+		delete(m.serviceWeightCache, meta)
+	}
+
+	// remove the metrics for services which are no longer present
+	for meta := range m.serviceWeightCache {
+		m.serviceWeightGauge.DeleteLabelValues(meta.VHost, meta.Route, meta.Namespace, meta.Service, meta.Port)
+	}
+
+	m.serviceWeightCache = weights
+}
+
+// SetReadyEndpointsMetric sets the percentage of the endpoints of
+// each virtual host's upstream Services which are ready.
+func (m *Metrics) SetReadyEndpointsMetric(ready map[string]float64) {
+	for vhost, value := range ready {
+		m.readyEndpointsGauge.WithLabelValues(vhost).Set(value)
+		delete(m.readyEndpointsCache, vhost)
+	}
+
+	// remove the metrics for virtual hosts which are no longer present
+	for vhost := range m.readyEndpointsCache {
+		m.readyEndpointsGauge.DeleteLabelValues(vhost)
+	}
+
+	m.readyEndpointsCache = ready
+}
+
+// SetDeprecatedAnnotationMetric sets the number of objects using each
+// deprecated annotation in each namespace.
+func (m *Metrics) SetDeprecatedAnnotationMetric(counts map[DeprecatedAnnotationMeta]int) {
+	for meta, value := range counts {
+		m.deprecatedAnnotationsGauge.WithLabelValues(meta.Namespace, meta.Annotation).Set(float64(value))
+		delete(m.deprecatedAnnotationsCache, meta)
+	}
+
+	// remove the metrics for annotations which are no longer used
+	for meta := range m.deprecatedAnnotationsCache {
+		m.deprecatedAnnotationsGauge.DeleteLabelValues(meta.Namespace, meta.Annotation)
+	}
+
+	m.deprecatedAnnotationsCache = counts
+}
+
+// SetFeatureGateMetric records whether each feature gate is enabled.
+func (m *Metrics) SetFeatureGateMetric(gates map[FeatureGateMeta]bool) {
+	for meta, enabled := range gates {
+		value := 0.0
+		if enabled {
+			value = 1
+		}
+		m.featureGateGauge.WithLabelValues(meta.Name, meta.Stage).Set(value)
+	}
+}
+
+// SetStaleServingMetric records whether Contour is serving stale
+// configuration, and when it last reached the Kubernetes API server.
+func (m *Metrics) SetStaleServingMetric(stale bool, lastContact time.Time) {
+	value := 0.0
+	if stale {
+		value = 1
+	}
+	m.staleServingGauge.WithLabelValues().Set(value)
+	m.kubernetesAPIContactGauge.WithLabelValues().Set(float64(lastContact.Unix()))
+}
+
 // Service serves various metric and health checking endpoints
 type Service struct {
 	httpsvc.Service
 	*prometheus.Registry
 	Client *kubernetes.Clientset
+
+	// ServingState, if set, reports whether Contour is serving stale
+	// configuration, when it last reached the Kubernetes API server,
+	// and why it last failed to. If not set, each health check probes
+	// the API server itself.
+	ServingState func() (stale bool, lastContact time.Time, err error)
 }
 
 // Start fulfills the g.Start contract.
 // When stop is closed the http server will shutdown.
 func (svc *Service) Start(stop <-chan struct{}) error {
 
-	registerHealthCheck(&svc.ServeMux, svc.Client)
+	if svc.ServingState != nil {
+		registerServingState(&svc.ServeMux, svc.ServingState)
+	} else {
+		registerHealthCheck(&svc.ServeMux, svc.Client)
+	}
 	registerMetrics(&svc.ServeMux, svc.Registry)
 
 	return svc.Service.Start(stop)
@@ -368,6 +540,29 @@ func registerHealthCheck(mux *http.ServeMux, client *kubernetes.Clientset) {
 	mux.HandleFunc("/healthz", healthCheckHandler)
 }
 
+// registerServingState registers health checks which succeed while
+// Contour serves its last configuration, even if the Kubernetes API
+// server cannot be reached, so that Contour is not restarted, nor
+// removed from its Service, and Envoy continues to be served.
+// /readyz reports whether that configuration is stale.
+func registerServingState(mux *http.ServeMux, state func() (bool, time.Time, error)) {
+	healthCheckHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "OK")
+	}
+	mux.HandleFunc("/health", healthCheckHandler)
+	mux.HandleFunc("/healthz", healthCheckHandler)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		stale, lastContact, err := state()
+		w.WriteHeader(http.StatusOK)
+		if !stale {
+			fmt.Fprintln(w, "serving")
+			return
+		}
+		fmt.Fprintf(w, "stale-serving: Kubernetes API server last reached at %s: %v\n", lastContact.UTC().Format(time.RFC3339), err)
+	})
+}
+
 func registerMetrics(mux *http.ServeMux, registry *prometheus.Registry) {
 	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 }
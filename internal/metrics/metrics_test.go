@@ -14,6 +14,9 @@
 package metrics
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -1367,3 +1370,172 @@ func TestRemoveProxyMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveServiceWeightMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	stable := ServiceWeightMeta{VHost: "example.com", Route: "prefix: /", Namespace: "default", Service: "stable", Port: "80"}
+	canary := ServiceWeightMeta{VHost: "example.com", Route: "prefix: /", Namespace: "default", Service: "canary", Port: "80"}
+
+	gather := func() map[string]float64 {
+		gathering, err := r.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]float64)
+		for _, mf := range gathering {
+			if mf.GetName() != ServiceWeightGauge {
+				continue
+			}
+			for _, metric := range mf.Metric {
+				for _, label := range metric.Label {
+					if label.GetName() == "service" {
+						got[label.GetValue()] = metric.Gauge.GetValue()
+					}
+				}
+			}
+		}
+		return got
+	}
+
+	m.SetServiceWeightMetric(map[ServiceWeightMeta]float64{stable: 80, canary: 20})
+	want := map[string]float64{"stable": 80, "canary": 20}
+	if got := gather(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// promoting the canary removes the metric for the stable service.
+	m.SetServiceWeightMetric(map[ServiceWeightMeta]float64{canary: 100})
+	want = map[string]float64{"canary": 100}
+	if got := gather(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRemoveDeprecatedAnnotationMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	class := DeprecatedAnnotationMeta{Namespace: "default", Annotation: "contour.heptio.com/ingress.class"}
+	retries := DeprecatedAnnotationMeta{Namespace: "default", Annotation: "contour.heptio.com/num-retries"}
+
+	gather := func() map[string]float64 {
+		gathering, err := r.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]float64)
+		for _, mf := range gathering {
+			if mf.GetName() != DeprecatedAnnotationsGauge {
+				continue
+			}
+			for _, metric := range mf.Metric {
+				for _, label := range metric.Label {
+					if label.GetName() == "annotation" {
+						got[label.GetValue()] = metric.Gauge.GetValue()
+					}
+				}
+			}
+		}
+		return got
+	}
+
+	m.SetDeprecatedAnnotationMetric(map[DeprecatedAnnotationMeta]int{class: 3, retries: 1})
+	want := map[string]float64{"contour.heptio.com/ingress.class": 3, "contour.heptio.com/num-retries": 1}
+	if got := gather(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// migrating the last object using an annotation removes its metric.
+	m.SetDeprecatedAnnotationMetric(map[DeprecatedAnnotationMeta]int{class: 2})
+	want = map[string]float64{"contour.heptio.com/ingress.class": 2}
+	if got := gather(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWriteFeatureGateMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	m.SetFeatureGateMetric(map[FeatureGateMeta]bool{
+		{Name: "MergeVirtualHosts", Stage: "ALPHA"}: true,
+		{Name: "RouteStats", Stage: "ALPHA"}:        false,
+	})
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range gathering {
+		if mf.GetName() != FeatureGateGauge {
+			continue
+		}
+		for _, metric := range mf.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "name" {
+					got[label.GetValue()] = metric.Gauge.GetValue()
+				}
+			}
+		}
+	}
+	want := map[string]float64{"MergeVirtualHosts": 1, "RouteStats": 0}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWriteStaleServingMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	m.SetStaleServingMetric(true, time.Unix(1000, 0))
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range gathering {
+		switch mf.GetName() {
+		case StaleServingGauge, KubernetesAPIContactGauge:
+			got[mf.GetName()] = mf.Metric[0].Gauge.GetValue()
+		}
+	}
+	want := map[string]float64{StaleServingGauge: 1, KubernetesAPIContactGauge: 1000}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestServingState(t *testing.T) {
+	var stale bool
+	mux := http.NewServeMux()
+	registerServingState(mux, func() (bool, time.Time, error) {
+		return stale, time.Unix(1000, 0), errors.New("connection refused")
+	})
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, rec.Body.String()
+	}
+	check := func(path string, wantCode int, wantBody string) {
+		t.Helper()
+		code, body := get(path)
+		if code != wantCode || body != wantBody {
+			t.Fatalf("%s: expected %d %q, got %d %q", path, wantCode, wantBody, code, body)
+		}
+	}
+
+	check("/healthz", http.StatusOK, "OK\n")
+	check("/readyz", http.StatusOK, "serving\n")
+
+	// a stale Contour still serves Envoy, so is neither
+	// restarted nor removed from its Service.
+	stale = true
+	check("/healthz", http.StatusOK, "OK\n")
+	check("/readyz", http.StatusOK, "stale-serving: Kubernetes API server last reached at 1970-01-01T00:16:40Z: connection refused\n")
+}
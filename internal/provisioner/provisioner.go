@@ -0,0 +1,186 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package provisioner creates and maintains the Envoy workload and
+// Service which Contour configures.
+package provisioner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// specHashAnnotation records the hash of the spec the provisioner
+// last wrote, so unchanged objects are not rewritten.
+const specHashAnnotation = "projectcontour.io/provisioner-spec-hash"
+
+// Provisioner creates the Envoy DaemonSet or Deployment and its
+// Service described by its Config, recreates them if they are
+// deleted, and updates them when the Config changes.
+type Provisioner struct {
+	Client kubernetes.Interface
+
+	Config
+
+	// Interval between reconciliations.
+	// If not set, defaults to one minute.
+	Interval time.Duration
+
+	logrus.FieldLogger
+}
+
+// Start reconciles the Envoy resources immediately and then every
+// Interval until stop is closed. Failures are logged and retried
+// at the next interval.
+func (p *Provisioner) Start(stop <-chan struct{}) error {
+	interval := p.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	p.Info("started")
+	defer p.Info("stopped")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Reconcile(); err != nil {
+			p.WithError(err).Error("failed to reconcile Envoy resources")
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Reconcile creates or updates the Envoy workload and Service.
+func (p *Provisioner) Reconcile() error {
+	if err := p.reconcileWorkload(); err != nil {
+		return err
+	}
+	return p.reconcileService()
+}
+
+func (p *Provisioner) reconcileWorkload() error {
+	switch p.kind() {
+	case KindDeployment:
+		want := Deployment(&p.Config)
+		client := p.Client.AppsV1().Deployments(want.Namespace)
+		got, err := client.Get(want.Name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			p.WithField("name", want.Name).Info("creating Envoy Deployment")
+			_, err = client.Create(want)
+			return err
+		case err != nil:
+			return err
+		case got.Annotations[specHashAnnotation] == want.Annotations[specHashAnnotation]:
+			return nil
+		}
+		got.Labels = want.Labels
+		got.Annotations = merge(got.Annotations, want.Annotations)
+		got.Spec = want.Spec
+		p.WithField("name", want.Name).Info("updating Envoy Deployment")
+		_, err = client.Update(got)
+		return err
+	default:
+		want := DaemonSet(&p.Config)
+		client := p.Client.AppsV1().DaemonSets(want.Namespace)
+		got, err := client.Get(want.Name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			p.WithField("name", want.Name).Info("creating Envoy DaemonSet")
+			_, err = client.Create(want)
+			return err
+		case err != nil:
+			return err
+		case got.Annotations[specHashAnnotation] == want.Annotations[specHashAnnotation]:
+			return nil
+		}
+		got.Labels = want.Labels
+		got.Annotations = merge(got.Annotations, want.Annotations)
+		got.Spec = want.Spec
+		p.WithField("name", want.Name).Info("updating Envoy DaemonSet")
+		_, err = client.Update(got)
+		return err
+	}
+}
+
+func (p *Provisioner) reconcileService() error {
+	want := Service(&p.Config)
+	client := p.Client.CoreV1().Services(want.Namespace)
+	got, err := client.Get(want.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		p.WithField("name", want.Name).Info("creating Envoy Service")
+		_, err = client.Create(want)
+		return err
+	case err != nil:
+		return err
+	case got.Annotations[specHashAnnotation] == want.Annotations[specHashAnnotation]:
+		return nil
+	}
+
+	// the cluster IP and any allocated node ports are assigned by
+	// the API server and must be carried over.
+	want.Spec.ClusterIP = got.Spec.ClusterIP
+	for i := range want.Spec.Ports {
+		for _, port := range got.Spec.Ports {
+			if port.Name == want.Spec.Ports[i].Name {
+				want.Spec.Ports[i].NodePort = port.NodePort
+			}
+		}
+	}
+	if want.Spec.ExternalTrafficPolicy == got.Spec.ExternalTrafficPolicy {
+		want.Spec.HealthCheckNodePort = got.Spec.HealthCheckNodePort
+	}
+
+	got.Labels = want.Labels
+	got.Annotations = merge(got.Annotations, want.Annotations)
+	got.Spec = want.Spec
+	p.WithField("name", want.Name).Info("updating Envoy Service")
+	_, err = client.Update(got)
+	return err
+}
+
+// merge returns the annotations in a overwritten by those in b.
+func merge(a, b map[string]string) map[string]string {
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// specHash returns a hash of the supplied spec.
+func specHash(spec interface{}) string {
+	buf, err := json.Marshal(spec)
+	if err != nil {
+		// specs are plain structs and always marshal.
+		panic(err)
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])[:10]
+}
@@ -0,0 +1,153 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provisioner
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestProvisionerReconcile(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	p := &Provisioner{
+		Client: client,
+		Config: Config{
+			Namespace: "projectcontour",
+		},
+		FieldLogger: discardLogger(),
+	}
+
+	checkErr(t, p.Reconcile())
+
+	ds, err := client.AppsV1().DaemonSets("projectcontour").Get("envoy", metav1.GetOptions{})
+	checkErr(t, err)
+	if got := ds.Spec.Template.Spec.Containers[0].Image; got != DEFAULT_ENVOY_IMAGE {
+		t.Fatalf("expected image %q, got %q", DEFAULT_ENVOY_IMAGE, got)
+	}
+
+	svc, err := client.CoreV1().Services("projectcontour").Get("envoy", metav1.GetOptions{})
+	checkErr(t, err)
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		t.Fatalf("expected service type LoadBalancer, got %q", svc.Spec.Type)
+	}
+
+	// simulate the API server allocating addresses to the Service.
+	svc.Spec.ClusterIP = "10.0.0.1"
+	svc.Spec.Ports[0].NodePort = 30080
+	_, err = client.CoreV1().Services("projectcontour").Update(svc)
+	checkErr(t, err)
+
+	// changing the config updates the existing objects.
+	p.EnvoyImage = "docker.io/envoyproxy/envoy:v1.12.2"
+	p.ServiceType = corev1.ServiceTypeNodePort
+	checkErr(t, p.Reconcile())
+
+	ds, err = client.AppsV1().DaemonSets("projectcontour").Get("envoy", metav1.GetOptions{})
+	checkErr(t, err)
+	if got := ds.Spec.Template.Spec.Containers[0].Image; got != p.EnvoyImage {
+		t.Fatalf("expected image %q, got %q", p.EnvoyImage, got)
+	}
+
+	svc, err = client.CoreV1().Services("projectcontour").Get("envoy", metav1.GetOptions{})
+	checkErr(t, err)
+	if svc.Spec.Type != corev1.ServiceTypeNodePort {
+		t.Fatalf("expected service type NodePort, got %q", svc.Spec.Type)
+	}
+	if svc.Spec.ClusterIP != "10.0.0.1" || svc.Spec.Ports[0].NodePort != 30080 {
+		t.Fatalf("expected allocated cluster IP and node port to be preserved, got %v", svc.Spec)
+	}
+}
+
+func TestProvisionerReconcileDeployment(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	p := &Provisioner{
+		Client: client,
+		Config: Config{
+			Namespace: "projectcontour",
+			Kind:      KindDeployment,
+			Replicas:  3,
+		},
+		FieldLogger: discardLogger(),
+	}
+
+	checkErr(t, p.Reconcile())
+
+	d, err := client.AppsV1().Deployments("projectcontour").Get("envoy", metav1.GetOptions{})
+	checkErr(t, err)
+	if *d.Spec.Replicas != 3 {
+		t.Fatalf("expected 3 replicas, got %d", *d.Spec.Replicas)
+	}
+
+	// an unchanged config leaves the Deployment alone.
+	client.ClearActions()
+	checkErr(t, p.Reconcile())
+	for _, a := range client.Actions() {
+		if a.GetVerb() != "get" {
+			t.Fatalf("expected only get actions, got %s %s", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  Config
+		wantErr bool
+	}{
+		"defaults": {
+			config: Config{Namespace: "projectcontour"},
+		},
+		"deployment": {
+			config: Config{Namespace: "projectcontour", Kind: KindDeployment},
+		},
+		"missing namespace": {
+			config:  Config{},
+			wantErr: true,
+		},
+		"unknown kind": {
+			config:  Config{Namespace: "projectcontour", Kind: "StatefulSet"},
+			wantErr: true,
+		},
+		"unknown service type": {
+			config:  Config{Namespace: "projectcontour", ServiceType: corev1.ServiceTypeExternalName},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func discardLogger() logrus.FieldLogger {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	return log
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
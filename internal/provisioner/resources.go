@@ -0,0 +1,325 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provisioner
+
+import (
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	KindDaemonSet  = "DaemonSet"
+	KindDeployment = "Deployment"
+
+	DEFAULT_NAME          = "envoy"
+	DEFAULT_ENVOY_IMAGE   = "docker.io/envoyproxy/envoy:v1.11.2"
+	DEFAULT_CONTOUR_IMAGE = "docker.io/projectcontour/contour:master"
+	DEFAULT_XDS_ADDRESS   = "contour"
+	DEFAULT_XDS_PORT      = 8001
+	DEFAULT_HTTP_PORT     = 8080
+	DEFAULT_HTTPS_PORT    = 8443
+	DEFAULT_STATS_PORT    = 8002
+	DEFAULT_ADMIN_PORT    = 9001
+)
+
+// Config describes the Envoy resources to provision.
+type Config struct {
+	// Namespace of the Envoy resources.
+	Namespace string
+
+	// Name of the Envoy workload and Service.
+	// If not set, defaults to DEFAULT_NAME.
+	Name string
+
+	// Kind of workload to run Envoy as, KindDaemonSet or KindDeployment.
+	// If not set, defaults to KindDaemonSet.
+	Kind string
+
+	// Replicas is the number of Envoy pods when Kind is KindDeployment.
+	// If not set, defaults to 2.
+	Replicas int32
+
+	// EnvoyImage is the Envoy container image.
+	// If not set, defaults to DEFAULT_ENVOY_IMAGE.
+	EnvoyImage string
+
+	// ContourImage is the image of the bootstrap init container.
+	// If not set, defaults to DEFAULT_CONTOUR_IMAGE.
+	ContourImage string
+
+	// ServiceType is the type of the Envoy Service.
+	// If not set, defaults to LoadBalancer.
+	ServiceType corev1.ServiceType
+
+	// XDSAddress and XDSPort are the address of Contour's xDS server.
+	// If not set, default to DEFAULT_XDS_ADDRESS and DEFAULT_XDS_PORT.
+	XDSAddress string
+	XDSPort    int
+
+	// HTTPPort and HTTPSPort are the ports Envoy's HTTP and HTTPS
+	// listeners bind to.
+	// If not set, default to DEFAULT_HTTP_PORT and DEFAULT_HTTPS_PORT.
+	HTTPPort  int
+	HTTPSPort int
+
+	// StatsPort is the port of Envoy's stats and readiness listener.
+	// If not set, defaults to DEFAULT_STATS_PORT.
+	StatsPort int
+}
+
+func (c *Config) name() string         { return stringOrDefault(c.Name, DEFAULT_NAME) }
+func (c *Config) kind() string         { return stringOrDefault(c.Kind, KindDaemonSet) }
+func (c *Config) envoyImage() string   { return stringOrDefault(c.EnvoyImage, DEFAULT_ENVOY_IMAGE) }
+func (c *Config) contourImage() string { return stringOrDefault(c.ContourImage, DEFAULT_CONTOUR_IMAGE) }
+func (c *Config) xdsAddress() string   { return stringOrDefault(c.XDSAddress, DEFAULT_XDS_ADDRESS) }
+func (c *Config) xdsPort() int         { return intOrDefault(c.XDSPort, DEFAULT_XDS_PORT) }
+func (c *Config) httpPort() int        { return intOrDefault(c.HTTPPort, DEFAULT_HTTP_PORT) }
+func (c *Config) httpsPort() int       { return intOrDefault(c.HTTPSPort, DEFAULT_HTTPS_PORT) }
+func (c *Config) statsPort() int       { return intOrDefault(c.StatsPort, DEFAULT_STATS_PORT) }
+
+func (c *Config) replicas() *int32 {
+	r := c.Replicas
+	if r == 0 {
+		r = 2
+	}
+	return &r
+}
+
+func (c *Config) serviceType() corev1.ServiceType {
+	if c.ServiceType == "" {
+		return corev1.ServiceTypeLoadBalancer
+	}
+	return c.ServiceType
+}
+
+func (c *Config) labels() map[string]string {
+	return map[string]string{"app": c.name()}
+}
+
+// Validate returns an error if the Config cannot be provisioned.
+func (c *Config) Validate() error {
+	if c.Namespace == "" {
+		return fmt.Errorf("namespace must be set")
+	}
+	switch c.kind() {
+	case KindDaemonSet, KindDeployment:
+	default:
+		return fmt.Errorf("kind %q must be one of %s or %s", c.Kind, KindDaemonSet, KindDeployment)
+	}
+	switch c.serviceType() {
+	case corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		return fmt.Errorf("service type %q must be one of ClusterIP, NodePort or LoadBalancer", c.ServiceType)
+	}
+	return nil
+}
+
+// DaemonSet returns the Envoy DaemonSet described by c.
+func DaemonSet(c *Config) *appsv1.DaemonSet {
+	maxUnavailable := intstr.FromString("10%")
+	spec := appsv1.DaemonSetSpec{
+		UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+				MaxUnavailable: &maxUnavailable,
+			},
+		},
+		Selector: &metav1.LabelSelector{
+			MatchLabels: c.labels(),
+		},
+		Template: podTemplate(c),
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: objectMeta(c, spec),
+		Spec:       spec,
+	}
+}
+
+// Deployment returns the Envoy Deployment described by c.
+func Deployment(c *Config) *appsv1.Deployment {
+	spec := appsv1.DeploymentSpec{
+		Replicas: c.replicas(),
+		Selector: &metav1.LabelSelector{
+			MatchLabels: c.labels(),
+		},
+		Template: podTemplate(c),
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: objectMeta(c, spec),
+		Spec:       spec,
+	}
+}
+
+// Service returns the Envoy Service described by c.
+func Service(c *Config) *corev1.Service {
+	spec := corev1.ServiceSpec{
+		Type:     c.serviceType(),
+		Selector: c.labels(),
+		Ports: []corev1.ServicePort{{
+			Name:       "http",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       80,
+			TargetPort: intstr.FromInt(c.httpPort()),
+		}, {
+			Name:       "https",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       443,
+			TargetPort: intstr.FromInt(c.httpsPort()),
+		}},
+	}
+	if spec.Type != corev1.ServiceTypeClusterIP {
+		spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+	}
+	return &corev1.Service{
+		ObjectMeta: objectMeta(c, spec),
+		Spec:       spec,
+	}
+}
+
+func objectMeta(c *Config, spec interface{}) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      c.name(),
+		Namespace: c.Namespace,
+		Labels:    c.labels(),
+		Annotations: map[string]string{
+			specHashAnnotation: specHash(spec),
+		},
+	}
+}
+
+func podTemplate(c *Config) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: c.labels(),
+			Annotations: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/port":   strconv.Itoa(c.statsPort()),
+				"prometheus.io/path":   "/stats/prometheus",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "envoy",
+				Image:   c.envoyImage(),
+				Command: []string{"envoy"},
+				Args: []string{
+					"-c",
+					"/config/envoy.json",
+					"--service-cluster $(CONTOUR_NAMESPACE)",
+					"--service-node $(ENVOY_POD_NAME)",
+					"--log-level info",
+				},
+				Env: []corev1.EnvVar{
+					fieldRefEnv("CONTOUR_NAMESPACE", "metadata.namespace"),
+					fieldRefEnv("ENVOY_POD_NAME", "metadata.name"),
+				},
+				Ports: []corev1.ContainerPort{{
+					Name:          "http",
+					ContainerPort: int32(c.httpPort()),
+					Protocol:      corev1.ProtocolTCP,
+				}, {
+					Name:          "https",
+					ContainerPort: int32(c.httpsPort()),
+					Protocol:      corev1.ProtocolTCP,
+				}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/ready",
+							Port: intstr.FromInt(c.statsPort()),
+						},
+					},
+					InitialDelaySeconds: 3,
+					PeriodSeconds:       3,
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "envoy-config", MountPath: "/config"},
+					{Name: "envoycert", MountPath: "/certs"},
+					{Name: "cacert", MountPath: "/ca"},
+				},
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.Handler{
+						Exec: &corev1.ExecAction{
+							Command: []string{
+								"bash", "-c", "--", "echo", "-ne",
+								"POST /healthcheck/fail HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n",
+								fmt.Sprintf(">/dev/tcp/localhost/%d", DEFAULT_ADMIN_PORT),
+							},
+						},
+					},
+				},
+			}},
+			InitContainers: []corev1.Container{{
+				Name:    "envoy-initconfig",
+				Image:   c.contourImage(),
+				Command: []string{"contour"},
+				Args: []string{
+					"bootstrap",
+					"/config/envoy.json",
+					"--xds-address=" + c.xdsAddress(),
+					"--xds-port=" + strconv.Itoa(c.xdsPort()),
+					"--envoy-cafile=/ca/cacert.pem",
+					"--envoy-cert-file=/certs/tls.crt",
+					"--envoy-key-file=/certs/tls.key",
+				},
+				Env: []corev1.EnvVar{
+					fieldRefEnv("CONTOUR_NAMESPACE", "metadata.namespace"),
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "envoy-config", MountPath: "/config"},
+					{Name: "envoycert", MountPath: "/certs", ReadOnly: true},
+					{Name: "cacert", MountPath: "/ca", ReadOnly: true},
+				},
+			}},
+			AutomountServiceAccountToken: new(bool),
+			Volumes: []corev1.Volume{
+				{Name: "envoy-config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "envoycert", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "envoycert"}}},
+				{Name: "cacert", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "cacert"}}},
+			},
+			RestartPolicy: corev1.RestartPolicyAlways,
+		},
+	}
+}
+
+func fieldRefEnv(name, path string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				APIVersion: "v1",
+				FieldPath:  path,
+			},
+		},
+	}
+}
+
+func stringOrDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func intOrDefault(i, def int) int {
+	if i == 0 {
+		return def
+	}
+	return i
+}
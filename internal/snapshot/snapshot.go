@@ -0,0 +1,209 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot exports the contents of Contour's xDS caches as a
+// tarball and serves a previously exported tarball read-only.
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+
+	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/projectcontour/contour/internal/grpc"
+
+	// register the filter configurations Contour embeds in
+	// listeners and routes so they can be marshalled to JSON.
+	_ "github.com/projectcontour/contour/internal/envoy"
+)
+
+// filenames maps each xDS type to its file in the tarball.
+var filenames = map[string]string{
+	cache.ClusterType:  "clusters.json",
+	cache.EndpointType: "endpoints.json",
+	cache.ListenerType: "listeners.json",
+	cache.RouteType:    "routes.json",
+	cache.SecretType:   "secrets.json",
+}
+
+// Write writes a gzipped tarball holding the contents of each of the
+// supplied resources to w. Each resource is written as a JSON encoded
+// DiscoveryResponse.
+func Write(w io.Writer, resources ...grpc.Resource) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+
+	for _, r := range resources {
+		name, ok := filenames[r.TypeURL()]
+		if !ok {
+			return fmt.Errorf("unknown resource type %q", r.TypeURL())
+		}
+		resp, err := discoveryResponse(r.TypeURL(), r.Contents())
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		m := &jsonpb.Marshaler{OrigName: true, Indent: "  "}
+		if err := m.Marshal(&buf, resp); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(buf.Len()),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func discoveryResponse(typeURL string, values []proto.Message) (*envoy_api_v2.DiscoveryResponse, error) {
+	resp := &envoy_api_v2.DiscoveryResponse{
+		TypeUrl: typeURL,
+	}
+	for _, v := range values {
+		a, err := ptypes.MarshalAny(v)
+		if err != nil {
+			return nil, err
+		}
+		resp.Resources = append(resp.Resources, a)
+	}
+	return resp, nil
+}
+
+// Read reads a tarball written by Write from r and returns a
+// Resource for each xDS type it holds.
+func Read(r io.Reader) ([]*Resource, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var resources []*Resource
+	seen := make(map[string]bool)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Ext(hdr.Name) != ".json" {
+			continue
+		}
+		buf, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		var resp envoy_api_v2.DiscoveryResponse
+		if err := jsonpb.Unmarshal(bytes.NewReader(buf), &resp); err != nil {
+			return nil, fmt.Errorf("%s: %v", hdr.Name, err)
+		}
+		if _, ok := filenames[resp.TypeUrl]; !ok {
+			return nil, fmt.Errorf("%s: unknown resource type %q", hdr.Name, resp.TypeUrl)
+		}
+		if seen[resp.TypeUrl] {
+			return nil, fmt.Errorf("%s: duplicate resource type %q", hdr.Name, resp.TypeUrl)
+		}
+		seen[resp.TypeUrl] = true
+		res, err := newResource(resp.TypeUrl, resp.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", hdr.Name, err)
+		}
+		resources = append(resources, res)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].typeURL < resources[j].typeURL
+	})
+	return resources, nil
+}
+
+// Resource is a grpc.Resource whose contents never change.
+type Resource struct {
+	typeURL string
+	values  []proto.Message
+	names   map[string]proto.Message
+}
+
+func newResource(typeURL string, resources []*any.Any) (*Resource, error) {
+	r := &Resource{
+		typeURL: typeURL,
+		names:   make(map[string]proto.Message),
+	}
+	for _, a := range resources {
+		var msg ptypes.DynamicAny
+		if err := ptypes.UnmarshalAny(a, &msg); err != nil {
+			return nil, err
+		}
+		r.values = append(r.values, msg.Message)
+		r.names[cache.GetResourceName(msg.Message)] = msg.Message
+	}
+	return r, nil
+}
+
+// Contents returns the contents of the snapshot for this type.
+func (r *Resource) Contents() []proto.Message {
+	return r.values
+}
+
+// Query returns the named entries of the snapshot for this type.
+// Names which are not in the snapshot are skipped.
+func (r *Resource) Query(names []string) []proto.Message {
+	var values []proto.Message
+	for _, n := range names {
+		if v, ok := r.names[n]; ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Register notifies ch immediately on a stream's first request, as
+// indicated by a negative last. As the contents never change later
+// registrations are never notified.
+func (r *Resource) Register(ch chan int, last int, hints ...string) {
+	if last < 0 {
+		ch <- 0
+	}
+}
+
+// TypeURL returns the xDS type of this resource.
+func (r *Resource) TypeURL() string {
+	return r.typeURL
+}
@@ -0,0 +1,109 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/envoy"
+)
+
+func TestWriteRead(t *testing.T) {
+	http := envoy.Listener("ingress_http", "0.0.0.0", 8080, nil,
+		envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0, envoy.HTTP1Options{}, 0),
+	)
+	var lc contour.ListenerCache
+	lc.Update(map[string]*v2.Listener{
+		http.Name: http,
+	})
+
+	var cc contour.ClusterCache
+	cc.Update(map[string]*v2.Cluster{
+		"default/kuard/80/da39a3ee5e": {
+			Name:                 "default/kuard/80/da39a3ee5e",
+			AltStatName:          "default_kuard_80",
+			ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
+		},
+		"default/httpbin/8080/da39a3ee5e": {
+			Name:                 "default/httpbin/8080/da39a3ee5e",
+			AltStatName:          "default_httpbin_8080",
+			ClusterDiscoveryType: envoy.ClusterDiscoveryType(v2.Cluster_EDS),
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, &cc, &lc); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(got))
+	}
+
+	clusters, listeners := got[0], got[1]
+	assert.Equal(t, cache.ClusterType, clusters.TypeURL())
+	assert.Equal(t, cache.ListenerType, listeners.TypeURL())
+	assertMessages(t, cc.Contents(), clusters.Contents())
+	assertMessages(t, lc.Contents(), listeners.Contents())
+	assertMessages(t, cc.Query([]string{"default/kuard/80/da39a3ee5e"}), clusters.Query([]string{"default/kuard/80/da39a3ee5e", "missing"}))
+}
+
+func TestResourceRegister(t *testing.T) {
+	r := &Resource{typeURL: cache.ClusterType}
+	ch := make(chan int, 1)
+
+	// the first request on a stream is answered immediately.
+	r.Register(ch, -1)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("expected notification for first request")
+	}
+
+	// later requests wait forever.
+	r.Register(ch, 0)
+	select {
+	case <-ch:
+		t.Fatal("unexpected notification")
+	default:
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	if _, err := Read(bytes.NewBufferString("not a tarball")); err == nil {
+		t.Fatal("expected error reading invalid snapshot")
+	}
+}
+
+func assertMessages(t *testing.T, want, got []proto.Message) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !proto.Equal(want[i], got[i]) {
+			t.Fatalf("message %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contourtest translates Kubernetes objects into the Envoy
+// configuration Contour serves for them, using the same DAG builder and
+// xDS caches as the contour serve command, so HTTPProxies and their
+// policies can be tested without a cluster or an Envoy.
+//
+// A table-driven test supplies the objects of each case to Translate,
+// then checks the virtual hosts, clusters and statuses of the result:
+//
+//	tr := contourtest.Translate(
+//		contourtest.Service("default", "www", 80),
+//		proxy,
+//	)
+//	contourtest.AssertStatus(t, tr, proxy, "valid", "valid HTTPProxy")
+//	vh := tr.VirtualHost("ingress_http", "www.example.com")
+package contourtest
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Translator translates Kubernetes objects as a Contour configured
+// with its fields would.
+type Translator struct {
+	// RootNamespaces restricts the namespaces of root HTTPProxies
+	// and IngressRoutes. If empty, roots may be in any namespace.
+	RootNamespaces []string
+
+	// IngressClass is Contour's ingress class, or a comma separated
+	// list of ingress classes. If not set, objects without an ingress
+	// class annotation, or annotated contour, are translated.
+	IngressClass string
+
+	// IngressClassRegex treats each ingress class as a regular
+	// expression.
+	IngressClassRegex bool
+}
+
+// Translation is the Envoy configuration Contour serves for a set of
+// objects, and the statuses it reports for their HTTPProxies and
+// IngressRoutes.
+type Translation struct {
+	// Listeners are keyed by name, such as ingress_http.
+	Listeners map[string]*v2.Listener
+
+	// RouteConfigurations are keyed by name, such as ingress_http
+	// and ingress_https.
+	RouteConfigurations map[string]*v2.RouteConfiguration
+
+	// Clusters are keyed by name.
+	Clusters map[string]*v2.Cluster
+
+	// ClusterLoadAssignments are keyed by cluster name.
+	ClusterLoadAssignments map[string]*v2.ClusterLoadAssignment
+
+	// Statuses are keyed by the namespace/name of the object.
+	Statuses map[string]Status
+}
+
+// Status is the status of an HTTPProxy or IngressRoute.
+type Status struct {
+	// Status is one of valid, invalid, warning or orphaned.
+	Status string
+
+	// Description explains the status.
+	Description string
+
+	// Vhost is the fqdn of the object, if it is a root.
+	Vhost string
+}
+
+// Translate translates objs as a Contour with the default
+// configuration would.
+func Translate(objs ...interface{}) *Translation {
+	var t Translator
+	return t.Translate(objs...)
+}
+
+// Translate translates objs, which may be Services, Endpoints,
+// Secrets, Ingresses, IngressRoutes, HTTPProxies and
+// TLSCertificateDelegations.
+func (t *Translator) Translate(objs ...interface{}) *Translation {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			RootNamespaces:    t.RootNamespaces,
+			IngressClass:      t.IngressClass,
+			IngressClassRegex: t.IngressClassRegex,
+			FieldLogger:       log,
+		},
+	}
+	et := &contour.EndpointsTranslator{
+		FieldLogger: log,
+	}
+	for _, obj := range objs {
+		if ep, ok := obj.(*v1.Endpoints); ok {
+			et.OnAdd(ep)
+			continue
+		}
+		builder.Source.Insert(obj)
+	}
+	d := builder.Build()
+
+	ch := &contour.CacheHandler{
+		Metrics:     metrics.NewMetrics(prometheus.NewRegistry()),
+		FieldLogger: log,
+	}
+	ch.OnChange(d)
+
+	tr := &Translation{
+		Listeners:              make(map[string]*v2.Listener),
+		RouteConfigurations:    make(map[string]*v2.RouteConfiguration),
+		Clusters:               make(map[string]*v2.Cluster),
+		ClusterLoadAssignments: make(map[string]*v2.ClusterLoadAssignment),
+		Statuses:               make(map[string]Status),
+	}
+	for _, m := range ch.ListenerCache.Contents() {
+		l := m.(*v2.Listener)
+		tr.Listeners[l.Name] = l
+	}
+	for _, m := range ch.RouteCache.Contents() {
+		rc := m.(*v2.RouteConfiguration)
+		tr.RouteConfigurations[rc.Name] = rc
+	}
+	for _, m := range ch.ClusterCache.Contents() {
+		c := m.(*v2.Cluster)
+		tr.Clusters[c.Name] = c
+	}
+	for _, m := range et.Contents() {
+		cla := m.(*v2.ClusterLoadAssignment)
+		tr.ClusterLoadAssignments[cla.ClusterName] = cla
+	}
+	for _, st := range d.Statuses() {
+		m := st.Object.GetObjectMeta()
+		tr.Statuses[m.GetNamespace()+"/"+m.GetName()] = Status{
+			Status:      st.Status,
+			Description: st.Description,
+			Vhost:       st.Vhost,
+		}
+	}
+	return tr
+}
+
+// VirtualHost returns the virtual host of the named route configuration
+// which serves requests for fqdn, or nil if there is none. As Envoy
+// does, an exact domain is preferred to the longest matching wildcard.
+func (tr *Translation) VirtualHost(routeConfiguration, fqdn string) *envoy_api_v2_route.VirtualHost {
+	rc, ok := tr.RouteConfigurations[routeConfiguration]
+	if !ok {
+		return nil
+	}
+	var wildcard *envoy_api_v2_route.VirtualHost
+	longest := -1
+	for _, vh := range rc.VirtualHosts {
+		for _, domain := range vh.Domains {
+			if domain == fqdn {
+				return vh
+			}
+			if !strings.HasPrefix(domain, "*") {
+				continue
+			}
+			suffix := domain[1:]
+			if strings.HasSuffix(fqdn, suffix) && len(suffix) > longest {
+				wildcard, longest = vh, len(suffix)
+			}
+		}
+	}
+	return wildcard
+}
+
+// Service returns a Service in namespace with a TCP port for each of
+// ports, which targets the same port of its pods.
+func Service(namespace, name string, ports ...int32) *v1.Service {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	for _, port := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, v1.ServicePort{
+			Protocol:   v1.ProtocolTCP,
+			Port:       port,
+			TargetPort: intstr.FromInt(int(port)),
+		})
+	}
+	return svc
+}
+
+// Endpoints returns the Endpoints of the Service in namespace, whose
+// addresses each serve port.
+func Endpoints(namespace, name string, port int32, addresses ...string) *v1.Endpoints {
+	ep := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	subset := v1.EndpointSubset{
+		Ports: []v1.EndpointPort{{
+			Port:     port,
+			Protocol: v1.ProtocolTCP,
+		}},
+	}
+	for _, a := range addresses {
+		subset.Addresses = append(subset.Addresses, v1.EndpointAddress{IP: a})
+	}
+	ep.Subsets = append(ep.Subsets, subset)
+	return ep
+}
+
+// AssertStatus fails the test if the status of obj is not status,
+// with description.
+func AssertStatus(t testing.TB, tr *Translation, obj metav1.Object, status, description string) {
+	t.Helper()
+	key := obj.GetNamespace() + "/" + obj.GetName()
+	got, ok := tr.Statuses[key]
+	switch {
+	case !ok:
+		t.Fatalf("%s: expected status %q, got none", key, status)
+	case got.Status != status || got.Description != description:
+		t.Fatalf("%s: expected status %q %q, got %q %q", key, status, description, got.Status, got.Description)
+	}
+}
+
+// AssertRoutes fails the test if the routes of the virtual host of the
+// named route configuration serving fqdn are not want.
+func AssertRoutes(t testing.TB, tr *Translation, routeConfiguration, fqdn string, want ...*envoy_api_v2_route.Route) {
+	t.Helper()
+	vh := tr.VirtualHost(routeConfiguration, fqdn)
+	if vh == nil {
+		t.Fatalf("%s: no virtual host serves %q", routeConfiguration, fqdn)
+	}
+	if diff := diff(want, vh.Routes); diff != "" {
+		t.Fatalf("%s: routes of %q differ: %s", routeConfiguration, fqdn, diff)
+	}
+}
+
+// AssertCluster fails the test if the named cluster is not want.
+func AssertCluster(t testing.TB, tr *Translation, want *v2.Cluster) {
+	t.Helper()
+	got, ok := tr.Clusters[want.Name]
+	if !ok {
+		t.Fatalf("no cluster %q", want.Name)
+	}
+	if diff := diff(want, got); diff != "" {
+		t.Fatalf("cluster %q differs: %s", want.Name, diff)
+	}
+}
+
+// diff returns the differences between want and got, which may hold
+// Envoy protobufs, or the empty string if they are equal.
+func diff(want, got interface{}) string {
+	return cmp.Diff(want, got, cmp.Comparer(proto.Equal))
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 VMware
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourtest
+
+import (
+	"testing"
+
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTranslate(t *testing.T) {
+	proxy := func(name, fqdn, service string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+				},
+				Routes: []projcontour.Route{{
+					Conditions: []projcontour.Condition{{
+						Prefix: "/",
+					}},
+					Services: []projcontour.Service{{
+						Name: service,
+						Port: 80,
+					}},
+				}},
+			},
+		}
+	}
+	www := proxy("www", "www.example.com", "www")
+	shops := proxy("shops", "*.shops.example.com", "shops")
+	missing := proxy("missing", "missing.example.com", "missing")
+
+	tr := Translate(
+		Service("default", "www", 80),
+		Endpoints("default", "www", 80, "10.0.0.1", "10.0.0.2"),
+		Service("default", "shops", 80),
+		www, shops, missing,
+	)
+
+	AssertStatus(t, tr, www, "valid", "valid HTTPProxy")
+	AssertStatus(t, tr, missing, "warning", "Service [missing:80] is invalid or missing")
+
+	AssertRoutes(t, tr, "ingress_http", "www.example.com", &envoy_api_v2_route.Route{
+		Match: envoy.RoutePrefix("/"),
+		Action: &envoy_api_v2_route.Route_Route{
+			Route: &envoy_api_v2_route.RouteAction{
+				ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+					Cluster: "default/www/80/da39a3ee5e",
+				},
+			},
+		},
+	})
+	if _, ok := tr.Clusters["default/www/80/da39a3ee5e"]; !ok {
+		t.Fatalf("expected cluster for default/www")
+	}
+	if cla := tr.ClusterLoadAssignments["default/www"]; len(cla.GetEndpoints()) != 1 || len(cla.Endpoints[0].LbEndpoints) != 2 {
+		t.Fatalf("expected two endpoints for default/www, got %v", cla)
+	}
+
+	// hosts are matched against wildcard domains.
+	if vh := tr.VirtualHost("ingress_http", "toys.shops.example.com"); vh == nil || vh.Domains[0] != "*.shops.example.com" {
+		t.Fatalf("expected *.shops.example.com to serve toys.shops.example.com, got %v", vh)
+	}
+	if vh := tr.VirtualHost("ingress_http", "www.example.org"); vh != nil {
+		t.Fatalf("expected no virtual host for www.example.org, got %v", vh)
+	}
+}
+
+func TestTranslatorRootNamespaces(t *testing.T) {
+	tr := (&Translator{RootNamespaces: []string{"roots"}}).Translate(&projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "www",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "www.example.com",
+			},
+		},
+	})
+
+	if st := tr.Statuses["default/www"]; st.Status != "invalid" {
+		t.Fatalf("expected root outside root namespaces to be invalid, got %v", st)
+	}
+}
+
+func TestTranslatorIngressClass(t *testing.T) {
+	proxy := func(name, class string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: name + ".example.com",
+				},
+				IngressClassName: class,
+			},
+		}
+	}
+	mine := proxy("mine", "internal")
+	theirs := proxy("theirs", "external")
+
+	tr := (&Translator{IngressClass: "internal"}).Translate(mine, theirs)
+
+	AssertStatus(t, tr, mine, "valid", "valid HTTPProxy")
+	// the HTTPProxy of another Contour is neither served nor statused.
+	if st, ok := tr.Statuses["default/theirs"]; ok {
+		t.Fatalf("expected no status for default/theirs, got %v", st)
+	}
+	if vh := tr.VirtualHost("ingress_http", "theirs.example.com"); vh != nil {
+		t.Fatalf("expected no virtual host for theirs.example.com, got %v", vh)
+	}
+}